@@ -0,0 +1,140 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"time"
+
+	"github.com/jordan-wright/email"
+)
+
+const (
+	channelTimeout     = 10 * time.Second
+	telegramAPIBaseURL = "https://api.telegram.org"
+)
+
+// EmailChannel sends the alerts by email.
+type EmailChannel struct {
+	sender EmailSender
+	from   string
+	to     []string
+}
+
+func (c *EmailChannel) Name() string {
+	return "email"
+}
+
+func (c *EmailChannel) Send(ctx context.Context, alert Alert) error {
+	mail := &email.Email{
+		From:    c.from,
+		To:      c.to,
+		Subject: fmt.Sprintf("[Peringatan] %s", alert.DrugName),
+		Text:    []byte(alert.Message),
+	}
+
+	if err := c.sender.Send(mail, channelTimeout); err != nil {
+		return fmt.Errorf("send alert email: %w", err)
+	}
+
+	return nil
+}
+
+func NewEmailChannel(sender EmailSender, from string, to []string) *EmailChannel {
+	return &EmailChannel{
+		sender: sender,
+		from:   from,
+		to:     to,
+	}
+}
+
+// HTTPChannel posts the alerts as JSON to a URL, e.g. a chat webhook.
+type HTTPChannel struct {
+	url        string
+	httpClient *http.Client
+}
+
+func (c *HTTPChannel) Name() string {
+	return "http"
+}
+
+func (c *HTTPChannel) Send(ctx context.Context, alert Alert) error {
+	return postJSON(ctx, c.httpClient, c.url, alert)
+}
+
+func NewHTTPChannel(url string) *HTTPChannel {
+	return &HTTPChannel{
+		url:        url,
+		httpClient: &http.Client{Timeout: channelTimeout},
+	}
+}
+
+// TelegramChannel sends the alert messages to a Telegram chat through a bot.
+type TelegramChannel struct {
+	botToken   string
+	chatID     string
+	httpClient *http.Client
+}
+
+func (c *TelegramChannel) Name() string {
+	return "telegram"
+}
+
+func (c *TelegramChannel) Send(ctx context.Context, alert Alert) error {
+	return postJSON(
+		ctx,
+		c.httpClient,
+		fmt.Sprintf("%s/bot%s/sendMessage", telegramAPIBaseURL, c.botToken),
+		map[string]string{
+			"chat_id": c.chatID,
+			"text":    alert.Message,
+		},
+	)
+}
+
+func NewTelegramChannel(botToken string, chatID string) *TelegramChannel {
+	return &TelegramChannel{
+		botToken:   botToken,
+		chatID:     chatID,
+		httpClient: &http.Client{Timeout: channelTimeout},
+	}
+}
+
+func postJSON(ctx context.Context, httpClient *http.Client, url string, body any) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("marshal request body: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := httpClient.Do(req)
+	if err != nil {
+		// The URL is left out of the error, since it may contain a secret, e.g. the Telegram bot token.
+		var urlErr *neturl.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+
+		return fmt.Errorf("send request: %w", err)
+	}
+	defer res.Body.Close()
+
+	// The body is drained so the connection can be reused.
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 1<<16))
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("got status code %d", res.StatusCode)
+	}
+
+	return nil
+}
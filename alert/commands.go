@@ -0,0 +1,21 @@
+package alert
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/turfaa/vmedis-proxy-api/pkg2/queue"
+)
+
+// RunAlerts evaluates the alert rules on the drug events until the context is done or a termination signal is received.
+func RunAlerts(ctx context.Context, service *Service, q queue.Queue) {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+
+	log.Println("Running alerts")
+	NewConsumer(service, q).StartConsuming(ctx)
+	log.Println("Alerts shut down successfully")
+}
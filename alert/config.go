@@ -0,0 +1,28 @@
+package alert
+
+import "time"
+
+// RulesConfig configures the thresholds of the alert rules.
+// The below-minimum-stock rule has no threshold, it uses the minimum stock of each drug.
+type RulesConfig struct {
+	// DaysOfCover is the number of days of average sales the stock of a drug must cover.
+	// Zero disables the low-days-of-cover rule.
+	DaysOfCover float64
+
+	// SalesLookbackDays is the number of days the average daily sales are computed over.
+	SalesLookbackDays int
+
+	// PriceChangePercentage is the percentage a price of a drug unit may change by without an alert.
+	// Zero disables the price-changed rule.
+	PriceChangePercentage float64
+
+	// Cooldown is the minimum time between two alerts of the same rule for the same drug.
+	Cooldown time.Duration
+}
+
+var DefaultRulesConfig = RulesConfig{
+	DaysOfCover:           7,
+	SalesLookbackDays:     28,
+	PriceChangePercentage: 20,
+	Cooldown:              24 * time.Hour,
+}
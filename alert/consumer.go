@@ -0,0 +1,92 @@
+package alert
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/turfaa/vmedis-proxy-api/events"
+	"github.com/turfaa/vmedis-proxy-api/kafkapb"
+	"github.com/turfaa/vmedis-proxy-api/pkg2/queue"
+	"github.com/turfaa/vmedis-proxy-api/pkg2/retry"
+)
+
+// ConsumerGroup is the consumer group of the drug events the alert rules are evaluated on.
+const ConsumerGroup = "vmedis-proxy-alerts"
+
+// DeadLetterTopic receives the drug events that still fail after the consumer retries.
+const DeadLetterTopic = "alert.dead_letter"
+
+// consumerRetryConfig retries a failing event a few times before it's sent to DeadLetterTopic,
+// so a broken event doesn't block the ones after it.
+var consumerRetryConfig = retry.Config{
+	MaxRetries:     3,
+	InitialBackoff: time.Second,
+	MaxBackoff:     10 * time.Second,
+}
+
+// Consumer evaluates the alert rules on the drug events produced after every drug detail upsert.
+type Consumer struct {
+	service  *Service
+	consumer *queue.Consumer
+}
+
+// StartConsuming consumes the drug event topics until the context is done.
+func (c *Consumer) StartConsuming(ctx context.Context) {
+	handlers := map[string]func(ctx context.Context, value []byte) error{
+		events.DrugStockChangedTopic: c.handleDrugStockChanged,
+		events.DrugPriceChangedTopic: c.handleDrugPriceChanged,
+	}
+
+	var wg sync.WaitGroup
+	for topic, handler := range handlers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.consume(ctx, topic, handler)
+		}()
+	}
+
+	wg.Wait()
+}
+
+func (c *Consumer) consume(ctx context.Context, topic string, handler func(ctx context.Context, value []byte) error) {
+	if err := c.consumer.Consume(ctx, topic, func(ctx context.Context, m queue.Message) error {
+		return handler(ctx, m.Value)
+	}); err != nil {
+		log.Printf("Error consuming %s: %s", topic, err)
+	}
+}
+
+func (c *Consumer) handleDrugStockChanged(ctx context.Context, value []byte) error {
+	var stockChanged kafkapb.DrugStockChanged
+	if err := protojson.Unmarshal(value, &stockChanged); err != nil {
+		return retry.Permanent(fmt.Errorf("unmarshal drug stock changed event: %w", err))
+	}
+
+	return c.service.EvaluateStockChanged(ctx, &stockChanged)
+}
+
+func (c *Consumer) handleDrugPriceChanged(ctx context.Context, value []byte) error {
+	var priceChanged kafkapb.DrugPriceChanged
+	if err := protojson.Unmarshal(value, &priceChanged); err != nil {
+		return retry.Permanent(fmt.Errorf("unmarshal drug price changed event: %w", err))
+	}
+
+	return c.service.EvaluatePriceChanged(ctx, &priceChanged)
+}
+
+func NewConsumer(service *Service, q queue.Queue) *Consumer {
+	return &Consumer{
+		service: service,
+		consumer: queue.NewConsumer(q, queue.ConsumerConfig{
+			Group:           ConsumerGroup,
+			DeadLetterTopic: DeadLetterTopic,
+			RetryConfig:     consumerRetryConfig,
+		}),
+	}
+}
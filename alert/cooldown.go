@@ -0,0 +1,47 @@
+package alert
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const cooldownRedisKeyPrefix = "alerts:cooldown:"
+
+// RedisCooldown keeps a drug from raising the same alert again until the cooldown expires.
+// The cooldowns are shared by every process raising alerts.
+type RedisCooldown struct {
+	redis redis.UniversalClient
+}
+
+// Start starts the cooldown of the rule for the drug.
+// It returns false if the cooldown already started and hasn't expired.
+func (c *RedisCooldown) Start(ctx context.Context, rule Rule, drugCode string, cooldown time.Duration) (bool, error) {
+	started, err := c.redis.SetNX(ctx, cooldownRedisKey(rule, drugCode), time.Now().Unix(), cooldown).Result()
+	if err != nil {
+		return false, fmt.Errorf("start %s cooldown of drug %s: %w", rule, drugCode, err)
+	}
+
+	return started, nil
+}
+
+// Cancel ends the cooldown of the rule for the drug, so the alert can be raised again right away.
+func (c *RedisCooldown) Cancel(ctx context.Context, rule Rule, drugCode string) error {
+	if err := c.redis.Del(ctx, cooldownRedisKey(rule, drugCode)).Err(); err != nil {
+		return fmt.Errorf("cancel %s cooldown of drug %s: %w", rule, drugCode, err)
+	}
+
+	return nil
+}
+
+func cooldownRedisKey(rule Rule, drugCode string) string {
+	return cooldownRedisKeyPrefix + string(rule) + ":" + drugCode
+}
+
+func NewRedisCooldown(redisClient redis.UniversalClient) *RedisCooldown {
+	return &RedisCooldown{
+		redis: redisClient,
+	}
+}
@@ -0,0 +1,108 @@
+package alert
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+)
+
+type Database struct {
+	db *gorm.DB
+}
+
+// soldUnit is the quantity of a drug sold in a unit.
+type soldUnit struct {
+	Unit   string
+	Amount float64
+}
+
+// CreateAlert creates the alert, unless the rule already fired on the same event.
+// It returns false if the alert already exists.
+func (d *Database) CreateAlert(ctx context.Context, alert *models.Alert) (bool, error) {
+	result := d.dbCtx(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "rule"}, {Name: "event_id"}},
+			DoNothing: true,
+		}).
+		Create(alert)
+	if result.Error != nil {
+		return false, fmt.Errorf("create %s alert of drug %s: %w", alert.Rule, alert.DrugCode, result.Error)
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+// GetAlerts returns the latest alerts, newest first.
+func (d *Database) GetAlerts(ctx context.Context, unacknowledgedOnly bool, limit int) ([]models.Alert, error) {
+	query := d.dbCtx(ctx).Order("id DESC").Limit(limit)
+	if unacknowledgedOnly {
+		query = query.Where("acknowledged_at IS NULL")
+	}
+
+	var alerts []models.Alert
+	if err := query.Find(&alerts).Error; err != nil {
+		return nil, fmt.Errorf("get alerts from db: %w", err)
+	}
+
+	return alerts, nil
+}
+
+// AcknowledgeAlert marks the alert acknowledged by the given user.
+// An alert already acknowledged keeps its first acknowledgement.
+func (d *Database) AcknowledgeAlert(ctx context.Context, id uint, acknowledgedBy string) (models.Alert, error) {
+	var alert models.Alert
+	err := d.dbCtx(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&alert, id).Error; err != nil {
+			return fmt.Errorf("get alert %d from db: %w", id, err)
+		}
+
+		if alert.AcknowledgedAt != nil {
+			return nil
+		}
+
+		now := time.Now()
+		alert.AcknowledgedAt = &now
+		alert.AcknowledgedBy = acknowledgedBy
+
+		if err := tx.Save(&alert).Error; err != nil {
+			return fmt.Errorf("save alert %d: %w", id, err)
+		}
+
+		return nil
+	})
+
+	return alert, err
+}
+
+// GetSoldUnitsBetweenTime returns the units of the drug sold between the given times.
+func (d *Database) GetSoldUnitsBetweenTime(ctx context.Context, drugCode string, from time.Time, until time.Time) ([]soldUnit, error) {
+	var units []soldUnit
+	if err := d.dbCtx(ctx).
+		Model(&models.SaleUnit{}).
+		Select("sale_units.unit, sale_units.amount").
+		Joins("JOIN sales ON sale_units.invoice_number = sales.invoice_number").
+		Where("sale_units.drug_code = ?", drugCode).
+		Where("sales.sold_at BETWEEN ? AND ?", from, until).
+		Where("sales.deleted_at IS NULL").
+		Find(&units).
+		Error; err != nil {
+		return nil, fmt.Errorf("get sold units of drug %s between %s and %s from db: %w", drugCode, from, until, err)
+	}
+
+	return units, nil
+}
+
+func (d *Database) dbCtx(ctx context.Context) *gorm.DB {
+	return d.db.WithContext(ctx)
+}
+
+func NewDatabase(db *gorm.DB) *Database {
+	return &Database{
+		db: db,
+	}
+}
@@ -0,0 +1,55 @@
+package alert
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/turfaa/vmedis-proxy-api/auth"
+)
+
+type ApiHandler struct {
+	service *Service
+}
+
+func NewApiHandler(service *Service) *ApiHandler {
+	return &ApiHandler{service: service}
+}
+
+// GetAlerts returns the in-app alerts, newest first.
+// The unacknowledged query parameter only returns the alerts that haven't been acknowledged.
+func (h *ApiHandler) GetAlerts(c *gin.Context) {
+	unacknowledgedOnly := c.Query("unacknowledged") == "true"
+
+	alerts, err := h.service.GetAlerts(c.Request.Context(), unacknowledgedOnly)
+	if err != nil {
+		c.JSON(500, gin.H{"error": fmt.Sprintf("failed to get alerts: %s", err)})
+		return
+	}
+
+	c.JSON(200, AlertsResponse{Alerts: alerts})
+}
+
+func (h *ApiHandler) AcknowledgeAlert(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{"error": fmt.Sprintf("invalid id: %s", err)})
+		return
+	}
+
+	alert, err := h.service.AcknowledgeAlert(c.Request.Context(), uint(id), auth.FromGinContext(c).Email)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(404, gin.H{"error": fmt.Sprintf("alert %d not found", id)})
+			return
+		}
+
+		c.JSON(500, gin.H{"error": fmt.Sprintf("failed to acknowledge alert %d: %s", id, err)})
+		return
+	}
+
+	c.JSON(200, AlertResponse{Alert: alert})
+}
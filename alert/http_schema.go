@@ -0,0 +1,9 @@
+package alert
+
+type AlertsResponse struct {
+	Alerts []Alert `json:"alerts"`
+}
+
+type AlertResponse struct {
+	Alert Alert `json:"alert"`
+}
@@ -0,0 +1,31 @@
+package alert
+
+import (
+	"context"
+	"time"
+
+	"github.com/jordan-wright/email"
+
+	"github.com/turfaa/vmedis-proxy-api/drug"
+)
+
+type DrugsGetter interface {
+	GetDrugsByVmedisCodes(ctx context.Context, vmedisCodes []string) ([]drug.Drug, error)
+}
+
+// Cooldown keeps a drug from raising the same alert too often.
+type Cooldown interface {
+	Start(ctx context.Context, rule Rule, drugCode string, cooldown time.Duration) (bool, error)
+	Cancel(ctx context.Context, rule Rule, drugCode string) error
+}
+
+// Channel sends the raised alerts outside of the app.
+// Every alert is also shown in-app, whichever channels are configured.
+type Channel interface {
+	Name() string
+	Send(ctx context.Context, alert Alert) error
+}
+
+type EmailSender interface {
+	Send(mail *email.Email, timeout time.Duration) error
+}
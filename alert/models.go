@@ -0,0 +1,49 @@
+package alert
+
+import (
+	"time"
+
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+)
+
+// Rule is the condition an alert is raised on.
+type Rule string
+
+const (
+	// RuleBelowMinimumStock fires when the stock of a drug is below its minimum stock.
+	RuleBelowMinimumStock Rule = "below_minimum_stock"
+
+	// RuleLowDaysOfCover fires when the stock of a drug covers fewer days of its average sales than configured.
+	RuleLowDaysOfCover Rule = "low_days_of_cover"
+
+	// RulePriceChanged fires when a price of a drug unit changes by more than the configured percentage.
+	RulePriceChanged Rule = "price_changed"
+)
+
+type Alert struct {
+	ID             uint       `json:"id"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	Rule           Rule       `json:"rule"`
+	DrugCode       string     `json:"drugCode"`
+	DrugName       string     `json:"drugName"`
+	Message        string     `json:"message"`
+	Value          float64    `json:"value"`
+	Threshold      float64    `json:"threshold"`
+	AcknowledgedAt *time.Time `json:"acknowledgedAt,omitempty"`
+	AcknowledgedBy string     `json:"acknowledgedBy,omitempty"`
+}
+
+func FromDBAlert(alert models.Alert) Alert {
+	return Alert{
+		ID:             alert.ID,
+		CreatedAt:      alert.CreatedAt,
+		Rule:           Rule(alert.Rule),
+		DrugCode:       alert.DrugCode,
+		DrugName:       alert.DrugName,
+		Message:        alert.Message,
+		Value:          alert.Value,
+		Threshold:      alert.Threshold,
+		AcknowledgedAt: alert.AcknowledgedAt,
+		AcknowledgedBy: alert.AcknowledgedBy,
+	}
+}
@@ -0,0 +1,199 @@
+package alert
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+	"github.com/turfaa/vmedis-proxy-api/drug"
+	"github.com/turfaa/vmedis-proxy-api/kafkapb"
+	"github.com/turfaa/vmedis-proxy-api/money"
+)
+
+// drugStock is the stock of a drug with the sizes of its units, to compare quantities of different units.
+type drugStock struct {
+	drug      drug.Drug
+	stocks    []*kafkapb.DrugStock
+	unitSizes map[string]float64
+}
+
+func newDrugStock(d drug.Drug, stocks []*kafkapb.DrugStock) drugStock {
+	units := slices.Clone(d.Units)
+	slices.SortFunc(units, func(a, b drug.Unit) int {
+		return cmp.Compare(a.UnitOrder, b.UnitOrder)
+	})
+
+	// Units that can't be converted are counted as they are.
+	sizes, ok := drug.UnitSizes(units)
+	if !ok {
+		sizes = nil
+	}
+
+	return drugStock{
+		drug:      d,
+		stocks:    stocks,
+		unitSizes: sizes,
+	}
+}
+
+// unitSize returns the content of the unit in the smallest unit.
+func (s drugStock) unitSize(unit string) float64 {
+	if size := s.unitSizes[strings.ToLower(unit)]; size > 0 {
+		return size
+	}
+
+	return 1
+}
+
+// smallestQuantity returns the total stock in the smallest unit.
+func (s drugStock) smallestQuantity() float64 {
+	var total float64
+	for _, stock := range s.stocks {
+		total += stock.GetQuantity() * s.unitSize(stock.GetUnit())
+	}
+
+	return total
+}
+
+// quantity returns the total stock in the given unit.
+// Without unit sizes, only the stock of the unit itself is counted.
+func (s drugStock) quantity(unit string) float64 {
+	if s.unitSizes == nil {
+		for _, stock := range s.stocks {
+			if stock.GetUnit() == unit {
+				return stock.GetQuantity()
+			}
+		}
+
+		return 0
+	}
+
+	return s.smallestQuantity() / s.unitSize(unit)
+}
+
+// belowMinimumStockAlert returns the alert of the drug if its stock is below its minimum stock, and false otherwise.
+// Drugs without a minimum stock never fire.
+func belowMinimumStockAlert(eventID string, stock drugStock) (models.Alert, bool) {
+	minimum := stock.drug.MinimumStock
+	if minimum.Quantity <= 0 {
+		return models.Alert{}, false
+	}
+
+	quantity := stock.quantity(minimum.Unit)
+	if quantity >= minimum.Quantity {
+		return models.Alert{}, false
+	}
+
+	return models.Alert{
+		Rule:     string(RuleBelowMinimumStock),
+		EventID:  eventID,
+		DrugCode: stock.drug.VmedisCode,
+		DrugName: stock.drug.Name,
+		Message: fmt.Sprintf(
+			"Stok %s tinggal %s %s, di bawah stok minimum %s %s",
+			stock.drug.Name,
+			formatQuantity(quantity),
+			minimum.Unit,
+			formatQuantity(minimum.Quantity),
+			minimum.Unit,
+		),
+		Value:     quantity,
+		Threshold: minimum.Quantity,
+	}, true
+}
+
+// lowDaysOfCoverAlert returns the alert of the drug if its stock covers fewer days of its average daily sales
+// than configured, and false otherwise.
+// soldQuantity is the quantity sold in the smallest unit during the configured sales lookback.
+// Drugs without sales never fire.
+func lowDaysOfCoverAlert(eventID string, stock drugStock, soldQuantity float64, config RulesConfig) (models.Alert, bool) {
+	if config.DaysOfCover <= 0 || config.SalesLookbackDays <= 0 || soldQuantity <= 0 {
+		return models.Alert{}, false
+	}
+
+	dailySales := soldQuantity / float64(config.SalesLookbackDays)
+	daysOfCover := max(stock.smallestQuantity(), 0) / dailySales
+	if daysOfCover >= config.DaysOfCover {
+		return models.Alert{}, false
+	}
+
+	return models.Alert{
+		Rule:     string(RuleLowDaysOfCover),
+		EventID:  eventID,
+		DrugCode: stock.drug.VmedisCode,
+		DrugName: stock.drug.Name,
+		Message: fmt.Sprintf(
+			"Stok %s hanya cukup untuk %s hari penjualan, di bawah batas %s hari",
+			stock.drug.Name,
+			strconv.FormatFloat(daysOfCover, 'f', 1, 64),
+			formatQuantity(config.DaysOfCover),
+		),
+		Value:     daysOfCover,
+		Threshold: config.DaysOfCover,
+	}, true
+}
+
+// priceChangedAlert returns the alert of the drug unit if any of its prices changed by more than the configured percentage,
+// and false otherwise. The alert describes the price that changed the most.
+// New units, and prices changed from zero, never fire.
+func priceChangedAlert(eventID string, drugName string, priceChanged *kafkapb.DrugPriceChanged, config RulesConfig) (models.Alert, bool) {
+	before, after := priceChanged.GetBefore(), priceChanged.GetAfter()
+	if config.PriceChangePercentage <= 0 || before == nil {
+		return models.Alert{}, false
+	}
+
+	prices := []struct {
+		name          string
+		before, after float64
+	}{
+		{"Harga 1", before.GetPriceOne(), after.GetPriceOne()},
+		{"Harga 2", before.GetPriceTwo(), after.GetPriceTwo()},
+		{"Harga 3", before.GetPriceThree(), after.GetPriceThree()},
+	}
+
+	var (
+		changedIndex      = -1
+		changedPercentage float64
+	)
+	for i, price := range prices {
+		if price.before <= 0 {
+			continue
+		}
+
+		percentage := (price.after - price.before) / price.before * 100
+		if math.Abs(percentage) > math.Abs(changedPercentage) {
+			changedIndex, changedPercentage = i, percentage
+		}
+	}
+
+	if changedIndex < 0 || math.Abs(changedPercentage) <= config.PriceChangePercentage {
+		return models.Alert{}, false
+	}
+
+	changed := prices[changedIndex]
+	return models.Alert{
+		Rule:     string(RulePriceChanged),
+		EventID:  eventID,
+		DrugCode: priceChanged.GetVmedisCode(),
+		DrugName: drugName,
+		Message: fmt.Sprintf(
+			"%s %s per %s berubah %s%% dari %s menjadi %s",
+			changed.name,
+			drugName,
+			priceChanged.GetUnit(),
+			strconv.FormatFloat(changedPercentage, 'f', 1, 64),
+			money.FormatRupiah(changed.before),
+			money.FormatRupiah(changed.after),
+		),
+		Value:     changedPercentage,
+		Threshold: config.PriceChangePercentage,
+	}, true
+}
+
+func formatQuantity(quantity float64) string {
+	return strconv.FormatFloat(quantity, 'f', -1, 64)
+}
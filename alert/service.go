@@ -0,0 +1,199 @@
+package alert
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+	"github.com/turfaa/vmedis-proxy-api/drug"
+	"github.com/turfaa/vmedis-proxy-api/kafkapb"
+	"github.com/turfaa/vmedis-proxy-api/pkg2/slices2"
+)
+
+const alertsLimit = 200
+
+type Service struct {
+	db          *Database
+	drugsGetter DrugsGetter
+	cooldown    Cooldown
+	channels    []Channel
+	config      RulesConfig
+}
+
+// GetAlerts returns the latest alerts, newest first.
+func (s *Service) GetAlerts(ctx context.Context, unacknowledgedOnly bool) ([]Alert, error) {
+	alerts, err := s.db.GetAlerts(ctx, unacknowledgedOnly, alertsLimit)
+	if err != nil {
+		return nil, fmt.Errorf("get alerts: %w", err)
+	}
+
+	return slices2.Map(alerts, FromDBAlert), nil
+}
+
+func (s *Service) AcknowledgeAlert(ctx context.Context, id uint, acknowledgedBy string) (Alert, error) {
+	alert, err := s.db.AcknowledgeAlert(ctx, id, acknowledgedBy)
+	if err != nil {
+		return Alert{}, fmt.Errorf("acknowledge alert %d: %w", id, err)
+	}
+
+	return FromDBAlert(alert), nil
+}
+
+// EvaluateStockChanged evaluates the stock rules on the stocks of the drug after the change.
+func (s *Service) EvaluateStockChanged(ctx context.Context, stockChanged *kafkapb.DrugStockChanged) error {
+	d, found, err := s.getDrug(ctx, stockChanged.GetVmedisCode())
+	if err != nil || !found {
+		return err
+	}
+
+	eventID := stockChanged.GetMetadata().GetEventId()
+	stock := newDrugStock(d, stockChanged.GetAfter())
+
+	var alerts []models.Alert
+	if alert, ok := belowMinimumStockAlert(eventID, stock); ok {
+		alerts = append(alerts, alert)
+	}
+
+	if s.config.DaysOfCover > 0 {
+		soldQuantity, err := s.getSoldQuantity(ctx, stock)
+		if err != nil {
+			return err
+		}
+
+		if alert, ok := lowDaysOfCoverAlert(eventID, stock, soldQuantity, s.config); ok {
+			alerts = append(alerts, alert)
+		}
+	}
+
+	return s.raise(ctx, alerts)
+}
+
+// EvaluatePriceChanged evaluates the price rule on the prices of the drug unit before and after the change.
+func (s *Service) EvaluatePriceChanged(ctx context.Context, priceChanged *kafkapb.DrugPriceChanged) error {
+	// The drug is only needed for its name, so it isn't read unless the rule fires.
+	if _, ok := priceChangedAlert("", "", priceChanged, s.config); !ok {
+		return nil
+	}
+
+	d, found, err := s.getDrug(ctx, priceChanged.GetVmedisCode())
+	if err != nil || !found {
+		return err
+	}
+
+	alert, _ := priceChangedAlert(priceChanged.GetMetadata().GetEventId(), d.Name, priceChanged, s.config)
+	return s.raise(ctx, []models.Alert{alert})
+}
+
+// raise stores the alerts to be shown in-app, and sends them to the channels.
+// An alert is dropped while the drug is cooling down from the same rule, or if it was already raised on the same event.
+func (s *Service) raise(ctx context.Context, alerts []models.Alert) error {
+	var errs []error
+	for _, alert := range alerts {
+		rule := Rule(alert.Rule)
+
+		started, err := s.startCooldown(ctx, rule, alert.DrugCode)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if !started {
+			continue
+		}
+
+		created, err := s.db.CreateAlert(ctx, &alert)
+		if err != nil {
+			// The cooldown is cancelled so the alert is raised when the event is retried.
+			if err := s.cancelCooldown(context.WithoutCancel(ctx), rule, alert.DrugCode); err != nil {
+				log.Printf("Error cancelling alert cooldown: %s", err)
+			}
+
+			errs = append(errs, err)
+			continue
+		}
+
+		if !created {
+			continue
+		}
+
+		log.Printf("Raised %s alert %d of drug %s", alert.Rule, alert.ID, alert.DrugCode)
+		s.send(ctx, FromDBAlert(alert))
+	}
+
+	return errors.Join(errs...)
+}
+
+// send sends the alert to every channel.
+// A failing channel doesn't stop the others, and isn't retried: the alert is still shown in-app.
+func (s *Service) send(ctx context.Context, alert Alert) {
+	for _, channel := range s.channels {
+		if err := channel.Send(ctx, alert); err != nil {
+			log.Printf("Error sending alert %d to the %s channel: %s", alert.ID, channel.Name(), err)
+		}
+	}
+}
+
+func (s *Service) startCooldown(ctx context.Context, rule Rule, drugCode string) (bool, error) {
+	if s.cooldown == nil || s.config.Cooldown <= 0 {
+		return true, nil
+	}
+
+	return s.cooldown.Start(ctx, rule, drugCode, s.config.Cooldown)
+}
+
+func (s *Service) cancelCooldown(ctx context.Context, rule Rule, drugCode string) error {
+	if s.cooldown == nil || s.config.Cooldown <= 0 {
+		return nil
+	}
+
+	return s.cooldown.Cancel(ctx, rule, drugCode)
+}
+
+// getDrug returns the drug with the given code, or false if the drug doesn't exist.
+func (s *Service) getDrug(ctx context.Context, vmedisCode string) (drug.Drug, bool, error) {
+	drugs, err := s.drugsGetter.GetDrugsByVmedisCodes(ctx, []string{vmedisCode})
+	if err != nil {
+		return drug.Drug{}, false, fmt.Errorf("get drug %s: %w", vmedisCode, err)
+	}
+
+	if len(drugs) == 0 {
+		return drug.Drug{}, false, nil
+	}
+
+	return drugs[0], true, nil
+}
+
+// getSoldQuantity returns the quantity of the drug sold in the smallest unit during the sales lookback.
+func (s *Service) getSoldQuantity(ctx context.Context, stock drugStock) (float64, error) {
+	until := time.Now()
+	from := until.AddDate(0, 0, -s.config.SalesLookbackDays)
+
+	soldUnits, err := s.db.GetSoldUnitsBetweenTime(ctx, stock.drug.VmedisCode, from, until)
+	if err != nil {
+		return 0, err
+	}
+
+	var quantity float64
+	for _, sold := range soldUnits {
+		quantity += sold.Amount * stock.unitSize(sold.Unit)
+	}
+
+	return quantity, nil
+}
+
+// NewService creates the alerting service.
+// The cooldown may be nil to raise every alert.
+func NewService(db *gorm.DB, drugsGetter DrugsGetter, cooldown Cooldown, channels []Channel, config RulesConfig) *Service {
+	return &Service{
+		db:          NewDatabase(db),
+		drugsGetter: drugsGetter,
+		cooldown:    cooldown,
+		channels:    channels,
+		config:      config,
+	}
+}
@@ -0,0 +1,310 @@
+package alert_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"gorm.io/gorm"
+
+	"github.com/turfaa/vmedis-proxy-api/alert"
+	"github.com/turfaa/vmedis-proxy-api/database"
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+	"github.com/turfaa/vmedis-proxy-api/drug"
+	"github.com/turfaa/vmedis-proxy-api/kafkapb"
+)
+
+var paracetamol = drug.Drug{
+	VmedisCode:   "PCT",
+	Name:         "Paracetamol",
+	MinimumStock: drug.Stock{Unit: "Strip", Quantity: 10},
+	Units: []drug.Unit{
+		{Unit: "Strip", ParentUnit: "Tablet", ConversionToParentUnit: 10, UnitOrder: 1},
+		{Unit: "Tablet", UnitOrder: 0},
+	},
+}
+
+// TestEvaluateStockChanged checks that a drug below its minimum stock, and with fewer days of cover than configured,
+// raises both alerts once, in-app and to the channels, and is then cooling down.
+func TestEvaluateStockChanged(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	channel := &recordingChannel{}
+	service := alert.NewService(db, drugsGetter{paracetamol}, newMemoryCooldown(), []alert.Channel{channel}, alert.DefaultRulesConfig)
+
+	// 28 Strips in 28 days is 10 Tablets a day, so 3 Strips and 20 Tablets last 5 days.
+	createSale(t, db, "INV-1", time.Now().AddDate(0, 0, -3), models.SaleUnit{DrugCode: "PCT", Amount: 28, Unit: "Strip"})
+	createSale(t, db, "INV-2", time.Now().AddDate(0, 0, -60), models.SaleUnit{DrugCode: "PCT", Amount: 1000, Unit: "Strip"})
+
+	if err := service.EvaluateStockChanged(ctx, stockChanged("event-1", 3, 20)); err != nil {
+		t.Fatalf("EvaluateStockChanged() error = %v", err)
+	}
+
+	alerts, err := service.GetAlerts(ctx, true)
+	if err != nil {
+		t.Fatalf("GetAlerts() error = %v", err)
+	}
+
+	if len(alerts) != 2 {
+		t.Fatalf("got %d alerts, want 2: %+v", len(alerts), alerts)
+	}
+
+	byRule := make(map[alert.Rule]alert.Alert, len(alerts))
+	for _, a := range alerts {
+		byRule[a.Rule] = a
+	}
+
+	if a := byRule[alert.RuleBelowMinimumStock]; a.Value != 5 || a.Threshold != 10 {
+		t.Errorf("below minimum stock alert = %+v, want 5 of 10 Strips", a)
+	}
+
+	if a := byRule[alert.RuleLowDaysOfCover]; a.Value != 5 || a.Threshold != 7 {
+		t.Errorf("low days of cover alert = %+v, want 5 of 7 days", a)
+	}
+
+	if sent := channel.sent(); len(sent) != 2 {
+		t.Errorf("sent %d alerts to the channel, want 2", len(sent))
+	}
+
+	// The drug is cooling down from both rules, even when the stock drops further.
+	if err := service.EvaluateStockChanged(ctx, stockChanged("event-2", 1, 0)); err != nil {
+		t.Fatalf("EvaluateStockChanged() error = %v", err)
+	}
+
+	if sent := channel.sent(); len(sent) != 2 {
+		t.Errorf("sent %d alerts to the channel while cooling down, want 2", len(sent))
+	}
+}
+
+// TestEvaluateStockChangedDeduplicates checks that an event evaluated twice raises its alerts once,
+// even without a cooldown.
+func TestEvaluateStockChangedDeduplicates(t *testing.T) {
+	ctx := context.Background()
+	channel := &recordingChannel{}
+	service := alert.NewService(newTestDB(t), drugsGetter{paracetamol}, nil, []alert.Channel{channel}, alert.DefaultRulesConfig)
+
+	for range 2 {
+		if err := service.EvaluateStockChanged(ctx, stockChanged("event-1", 0, 5)); err != nil {
+			t.Fatalf("EvaluateStockChanged() error = %v", err)
+		}
+	}
+
+	if sent := channel.sent(); len(sent) != 1 || sent[0].Rule != alert.RuleBelowMinimumStock {
+		t.Errorf("sent %+v, want 1 below minimum stock alert", sent)
+	}
+
+	// Without sales, the stock covers any number of days.
+	if err := service.EvaluateStockChanged(ctx, stockChanged("event-2", 20, 0)); err != nil {
+		t.Fatalf("EvaluateStockChanged() error = %v", err)
+	}
+
+	if sent := channel.sent(); len(sent) != 1 {
+		t.Errorf("sent %d alerts for a drug above its minimum stock, want 1", len(sent))
+	}
+}
+
+func TestEvaluatePriceChanged(t *testing.T) {
+	tests := []struct {
+		name         string
+		before       *kafkapb.DrugUnitPrices
+		after        *kafkapb.DrugUnitPrices
+		wantAlert    bool
+		wantMessage  string
+		wantPercents float64
+	}{
+		{
+			name:   "new unit",
+			before: nil,
+			after:  &kafkapb.DrugUnitPrices{PriceOne: 10000},
+		},
+		{
+			name:   "small change",
+			before: &kafkapb.DrugUnitPrices{PriceOne: 10000, PriceTwo: 9000},
+			after:  &kafkapb.DrugUnitPrices{PriceOne: 11000, PriceTwo: 9000},
+		},
+		{
+			name:         "largest change is reported",
+			before:       &kafkapb.DrugUnitPrices{PriceOne: 10000, PriceTwo: 8000},
+			after:        &kafkapb.DrugUnitPrices{PriceOne: 12500, PriceTwo: 6000},
+			wantAlert:    true,
+			wantMessage:  "Harga 1 Paracetamol per Strip berubah 25.0% dari Rp 10.000 menjadi Rp 12.500",
+			wantPercents: 25,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			channel := &recordingChannel{}
+			service := alert.NewService(newTestDB(t), drugsGetter{paracetamol}, nil, []alert.Channel{channel}, alert.DefaultRulesConfig)
+
+			if err := service.EvaluatePriceChanged(ctx, &kafkapb.DrugPriceChanged{
+				Metadata:   &kafkapb.EventMetadata{EventId: "event-1", OccurredAt: timestamppb.Now()},
+				VmedisCode: "PCT",
+				Unit:       "Strip",
+				Before:     tt.before,
+				After:      tt.after,
+			}); err != nil {
+				t.Fatalf("EvaluatePriceChanged() error = %v", err)
+			}
+
+			sent := channel.sent()
+			if !tt.wantAlert {
+				if len(sent) != 0 {
+					t.Errorf("sent %+v, want no alert", sent)
+				}
+				return
+			}
+
+			if len(sent) != 1 {
+				t.Fatalf("sent %d alerts, want 1", len(sent))
+			}
+
+			if sent[0].Rule != alert.RulePriceChanged || sent[0].Value != tt.wantPercents {
+				t.Errorf("sent %+v, want a %v%% price changed alert", sent[0], tt.wantPercents)
+			}
+
+			if sent[0].Message != tt.wantMessage {
+				t.Errorf("message = %q, want %q", sent[0].Message, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestAcknowledgeAlert(t *testing.T) {
+	ctx := context.Background()
+	service := alert.NewService(newTestDB(t), drugsGetter{paracetamol}, nil, nil, alert.DefaultRulesConfig)
+
+	if err := service.EvaluateStockChanged(ctx, stockChanged("event-1", 0, 5)); err != nil {
+		t.Fatalf("EvaluateStockChanged() error = %v", err)
+	}
+
+	alerts, err := service.GetAlerts(ctx, true)
+	if err != nil || len(alerts) != 1 {
+		t.Fatalf("GetAlerts() = %+v, %v, want 1 alert", alerts, err)
+	}
+
+	acknowledged, err := service.AcknowledgeAlert(ctx, alerts[0].ID, "staff@example.com")
+	if err != nil {
+		t.Fatalf("AcknowledgeAlert() error = %v", err)
+	}
+
+	if acknowledged.AcknowledgedAt == nil || acknowledged.AcknowledgedBy != "staff@example.com" {
+		t.Errorf("acknowledged alert = %+v, want acknowledged by staff@example.com", acknowledged)
+	}
+
+	if unacknowledged, err := service.GetAlerts(ctx, true); err != nil || len(unacknowledged) != 0 {
+		t.Errorf("GetAlerts(unacknowledged) = %+v, %v, want none", unacknowledged, err)
+	}
+
+	if all, err := service.GetAlerts(ctx, false); err != nil || len(all) != 1 {
+		t.Errorf("GetAlerts() = %+v, %v, want the acknowledged alert", all, err)
+	}
+}
+
+func stockChanged(eventID string, strips float64, tablets float64) *kafkapb.DrugStockChanged {
+	return &kafkapb.DrugStockChanged{
+		Metadata:   &kafkapb.EventMetadata{EventId: eventID, OccurredAt: timestamppb.Now()},
+		VmedisCode: "PCT",
+		After: []*kafkapb.DrugStock{
+			{Unit: "Strip", Quantity: strips},
+			{Unit: "Tablet", Quantity: tablets},
+		},
+	}
+}
+
+func createSale(t *testing.T, db *gorm.DB, invoiceNumber string, soldAt time.Time, units ...models.SaleUnit) {
+	t.Helper()
+
+	for i := range units {
+		units[i].IDInSale = i + 1
+	}
+
+	if err := db.Create(&models.Sale{InvoiceNumber: invoiceNumber, SoldAt: soldAt, SaleUnits: units}).Error; err != nil {
+		t.Fatalf("create sale %s: %v", invoiceNumber, err)
+	}
+}
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := database.SqliteDB(t.TempDir() + "/test.db")
+	if err != nil {
+		t.Fatalf("open test database: %v", err)
+	}
+
+	return db
+}
+
+type drugsGetter []drug.Drug
+
+func (g drugsGetter) GetDrugsByVmedisCodes(_ context.Context, vmedisCodes []string) ([]drug.Drug, error) {
+	var drugs []drug.Drug
+	for _, d := range g {
+		for _, code := range vmedisCodes {
+			if d.VmedisCode == code {
+				drugs = append(drugs, d)
+			}
+		}
+	}
+
+	return drugs, nil
+}
+
+type recordingChannel struct {
+	mu     sync.Mutex
+	alerts []alert.Alert
+}
+
+func (c *recordingChannel) Name() string {
+	return "recording"
+}
+
+func (c *recordingChannel) Send(_ context.Context, a alert.Alert) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.alerts = append(c.alerts, a)
+	return nil
+}
+
+func (c *recordingChannel) sent() []alert.Alert {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.alerts
+}
+
+// memoryCooldown is an in-process alert.Cooldown, in place of Redis.
+type memoryCooldown struct {
+	mu      sync.Mutex
+	expires map[string]time.Time
+}
+
+func (c *memoryCooldown) Start(_ context.Context, rule alert.Rule, drugCode string, cooldown time.Duration) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := fmt.Sprintf("%s:%s", rule, drugCode)
+	if time.Now().Before(c.expires[key]) {
+		return false, nil
+	}
+
+	c.expires[key] = time.Now().Add(cooldown)
+	return true, nil
+}
+
+func (c *memoryCooldown) Cancel(_ context.Context, rule alert.Rule, drugCode string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.expires, fmt.Sprintf("%s:%s", rule, drugCode))
+	return nil
+}
+
+func newMemoryCooldown() *memoryCooldown {
+	return &memoryCooldown{expires: make(map[string]time.Time)}
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/turfaa/vmedis-proxy-api/alert"
+)
+
+var alertsCmd = &cobra.Command{
+	Use:   "alerts",
+	Short: "Alerts commands",
+}
+
+var alertsCommands = []commandWithInit{
+	{
+		command: &cobra.Command{
+			Use:   "run",
+			Short: "Evaluate the alert rules on the drug events and send the alerts to the channels",
+			Run: func(cmd *cobra.Command, args []string) {
+				alert.RunAlerts(cmd.Context(), getAlertService(), getQueue())
+			},
+		},
+	},
+}
+
+// getAlertRulesConfig returns the alert rules config from the alerts section of the config, with the default thresholds.
+func getAlertRulesConfig() alert.RulesConfig {
+	config := alert.DefaultRulesConfig
+
+	if viper.IsSet("alerts.days_of_cover") {
+		config.DaysOfCover = viper.GetFloat64("alerts.days_of_cover")
+	}
+
+	if viper.IsSet("alerts.sales_lookback_days") {
+		config.SalesLookbackDays = viper.GetInt("alerts.sales_lookback_days")
+	}
+
+	if viper.IsSet("alerts.price_change_percentage") {
+		config.PriceChangePercentage = viper.GetFloat64("alerts.price_change_percentage")
+	}
+
+	if viper.IsSet("alerts.cooldown") {
+		config.Cooldown = viper.GetDuration("alerts.cooldown")
+	}
+
+	return config
+}
+
+// getAlertChannels returns the configured alert channels.
+// The alerts are always shown in-app, so no channel needs to be configured.
+func getAlertChannels() []alert.Channel {
+	var channels []alert.Channel
+
+	if to := viper.GetStringSlice("alerts.email.to"); len(to) > 0 {
+		channels = append(channels, alert.NewEmailChannel(getEmailer(), viper.GetString("email.from"), to))
+	}
+
+	if url := viper.GetString("alerts.http.url"); url != "" {
+		channels = append(channels, alert.NewHTTPChannel(url))
+	}
+
+	if botToken := viper.GetString("alerts.telegram.bot_token"); botToken != "" {
+		channels = append(channels, alert.NewTelegramChannel(botToken, viper.GetString("alerts.telegram.chat_id")))
+	}
+
+	return channels
+}
+
+func init() {
+	initSubcommands(alertsCmd, alertsCommands)
+}
@@ -10,15 +10,21 @@ import (
 	"time"
 
 	"github.com/redis/go-redis/v9"
-	"github.com/segmentio/kafka-go"
 	"github.com/spf13/viper"
 	"golang.org/x/time/rate"
 	"gorm.io/gorm"
 
+	"github.com/turfaa/vmedis-proxy-api/alert"
 	"github.com/turfaa/vmedis-proxy-api/auth"
 	"github.com/turfaa/vmedis-proxy-api/database"
 	"github.com/turfaa/vmedis-proxy-api/drug"
+	"github.com/turfaa/vmedis-proxy-api/events"
+	"github.com/turfaa/vmedis-proxy-api/forecast"
+	"github.com/turfaa/vmedis-proxy-api/inventory"
+	"github.com/turfaa/vmedis-proxy-api/kfa"
+	"github.com/turfaa/vmedis-proxy-api/outbox"
 	"github.com/turfaa/vmedis-proxy-api/pkg2/email2"
+	"github.com/turfaa/vmedis-proxy-api/pkg2/queue"
 	"github.com/turfaa/vmedis-proxy-api/procurement"
 	"github.com/turfaa/vmedis-proxy-api/rejecteddrug"
 	"github.com/turfaa/vmedis-proxy-api/sale"
@@ -26,6 +32,7 @@ import (
 	"github.com/turfaa/vmedis-proxy-api/stockopname"
 	vmedisv1 "github.com/turfaa/vmedis-proxy-api/vmedis/v1"
 	token2 "github.com/turfaa/vmedis-proxy-api/vmedis/v1/token"
+	"github.com/turfaa/vmedis-proxy-api/webhook"
 )
 
 var (
@@ -33,7 +40,9 @@ var (
 	vmedisClient       atomic.Pointer[vmedisv1.Client]
 	redisClient        atomic.Pointer[redis.UniversalClient]
 	drugProducer       atomic.Pointer[drug.Producer]
-	kafkaWriter        atomic.Pointer[kafka.Writer]
+	eventProducer      atomic.Pointer[events.Producer]
+	messageQueue       atomic.Pointer[queue.Queue]
+	messageOutbox      atomic.Pointer[outbox.Outbox]
 	tokenProvider      atomic.Pointer[token2.Provider]
 	vmedisRateLimiter  atomic.Pointer[rate.Limiter]
 	tokenRefresher     atomic.Pointer[token2.Refresher]
@@ -56,6 +65,21 @@ var (
 
 	rejectedDrugService atomic.Pointer[rejecteddrug.Service]
 	rejectedDrugHandler atomic.Pointer[rejecteddrug.ApiHandler]
+
+	kfaService atomic.Pointer[kfa.Service]
+	kfaHandler atomic.Pointer[kfa.ApiHandler]
+
+	inventoryService atomic.Pointer[inventory.Service]
+	inventoryHandler atomic.Pointer[inventory.ApiHandler]
+
+	forecastService atomic.Pointer[forecast.Service]
+	forecastHandler atomic.Pointer[forecast.ApiHandler]
+
+	webhookService atomic.Pointer[webhook.Service]
+	webhookHandler atomic.Pointer[webhook.ApiHandler]
+
+	alertService atomic.Pointer[alert.Service]
+	alertHandler atomic.Pointer[alert.ApiHandler]
 )
 
 func getDatabase() *gorm.DB {
@@ -135,12 +159,14 @@ func getRedisClient() redis.UniversalClient {
 	return newClient
 }
 
+// getDrugProducer returns the producer of the updated drug messages written through the outbox,
+// to be published by the outbox relay once the transaction writing them commits.
 func getDrugProducer() *drug.Producer {
 	if val := drugProducer.Load(); val != nil {
 		return val
 	}
 
-	newProducer := drug.NewProducer(getKafkaWriter())
+	newProducer := drug.NewProducer(getOutbox())
 
 	if !drugProducer.CompareAndSwap(nil, newProducer) {
 		return drugProducer.Load()
@@ -149,23 +175,62 @@ func getDrugProducer() *drug.Producer {
 	return newProducer
 }
 
-func getKafkaWriter() *kafka.Writer {
-	if val := kafkaWriter.Load(); val != nil {
+// getEventProducer returns the producer of the domain events written through the outbox,
+// to be published by the outbox relay once the transaction writing them commits.
+func getEventProducer() *events.Producer {
+	if val := eventProducer.Load(); val != nil {
 		return val
 	}
 
-	newWriter := &kafka.Writer{
-		Addr:         kafka.TCP(viper.GetStringSlice("kafka_brokers")...),
-		Balancer:     &kafka.LeastBytes{},
-		RequiredAcks: kafka.RequireOne,
-		Compression:  kafka.Snappy,
+	newProducer := events.NewProducer(getOutbox())
+
+	if !eventProducer.CompareAndSwap(nil, newProducer) {
+		return eventProducer.Load()
+	}
+
+	return newProducer
+}
+
+func getQueue() queue.Queue {
+	if val := messageQueue.Load(); val != nil {
+		return *val
+	}
+
+	backend, err := queue.ParseBackend(viper.GetString("queue_backend"))
+	if err != nil {
+		log.Fatalf("Error parsing queue backend: %s", err)
+	}
+
+	var newQueue queue.Queue
+	switch backend {
+	case queue.BackendKafka:
+		newQueue = queue.NewKafka(viper.GetStringSlice("kafka_brokers"))
+	case queue.BackendRedis:
+		newQueue = queue.NewRedis(getRedisClient())
+	case queue.BackendMemory:
+		log.Printf("Using the in-process queue, the messages are only consumed by this process")
+		newQueue = queue.NewMemory()
 	}
 
-	if !kafkaWriter.CompareAndSwap(nil, newWriter) {
-		return kafkaWriter.Load()
+	if !messageQueue.CompareAndSwap(nil, &newQueue) {
+		return *messageQueue.Load()
 	}
 
-	return newWriter
+	return newQueue
+}
+
+func getOutbox() *outbox.Outbox {
+	if val := messageOutbox.Load(); val != nil {
+		return val
+	}
+
+	newOutbox := outbox.NewOutbox(getDatabase())
+
+	if !messageOutbox.CompareAndSwap(nil, newOutbox) {
+		return messageOutbox.Load()
+	}
+
+	return newOutbox
 }
 
 func getTokenProvider() *token2.Provider {
@@ -221,7 +286,7 @@ func getDrugService() *drug.Service {
 		getRedisClient(),
 		getDatabase(),
 		getVmedisClient(),
-		getKafkaWriter(),
+		getQueue(),
 	)
 
 	if !drugService.CompareAndSwap(nil, newService) {
@@ -256,6 +321,7 @@ func getProcurementService() *procurement.Service {
 		getVmedisClient(),
 		getDrugProducer(),
 		getDrugDatabase(),
+		getEventProducer(),
 	)
 
 	if !procurementService.CompareAndSwap(nil, newService) {
@@ -275,6 +341,7 @@ func getSaleService() *sale.Service {
 		getVmedisClient(),
 		getDrugService(),
 		getDrugProducer(),
+		getEventProducer(),
 	)
 
 	if !saleService.CompareAndSwap(nil, newService) {
@@ -322,6 +389,8 @@ func getStockOpnameService() *stockopname.Service {
 		getDatabase(),
 		getVmedisClient(),
 		getDrugProducer(),
+		getEventProducer(),
+		getDrugService(),
 	)
 
 	if !stockOpnameService.CompareAndSwap(nil, newService) {
@@ -364,12 +433,12 @@ func getSaleHandler() *sale.ApiHandler {
 	return newHandler
 }
 
-func getProcurementHandler() *procurement.ApiHandler {
+func getProcurementHandler(minMarginPercentage float64) *procurement.ApiHandler {
 	if val := procurementHandler.Load(); val != nil {
 		return val
 	}
 
-	newHandler := procurement.NewApiHandler(getProcurementService())
+	newHandler := procurement.NewApiHandler(getProcurementService(), minMarginPercentage)
 
 	if !procurementHandler.CompareAndSwap(nil, newHandler) {
 		return procurementHandler.Load()
@@ -425,7 +494,7 @@ func getShiftService() *shift.Service {
 		return val
 	}
 
-	newService := shift.NewService(getDatabase(), getRedisClient(), getVmedisClient())
+	newService := shift.NewService(getDatabase(), getRedisClient(), getVmedisClient(), getEventProducer())
 
 	if !shiftService.CompareAndSwap(nil, newService) {
 		return shiftService.Load()
@@ -481,7 +550,7 @@ func getRejectedDrugService() *rejecteddrug.Service {
 		return val
 	}
 
-	newService := rejecteddrug.NewService(getDatabase())
+	newService := rejecteddrug.NewService(getDatabase(), getDrugService(), getDrugService(), getEventProducer())
 
 	if !rejectedDrugService.CompareAndSwap(nil, newService) {
 		return rejectedDrugService.Load()
@@ -503,3 +572,149 @@ func getRejectedDrugHandler() *rejecteddrug.ApiHandler {
 
 	return newHandler
 }
+
+func getKFAService() *kfa.Service {
+	if val := kfaService.Load(); val != nil {
+		return val
+	}
+
+	newService := kfa.NewService(getDatabase(), getDrugService())
+
+	if !kfaService.CompareAndSwap(nil, newService) {
+		return kfaService.Load()
+	}
+
+	return newService
+}
+
+func getKFAHandler() *kfa.ApiHandler {
+	if val := kfaHandler.Load(); val != nil {
+		return val
+	}
+
+	newHandler := kfa.NewApiHandler(getKFAService())
+
+	if !kfaHandler.CompareAndSwap(nil, newHandler) {
+		return kfaHandler.Load()
+	}
+
+	return newHandler
+}
+
+func getInventoryService() *inventory.Service {
+	if val := inventoryService.Load(); val != nil {
+		return val
+	}
+
+	newService := inventory.NewService(getDatabase(), getDrugDatabase())
+
+	if !inventoryService.CompareAndSwap(nil, newService) {
+		return inventoryService.Load()
+	}
+
+	return newService
+}
+
+func getInventoryHandler() *inventory.ApiHandler {
+	if val := inventoryHandler.Load(); val != nil {
+		return val
+	}
+
+	newHandler := inventory.NewApiHandler(getInventoryService())
+
+	if !inventoryHandler.CompareAndSwap(nil, newHandler) {
+		return inventoryHandler.Load()
+	}
+
+	return newHandler
+}
+
+func getForecastService() *forecast.Service {
+	if val := forecastService.Load(); val != nil {
+		return val
+	}
+
+	newService := forecast.NewService(getDatabase(), getDrugDatabase())
+
+	if !forecastService.CompareAndSwap(nil, newService) {
+		return forecastService.Load()
+	}
+
+	return newService
+}
+
+func getForecastHandler() *forecast.ApiHandler {
+	if val := forecastHandler.Load(); val != nil {
+		return val
+	}
+
+	newHandler := forecast.NewApiHandler(getForecastService())
+
+	if !forecastHandler.CompareAndSwap(nil, newHandler) {
+		return forecastHandler.Load()
+	}
+
+	return newHandler
+}
+
+func getWebhookService() *webhook.Service {
+	if val := webhookService.Load(); val != nil {
+		return val
+	}
+
+	newService := webhook.NewService(getDatabase(), getDrugService())
+
+	if !webhookService.CompareAndSwap(nil, newService) {
+		return webhookService.Load()
+	}
+
+	return newService
+}
+
+func getWebhookHandler() *webhook.ApiHandler {
+	if val := webhookHandler.Load(); val != nil {
+		return val
+	}
+
+	newHandler := webhook.NewApiHandler(getWebhookService())
+
+	if !webhookHandler.CompareAndSwap(nil, newHandler) {
+		return webhookHandler.Load()
+	}
+
+	return newHandler
+}
+
+func getAlertService() *alert.Service {
+	if val := alertService.Load(); val != nil {
+		return val
+	}
+
+	newService := alert.NewService(
+		getDatabase(),
+		getDrugService(),
+		alert.NewRedisCooldown(getRedisClient()),
+		getAlertChannels(),
+		getAlertRulesConfig(),
+	)
+
+	if !alertService.CompareAndSwap(nil, newService) {
+		return alertService.Load()
+	}
+
+	return newService
+}
+
+func getAlertHandler() *alert.ApiHandler {
+	if val := alertHandler.Load(); val != nil {
+		return val
+	}
+
+	newHandler := alert.NewApiHandler(getAlertService())
+
+	if !alertHandler.CompareAndSwap(nil, newHandler) {
+		return alertHandler.Load()
+	}
+
+	return newHandler
+}
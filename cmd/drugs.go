@@ -5,6 +5,7 @@ import (
 	"github.com/spf13/viper"
 
 	"github.com/turfaa/vmedis-proxy-api/drug"
+	"github.com/turfaa/vmedis-proxy-api/kfa"
 )
 
 var drugsCmd = &cobra.Command{
@@ -23,7 +24,7 @@ var drugsCommands = []commandWithInit{
 					getRedisClient(),
 					getDatabase(),
 					getVmedisClient(),
-					getKafkaWriter(),
+					getQueue(),
 				)
 			},
 		},
@@ -40,16 +41,129 @@ var drugsCommands = []commandWithInit{
 						DB:           getDatabase(),
 						RedisClient:  getRedisClient(),
 						VmedisClient: getVmedisClient(),
-						KafkaWriter:  getKafkaWriter(),
-						Brokers:      viper.GetStringSlice("kafka_brokers"),
+						Queue:        getQueue(),
 						Concurrency:  viper.GetInt("consumer_concurrency"),
+						MaxRetries:   viper.GetInt("consumer_max_retries"),
 					})
 			},
 		},
 		init: func(cmd *cobra.Command) {
-			cmd.Flags().Int("consumer-concurrency", 10, "Consumer concurrency")
+			cmd.Flags().Int("consumer-concurrency", drug.DefaultConsumerConcurrency, "Consumer concurrency")
+			cmd.Flags().Int("consumer-max-retries", drug.DefaultConsumerMaxRetries, "Number of retries of a failing message before it's sent to the dead-letter topic")
 
 			viper.BindPFlag("consumer_concurrency", cmd.Flags().Lookup("consumer-concurrency"))
+			viper.BindPFlag("consumer_max_retries", cmd.Flags().Lookup("consumer-max-retries"))
+		},
+	},
+
+	{
+		command: &cobra.Command{
+			Use:   "replay-dlq",
+			Short: "Requeue the drug messages in the dead-letter topic to their original topics",
+			Run: func(cmd *cobra.Command, args []string) {
+				drug.RequeueDeadLetters(
+					cmd.Context(),
+					getRedisClient(),
+					getDatabase(),
+					getVmedisClient(),
+					getQueue(),
+				)
+			},
+		},
+	},
+
+	{
+		command: &cobra.Command{
+			Use:   "import-kfa",
+			Short: "Import the KFA master file and suggest KFA codes for drugs",
+			Run: func(cmd *cobra.Command, args []string) {
+				kfa.ImportKFAProductsFromFile(
+					cmd.Context(),
+					getDatabase(),
+					viper.GetString("kfa_file"),
+					kfa.FileFormat(viper.GetString("kfa_file_format")),
+				)
+			},
+		},
+		init: func(cmd *cobra.Command) {
+			cmd.Flags().String("file", "", "Path to the KFA master file (CSV or JSON)")
+			cmd.Flags().String("format", "", "KFA master file format (csv or json), guessed from the file extension if empty")
+			cmd.MarkFlagRequired("file")
+
+			viper.BindPFlag("kfa_file", cmd.Flags().Lookup("file"))
+			viper.BindPFlag("kfa_file_format", cmd.Flags().Lookup("format"))
+		},
+	},
+
+	{
+		command: &cobra.Command{
+			Use:   "import-barcodes",
+			Short: "Import drug barcodes from a CSV file with barcode, kode_obat, and satuan columns",
+			Run: func(cmd *cobra.Command, args []string) {
+				drug.ImportBarcodesFromFile(
+					cmd.Context(),
+					getRedisClient(),
+					getDatabase(),
+					getVmedisClient(),
+					getQueue(),
+					viper.GetString("barcode_file"),
+				)
+			},
+		},
+		init: func(cmd *cobra.Command) {
+			cmd.Flags().String("file", "", "Path to the barcode CSV file")
+			cmd.MarkFlagRequired("file")
+
+			viper.BindPFlag("barcode_file", cmd.Flags().Lookup("file"))
+		},
+	},
+
+	{
+		command: &cobra.Command{
+			Use:   "generate-labels",
+			Short: "Render shelf price labels of the given drugs, or of the drugs whose prices changed, to a PDF file",
+			Run: func(cmd *cobra.Command, args []string) {
+				drug.GenerateLabelsToFile(
+					cmd.Context(),
+					getRedisClient(),
+					getDatabase(),
+					getVmedisClient(),
+					getQueue(),
+					drug.GenerateLabelsRequest{
+						DrugCodes:          viper.GetString("labels_drug_codes"),
+						PricesChangedSince: viper.GetString("labels_prices_changed_since"),
+						WithBarcodes:       viper.GetBool("labels_with_barcodes"),
+					},
+					viper.GetString("labels_output"),
+				)
+			},
+		},
+		init: func(cmd *cobra.Command) {
+			cmd.Flags().String("drug-codes", "", "Comma-separated vmedis codes of the drugs")
+			cmd.Flags().String("since", "", "Render the drugs whose prices changed since this date (YYYY-MM-DD)")
+			cmd.Flags().Bool("barcodes", false, "Print the drug barcodes on the labels")
+			cmd.Flags().String("output", "labels.pdf", "Path of the PDF file")
+
+			viper.BindPFlag("labels_drug_codes", cmd.Flags().Lookup("drug-codes"))
+			viper.BindPFlag("labels_prices_changed_since", cmd.Flags().Lookup("since"))
+			viper.BindPFlag("labels_with_barcodes", cmd.Flags().Lookup("barcodes"))
+			viper.BindPFlag("labels_output", cmd.Flags().Lookup("output"))
+		},
+	},
+
+	{
+		command: &cobra.Command{
+			Use:   "seed-equivalence-groups",
+			Short: "Group drugs by the active ingredient, strength, and dosage form of their KFA product",
+			Run: func(cmd *cobra.Command, args []string) {
+				drug.SeedEquivalenceGroupsFromKFA(
+					cmd.Context(),
+					getRedisClient(),
+					getDatabase(),
+					getVmedisClient(),
+					getQueue(),
+				)
+			},
 		},
 	},
 }
@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/turfaa/vmedis-proxy-api/forecast"
+)
+
+var forecastsCmd = &cobra.Command{
+	Use:   "forecasts",
+	Short: "Forecast commands",
+}
+
+var forecastsCommands = []commandWithInit{
+	{
+		command: &cobra.Command{
+			Use:   "build",
+			Short: "Forecast the daily demand of every sold drug for the next 30 days",
+			Run: func(cmd *cobra.Command, args []string) {
+				forecast.BuildForecasts(
+					cmd.Context(),
+					getDatabase(),
+					getDrugDatabase(),
+					viper.GetString("forecast_until"),
+					viper.GetInt("forecast_history_days"),
+				)
+			},
+		},
+		init: func(cmd *cobra.Command) {
+			cmd.Flags().String("until", "", "Last date of the sales history (YYYY-MM-DD), defaults to yesterday")
+			cmd.Flags().Int("history-days", forecast.DefaultHistoryDays, "Length of the sales history in days")
+
+			viper.BindPFlag("forecast_until", cmd.Flags().Lookup("until"))
+			viper.BindPFlag("forecast_history_days", cmd.Flags().Lookup("history-days"))
+		},
+	},
+}
+
+func init() {
+	initSubcommands(forecastsCmd, forecastsCommands)
+}
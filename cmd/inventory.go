@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/turfaa/vmedis-proxy-api/inventory"
+)
+
+var inventoryCmd = &cobra.Command{
+	Use:   "inventory",
+	Short: "Inventory commands",
+}
+
+var inventoryCommands = []commandWithInit{
+	{
+		command: &cobra.Command{
+			Use:   "classify",
+			Short: "Classify drugs by their revenue contribution (ABC) and demand variability (XYZ)",
+			Run: func(cmd *cobra.Command, args []string) {
+				inventory.ClassifyDrugs(
+					cmd.Context(),
+					getDatabase(),
+					getDrugDatabase(),
+					viper.GetString("classification_until"),
+					viper.GetInt("classification_weeks"),
+				)
+			},
+		},
+		init: func(cmd *cobra.Command) {
+			cmd.Flags().String("until", "", "Last date of the period (YYYY-MM-DD), defaults to yesterday")
+			cmd.Flags().Int("weeks", inventory.DefaultClassificationWeeks, "Length of the period in weeks")
+
+			viper.BindPFlag("classification_until", cmd.Flags().Lookup("until"))
+			viper.BindPFlag("classification_weeks", cmd.Flags().Lookup("weeks"))
+		},
+	},
+}
+
+func init() {
+	initSubcommands(inventoryCmd, inventoryCommands)
+}
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/turfaa/vmedis-proxy-api/outbox"
+)
+
+var outboxCmd = &cobra.Command{
+	Use:   "outbox",
+	Short: "Outbox commands",
+}
+
+var outboxCommands = []commandWithInit{
+	{
+		command: &cobra.Command{
+			Use:   "run-relay",
+			Short: "Run the relay publishing the outbox messages to the queue",
+			Run: func(cmd *cobra.Command, args []string) {
+				outbox.RunRelay(cmd.Context(), getDatabase(), getQueue(), getOutboxRelayConfig())
+			},
+		},
+		init: initOutboxRelayFlags,
+	},
+}
+
+func initOutboxRelayFlags(cmd *cobra.Command) {
+	cmd.Flags().Int("outbox-batch-size", outbox.DefaultRelayConfig.BatchSize, "Maximum number of outbox messages published at once")
+	cmd.Flags().Duration("outbox-poll-interval", outbox.DefaultRelayConfig.PollInterval, "Interval between polls of the outbox after it's drained")
+
+	viper.BindPFlag("outbox_batch_size", cmd.Flags().Lookup("outbox-batch-size"))
+	viper.BindPFlag("outbox_poll_interval", cmd.Flags().Lookup("outbox-poll-interval"))
+}
+
+func getOutboxRelayConfig() outbox.RelayConfig {
+	config := outbox.DefaultRelayConfig
+	config.BatchSize = viper.GetInt("outbox_batch_size")
+	config.PollInterval = viper.GetDuration("outbox_poll_interval")
+	return config
+}
+
+func init() {
+	initSubcommands(outboxCmd, outboxCommands)
+}
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 
 	"github.com/turfaa/vmedis-proxy-api/drug"
 	"github.com/turfaa/vmedis-proxy-api/procurement"
@@ -29,6 +30,7 @@ var procurementsCommands = []commandWithInit{
 					getVmedisClient(),
 					getDrugProducer(),
 					drug.NewDatabase(getDatabase()),
+					getEventProducer(),
 				)
 			},
 		},
@@ -52,6 +54,7 @@ var procurementsCommands = []commandWithInit{
 					getVmedisClient(),
 					getDrugProducer(),
 					drug.NewDatabase(getDatabase()),
+					getEventProducer(),
 				)
 			},
 		},
@@ -71,9 +74,27 @@ var procurementsCommands = []commandWithInit{
 					getVmedisClient(),
 					getDrugProducer(),
 					drug.NewDatabase(getDatabase()),
+					getEventProducer(),
+					procurement.RecommendationOptions{
+						Strategy:         procurement.RecommendationStrategy(viper.GetString("recommendation_strategy")),
+						HistoryDays:      viper.GetInt("recommendation_history_days"),
+						ReviewPeriodDays: viper.GetFloat64("recommendation_review_period_days"),
+						ServiceLevel:     viper.GetFloat64("recommendation_service_level"),
+					},
 				)
 			},
 		},
+		init: func(cmd *cobra.Command) {
+			cmd.Flags().String("strategy", string(procurement.RecommendationStrategyMinimumStock), "Recommendation strategy, MINIMUM_STOCK or VELOCITY")
+			cmd.Flags().Int("history-days", procurement.DefaultRecommendationHistoryDays, "Days of sales the velocity is measured from")
+			cmd.Flags().Float64("review-period-days", procurement.DefaultReviewPeriodDays, "Days between two orders to the same supplier")
+			cmd.Flags().Float64("service-level", procurement.DefaultServiceLevel, "Targeted probability of not running out of stock before the next delivery")
+
+			viper.BindPFlag("recommendation_strategy", cmd.Flags().Lookup("strategy"))
+			viper.BindPFlag("recommendation_history_days", cmd.Flags().Lookup("history-days"))
+			viper.BindPFlag("recommendation_review_period_days", cmd.Flags().Lookup("review-period-days"))
+			viper.BindPFlag("recommendation_service_level", cmd.Flags().Lookup("service-level"))
+		},
 	},
 }
 
@@ -8,6 +8,8 @@ import (
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+
+	"github.com/turfaa/vmedis-proxy-api/pkg2/queue"
 )
 
 var (
@@ -70,6 +72,7 @@ func initAppCommand(command *cobra.Command) {
 	command.Flags().String("redis-db", "0", "redis db")
 	command.Flags().Bool("redis-sentinel-mode", false, "redis sentinel mode")
 	command.Flags().String("redis-sentinel-master-name", "", "redis sentinel master name")
+	command.Flags().String("queue-backend", string(queue.BackendKafka), "queue backend: kafka, redis (streams), or memory (in-process)")
 	command.Flags().StringSlice("kafka-brokers", nil, "kafka brokers")
 	command.Flags().String("email.smtp-address", "", "smtp address")
 	command.Flags().String("email.smtp-username", "", "smtp username")
@@ -87,6 +90,7 @@ func initAppCommand(command *cobra.Command) {
 	viper.BindPFlag("redis_db", command.Flags().Lookup("redis-db"))
 	viper.BindPFlag("redis_sentinel_mode", command.Flags().Lookup("redis-sentinel-mode"))
 	viper.BindPFlag("redis_sentinel_master_name", command.Flags().Lookup("redis-sentinel-master-name"))
+	viper.BindPFlag("queue_backend", command.Flags().Lookup("queue-backend"))
 	viper.BindPFlag("kafka_brokers", command.Flags().Lookup("kafka-brokers"))
 	viper.BindPFlag("email.smtp_address", command.Flags().Lookup("email.smtp-address"))
 	viper.BindPFlag("email.smtp_username", command.Flags().Lookup("email.smtp-username"))
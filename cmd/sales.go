@@ -27,6 +27,7 @@ var salesCommands = []commandWithInit{
 					getVmedisClient(),
 					getDrugService(),
 					getDrugProducer(),
+					getEventProducer(),
 				)
 			},
 		},
@@ -49,6 +50,7 @@ var salesCommands = []commandWithInit{
 					getVmedisClient(),
 					getDrugService(),
 					getDrugProducer(),
+					getEventProducer(),
 				)
 			},
 		},
@@ -67,6 +69,7 @@ var salesCommands = []commandWithInit{
 					getVmedisClient(),
 					getDrugService(),
 					getDrugProducer(),
+					getEventProducer(),
 				)
 			},
 		},
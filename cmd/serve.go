@@ -7,7 +7,12 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
+	"github.com/turfaa/vmedis-proxy-api/alert"
+	"github.com/turfaa/vmedis-proxy-api/drug"
+	"github.com/turfaa/vmedis-proxy-api/outbox"
+	"github.com/turfaa/vmedis-proxy-api/pkg2/queue"
 	"github.com/turfaa/vmedis-proxy-api/proxy"
+	"github.com/turfaa/vmedis-proxy-api/webhook"
 )
 
 // serveCmd represents the serve command
@@ -23,6 +28,32 @@ var serveCmd = commandWithInit{
 				log.Fatalf("Error parsing stock opname start date '%s': %s", stockOpnameStartDateStr, err)
 			}
 
+			if viper.GetBool("run_outbox_relay") {
+				go outbox.RunRelay(cmd.Context(), getDatabase(), getQueue(), getOutboxRelayConfig())
+			}
+
+			if viper.GetBool("run_webhooks") {
+				go webhook.RunWebhooks(cmd.Context(), getWebhookService(), getQueue(), webhook.DefaultDispatcherConfig)
+			}
+
+			if viper.GetBool("run_alerts") {
+				go alert.RunAlerts(cmd.Context(), getAlertService(), getQueue())
+			}
+
+			// The in-process queue is only consumed by this process, so the consumers run alongside the server.
+			if viper.GetString("queue_backend") == string(queue.BackendMemory) {
+				go drug.RunUpdatedDrugsConsumer(
+					cmd.Context(),
+					drug.ConsumerConfig{
+						DB:           getDatabase(),
+						RedisClient:  getRedisClient(),
+						VmedisClient: getVmedisClient(),
+						Queue:        getQueue(),
+						Concurrency:  drug.DefaultConsumerConcurrency,
+						MaxRetries:   drug.DefaultConsumerMaxRetries,
+					})
+			}
+
 			proxy.Run(
 				proxy.Config{
 					DB:                  getDatabase(),
@@ -31,19 +62,33 @@ var serveCmd = commandWithInit{
 					AuthHandler:         getAuthHandler(),
 					DrugHandler:         getDrugHandler(stockOpnameStartDate),
 					SaleHandler:         getSaleHandler(),
-					ProcurementHandler:  getProcurementHandler(),
+					ProcurementHandler:  getProcurementHandler(viper.GetFloat64("min_margin_percentage")),
 					StockOpnameHandler:  getStockOpnameHandler(),
 					ShiftHandler:        getShiftHandler(),
 					TokenHandler:        getTokenHandler(),
 					RejectedDrugHandler: getRejectedDrugHandler(),
+					KFAHandler:          getKFAHandler(),
+					InventoryHandler:    getInventoryHandler(),
+					ForecastHandler:     getForecastHandler(),
+					WebhookHandler:      getWebhookHandler(),
+					AlertHandler:        getAlertHandler(),
 				},
 			)
 		},
 	},
 	init: func(cmd *cobra.Command) {
 		cmd.Flags().String("stock-opname-start-date", time.Now().AddDate(0, 0, -14).Format(time.DateOnly), "Stock opname start date")
+		cmd.Flags().Float64("min-margin-percentage", 10, "Margin percentage below which drug prices are flagged in the margin report")
+		cmd.Flags().Bool("run-outbox-relay", true, "Run the outbox relay alongside the server, disable when it runs as a separate process")
+		cmd.Flags().Bool("run-webhooks", true, "Send the webhook events alongside the server, disable when they're sent by a separate process")
+		cmd.Flags().Bool("run-alerts", true, "Evaluate the alert rules alongside the server, disable when they're evaluated by a separate process")
+		initOutboxRelayFlags(cmd)
 
 		viper.BindPFlag("stock_opname_start_date", cmd.Flags().Lookup("stock-opname-start-date"))
+		viper.BindPFlag("min_margin_percentage", cmd.Flags().Lookup("min-margin-percentage"))
+		viper.BindPFlag("run_outbox_relay", cmd.Flags().Lookup("run-outbox-relay"))
+		viper.BindPFlag("run_webhooks", cmd.Flags().Lookup("run-webhooks"))
+		viper.BindPFlag("run_alerts", cmd.Flags().Lookup("run-alerts"))
 	},
 }
 
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 
 	"github.com/turfaa/vmedis-proxy-api/stockopname"
 )
@@ -15,16 +16,80 @@ var stockOpnamesCommands = []commandWithInit{
 	{
 		command: &cobra.Command{
 			Use:   "dump",
-			Short: "Dump today's stock opnames",
+			Short: "Dump the stock opnames between two dates, today's by default",
 			Run: func(cmd *cobra.Command, args []string) {
-				stockopname.DumpTodayStockOpnames(
+				startTime, endTime := getDateRangeFromFlags(cmd)
+
+				stockopname.DumpStockOpnamesBetweenDatesFromVmedisToDB(
+					cmd.Context(),
+					startTime,
+					endTime,
+					getDatabase(),
+					getVmedisClient(),
+					getDrugProducer(),
+					getEventProducer(),
+					getDrugService(),
+				)
+			},
+		},
+		init: func(cmd *cobra.Command) {
+			registerDateRangeFlags(cmd, 0)
+		},
+	},
+
+	{
+		command: &cobra.Command{
+			Use:   "reconcile",
+			Short: "Soft-delete stock opnames that no longer exist in Vmedis, one date at a time",
+			Run: func(cmd *cobra.Command, args []string) {
+				startTime, endTime := getDateRangeFromFlags(cmd)
+
+				stockopname.ReconcileStockOpnamesBetweenDatesWithVmedis(
 					cmd.Context(),
+					startTime,
+					endTime,
 					getDatabase(),
 					getVmedisClient(),
 					getDrugProducer(),
+					getEventProducer(),
+					getDrugService(),
 				)
 			},
 		},
+		init: func(cmd *cobra.Command) {
+			registerDateRangeFlags(cmd, 0)
+		},
+	},
+
+	{
+		command: &cobra.Command{
+			Use:   "plan-cycle-count",
+			Short: "Spread the drugs across the working days of a cycle and assign them to the staff members",
+			Run: func(cmd *cobra.Command, args []string) {
+				stockopname.PlanCycleCount(
+					cmd.Context(),
+					getDatabase(),
+					getVmedisClient(),
+					getDrugProducer(),
+					getEventProducer(),
+					getDrugService(),
+					stockopname.CycleCountPlanRequest{
+						StartDate: viper.GetString("cycle_count_start_date"),
+						CycleDays: viper.GetInt("cycle_count_days"),
+						Staff:     viper.GetStringSlice("cycle_count_staff"),
+					},
+				)
+			},
+		},
+		init: func(cmd *cobra.Command) {
+			cmd.Flags().String("start-date", "", "First date of the cycle (YYYY-MM-DD), defaulting to today")
+			cmd.Flags().Int("cycle-days", stockopname.DefaultCycleDays, "Length of the cycle in days")
+			cmd.Flags().StringSlice("staff", nil, "Emails of the staff members, defaulting to the users with the staff role")
+
+			viper.BindPFlag("cycle_count_start_date", cmd.Flags().Lookup("start-date"))
+			viper.BindPFlag("cycle_count_days", cmd.Flags().Lookup("cycle-days"))
+			viper.BindPFlag("cycle_count_staff", cmd.Flags().Lookup("staff"))
+		},
 	},
 }
 
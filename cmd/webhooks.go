@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/turfaa/vmedis-proxy-api/webhook"
+)
+
+var webhooksCmd = &cobra.Command{
+	Use:   "webhooks",
+	Short: "Webhooks commands",
+}
+
+var webhooksCommands = []commandWithInit{
+	{
+		command: &cobra.Command{
+			Use:   "run",
+			Short: "Turn the domain events into webhook events and send them to the subscriptions",
+			Run: func(cmd *cobra.Command, args []string) {
+				webhook.RunWebhooks(cmd.Context(), getWebhookService(), getQueue(), webhook.DefaultDispatcherConfig)
+			},
+		},
+	},
+}
+
+func init() {
+	initSubcommands(webhooksCmd, webhooksCommands)
+}
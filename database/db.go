@@ -66,6 +66,24 @@ func AutoMigrate(db *gorm.DB) error {
 		models.VmedisToken{},
 		models.Shift{},
 		models.RejectedDrug{},
+		models.KFAProduct{},
+		models.KFAMatch{},
+		models.DrugEquivalenceGroup{},
+		models.DrugEquivalenceGroupMember{},
+		models.DrugBarcode{},
+		models.DrugClassification{},
+		models.OutboxMessage{},
+		models.WebhookSubscription{},
+		models.WebhookDelivery{},
+		models.Alert{},
+		models.CycleCountTask{},
+		models.StockCountSession{},
+		models.StockCountLine{},
+		models.DrugForecast{},
+		models.SupplierLeadTime{},
+		models.PurchaseOrder{},
+		models.PurchaseOrderLine{},
+		models.PurchaseOrderReceipt{},
 	}
 
 	for _, model := range availableModels {
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// Alert is an alert rule that fired for a drug.
+// Alerts are shown in-app, and sent to the configured alert channels.
+type Alert struct {
+	ID        uint      `gorm:"primarykey"`
+	CreatedAt time.Time `gorm:"index"`
+	UpdatedAt time.Time
+
+	Rule string `gorm:"index;uniqueIndex:idx_alert_rule_event"`
+
+	// EventID is the ID of the domain event the rule fired on,
+	// so an event consumed more than once raises the alert once.
+	EventID  string `gorm:"uniqueIndex:idx_alert_rule_event"`
+	DrugCode string `gorm:"index"`
+	DrugName string
+	Message  string
+
+	// Value is the measured value that crossed the Threshold of the rule,
+	// e.g. the stock quantity, the days of cover, or the percentage of a price change.
+	Value     float64
+	Threshold float64
+
+	AcknowledgedAt *time.Time `gorm:"index"`
+	AcknowledgedBy string
+}
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// CycleCountTask is a drug planned to be stock opnamed by a staff member on a date of the cycle count plan.
+// A task is completed by any stock opname of the drug from its date until the next task of the drug.
+type CycleCountTask struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	Date     datatypes.Date `gorm:"uniqueIndex:idx_cycle_count_task_date_drug_code"`
+	DrugCode string         `gorm:"index;uniqueIndex:idx_cycle_count_task_date_drug_code"`
+	DrugName string
+
+	// Assignee is the email of the staff member counting the drug, empty if no staff member was available.
+	Assignee string `gorm:"index"`
+
+	// CountsPerCycle is the number of times the drug is counted in a cycle,
+	// higher for the drugs with high-value and high-variance stock opname differences.
+	CountsPerCycle int
+}
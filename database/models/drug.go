@@ -17,6 +17,17 @@ type Drug struct {
 	Manufacturer string `gorm:"index"`
 	MinimumStock Stock  `gorm:"embedded;embeddedPrefix:minimum_stock_"`
 
+	// ContentHash is the hash of the drug's metadata, units, and stocks, used to detect real changes.
+	ContentHash string
+
+	// ChangedAt is the last time the content of the drug changed, or the drug was removed or restored,
+	// unlike UpdatedAt which changes on every dump.
+	// It is nil for drugs that haven't been dumped since the column was introduced.
+	ChangedAt *time.Time `gorm:"index"`
+
+	// RemovedAt is the time the drug was found missing from Vmedis. It is nil for existing drugs.
+	RemovedAt *time.Time `gorm:"index"`
+
 	Units  []DrugUnit  `gorm:"foreignKey:DrugVmedisCode;references:VmedisCode"`
 	Stocks []DrugStock `gorm:"foreignKey:DrugVmedisCode;references:VmedisCode"`
 }
@@ -63,6 +74,10 @@ type DrugUnit struct {
 	PriceOne   float64
 	PriceTwo   float64
 	PriceThree float64
+
+	// PriceUpdatedAt is the last time any of the prices changed, unlike UpdatedAt which changes on every dump.
+	// It is nil for units whose prices haven't changed since the column was introduced.
+	PriceUpdatedAt *time.Time `gorm:"index"`
 }
 
 // DrugStock represents a stock of a drug.
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+)
+
+// DrugBarcode is a barcode (EAN/GTIN) printed on a drug package.
+// A drug can have several barcodes, e.g. one per unit or per manufacturer.
+type DrugBarcode struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	// Barcode is stored in its canonical form, see drug.CanonicalBarcode.
+	Barcode        string `gorm:"unique;not null"`
+	DrugVmedisCode string `gorm:"index;not null"`
+
+	// Unit is the drug unit the barcode is printed on, e.g. the box or the strip.
+	// Empty if unknown.
+	Unit string
+}
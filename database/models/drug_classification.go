@@ -0,0 +1,62 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// DrugClassification is the ABC/XYZ class of a drug within a period.
+// ABC classifies the drug by its revenue contribution and XYZ by the variability of its weekly demand.
+// A period is identified by its end date, so reclassifying a period replaces its classifications.
+type DrugClassification struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	PeriodStart    datatypes.Date `gorm:"not null"`
+	PeriodEnd      datatypes.Date `gorm:"uniqueIndex:idx_drug_classifications_period_drug;index;not null"`
+	DrugVmedisCode string         `gorm:"uniqueIndex:idx_drug_classifications_period_drug;index;not null"`
+
+	Revenue float64
+
+	// RevenueShare is the share of the drug in the revenue of the period, between 0 and 1.
+	RevenueShare float64
+
+	// CoefficientOfVariation is the coefficient of variation of the weekly sold quantities.
+	// It is nil if the drug wasn't sold in the period.
+	CoefficientOfVariation *float64
+
+	ABCClass ABCClass `gorm:"index;not null"`
+	XYZClass XYZClass `gorm:"index;not null"`
+}
+
+// ABCClass classifies a drug by its revenue contribution.
+// A drugs make up the bulk of the revenue, while C drugs barely contribute.
+type ABCClass string
+
+const (
+	ABCClassA ABCClass = "A"
+	ABCClassB ABCClass = "B"
+	ABCClassC ABCClass = "C"
+)
+
+// AllABCClasses returns all ABC classes, from the highest revenue contribution.
+func AllABCClasses() []ABCClass {
+	return []ABCClass{ABCClassA, ABCClassB, ABCClassC}
+}
+
+// XYZClass classifies a drug by the variability of its demand.
+// X drugs sell steadily, while Z drugs sell erratically or not at all.
+type XYZClass string
+
+const (
+	XYZClassX XYZClass = "X"
+	XYZClassY XYZClass = "Y"
+	XYZClassZ XYZClass = "Z"
+)
+
+// AllXYZClasses returns all XYZ classes, from the steadiest demand.
+func AllXYZClasses() []XYZClass {
+	return []XYZClass{XYZClassX, XYZClassY, XYZClassZ}
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+)
+
+// DrugEquivalenceGroup is a group of drugs that can substitute each other,
+// i.e. drugs with the same active ingredient, strength, and dosage form.
+type DrugEquivalenceGroup struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	Name             string `gorm:"index;not null"`
+	ActiveIngredient string `gorm:"index"`
+	Strength         string
+	DosageForm       string
+
+	Members []DrugEquivalenceGroupMember `gorm:"foreignKey:GroupID"`
+}
+
+// DrugEquivalenceGroupMember is a drug in an equivalence group.
+// A drug belongs to at most one group.
+type DrugEquivalenceGroupMember struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+
+	GroupID        uint   `gorm:"index;not null"`
+	DrugVmedisCode string `gorm:"unique;not null"`
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// DrugForecast is the expected demand of a drug on a day, in the smallest unit of the drug.
+// The forecasts of all drugs are replaced every time they are built.
+type DrugForecast struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	DrugVmedisCode string         `gorm:"uniqueIndex:idx_drug_forecasts_drug_date;not null"`
+	Date           datatypes.Date `gorm:"uniqueIndex:idx_drug_forecasts_drug_date;not null"`
+
+	Quantity float64
+
+	// Unit is the smallest unit of the drug, empty if the drug has no known units.
+	Unit string
+
+	// HistoryEnd is the last day of the sales the forecast is built from.
+	HistoryEnd datatypes.Date `gorm:"not null"`
+
+	// Model is the model chosen for the drug by its backtest error.
+	Model ForecastModel `gorm:"not null"`
+
+	// BacktestError is the mean absolute error of the model over the backtest days.
+	BacktestError float64
+}
+
+// ForecastModel is a model forecasting the daily demand of a drug.
+type ForecastModel string
+
+const (
+	// ForecastModelMovingAverage7 forecasts the average demand of the last 7 days.
+	ForecastModelMovingAverage7 ForecastModel = "moving-average-7"
+
+	// ForecastModelMovingAverage28 forecasts the average demand of the last 28 days.
+	ForecastModelMovingAverage28 ForecastModel = "moving-average-28"
+
+	// ForecastModelSeasonalExponentialSmoothing forecasts the demand by exponential smoothing with weekly seasonality.
+	ForecastModelSeasonalExponentialSmoothing ForecastModel = "seasonal-exponential-smoothing"
+)
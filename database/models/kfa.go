@@ -0,0 +1,85 @@
+package models
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"time"
+)
+
+// KFAProduct is a product in the KFA (Kamus Farmasi dan Alat Kesehatan) SatuSehat master file.
+type KFAProduct struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	Code             string `gorm:"unique;not null"`
+	Name             string `gorm:"index;not null"`
+	Manufacturer     string `gorm:"index"`
+	ActiveIngredient string `gorm:"index"`
+	Strength         string
+	DosageForm       string
+}
+
+// KFAMatch is a suggested KFA code for a drug, waiting to be reviewed.
+type KFAMatch struct {
+	ID        uint      `gorm:"primarykey"`
+	CreatedAt time.Time `gorm:"not null;default:CURRENT_TIMESTAMP"`
+	UpdatedAt time.Time `gorm:"not null;default:CURRENT_TIMESTAMP"`
+
+	DrugVmedisCode string `gorm:"uniqueIndex:idx_kfa_matches_drug_kfa;not null"`
+	KFACode        string `gorm:"uniqueIndex:idx_kfa_matches_drug_kfa;index;not null"`
+
+	// Confidence is the similarity score of the match, between 0 and 1.
+	Confidence float64        `gorm:"index"`
+	Status     KFAMatchStatus `gorm:"index;not null;default:PENDING"`
+	ReviewedAt *time.Time
+	ReviewedBy string
+}
+
+type KFAMatchStatus string
+
+const (
+	KFAMatchStatusPending  KFAMatchStatus = "PENDING"
+	KFAMatchStatusAccepted KFAMatchStatus = "ACCEPTED"
+	KFAMatchStatusRejected KFAMatchStatus = "REJECTED"
+)
+
+// AllKFAMatchStatuses returns all known KFA match statuses.
+func AllKFAMatchStatuses() []KFAMatchStatus {
+	return []KFAMatchStatus{
+		KFAMatchStatusPending,
+		KFAMatchStatusAccepted,
+		KFAMatchStatusRejected,
+	}
+}
+
+func (s KFAMatchStatus) Valid() bool {
+	for _, status := range AllKFAMatchStatuses() {
+		if s == status {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *KFAMatchStatus) Scan(src any) error {
+	switch val := src.(type) {
+	case string:
+		*s = KFAMatchStatus(val)
+	case []byte:
+		*s = KFAMatchStatus(val)
+	default:
+		return fmt.Errorf("unsupported type: %T", src)
+	}
+
+	return nil
+}
+
+func (s KFAMatchStatus) Value() (driver.Value, error) {
+	return string(s), nil
+}
+
+func (s KFAMatchStatus) String() string {
+	return string(s)
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// OutboxMessage is a message written in the same transaction as the change it announces,
+// waiting to be published to the queue by the outbox relay.
+type OutboxMessage struct {
+	ID        uint      `gorm:"primarykey"`
+	CreatedAt time.Time `gorm:"index"`
+
+	Topic   string
+	Key     []byte
+	Value   []byte
+	Headers map[string]string `gorm:"serializer:json"`
+
+	// NextAttemptAt is the earliest time the relay may publish the message,
+	// pushed back while a relay is publishing it and after every failed attempt.
+	NextAttemptAt time.Time `gorm:"index"`
+
+	// ClaimedBy identifies the relay run that last claimed the message.
+	ClaimedBy string
+
+	Attempts  int
+	LastError string
+	SentAt    sql.NullTime `gorm:"index"`
+}
@@ -0,0 +1,68 @@
+package models
+
+import "time"
+
+// PurchaseOrderStatus is the state of a purchase order.
+// A purchase order moves from draft to sent, then to partially received and received as its items arrive.
+type PurchaseOrderStatus string
+
+const (
+	PurchaseOrderStatusDraft             PurchaseOrderStatus = "DRAFT"
+	PurchaseOrderStatusSent              PurchaseOrderStatus = "SENT"
+	PurchaseOrderStatusPartiallyReceived PurchaseOrderStatus = "PARTIALLY_RECEIVED"
+	PurchaseOrderStatusReceived          PurchaseOrderStatus = "RECEIVED"
+)
+
+// PurchaseOrder is an order of drugs to a supplier, built from the procurement recommendations.
+type PurchaseOrder struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	Supplier  string              `gorm:"index"`
+	Status    PurchaseOrderStatus `gorm:"index;not null"`
+	CreatedBy string
+
+	// SentAt is the time the order was sent to the supplier, nil while it's still a draft.
+	// Only the procurements invoiced since the date it was sent are matched with its lines.
+	SentAt *time.Time
+	SentBy string
+
+	// ReceivedAt is the time all lines of the order were received.
+	ReceivedAt *time.Time
+
+	Lines []PurchaseOrderLine `gorm:"foreignKey:PurchaseOrderID"`
+}
+
+// PurchaseOrderLine is the ordered quantity of a drug in one of its units.
+type PurchaseOrderLine struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	PurchaseOrderID uint   `gorm:"uniqueIndex:idx_purchase_order_line"`
+	DrugCode        string `gorm:"index;uniqueIndex:idx_purchase_order_line"`
+	DrugName        string
+	Unit            string
+	Quantity        float64
+
+	// ReceivedQuantity is the sum of the matched procurement units, in the unit of the line.
+	ReceivedQuantity float64
+
+	// Reasoning explains the recommended quantity the line was built from.
+	Reasoning string
+}
+
+// PurchaseOrderReceipt is a procurement unit matched with a purchase order line.
+// A procurement unit is matched with at most one line.
+type PurchaseOrderReceipt struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+
+	PurchaseOrderLineID uint   `gorm:"index"`
+	InvoiceNumber       string `gorm:"uniqueIndex:idx_purchase_order_receipt"`
+	IDInProcurement     int    `gorm:"uniqueIndex:idx_purchase_order_receipt"`
+
+	// Quantity is the procured amount, in the unit of the line.
+	Quantity float64
+}
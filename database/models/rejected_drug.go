@@ -15,9 +15,11 @@ type RejectedDrug struct {
 	DrugName        string                 `gorm:"index;not null"`
 	Resolution      RejectedDrugResolution `gorm:"index;not null;default:UNRESOLVED"`
 	ResolutionNotes string
-	ResolvedAt      *time.Time `gorm:"index"`
-	CreatedBy       string     `gorm:"index"`
-	ResolvedBy      string     `gorm:"index"`
+	// SubstituteDrugCode is the vmedis code of the drug sold instead, if any.
+	SubstituteDrugCode string     `gorm:"index"`
+	ResolvedAt         *time.Time `gorm:"index"`
+	CreatedBy          string     `gorm:"index"`
+	ResolvedBy         string     `gorm:"index"`
 }
 
 type RejectedDrugResolution string
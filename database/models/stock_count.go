@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// StockCountSession is a counting session of the physical stocks, recorded before the counts are entered into Vmedis.
+type StockCountSession struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	Date      datatypes.Date `gorm:"index"`
+	Name      string
+	CreatedBy string
+
+	// ApprovedAt is the time a supervisor approved the counts, nil while the session is still being counted.
+	ApprovedAt *time.Time `gorm:"index"`
+	ApprovedBy string
+
+	Lines []StockCountLine `gorm:"foreignKey:SessionID"`
+}
+
+// StockCountLine is the counted quantity of a batch of a drug in one of its units.
+type StockCountLine struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	SessionID uint   `gorm:"uniqueIndex:idx_stock_count_line"`
+	DrugCode  string `gorm:"index;uniqueIndex:idx_stock_count_line"`
+	DrugName  string
+	BatchCode string `gorm:"uniqueIndex:idx_stock_count_line"`
+	Unit      string `gorm:"uniqueIndex:idx_stock_count_line"`
+	Quantity  float64
+	CountedBy string
+}
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"gorm.io/datatypes"
+	"gorm.io/gorm"
 )
 
 // StockOpname represents a stock opname.
@@ -11,7 +12,10 @@ type StockOpname struct {
 	ID        uint      `gorm:"primarykey"`
 	CreatedAt time.Time `gorm:"index"`
 	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
 
+	// VmedisID stays unique across soft-deleted stock opnames too, so re-dumping a
+	// stock opname that was soft-deleted here revives it.
 	VmedisID            string         `gorm:"unique"`
 	Date                datatypes.Date `gorm:"index:idx_so_date_drug_code"`
 	DrugCode            string         `gorm:"index:idx_so_date_drug_code;index:idx_so_drug_code"`
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// SupplierLeadTime is the number of days a supplier takes to deliver an order.
+type SupplierLeadTime struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	Supplier string  `gorm:"unique;not null"`
+	Days     float64 `gorm:"not null"`
+}
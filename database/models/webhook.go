@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+// WebhookSubscription is a URL that receives the webhook events of the subscribed types.
+type WebhookSubscription struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	URL string
+
+	// Secret is the key the event payloads sent to the URL are signed with.
+	Secret     string
+	EventTypes []string `gorm:"serializer:json"`
+	Active     bool     `gorm:"index"`
+	CreatedBy  string
+}
+
+// WebhookDeliveryStatus is the status of a webhook delivery.
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryStatusPending   WebhookDeliveryStatus = "PENDING"
+	WebhookDeliveryStatusSucceeded WebhookDeliveryStatus = "SUCCEEDED"
+	WebhookDeliveryStatusFailed    WebhookDeliveryStatus = "FAILED"
+)
+
+// WebhookDelivery is the delivery of an event to a webhook subscription, and the log of its attempts.
+type WebhookDelivery struct {
+	ID        uint      `gorm:"primarykey"`
+	CreatedAt time.Time `gorm:"index"`
+	UpdatedAt time.Time
+
+	SubscriptionID uint   `gorm:"uniqueIndex:idx_webhook_delivery_subscription_event"`
+	EventID        string `gorm:"uniqueIndex:idx_webhook_delivery_subscription_event"`
+	EventType      string
+	Payload        []byte
+
+	Status WebhookDeliveryStatus `gorm:"index"`
+
+	// NextAttemptAt is the earliest time the pending delivery may be attempted,
+	// pushed back while a dispatcher is sending it and after every failed attempt.
+	NextAttemptAt time.Time `gorm:"index"`
+	ClaimedBy     string
+
+	Attempts       int
+	LastStatusCode int
+	LastError      string
+	DeliveredAt    *time.Time
+}
@@ -0,0 +1,362 @@
+package drug
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+	"github.com/turfaa/vmedis-proxy-api/pkg2/csv2"
+	"github.com/turfaa/vmedis-proxy-api/pkg2/slices2"
+)
+
+var (
+	// ErrInvalidBarcode is returned when a barcode is not a valid EAN-8, UPC-A, EAN-13, or GTIN-14.
+	ErrInvalidBarcode = errors.New("invalid barcode")
+
+	// ErrDuplicateBarcode is returned when a barcode is already registered to a drug.
+	ErrDuplicateBarcode = errors.New("duplicate barcode")
+
+	// ErrUnknownDrugUnit is returned when a barcode refers to a unit the drug doesn't have.
+	ErrUnknownDrugUnit = errors.New("unknown drug unit")
+)
+
+// barcodeFieldAliases lists the accepted CSV column names of each barcode field.
+var barcodeFieldAliases = map[string][]string{
+	"barcode":   {"barcode", "kode_barcode", "ean", "gtin"},
+	"drug_code": {"drug_code", "vmedis_code", "kode_obat", "kode"},
+	"unit":      {"unit", "satuan"},
+}
+
+// CanonicalBarcode validates the given EAN-8, UPC-A, EAN-13, or GTIN-14 barcode and returns its canonical form,
+// so that the same package scanned by different scanners is found:
+// UPC-A barcodes are prefixed with a zero and GTIN-14 barcodes with a zero indicator are trimmed to EAN-13.
+func CanonicalBarcode(barcode string) (string, error) {
+	barcode = strings.TrimSpace(barcode)
+
+	switch len(barcode) {
+	case 8, 12, 13, 14:
+	default:
+		return "", fmt.Errorf("%w: %q must have 8, 12, 13, or 14 digits", ErrInvalidBarcode, barcode)
+	}
+
+	sum := 0
+	for i := len(barcode) - 1; i >= 0; i-- {
+		digit := int(barcode[i] - '0')
+		if digit < 0 || digit > 9 {
+			return "", fmt.Errorf("%w: %q must only contain digits", ErrInvalidBarcode, barcode)
+		}
+
+		// The check digit is the last digit, and the weights alternate between 3 and 1 from the right of it.
+		if i == len(barcode)-1 {
+			continue
+		}
+
+		if (len(barcode)-1-i)%2 == 1 {
+			sum += 3 * digit
+		} else {
+			sum += digit
+		}
+	}
+
+	if checkDigit := (10 - sum%10) % 10; int(barcode[len(barcode)-1]-'0') != checkDigit {
+		return "", fmt.Errorf("%w: %q has a wrong check digit, expected %d", ErrInvalidBarcode, barcode, checkDigit)
+	}
+
+	switch {
+	case len(barcode) == 12:
+		return "0" + barcode, nil
+	case len(barcode) == 14 && barcode[0] == '0':
+		return barcode[1:], nil
+	default:
+		return barcode, nil
+	}
+}
+
+func (s *Service) GetBarcodes(ctx context.Context, drugVmedisCode string) ([]Barcode, error) {
+	barcodes, err := s.db.GetBarcodes(ctx, drugVmedisCode)
+	if err != nil {
+		return nil, fmt.Errorf("get barcodes: %w", err)
+	}
+
+	return slices2.Map(barcodes, FromDBBarcode), nil
+}
+
+func (s *Service) GetBarcodeByID(ctx context.Context, id uint) (Barcode, error) {
+	barcode, err := s.db.GetBarcodeByID(ctx, id)
+	if err != nil {
+		return Barcode{}, fmt.Errorf("get barcode %d: %w", id, err)
+	}
+
+	return FromDBBarcode(barcode), nil
+}
+
+// GetDrugByBarcode returns the drug with the given barcode and the unit the barcode is printed on.
+// gorm.ErrRecordNotFound is returned if the barcode is not registered.
+func (s *Service) GetDrugByBarcode(ctx context.Context, code string) (Drug, string, error) {
+	canonical, err := CanonicalBarcode(code)
+	if err != nil {
+		// Invalid barcodes are never registered.
+		return Drug{}, "", fmt.Errorf("%w: %w", gorm.ErrRecordNotFound, err)
+	}
+
+	barcode, err := s.db.GetBarcodeByCode(ctx, canonical)
+	if err != nil {
+		return Drug{}, "", fmt.Errorf("get barcode %s: %w", canonical, err)
+	}
+
+	drugs, err := s.GetDrugsByVmedisCodes(ctx, []string{barcode.DrugVmedisCode})
+	if err != nil {
+		return Drug{}, "", fmt.Errorf("get drug %s: %w", barcode.DrugVmedisCode, err)
+	}
+
+	if len(drugs) == 0 {
+		return Drug{}, "", fmt.Errorf("get drug %s: %w", barcode.DrugVmedisCode, gorm.ErrRecordNotFound)
+	}
+
+	return drugs[0], barcode.Unit, nil
+}
+
+func (s *Service) CreateBarcode(ctx context.Context, request CreateBarcodeRequest) (Barcode, error) {
+	barcode, err := s.validateBarcode(ctx, 0, request.Barcode, strings.TrimSpace(request.DrugCode), request.Unit)
+	if err != nil {
+		return Barcode{}, err
+	}
+
+	created, err := s.db.CreateBarcode(ctx, barcode)
+	if err != nil {
+		return Barcode{}, fmt.Errorf("create barcode: %w", err)
+	}
+
+	return FromDBBarcode(created), nil
+}
+
+func (s *Service) UpdateBarcode(ctx context.Context, id uint, request UpdateBarcodeRequest) (Barcode, error) {
+	barcode, err := s.db.GetBarcodeByID(ctx, id)
+	if err != nil {
+		return Barcode{}, fmt.Errorf("get barcode %d: %w", id, err)
+	}
+
+	code, drugCode, unit := barcode.Barcode, barcode.DrugVmedisCode, barcode.Unit
+	if request.Barcode != nil {
+		code = *request.Barcode
+	}
+
+	if request.DrugCode != nil {
+		drugCode = strings.TrimSpace(*request.DrugCode)
+	}
+
+	if request.Unit != nil {
+		unit = *request.Unit
+	}
+
+	validated, err := s.validateBarcode(ctx, id, code, drugCode, unit)
+	if err != nil {
+		return Barcode{}, err
+	}
+
+	barcode.Barcode = validated.Barcode
+	barcode.DrugVmedisCode = validated.DrugVmedisCode
+	barcode.Unit = validated.Unit
+
+	if err := s.db.UpdateBarcode(ctx, barcode); err != nil {
+		return Barcode{}, fmt.Errorf("update barcode %d: %w", id, err)
+	}
+
+	return FromDBBarcode(barcode), nil
+}
+
+func (s *Service) DeleteBarcode(ctx context.Context, id uint) error {
+	if err := s.db.DeleteBarcode(ctx, id); err != nil {
+		return fmt.Errorf("delete barcode %d: %w", id, err)
+	}
+
+	return nil
+}
+
+// ImportBarcodes imports barcodes from CSV with a header row.
+// The barcode and drug code columns are required, the unit column is optional.
+// Invalid rows are skipped and reported in the result, and barcodes that are
+// already registered are moved to the drug and unit of the imported row.
+func (s *Service) ImportBarcodes(ctx context.Context, r io.Reader) (ImportBarcodesResult, error) {
+	rows, err := parseCSVBarcodes(r)
+	if err != nil {
+		return ImportBarcodesResult{}, fmt.Errorf("parse CSV barcodes: %w", err)
+	}
+
+	drugCodes := slices.Compact(slices.Sorted(slices.Values(slices2.Map(rows, func(row barcodeRow) string {
+		return row.drugCode
+	}))))
+
+	existingDrugCodes, err := s.db.GetExistingDrugVmedisCodes(ctx, drugCodes)
+	if err != nil {
+		return ImportBarcodesResult{}, fmt.Errorf("get existing drugs: %w", err)
+	}
+
+	unitNames, err := s.db.GetDrugUnitNames(ctx, drugCodes)
+	if err != nil {
+		return ImportBarcodesResult{}, fmt.Errorf("get drug units: %w", err)
+	}
+
+	var (
+		result   = ImportBarcodesResult{Errors: []string{}}
+		barcodes []models.DrugBarcode
+		indexOf  = make(map[string]int)
+	)
+	for _, row := range rows {
+		barcode, err := newBarcode(row.barcode, row.drugCode, row.unit, existingDrugCodes, unitNames)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("baris %d: %s", row.line, err))
+			continue
+		}
+
+		// The last row of a duplicated barcode wins.
+		if i, ok := indexOf[barcode.Barcode]; ok {
+			barcodes[i] = barcode
+			continue
+		}
+
+		indexOf[barcode.Barcode] = len(barcodes)
+		barcodes = append(barcodes, barcode)
+	}
+
+	if err := s.db.UpsertBarcodes(ctx, barcodes); err != nil {
+		return ImportBarcodesResult{}, fmt.Errorf("upsert barcodes: %w", err)
+	}
+
+	result.Imported = len(barcodes)
+	return result, nil
+}
+
+// validateBarcode validates the barcode fields and returns them as a database model.
+// id is the ID of the barcode being updated, or zero when creating one.
+func (s *Service) validateBarcode(ctx context.Context, id uint, code, drugCode, unit string) (models.DrugBarcode, error) {
+	existingDrugCodes, err := s.db.GetExistingDrugVmedisCodes(ctx, []string{drugCode})
+	if err != nil {
+		return models.DrugBarcode{}, fmt.Errorf("get existing drugs: %w", err)
+	}
+
+	unitNames, err := s.db.GetDrugUnitNames(ctx, []string{drugCode})
+	if err != nil {
+		return models.DrugBarcode{}, fmt.Errorf("get drug units: %w", err)
+	}
+
+	barcode, err := newBarcode(code, drugCode, unit, existingDrugCodes, unitNames)
+	if err != nil {
+		return models.DrugBarcode{}, err
+	}
+
+	registered, err := s.db.GetBarcodeByCode(ctx, barcode.Barcode)
+	if err == nil && registered.ID != id {
+		return models.DrugBarcode{}, fmt.Errorf("%w: %s is already registered to drug %s", ErrDuplicateBarcode, barcode.Barcode, registered.DrugVmedisCode)
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.DrugBarcode{}, fmt.Errorf("get barcode %s: %w", barcode.Barcode, err)
+	}
+
+	return barcode, nil
+}
+
+func newBarcode(code, drugCode, unit string, existingDrugCodes []string, unitNames map[string][]string) (models.DrugBarcode, error) {
+	canonical, err := CanonicalBarcode(code)
+	if err != nil {
+		return models.DrugBarcode{}, err
+	}
+
+	if !slices.Contains(existingDrugCodes, drugCode) {
+		return models.DrugBarcode{}, fmt.Errorf("%w: %s", ErrUnknownDrugCodes, drugCode)
+	}
+
+	unit = strings.TrimSpace(unit)
+	if unit != "" {
+		i := slices.IndexFunc(unitNames[drugCode], func(name string) bool {
+			return strings.EqualFold(name, unit)
+		})
+		if i < 0 {
+			return models.DrugBarcode{}, fmt.Errorf(
+				"%w: %s is not a unit of drug %s, expected one of [%s]",
+				ErrUnknownDrugUnit, unit, drugCode, strings.Join(unitNames[drugCode], ", "),
+			)
+		}
+
+		// Use the spelling of the drug unit.
+		unit = unitNames[drugCode][i]
+	}
+
+	return models.DrugBarcode{
+		Barcode:        canonical,
+		DrugVmedisCode: drugCode,
+		Unit:           unit,
+	}, nil
+}
+
+type barcodeRow struct {
+	line     int
+	barcode  string
+	drugCode string
+	unit     string
+}
+
+func parseCSVBarcodes(r io.Reader) ([]barcodeRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+
+	fieldIndex := csv2.FieldIndex(header, barcodeFieldAliases)
+
+	if _, ok := fieldIndex["barcode"]; !ok {
+		return nil, fmt.Errorf("barcode column not found in header %v", header)
+	}
+	if _, ok := fieldIndex["drug_code"]; !ok {
+		return nil, fmt.Errorf("drug code column not found in header %v", header)
+	}
+
+	var rows []barcodeRow
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read record: %w", err)
+		}
+
+		line, _ := reader.FieldPos(0)
+
+		get := func(field string) string {
+			i, ok := fieldIndex[field]
+			if !ok || i >= len(record) {
+				return ""
+			}
+
+			return strings.TrimSpace(record[i])
+		}
+
+		row := barcodeRow{
+			line:     line,
+			barcode:  get("barcode"),
+			drugCode: get("drug_code"),
+			unit:     get("unit"),
+		}
+
+		// Skip empty lines, e.g. the trailing ones of a spreadsheet export.
+		if row.barcode == "" && row.drugCode == "" {
+			continue
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
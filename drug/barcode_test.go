@@ -0,0 +1,167 @@
+package drug_test
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/turfaa/vmedis-proxy-api/auth"
+	"github.com/turfaa/vmedis-proxy-api/cui"
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+	"github.com/turfaa/vmedis-proxy-api/drug"
+)
+
+// TestBarcodeJourney registers barcodes one by one and in bulk like an admin
+// would, then scans them like the cashier does.
+func TestBarcodeJourney(t *testing.T) {
+	db, _, router := setup(t)
+
+	mustCreate(t, db, &models.Drug{VmedisID: 1, VmedisCode: "SANMOL", Name: "SANMOL 500MG"})
+	mustCreate(t, db, &models.DrugUnit{DrugVmedisCode: "SANMOL", Unit: "Strip", PriceOne: 5000, PriceTwo: 4500, PriceThree: 6000})
+	mustCreate(t, db, &models.DrugUnit{DrugVmedisCode: "SANMOL", Unit: "Box", ParentUnit: "Strip", ConversionToParentUnit: 10, UnitOrder: 1, PriceOne: 48000})
+	mustCreate(t, db, &models.Drug{VmedisID: 2, VmedisCode: "BODREX", Name: "BODREX"})
+	mustCreate(t, db, &models.DrugUnit{DrugVmedisCode: "BODREX", Unit: "Strip", PriceOne: 4000})
+
+	do := func(role auth.Role, method, path, body string) (int, string) {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("X-Role", string(role))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code, w.Body.String()
+	}
+
+	code, body := do(auth.RoleAdmin, "POST", "/drugs/barcodes", `{"barcode": "8992761111114", "drugCode": "SANMOL"}`)
+	if code != 400 {
+		t.Fatalf("create with wrong check digit: got code %d, body %s", code, body)
+	}
+
+	code, body = do(auth.RoleAdmin, "POST", "/drugs/barcodes", `{"barcode": "8992761111113", "drugCode": "SANMOL", "unit": "Tube"}`)
+	if code != 400 {
+		t.Fatalf("create with unknown unit: got code %d, body %s", code, body)
+	}
+
+	code, body = do(auth.RoleAdmin, "POST", "/drugs/barcodes", `{"barcode": "8992761111113", "drugCode": "SANMOL", "unit": "strip"}`)
+	if code != 201 {
+		t.Fatalf("create: got code %d, body %s", code, body)
+	}
+	created := unmarshal[drug.BarcodeResponse](t, body)
+	if created.Barcode.Unit != "Strip" {
+		t.Fatalf("create: expected the unit to be spelled like the drug unit, got %s", body)
+	}
+
+	code, body = do(auth.RoleAdmin, "POST", "/drugs/barcodes", `{"barcode": "8992761111113", "drugCode": "BODREX"}`)
+	if code != 400 {
+		t.Fatalf("create duplicate: got code %d, body %s", code, body)
+	}
+
+	code, body = do(auth.RoleGuest, "GET", "/drugs/by-barcode/8992761111113", "")
+	if code != 200 {
+		t.Fatalf("scan: got code %d, body %s", code, body)
+	}
+	scanned := unmarshal[drug.DrugByBarcodeResponse](t, body)
+	if scanned.Drug.VmedisCode != "SANMOL" || scanned.Unit != "Strip" {
+		t.Fatalf("scan: expected SANMOL per strip, got %s", body)
+	}
+	if strings.Contains(body, "Harga Diskon") || !strings.Contains(body, "Harga Normal") {
+		t.Fatalf("scan: guest should only see the normal price: %s", body)
+	}
+
+	code, body = do(auth.RoleGuest, "GET", "/drugs/by-barcode/12345678", "")
+	if code != 404 {
+		t.Fatalf("scan unknown barcode: got code %d, body %s", code, body)
+	}
+
+	code, body = do(auth.RoleAdmin, "POST", "/drugs/barcodes/import", `{"csv": "Barcode,Kode Obat,Satuan\n012345678905,SANMOL,Box\n12345670,UNKNOWN,\n8993001001232,BODREX,\n8993001001233,BODREX,\n8992761111113,BODREX,Strip\n"}`)
+	if code != 200 {
+		t.Fatalf("import: got code %d, body %s", code, body)
+	}
+	imported := unmarshal[drug.ImportBarcodesResult](t, body)
+	if imported.Imported != 3 || len(imported.Errors) != 2 {
+		t.Fatalf("import: expected 3 imported and 2 skipped rows, got %s", body)
+	}
+	if !strings.HasPrefix(imported.Errors[0], "baris 3:") {
+		t.Fatalf("import: expected the errors to point to the CSV line, got %s", body)
+	}
+
+	// A UPC-A barcode is found when scanned as EAN-13 or GTIN-14.
+	code, body = do(auth.RoleStaff, "GET", "/drugs/by-barcode/00012345678905", "")
+	if code != 200 {
+		t.Fatalf("scan GTIN-14: got code %d, body %s", code, body)
+	}
+	scanned = unmarshal[drug.DrugByBarcodeResponse](t, body)
+	if scanned.Drug.VmedisCode != "SANMOL" || scanned.Unit != "Box" || !strings.Contains(body, "Harga Resep") {
+		t.Fatalf("scan GTIN-14: expected SANMOL per box with the staff sections, got %s", body)
+	}
+
+	// The import moved the first barcode to BODREX.
+	code, body = do(auth.RoleGuest, "GET", "/drugs/by-barcode/8992761111113", "")
+	if code != 200 || unmarshal[drug.DrugByBarcodeResponse](t, body).Drug.VmedisCode != "BODREX" {
+		t.Fatalf("scan moved barcode: got code %d, body %s", code, body)
+	}
+
+	code, body = do(auth.RoleStaff, "GET", "/drugs/barcodes?drug_code=BODREX", "")
+	if code != 200 {
+		t.Fatalf("list: got code %d, body %s", code, body)
+	}
+	list := unmarshal[cui.Table](t, body)
+	if len(list.Rows) != 2 || list.Rows[0].Columns[1] != "BODREX" {
+		t.Fatalf("list: expected the 2 barcodes of BODREX, got %s", body)
+	}
+
+	id := list.Rows[0].ID
+	code, body = do(auth.RoleAdmin, "GET", "/drugs/barcodes/"+id+"/form", "")
+	if code != 200 {
+		t.Fatalf("update form: got code %d, body %s", code, body)
+	}
+	form := unmarshal[cui.Form](t, body)
+	if form.Fields[0].Value != list.Rows[0].Columns[0] || form.Fields[1].Value != "BODREX" {
+		t.Fatalf("update form: not prefilled: %s", body)
+	}
+
+	code, body = do(auth.RoleAdmin, "PATCH", "/drugs/barcodes/"+id, `{"drugCode": "SANMOL", "unit": "Box"}`)
+	if code != 200 {
+		t.Fatalf("update: got code %d, body %s", code, body)
+	}
+
+	code, body = do(auth.RoleAdmin, "DELETE", "/drugs/barcodes/"+id, "")
+	if code != 200 {
+		t.Fatalf("delete: got code %d, body %s", code, body)
+	}
+
+	code, body = do(auth.RoleAdmin, "GET", "/drugs/barcodes/"+id, "")
+	if code != 404 {
+		t.Fatalf("detail after delete: got code %d, body %s", code, body)
+	}
+}
+
+func TestCanonicalBarcode(t *testing.T) {
+	tests := []struct {
+		barcode string
+		want    string
+		wantErr bool
+	}{
+		{barcode: "8992761111113", want: "8992761111113"},
+		{barcode: " 12345670 ", want: "12345670"},
+		{barcode: "012345678905", want: "0012345678905"},
+		{barcode: "00012345678905", want: "0012345678905"},
+		{barcode: "10012345678902", want: "10012345678902"},
+		{barcode: "8992761111114", wantErr: true},
+		{barcode: "899276111111A", wantErr: true},
+		{barcode: "123", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := drug.CanonicalBarcode(tt.barcode)
+		if tt.wantErr {
+			if !errors.Is(err, drug.ErrInvalidBarcode) {
+				t.Errorf("CanonicalBarcode(%q): got error %v, want ErrInvalidBarcode", tt.barcode, err)
+			}
+			continue
+		}
+
+		if err != nil || got != tt.want {
+			t.Errorf("CanonicalBarcode(%q) = %q, %v, want %q", tt.barcode, got, err, tt.want)
+		}
+	}
+}
@@ -1,8 +1,11 @@
 package drug
 
 import (
+	"cmp"
 	"context"
+	"errors"
 	"fmt"
+	"slices"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -10,10 +13,15 @@ import (
 )
 
 const (
-	drugsKey                            = "drug:drugs"
+	drugsByCodeKey                      = "drug:drugs-by-code"
+	drugsByCodeCompleteKey              = "drug:drugs-by-code:complete"
+	drugsVersionKey                     = "drug:drugs-version"
 	drugDetailsByVmedisCodeProcessedKey = "drug:drug-details-by-vmedis-code-processed:%s"
 	drugDetailsByVmedisIDProcessedKey   = "drug-details-by-vmedis-id-processed:%s"
 
+	// drugsByCodeExpiry bounds how long the per-drug cache lives without a full reload from DB,
+	// in case an update is missed.
+	drugsByCodeExpiry   = 24 * time.Hour
 	processedKeysExpiry = 30 * 24 * time.Hour
 )
 
@@ -21,28 +29,119 @@ type Cache struct {
 	redis redis.UniversalClient
 }
 
+// GetDrugs returns all the cached drugs sorted by name.
+// It returns redis.Nil if the cache hasn't been completely loaded.
 func (c *Cache) GetDrugs(ctx context.Context) ([]Drug, error) {
-	res, err := c.redis.Get(ctx, drugsKey).Result()
+	complete, err := c.redis.Exists(ctx, drugsByCodeCompleteKey).Result()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get %s in redis: %w", drugsKey, err)
+		return nil, fmt.Errorf("failed to check if %s exists in redis: %w", drugsByCodeCompleteKey, err)
 	}
 
-	var drugs []Drug
-	if err := msgpack.Unmarshal([]byte(res), &drugs); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal %s in redis: %w", drugsKey, err)
+	if complete == 0 {
+		return nil, fmt.Errorf("%s doesn't exist in redis: %w", drugsByCodeCompleteKey, redis.Nil)
 	}
 
+	res, err := c.redis.HVals(ctx, drugsByCodeKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s in redis: %w", drugsByCodeKey, err)
+	}
+
+	drugs := make([]Drug, len(res))
+	for i, r := range res {
+		if err := msgpack.Unmarshal([]byte(r), &drugs[i]); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal %s in redis: %w", drugsByCodeKey, err)
+		}
+	}
+
+	slices.SortFunc(drugs, func(a, b Drug) int {
+		return cmp.Or(cmp.Compare(a.Name, b.Name), cmp.Compare(a.VmedisCode, b.VmedisCode))
+	})
+
 	return drugs, nil
 }
 
-func (c *Cache) SetDrugs(ctx context.Context, drugs []Drug, ttl time.Duration) error {
-	bytes, err := msgpack.Marshal(drugs)
+// SetDrugs loads all the drugs to the cache and marks it complete.
+// Drugs already cached are kept, since they were set by more recent updates.
+func (c *Cache) SetDrugs(ctx context.Context, drugs []Drug) error {
+	values := make(map[string][]byte, len(drugs))
+	for _, drug := range drugs {
+		bytes, err := msgpack.Marshal(drug)
+		if err != nil {
+			return fmt.Errorf("failed to marshal drug %s: %w", drug.VmedisCode, err)
+		}
+
+		values[drug.VmedisCode] = bytes
+	}
+
+	if _, err := c.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for code, bytes := range values {
+			pipe.HSetNX(ctx, drugsByCodeKey, code, bytes)
+		}
+
+		pipe.Expire(ctx, drugsByCodeKey, drugsByCodeExpiry)
+		pipe.Set(ctx, drugsByCodeCompleteKey, time.Now(), drugsByCodeExpiry)
+		return nil
+	}); err != nil {
+		return fmt.Errorf("failed to set %s in redis: %w", drugsByCodeKey, err)
+	}
+
+	return nil
+}
+
+// SetDrug caches the drug, and returns whether it's different from the cached one.
+func (c *Cache) SetDrug(ctx context.Context, drug Drug) (bool, error) {
+	bytes, err := msgpack.Marshal(drug)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal drug %s: %w", drug.VmedisCode, err)
+	}
+
+	cached, err := c.redis.HGet(ctx, drugsByCodeKey, drug.VmedisCode).Bytes()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return false, fmt.Errorf("failed to get %s of %s in redis: %w", drug.VmedisCode, drugsByCodeKey, err)
+	}
+
+	if err == nil && string(cached) == string(bytes) {
+		return false, nil
+	}
+
+	if err := c.redis.HSet(ctx, drugsByCodeKey, drug.VmedisCode, bytes).Err(); err != nil {
+		return false, fmt.Errorf("failed to set %s of %s in redis: %w", drug.VmedisCode, drugsByCodeKey, err)
+	}
+
+	return true, nil
+}
+
+// DeleteDrugs removes the drugs from the cache.
+func (c *Cache) DeleteDrugs(ctx context.Context, vmedisCodes []string) error {
+	if len(vmedisCodes) == 0 {
+		return nil
+	}
+
+	if err := c.redis.HDel(ctx, drugsByCodeKey, vmedisCodes...).Err(); err != nil {
+		return fmt.Errorf("failed to delete %v of %s in redis: %w", vmedisCodes, drugsByCodeKey, err)
+	}
+
+	return nil
+}
+
+// GetDrugsVersion returns the version of the drugs, which changes every time any drug changes.
+func (c *Cache) GetDrugsVersion(ctx context.Context) (int64, error) {
+	version, err := c.redis.Get(ctx, drugsVersionKey).Int64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+
 	if err != nil {
-		return fmt.Errorf("failed to marshal drugs: %w", err)
+		return 0, fmt.Errorf("failed to get %s in redis: %w", drugsVersionKey, err)
 	}
 
-	if err := c.redis.Set(ctx, drugsKey, bytes, ttl).Err(); err != nil {
-		return fmt.Errorf("failed to set %s in redis: %w", drugsKey, err)
+	return version, nil
+}
+
+// BumpDrugsVersion changes the version of the drugs, invalidating the responses cached by the version.
+func (c *Cache) BumpDrugsVersion(ctx context.Context) error {
+	if err := c.redis.Incr(ctx, drugsVersionKey).Err(); err != nil {
+		return fmt.Errorf("failed to increment %s in redis: %w", drugsVersionKey, err)
 	}
 
 	return nil
@@ -0,0 +1,143 @@
+package drug_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+	"github.com/turfaa/vmedis-proxy-api/drug"
+	vmedisv1 "github.com/turfaa/vmedis-proxy-api/vmedis/v1"
+)
+
+// TestCache checks that the drugs are only read from the cache once it's completely loaded,
+// that loading it doesn't overwrite the drugs set by more recent updates, and that the drugs version is bumped.
+func TestCache(t *testing.T) {
+	ctx := context.Background()
+	cache := drug.NewCache(newRedis(t))
+
+	if _, err := cache.GetDrugs(ctx); !errors.Is(err, redis.Nil) {
+		t.Fatalf("GetDrugs() of an unloaded cache error = %v, want redis.Nil", err)
+	}
+
+	bodrex := drug.Drug{VmedisCode: "BODREX", Name: "BODREX", Stocks: []drug.Stock{{Unit: "Strip", Quantity: 3}}}
+	if changed, err := cache.SetDrug(ctx, bodrex); err != nil || !changed {
+		t.Fatalf("SetDrug() = %t, %v, want changed", changed, err)
+	}
+	if changed, err := cache.SetDrug(ctx, bodrex); err != nil || changed {
+		t.Fatalf("SetDrug() again = %t, %v, want unchanged", changed, err)
+	}
+
+	if err := cache.SetDrugs(ctx, []drug.Drug{
+		{VmedisCode: "SANMOL", Name: "SANMOL 500MG"},
+		{VmedisCode: "BODREX", Name: "BODREX"},
+	}); err != nil {
+		t.Fatalf("SetDrugs() error = %v", err)
+	}
+
+	drugs, err := cache.GetDrugs(ctx)
+	if err != nil {
+		t.Fatalf("GetDrugs() error = %v", err)
+	}
+	if len(drugs) != 2 || drugs[0].VmedisCode != "BODREX" || len(drugs[0].Stocks) != 1 || drugs[1].VmedisCode != "SANMOL" {
+		t.Fatalf("GetDrugs() = %+v, want BODREX with its stocks and SANMOL", drugs)
+	}
+
+	if err := cache.DeleteDrugs(ctx, []string{"BODREX"}); err != nil {
+		t.Fatalf("DeleteDrugs() error = %v", err)
+	}
+	if drugs, err := cache.GetDrugs(ctx); err != nil || len(drugs) != 1 || drugs[0].VmedisCode != "SANMOL" {
+		t.Fatalf("GetDrugs() after DeleteDrugs() = %+v, %v, want SANMOL", drugs, err)
+	}
+
+	if version, err := cache.GetDrugsVersion(ctx); err != nil || version != 0 {
+		t.Fatalf("GetDrugsVersion() = %d, %v, want 0", version, err)
+	}
+	if err := cache.BumpDrugsVersion(ctx); err != nil {
+		t.Fatalf("BumpDrugsVersion() error = %v", err)
+	}
+	if version, err := cache.GetDrugsVersion(ctx); err != nil || version != 1 {
+		t.Fatalf("GetDrugsVersion() after BumpDrugsVersion() = %d, %v, want 1", version, err)
+	}
+}
+
+// TestRefreshDrugBumpsDrugsVersion checks that the drugs version is bumped every time a drug changes,
+// including when it's changed outside of the Vmedis dumps or removed, but not when it stays the same.
+func TestRefreshDrugBumpsDrugsVersion(t *testing.T) {
+	db, _, _ := setup(t)
+	ctx := context.Background()
+
+	redisClient := newRedis(t)
+	service := drug.NewService(redisClient, db, nil, nil)
+	cache := drug.NewCache(redisClient)
+
+	if err := drug.NewDatabase(db).UpsertVmedisDrugs(ctx, []vmedisv1.Drug{
+		{VmedisID: 1, VmedisCode: "SANMOL", Name: "SANMOL 500MG"},
+	}, "vmedis_code", []string{"vmedis_id", "name", "manufacturer"}); err != nil {
+		t.Fatalf("upsert drugs: %s", err)
+	}
+
+	// Marks the cache as completely loaded, so the drugs are read from it.
+	if err := cache.SetDrugs(ctx, nil); err != nil {
+		t.Fatalf("SetDrugs() error = %v", err)
+	}
+
+	version := func() int64 {
+		t.Helper()
+
+		version, err := service.GetDrugsVersion(ctx)
+		if err != nil {
+			t.Fatalf("GetDrugsVersion() error = %v", err)
+		}
+
+		return version
+	}
+
+	before := version()
+	if err := service.RefreshDrug(ctx, "SANMOL"); err != nil {
+		t.Fatalf("RefreshDrug() error = %v", err)
+	}
+	if after := version(); after <= before {
+		t.Fatalf("drugs version after refreshing a new drug = %d, want more than %d", after, before)
+	}
+
+	before = version()
+	if err := service.RefreshDrug(ctx, "SANMOL"); err != nil {
+		t.Fatalf("RefreshDrug() again error = %v", err)
+	}
+	if after := version(); after != before {
+		t.Fatalf("drugs version after refreshing an unchanged drug = %d, want %d", after, before)
+	}
+
+	// Like accepting a KFA match.
+	if err := db.Model(&models.Drug{}).Where("vmedis_code = ?", "SANMOL").Update("kfa_code", "93000001").Error; err != nil {
+		t.Fatalf("set kfa code: %s", err)
+	}
+	before = version()
+	if err := service.RefreshDrug(ctx, "SANMOL"); err != nil {
+		t.Fatalf("RefreshDrug() after setting the KFA code error = %v", err)
+	}
+	if after := version(); after <= before {
+		t.Fatalf("drugs version after setting the KFA code = %d, want more than %d", after, before)
+	}
+	if drugs, err := cache.GetDrugs(ctx); err != nil || len(drugs) != 1 || drugs[0].KFACode != "93000001" {
+		t.Fatalf("cached drugs = %+v, %v, want SANMOL with its KFA code", drugs, err)
+	}
+
+	if err := db.Model(&models.Drug{}).Where("vmedis_code = ?", "SANMOL").Update("removed_at", time.Now()).Error; err != nil {
+		t.Fatalf("remove drug: %s", err)
+	}
+	before = version()
+	if err := service.RefreshCachedDrugByVmedisCode(ctx, "SANMOL"); err != nil {
+		t.Fatalf("RefreshCachedDrugByVmedisCode() of a removed drug error = %v", err)
+	}
+	if after := version(); after <= before {
+		t.Fatalf("drugs version after removing a drug = %d, want more than %d", after, before)
+	}
+	if drugs, err := cache.GetDrugs(ctx); err != nil || len(drugs) != 0 {
+		t.Fatalf("cached drugs after removing a drug = %+v, %v, want none", drugs, err)
+	}
+}
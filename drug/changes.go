@@ -0,0 +1,119 @@
+package drug
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+)
+
+const (
+	drugChangesCursorPrefix = "v1:"
+
+	// drugChangesCursorLag moves the returned cursor back, so changes written concurrently with
+	// the call are returned again by the next call instead of being missed.
+	drugChangesCursorLag = time.Minute
+)
+
+// ErrInvalidCursor is returned when the drug changes cursor can't be parsed.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// GetDrugChanges returns the drugs whose metadata, units, or stocks changed since the given cursor,
+// the drugs removed from Vmedis since the cursor, and the cursor of the next call.
+// Empty cursor returns every existing drug without removed drugs.
+func (s *Service) GetDrugChanges(ctx context.Context, cursor string) (DrugChanges, error) {
+	since, err := parseDrugChangesCursor(cursor)
+	if err != nil {
+		return DrugChanges{}, err
+	}
+
+	now := time.Now()
+
+	var dbDrugs []models.Drug
+	if since.IsZero() {
+		dbDrugs, err = s.db.GetExistingDrugs(ctx)
+	} else {
+		dbDrugs, err = s.db.GetDrugsChangedAfter(ctx, since)
+	}
+	if err != nil {
+		return DrugChanges{}, fmt.Errorf("get changed drugs: %w", err)
+	}
+
+	changes := DrugChanges{
+		Drugs:        make([]Drug, 0, len(dbDrugs)),
+		RemovedDrugs: []RemovedDrug{},
+		Cursor:       formatDrugChangesCursor(now.Add(-drugChangesCursorLag)),
+	}
+
+	for _, drug := range dbDrugs {
+		if drug.RemovedAt != nil {
+			changes.RemovedDrugs = append(changes.RemovedDrugs, RemovedDrug{
+				VmedisCode: drug.VmedisCode,
+				RemovedAt:  *drug.RemovedAt,
+			})
+			continue
+		}
+
+		changes.Drugs = append(changes.Drugs, FromDBDrug(drug))
+	}
+
+	return changes, nil
+}
+
+func parseDrugChangesCursor(cursor string) (time.Time, error) {
+	if cursor == "" {
+		return time.Time{}, nil
+	}
+
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %w", ErrInvalidCursor, err)
+	}
+
+	nanos, found := strings.CutPrefix(string(decoded), drugChangesCursorPrefix)
+	if !found {
+		return time.Time{}, fmt.Errorf("%w: unknown version", ErrInvalidCursor)
+	}
+
+	unixNano, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %w", ErrInvalidCursor, err)
+	}
+
+	return time.Unix(0, unixNano), nil
+}
+
+func formatDrugChangesCursor(t time.Time) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(drugChangesCursorPrefix + strconv.FormatInt(t.UnixNano(), 10)))
+}
+
+// contentHash returns the hash of what the API exposes of the drug,
+// independent of the order the units and stocks are stored in.
+func contentHash(dbDrug models.Drug) (string, error) {
+	drug := FromDBDrug(dbDrug)
+
+	slices.SortFunc(drug.Units, func(a, b Unit) int {
+		return cmp.Or(cmp.Compare(a.UnitOrder, b.UnitOrder), cmp.Compare(a.Unit, b.Unit))
+	})
+	slices.SortFunc(drug.Stocks, func(a, b Stock) int {
+		return cmp.Or(cmp.Compare(a.Unit, b.Unit), cmp.Compare(a.Quantity, b.Quantity))
+	})
+
+	b, err := json.Marshal(drug)
+	if err != nil {
+		return "", fmt.Errorf("marshal drug: %w", err)
+	}
+
+	hash := sha256.Sum256(b)
+	return hex.EncodeToString(hash[:]), nil
+}
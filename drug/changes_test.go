@@ -0,0 +1,127 @@
+package drug_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/turfaa/vmedis-proxy-api/auth"
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+	"github.com/turfaa/vmedis-proxy-api/drug"
+	vmedisv1 "github.com/turfaa/vmedis-proxy-api/vmedis/v1"
+)
+
+// TestDrugChanges checks that only the drugs whose content really changed since the cursor are returned,
+// rendered for the caller's role, together with the tombstones of the drugs removed from Vmedis.
+func TestDrugChanges(t *testing.T) {
+	db, _, router := setup(t)
+	database := drug.NewDatabase(db)
+	ctx := context.Background()
+
+	do := func(role auth.Role, since string) (int, string) {
+		req := httptest.NewRequest("GET", "/drugs/changes?since="+url.QueryEscape(since), nil)
+		req.Header.Set("X-Role", string(role))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code, w.Body.String()
+	}
+
+	// dump simulates dumping the drug details from Vmedis.
+	dump := func(code string, units []vmedisv1.Unit, stocks []vmedisv1.Stock) {
+		t.Helper()
+
+		if err := database.UpsertVmedisDrugUnits(ctx, code, units); err != nil {
+			t.Fatalf("upsert units of %s: %s", code, err)
+		}
+		if err := database.UpsertVmedisDrugStocks(ctx, code, stocks); err != nil {
+			t.Fatalf("upsert stocks of %s: %s", code, err)
+		}
+		if _, err := database.RefreshDrugChanges(ctx, []string{code}, time.Now()); err != nil {
+			t.Fatalf("refresh changes of %s: %s", code, err)
+		}
+	}
+
+	if err := database.UpsertVmedisDrugs(ctx, []vmedisv1.Drug{
+		{VmedisID: 1, VmedisCode: "SANMOL", Name: "SANMOL 500MG"},
+		{VmedisID: 2, VmedisCode: "BODREX", Name: "BODREX"},
+		{VmedisID: 3, VmedisCode: "PROMAG", Name: "PROMAG"},
+	}, "vmedis_code", []string{"vmedis_id", "name", "manufacturer"}); err != nil {
+		t.Fatalf("upsert drugs: %s", err)
+	}
+
+	sanmolUnits := []vmedisv1.Unit{{Unit: "Strip", PriceOne: 5000, PriceTwo: 4500, PriceThree: 6000}}
+	bodrexUnits := []vmedisv1.Unit{{Unit: "Strip", PriceOne: 4000}}
+	dump("SANMOL", sanmolUnits, []vmedisv1.Stock{{Unit: "Strip", Quantity: 10}})
+	dump("BODREX", bodrexUnits, []vmedisv1.Stock{{Unit: "Strip", Quantity: 3}})
+	dump("PROMAG", []vmedisv1.Unit{{Unit: "Strip", PriceOne: 7000}}, nil)
+
+	code, body := do(auth.RoleGuest, "")
+	full := unmarshal[drug.DrugChangesResponse](t, body)
+	if code != 200 || len(full.Drugs) != 3 || len(full.RemovedDrugs) != 0 || full.Cursor == "" {
+		t.Fatalf("full sync: got code %d, body %s", code, body)
+	}
+
+	// Pretend the previous dumps happened long ago, so the cursor is after them.
+	hourAgo := time.Now().Add(-time.Hour)
+	if err := db.Model(&models.Drug{}).Where("1 = 1").UpdateColumns(map[string]any{"changed_at": hourAgo, "updated_at": hourAgo}).Error; err != nil {
+		t.Fatalf("move drug times: %s", err)
+	}
+
+	code, body = do(auth.RoleGuest, full.Cursor)
+	if changes := unmarshal[drug.DrugChangesResponse](t, body); code != 200 || len(changes.Drugs) != 0 || len(changes.RemovedDrugs) != 0 {
+		t.Fatalf("no changes: got code %d, body %s", code, body)
+	}
+
+	// The next full dump finds SANMOL with a new price, BODREX unchanged, and PROMAG missing.
+	dumpStartedAt := time.Now()
+	if err := database.UpsertVmedisDrugs(ctx, []vmedisv1.Drug{
+		{VmedisID: 1, VmedisCode: "SANMOL", Name: "SANMOL 500MG"},
+		{VmedisID: 2, VmedisCode: "BODREX", Name: "BODREX"},
+	}, "vmedis_code", []string{"vmedis_id", "name", "manufacturer"}); err != nil {
+		t.Fatalf("upsert drugs again: %s", err)
+	}
+	dump("SANMOL", []vmedisv1.Unit{{Unit: "Strip", PriceOne: 5500, PriceTwo: 5000, PriceThree: 6500}}, []vmedisv1.Stock{{Unit: "Strip", Quantity: 10}})
+	dump("BODREX", bodrexUnits, []vmedisv1.Stock{{Unit: "Strip", Quantity: 3}})
+
+	if removed, err := database.MarkDrugsRemovedNotUpdatedSince(ctx, dumpStartedAt, time.Now()); err != nil || len(removed) != 1 || removed[0] != "PROMAG" {
+		t.Fatalf("mark removed drugs: got %v, %v", removed, err)
+	}
+
+	code, body = do(auth.RoleGuest, full.Cursor)
+	guestChanges := unmarshal[drug.DrugChangesResponse](t, body)
+	if code != 200 || len(guestChanges.Drugs) != 1 || guestChanges.Drugs[0].VmedisCode != "SANMOL" {
+		t.Fatalf("guest changes: got code %d, body %s", code, body)
+	}
+	if len(guestChanges.RemovedDrugs) != 1 || guestChanges.RemovedDrugs[0].VmedisCode != "PROMAG" {
+		t.Fatalf("guest changes: expected PROMAG tombstone, got %s", body)
+	}
+
+	code, body = do(auth.RoleAdmin, full.Cursor)
+	adminChanges := unmarshal[drug.DrugChangesResponse](t, body)
+	if code != 200 || len(adminChanges.Drugs) != 1 || len(adminChanges.Drugs[0].Sections) <= len(guestChanges.Drugs[0].Sections) {
+		t.Fatalf("admin changes: expected more sections than guest, got code %d, body %s", code, body)
+	}
+
+	// PROMAG is back in Vmedis, which must be synced even though its content is the same.
+	if err := db.Model(&models.Drug{}).Where("1 = 1").UpdateColumn("changed_at", hourAgo).Error; err != nil {
+		t.Fatalf("move drug times: %s", err)
+	}
+	if err := database.UpsertVmedisDrugs(ctx, []vmedisv1.Drug{
+		{VmedisID: 3, VmedisCode: "PROMAG", Name: "PROMAG"},
+	}, "vmedis_code", []string{"vmedis_id", "name", "manufacturer"}); err != nil {
+		t.Fatalf("restore drug: %s", err)
+	}
+
+	code, body = do(auth.RoleGuest, full.Cursor)
+	restored := unmarshal[drug.DrugChangesResponse](t, body)
+	if code != 200 || len(restored.Drugs) != 1 || restored.Drugs[0].VmedisCode != "PROMAG" || len(restored.RemovedDrugs) != 0 {
+		t.Fatalf("restored drug: got code %d, body %s", code, body)
+	}
+
+	code, body = do(auth.RoleGuest, "not-a-cursor")
+	if code != 400 {
+		t.Fatalf("invalid cursor: got code %d, body %s", code, body)
+	}
+}
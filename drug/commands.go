@@ -5,13 +5,12 @@ import (
 	"log"
 	"os"
 	"os/signal"
-	"sync"
 	"syscall"
 
 	"github.com/redis/go-redis/v9"
-	"github.com/segmentio/kafka-go"
 	"gorm.io/gorm"
 
+	"github.com/turfaa/vmedis-proxy-api/pkg2/queue"
 	vmedisv1 "github.com/turfaa/vmedis-proxy-api/vmedis/v1"
 )
 
@@ -20,42 +19,122 @@ func DumpDrugsFromVmedisToDB(
 	redisClient redis.UniversalClient,
 	db *gorm.DB,
 	vmedisClient *vmedisv1.Client,
-	kafkaWriter *kafka.Writer,
+	q queue.Queue,
 ) {
-	service := NewService(redisClient, db, vmedisClient, kafkaWriter)
+	service := NewService(redisClient, db, vmedisClient, q)
 
 	if err := service.DumpDrugsFromVmedisToDB(ctx); err != nil {
 		log.Fatalf("DumpDrugsFromVmedisToDB: %s", err)
 	}
 }
 
-func RunUpdatedDrugsConsumer(ctx context.Context, config ConsumerConfig) {
-	consumer := NewUpdatedDrugsConsumer(config)
+func SeedEquivalenceGroupsFromKFA(
+	ctx context.Context,
+	redisClient redis.UniversalClient,
+	db *gorm.DB,
+	vmedisClient *vmedisv1.Client,
+	q queue.Queue,
+) {
+	service := NewService(redisClient, db, vmedisClient, q)
 
-	var wg sync.WaitGroup
-	wg.Add(1)
-	go func() {
-		consumer.StartConsuming()
-		wg.Done()
-	}()
+	result, err := service.SeedEquivalenceGroupsFromKFA(ctx)
+	if err != nil {
+		log.Fatalf("SeedEquivalenceGroupsFromKFA: %s", err)
+	}
 
-	done := make(chan os.Signal, 1)
-	signal.Notify(done, os.Interrupt, syscall.SIGTERM, syscall.SIGINT)
+	log.Printf("Created %d equivalence groups and added %d drugs to them", result.CreatedGroups, result.AddedDrugs)
+}
 
-	go func() {
-		sig := <-done
+// ImportBarcodesFromFile imports the drug barcodes from the CSV file at the given path.
+func ImportBarcodesFromFile(
+	ctx context.Context,
+	redisClient redis.UniversalClient,
+	db *gorm.DB,
+	vmedisClient *vmedisv1.Client,
+	q queue.Queue,
+	path string,
+) {
+	file, err := os.Open(path)
+	if err != nil {
+		log.Fatalf("Open barcode file %s: %s", path, err)
+	}
+	defer file.Close()
 
-		log.Printf("%s signal received, shutting down consumers", sig)
-		consumer.Close()
-	}()
+	service := NewService(redisClient, db, vmedisClient, q)
+
+	result, err := service.ImportBarcodes(ctx, file)
+	if err != nil {
+		log.Fatalf("ImportBarcodes: %s", err)
+	}
+
+	for _, rowErr := range result.Errors {
+		log.Printf("Skipped %s", rowErr)
+	}
+
+	log.Printf("Imported %d barcodes from %s, skipped %d rows", result.Imported, path, len(result.Errors))
+}
+
+// GenerateLabelsToFile renders the shelf price labels of the selected drugs to a PDF file at the given path.
+func GenerateLabelsToFile(
+	ctx context.Context,
+	redisClient redis.UniversalClient,
+	db *gorm.DB,
+	vmedisClient *vmedisv1.Client,
+	q queue.Queue,
+	request GenerateLabelsRequest,
+	path string,
+) {
+	service := NewService(redisClient, db, vmedisClient, q)
+
+	labels, err := service.GetLabels(ctx, request)
+	if err != nil {
+		log.Fatalf("GetLabels: %s", err)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		log.Fatalf("Create label file %s: %s", path, err)
+	}
+	defer file.Close()
+
+	if err := RenderLabelsPDF(file, labels); err != nil {
+		log.Fatalf("RenderLabelsPDF: %s", err)
+	}
+
+	log.Printf("Rendered %d labels to %s", len(labels), path)
+}
+
+// RunUpdatedDrugsConsumer consumes the updated drug topics until the context is done or a termination signal is received,
+// finishing the messages being handled before returning.
+func RunUpdatedDrugsConsumer(ctx context.Context, config ConsumerConfig) {
+	consumer := NewUpdatedDrugsConsumer(config)
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
 
 	go func() {
 		<-ctx.Done()
-
 		log.Printf("Context done [%s], shutting down consumers", ctx.Err())
-		consumer.Close()
 	}()
 
-	wg.Wait()
+	consumer.StartConsuming(ctx)
 	log.Println("Consumers shut down successfully")
 }
+
+// RequeueDeadLetters produces the drug messages that failed after the consumer retries back to their original topics.
+func RequeueDeadLetters(
+	ctx context.Context,
+	redisClient redis.UniversalClient,
+	db *gorm.DB,
+	vmedisClient *vmedisv1.Client,
+	q queue.Queue,
+) {
+	service := NewService(redisClient, db, vmedisClient, q)
+
+	requeued, err := service.RequeueDeadLetters(ctx)
+	if err != nil {
+		log.Fatalf("RequeueDeadLetters: %s", err)
+	}
+
+	log.Printf("Requeued %d dead letters from %s", requeued, DeadLetterTopic)
+}
@@ -4,9 +4,9 @@ import (
 	"time"
 
 	"github.com/redis/go-redis/v9"
-	"github.com/segmentio/kafka-go"
 	"gorm.io/gorm"
 
+	"github.com/turfaa/vmedis-proxy-api/pkg2/queue"
 	vmedisv1 "github.com/turfaa/vmedis-proxy-api/vmedis/v1"
 )
 
@@ -19,9 +19,10 @@ type ConsumerConfig struct {
 	DB           *gorm.DB
 	RedisClient  redis.UniversalClient
 	VmedisClient *vmedisv1.Client
-	KafkaWriter  *kafka.Writer
-
-	Brokers []string
+	Queue        queue.Queue
 
 	Concurrency int
+
+	// MaxRetries is the number of retries of a failing message before it's sent to DeadLetterTopic.
+	MaxRetries int
 }
@@ -2,34 +2,38 @@ package drug
 
 import (
 	"context"
-	"errors"
-	"io"
 	"log"
 	"sync"
+	"time"
 
-	"github.com/segmentio/kafka-go"
+	"github.com/turfaa/vmedis-proxy-api/pkg2/queue"
+	"github.com/turfaa/vmedis-proxy-api/pkg2/retry"
 )
 
 const (
 	ConsumerGroupID = "drug-consumer"
+
+	DefaultConsumerConcurrency = 10
+
+	// DefaultConsumerMaxRetries is the number of retries of a failing message before it's sent to DeadLetterTopic.
+	DefaultConsumerMaxRetries = 3
 )
 
 type UpdatedDrugConsumer struct {
-	brokers     []string
-	handler     *ConsumerHandler
-	readers     []*kafka.Reader
-	lock        sync.Mutex
-	concurrency int
+	consumer *queue.Consumer
+	handler  *ConsumerHandler
 }
 
-func (c *UpdatedDrugConsumer) StartConsuming() {
+// StartConsuming consumes the updated drug topics until the context is done,
+// then finishes the messages being handled before returning.
+func (c *UpdatedDrugConsumer) StartConsuming(ctx context.Context) {
 	var wg sync.WaitGroup
 
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
 
-		if err := c.StartConsumingDumpDrugDetailsByVmedisCode(); err != nil {
+		if err := c.StartConsumingDumpDrugDetailsByVmedisCode(ctx); err != nil {
 			log.Printf("StartConsumingDumpDrugDetailsByVmedisCode returns error: %s", err)
 		}
 	}()
@@ -38,7 +42,7 @@ func (c *UpdatedDrugConsumer) StartConsuming() {
 	go func() {
 		defer wg.Done()
 
-		if err := c.StartConsumingDumpDrugDetailsByVmedisID(); err != nil {
+		if err := c.StartConsumingDumpDrugDetailsByVmedisID(ctx); err != nil {
 			log.Printf("StartConsumingDumpDrugDetailsByVmedisID returns error: %s", err)
 		}
 	}()
@@ -46,116 +50,30 @@ func (c *UpdatedDrugConsumer) StartConsuming() {
 	wg.Wait()
 }
 
-func (c *UpdatedDrugConsumer) StartConsumingDumpDrugDetailsByVmedisCode() error {
-	reader := kafka.NewReader(kafka.ReaderConfig{
-		Brokers: c.brokers,
-		Topic:   VmedisCodeUpdatedTopic,
-		GroupID: ConsumerGroupID,
-	})
-
-	c.lock.Lock()
-	c.readers = append(c.readers, reader)
-	c.lock.Unlock()
-
-	messageChan := make(chan kafka.Message, 1000)
-
-	var wg sync.WaitGroup
-	for i := 0; i < c.concurrency; i++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			c.processDumpDrugDetailsByVmedisCode(messageChan)
-		}()
-	}
-
-	defer func() {
-		close(messageChan)
-		wg.Wait()
-	}()
-
-	for {
-		m, err := reader.ReadMessage(context.Background())
-		if errors.Is(err, io.EOF) {
-			return nil
-		}
-
-		if err != nil {
-			return err
-		}
-
-		messageChan <- m
-	}
+func (c *UpdatedDrugConsumer) StartConsumingDumpDrugDetailsByVmedisCode(ctx context.Context) error {
+	return c.consumer.Consume(ctx, VmedisCodeUpdatedTopic, c.handler.DumpDrugDetailsByVmedisCode)
 }
 
-func (c *UpdatedDrugConsumer) processDumpDrugDetailsByVmedisCode(messages <-chan kafka.Message) {
-	for m := range messages {
-		if err := c.handler.DumpDrugDetailsByVmedisCode(context.Background(), m); err != nil {
-			log.Printf("failed to dump drug details by vmedis code: %s", err)
-		}
-	}
+func (c *UpdatedDrugConsumer) StartConsumingDumpDrugDetailsByVmedisID(ctx context.Context) error {
+	return c.consumer.Consume(ctx, VmedisIDUpdatedTopic, c.handler.DumpDrugDetailsByVmedisID)
 }
 
-func (c *UpdatedDrugConsumer) StartConsumingDumpDrugDetailsByVmedisID() error {
-	reader := kafka.NewReader(kafka.ReaderConfig{
-		Brokers: c.brokers,
-		Topic:   VmedisIDUpdatedTopic,
-		GroupID: ConsumerGroupID,
-	})
-
-	c.lock.Lock()
-	c.readers = append(c.readers, reader)
-	c.lock.Unlock()
-
-	messageChan := make(chan kafka.Message, 1000)
-
-	var wg sync.WaitGroup
-	for i := 0; i < c.concurrency; i++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			c.processDumpDrugDetailsByVmedisID(messageChan)
-		}()
-	}
-
-	defer func() {
-		close(messageChan)
-		wg.Wait()
-	}()
-
-	for {
-		m, err := reader.ReadMessage(context.Background())
-		if errors.Is(err, io.EOF) {
-			return nil
-		}
-
-		if err != nil {
-			return err
-		}
-
-		messageChan <- m
-	}
-}
-
-func (c *UpdatedDrugConsumer) processDumpDrugDetailsByVmedisID(messages <-chan kafka.Message) {
-	for m := range messages {
-		if err := c.handler.DumpDrugDetailsByVmedisID(context.Background(), m); err != nil {
-			log.Printf("failed to dump drug details by vmedis id: %s", err)
-		}
-	}
-}
-
-func (c *UpdatedDrugConsumer) Close() {
-	for _, reader := range c.readers {
-		if err := reader.Close(); err != nil {
-			log.Printf("failed to close reader: %s", err)
-		}
+func NewUpdatedDrugsConsumer(config ConsumerConfig) *UpdatedDrugConsumer {
+	return &UpdatedDrugConsumer{
+		consumer: queue.NewConsumer(config.Queue, queue.ConsumerConfig{
+			Group:           ConsumerGroupID,
+			DeadLetterTopic: DeadLetterTopic,
+			Concurrency:     config.Concurrency,
+			RetryConfig:     consumerRetryConfig(config.MaxRetries),
+		}),
+		handler: NewConsumerHandler(config.DB, config.RedisClient, config.VmedisClient, config.Queue),
 	}
 }
 
-func NewUpdatedDrugsConsumer(config ConsumerConfig) *UpdatedDrugConsumer {
-	return &UpdatedDrugConsumer{
-		brokers:     config.Brokers,
-		handler:     NewConsumerHandler(config.DB, config.RedisClient, config.VmedisClient, config.KafkaWriter),
-		concurrency: config.Concurrency,
+func consumerRetryConfig(maxRetries int) retry.Config {
+	return retry.Config{
+		MaxRetries:     maxRetries,
+		InitialBackoff: time.Second,
+		MaxBackoff:     30 * time.Second,
 	}
 }
@@ -0,0 +1,48 @@
+package drug
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/glebarez/sqlite"
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/protobuf/encoding/protojson"
+	"gorm.io/gorm"
+
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+	"github.com/turfaa/vmedis-proxy-api/kafkapb"
+	"github.com/turfaa/vmedis-proxy-api/pkg2/queue"
+	"github.com/turfaa/vmedis-proxy-api/pkg2/retry"
+)
+
+// TestConsumerHandlerPermanentErrors checks that the messages that can never be handled fail permanently,
+// so they're sent to DeadLetterTopic without being retried.
+func TestConsumerHandlerPermanentErrors(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open database: %s", err)
+	}
+	if err := db.AutoMigrate(&models.Drug{}, &models.DrugUnit{}, &models.DrugStock{}); err != nil {
+		t.Fatalf("migrate database: %s", err)
+	}
+
+	redisClient := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	t.Cleanup(func() { redisClient.Close() })
+
+	handler := NewConsumerHandler(db, redisClient, nil, queue.NewMemory())
+	ctx := context.Background()
+
+	if err := handler.DumpDrugDetailsByVmedisCode(ctx, queue.Message{Value: []byte("not json")}); !retry.IsPermanent(err) {
+		t.Errorf("DumpDrugDetailsByVmedisCode() of an unparseable payload error = %v, want a permanent error", err)
+	}
+
+	payload, err := protojson.Marshal(&kafkapb.UpdatedDrugByVmedisCode{RequestKey: "unknown", VmedisCode: "UNKNOWN"})
+	if err != nil {
+		t.Fatalf("marshal payload: %s", err)
+	}
+	if err := handler.DumpDrugDetailsByVmedisCode(ctx, queue.Message{Value: payload}); !retry.IsPermanent(err) || !errors.Is(err, ErrDrugNotFound) {
+		t.Errorf("DumpDrugDetailsByVmedisCode() of an unknown drug error = %v, want a permanent ErrDrugNotFound", err)
+	}
+}
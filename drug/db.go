@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"slices"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -129,6 +130,80 @@ func (d *Database) GetDrugSaleStatisticsBetweenTimes(
 	return stats, nil
 }
 
+// GetSoldQuantitiesBetweenTime returns the quantities of the drugs sold between the given times.
+// Quantities are counted in the smallest unit, so sales of different units are comparable.
+// Units that can't be converted are counted as they are.
+func (d *Database) GetSoldQuantitiesBetweenTime(ctx context.Context, from time.Time, until time.Time) ([]SoldQuantity, error) {
+	var soldUnits []struct {
+		DrugCode string
+		Amount   float64
+		Unit     string
+		Total    float64
+		SoldAt   time.Time
+	}
+	if err := d.dbCtx(ctx).
+		Raw(
+			`
+SELECT
+	sale_units.drug_code,
+	sale_units.amount,
+	sale_units.unit,
+	sale_units.total,
+	sales.sold_at
+FROM sale_units
+JOIN sales ON sale_units.invoice_number = sales.invoice_number
+WHERE sales.sold_at BETWEEN ? AND ?
+	AND sales.deleted_at IS NULL
+	AND sale_units.deleted_at IS NULL
+			`,
+			from,
+			until,
+		).
+		Find(&soldUnits).
+		Error; err != nil {
+		return nil, fmt.Errorf("get sold units between %s and %s: %w", from, until, err)
+	}
+
+	drugCodes := make([]string, 0, len(soldUnits))
+	for _, sold := range soldUnits {
+		drugCodes = append(drugCodes, sold.DrugCode)
+	}
+	slices.Sort(drugCodes)
+	drugCodes = slices.Compact(drugCodes)
+
+	units, err := d.GetDrugUnitsByDrugVmedisCodes(ctx, drugCodes)
+	if err != nil {
+		return nil, fmt.Errorf("get drug units: %w", err)
+	}
+
+	unitSizes := make(map[string]map[string]float64, len(units))
+	for code, drugUnits := range units {
+		if sizes, ok := UnitSizes(drugUnits); ok {
+			unitSizes[code] = sizes
+		}
+	}
+
+	quantities := make([]SoldQuantity, 0, len(soldUnits))
+	for _, sold := range soldUnits {
+		quantity := SoldQuantity{
+			DrugCode: sold.DrugCode,
+			Quantity: sold.Amount,
+			Unit:     sold.Unit,
+			Total:    sold.Total,
+			SoldAt:   sold.SoldAt,
+		}
+
+		if size := unitSizes[sold.DrugCode][strings.ToLower(sold.Unit)]; size > 0 {
+			quantity.Quantity *= size
+			quantity.Unit = units[sold.DrugCode][0].Unit
+		}
+
+		quantities = append(quantities, quantity)
+	}
+
+	return quantities, nil
+}
+
 // GetDrugUnitsByDrugVmedisCodes returns drug units by drug vmedis codes.
 // The drug units are sorted from the smallest to the largest.
 func (d *Database) GetDrugUnitsByDrugVmedisCodes(ctx context.Context, drugVmedisCodes []string) (map[string][]Unit, error) {
@@ -168,6 +243,22 @@ func (d *Database) GetDrugUnitsByDrugVmedisCodes(ctx context.Context, drugVmedis
 	return unitsByDrugVmedisCode, nil
 }
 
+// GetDrugVmedisCodesWithPricesUpdatedAfter returns the vmedis codes of the drugs
+// with at least one unit whose prices changed after the given time.
+func (d *Database) GetDrugVmedisCodesWithPricesUpdatedAfter(ctx context.Context, minimumPriceUpdatedTime time.Time) ([]string, error) {
+	var codes []string
+	if err := d.dbCtx(ctx).
+		Model(&models.DrugUnit{}).
+		Distinct("drug_vmedis_code").
+		Where("price_updated_at > ?", minimumPriceUpdatedTime).
+		Pluck("drug_vmedis_code", &codes).
+		Error; err != nil {
+		return nil, fmt.Errorf("get drugs with prices updated after %s: %w", minimumPriceUpdatedTime, err)
+	}
+
+	return codes, nil
+}
+
 // UpsertVmedisDrug upserts the given drug.
 func (d *Database) UpsertVmedisDrug(ctx context.Context, drug vmedisv1.Drug, keyColumn string, updateColumns []string) error {
 	return d.UpsertVmedisDrugs(ctx, []vmedisv1.Drug{drug}, keyColumn, updateColumns)
@@ -179,8 +270,10 @@ func (d *Database) UpsertVmedisDrugs(ctx context.Context, drugs []vmedisv1.Drug,
 		return nil
 	}
 
+	now := time.Now()
 	dbDrugs := slices2.Map(drugs, func(drug vmedisv1.Drug) models.Drug {
 		return models.Drug{
+			ChangedAt:    &now,
 			VmedisID:     drug.VmedisID,
 			VmedisCode:   drug.VmedisCode,
 			KFACode:      drug.KFACode,
@@ -197,10 +290,26 @@ func (d *Database) UpsertVmedisDrugs(ctx context.Context, drugs []vmedisv1.Drug,
 		updateColumns = append(updateColumns, "updated_at")
 	}
 
+	// A drug found in Vmedis again is restored, which counts as a change.
+	doUpdates := clause.AssignmentColumns(updateColumns)
+	doUpdates = append(doUpdates,
+		clause.Assignment{
+			Column: clause.Column{Name: "changed_at"},
+			Value: gorm.Expr(`CASE
+				WHEN drugs.removed_at IS NOT NULL THEN excluded.changed_at
+				ELSE drugs.changed_at
+			END`),
+		},
+		clause.Assignment{
+			Column: clause.Column{Name: "removed_at"},
+			Value:  nil,
+		},
+	)
+
 	ops := d.dbCtx(ctx).
 		Clauses(clause.OnConflict{
 			Columns:   []clause.Column{{Name: keyColumn}},
-			DoUpdates: clause.AssignmentColumns(updateColumns),
+			DoUpdates: doUpdates,
 		}).
 		Create(&dbDrugs)
 
@@ -217,6 +326,7 @@ func (d *Database) UpsertVmedisDrugUnits(ctx context.Context, drugVmedisCode str
 		return nil
 	}
 
+	now := time.Now()
 	dbUnits := slices2.Map(units, func(unit vmedisv1.Unit) models.DrugUnit {
 		return models.DrugUnit{
 			DrugVmedisCode:         drugVmedisCode,
@@ -227,21 +337,36 @@ func (d *Database) UpsertVmedisDrugUnits(ctx context.Context, drugVmedisCode str
 			PriceTwo:               unit.PriceTwo,
 			PriceThree:             unit.PriceThree,
 			UnitOrder:              unit.UnitOrder,
+			PriceUpdatedAt:         &now,
 		}
 	})
 
+	doUpdates := clause.AssignmentColumns([]string{
+		"updated_at",
+		"parent_unit",
+		"conversion_to_parent_unit",
+		"unit_order",
+		"price_one",
+		"price_two",
+		"price_three",
+	})
+
+	// Only move price_updated_at when one of the prices actually changes.
+	doUpdates = append(doUpdates, clause.Assignment{
+		Column: clause.Column{Name: "price_updated_at"},
+		Value: gorm.Expr(`CASE
+			WHEN drug_units.price_one <> excluded.price_one
+				OR drug_units.price_two <> excluded.price_two
+				OR drug_units.price_three <> excluded.price_three
+			THEN excluded.price_updated_at
+			ELSE drug_units.price_updated_at
+		END`),
+	})
+
 	ops := d.dbCtx(ctx).
 		Clauses(clause.OnConflict{
-			Columns: []clause.Column{{Name: "drug_vmedis_code"}, {Name: "unit"}},
-			DoUpdates: clause.AssignmentColumns([]string{
-				"updated_at",
-				"parent_unit",
-				"conversion_to_parent_unit",
-				"unit_order",
-				"price_one",
-				"price_two",
-				"price_three",
-			}),
+			Columns:   []clause.Column{{Name: "drug_vmedis_code"}, {Name: "unit"}},
+			DoUpdates: doUpdates,
 		}).
 		Create(&dbUnits)
 
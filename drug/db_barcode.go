@@ -0,0 +1,140 @@
+package drug
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm/clause"
+
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+)
+
+// GetBarcodes returns the barcodes sorted by drug and barcode.
+// If drugVmedisCode is not empty, only the barcodes of that drug are returned.
+func (d *Database) GetBarcodes(ctx context.Context, drugVmedisCode string) ([]models.DrugBarcode, error) {
+	query := d.dbCtx(ctx).Order("drug_vmedis_code").Order("barcode")
+	if drugVmedisCode != "" {
+		query = query.Where("drug_vmedis_code = ?", drugVmedisCode)
+	}
+
+	var barcodes []models.DrugBarcode
+	if err := query.Find(&barcodes).Error; err != nil {
+		return nil, fmt.Errorf("get barcodes from db: %w", err)
+	}
+
+	return barcodes, nil
+}
+
+func (d *Database) GetBarcodeByID(ctx context.Context, id uint) (models.DrugBarcode, error) {
+	var barcode models.DrugBarcode
+	if err := d.dbCtx(ctx).First(&barcode, id).Error; err != nil {
+		return models.DrugBarcode{}, fmt.Errorf("get barcode %d from db: %w", id, err)
+	}
+
+	return barcode, nil
+}
+
+// GetBarcodeByCode returns the barcode with the given canonical code.
+func (d *Database) GetBarcodeByCode(ctx context.Context, code string) (models.DrugBarcode, error) {
+	var barcode models.DrugBarcode
+	if err := d.dbCtx(ctx).Where("barcode = ?", code).First(&barcode).Error; err != nil {
+		return models.DrugBarcode{}, fmt.Errorf("get barcode %s from db: %w", code, err)
+	}
+
+	return barcode, nil
+}
+
+func (d *Database) CreateBarcode(ctx context.Context, barcode models.DrugBarcode) (models.DrugBarcode, error) {
+	if err := d.dbCtx(ctx).Create(&barcode).Error; err != nil {
+		return models.DrugBarcode{}, fmt.Errorf("create barcode %s in db: %w", barcode.Barcode, err)
+	}
+
+	return barcode, nil
+}
+
+func (d *Database) UpdateBarcode(ctx context.Context, barcode models.DrugBarcode) error {
+	if err := d.dbCtx(ctx).Save(&barcode).Error; err != nil {
+		return fmt.Errorf("update barcode %d in db: %w", barcode.ID, err)
+	}
+
+	return nil
+}
+
+func (d *Database) DeleteBarcode(ctx context.Context, id uint) error {
+	if err := d.dbCtx(ctx).Delete(&models.DrugBarcode{}, id).Error; err != nil {
+		return fmt.Errorf("delete barcode %d from db: %w", id, err)
+	}
+
+	return nil
+}
+
+// UpsertBarcodes inserts the given barcodes.
+// Barcodes that are already registered are moved to the given drug and unit.
+func (d *Database) UpsertBarcodes(ctx context.Context, barcodes []models.DrugBarcode) error {
+	if len(barcodes) == 0 {
+		return nil
+	}
+
+	if err := d.dbCtx(ctx).
+		Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "barcode"}},
+			DoUpdates: clause.AssignmentColumns([]string{
+				"updated_at",
+				"drug_vmedis_code",
+				"unit",
+			}),
+		}).
+		CreateInBatches(&barcodes, insertBatchSize).
+		Error; err != nil {
+		return fmt.Errorf("upsert %d barcodes in db: %w", len(barcodes), err)
+	}
+
+	return nil
+}
+
+// GetDrugUnitNames returns the unit names of the given drugs, by their vmedis codes.
+func (d *Database) GetDrugUnitNames(ctx context.Context, drugVmedisCodes []string) (map[string][]string, error) {
+	if len(drugVmedisCodes) == 0 {
+		return nil, nil
+	}
+
+	var units []models.DrugUnit
+	if err := d.dbCtx(ctx).
+		Select("drug_vmedis_code", "unit").
+		Where("drug_vmedis_code IN ?", drugVmedisCodes).
+		Order("unit_order").
+		Find(&units).
+		Error; err != nil {
+		return nil, fmt.Errorf("get unit names of %d drugs from db: %w", len(drugVmedisCodes), err)
+	}
+
+	names := make(map[string][]string, len(drugVmedisCodes))
+	for _, unit := range units {
+		names[unit.DrugVmedisCode] = append(names[unit.DrugVmedisCode], unit.Unit)
+	}
+
+	return names, nil
+}
+
+// GetBarcodesByDrugVmedisCodes returns the barcodes of the given drugs, by their vmedis codes.
+func (d *Database) GetBarcodesByDrugVmedisCodes(ctx context.Context, drugVmedisCodes []string) (map[string][]models.DrugBarcode, error) {
+	if len(drugVmedisCodes) == 0 {
+		return nil, nil
+	}
+
+	var barcodes []models.DrugBarcode
+	if err := d.dbCtx(ctx).
+		Where("drug_vmedis_code IN ?", drugVmedisCodes).
+		Order("barcode").
+		Find(&barcodes).
+		Error; err != nil {
+		return nil, fmt.Errorf("get barcodes of %d drugs from db: %w", len(drugVmedisCodes), err)
+	}
+
+	barcodesByDrug := make(map[string][]models.DrugBarcode, len(drugVmedisCodes))
+	for _, barcode := range barcodes {
+		barcodesByDrug[barcode.DrugVmedisCode] = append(barcodesByDrug[barcode.DrugVmedisCode], barcode)
+	}
+
+	return barcodesByDrug, nil
+}
@@ -0,0 +1,141 @@
+package drug
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+)
+
+// GetExistingDrugs returns the drugs that haven't been removed from Vmedis.
+func (d *Database) GetExistingDrugs(ctx context.Context) ([]models.Drug, error) {
+	var drugs []models.Drug
+	if err := d.dbCtx(ctx).
+		Preload("Units").
+		Preload("Stocks").
+		Where("removed_at IS NULL").
+		Order("name").
+		Find(&drugs).
+		Error; err != nil {
+		return nil, fmt.Errorf("get existing drugs: %w", err)
+	}
+
+	return drugs, nil
+}
+
+// GetDrugsChangedAfter returns the drugs changed, removed, or restored after the given time.
+func (d *Database) GetDrugsChangedAfter(ctx context.Context, minimumChangedTime time.Time) ([]models.Drug, error) {
+	var drugs []models.Drug
+	if err := d.dbCtx(ctx).
+		Preload("Units").
+		Preload("Stocks").
+		Where("changed_at > ?", minimumChangedTime).
+		Order("name").
+		Find(&drugs).
+		Error; err != nil {
+		return nil, fmt.Errorf("get drugs changed after %s: %w", minimumChangedTime, err)
+	}
+
+	return drugs, nil
+}
+
+// RefreshDrugChanges recalculates the content hash of the drugs with the given vmedis codes,
+// moves their changed_at to the given time if the content actually changed,
+// and returns the vmedis codes of the changed drugs.
+func (d *Database) RefreshDrugChanges(ctx context.Context, vmedisCodes []string, changedAt time.Time) ([]string, error) {
+	if len(vmedisCodes) == 0 {
+		return nil, nil
+	}
+
+	var drugs []models.Drug
+	if err := d.dbCtx(ctx).
+		Preload("Units").
+		Preload("Stocks").
+		Where("vmedis_code IN ?", vmedisCodes).
+		Find(&drugs).
+		Error; err != nil {
+		return nil, fmt.Errorf("get drugs %v: %w", vmedisCodes, err)
+	}
+
+	var changed []string
+	for _, drug := range drugs {
+		hash, err := contentHash(drug)
+		if err != nil {
+			return nil, fmt.Errorf("hash drug %s: %w", drug.VmedisCode, err)
+		}
+
+		if hash == drug.ContentHash {
+			continue
+		}
+
+		if err := d.dbCtx(ctx).
+			Model(&models.Drug{}).
+			Where("id = ?", drug.ID).
+			UpdateColumns(map[string]any{
+				"content_hash": hash,
+				"changed_at":   changedAt,
+			}).
+			Error; err != nil {
+			return nil, fmt.Errorf("update content hash of drug %s: %w", drug.VmedisCode, err)
+		}
+
+		changed = append(changed, drug.VmedisCode)
+	}
+
+	return changed, nil
+}
+
+// MarkDrugsRemovedNotUpdatedSince marks the drugs not updated since the given time as removed,
+// and returns the vmedis codes of the newly removed drugs.
+func (d *Database) MarkDrugsRemovedNotUpdatedSince(ctx context.Context, minimumUpdatedTime time.Time, removedAt time.Time) ([]string, error) {
+	var codes []string
+	if err := d.dbCtx(ctx).
+		Transaction(func(tx *gorm.DB) error {
+			if err := tx.
+				Model(&models.Drug{}).
+				Where("removed_at IS NULL AND updated_at < ?", minimumUpdatedTime).
+				Pluck("vmedis_code", &codes).
+				Error; err != nil {
+				return fmt.Errorf("get drugs not updated since %s: %w", minimumUpdatedTime, err)
+			}
+
+			if len(codes) == 0 {
+				return nil
+			}
+
+			if err := tx.
+				Model(&models.Drug{}).
+				Where("vmedis_code IN ?", codes).
+				UpdateColumns(map[string]any{
+					"removed_at": removedAt,
+					"changed_at": removedAt,
+				}).
+				Error; err != nil {
+				return fmt.Errorf("mark drugs %v as removed: %w", codes, err)
+			}
+
+			return nil
+		}); err != nil {
+		return nil, fmt.Errorf("mark drugs not updated since %s as removed transaction: %w", minimumUpdatedTime, err)
+	}
+
+	return codes, nil
+}
+
+// GetDrugByVmedisID returns the drug with the given vmedis ID, including the removed one.
+func (d *Database) GetDrugByVmedisID(ctx context.Context, vmedisID int64) (models.Drug, error) {
+	var drug models.Drug
+	if err := d.dbCtx(ctx).
+		Preload("Units").
+		Preload("Stocks").
+		Where("vmedis_id = ?", vmedisID).
+		Take(&drug).
+		Error; err != nil {
+		return models.Drug{}, fmt.Errorf("get drug %d: %w", vmedisID, err)
+	}
+
+	return drug, nil
+}
@@ -0,0 +1,107 @@
+package drug
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+)
+
+// GetStockedDrugs returns the drugs that have stock, with their stocks.
+func (d *Database) GetStockedDrugs(ctx context.Context) ([]models.Drug, error) {
+	var drugs []models.Drug
+	if err := d.dbCtx(ctx).
+		Preload("Stocks").
+		Where("vmedis_code IN (SELECT drug_vmedis_code FROM drug_stocks WHERE quantity > 0)").
+		Order("name").
+		Find(&drugs).
+		Error; err != nil {
+		return nil, fmt.Errorf("get stocked drugs from db: %w", err)
+	}
+
+	return drugs, nil
+}
+
+// getLastSales returns the latest sale of the given drugs, by their vmedis codes.
+func (d *Database) getLastSales(ctx context.Context, drugVmedisCodes []string) (map[string]drugLastSale, error) {
+	if len(drugVmedisCodes) == 0 {
+		return nil, nil
+	}
+
+	var sales []drugLastSale
+	if err := d.dbCtx(ctx).
+		Raw(
+			`
+SELECT drug_code, sold_at
+FROM (
+	SELECT
+		sale_units.drug_code,
+		sales.sold_at,
+		ROW_NUMBER() OVER (PARTITION BY sale_units.drug_code ORDER BY sales.sold_at DESC) AS recency
+	FROM sale_units
+	JOIN sales ON sale_units.invoice_number = sales.invoice_number
+	WHERE sale_units.drug_code IN ?
+		AND sales.deleted_at IS NULL
+		AND sale_units.deleted_at IS NULL
+) last_sales
+WHERE recency = 1
+			`,
+			drugVmedisCodes,
+		).
+		Find(&sales).
+		Error; err != nil {
+		return nil, fmt.Errorf("get last sales of %d drugs from db: %w", len(drugVmedisCodes), err)
+	}
+
+	salesByDrug := make(map[string]drugLastSale, len(sales))
+	for _, sale := range sales {
+		salesByDrug[sale.DrugCode] = sale
+	}
+
+	return salesByDrug, nil
+}
+
+// getLastProcurements returns the latest procurement of the given drugs, by their vmedis codes.
+func (d *Database) getLastProcurements(ctx context.Context, drugVmedisCodes []string) (map[string]drugLastProcurement, error) {
+	if len(drugVmedisCodes) == 0 {
+		return nil, nil
+	}
+
+	var procurements []drugLastProcurement
+	if err := d.dbCtx(ctx).
+		Raw(
+			`
+SELECT drug_code, unit, total_unit_price, unit_taxed_price, invoice_date
+FROM (
+	SELECT
+		procurement_units.drug_code,
+		procurement_units.unit,
+		procurement_units.total_unit_price,
+		procurement_units.unit_taxed_price,
+		procurements.invoice_date,
+		ROW_NUMBER() OVER (
+			PARTITION BY procurement_units.drug_code
+			ORDER BY procurements.invoice_date DESC, procurement_units.created_at DESC, procurement_units.id DESC
+		) AS recency
+	FROM procurement_units
+	JOIN procurements ON procurement_units.invoice_number = procurements.invoice_number
+	WHERE procurement_units.drug_code IN ?
+		AND procurements.deleted_at IS NULL
+		AND procurement_units.deleted_at IS NULL
+) last_procurements
+WHERE recency = 1
+			`,
+			drugVmedisCodes,
+		).
+		Find(&procurements).
+		Error; err != nil {
+		return nil, fmt.Errorf("get last procurements of %d drugs from db: %w", len(drugVmedisCodes), err)
+	}
+
+	procurementsByDrug := make(map[string]drugLastProcurement, len(procurements))
+	for _, procurement := range procurements {
+		procurementsByDrug[procurement.DrugCode] = procurement
+	}
+
+	return procurementsByDrug, nil
+}
@@ -0,0 +1,233 @@
+package drug
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+)
+
+// kfaEquivalence is the KFA-derived equivalence attributes of a drug.
+type kfaEquivalence struct {
+	VmedisCode       string
+	ActiveIngredient string
+	Strength         string
+	DosageForm       string
+}
+
+// GetEquivalenceGroups returns all equivalence groups with their members.
+func (d *Database) GetEquivalenceGroups(ctx context.Context) ([]models.DrugEquivalenceGroup, error) {
+	var groups []models.DrugEquivalenceGroup
+	if err := d.dbCtx(ctx).Preload("Members").Order("name").Find(&groups).Error; err != nil {
+		return nil, fmt.Errorf("get equivalence groups from db: %w", err)
+	}
+
+	return groups, nil
+}
+
+// GetEquivalenceGroupByID returns the equivalence group with its members.
+func (d *Database) GetEquivalenceGroupByID(ctx context.Context, id uint) (models.DrugEquivalenceGroup, error) {
+	var group models.DrugEquivalenceGroup
+	if err := d.dbCtx(ctx).Preload("Members").First(&group, id).Error; err != nil {
+		return models.DrugEquivalenceGroup{}, fmt.Errorf("get equivalence group %d from db: %w", id, err)
+	}
+
+	return group, nil
+}
+
+// CreateEquivalenceGroup creates the group with the given drugs as its members.
+// Drugs that already belong to another group are moved to the new group.
+func (d *Database) CreateEquivalenceGroup(ctx context.Context, group models.DrugEquivalenceGroup, drugVmedisCodes []string) (models.DrugEquivalenceGroup, error) {
+	group.Members = nil
+
+	if err := d.dbCtx(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&group).Error; err != nil {
+			return fmt.Errorf("create group: %w", err)
+		}
+
+		return addEquivalenceGroupMembers(tx, group.ID, drugVmedisCodes)
+	}); err != nil {
+		return models.DrugEquivalenceGroup{}, fmt.Errorf("create equivalence group %s: %w", group.Name, err)
+	}
+
+	return group, nil
+}
+
+// UpdateEquivalenceGroup saves the group.
+// If drugVmedisCodes is not nil, the members of the group are replaced by the given drugs.
+func (d *Database) UpdateEquivalenceGroup(ctx context.Context, group models.DrugEquivalenceGroup, drugVmedisCodes []string) error {
+	group.Members = nil
+
+	if err := d.dbCtx(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&group).Error; err != nil {
+			return fmt.Errorf("save group: %w", err)
+		}
+
+		if drugVmedisCodes == nil {
+			return nil
+		}
+
+		if err := tx.Where("group_id = ?", group.ID).Delete(&models.DrugEquivalenceGroupMember{}).Error; err != nil {
+			return fmt.Errorf("delete members: %w", err)
+		}
+
+		return addEquivalenceGroupMembers(tx, group.ID, drugVmedisCodes)
+	}); err != nil {
+		return fmt.Errorf("update equivalence group %d: %w", group.ID, err)
+	}
+
+	return nil
+}
+
+// DeleteEquivalenceGroup deletes the group and its members.
+func (d *Database) DeleteEquivalenceGroup(ctx context.Context, id uint) error {
+	if err := d.dbCtx(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("group_id = ?", id).Delete(&models.DrugEquivalenceGroupMember{}).Error; err != nil {
+			return fmt.Errorf("delete members: %w", err)
+		}
+
+		if err := tx.Delete(&models.DrugEquivalenceGroup{}, id).Error; err != nil {
+			return fmt.Errorf("delete group: %w", err)
+		}
+
+		return nil
+	}); err != nil {
+		return fmt.Errorf("delete equivalence group %d: %w", id, err)
+	}
+
+	return nil
+}
+
+// AddEquivalenceGroupMembers adds the given drugs to the group.
+// Drugs that already belong to another group are moved.
+func (d *Database) AddEquivalenceGroupMembers(ctx context.Context, groupID uint, drugVmedisCodes []string) error {
+	if err := d.dbCtx(ctx).Transaction(func(tx *gorm.DB) error {
+		return addEquivalenceGroupMembers(tx, groupID, drugVmedisCodes)
+	}); err != nil {
+		return fmt.Errorf("add %d members to equivalence group %d: %w", len(drugVmedisCodes), groupID, err)
+	}
+
+	return nil
+}
+
+func addEquivalenceGroupMembers(tx *gorm.DB, groupID uint, drugVmedisCodes []string) error {
+	if len(drugVmedisCodes) == 0 {
+		return nil
+	}
+
+	if err := tx.Where("drug_vmedis_code IN ?", drugVmedisCodes).Delete(&models.DrugEquivalenceGroupMember{}).Error; err != nil {
+		return fmt.Errorf("delete previous memberships: %w", err)
+	}
+
+	members := make([]models.DrugEquivalenceGroupMember, len(drugVmedisCodes))
+	for i, code := range drugVmedisCodes {
+		members[i] = models.DrugEquivalenceGroupMember{
+			GroupID:        groupID,
+			DrugVmedisCode: code,
+		}
+	}
+
+	if err := tx.Create(&members).Error; err != nil {
+		return fmt.Errorf("create members: %w", err)
+	}
+
+	return nil
+}
+
+// GetEquivalentDrugCodes returns the vmedis codes of the drugs in the same group as the given drugs,
+// excluding the given drugs themselves.
+func (d *Database) GetEquivalentDrugCodes(ctx context.Context, drugVmedisCodes []string) ([]string, error) {
+	if len(drugVmedisCodes) == 0 {
+		return nil, nil
+	}
+
+	var codes []string
+	if err := d.dbCtx(ctx).
+		Model(&models.DrugEquivalenceGroupMember{}).
+		Where(
+			"group_id IN (SELECT group_id FROM drug_equivalence_group_members WHERE drug_vmedis_code IN ?)",
+			drugVmedisCodes,
+		).
+		Where("drug_vmedis_code NOT IN ?", drugVmedisCodes).
+		Pluck("drug_vmedis_code", &codes).
+		Error; err != nil {
+		return nil, fmt.Errorf("get drugs equivalent to %v from db: %w", drugVmedisCodes, err)
+	}
+
+	return codes, nil
+}
+
+// GetDrugVmedisCodesByName returns the vmedis codes of the drugs whose name contains the given name.
+func (d *Database) GetDrugVmedisCodesByName(ctx context.Context, name string, limit int) ([]string, error) {
+	var codes []string
+	if err := d.dbCtx(ctx).
+		Model(&models.Drug{}).
+		Where("LOWER(name) LIKE ?", "%"+strings.ToLower(name)+"%").
+		Order("name").
+		Limit(limit).
+		Pluck("vmedis_code", &codes).
+		Error; err != nil {
+		return nil, fmt.Errorf("get drugs named like %s from db: %w", name, err)
+	}
+
+	return codes, nil
+}
+
+// GetExistingDrugVmedisCodes returns the given vmedis codes that exist in the database.
+func (d *Database) GetExistingDrugVmedisCodes(ctx context.Context, drugVmedisCodes []string) ([]string, error) {
+	if len(drugVmedisCodes) == 0 {
+		return nil, nil
+	}
+
+	var codes []string
+	if err := d.dbCtx(ctx).
+		Model(&models.Drug{}).
+		Where("vmedis_code IN ?", drugVmedisCodes).
+		Pluck("vmedis_code", &codes).
+		Error; err != nil {
+		return nil, fmt.Errorf("get existing drugs of %v from db: %w", drugVmedisCodes, err)
+	}
+
+	return codes, nil
+}
+
+// GetDrugNamesByVmedisCodes returns the drug names by their vmedis codes.
+func (d *Database) GetDrugNamesByVmedisCodes(ctx context.Context, drugVmedisCodes []string) (map[string]string, error) {
+	var drugs []models.Drug
+	if err := d.dbCtx(ctx).
+		Select("vmedis_code", "name").
+		Where("vmedis_code IN ?", drugVmedisCodes).
+		Find(&drugs).
+		Error; err != nil {
+		return nil, fmt.Errorf("get drug names of %v from db: %w", drugVmedisCodes, err)
+	}
+
+	names := make(map[string]string, len(drugs))
+	for _, drug := range drugs {
+		names[drug.VmedisCode] = drug.Name
+	}
+
+	return names, nil
+}
+
+// getKFAEquivalences returns the equivalence attributes of the drugs with a known KFA code.
+func (d *Database) getKFAEquivalences(ctx context.Context) ([]kfaEquivalence, error) {
+	var equivalences []kfaEquivalence
+	if err := d.dbCtx(ctx).
+		Raw(`
+			SELECT drugs.vmedis_code, kfa_products.active_ingredient, kfa_products.strength, kfa_products.dosage_form
+			FROM drugs
+			JOIN kfa_products ON kfa_products.code = drugs.kfa_code
+			WHERE drugs.kfa_code <> '' AND kfa_products.active_ingredient <> ''
+			ORDER BY drugs.vmedis_code
+		`).
+		Scan(&equivalences).
+		Error; err != nil {
+		return nil, fmt.Errorf("get KFA equivalences from db: %w", err)
+	}
+
+	return equivalences, nil
+}
@@ -0,0 +1,97 @@
+package drug
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/turfaa/vmedis-proxy-api/pkg2/queue"
+)
+
+const (
+	// DeadLetterTopic receives the drug messages that still fail after the consumer retries.
+	DeadLetterTopic = "drug.dead_letter"
+
+	// DeadLetterReplayerGroupID is the consumer group whose commits mark the requeued dead letters.
+	DeadLetterReplayerGroupID = "drug-dead-letter-replayer"
+)
+
+// DeadLetterQueue reads and requeues the messages in DeadLetterTopic.
+type DeadLetterQueue struct {
+	queue queue.Queue
+}
+
+// GetDeadLetters returns the dead letters that haven't been requeued, from the oldest.
+func (q *DeadLetterQueue) GetDeadLetters(ctx context.Context) ([]DeadLetter, error) {
+	messages, err := q.queue.Pending(ctx, DeadLetterTopic, DeadLetterReplayerGroupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending dead letters: %w", err)
+	}
+
+	deadLetters := make([]DeadLetter, 0, len(messages))
+	for _, message := range messages {
+		deadLetters = append(deadLetters, deadLetterFromMessage(message))
+	}
+
+	return deadLetters, nil
+}
+
+// Requeue produces the dead letters that haven't been requeued back to their original topics,
+// and returns the number of requeued messages.
+func (q *DeadLetterQueue) Requeue(ctx context.Context) (int, error) {
+	pending, err := q.queue.Pending(ctx, DeadLetterTopic, DeadLetterReplayerGroupID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get pending dead letters: %w", err)
+	}
+
+	messages := make([]queue.Message, 0, len(pending))
+	for _, message := range pending {
+		deadLetter := deadLetterFromMessage(message)
+		if deadLetter.OriginalTopic == "" {
+			log.Printf("Skipping dead letter %s without original topic", deadLetter.ID)
+			continue
+		}
+
+		messages = append(messages, queue.Message{
+			Topic: deadLetter.OriginalTopic,
+			Key:   message.Key,
+			Value: message.Value,
+		})
+	}
+
+	if len(messages) > 0 {
+		if err := q.queue.Publish(ctx, messages...); err != nil {
+			return 0, fmt.Errorf("failed to requeue dead letters: %w", err)
+		}
+	}
+
+	if err := q.queue.CommitPending(ctx, DeadLetterTopic, DeadLetterReplayerGroupID, pending); err != nil {
+		return 0, fmt.Errorf("failed to commit requeued dead letters: %w", err)
+	}
+
+	return len(messages), nil
+}
+
+func deadLetterFromMessage(message queue.Message) DeadLetter {
+	deadLetter := DeadLetter{
+		ID:            message.ID,
+		OriginalTopic: message.Headers[queue.DeadLetterOriginalTopicHeader],
+		Key:           string(message.Key),
+		Value:         string(message.Value),
+		Error:         message.Headers[queue.DeadLetterErrorHeader],
+	}
+
+	deadLetter.Attempts, _ = strconv.Atoi(message.Headers[queue.DeadLetterAttemptsHeader])
+	deadLetter.FailedAt, _ = time.Parse(time.RFC3339, message.Headers[queue.DeadLetterFailedAtHeader])
+
+	return deadLetter
+}
+
+// NewDeadLetterQueue creates a new DeadLetterQueue on the queue.
+func NewDeadLetterQueue(q queue.Queue) *DeadLetterQueue {
+	return &DeadLetterQueue{
+		queue: q,
+	}
+}
@@ -0,0 +1,81 @@
+package drug
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/turfaa/vmedis-proxy-api/cui"
+	"github.com/turfaa/vmedis-proxy-api/pkg2/queue"
+)
+
+// TestRequeueDeadLetters checks that the dead letters are listed until they're requeued from the API,
+// and that requeueing produces them back to their original topics.
+func TestRequeueDeadLetters(t *testing.T) {
+	ctx := context.Background()
+	q := queue.NewMemory()
+
+	message := queue.Message{Topic: VmedisCodeUpdatedTopic, Key: []byte("SANMOL"), Value: []byte(`{"vmedisCode":"SANMOL"}`)}
+	if err := q.Publish(ctx, queue.NewDeadLetter(DeadLetterTopic, message, errors.New("vmedis is down"), 4, time.Now())); err != nil {
+		t.Fatalf("publish dead letter: %s", err)
+	}
+
+	handler := NewApiHandler(ApiHandlerConfig{Service: &Service{deadLetters: NewDeadLetterQueue(q)}})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	// Mirrors the route registration in proxy/api.go, without auth middleware.
+	router.GET("/drugs/dead-letters", handler.GetDeadLetters)
+	router.POST("/drugs/dead-letters/requeue", handler.RequeueDeadLetters)
+
+	do := func(method, path string) (int, string) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w.Code, w.Body.String()
+	}
+
+	code, body := do("GET", "/drugs/dead-letters")
+	var table cui.Table
+	if err := json.Unmarshal([]byte(body), &table); err != nil {
+		t.Fatalf("unmarshal %s: %s", body, err)
+	}
+	if code != 200 || len(table.Rows) != 1 {
+		t.Fatalf("dead letters: got code %d, body %s", code, body)
+	}
+	if columns := table.Rows[0].Columns; columns[0] != VmedisCodeUpdatedTopic || columns[1] != "SANMOL" || columns[2] != "vmedis is down" || columns[3] != "4" {
+		t.Errorf("dead letter row = %q", columns)
+	}
+
+	code, body = do("POST", "/drugs/dead-letters/requeue")
+	if code != 200 || body != `{"requeued":1}` {
+		t.Fatalf("requeue: got code %d, body %s", code, body)
+	}
+
+	code, body = do("GET", "/drugs/dead-letters")
+	if code != 200 || body != `{"header":["Topik","Kunci","Error","Jumlah Percobaan","Gagal Pada"],"rows":[]}` {
+		t.Errorf("dead letters after requeue: got code %d, body %s", code, body)
+	}
+
+	subscription, err := q.Subscribe(ctx, VmedisCodeUpdatedTopic, ConsumerGroupID)
+	if err != nil {
+		t.Fatalf("subscribe: %s", err)
+	}
+	defer subscription.Close()
+
+	fetchCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+
+	requeued, err := subscription.Fetch(fetchCtx)
+	if err != nil {
+		t.Fatalf("fetch requeued message: %s", err)
+	}
+	if string(requeued.Key) != "SANMOL" || string(requeued.Value) != string(message.Value) {
+		t.Errorf("requeued message = %+v, want the original message", requeued)
+	}
+}
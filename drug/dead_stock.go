@@ -0,0 +1,231 @@
+package drug
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+
+	"github.com/turfaa/vmedis-proxy-api/pkg2/slices2"
+)
+
+const deadStocksSheetName = "Stok Mati"
+
+// GetDeadStocks returns the drugs in stock that were sold at most request.MaxSales times in the last request.Days days,
+// sorted by the stock value descending, so the drugs tying up the most money come first.
+func (s *Service) GetDeadStocks(ctx context.Context, request DeadStocksRequest) ([]DeadStock, error) {
+	days := request.Days
+	if days <= 0 {
+		days = defaultDeadStockDays
+	}
+
+	drugs, err := s.db.GetStockedDrugs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get stocked drugs: %w", err)
+	}
+
+	if len(drugs) == 0 {
+		return nil, nil
+	}
+
+	now := time.Now()
+	saleStatistics, err := s.db.GetDrugSaleStatisticsBetweenTimes(ctx, now.AddDate(0, 0, -days), now)
+	if err != nil {
+		return nil, fmt.Errorf("get drug sale statistics of the last %d days: %w", days, err)
+	}
+
+	numberOfSales := make(map[string]int, len(saleStatistics))
+	for _, stat := range saleStatistics {
+		numberOfSales[stat.DrugCode] = stat.NumberOfSales
+	}
+
+	var deadStocks []DeadStock
+	for _, drug := range drugs {
+		if numberOfSales[drug.VmedisCode] > request.MaxSales {
+			continue
+		}
+
+		deadStocks = append(deadStocks, DeadStock{
+			DrugVmedisCode: drug.VmedisCode,
+			DrugName:       drug.Name,
+			Stocks: slices2.Filter(slices2.Map(drug.Stocks, FromDBDrugStock), func(stock Stock) bool {
+				return stock.Quantity > 0
+			}),
+			NumberOfSales: numberOfSales[drug.VmedisCode],
+		})
+	}
+
+	if err := s.fillDeadStocksHistory(ctx, deadStocks); err != nil {
+		return nil, err
+	}
+
+	slices.SortStableFunc(deadStocks, func(a, b DeadStock) int {
+		return cmp.Compare(deadStockValue(b), deadStockValue(a))
+	})
+
+	return deadStocks, nil
+}
+
+// fillDeadStocksHistory fills the last sale, the last procurement, and the stock value of the dead stocks.
+func (s *Service) fillDeadStocksHistory(ctx context.Context, deadStocks []DeadStock) error {
+	drugCodes := slices2.Map(deadStocks, func(d DeadStock) string { return d.DrugVmedisCode })
+
+	lastSales, err := s.db.getLastSales(ctx, drugCodes)
+	if err != nil {
+		return fmt.Errorf("get last sales: %w", err)
+	}
+
+	lastProcurements, err := s.db.getLastProcurements(ctx, drugCodes)
+	if err != nil {
+		return fmt.Errorf("get last procurements: %w", err)
+	}
+
+	units, err := s.db.GetDrugUnitsByDrugVmedisCodes(ctx, drugCodes)
+	if err != nil {
+		return fmt.Errorf("get drug units: %w", err)
+	}
+
+	for i := range deadStocks {
+		deadStock := &deadStocks[i]
+
+		if sale, ok := lastSales[deadStock.DrugVmedisCode]; ok {
+			deadStock.LastSoldAt = &sale.SoldAt
+		}
+
+		procurement, ok := lastProcurements[deadStock.DrugVmedisCode]
+		if !ok {
+			continue
+		}
+
+		deadStock.LastProcuredAt = &procurement.InvoiceDate
+
+		value, ok := calculateStockValue(deadStock.Stocks, units[deadStock.DrugVmedisCode], procurement)
+		if !ok {
+			log.Printf("Can't calculate the stock value of drug %s: procurement unit %s can't be converted into its stock units", deadStock.DrugVmedisCode, procurement.Unit)
+			continue
+		}
+
+		deadStock.StockValue = &value
+	}
+
+	return nil
+}
+
+// calculateStockValue converts the procurement price into each stock unit and sums up the stock value.
+func calculateStockValue(stocks []Stock, units []Unit, procurement drugLastProcurement) (float64, bool) {
+	sizes, ok := UnitSizes(units)
+	if !ok {
+		return 0, false
+	}
+
+	procurementUnitSize := sizes[strings.ToLower(procurement.Unit)]
+	if procurementUnitSize == 0 {
+		return 0, false
+	}
+
+	price := procurement.UnitTaxedPrice
+	if price <= 0 {
+		price = procurement.TotalUnitPrice
+	}
+
+	value := 0.0
+	for _, stock := range stocks {
+		stockUnitSize := sizes[strings.ToLower(stock.Unit)]
+		if stockUnitSize == 0 {
+			return 0, false
+		}
+
+		value += stock.Quantity * stockUnitSize / procurementUnitSize * price
+	}
+
+	return value, true
+}
+
+func deadStockValue(deadStock DeadStock) float64 {
+	if deadStock.StockValue == nil {
+		return 0
+	}
+
+	return *deadStock.StockValue
+}
+
+// WriteDeadStocksXLSX writes the dead stocks as an XLSX file.
+func WriteDeadStocksXLSX(w io.Writer, deadStocks []DeadStock) error {
+	f := excelize.NewFile()
+	defer func() {
+		if err := f.Close(); err != nil {
+			log.Printf("failed to close file: %s", err)
+		}
+	}()
+
+	if _, err := f.NewSheet(deadStocksSheetName); err != nil {
+		return fmt.Errorf("create excel sheet: %w", err)
+	}
+
+	var errs []error
+
+	header := []string{
+		"Kode Obat",
+		"Nama Obat",
+		"Stok",
+		"Nilai Stok",
+		"Jumlah Penjualan",
+		"Penjualan Terakhir",
+		"Pembelian Terakhir",
+	}
+	for i, h := range header {
+		errs = append(errs, f.SetCellStr(deadStocksSheetName, deadStocksCell(i, 1), h))
+	}
+
+	for i, deadStock := range deadStocks {
+		row := i + 2
+
+		errs = append(errs, f.SetCellStr(deadStocksSheetName, deadStocksCell(0, row), deadStock.DrugVmedisCode))
+		errs = append(errs, f.SetCellStr(deadStocksSheetName, deadStocksCell(1, row), deadStock.DrugName))
+		errs = append(errs, f.SetCellStr(deadStocksSheetName, deadStocksCell(2, row), formatStocks(deadStock.Stocks)))
+		if deadStock.StockValue != nil {
+			errs = append(errs, f.SetCellFloat(deadStocksSheetName, deadStocksCell(3, row), *deadStock.StockValue, 2, 64))
+		}
+		errs = append(errs, f.SetCellInt(deadStocksSheetName, deadStocksCell(4, row), int64(deadStock.NumberOfSales)))
+		errs = append(errs, f.SetCellStr(deadStocksSheetName, deadStocksCell(5, row), formatOptionalDate(deadStock.LastSoldAt)))
+		errs = append(errs, f.SetCellStr(deadStocksSheetName, deadStocksCell(6, row), formatOptionalDate(deadStock.LastProcuredAt)))
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("fill dead stocks sheet: %w", err)
+	}
+
+	if err := f.DeleteSheet("Sheet1"); err != nil {
+		return fmt.Errorf("delete default XLSX sheet: %w", err)
+	}
+
+	if err := f.Write(w); err != nil {
+		return fmt.Errorf("write XLSX: %w", err)
+	}
+
+	return nil
+}
+
+func deadStocksCell(column int, row int) string {
+	cell, _ := excelize.CoordinatesToCellName(column+1, row)
+	return cell
+}
+
+func formatStocks(stocks []Stock) string {
+	return strings.Join(slices2.Map(stocks, Stock.String), " ")
+}
+
+func formatOptionalDate(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+
+	return t.Format("2006-01-02")
+}
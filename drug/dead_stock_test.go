@@ -0,0 +1,150 @@
+package drug_test
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+	"gorm.io/datatypes"
+
+	"github.com/turfaa/vmedis-proxy-api/auth"
+	"github.com/turfaa/vmedis-proxy-api/cui"
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+	"github.com/turfaa/vmedis-proxy-api/drug"
+)
+
+// TestDeadStocks checks that only the stocked drugs that barely sold are reported,
+// valued at their latest procurement price, with the most valuable stock first.
+func TestDeadStocks(t *testing.T) {
+	db, service, router := setup(t)
+	ctx := context.Background()
+
+	now := time.Now()
+	lastYear := now.AddDate(-1, 0, 0)
+
+	for _, d := range []models.Drug{
+		{VmedisID: 1, VmedisCode: "SANMOL", Name: "SANMOL 500MG"},
+		{VmedisID: 2, VmedisCode: "BODREX", Name: "BODREX"},
+		{VmedisID: 3, VmedisCode: "MIXAGRIP", Name: "MIXAGRIP"},
+		{VmedisID: 4, VmedisCode: "PROMAG", Name: "PROMAG"},
+		{VmedisID: 5, VmedisCode: "EMPTY", Name: "EMPTY"},
+	} {
+		mustCreate(t, db, &d)
+	}
+
+	mustCreate(t, db, &[]models.DrugUnit{
+		{DrugVmedisCode: "SANMOL", Unit: "Tablet"},
+		{DrugVmedisCode: "SANMOL", Unit: "Strip", ParentUnit: "Tablet", ConversionToParentUnit: 10, UnitOrder: 1},
+		{DrugVmedisCode: "SANMOL", Unit: "Box", ParentUnit: "Strip", ConversionToParentUnit: 10, UnitOrder: 2},
+		{DrugVmedisCode: "BODREX", Unit: "Strip"},
+		{DrugVmedisCode: "MIXAGRIP", Unit: "Strip"},
+		{DrugVmedisCode: "PROMAG", Unit: "Strip"},
+	})
+
+	mustCreate(t, db, &[]models.DrugStock{
+		{DrugVmedisCode: "SANMOL", Stock: models.Stock{Unit: "Box", Quantity: 2}},
+		{DrugVmedisCode: "SANMOL", Stock: models.Stock{Unit: "Strip", Quantity: 5}},
+		{DrugVmedisCode: "BODREX", Stock: models.Stock{Unit: "Strip", Quantity: 3}},
+		{DrugVmedisCode: "MIXAGRIP", Stock: models.Stock{Unit: "Strip", Quantity: 10}},
+		{DrugVmedisCode: "PROMAG", Stock: models.Stock{Unit: "Strip", Quantity: 1}},
+		{DrugVmedisCode: "EMPTY", Stock: models.Stock{Unit: "Strip", Quantity: 0}},
+	})
+
+	// SANMOL was last sold a year ago, BODREX sold once recently, MIXAGRIP sold twice recently,
+	// and PROMAG was never sold nor procured.
+	mustCreate(t, db, &[]models.Sale{
+		{InvoiceNumber: "S-1", SoldAt: lastYear, SaleUnits: []models.SaleUnit{{IDInSale: 1, DrugCode: "SANMOL", Amount: 1, Unit: "Strip"}}},
+		{InvoiceNumber: "S-2", SoldAt: now.AddDate(0, 0, -3), SaleUnits: []models.SaleUnit{
+			{IDInSale: 1, DrugCode: "BODREX", Amount: 1, Unit: "Strip"},
+			{IDInSale: 2, DrugCode: "MIXAGRIP", Amount: 1, Unit: "Strip"},
+		}},
+		{InvoiceNumber: "S-3", SoldAt: now.AddDate(0, 0, -2), SaleUnits: []models.SaleUnit{{IDInSale: 1, DrugCode: "MIXAGRIP", Amount: 1, Unit: "Strip"}}},
+	})
+
+	mustCreate(t, db, &[]models.Procurement{
+		{InvoiceNumber: "P-1", InvoiceDate: datatypes.Date(lastYear.AddDate(0, -1, 0)), ProcurementUnits: []models.ProcurementUnit{
+			{IDInProcurement: 1, DrugCode: "SANMOL", Unit: "Box", TotalUnitPrice: 30000},
+			{IDInProcurement: 2, DrugCode: "BODREX", Unit: "Strip", TotalUnitPrice: 2000, UnitTaxedPrice: 2200},
+		}},
+		{InvoiceNumber: "P-2", InvoiceDate: datatypes.Date(lastYear.AddDate(0, 0, -7)), ProcurementUnits: []models.ProcurementUnit{
+			{IDInProcurement: 1, DrugCode: "SANMOL", Unit: "Strip", TotalUnitPrice: 4000},
+		}},
+	})
+
+	deadStocks, err := service.GetDeadStocks(ctx, drug.DeadStocksRequest{Days: 30})
+	if err != nil {
+		t.Fatalf("get dead stocks: %s", err)
+	}
+
+	if len(deadStocks) != 2 || deadStocks[0].DrugVmedisCode != "SANMOL" || deadStocks[1].DrugVmedisCode != "PROMAG" {
+		t.Fatalf("get dead stocks: expected SANMOL and PROMAG, got %+v", deadStocks)
+	}
+
+	// 2 boxes and 5 strips at the latest price of Rp 4.000 per strip.
+	sanmol := deadStocks[0]
+	if sanmol.StockValue == nil || *sanmol.StockValue != 100000 {
+		t.Fatalf("SANMOL stock value: got %+v", sanmol)
+	}
+	if sanmol.LastSoldAt == nil || !sanmol.LastSoldAt.Equal(lastYear) {
+		t.Fatalf("SANMOL last sold at: got %v", sanmol.LastSoldAt)
+	}
+	if sanmol.LastProcuredAt == nil || sanmol.LastProcuredAt.Format("2006-01-02") != lastYear.AddDate(0, 0, -7).Format("2006-01-02") {
+		t.Fatalf("SANMOL last procured at: got %v", sanmol.LastProcuredAt)
+	}
+
+	promag := deadStocks[1]
+	if promag.StockValue != nil || promag.LastSoldAt != nil || promag.LastProcuredAt != nil {
+		t.Fatalf("PROMAG: expected no history, got %+v", promag)
+	}
+
+	do := func(path string) (int, *httptest.ResponseRecorder) {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("X-Role", string(auth.RoleAdmin))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code, w
+	}
+
+	code, w := do("/drugs/dead-stocks?days=30&maxSales=1")
+	if code != 200 {
+		t.Fatalf("dead stocks table: got code %d, body %s", code, w.Body.String())
+	}
+
+	table := unmarshal[cui.Table](t, w.Body.String())
+	if len(table.Rows) != 3 || table.Rows[1].ID != "BODREX" {
+		t.Fatalf("dead stocks table: expected SANMOL, BODREX, and PROMAG, got %s", w.Body.String())
+	}
+	if got := table.Rows[1].Columns; got[1] != "3 Strip" || got[2] != "Rp 6.600" || got[3] != "1" {
+		t.Fatalf("dead stocks table: unexpected BODREX row %v", got)
+	}
+	if table.Rows[2].Columns[2] != "-" || table.Footer[2] != "Rp 106.600" {
+		t.Fatalf("dead stocks table: unexpected PROMAG row %v or footer %v", table.Rows[2].Columns, table.Footer)
+	}
+
+	code, w = do("/drugs/dead-stocks?days=abc")
+	if code != 400 {
+		t.Fatalf("invalid days: got code %d, body %s", code, w.Body.String())
+	}
+
+	code, w = do("/drugs/dead-stocks/xlsx?days=30")
+	if code != 200 {
+		t.Fatalf("export dead stocks: got code %d, body %s", code, w.Body.String())
+	}
+
+	f, err := excelize.OpenReader(bytes.NewReader(w.Body.Bytes()))
+	if err != nil {
+		t.Fatalf("open exported dead stocks: %s", err)
+	}
+	defer f.Close()
+
+	rows, err := f.GetRows("Stok Mati")
+	if err != nil {
+		t.Fatalf("read exported dead stocks: %s", err)
+	}
+	if len(rows) != 3 || rows[1][0] != "SANMOL" || rows[1][2] != "2 Box 5 Strip" || rows[1][3] != "100000" {
+		t.Fatalf("export dead stocks: got rows %v", rows)
+	}
+}
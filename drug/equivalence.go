@@ -0,0 +1,266 @@
+package drug
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+	"github.com/turfaa/vmedis-proxy-api/pkg2/slices2"
+)
+
+// maxDrugsMatchedByName is the maximum number of drugs looked up by name when suggesting substitutes.
+const maxDrugsMatchedByName = 20
+
+// ErrUnknownDrugCodes is returned when an equivalence group refers to drugs that don't exist.
+var ErrUnknownDrugCodes = errors.New("unknown drug codes")
+
+func (s *Service) GetEquivalenceGroups(ctx context.Context) ([]EquivalenceGroup, error) {
+	groups, err := s.db.GetEquivalenceGroups(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get equivalence groups: %w", err)
+	}
+
+	return slices2.Map(groups, FromDBEquivalenceGroup), nil
+}
+
+func (s *Service) GetEquivalenceGroupByID(ctx context.Context, id uint) (EquivalenceGroup, error) {
+	group, err := s.db.GetEquivalenceGroupByID(ctx, id)
+	if err != nil {
+		return EquivalenceGroup{}, fmt.Errorf("get equivalence group %d: %w", id, err)
+	}
+
+	return FromDBEquivalenceGroup(group), nil
+}
+
+// GetDrugNamesByVmedisCodes returns the drug names by their vmedis codes.
+func (s *Service) GetDrugNamesByVmedisCodes(ctx context.Context, vmedisCodes []string) (map[string]string, error) {
+	return s.db.GetDrugNamesByVmedisCodes(ctx, vmedisCodes)
+}
+
+func (s *Service) CreateEquivalenceGroup(ctx context.Context, request CreateEquivalenceGroupRequest) (EquivalenceGroup, error) {
+	drugCodes := parseDrugCodes(request.DrugCodes)
+	if err := s.ensureDrugsExist(ctx, drugCodes); err != nil {
+		return EquivalenceGroup{}, err
+	}
+
+	group, err := s.db.CreateEquivalenceGroup(ctx, models.DrugEquivalenceGroup{
+		Name:             strings.TrimSpace(request.Name),
+		ActiveIngredient: strings.TrimSpace(request.ActiveIngredient),
+		Strength:         strings.TrimSpace(request.Strength),
+		DosageForm:       strings.TrimSpace(request.DosageForm),
+	}, drugCodes)
+	if err != nil {
+		return EquivalenceGroup{}, fmt.Errorf("create equivalence group: %w", err)
+	}
+
+	return s.GetEquivalenceGroupByID(ctx, group.ID)
+}
+
+func (s *Service) UpdateEquivalenceGroup(ctx context.Context, id uint, request UpdateEquivalenceGroupRequest) (EquivalenceGroup, error) {
+	group, err := s.db.GetEquivalenceGroupByID(ctx, id)
+	if err != nil {
+		return EquivalenceGroup{}, fmt.Errorf("get equivalence group %d: %w", id, err)
+	}
+
+	if request.Name != nil {
+		group.Name = strings.TrimSpace(*request.Name)
+	}
+
+	if request.ActiveIngredient != nil {
+		group.ActiveIngredient = strings.TrimSpace(*request.ActiveIngredient)
+	}
+
+	if request.Strength != nil {
+		group.Strength = strings.TrimSpace(*request.Strength)
+	}
+
+	if request.DosageForm != nil {
+		group.DosageForm = strings.TrimSpace(*request.DosageForm)
+	}
+
+	var drugCodes []string
+	if request.DrugCodes != nil {
+		drugCodes = parseDrugCodes(*request.DrugCodes)
+		if err := s.ensureDrugsExist(ctx, drugCodes); err != nil {
+			return EquivalenceGroup{}, err
+		}
+	}
+
+	if err := s.db.UpdateEquivalenceGroup(ctx, group, drugCodes); err != nil {
+		return EquivalenceGroup{}, fmt.Errorf("update equivalence group %d: %w", id, err)
+	}
+
+	return s.GetEquivalenceGroupByID(ctx, id)
+}
+
+func (s *Service) DeleteEquivalenceGroup(ctx context.Context, id uint) error {
+	if err := s.db.DeleteEquivalenceGroup(ctx, id); err != nil {
+		return fmt.Errorf("delete equivalence group %d: %w", id, err)
+	}
+
+	return nil
+}
+
+// GetSubstitutes returns the in-stock drugs that are equivalent to the given drug, sorted by name.
+func (s *Service) GetSubstitutes(ctx context.Context, vmedisCode string) ([]Drug, error) {
+	return s.getInStockEquivalents(ctx, []string{vmedisCode})
+}
+
+// GetSubstitutesByDrugName returns the in-stock drugs that are equivalent to
+// the drugs whose name contains the given name, sorted by name.
+func (s *Service) GetSubstitutesByDrugName(ctx context.Context, name string) ([]Drug, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, nil
+	}
+
+	codes, err := s.db.GetDrugVmedisCodesByName(ctx, name, maxDrugsMatchedByName)
+	if err != nil {
+		return nil, fmt.Errorf("get drugs named like %s: %w", name, err)
+	}
+
+	return s.getInStockEquivalents(ctx, codes)
+}
+
+func (s *Service) getInStockEquivalents(ctx context.Context, vmedisCodes []string) ([]Drug, error) {
+	equivalentCodes, err := s.db.GetEquivalentDrugCodes(ctx, vmedisCodes)
+	if err != nil {
+		return nil, fmt.Errorf("get drugs equivalent to %v: %w", vmedisCodes, err)
+	}
+
+	if len(equivalentCodes) == 0 {
+		return nil, nil
+	}
+
+	drugs, err := s.GetDrugsByVmedisCodes(ctx, equivalentCodes)
+	if err != nil {
+		return nil, fmt.Errorf("get drugs %v: %w", equivalentCodes, err)
+	}
+
+	inStock := slices2.Filter(drugs, func(drug Drug) bool {
+		return slices.ContainsFunc(drug.Stocks, func(stock Stock) bool {
+			return stock.Quantity > 0
+		})
+	})
+
+	slices.SortFunc(inStock, func(a, b Drug) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return inStock, nil
+}
+
+// SeedEquivalenceGroupsFromKFA groups the drugs with a KFA code by the active ingredient,
+// strength, and dosage form of their KFA product.
+// Drugs that already belong to a group are left as they are, so the groups edited by admins are kept.
+func (s *Service) SeedEquivalenceGroupsFromKFA(ctx context.Context) (SeedEquivalenceGroupsResult, error) {
+	equivalences, err := s.db.getKFAEquivalences(ctx)
+	if err != nil {
+		return SeedEquivalenceGroupsResult{}, fmt.Errorf("get KFA equivalences: %w", err)
+	}
+
+	groups, err := s.db.GetEquivalenceGroups(ctx)
+	if err != nil {
+		return SeedEquivalenceGroupsResult{}, fmt.Errorf("get equivalence groups: %w", err)
+	}
+
+	groupIDByKey := make(map[string]uint, len(groups))
+	groupedDrugs := make(map[string]bool)
+	for _, group := range groups {
+		groupIDByKey[equivalenceKey(group.ActiveIngredient, group.Strength, group.DosageForm)] = group.ID
+		for _, member := range group.Members {
+			groupedDrugs[member.DrugVmedisCode] = true
+		}
+	}
+
+	var (
+		keys                []string
+		equivalenceByKey    = make(map[string]kfaEquivalence)
+		ungroupedCodesByKey = make(map[string][]string)
+	)
+	for _, equivalence := range equivalences {
+		if groupedDrugs[equivalence.VmedisCode] {
+			continue
+		}
+
+		key := equivalenceKey(equivalence.ActiveIngredient, equivalence.Strength, equivalence.DosageForm)
+		if _, ok := equivalenceByKey[key]; !ok {
+			keys = append(keys, key)
+			equivalenceByKey[key] = equivalence
+		}
+
+		ungroupedCodesByKey[key] = append(ungroupedCodesByKey[key], equivalence.VmedisCode)
+	}
+
+	var result SeedEquivalenceGroupsResult
+	for _, key := range keys {
+		codes := ungroupedCodesByKey[key]
+
+		if groupID, ok := groupIDByKey[key]; ok {
+			if err := s.db.AddEquivalenceGroupMembers(ctx, groupID, codes); err != nil {
+				return result, fmt.Errorf("add drugs to equivalence group %d: %w", groupID, err)
+			}
+
+			result.AddedDrugs += len(codes)
+			continue
+		}
+
+		equivalence := equivalenceByKey[key]
+		if _, err := s.db.CreateEquivalenceGroup(ctx, models.DrugEquivalenceGroup{
+			Name:             equivalenceGroupName(equivalence),
+			ActiveIngredient: equivalence.ActiveIngredient,
+			Strength:         equivalence.Strength,
+			DosageForm:       equivalence.DosageForm,
+		}, codes); err != nil {
+			return result, fmt.Errorf("create equivalence group %s: %w", key, err)
+		}
+
+		result.CreatedGroups++
+		result.AddedDrugs += len(codes)
+	}
+
+	return result, nil
+}
+
+func (s *Service) ensureDrugsExist(ctx context.Context, vmedisCodes []string) error {
+	existing, err := s.db.GetExistingDrugVmedisCodes(ctx, vmedisCodes)
+	if err != nil {
+		return fmt.Errorf("get existing drugs: %w", err)
+	}
+
+	var unknown []string
+	for _, code := range vmedisCodes {
+		if !slices.Contains(existing, code) {
+			unknown = append(unknown, code)
+		}
+	}
+
+	if len(unknown) > 0 {
+		return fmt.Errorf("%w: %s", ErrUnknownDrugCodes, strings.Join(unknown, ", "))
+	}
+
+	return nil
+}
+
+func equivalenceKey(activeIngredient, strength, dosageForm string) string {
+	normalize := func(s string) string {
+		return strings.Join(strings.Fields(strings.ToLower(s)), " ")
+	}
+
+	return normalize(activeIngredient) + "|" + normalize(strength) + "|" + normalize(dosageForm)
+}
+
+func equivalenceGroupName(equivalence kfaEquivalence) string {
+	parts := []string{equivalence.ActiveIngredient}
+	if equivalence.Strength != "" {
+		parts = append(parts, equivalence.Strength)
+	}
+	if equivalence.DosageForm != "" {
+		parts = append(parts, equivalence.DosageForm)
+	}
+
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,144 @@
+package drug_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/turfaa/vmedis-proxy-api/auth"
+	"github.com/turfaa/vmedis-proxy-api/cui"
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+	"github.com/turfaa/vmedis-proxy-api/drug"
+)
+
+// TestEquivalenceGroupsAndSubstitutes seeds equivalence groups from KFA
+// codes, edits them like an admin would, and checks that the substitutes
+// endpoint only lists in-stock equivalents with the caller's prices.
+func TestEquivalenceGroupsAndSubstitutes(t *testing.T) {
+	db, service, router := setup(t)
+
+	for _, product := range []models.KFAProduct{
+		{Code: "K1", Name: "Paracetamol 500 mg Tablet", ActiveIngredient: "Paracetamol", Strength: "500 mg", DosageForm: "Tablet"},
+		{Code: "K2", Name: "Sanmol 500 mg Tablet", ActiveIngredient: "paracetamol", Strength: "500 MG", DosageForm: "tablet"},
+		{Code: "K3", Name: "Paracetamol Sirup", ActiveIngredient: "Paracetamol", Strength: "120 mg/5 ml", DosageForm: "Sirup"},
+	} {
+		mustCreate(t, db, &product)
+	}
+
+	for _, d := range []models.Drug{
+		{VmedisID: 1, VmedisCode: "PCT", KFACode: "K1", Name: "PARACETAMOL 500MG"},
+		{VmedisID: 2, VmedisCode: "SANMOL", KFACode: "K2", Name: "SANMOL 500MG"},
+		{VmedisID: 3, VmedisCode: "PCT-GENERIK", KFACode: "K1", Name: "PARACETAMOL GENERIK 500MG"},
+		{VmedisID: 4, VmedisCode: "PCT-SYR", KFACode: "K3", Name: "PARACETAMOL SIRUP"},
+		{VmedisID: 5, VmedisCode: "BODREX", Name: "BODREX"},
+	} {
+		mustCreate(t, db, &d)
+	}
+
+	mustCreate(t, db, &models.DrugUnit{DrugVmedisCode: "SANMOL", Unit: "Strip", PriceOne: 5000, PriceTwo: 4500, PriceThree: 6000})
+	mustCreate(t, db, &models.DrugStock{DrugVmedisCode: "SANMOL", Stock: models.Stock{Unit: "Strip", Quantity: 3}})
+	mustCreate(t, db, &models.DrugUnit{DrugVmedisCode: "BODREX", Unit: "Strip", PriceOne: 4000})
+	mustCreate(t, db, &models.DrugStock{DrugVmedisCode: "BODREX", Stock: models.Stock{Unit: "Strip", Quantity: 10}})
+
+	result, err := service.SeedEquivalenceGroupsFromKFA(context.Background())
+	if err != nil {
+		t.Fatalf("seed from KFA: %s", err)
+	}
+	if result.CreatedGroups != 2 || result.AddedDrugs != 4 {
+		t.Fatalf("seed from KFA: got %+v, want 2 groups with 4 drugs", result)
+	}
+
+	do := func(role auth.Role, method, path, body string) (int, string) {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("X-Role", string(role))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code, w.Body.String()
+	}
+
+	// PCT-GENERIK is out of stock, so SANMOL is the only substitute.
+	code, body := do(auth.RoleGuest, "GET", "/drugs/PCT/substitutes", "")
+	if code != 200 {
+		t.Fatalf("substitutes: got code %d, body %s", code, body)
+	}
+	substitutes := unmarshal[drug.DrugsResponseV2](t, body)
+	if len(substitutes.Drugs) != 1 || substitutes.Drugs[0].VmedisCode != "SANMOL" {
+		t.Fatalf("substitutes: expected only SANMOL, got %s", body)
+	}
+	if strings.Contains(body, "Harga Diskon") || !strings.Contains(body, "Harga Normal") {
+		t.Fatalf("substitutes: guest should only see the normal price: %s", body)
+	}
+
+	code, body = do(auth.RoleStaff, "GET", "/drugs/PCT/substitutes", "")
+	if code != 200 || !strings.Contains(body, "Harga Resep") {
+		t.Fatalf("substitutes as staff: expected the prescription price, got code %d, body %s", code, body)
+	}
+
+	code, body = do(auth.RoleAdmin, "GET", "/drugs/equivalence-groups", "")
+	if code != 200 {
+		t.Fatalf("list groups: got code %d, body %s", code, body)
+	}
+	groups := unmarshal[cui.Table](t, body)
+	if len(groups.Rows) != 2 {
+		t.Fatalf("list groups: expected 2 groups, got %s", body)
+	}
+
+	var tabletGroupID string
+	for _, row := range groups.Rows {
+		if row.Columns[4] == "3" {
+			tabletGroupID = row.ID
+		}
+	}
+	if tabletGroupID == "" {
+		t.Fatalf("list groups: expected a group with the three tablets, got %s", body)
+	}
+
+	code, body = do(auth.RoleAdmin, "GET", "/drugs/equivalence-groups/"+tabletGroupID+"/form", "")
+	if code != 200 {
+		t.Fatalf("update form: got code %d, body %s", code, body)
+	}
+	form := unmarshal[cui.Form](t, body)
+	if form.Fields[len(form.Fields)-1].ID != "drugCodes" || !strings.Contains(form.Fields[len(form.Fields)-1].Value, "SANMOL") {
+		t.Fatalf("update form: drug codes not prefilled: %s", body)
+	}
+
+	code, body = do(auth.RoleAdmin, "PATCH", "/drugs/equivalence-groups/"+tabletGroupID, `{"drugCodes": "PCT\nUNKNOWN"}`)
+	if code != 400 {
+		t.Fatalf("update with unknown drug: got code %d, body %s", code, body)
+	}
+
+	code, body = do(auth.RoleAdmin, "PATCH", "/drugs/equivalence-groups/"+tabletGroupID, `{"drugCodes": "PCT, PCT-GENERIK, BODREX"}`)
+	if code != 200 {
+		t.Fatalf("update members: got code %d, body %s", code, body)
+	}
+
+	code, body = do(auth.RoleGuest, "GET", "/drugs/PCT/substitutes", "")
+	if code != 200 {
+		t.Fatalf("substitutes after update: got code %d, body %s", code, body)
+	}
+	substitutes = unmarshal[drug.DrugsResponseV2](t, body)
+	if len(substitutes.Drugs) != 1 || substitutes.Drugs[0].VmedisCode != "BODREX" {
+		t.Fatalf("substitutes after update: expected only BODREX, got %s", body)
+	}
+
+	// Seeding again must not move SANMOL back, because it is no longer in the
+	// edited group but it still has the same KFA attributes.
+	result, err = service.SeedEquivalenceGroupsFromKFA(context.Background())
+	if err != nil {
+		t.Fatalf("seed again: %s", err)
+	}
+	if result.CreatedGroups != 0 || result.AddedDrugs != 1 {
+		t.Fatalf("seed again: got %+v, want SANMOL to be added back to the existing group", result)
+	}
+
+	code, body = do(auth.RoleAdmin, "DELETE", "/drugs/equivalence-groups/"+tabletGroupID, "")
+	if code != 200 {
+		t.Fatalf("delete: got code %d, body %s", code, body)
+	}
+
+	code, body = do(auth.RoleGuest, "GET", "/drugs/PCT/substitutes", "")
+	if code != 200 || len(unmarshal[drug.DrugsResponseV2](t, body).Drugs) != 0 {
+		t.Fatalf("substitutes after delete: expected none, got code %d, body %s", code, body)
+	}
+}
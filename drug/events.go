@@ -0,0 +1,82 @@
+package drug
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+	"github.com/turfaa/vmedis-proxy-api/kafkapb"
+)
+
+// priceChangedEvents returns the events of the units of the drug whose prices differ from before.
+// The before drug is empty if the drug is new.
+func priceChangedEvents(before models.Drug, after models.Drug) []*kafkapb.DrugPriceChanged {
+	beforeUnits := make(map[string]models.DrugUnit, len(before.Units))
+	for _, unit := range before.Units {
+		beforeUnits[unit.Unit] = unit
+	}
+
+	var changes []*kafkapb.DrugPriceChanged
+	for _, unit := range after.Units {
+		event := &kafkapb.DrugPriceChanged{
+			VmedisCode: after.VmedisCode,
+			Unit:       unit.Unit,
+			After:      unitPricesEvent(unit),
+		}
+
+		if beforeUnit, ok := beforeUnits[unit.Unit]; ok {
+			if beforeUnit.PriceOne == unit.PriceOne && beforeUnit.PriceTwo == unit.PriceTwo && beforeUnit.PriceThree == unit.PriceThree {
+				continue
+			}
+
+			event.Before = unitPricesEvent(beforeUnit)
+		}
+
+		changes = append(changes, event)
+	}
+
+	return changes
+}
+
+// stockChangedEvent returns the event of the drug if its stocks differ from before, or nil otherwise.
+// The before drug is empty if the drug is new.
+func stockChangedEvent(before models.Drug, after models.Drug) *kafkapb.DrugStockChanged {
+	beforeStocks := stocksEvent(before.Stocks)
+	afterStocks := stocksEvent(after.Stocks)
+
+	if slices.EqualFunc(beforeStocks, afterStocks, func(a, b *kafkapb.DrugStock) bool {
+		return a.Unit == b.Unit && a.Quantity == b.Quantity
+	}) {
+		return nil
+	}
+
+	return &kafkapb.DrugStockChanged{
+		VmedisCode: after.VmedisCode,
+		Before:     beforeStocks,
+		After:      afterStocks,
+	}
+}
+
+func unitPricesEvent(unit models.DrugUnit) *kafkapb.DrugUnitPrices {
+	return &kafkapb.DrugUnitPrices{
+		PriceOne:   unit.PriceOne,
+		PriceTwo:   unit.PriceTwo,
+		PriceThree: unit.PriceThree,
+	}
+}
+
+func stocksEvent(stocks []models.DrugStock) []*kafkapb.DrugStock {
+	eventStocks := make([]*kafkapb.DrugStock, 0, len(stocks))
+	for _, stock := range stocks {
+		eventStocks = append(eventStocks, &kafkapb.DrugStock{
+			Unit:     stock.Stock.Unit,
+			Quantity: stock.Stock.Quantity,
+		})
+	}
+
+	slices.SortFunc(eventStocks, func(a, b *kafkapb.DrugStock) int {
+		return strings.Compare(a.Unit, b.Unit)
+	})
+
+	return eventStocks
+}
@@ -0,0 +1,59 @@
+package drug
+
+import (
+	"testing"
+
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+)
+
+// TestDrugChangedEvents checks that only the units whose prices changed get an event,
+// with the before prices unset for new units, and that reordered stocks aren't a change.
+func TestDrugChangedEvents(t *testing.T) {
+	before := models.Drug{
+		VmedisCode: "D1",
+		Units: []models.DrugUnit{
+			{Unit: "tablet", PriceOne: 1000, PriceTwo: 900, PriceThree: 1100},
+			{Unit: "strip", PriceOne: 10000, PriceTwo: 9000, PriceThree: 11000},
+		},
+		Stocks: []models.DrugStock{
+			{Stock: models.Stock{Unit: "tablet", Quantity: 5}},
+			{Stock: models.Stock{Unit: "strip", Quantity: 2}},
+		},
+	}
+
+	after := models.Drug{
+		VmedisCode: "D1",
+		Units: []models.DrugUnit{
+			{Unit: "tablet", PriceOne: 1000, PriceTwo: 900, PriceThree: 1100},
+			{Unit: "strip", PriceOne: 12000, PriceTwo: 9000, PriceThree: 11000},
+			{Unit: "box", PriceOne: 100000, PriceTwo: 90000, PriceThree: 110000},
+		},
+		Stocks: []models.DrugStock{
+			{Stock: models.Stock{Unit: "strip", Quantity: 2}},
+			{Stock: models.Stock{Unit: "tablet", Quantity: 5}},
+		},
+	}
+
+	priceChanges := priceChangedEvents(before, after)
+	if len(priceChanges) != 2 {
+		t.Fatalf("got %d price changes, want 2: %v", len(priceChanges), priceChanges)
+	}
+
+	strip, box := priceChanges[0], priceChanges[1]
+	if strip.Unit != "strip" || strip.GetBefore().GetPriceOne() != 10000 || strip.GetAfter().GetPriceOne() != 12000 {
+		t.Errorf("strip price change = %v, want 10000 -> 12000", strip)
+	}
+	if box.Unit != "box" || box.Before != nil || box.GetAfter().GetPriceOne() != 100000 {
+		t.Errorf("box price change = %v, want a new unit without before prices", box)
+	}
+
+	if stockChanged := stockChangedEvent(before, after); stockChanged != nil {
+		t.Errorf("reordered stocks are reported as changed: %v", stockChanged)
+	}
+
+	after.Stocks[0].Stock.Quantity = 1
+	stockChanged := stockChangedEvent(before, after)
+	if stockChanged == nil || len(stockChanged.Before) != 2 || len(stockChanged.After) != 2 {
+		t.Fatalf("stock change = %v, want the whole stocks before and after", stockChanged)
+	}
+}
@@ -2,6 +2,7 @@ package drug
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"strings"
@@ -9,11 +10,12 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/redis/go-redis/v9"
-	"github.com/segmentio/kafka-go"
 	"google.golang.org/protobuf/encoding/protojson"
 	"gorm.io/gorm"
 
 	"github.com/turfaa/vmedis-proxy-api/kafkapb"
+	"github.com/turfaa/vmedis-proxy-api/pkg2/queue"
+	"github.com/turfaa/vmedis-proxy-api/pkg2/retry"
 	"github.com/turfaa/vmedis-proxy-api/pkg2/time2"
 	vmedisv1 "github.com/turfaa/vmedis-proxy-api/vmedis/v1"
 )
@@ -133,15 +135,15 @@ type ConsumerHandler struct {
 	cache   *Cache
 }
 
-func (h *ConsumerHandler) DumpDrugDetailsByVmedisCode(ctx context.Context, kafkaMessage kafka.Message) error {
+func (h *ConsumerHandler) DumpDrugDetailsByVmedisCode(ctx context.Context, message queue.Message) error {
 	var payload kafkapb.UpdatedDrugByVmedisCode
-	if err := protojson.Unmarshal(kafkaMessage.Value, &payload); err != nil {
-		return fmt.Errorf("failed to unmarshal kafka message: %s", err)
+	if err := protojson.Unmarshal(message.Value, &payload); err != nil {
+		return retry.Permanent(fmt.Errorf("failed to unmarshal message: %s", err))
 	}
 
 	processed, err := h.cache.HasDrugDetailsByVmedisCodeProcessed(ctx, payload.RequestKey)
 	if err != nil {
-		return fmt.Errorf("failed to check if drug details by vmedis code processed: %s", err)
+		return fmt.Errorf("failed to check if drug details by vmedis code processed: %w", err)
 	}
 
 	if processed {
@@ -151,25 +153,29 @@ func (h *ConsumerHandler) DumpDrugDetailsByVmedisCode(ctx context.Context, kafka
 	log.Printf("Processing message %s to dump drug details by vmedis code", payload.RequestKey)
 
 	if err := h.service.DumpDrugDetailsFromVmedisToDBByVmedisCode(ctx, payload.VmedisCode); err != nil {
-		return fmt.Errorf("failed to dump drug details by vmedis code: %s", err)
+		return permanentIfDrugNotFound(fmt.Errorf("failed to dump drug details by vmedis code: %w", err))
+	}
+
+	if err := h.service.RefreshCachedDrugByVmedisCode(ctx, payload.VmedisCode); err != nil {
+		return permanentIfDrugNotFound(fmt.Errorf("failed to refresh cached drug by vmedis code: %w", err))
 	}
 
 	if err := h.cache.MarkDrugDetailsByVmedisCodeProcessed(ctx, payload.RequestKey); err != nil {
-		return fmt.Errorf("failed to mark drug details by vmedis code processed: %s", err)
+		return fmt.Errorf("failed to mark drug details by vmedis code processed: %w", err)
 	}
 
 	return nil
 }
 
-func (h *ConsumerHandler) DumpDrugDetailsByVmedisID(ctx context.Context, kafkaMessage kafka.Message) error {
+func (h *ConsumerHandler) DumpDrugDetailsByVmedisID(ctx context.Context, message queue.Message) error {
 	var payload kafkapb.UpdatedDrugByVmedisID
-	if err := protojson.Unmarshal(kafkaMessage.Value, &payload); err != nil {
-		return fmt.Errorf("failed to unmarshal kafka message: %s", err)
+	if err := protojson.Unmarshal(message.Value, &payload); err != nil {
+		return retry.Permanent(fmt.Errorf("failed to unmarshal message: %s", err))
 	}
 
 	processed, err := h.cache.HasDrugDetailsByVmedisIDProcessed(ctx, payload.RequestKey)
 	if err != nil {
-		return fmt.Errorf("failed to check if drug details by vmedis id processed: %s", err)
+		return fmt.Errorf("failed to check if drug details by vmedis id processed: %w", err)
 	}
 
 	if processed {
@@ -179,20 +185,34 @@ func (h *ConsumerHandler) DumpDrugDetailsByVmedisID(ctx context.Context, kafkaMe
 	log.Printf("Processing message %s to dump drug details by vmedis id", payload.RequestKey)
 
 	if err := h.service.DumpDrugDetailsFromVmedisToDBByVmedisID(ctx, payload.VmedisId); err != nil {
-		return fmt.Errorf("failed to dump drug details by vmedis id: %s", err)
+		return fmt.Errorf("failed to dump drug details by vmedis id: %w", err)
+	}
+
+	if err := h.service.RefreshCachedDrugByVmedisID(ctx, payload.VmedisId); err != nil {
+		return permanentIfDrugNotFound(fmt.Errorf("failed to refresh cached drug by vmedis id: %w", err))
 	}
 
 	if err := h.cache.MarkDrugDetailsByVmedisIDProcessed(ctx, payload.RequestKey); err != nil {
-		return fmt.Errorf("failed to mark drug details by vmedis id processed: %s", err)
+		return fmt.Errorf("failed to mark drug details by vmedis id processed: %w", err)
 	}
 
 	return nil
 }
 
+// permanentIfDrugNotFound marks the error permanent if the drug doesn't exist in DB,
+// because retrying the message won't create it.
+func permanentIfDrugNotFound(err error) error {
+	if errors.Is(err, ErrDrugNotFound) || errors.Is(err, gorm.ErrRecordNotFound) {
+		return retry.Permanent(err)
+	}
+
+	return err
+}
+
 // NewConsumerHandler creates a new ConsumerHandler.
-func NewConsumerHandler(db *gorm.DB, redisClient redis.UniversalClient, vmedisClient *vmedisv1.Client, kafkaWriter *kafka.Writer) *ConsumerHandler {
+func NewConsumerHandler(db *gorm.DB, redisClient redis.UniversalClient, vmedisClient *vmedisv1.Client, q queue.Queue) *ConsumerHandler {
 	return &ConsumerHandler{
-		service: NewService(redisClient, db, vmedisClient, kafkaWriter),
+		service: NewService(redisClient, db, vmedisClient, q),
 		cache:   NewCache(redisClient),
 	}
 }
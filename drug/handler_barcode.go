@@ -0,0 +1,301 @@
+package drug
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/turfaa/vmedis-proxy-api/auth"
+	"github.com/turfaa/vmedis-proxy-api/cui"
+	"github.com/turfaa/vmedis-proxy-api/pkg2/slices2"
+)
+
+// GetDrugByBarcode returns the drug with the given barcode,
+// with the sections (and so the prices) tailored to the caller's role.
+func (h *ApiHandler) GetDrugByBarcode(c *gin.Context) {
+	code := c.Param("code")
+
+	drug, unit, err := h.service.GetDrugByBarcode(c.Request.Context(), code)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(404, gin.H{"error": fmt.Sprintf("barcode %s not found", code)})
+			return
+		}
+
+		c.JSON(500, gin.H{"error": fmt.Sprintf("failed to get drug by barcode %s: %s", code, err)})
+		return
+	}
+
+	c.JSON(200, DrugByBarcodeResponse{
+		Drug: h.transformToDrugV2(auth.FromGinContext(c), drug),
+		Unit: unit,
+	})
+}
+
+// GetBarcodes returns the barcodes as a display-ready table.
+// The row IDs are the barcode IDs.
+func (h *ApiHandler) GetBarcodes(c *gin.Context) {
+	barcodes, err := h.service.GetBarcodes(c.Request.Context(), c.Query("drug_code"))
+	if err != nil {
+		c.JSON(500, gin.H{"error": fmt.Sprintf("failed to get barcodes: %s", err)})
+		return
+	}
+
+	names, err := h.service.GetDrugNamesByVmedisCodes(c.Request.Context(), slices2.Map(barcodes, func(barcode Barcode) string {
+		return barcode.DrugVmedisCode
+	}))
+	if err != nil {
+		c.JSON(500, gin.H{"error": fmt.Sprintf("failed to get drug names of barcodes: %s", err)})
+		return
+	}
+
+	c.JSON(200, h.transformBarcodesToTable(barcodes, names))
+}
+
+// GetBarcode returns a barcode as a display-ready key-value table.
+func (h *ApiHandler) GetBarcode(c *gin.Context) {
+	barcode, ok := h.getBarcodeFromParam(c)
+	if !ok {
+		return
+	}
+
+	names, err := h.service.GetDrugNamesByVmedisCodes(c.Request.Context(), []string{barcode.DrugVmedisCode})
+	if err != nil {
+		c.JSON(500, gin.H{"error": fmt.Sprintf("failed to get drug name of barcode %d: %s", barcode.ID, err)})
+		return
+	}
+
+	c.JSON(200, h.transformBarcodeToTable(barcode, names[barcode.DrugVmedisCode]))
+}
+
+func (h *ApiHandler) CreateBarcode(c *gin.Context) {
+	var request CreateBarcodeRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(400, gin.H{"error": fmt.Sprintf("invalid request: %s", err)})
+		return
+	}
+
+	barcode, err := h.service.CreateBarcode(c.Request.Context(), request)
+	if err != nil {
+		if isBarcodeValidationError(err) {
+			c.JSON(400, gin.H{"error": fmt.Sprintf("invalid request: %s", err)})
+			return
+		}
+
+		c.JSON(500, gin.H{"error": fmt.Sprintf("failed to create barcode: %s", err)})
+		return
+	}
+
+	c.JSON(201, BarcodeResponse{Barcode: barcode})
+}
+
+func (h *ApiHandler) UpdateBarcode(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{"error": fmt.Sprintf("invalid id: %s", err)})
+		return
+	}
+
+	var request UpdateBarcodeRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(400, gin.H{"error": fmt.Sprintf("invalid request: %s", err)})
+		return
+	}
+
+	barcode, err := h.service.UpdateBarcode(c.Request.Context(), uint(id), request)
+	if err != nil {
+		if isBarcodeValidationError(err) {
+			c.JSON(400, gin.H{"error": fmt.Sprintf("invalid request: %s", err)})
+			return
+		}
+
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(404, gin.H{"error": fmt.Sprintf("barcode %d not found", id)})
+			return
+		}
+
+		c.JSON(500, gin.H{"error": fmt.Sprintf("failed to update barcode %d: %s", id, err)})
+		return
+	}
+
+	c.JSON(200, BarcodeResponse{Barcode: barcode})
+}
+
+func (h *ApiHandler) DeleteBarcode(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{"error": fmt.Sprintf("invalid id: %s", err)})
+		return
+	}
+
+	if err := h.service.DeleteBarcode(c.Request.Context(), uint(id)); err != nil {
+		c.JSON(500, gin.H{"error": fmt.Sprintf("failed to delete barcode %d: %s", id, err)})
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "Barcode deleted successfully"})
+}
+
+// ImportBarcodes imports barcodes from the pasted CSV text.
+// Invalid rows are skipped and reported in the response.
+func (h *ApiHandler) ImportBarcodes(c *gin.Context) {
+	var request ImportBarcodesRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(400, gin.H{"error": fmt.Sprintf("invalid request: %s", err)})
+		return
+	}
+
+	result, err := h.service.ImportBarcodes(c.Request.Context(), strings.NewReader(request.CSV))
+	if err != nil {
+		c.JSON(500, gin.H{"error": fmt.Sprintf("failed to import barcodes: %s", err)})
+		return
+	}
+
+	c.JSON(200, result)
+}
+
+// GetCreateBarcodeForm returns an empty form for registering a barcode.
+// The filled form can be submitted to `POST /drugs/barcodes`.
+func (h *ApiHandler) GetCreateBarcodeForm(c *gin.Context) {
+	c.JSON(200, cui.Form{
+		Title:  "Daftarkan Barcode",
+		Fields: barcodeFormFields(Barcode{DrugVmedisCode: c.Query("drug_code")}),
+	})
+}
+
+// GetUpdateBarcodeForm returns a form prefilled with the current raw values of the barcode.
+// The filled form can be submitted to `PATCH /drugs/barcodes/:id`.
+func (h *ApiHandler) GetUpdateBarcodeForm(c *gin.Context) {
+	barcode, ok := h.getBarcodeFromParam(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(200, cui.Form{
+		Title:  "Perbarui Barcode",
+		Fields: barcodeFormFields(barcode),
+	})
+}
+
+// GetImportBarcodesForm returns a form for pasting barcodes as CSV.
+// The filled form can be submitted to `POST /drugs/barcodes/import`.
+func (h *ApiHandler) GetImportBarcodesForm(c *gin.Context) {
+	c.JSON(200, cui.Form{
+		Title: "Impor Barcode",
+		Fields: []cui.Field{
+			{
+				ID:       "csv",
+				Label:    "CSV (kolom: barcode, kode_obat, satuan)",
+				Type:     cui.FieldTypeTextArea,
+				Value:    "barcode,kode_obat,satuan\n",
+				Required: true,
+			},
+		},
+	})
+}
+
+func (h *ApiHandler) getBarcodeFromParam(c *gin.Context) (Barcode, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{"error": fmt.Sprintf("invalid id: %s", err)})
+		return Barcode{}, false
+	}
+
+	barcode, err := h.service.GetBarcodeByID(c.Request.Context(), uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(404, gin.H{"error": fmt.Sprintf("barcode %d not found", id)})
+			return Barcode{}, false
+		}
+
+		c.JSON(500, gin.H{"error": fmt.Sprintf("failed to get barcode %d: %s", id, err)})
+		return Barcode{}, false
+	}
+
+	return barcode, true
+}
+
+func isBarcodeValidationError(err error) bool {
+	return errors.Is(err, ErrInvalidBarcode) ||
+		errors.Is(err, ErrDuplicateBarcode) ||
+		errors.Is(err, ErrUnknownDrugCodes) ||
+		errors.Is(err, ErrUnknownDrugUnit)
+}
+
+func barcodeFormFields(barcode Barcode) []cui.Field {
+	return []cui.Field{
+		{
+			ID:       "barcode",
+			Label:    "Barcode (EAN/GTIN)",
+			Type:     cui.FieldTypeText,
+			Value:    barcode.Barcode,
+			Required: true,
+		},
+		{
+			ID:       "drugCode",
+			Label:    "Kode Obat Vmedis",
+			Type:     cui.FieldTypeText,
+			Value:    barcode.DrugVmedisCode,
+			Required: true,
+		},
+		{
+			ID:    "unit",
+			Label: "Satuan",
+			Type:  cui.FieldTypeText,
+			Value: barcode.Unit,
+		},
+	}
+}
+
+func (h *ApiHandler) transformBarcodesToTable(barcodes []Barcode, drugNames map[string]string) cui.Table {
+	header := []string{
+		"Barcode",
+		"Nama Obat",
+		"Kode Obat",
+		"Satuan",
+	}
+
+	rows := slices2.Map(barcodes, func(barcode Barcode) cui.Row {
+		return cui.Row{
+			ID: strconv.FormatUint(uint64(barcode.ID), 10),
+			Columns: []string{
+				barcode.Barcode,
+				orDash(drugNames[barcode.DrugVmedisCode]),
+				barcode.DrugVmedisCode,
+				orDash(barcode.Unit),
+			},
+		}
+	})
+
+	return cui.Table{
+		Header: header,
+		Rows:   rows,
+	}
+}
+
+func (h *ApiHandler) transformBarcodeToTable(barcode Barcode, drugName string) cui.Table {
+	return cui.Table{
+		Rows: []cui.Row{
+			{
+				ID:      "barcode",
+				Columns: []string{"Barcode", barcode.Barcode},
+			},
+			{
+				ID:      "nama_obat",
+				Columns: []string{"Nama Obat", orDash(drugName)},
+			},
+			{
+				ID:      "kode_obat",
+				Columns: []string{"Kode Obat", barcode.DrugVmedisCode},
+			},
+			{
+				ID:      "satuan",
+				Columns: []string{"Satuan", orDash(barcode.Unit)},
+			},
+		},
+	}
+}
@@ -0,0 +1,54 @@
+package drug
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/turfaa/vmedis-proxy-api/cui"
+)
+
+// GetDeadLetters returns the drug messages that failed after the consumer retries and haven't been requeued, as a table.
+func (h *ApiHandler) GetDeadLetters(c *gin.Context) {
+	deadLetters, err := h.service.GetDeadLetters(c.Request.Context())
+	if err != nil {
+		c.JSON(500, gin.H{"error": fmt.Sprintf("failed to get dead letters: %s", err)})
+		return
+	}
+
+	rows := make([]cui.Row, len(deadLetters))
+	for i, deadLetter := range deadLetters {
+		failedAt := "-"
+		if !deadLetter.FailedAt.IsZero() {
+			failedAt = deadLetter.FailedAt.Format("2006-01-02 15:04:05")
+		}
+
+		rows[i] = cui.Row{
+			ID: deadLetter.ID,
+			Columns: []string{
+				deadLetter.OriginalTopic,
+				deadLetter.Key,
+				deadLetter.Error,
+				strconv.Itoa(deadLetter.Attempts),
+				failedAt,
+			},
+		}
+	}
+
+	c.JSON(200, cui.Table{
+		Header: []string{"Topik", "Kunci", "Error", "Jumlah Percobaan", "Gagal Pada"},
+		Rows:   rows,
+	})
+}
+
+// RequeueDeadLetters produces the drug messages that failed after the consumer retries back to their original topics.
+func (h *ApiHandler) RequeueDeadLetters(c *gin.Context) {
+	requeued, err := h.service.RequeueDeadLetters(c.Request.Context())
+	if err != nil {
+		c.JSON(500, gin.H{"error": fmt.Sprintf("failed to requeue dead letters: %s", err)})
+		return
+	}
+
+	c.JSON(200, RequeueDeadLettersResponse{Requeued: requeued})
+}
@@ -0,0 +1,111 @@
+package drug
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/turfaa/vmedis-proxy-api/cui"
+	"github.com/turfaa/vmedis-proxy-api/money"
+)
+
+const xlsxContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+// GetDeadStocks returns the drugs in stock that barely sold as a table.
+func (h *ApiHandler) GetDeadStocks(c *gin.Context) {
+	deadStocks, ok := h.getDeadStocks(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(200, transformDeadStocksToTable(deadStocks))
+}
+
+// ExportDeadStocks returns the drugs in stock that barely sold as an XLSX file.
+func (h *ApiHandler) ExportDeadStocks(c *gin.Context) {
+	deadStocks, ok := h.getDeadStocks(c)
+	if !ok {
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := WriteDeadStocksXLSX(&buf, deadStocks); err != nil {
+		c.JSON(500, gin.H{"error": fmt.Sprintf("failed to export dead stocks: %s", err)})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="stok-mati-%s.xlsx"`, time.Now().Format("2006-01-02")))
+	c.Data(200, xlsxContentType, buf.Bytes())
+}
+
+func (h *ApiHandler) getDeadStocks(c *gin.Context) ([]DeadStock, bool) {
+	var request DeadStocksRequest
+	if err := c.ShouldBindQuery(&request); err != nil {
+		c.JSON(400, gin.H{"error": fmt.Sprintf("invalid request: %s", err)})
+		return nil, false
+	}
+
+	deadStocks, err := h.service.GetDeadStocks(c.Request.Context(), request)
+	if err != nil {
+		c.JSON(500, gin.H{"error": fmt.Sprintf("failed to get dead stocks: %s", err)})
+		return nil, false
+	}
+
+	return deadStocks, true
+}
+
+func transformDeadStocksToTable(deadStocks []DeadStock) cui.Table {
+	var totalValue float64
+
+	rows := make([]cui.Row, len(deadStocks))
+	for i, deadStock := range deadStocks {
+		value := "-"
+		if deadStock.StockValue != nil {
+			totalValue += *deadStock.StockValue
+			value = money.FormatRupiah(*deadStock.StockValue)
+		}
+
+		rows[i] = cui.Row{
+			ID: deadStock.DrugVmedisCode,
+			Columns: []string{
+				deadStock.DrugName,
+				formatStocks(deadStock.Stocks),
+				value,
+				strconv.Itoa(deadStock.NumberOfSales),
+				formatOptionalDateOrDash(deadStock.LastSoldAt),
+				formatOptionalDateOrDash(deadStock.LastProcuredAt),
+			},
+		}
+	}
+
+	return cui.Table{
+		Header: []string{
+			"Nama Obat",
+			"Stok",
+			"Nilai Stok",
+			"Jumlah Penjualan",
+			"Penjualan Terakhir",
+			"Pembelian Terakhir",
+		},
+		Rows: rows,
+		Footer: []string{
+			"Total",
+			"",
+			money.FormatRupiah(totalValue),
+			"",
+			"",
+			"",
+		},
+	}
+}
+
+func formatOptionalDateOrDash(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+
+	return t.Format("2006-01-02")
+}
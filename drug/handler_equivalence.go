@@ -0,0 +1,284 @@
+package drug
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/turfaa/vmedis-proxy-api/auth"
+	"github.com/turfaa/vmedis-proxy-api/cui"
+	"github.com/turfaa/vmedis-proxy-api/pkg2/slices2"
+)
+
+// GetSubstitutes returns the in-stock drugs equivalent to the drug with the given code,
+// with the sections (and so the prices) tailored to the caller's role.
+func (h *ApiHandler) GetSubstitutes(c *gin.Context) {
+	code := c.Param("code")
+
+	drugs, err := h.service.GetSubstitutes(c.Request.Context(), code)
+	if err != nil {
+		c.JSON(500, gin.H{"error": fmt.Sprintf("failed to get substitutes of drug %s: %s", code, err)})
+		return
+	}
+
+	c.JSON(200, DrugsResponseV2{
+		Drugs: h.transformToDrugsV2(auth.FromGinContext(c), drugs),
+	})
+}
+
+// GetEquivalenceGroups returns the equivalence groups as a display-ready table.
+// The row IDs are the group IDs.
+func (h *ApiHandler) GetEquivalenceGroups(c *gin.Context) {
+	groups, err := h.service.GetEquivalenceGroups(c.Request.Context())
+	if err != nil {
+		c.JSON(500, gin.H{"error": fmt.Sprintf("failed to get equivalence groups: %s", err)})
+		return
+	}
+
+	c.JSON(200, h.transformEquivalenceGroupsToTable(groups))
+}
+
+// GetEquivalenceGroup returns an equivalence group and its drugs as a display-ready key-value table.
+func (h *ApiHandler) GetEquivalenceGroup(c *gin.Context) {
+	group, ok := h.getEquivalenceGroupFromParam(c)
+	if !ok {
+		return
+	}
+
+	names, err := h.service.GetDrugNamesByVmedisCodes(c.Request.Context(), group.DrugVmedisCodes)
+	if err != nil {
+		c.JSON(500, gin.H{"error": fmt.Sprintf("failed to get drug names of equivalence group %d: %s", group.ID, err)})
+		return
+	}
+
+	c.JSON(200, h.transformEquivalenceGroupToTable(group, names))
+}
+
+func (h *ApiHandler) CreateEquivalenceGroup(c *gin.Context) {
+	var request CreateEquivalenceGroupRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(400, gin.H{"error": fmt.Sprintf("invalid request: %s", err)})
+		return
+	}
+
+	group, err := h.service.CreateEquivalenceGroup(c.Request.Context(), request)
+	if err != nil {
+		if errors.Is(err, ErrUnknownDrugCodes) {
+			c.JSON(400, gin.H{"error": fmt.Sprintf("invalid request: %s", err)})
+			return
+		}
+
+		c.JSON(500, gin.H{"error": fmt.Sprintf("failed to create equivalence group: %s", err)})
+		return
+	}
+
+	c.JSON(201, EquivalenceGroupResponse{EquivalenceGroup: group})
+}
+
+func (h *ApiHandler) UpdateEquivalenceGroup(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{"error": fmt.Sprintf("invalid id: %s", err)})
+		return
+	}
+
+	var request UpdateEquivalenceGroupRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(400, gin.H{"error": fmt.Sprintf("invalid request: %s", err)})
+		return
+	}
+
+	group, err := h.service.UpdateEquivalenceGroup(c.Request.Context(), uint(id), request)
+	if err != nil {
+		if errors.Is(err, ErrUnknownDrugCodes) {
+			c.JSON(400, gin.H{"error": fmt.Sprintf("invalid request: %s", err)})
+			return
+		}
+
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(404, gin.H{"error": fmt.Sprintf("equivalence group %d not found", id)})
+			return
+		}
+
+		c.JSON(500, gin.H{"error": fmt.Sprintf("failed to update equivalence group %d: %s", id, err)})
+		return
+	}
+
+	c.JSON(200, EquivalenceGroupResponse{EquivalenceGroup: group})
+}
+
+func (h *ApiHandler) DeleteEquivalenceGroup(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{"error": fmt.Sprintf("invalid id: %s", err)})
+		return
+	}
+
+	if err := h.service.DeleteEquivalenceGroup(c.Request.Context(), uint(id)); err != nil {
+		c.JSON(500, gin.H{"error": fmt.Sprintf("failed to delete equivalence group %d: %s", id, err)})
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "Equivalence group deleted successfully"})
+}
+
+// SeedEquivalenceGroupsFromKFA groups the drugs that are not in any group yet by their KFA product.
+func (h *ApiHandler) SeedEquivalenceGroupsFromKFA(c *gin.Context) {
+	result, err := h.service.SeedEquivalenceGroupsFromKFA(c.Request.Context())
+	if err != nil {
+		c.JSON(500, gin.H{"error": fmt.Sprintf("failed to seed equivalence groups from KFA: %s", err)})
+		return
+	}
+
+	c.JSON(200, result)
+}
+
+// GetCreateEquivalenceGroupForm returns an empty form for creating an equivalence group.
+// The filled form can be submitted to `POST /drugs/equivalence-groups`.
+func (h *ApiHandler) GetCreateEquivalenceGroupForm(c *gin.Context) {
+	c.JSON(200, cui.Form{
+		Title:  "Buat Grup Obat Setara",
+		Fields: equivalenceGroupFormFields(EquivalenceGroup{}),
+	})
+}
+
+// GetUpdateEquivalenceGroupForm returns a form prefilled with the current raw values of the group.
+// The filled form can be submitted to `PATCH /drugs/equivalence-groups/:id`.
+func (h *ApiHandler) GetUpdateEquivalenceGroupForm(c *gin.Context) {
+	group, ok := h.getEquivalenceGroupFromParam(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(200, cui.Form{
+		Title:  "Perbarui Grup Obat Setara",
+		Fields: equivalenceGroupFormFields(group),
+	})
+}
+
+func (h *ApiHandler) getEquivalenceGroupFromParam(c *gin.Context) (EquivalenceGroup, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{"error": fmt.Sprintf("invalid id: %s", err)})
+		return EquivalenceGroup{}, false
+	}
+
+	group, err := h.service.GetEquivalenceGroupByID(c.Request.Context(), uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(404, gin.H{"error": fmt.Sprintf("equivalence group %d not found", id)})
+			return EquivalenceGroup{}, false
+		}
+
+		c.JSON(500, gin.H{"error": fmt.Sprintf("failed to get equivalence group %d: %s", id, err)})
+		return EquivalenceGroup{}, false
+	}
+
+	return group, true
+}
+
+func equivalenceGroupFormFields(group EquivalenceGroup) []cui.Field {
+	return []cui.Field{
+		{
+			ID:       "name",
+			Label:    "Nama Grup",
+			Type:     cui.FieldTypeText,
+			Value:    group.Name,
+			Required: true,
+		},
+		{
+			ID:    "activeIngredient",
+			Label: "Zat Aktif",
+			Type:  cui.FieldTypeText,
+			Value: group.ActiveIngredient,
+		},
+		{
+			ID:    "strength",
+			Label: "Kekuatan",
+			Type:  cui.FieldTypeText,
+			Value: group.Strength,
+		},
+		{
+			ID:    "dosageForm",
+			Label: "Bentuk Sediaan",
+			Type:  cui.FieldTypeText,
+			Value: group.DosageForm,
+		},
+		{
+			ID:    "drugCodes",
+			Label: "Kode Obat Vmedis (satu per baris)",
+			Type:  cui.FieldTypeTextArea,
+			Value: strings.Join(group.DrugVmedisCodes, "\n"),
+		},
+	}
+}
+
+func (h *ApiHandler) transformEquivalenceGroupsToTable(groups []EquivalenceGroup) cui.Table {
+	header := []string{
+		"Nama Grup",
+		"Zat Aktif",
+		"Kekuatan",
+		"Bentuk Sediaan",
+		"Jumlah Obat",
+	}
+
+	rows := slices2.Map(groups, func(group EquivalenceGroup) cui.Row {
+		return cui.Row{
+			ID: strconv.FormatUint(uint64(group.ID), 10),
+			Columns: []string{
+				group.Name,
+				group.ActiveIngredient,
+				group.Strength,
+				group.DosageForm,
+				strconv.Itoa(len(group.DrugVmedisCodes)),
+			},
+		}
+	})
+
+	return cui.Table{
+		Header: header,
+		Rows:   rows,
+	}
+}
+
+func (h *ApiHandler) transformEquivalenceGroupToTable(group EquivalenceGroup, drugNames map[string]string) cui.Table {
+	rows := []cui.Row{
+		{
+			ID:      "nama_grup",
+			Columns: []string{"Nama Grup", group.Name},
+		},
+		{
+			ID:      "zat_aktif",
+			Columns: []string{"Zat Aktif", orDash(group.ActiveIngredient)},
+		},
+		{
+			ID:      "kekuatan",
+			Columns: []string{"Kekuatan", orDash(group.Strength)},
+		},
+		{
+			ID:      "bentuk_sediaan",
+			Columns: []string{"Bentuk Sediaan", orDash(group.DosageForm)},
+		},
+	}
+
+	for _, code := range group.DrugVmedisCodes {
+		rows = append(rows, cui.Row{
+			ID:      "obat_" + code,
+			Columns: []string{"Obat", fmt.Sprintf("%s (%s)", drugNames[code], code)},
+		})
+	}
+
+	return cui.Table{Rows: rows}
+}
+
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+
+	return value
+}
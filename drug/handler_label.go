@@ -0,0 +1,39 @@
+package drug
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GenerateLabels renders the shelf price labels of the selected drugs as a PDF file.
+func (h *ApiHandler) GenerateLabels(c *gin.Context) {
+	var request GenerateLabelsRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(400, gin.H{"error": fmt.Sprintf("invalid request: %s", err)})
+		return
+	}
+
+	labels, err := h.service.GetLabels(c.Request.Context(), request)
+	if err != nil {
+		if errors.Is(err, ErrInvalidLabelsRequest) || errors.Is(err, ErrNoLabels) {
+			c.JSON(400, gin.H{"error": fmt.Sprintf("invalid request: %s", err)})
+			return
+		}
+
+		c.JSON(500, gin.H{"error": fmt.Sprintf("failed to get labels: %s", err)})
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := RenderLabelsPDF(&buf, labels); err != nil {
+		c.JSON(500, gin.H{"error": fmt.Sprintf("failed to render labels: %s", err)})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="label-harga-%s.pdf"`, time.Now().Format("2006-01-02")))
+	c.Data(200, "application/pdf", buf.Bytes())
+}
@@ -1,6 +1,7 @@
 package drug
 
 import (
+	"errors"
 	"fmt"
 	"slices"
 	"strings"
@@ -30,6 +31,33 @@ func (h *ApiHandler) GetDrugsV2(c *gin.Context) {
 	c.JSON(200, res)
 }
 
+// GetDrugChanges handles row-based get drug changes request.
+// The sections of the changed drugs are rendered for the caller's role, like GetDrugsV2.
+func (h *ApiHandler) GetDrugChanges(c *gin.Context) {
+	var request DrugChangesRequest
+	if err := c.ShouldBindQuery(&request); err != nil {
+		c.JSON(400, gin.H{"error": fmt.Sprintf("invalid request: %s", err)})
+		return
+	}
+
+	changes, err := h.service.GetDrugChanges(c.Request.Context(), request.Since)
+	if err != nil {
+		if errors.Is(err, ErrInvalidCursor) {
+			c.JSON(400, gin.H{"error": fmt.Sprintf("invalid request: %s", err)})
+			return
+		}
+
+		c.JSON(500, gin.H{"error": fmt.Sprintf("failed to get drug changes: %s", err)})
+		return
+	}
+
+	c.JSON(200, DrugChangesResponse{
+		Drugs:        h.transformToDrugsV2(auth.FromGinContext(c), changes.Drugs),
+		RemovedDrugs: changes.RemovedDrugs,
+		Cursor:       changes.Cursor,
+	})
+}
+
 func (h *ApiHandler) transformToDrugsV2(user auth.User, drugs []Drug) []DrugsResponseV2_Drug {
 	transformedDrugs := make([]DrugsResponseV2_Drug, len(drugs))
 	for i, drug := range drugs {
@@ -59,8 +87,8 @@ func (h *ApiHandler) transformToDrugV2(user auth.User, drug Drug) DrugsResponseV
 			rows := make([]string, len(units))
 			for i, unit := range units {
 				rows[i] = fmt.Sprintf("%s / %s", money.FormatRupiah(unit.PriceOne), unit.Unit)
-				if unit.ConversionToParentUnit > 0 {
-					rows[i] += fmt.Sprintf(" (%0.0f %s)", unit.ConversionToParentUnit, unit.ParentUnit)
+				if conversion := unitConversionText(unit); conversion != "" {
+					rows[i] += " " + conversion
 				}
 			}
 
@@ -75,8 +103,8 @@ func (h *ApiHandler) transformToDrugV2(user auth.User, drug Drug) DrugsResponseV
 			rows := make([]string, len(units))
 			for i, unit := range units {
 				rows[i] = fmt.Sprintf("%s / %s", money.FormatRupiah(unit.PriceTwo), unit.Unit)
-				if unit.ConversionToParentUnit > 0 {
-					rows[i] += fmt.Sprintf(" (%0.0f %s)", unit.ConversionToParentUnit, unit.ParentUnit)
+				if conversion := unitConversionText(unit); conversion != "" {
+					rows[i] += " " + conversion
 				}
 			}
 
@@ -91,8 +119,8 @@ func (h *ApiHandler) transformToDrugV2(user auth.User, drug Drug) DrugsResponseV
 			rows := make([]string, len(units))
 			for i, unit := range units {
 				rows[i] = fmt.Sprintf("%s / %s", money.FormatRupiah(unit.PriceThree), unit.Unit)
-				if unit.ConversionToParentUnit > 0 {
-					rows[i] += fmt.Sprintf(" (%0.0f %s)", unit.ConversionToParentUnit, unit.ParentUnit)
+				if conversion := unitConversionText(unit); conversion != "" {
+					rows[i] += " " + conversion
 				}
 			}
 
@@ -139,3 +167,13 @@ func (h *ApiHandler) transformToDrugV2(user auth.User, drug Drug) DrugsResponseV
 		Sections:   sections,
 	}
 }
+
+// unitConversionText returns the content of the unit in its parent unit, e.g. "(10 Tablet)".
+// It returns an empty string for the smallest unit.
+func unitConversionText(unit Unit) string {
+	if unit.ConversionToParentUnit <= 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("(%0.0f %s)", unit.ConversionToParentUnit, unit.ParentUnit)
+}
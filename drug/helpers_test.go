@@ -0,0 +1,120 @@
+package drug_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+
+	"github.com/turfaa/vmedis-proxy-api/auth"
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+	"github.com/turfaa/vmedis-proxy-api/drug"
+)
+
+func setup(t *testing.T) (*gorm.DB, *drug.Service, *gin.Engine) {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open database: %s", err)
+	}
+	if err := db.AutoMigrate(
+		&models.Drug{},
+		&models.DrugUnit{},
+		&models.DrugStock{},
+		&models.KFAProduct{},
+		&models.DrugEquivalenceGroup{},
+		&models.DrugEquivalenceGroupMember{},
+		&models.DrugBarcode{},
+		&models.Sale{},
+		&models.SaleUnit{},
+		&models.Procurement{},
+		&models.ProcurementUnit{},
+	); err != nil {
+		t.Fatalf("migrate database: %s", err)
+	}
+
+	// The tested features don't touch vmedis or the queue.
+	service := drug.NewService(newRedis(t), db, nil, nil)
+	handler := drug.NewApiHandler(drug.ApiHandlerConfig{Service: service})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	// Stands in for the auth middleware, taking the role from a header.
+	router.Use(func(c *gin.Context) {
+		auth.SetGinContext(c, auth.User{Role: auth.Role(c.GetHeader("X-Role"))})
+	})
+
+	// Mirrors the route registration in proxy/api.go, without the role checks.
+	drugs := router.Group("/drugs")
+	{
+		drugs.GET("/changes", handler.GetDrugChanges)
+		drugs.GET("/:code/substitutes", handler.GetSubstitutes)
+		drugs.GET("/by-barcode/:code", handler.GetDrugByBarcode)
+		drugs.POST("/labels", handler.GenerateLabels)
+		drugs.GET("/dead-stocks", handler.GetDeadStocks)
+		drugs.GET("/dead-stocks/xlsx", handler.ExportDeadStocks)
+
+		barcodes := drugs.Group("/barcodes")
+		{
+			barcodes.GET("", handler.GetBarcodes)
+			barcodes.POST("", handler.CreateBarcode)
+			barcodes.GET("/form", handler.GetCreateBarcodeForm)
+			barcodes.GET("/import/form", handler.GetImportBarcodesForm)
+			barcodes.POST("/import", handler.ImportBarcodes)
+			barcodes.GET("/:id", handler.GetBarcode)
+			barcodes.GET("/:id/form", handler.GetUpdateBarcodeForm)
+			barcodes.PATCH("/:id", handler.UpdateBarcode)
+			barcodes.DELETE("/:id", handler.DeleteBarcode)
+		}
+
+		equivalenceGroups := drugs.Group("/equivalence-groups")
+		{
+			equivalenceGroups.GET("", handler.GetEquivalenceGroups)
+			equivalenceGroups.POST("", handler.CreateEquivalenceGroup)
+			equivalenceGroups.GET("/form", handler.GetCreateEquivalenceGroupForm)
+			equivalenceGroups.POST("/seed-from-kfa", handler.SeedEquivalenceGroupsFromKFA)
+			equivalenceGroups.GET("/:id", handler.GetEquivalenceGroup)
+			equivalenceGroups.GET("/:id/form", handler.GetUpdateEquivalenceGroupForm)
+			equivalenceGroups.PATCH("/:id", handler.UpdateEquivalenceGroup)
+			equivalenceGroups.DELETE("/:id", handler.DeleteEquivalenceGroup)
+		}
+	}
+
+	return db, service, router
+}
+
+// newRedis returns a client of an in-memory redis server that lives as long as the test.
+func newRedis(t *testing.T) *redis.Client {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	return client
+}
+
+func mustCreate(t *testing.T, db *gorm.DB, value any) {
+	t.Helper()
+
+	if err := db.Create(value).Error; err != nil {
+		t.Fatalf("create %T: %s", value, err)
+	}
+}
+
+func unmarshal[T any](t *testing.T, body string) T {
+	t.Helper()
+
+	var value T
+	if err := json.Unmarshal([]byte(body), &value); err != nil {
+		t.Fatalf("unmarshal %T from %s: %s", value, body, err)
+	}
+
+	return value
+}
@@ -0,0 +1,112 @@
+package drug
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+	"github.com/turfaa/vmedis-proxy-api/pkg2/time2"
+)
+
+var (
+	// ErrInvalidLabelsRequest is returned when a labels request doesn't select drugs properly.
+	ErrInvalidLabelsRequest = errors.New("invalid labels request")
+
+	// ErrNoLabels is returned when the selected drugs have no labels to print.
+	ErrNoLabels = errors.New("no labels to print")
+)
+
+// GetLabels returns the shelf price labels of the selected drugs, sorted by drug name.
+// Each drug gets one label per unit shown in the drug list.
+func (s *Service) GetLabels(ctx context.Context, request GenerateLabelsRequest) ([]Label, error) {
+	drugCodes, err := s.getLabelDrugCodes(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	drugs, err := s.GetDrugsByVmedisCodes(ctx, drugCodes)
+	if err != nil {
+		return nil, fmt.Errorf("get drugs %v: %w", drugCodes, err)
+	}
+
+	slices.SortFunc(drugs, func(a, b Drug) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	var barcodes map[string][]models.DrugBarcode
+	if request.WithBarcodes {
+		barcodes, err = s.db.GetBarcodesByDrugVmedisCodes(ctx, drugCodes)
+		if err != nil {
+			return nil, fmt.Errorf("get barcodes: %w", err)
+		}
+	}
+
+	var labels []Label
+	for _, drug := range drugs {
+		for _, unit := range filterUnits(drug.Units) {
+			labels = append(labels, Label{
+				DrugVmedisCode: drug.VmedisCode,
+				DrugName:       drug.Name,
+				Unit:           unit.Unit,
+				Price:          unit.PriceOne,
+				Conversion:     unitConversionText(unit),
+				Barcode:        labelBarcode(barcodes[drug.VmedisCode], unit.Unit),
+			})
+		}
+	}
+
+	if len(labels) == 0 {
+		return nil, ErrNoLabels
+	}
+
+	return labels, nil
+}
+
+func (s *Service) getLabelDrugCodes(ctx context.Context, request GenerateLabelsRequest) ([]string, error) {
+	drugCodes := parseDrugCodes(request.DrugCodes)
+	since := strings.TrimSpace(request.PricesChangedSince)
+
+	switch {
+	case len(drugCodes) > 0 && since != "":
+		return nil, fmt.Errorf("%w: only one of drug codes or prices changed since can be set", ErrInvalidLabelsRequest)
+
+	case len(drugCodes) > 0:
+		return drugCodes, nil
+
+	case since != "":
+		sinceTime, err := time2.BeginningOfDate(since)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidLabelsRequest, err)
+		}
+
+		drugCodes, err := s.db.GetDrugVmedisCodesWithPricesUpdatedAfter(ctx, sinceTime)
+		if err != nil {
+			return nil, fmt.Errorf("get drugs with prices changed since %s: %w", since, err)
+		}
+
+		return drugCodes, nil
+
+	default:
+		return nil, fmt.Errorf("%w: either drug codes or prices changed since must be set", ErrInvalidLabelsRequest)
+	}
+}
+
+// labelBarcode returns the barcode printed on the given unit,
+// falling back to a barcode without a unit.
+func labelBarcode(barcodes []models.DrugBarcode, unit string) string {
+	fallback := ""
+	for _, barcode := range barcodes {
+		if strings.EqualFold(barcode.Unit, unit) {
+			return barcode.Barcode
+		}
+
+		if barcode.Unit == "" && fallback == "" {
+			fallback = barcode.Barcode
+		}
+	}
+
+	return fallback
+}
@@ -0,0 +1,236 @@
+package drug
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/go-pdf/fpdf"
+
+	"github.com/turfaa/vmedis-proxy-api/money"
+)
+
+// The labels are laid out in a 3x8 grid on A4 paper, leaving enough margin for most printers.
+const (
+	labelColumns  = 3
+	labelRows     = 8
+	labelWidth    = 64.0
+	labelHeight   = 34.0
+	labelPadding  = 3.0
+	labelsMarginX = (210 - labelColumns*labelWidth) / 2
+	labelsMarginY = (297 - labelRows*labelHeight) / 2
+
+	barcodeMaxWidth     = 34.0
+	barcodeMaxModule    = 0.33
+	barcodeHeight       = 7.0
+	labelNameMaxLines   = 2
+	labelNameFontSize   = 9.0
+	labelPriceFontSize  = 17.0
+	labelDetailFontSize = 8.0
+)
+
+// RenderLabelsPDF renders the labels as printable A4 label sheets.
+func RenderLabelsPDF(w io.Writer, labels []Label) error {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(0, 0, 0)
+	pdf.SetAutoPageBreak(false, 0)
+	pdf.SetTitle("Label Harga", true)
+
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+
+	for i, label := range labels {
+		position := i % (labelColumns * labelRows)
+		if position == 0 {
+			pdf.AddPage()
+		}
+
+		x := labelsMarginX + float64(position%labelColumns)*labelWidth
+		y := labelsMarginY + float64(position/labelColumns)*labelHeight
+
+		renderLabel(pdf, tr, x, y, label)
+	}
+
+	if err := pdf.Output(w); err != nil {
+		return fmt.Errorf("output PDF: %w", err)
+	}
+
+	return nil
+}
+
+func renderLabel(pdf *fpdf.Fpdf, tr func(string) string, x, y float64, label Label) {
+	contentWidth := labelWidth - 2*labelPadding
+
+	// Cutting guide.
+	pdf.SetDrawColor(200, 200, 200)
+	pdf.SetLineWidth(0.1)
+	pdf.Rect(x, y, labelWidth, labelHeight, "D")
+
+	pdf.SetTextColor(0, 0, 0)
+	pdf.SetFont("Helvetica", "B", labelNameFontSize)
+	lines := pdf.SplitText(tr(label.DrugName), contentWidth)
+	if len(lines) > labelNameMaxLines {
+		lines = lines[:labelNameMaxLines]
+		lines[labelNameMaxLines-1] = strings.TrimRight(lines[labelNameMaxLines-1], " ") + "..."
+	}
+
+	cursorY := y + labelPadding
+	for _, line := range lines {
+		pdf.SetXY(x+labelPadding, cursorY)
+		pdf.CellFormat(contentWidth, 4, line, "", 0, "L", false, 0, "")
+		cursorY += 4
+	}
+
+	price := money.FormatRupiah(label.Price)
+	pdf.SetFont("Helvetica", "B", labelPriceFontSize)
+	priceWidth := pdf.GetStringWidth(price)
+	pdf.SetXY(x+labelPadding, cursorY+1)
+	pdf.CellFormat(priceWidth, 8, tr(price), "", 0, "L", false, 0, "")
+
+	// The unit and its conversion share the price line, leaving the bottom of the label for the barcode.
+	unit := " / " + label.Unit
+	pdf.SetFont("Helvetica", "", labelNameFontSize)
+	unitWidth := pdf.GetStringWidth(unit)
+	pdf.CellFormat(unitWidth, 8, tr(unit), "", 0, "L", false, 0, "")
+
+	if label.Conversion != "" {
+		pdf.SetFont("Helvetica", "", labelDetailFontSize)
+		pdf.SetTextColor(80, 80, 80)
+		pdf.CellFormat(contentWidth-priceWidth-unitWidth, 8, tr(" "+label.Conversion), "", 0, "L", false, 0, "")
+	}
+
+	bottomY := y + labelHeight - labelPadding
+
+	pdf.SetFont("Helvetica", "", 6)
+	pdf.SetTextColor(80, 80, 80)
+	pdf.SetXY(x+labelPadding, bottomY-3)
+	pdf.CellFormat(contentWidth, 3, tr(label.DrugVmedisCode), "", 0, "R", false, 0, "")
+
+	if modules, ok := barcodeModules(label.Barcode); ok {
+		renderBarcode(pdf, x+labelPadding, bottomY-barcodeHeight-2.5, modules)
+
+		pdf.SetFont("Courier", "", 6)
+		pdf.SetTextColor(0, 0, 0)
+		pdf.SetXY(x+labelPadding, bottomY-2.5)
+		pdf.CellFormat(barcodeMaxWidth, 2.5, label.Barcode, "", 0, "L", false, 0, "")
+	}
+}
+
+func renderBarcode(pdf *fpdf.Fpdf, x, y float64, modules string) {
+	moduleWidth := min(barcodeMaxModule, barcodeMaxWidth/float64(len(modules)))
+
+	pdf.SetFillColor(0, 0, 0)
+	for i := 0; i < len(modules); {
+		if modules[i] != '1' {
+			i++
+			continue
+		}
+
+		// Draw consecutive bar modules as one rectangle.
+		start := i
+		for i < len(modules) && modules[i] == '1' {
+			i++
+		}
+
+		pdf.Rect(x+float64(start)*moduleWidth, y, float64(i-start)*moduleWidth, barcodeHeight, "F")
+	}
+}
+
+var (
+	eanLCodes = [10]string{
+		"0001101", "0011001", "0010011", "0111101", "0100011",
+		"0110001", "0101111", "0111011", "0110111", "0001011",
+	}
+	eanGCodes = [10]string{
+		"0100111", "0110011", "0011011", "0100001", "0011101",
+		"0111001", "0000101", "0010001", "0001001", "0010111",
+	}
+	eanRCodes = [10]string{
+		"1110010", "1100110", "1101100", "1000010", "1011100",
+		"1001110", "1010000", "1000100", "1001000", "1110100",
+	}
+
+	// ean13Parities is the L/G parity of the left digits of an EAN-13 barcode, by the first digit.
+	ean13Parities = [10]string{
+		"LLLLLL", "LLGLGG", "LLGGLG", "LLGGGL", "LGLLGG",
+		"LGGLLG", "LGGGLL", "LGLGLG", "LGLGGL", "LGGLGL",
+	}
+
+	// itfPatterns is the narrow (n) and wide (w) elements of each ITF-14 digit.
+	itfPatterns = [10]string{
+		"nnwwn", "wnnnw", "nwnnw", "wwnnn", "nnwnw",
+		"wnwnn", "nwwnn", "nnnww", "wnnwn", "nwnwn",
+	}
+)
+
+// barcodeModules encodes the canonical barcode as bar (1) and space (0) modules.
+// EAN-8 and EAN-13 barcodes are encoded as EAN, and GTIN-14 barcodes as ITF-14.
+func barcodeModules(barcode string) (string, bool) {
+	if _, err := CanonicalBarcode(barcode); err != nil {
+		return "", false
+	}
+
+	digits := make([]int, len(barcode))
+	for i := range barcode {
+		digits[i] = int(barcode[i] - '0')
+	}
+
+	var modules strings.Builder
+	switch len(digits) {
+	case 8:
+		modules.WriteString("101")
+		for _, d := range digits[:4] {
+			modules.WriteString(eanLCodes[d])
+		}
+		modules.WriteString("01010")
+		for _, d := range digits[4:] {
+			modules.WriteString(eanRCodes[d])
+		}
+		modules.WriteString("101")
+
+	case 13:
+		parity := ean13Parities[digits[0]]
+		modules.WriteString("101")
+		for i, d := range digits[1:7] {
+			if parity[i] == 'L' {
+				modules.WriteString(eanLCodes[d])
+			} else {
+				modules.WriteString(eanGCodes[d])
+			}
+		}
+		modules.WriteString("01010")
+		for _, d := range digits[7:] {
+			modules.WriteString(eanRCodes[d])
+		}
+		modules.WriteString("101")
+
+	case 14:
+		element := func(width byte, bar bool) {
+			c := "0"
+			if bar {
+				c = "1"
+			}
+
+			n := 1
+			if width == 'w' {
+				n = 3
+			}
+
+			modules.WriteString(strings.Repeat(c, n))
+		}
+
+		modules.WriteString("1010")
+		for i := 0; i < len(digits); i += 2 {
+			bars, spaces := itfPatterns[digits[i]], itfPatterns[digits[i+1]]
+			for j := range 5 {
+				element(bars[j], true)
+				element(spaces[j], false)
+			}
+		}
+		modules.WriteString("111" + "0" + "1")
+
+	default:
+		return "", false
+	}
+
+	return modules.String(), true
+}
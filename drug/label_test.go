@@ -0,0 +1,99 @@
+package drug_test
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/turfaa/vmedis-proxy-api/auth"
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+	"github.com/turfaa/vmedis-proxy-api/drug"
+	vmedisv1 "github.com/turfaa/vmedis-proxy-api/vmedis/v1"
+)
+
+// TestLabels checks that price changes are tracked across drug dumps and that
+// the labels of the changed drugs are rendered as a PDF.
+func TestLabels(t *testing.T) {
+	db, service, router := setup(t)
+	ctx := context.Background()
+	drugDB := drug.NewDatabase(db)
+
+	mustCreate(t, db, &models.Drug{VmedisID: 1, VmedisCode: "SANMOL", Name: "SANMOL 500MG"})
+	mustCreate(t, db, &models.Drug{VmedisID: 2, VmedisCode: "BODREX", Name: "BODREX"})
+	mustCreate(t, db, &models.DrugBarcode{Barcode: "8992761111113", DrugVmedisCode: "SANMOL", Unit: "Box"})
+	mustCreate(t, db, &models.DrugBarcode{Barcode: "00012345678905", DrugVmedisCode: "SANMOL"})
+
+	sanmolUnits := []vmedisv1.Unit{
+		{Unit: "Strip", PriceOne: 5000},
+		{Unit: "Box", ParentUnit: "Strip", ConversionToParentUnit: 10, UnitOrder: 1, PriceOne: 48000},
+	}
+	for code, units := range map[string][]vmedisv1.Unit{
+		"SANMOL": sanmolUnits,
+		"BODREX": {{Unit: "Strip", PriceOne: 4000}},
+	} {
+		if err := drugDB.UpsertVmedisDrugUnits(ctx, code, units); err != nil {
+			t.Fatalf("insert units of %s: %s", code, err)
+		}
+	}
+
+	// Pretend the units were inserted a while ago.
+	lastWeek := time.Now().AddDate(0, 0, -7)
+	if err := db.Model(&models.DrugUnit{}).Where("1 = 1").Update("price_updated_at", lastWeek).Error; err != nil {
+		t.Fatalf("backdate price_updated_at: %s", err)
+	}
+
+	// Only the box price of SANMOL changes in the next dump.
+	// Dumping the same prices of BODREX again must not count as a price change.
+	sanmolUnits[1].PriceOne = 50000
+	for code, units := range map[string][]vmedisv1.Unit{
+		"SANMOL": sanmolUnits,
+		"BODREX": {{Unit: "Strip", PriceOne: 4000}},
+	} {
+		if err := drugDB.UpsertVmedisDrugUnits(ctx, code, units); err != nil {
+			t.Fatalf("upsert units of %s: %s", code, err)
+		}
+	}
+
+	labels, err := service.GetLabels(ctx, drug.GenerateLabelsRequest{
+		PricesChangedSince: time.Now().AddDate(0, 0, -1).Format("2006-01-02"),
+		WithBarcodes:       true,
+	})
+	if err != nil {
+		t.Fatalf("get labels of changed prices: %s", err)
+	}
+	if len(labels) != 2 {
+		t.Fatalf("get labels of changed prices: expected the 2 units of SANMOL, got %+v", labels)
+	}
+	if labels[0].Unit != "Strip" || labels[0].Barcode != "00012345678905" || labels[0].Conversion != "" {
+		t.Fatalf("strip label: got %+v", labels[0])
+	}
+	if labels[1].Unit != "Box" || labels[1].Price != 50000 || labels[1].Barcode != "8992761111113" || labels[1].Conversion != "(10 Strip)" {
+		t.Fatalf("box label: got %+v", labels[1])
+	}
+
+	do := func(role auth.Role, method, path, body string) (int, string, string) {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("X-Role", string(role))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code, w.Header().Get("Content-Type"), w.Body.String()
+	}
+
+	code, contentType, body := do(auth.RoleStaff, "POST", "/drugs/labels", `{"drugCodes": "SANMOL, BODREX", "withBarcodes": true}`)
+	if code != 200 || contentType != "application/pdf" || !bytes.HasPrefix([]byte(body), []byte("%PDF")) {
+		t.Fatalf("render labels: got code %d, content type %s", code, contentType)
+	}
+
+	code, _, body = do(auth.RoleStaff, "POST", "/drugs/labels", `{}`)
+	if code != 400 {
+		t.Fatalf("render without selection: got code %d, body %s", code, body)
+	}
+
+	code, _, body = do(auth.RoleStaff, "POST", "/drugs/labels", `{"pricesChangedSince": "2999-01-01"}`)
+	if code != 400 {
+		t.Fatalf("render without changed prices: got code %d, body %s", code, body)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/turfaa/vmedis-proxy-api/database/models"
 	"github.com/turfaa/vmedis-proxy-api/vmedis/v1"
@@ -199,9 +201,43 @@ func FromDBDrugUnit(unit models.DrugUnit) Unit {
 	}
 }
 
+// UnitSizes returns the content of each unit in the smallest unit, by the lowercased unit name.
+// The units must be sorted from the smallest to the largest, as returned by Database.GetDrugUnitsByDrugVmedisCodes.
+// It returns false if a unit has no conversion to its parent unit.
+func UnitSizes(units []Unit) (map[string]float64, bool) {
+	sizes := make(map[string]float64, len(units))
+
+	size := 1.0
+	for i, unit := range units {
+		if i > 0 {
+			if unit.ConversionToParentUnit <= 0 {
+				return nil, false
+			}
+
+			size *= unit.ConversionToParentUnit
+		}
+
+		sizes[strings.ToLower(unit.Unit)] = size
+	}
+
+	return sizes, true
+}
+
 // SaleStatistics represents statistics of sales of a drug.
 type SaleStatistics struct {
 	DrugCode      string
 	NumberOfSales int
 	TotalAmount   float64
 }
+
+// SoldQuantity is the quantity of a drug sold in a sale, in the smallest unit of the drug.
+type SoldQuantity struct {
+	DrugCode string
+
+	// Quantity is in Unit, the smallest unit of the drug, or the sold unit if it can't be converted.
+	Quantity float64
+	Unit     string
+
+	Total  float64
+	SoldAt time.Time
+}
@@ -0,0 +1,62 @@
+package drug
+
+import (
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+)
+
+// Barcode is a barcode (EAN/GTIN) printed on a drug package.
+type Barcode struct {
+	ID             uint   `json:"id"`
+	Barcode        string `json:"barcode"`
+	DrugVmedisCode string `json:"drugVmedisCode"`
+	Unit           string `json:"unit"`
+}
+
+func FromDBBarcode(barcode models.DrugBarcode) Barcode {
+	return Barcode{
+		ID:             barcode.ID,
+		Barcode:        barcode.Barcode,
+		DrugVmedisCode: barcode.DrugVmedisCode,
+		Unit:           barcode.Unit,
+	}
+}
+
+type CreateBarcodeRequest struct {
+	Barcode  string `json:"barcode" binding:"required"`
+	DrugCode string `json:"drugCode" binding:"required"`
+	Unit     string `json:"unit"`
+}
+
+// UpdateBarcodeRequest updates a barcode.
+// Nil fields are left unchanged.
+type UpdateBarcodeRequest struct {
+	Barcode  *string `json:"barcode"`
+	DrugCode *string `json:"drugCode"`
+	Unit     *string `json:"unit"`
+}
+
+type BarcodeResponse struct {
+	Barcode Barcode `json:"barcode"`
+}
+
+// ImportBarcodesRequest imports barcodes from CSV text.
+// See Service.ImportBarcodes for the accepted columns.
+type ImportBarcodesRequest struct {
+	CSV string `json:"csv" binding:"required"`
+}
+
+// ImportBarcodesResult is the result of a bulk barcode import.
+// Errors describe the skipped rows.
+type ImportBarcodesResult struct {
+	Imported int      `json:"imported"`
+	Errors   []string `json:"errors"`
+}
+
+// DrugByBarcodeResponse is the drug of a scanned barcode,
+// rendered the same way as the drugs of DrugsResponseV2.
+type DrugByBarcodeResponse struct {
+	Drug DrugsResponseV2_Drug `json:"drug"`
+
+	// Unit is the unit the barcode is printed on. Empty if unknown.
+	Unit string `json:"unit"`
+}
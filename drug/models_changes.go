@@ -0,0 +1,33 @@
+package drug
+
+import (
+	"time"
+)
+
+// DrugChangesRequest is the request schema for the drug changes API.
+type DrugChangesRequest struct {
+	// Since is the cursor returned by the previous call. Empty returns every existing drug.
+	Since string `form:"since"`
+}
+
+// DrugChangesResponse is the response schema for the drug changes API.
+type DrugChangesResponse struct {
+	Drugs        []DrugsResponseV2_Drug `json:"drugs"`
+	RemovedDrugs []RemovedDrug          `json:"removedDrugs"`
+
+	// Cursor is to be passed as since in the next call.
+	Cursor string `json:"cursor"`
+}
+
+// RemovedDrug is the tombstone of a drug removed from Vmedis.
+type RemovedDrug struct {
+	VmedisCode string    `json:"vmedisCode"`
+	RemovedAt  time.Time `json:"removedAt"`
+}
+
+// DrugChanges is the drugs changed since a cursor.
+type DrugChanges struct {
+	Drugs        []Drug
+	RemovedDrugs []RemovedDrug
+	Cursor       string
+}
@@ -0,0 +1,21 @@
+package drug
+
+import (
+	"time"
+)
+
+// DeadLetter is a drug message that still failed after the consumer retries.
+type DeadLetter struct {
+	ID            string
+	OriginalTopic string
+	Key           string
+	Value         string
+	Error         string
+	Attempts      int
+	FailedAt      time.Time
+}
+
+// RequeueDeadLettersResponse is the response schema for the requeue dead letters API.
+type RequeueDeadLettersResponse struct {
+	Requeued int `json:"requeued"`
+}
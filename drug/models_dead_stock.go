@@ -0,0 +1,50 @@
+package drug
+
+import (
+	"time"
+)
+
+const (
+	defaultDeadStockDays = 90
+)
+
+// DeadStocksRequest is the request schema for the dead stock report APIs.
+type DeadStocksRequest struct {
+	// Days is the number of days to look back for sales, defaulting to 90.
+	Days int `json:"days" form:"days"`
+
+	// MaxSales is the maximum number of sales within the days for a drug to be reported.
+	// Zero only reports the drugs without sales.
+	MaxSales int `json:"maxSales" form:"maxSales"`
+}
+
+// DeadStock is a drug in stock that barely sold.
+type DeadStock struct {
+	DrugVmedisCode string
+	DrugName       string
+	Stocks         []Stock
+	NumberOfSales  int
+
+	// StockValue is the value of the stocks at the latest procurement price.
+	// It is nil if the drug was never procured or its procurement unit can't be converted into its stock units.
+	StockValue *float64
+
+	// LastSoldAt and LastProcuredAt are nil if the drug was never sold or procured.
+	LastSoldAt     *time.Time
+	LastProcuredAt *time.Time
+}
+
+// drugLastProcurement is the latest procurement of a drug.
+type drugLastProcurement struct {
+	DrugCode       string
+	Unit           string
+	TotalUnitPrice float64
+	UnitTaxedPrice float64
+	InvoiceDate    time.Time
+}
+
+// drugLastSale is the latest sale of a drug.
+type drugLastSale struct {
+	DrugCode string
+	SoldAt   time.Time
+}
@@ -0,0 +1,82 @@
+package drug
+
+import (
+	"strings"
+
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+	"github.com/turfaa/vmedis-proxy-api/pkg2/slices2"
+)
+
+// EquivalenceGroup is a group of drugs that can substitute each other.
+type EquivalenceGroup struct {
+	ID               uint     `json:"id"`
+	Name             string   `json:"name"`
+	ActiveIngredient string   `json:"activeIngredient"`
+	Strength         string   `json:"strength"`
+	DosageForm       string   `json:"dosageForm"`
+	DrugVmedisCodes  []string `json:"drugVmedisCodes"`
+}
+
+func FromDBEquivalenceGroup(group models.DrugEquivalenceGroup) EquivalenceGroup {
+	return EquivalenceGroup{
+		ID:               group.ID,
+		Name:             group.Name,
+		ActiveIngredient: group.ActiveIngredient,
+		Strength:         group.Strength,
+		DosageForm:       group.DosageForm,
+		DrugVmedisCodes: slices2.Map(group.Members, func(member models.DrugEquivalenceGroupMember) string {
+			return member.DrugVmedisCode
+		}),
+	}
+}
+
+// CreateEquivalenceGroupRequest creates an equivalence group.
+// DrugCodes are the vmedis codes of the members, separated by commas or new lines.
+type CreateEquivalenceGroupRequest struct {
+	Name             string `json:"name" binding:"required"`
+	ActiveIngredient string `json:"activeIngredient"`
+	Strength         string `json:"strength"`
+	DosageForm       string `json:"dosageForm"`
+	DrugCodes        string `json:"drugCodes"`
+}
+
+// UpdateEquivalenceGroupRequest updates an equivalence group.
+// Nil fields are left unchanged.
+type UpdateEquivalenceGroupRequest struct {
+	Name             *string `json:"name"`
+	ActiveIngredient *string `json:"activeIngredient"`
+	Strength         *string `json:"strength"`
+	DosageForm       *string `json:"dosageForm"`
+	DrugCodes        *string `json:"drugCodes"`
+}
+
+type EquivalenceGroupResponse struct {
+	EquivalenceGroup EquivalenceGroup `json:"equivalenceGroup"`
+}
+
+// SeedEquivalenceGroupsResult is the result of seeding equivalence groups from KFA codes.
+type SeedEquivalenceGroupsResult struct {
+	CreatedGroups int `json:"createdGroups"`
+	AddedDrugs    int `json:"addedDrugs"`
+}
+
+// parseDrugCodes parses vmedis codes separated by commas, semicolons, or whitespaces.
+// Duplicated codes are removed.
+func parseDrugCodes(s string) []string {
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ';' || r == ' ' || r == '\n' || r == '\r' || r == '\t'
+	})
+
+	seen := make(map[string]bool, len(fields))
+	codes := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if seen[field] {
+			continue
+		}
+
+		seen[field] = true
+		codes = append(codes, field)
+	}
+
+	return codes
+}
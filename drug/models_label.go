@@ -0,0 +1,27 @@
+package drug
+
+// Label is a shelf price label of a drug unit.
+type Label struct {
+	DrugVmedisCode string
+	DrugName       string
+	Unit           string
+	Price          float64
+
+	// Conversion is the content of the unit in its parent unit, e.g. "(10 Tablet)".
+	Conversion string
+
+	// Barcode is empty if the drug has no barcode or barcodes are not requested.
+	Barcode string
+}
+
+// GenerateLabelsRequest selects the drugs to print shelf price labels for.
+// Either DrugCodes or PricesChangedSince must be set.
+type GenerateLabelsRequest struct {
+	// DrugCodes are the vmedis codes of the drugs, separated by commas or new lines.
+	DrugCodes string `json:"drugCodes"`
+
+	// PricesChangedSince selects the drugs whose prices changed since the date, in the YYYY-MM-DD format.
+	PricesChangedSince string `json:"pricesChangedSince"`
+
+	WithBarcodes bool `json:"withBarcodes"`
+}
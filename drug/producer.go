@@ -5,10 +5,10 @@ import (
 	"fmt"
 	"strconv"
 
-	"github.com/segmentio/kafka-go"
 	"google.golang.org/protobuf/encoding/protojson"
 
 	"github.com/turfaa/vmedis-proxy-api/kafkapb"
+	"github.com/turfaa/vmedis-proxy-api/pkg2/queue"
 )
 
 const (
@@ -17,25 +17,25 @@ const (
 )
 
 type Producer struct {
-	writer *kafka.Writer
+	queue queue.Publisher
 }
 
 func (p *Producer) ProduceUpdatedDrugsByVmedisID(ctx context.Context, messages []*kafkapb.UpdatedDrugByVmedisID) error {
-	kafkaMessages := make([]kafka.Message, 0, len(messages))
+	queueMessages := make([]queue.Message, 0, len(messages))
 	for _, message := range messages {
 		messageJson, err := protojson.Marshal(message)
 		if err != nil {
 			return fmt.Errorf("failed to marshal message: %w", err)
 		}
 
-		kafkaMessages = append(kafkaMessages, kafka.Message{
+		queueMessages = append(queueMessages, queue.Message{
 			Topic: VmedisIDUpdatedTopic,
 			Key:   []byte(strconv.FormatInt(message.VmedisId, 10)),
 			Value: messageJson,
 		})
 	}
 
-	if err := p.writer.WriteMessages(ctx, kafkaMessages...); err != nil {
+	if err := p.queue.Publish(ctx, queueMessages...); err != nil {
 		return fmt.Errorf("failed to produce message: %w", err)
 	}
 
@@ -43,29 +43,29 @@ func (p *Producer) ProduceUpdatedDrugsByVmedisID(ctx context.Context, messages [
 }
 
 func (p *Producer) ProduceUpdatedDrugByVmedisCode(ctx context.Context, messages []*kafkapb.UpdatedDrugByVmedisCode) error {
-	kafkaMessages := make([]kafka.Message, 0, len(messages))
+	queueMessages := make([]queue.Message, 0, len(messages))
 	for _, message := range messages {
 		messageJson, err := protojson.Marshal(message)
 		if err != nil {
 			return fmt.Errorf("failed to marshal message: %w", err)
 		}
 
-		kafkaMessages = append(kafkaMessages, kafka.Message{
+		queueMessages = append(queueMessages, queue.Message{
 			Topic: VmedisCodeUpdatedTopic,
 			Key:   []byte(message.VmedisCode),
 			Value: messageJson,
 		})
 	}
 
-	if err := p.writer.WriteMessages(ctx, kafkaMessages...); err != nil {
+	if err := p.queue.Publish(ctx, queueMessages...); err != nil {
 		return fmt.Errorf("failed to produce message: %w", err)
 	}
 
 	return nil
 }
 
-func NewProducer(writer *kafka.Writer) *Producer {
+func NewProducer(q queue.Publisher) *Producer {
 	return &Producer{
-		writer: writer,
+		queue: q,
 	}
 }
@@ -9,11 +9,12 @@ import (
 	"time"
 
 	"github.com/redis/go-redis/v9"
-	"github.com/segmentio/kafka-go"
 	"gorm.io/gorm"
 
 	"github.com/turfaa/vmedis-proxy-api/database/models"
+	"github.com/turfaa/vmedis-proxy-api/events"
 	"github.com/turfaa/vmedis-proxy-api/kafkapb"
+	"github.com/turfaa/vmedis-proxy-api/pkg2/queue"
 	"github.com/turfaa/vmedis-proxy-api/pkg2/slices2"
 	vmedisv1 "github.com/turfaa/vmedis-proxy-api/vmedis/v1"
 )
@@ -32,12 +33,17 @@ var (
 	}
 )
 
+// ErrDrugNotFound is returned when a drug doesn't exist in DB.
+var ErrDrugNotFound = errors.New("drug not found in DB")
+
 // Service provides business logic related to drugs.
 type Service struct {
-	cache    *Cache
-	db       *Database
-	vmedis   *vmedisv1.Client
-	producer *Producer
+	cache       *Cache
+	db          *Database
+	vmedis      *vmedisv1.Client
+	producer    *Producer
+	events      *events.Producer
+	deadLetters *DeadLetterQueue
 }
 
 // GetDrugs returns all drugs.
@@ -57,8 +63,15 @@ func (s *Service) GetDrugs(ctx context.Context) ([]Drug, error) {
 	}
 
 	go func() {
-		if err := s.cache.SetDrugs(context.Background(), drugs, time.Minute); err != nil {
+		ctx := context.Background()
+		if err := s.cache.SetDrugs(ctx, drugs); err != nil {
 			log.Printf("Error setting drugs to cache: %s", err)
+			return
+		}
+
+		// The reloaded drugs may differ from the ones the cached responses were rendered from.
+		if err := s.cache.BumpDrugsVersion(ctx); err != nil {
+			log.Printf("Error bumping drugs version: %s", err)
 		}
 	}()
 
@@ -179,7 +192,8 @@ func sortDrugsBySalesAmount(drugs []Drug, saleStatisticsByDrugCode map[string]Sa
 func (s *Service) DumpDrugsFromVmedisToDB(ctx context.Context) error {
 	log.Println("Dumping drugs from Vmedis to DB")
 
-	requestKey := fmt.Sprintf("dump_drugs_from_vmedis_to_db:%s", time.Now().Format("2006-01-02_15-04-05"))
+	startedAt := time.Now()
+	requestKey := fmt.Sprintf("dump_drugs_from_vmedis_to_db:%s", startedAt.Format("2006-01-02_15-04-05"))
 
 	drugs, err := s.vmedis.GetAllDrugs(ctx)
 	if err != nil {
@@ -203,6 +217,11 @@ func (s *Service) DumpDrugsFromVmedisToDB(ctx context.Context) error {
 		}
 		log.Println("Upserted drugs to DB")
 
+		if err := s.refreshDrugChanges(ctx, slices2.Map(batch, func(drug vmedisv1.Drug) string { return drug.VmedisCode })); err != nil {
+			log.Printf("Error refreshing drug changes: %s", err)
+			errs = append(errs, err)
+		}
+
 		updatedDrugs := make([]*kafkapb.UpdatedDrugByVmedisID, 0, len(batch))
 		for _, drug := range batch {
 			updatedDrugs = append(updatedDrugs, &kafkapb.UpdatedDrugByVmedisID{
@@ -227,6 +246,30 @@ func (s *Service) DumpDrugsFromVmedisToDB(ctx context.Context) error {
 		return fmt.Errorf("dump drugs from Vmedis to DB: %w", errors.Join(errs...))
 	}
 
+	// Every drug still in Vmedis has just been upserted, so the drugs not updated by this dump are gone.
+	// It's skipped when Vmedis returns nothing, to not remove the whole catalog on a bad response.
+	if len(drugs) > 0 {
+		removed, err := s.db.MarkDrugsRemovedNotUpdatedSince(ctx, startedAt, time.Now())
+		if err != nil {
+			return fmt.Errorf("mark removed drugs: %w", err)
+		}
+		log.Printf("Marked %d drugs as removed from Vmedis", len(removed))
+
+		if len(removed) > 0 {
+			if err := s.cache.DeleteDrugs(ctx, removed); err != nil {
+				return fmt.Errorf("delete removed drugs from cache: %w", err)
+			}
+
+			if err := s.cache.BumpDrugsVersion(ctx); err != nil {
+				return fmt.Errorf("bump drugs version: %w", err)
+			}
+		}
+	}
+
+	if err := s.events.ProduceDumpCompleted(ctx, []*kafkapb.DumpCompleted{events.DumpCompleted(events.DumpDrugs, startedAt, len(drugs))}); err != nil {
+		return fmt.Errorf("produce dump completed event: %w", err)
+	}
+
 	log.Println("Finished dumping drugs from Vmedis to DB")
 	return nil
 }
@@ -240,7 +283,7 @@ func (s *Service) DumpDrugDetailsFromVmedisToDBByVmedisCode(ctx context.Context,
 		return fmt.Errorf("get drug %s from DB: %w", vmedisCode, err)
 	}
 	if len(drugs) == 0 {
-		return fmt.Errorf("drug %s not found in DB", vmedisCode)
+		return fmt.Errorf("%w: %s", ErrDrugNotFound, vmedisCode)
 	}
 
 	drug := drugs[0]
@@ -259,6 +302,11 @@ func (s *Service) DumpDrugDetailsFromVmedisToDBByVmedisID(ctx context.Context, v
 	}
 	log.Printf("Got drug %d from Vmedis", vmedisID)
 
+	before, err := s.db.GetDrugByVmedisID(ctx, vmedisID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("get drug %d from DB: %w", vmedisID, err)
+	}
+
 	log.Printf("Upserting drug %d details to DB", vmedisID)
 	if err := s.db.UpsertVmedisDrug(
 		ctx,
@@ -282,16 +330,146 @@ func (s *Service) DumpDrugDetailsFromVmedisToDBByVmedisID(ctx context.Context, v
 	}
 	log.Printf("Upserted %d drug %d stocks to DB", len(drug.Stocks), vmedisID)
 
+	if err := s.refreshDrugChanges(ctx, []string{drug.VmedisCode}); err != nil {
+		return fmt.Errorf("refresh drug %d changes: %w", vmedisID, err)
+	}
+
+	if err := s.produceDrugChangedEvents(ctx, vmedisID, before); err != nil {
+		return fmt.Errorf("produce drug %d changed events: %w", vmedisID, err)
+	}
+
 	log.Printf("Finished dumping drug details of %d from Vmedis to DB", vmedisID)
 	return nil
 }
 
+// produceDrugChangedEvents produces the price and stock changed events of the drug dumped over the before drug,
+// which is empty if the drug is new.
+func (s *Service) produceDrugChangedEvents(ctx context.Context, vmedisID int64, before models.Drug) error {
+	after, err := s.db.GetDrugByVmedisID(ctx, vmedisID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.events.ProduceDrugPriceChanged(ctx, priceChangedEvents(before, after)); err != nil {
+		return err
+	}
+
+	if stockChanged := stockChangedEvent(before, after); stockChanged != nil {
+		if err := s.events.ProduceDrugStockChanged(ctx, []*kafkapb.DrugStockChanged{stockChanged}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// refreshDrugChanges moves the changed_at of the drugs whose content changed,
+// and bumps the drugs version if any did, so the cached drug changes responses are invalidated.
+func (s *Service) refreshDrugChanges(ctx context.Context, vmedisCodes []string) error {
+	changed, err := s.db.RefreshDrugChanges(ctx, vmedisCodes, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if len(changed) == 0 {
+		return nil
+	}
+
+	if err := s.cache.BumpDrugsVersion(ctx); err != nil {
+		return fmt.Errorf("bump drugs version: %w", err)
+	}
+
+	return nil
+}
+
+// RefreshDrug refreshes the changes and the cached drug with the given vmedis code
+// after it's changed in DB outside of the Vmedis dumps.
+func (s *Service) RefreshDrug(ctx context.Context, vmedisCode string) error {
+	if err := s.refreshDrugChanges(ctx, []string{vmedisCode}); err != nil {
+		return fmt.Errorf("refresh drug %s changes: %w", vmedisCode, err)
+	}
+
+	return s.RefreshCachedDrugByVmedisCode(ctx, vmedisCode)
+}
+
+// RefreshCachedDrugByVmedisCode updates the cached drug with the given vmedis code from DB.
+func (s *Service) RefreshCachedDrugByVmedisCode(ctx context.Context, vmedisCode string) error {
+	drugs, err := s.db.GetDrugsByVmedisCodesUpdatedAfter(ctx, []string{vmedisCode}, time.Time{})
+	if err != nil {
+		return fmt.Errorf("get drug %s from DB: %w", vmedisCode, err)
+	}
+	if len(drugs) == 0 {
+		return fmt.Errorf("%w: %s", ErrDrugNotFound, vmedisCode)
+	}
+
+	return s.refreshCachedDrug(ctx, drugs[0])
+}
+
+// RefreshCachedDrugByVmedisID updates the cached drug with the given vmedis ID from DB.
+func (s *Service) RefreshCachedDrugByVmedisID(ctx context.Context, vmedisID int64) error {
+	drug, err := s.db.GetDrugByVmedisID(ctx, vmedisID)
+	if err != nil {
+		return fmt.Errorf("get drug %d from DB: %w", vmedisID, err)
+	}
+
+	return s.refreshCachedDrug(ctx, drug)
+}
+
+// refreshCachedDrug updates the cached drug, and bumps the drugs version if it changed,
+// so the cached drugs responses are invalidated.
+func (s *Service) refreshCachedDrug(ctx context.Context, dbDrug models.Drug) error {
+	if dbDrug.RemovedAt != nil {
+		if err := s.cache.DeleteDrugs(ctx, []string{dbDrug.VmedisCode}); err != nil {
+			return fmt.Errorf("delete drug %s from cache: %w", dbDrug.VmedisCode, err)
+		}
+
+		if err := s.cache.BumpDrugsVersion(ctx); err != nil {
+			return fmt.Errorf("bump drugs version: %w", err)
+		}
+
+		return nil
+	}
+
+	changed, err := s.cache.SetDrug(ctx, FromDBDrug(dbDrug))
+	if err != nil {
+		return fmt.Errorf("set drug %s to cache: %w", dbDrug.VmedisCode, err)
+	}
+
+	if !changed {
+		return nil
+	}
+
+	if err := s.cache.BumpDrugsVersion(ctx); err != nil {
+		return fmt.Errorf("bump drugs version: %w", err)
+	}
+
+	return nil
+}
+
+// GetDrugsVersion returns the version of the drugs, which changes every time any drug changes.
+func (s *Service) GetDrugsVersion(ctx context.Context) (int64, error) {
+	return s.cache.GetDrugsVersion(ctx)
+}
+
+// GetDeadLetters returns the drug messages that failed after the consumer retries and haven't been requeued.
+func (s *Service) GetDeadLetters(ctx context.Context) ([]DeadLetter, error) {
+	return s.deadLetters.GetDeadLetters(ctx)
+}
+
+// RequeueDeadLetters produces the drug messages that failed after the consumer retries back to their original topics,
+// and returns the number of requeued messages.
+func (s *Service) RequeueDeadLetters(ctx context.Context) (int, error) {
+	return s.deadLetters.Requeue(ctx)
+}
+
 // NewService creates a new drug service.
-func NewService(redisClient redis.UniversalClient, db *gorm.DB, vmedisClient *vmedisv1.Client, kafkaWriter *kafka.Writer) *Service {
+func NewService(redisClient redis.UniversalClient, db *gorm.DB, vmedisClient *vmedisv1.Client, q queue.Queue) *Service {
 	return &Service{
-		cache:    NewCache(redisClient),
-		db:       NewDatabase(db),
-		vmedis:   vmedisClient,
-		producer: NewProducer(kafkaWriter),
+		cache:       NewCache(redisClient),
+		db:          NewDatabase(db),
+		vmedis:      vmedisClient,
+		producer:    NewProducer(q),
+		events:      events.NewProducer(q),
+		deadLetters: NewDeadLetterQueue(q),
 	}
 }
@@ -0,0 +1,160 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/turfaa/vmedis-proxy-api/kafkapb"
+	"github.com/turfaa/vmedis-proxy-api/pkg2/queue"
+)
+
+// The topics are versioned, so a breaking change to an event is published to a new topic
+// while the consumers of the old one keep working. See kafkapb/events.proto for the schemas.
+const (
+	DrugPriceChangedTopic    = "drug.price_changed.v1"
+	DrugStockChangedTopic    = "drug.stock_changed.v1"
+	SaleRecordedTopic        = "sale.recorded.v1"
+	SaleDeletedTopic         = "sale.deleted.v1"
+	ProcurementRecordedTopic = "procurement.recorded.v1"
+	ShiftClosedTopic         = "shift.closed.v1"
+	DumpCompletedTopic       = "dump.completed.v1"
+	RejectedDrugCreatedTopic = "rejected_drug.created.v1"
+)
+
+// The names of the dumps in DumpCompleted events.
+const (
+	DumpDrugs        = "drugs"
+	DumpSales        = "sales"
+	DumpProcurements = "procurements"
+	DumpStockOpnames = "stock_opnames"
+	DumpShifts       = "shifts"
+)
+
+// Producer publishes the domain events.
+type Producer struct {
+	queue queue.Publisher
+}
+
+func (p *Producer) ProduceDrugPriceChanged(ctx context.Context, events []*kafkapb.DrugPriceChanged) error {
+	return produce(ctx, p.queue, DrugPriceChangedTopic, events,
+		func(e *kafkapb.DrugPriceChanged) string { return e.GetVmedisCode() },
+		func(e *kafkapb.DrugPriceChanged, m *kafkapb.EventMetadata) { e.Metadata = m },
+	)
+}
+
+func (p *Producer) ProduceDrugStockChanged(ctx context.Context, events []*kafkapb.DrugStockChanged) error {
+	return produce(ctx, p.queue, DrugStockChangedTopic, events,
+		func(e *kafkapb.DrugStockChanged) string { return e.GetVmedisCode() },
+		func(e *kafkapb.DrugStockChanged, m *kafkapb.EventMetadata) { e.Metadata = m },
+	)
+}
+
+func (p *Producer) ProduceSaleRecorded(ctx context.Context, events []*kafkapb.SaleRecorded) error {
+	return produce(ctx, p.queue, SaleRecordedTopic, events,
+		func(e *kafkapb.SaleRecorded) string { return e.GetAfter().GetInvoiceNumber() },
+		func(e *kafkapb.SaleRecorded, m *kafkapb.EventMetadata) { e.Metadata = m },
+	)
+}
+
+func (p *Producer) ProduceSaleDeleted(ctx context.Context, events []*kafkapb.SaleDeleted) error {
+	return produce(ctx, p.queue, SaleDeletedTopic, events,
+		func(e *kafkapb.SaleDeleted) string { return e.GetInvoiceNumber() },
+		func(e *kafkapb.SaleDeleted, m *kafkapb.EventMetadata) { e.Metadata = m },
+	)
+}
+
+func (p *Producer) ProduceProcurementRecorded(ctx context.Context, events []*kafkapb.ProcurementRecorded) error {
+	return produce(ctx, p.queue, ProcurementRecordedTopic, events,
+		func(e *kafkapb.ProcurementRecorded) string { return e.GetAfter().GetInvoiceNumber() },
+		func(e *kafkapb.ProcurementRecorded, m *kafkapb.EventMetadata) { e.Metadata = m },
+	)
+}
+
+func (p *Producer) ProduceShiftClosed(ctx context.Context, events []*kafkapb.ShiftClosed) error {
+	return produce(ctx, p.queue, ShiftClosedTopic, events,
+		func(e *kafkapb.ShiftClosed) string { return e.GetShift().GetCode() },
+		func(e *kafkapb.ShiftClosed, m *kafkapb.EventMetadata) { e.Metadata = m },
+	)
+}
+
+func (p *Producer) ProduceDumpCompleted(ctx context.Context, events []*kafkapb.DumpCompleted) error {
+	return produce(ctx, p.queue, DumpCompletedTopic, events,
+		func(e *kafkapb.DumpCompleted) string { return e.GetDump() },
+		func(e *kafkapb.DumpCompleted, m *kafkapb.EventMetadata) { e.Metadata = m },
+	)
+}
+
+func (p *Producer) ProduceRejectedDrugCreated(ctx context.Context, events []*kafkapb.RejectedDrugCreated) error {
+	return produce(ctx, p.queue, RejectedDrugCreatedTopic, events,
+		func(e *kafkapb.RejectedDrugCreated) string { return strconv.FormatUint(e.GetId(), 10) },
+		func(e *kafkapb.RejectedDrugCreated, m *kafkapb.EventMetadata) { e.Metadata = m },
+	)
+}
+
+// produce fills the metadata of the events that don't have one yet, and publishes them to the topic.
+func produce[E interface {
+	proto.Message
+	GetMetadata() *kafkapb.EventMetadata
+}](
+	ctx context.Context,
+	publisher queue.Publisher,
+	topic string,
+	events []E,
+	key func(E) string,
+	setMetadata func(E, *kafkapb.EventMetadata),
+) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	now := timestamppb.New(time.Now())
+
+	messages := make([]queue.Message, 0, len(events))
+	for _, event := range events {
+		if event.GetMetadata() == nil {
+			setMetadata(event, &kafkapb.EventMetadata{
+				EventId:    uuid.NewString(),
+				OccurredAt: now,
+			})
+		}
+
+		eventJson, err := protojson.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("failed to marshal %s event: %w", topic, err)
+		}
+
+		messages = append(messages, queue.Message{
+			Topic: topic,
+			Key:   []byte(key(event)),
+			Value: eventJson,
+		})
+	}
+
+	if err := publisher.Publish(ctx, messages...); err != nil {
+		return fmt.Errorf("failed to produce %s events: %w", topic, err)
+	}
+
+	return nil
+}
+
+// DumpCompleted returns the event of a dump that started at startedAt and stored the given number of records.
+func DumpCompleted(dump string, startedAt time.Time, records int) *kafkapb.DumpCompleted {
+	return &kafkapb.DumpCompleted{
+		Dump:      dump,
+		StartedAt: timestamppb.New(startedAt),
+		Records:   int64(records),
+	}
+}
+
+func NewProducer(q queue.Publisher) *Producer {
+	return &Producer{
+		queue: q,
+	}
+}
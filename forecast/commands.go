@@ -0,0 +1,20 @@
+package forecast
+
+import (
+	"context"
+	"log"
+
+	"gorm.io/gorm"
+)
+
+// BuildForecasts forecasts the demand of every drug sold in the given number of days until the given date.
+func BuildForecasts(ctx context.Context, db *gorm.DB, soldQuantitiesGetter SoldQuantitiesGetter, until string, historyDays int) {
+	service := NewService(db, soldQuantitiesGetter)
+
+	history, forecasted, err := service.Build(ctx, until, historyDays)
+	if err != nil {
+		log.Fatalf("Build: %s", err)
+	}
+
+	log.Printf("Forecasted %d drugs from their sales from %s to %s", forecasted, history.Start, history.End)
+}
@@ -0,0 +1,57 @@
+package forecast
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+)
+
+const insertBatchSize = 500
+
+type Database struct {
+	db *gorm.DB
+}
+
+func NewDatabase(db *gorm.DB) *Database {
+	return &Database{db: db}
+}
+
+// ReplaceForecasts replaces the forecasts of every drug with the given forecasts.
+func (d *Database) ReplaceForecasts(ctx context.Context, forecasts []models.DrugForecast) error {
+	return d.dbCtx(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("1 = 1").Delete(&models.DrugForecast{}).Error; err != nil {
+			return fmt.Errorf("delete forecasts: %w", err)
+		}
+
+		if len(forecasts) == 0 {
+			return nil
+		}
+
+		if err := tx.CreateInBatches(&forecasts, insertBatchSize).Error; err != nil {
+			return fmt.Errorf("create %d forecasts: %w", len(forecasts), err)
+		}
+
+		return nil
+	})
+}
+
+// GetDrugForecasts returns the daily forecasts of a drug, sorted by the date.
+func (d *Database) GetDrugForecasts(ctx context.Context, drugVmedisCode string) ([]models.DrugForecast, error) {
+	var forecasts []models.DrugForecast
+	if err := d.dbCtx(ctx).
+		Where("drug_vmedis_code = ?", drugVmedisCode).
+		Order("date").
+		Find(&forecasts).
+		Error; err != nil {
+		return nil, fmt.Errorf("get forecasts of drug %s from db: %w", drugVmedisCode, err)
+	}
+
+	return forecasts, nil
+}
+
+func (d *Database) dbCtx(ctx context.Context) *gorm.DB {
+	return d.db.WithContext(ctx)
+}
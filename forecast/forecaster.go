@@ -0,0 +1,126 @@
+package forecast
+
+import (
+	"math"
+
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+)
+
+const (
+	// seasonLength is the length of the weekly seasonality in days.
+	seasonLength = 7
+
+	// The smoothing factors of the level and the seasonality of the exponential smoothing.
+	levelSmoothing  = 0.3
+	seasonSmoothing = 0.1
+)
+
+// forecaster forecasts the daily demand of the given number of days after the daily history.
+type forecaster struct {
+	model    models.ForecastModel
+	forecast func(history []float64, horizon int) []float64
+}
+
+// forecasters are the candidate models, from the simplest, so ties are won by the simpler model.
+var forecasters = []forecaster{
+	{model: models.ForecastModelMovingAverage7, forecast: movingAverage(7)},
+	{model: models.ForecastModelMovingAverage28, forecast: movingAverage(28)},
+	{model: models.ForecastModelSeasonalExponentialSmoothing, forecast: seasonalExponentialSmoothing},
+}
+
+// fittedForecast is the forecast of the model with the lowest backtest error.
+type fittedForecast struct {
+	Model         models.ForecastModel
+	BacktestError float64
+	Quantities    []float64
+}
+
+// fit backtests every model by forecasting the last backtestDays days of the history from the days before them,
+// then forecasts the horizon from the whole history with the model with the lowest mean absolute error.
+// The history must be longer than backtestDays.
+func fit(history []float64, backtestDays int, horizon int) fittedForecast {
+	train, test := history[:len(history)-backtestDays], history[len(history)-backtestDays:]
+
+	best := fittedForecast{BacktestError: math.Inf(1)}
+	var bestForecaster forecaster
+	for _, f := range forecasters {
+		backtestError := meanAbsoluteError(f.forecast(train, backtestDays), test)
+		if backtestError < best.BacktestError {
+			best.Model = f.model
+			best.BacktestError = backtestError
+			bestForecaster = f
+		}
+	}
+
+	best.Quantities = bestForecaster.forecast(history, horizon)
+	return best
+}
+
+// movingAverage forecasts the average of the last days of the history for every day of the horizon.
+func movingAverage(days int) func(history []float64, horizon int) []float64 {
+	return func(history []float64, horizon int) []float64 {
+		window := history[max(len(history)-days, 0):]
+
+		average := 0.0
+		if len(window) > 0 {
+			for _, quantity := range window {
+				average += quantity
+			}
+			average /= float64(len(window))
+		}
+
+		quantities := make([]float64, horizon)
+		for i := range quantities {
+			quantities[i] = average
+		}
+
+		return quantities
+	}
+}
+
+// seasonalExponentialSmoothing forecasts by additive exponential smoothing with weekly seasonality and without trend.
+// The level starts at the average of the first week and the seasonality at the deviations of the first week from it.
+// Histories shorter than a week are forecasted by their average.
+func seasonalExponentialSmoothing(history []float64, horizon int) []float64 {
+	if len(history) < seasonLength {
+		return movingAverage(len(history))(history, horizon)
+	}
+
+	level := 0.0
+	for _, quantity := range history[:seasonLength] {
+		level += quantity
+	}
+	level /= seasonLength
+
+	season := make([]float64, seasonLength)
+	for i, quantity := range history[:seasonLength] {
+		season[i] = quantity - level
+	}
+
+	for t := seasonLength; t < len(history); t++ {
+		s := season[t%seasonLength]
+		newLevel := levelSmoothing*(history[t]-s) + (1-levelSmoothing)*level
+		season[t%seasonLength] = seasonSmoothing*(history[t]-newLevel) + (1-seasonSmoothing)*s
+		level = newLevel
+	}
+
+	quantities := make([]float64, horizon)
+	for i := range quantities {
+		quantities[i] = max(level+season[(len(history)+i)%seasonLength], 0)
+	}
+
+	return quantities
+}
+
+func meanAbsoluteError(forecast []float64, actual []float64) float64 {
+	if len(actual) == 0 {
+		return 0
+	}
+
+	total := 0.0
+	for i := range actual {
+		total += math.Abs(forecast[i] - actual[i])
+	}
+
+	return total / float64(len(actual))
+}
@@ -0,0 +1,63 @@
+package forecast
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/turfaa/vmedis-proxy-api/cui"
+)
+
+type ApiHandler struct {
+	service *Service
+}
+
+func NewApiHandler(service *Service) *ApiHandler {
+	return &ApiHandler{service: service}
+}
+
+// GetDrugForecast returns the expected daily demand of a drug as a display-ready table, with the total in the footer.
+// The row IDs are the dates.
+func (h *ApiHandler) GetDrugForecast(c *gin.Context) {
+	forecast, err := h.service.GetDrugForecast(c.Request.Context(), c.Param("code"))
+	if err != nil {
+		if errors.Is(err, ErrNoForecast) {
+			c.JSON(404, gin.H{"error": fmt.Sprintf("forecast not found: %s", err)})
+			return
+		}
+
+		c.JSON(500, gin.H{"error": fmt.Sprintf("failed to get drug forecast: %s", err)})
+		return
+	}
+
+	c.JSON(200, h.transformDrugForecastToTable(forecast))
+}
+
+func (h *ApiHandler) transformDrugForecastToTable(forecast DrugForecast) cui.Table {
+	rows := make([]cui.Row, len(forecast.Days))
+	for i, day := range forecast.Days {
+		rows[i] = cui.Row{
+			ID: day.Date,
+			Columns: []string{
+				day.Date,
+				formatQuantity(day.Quantity, forecast.Unit),
+			},
+		}
+	}
+
+	return cui.Table{
+		Header: []string{"Tanggal", "Perkiraan Permintaan"},
+		Rows:   rows,
+		Footer: []string{
+			fmt.Sprintf("Total (model %s, galat %s)", forecast.Model, strconv.FormatFloat(forecast.BacktestError, 'f', 2, 64)),
+			formatQuantity(forecast.Total(), forecast.Unit),
+		},
+	}
+}
+
+func formatQuantity(quantity float64, unit string) string {
+	return strings.TrimSpace(strconv.FormatFloat(quantity, 'f', 1, 64) + " " + unit)
+}
@@ -0,0 +1,174 @@
+package forecast_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/turfaa/vmedis-proxy-api/cui"
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+	"github.com/turfaa/vmedis-proxy-api/drug"
+	"github.com/turfaa/vmedis-proxy-api/forecast"
+)
+
+// TestForecast checks that the daily demands are counted in the smallest unit, that each drug gets
+// the model with the lowest backtest error, with ties won by the simpler model, and that the stored
+// forecasts are shown per drug and replaced by the next build.
+func TestForecast(t *testing.T) {
+	ctx := context.Background()
+	db, service, router := setup(t)
+
+	for _, d := range []models.Drug{
+		{VmedisID: 1, VmedisCode: "SANMOL", Name: "SANMOL 500MG"},
+		{VmedisID: 2, VmedisCode: "PROMAG", Name: "PROMAG"},
+	} {
+		mustCreate(t, db, &d)
+	}
+
+	mustCreate(t, db, &[]models.DrugUnit{
+		{DrugVmedisCode: "SANMOL", Unit: "Strip"},
+		{DrugVmedisCode: "SANMOL", Unit: "Box", ParentUnit: "Strip", ConversionToParentUnit: 10, UnitOrder: 1},
+		{DrugVmedisCode: "PROMAG", Unit: "Strip"},
+	})
+
+	// A box of SANMOL is 10 strips, so SANMOL sells 10 strips every day of March 2024,
+	// while PROMAG only sells on Saturdays.
+	var sales []models.Sale
+	for day := 1; day <= 28; day++ {
+		soldAt := time.Date(2024, 3, day, 10, 0, 0, 0, time.Local)
+		units := []models.SaleUnit{{DrugCode: "SANMOL", Amount: 1, Unit: "Box"}}
+		if soldAt.Weekday() == time.Saturday {
+			units = append(units, models.SaleUnit{DrugCode: "PROMAG", Amount: 14, Unit: "Strip"})
+		}
+
+		sales = append(sales, newSale(soldAt.Format("S-20060102"), soldAt, units...))
+	}
+	mustCreate(t, db, &sales)
+
+	if _, _, err := service.Build(ctx, "2024-03-28", 7); !errors.Is(err, forecast.ErrInvalidForecastRequest) {
+		t.Fatalf("build with a week of history: got error %v, want ErrInvalidForecastRequest", err)
+	}
+
+	history, forecasted, err := service.Build(ctx, "2024-03-28", 28)
+	if err != nil {
+		t.Fatalf("build: %s", err)
+	}
+	if history.Start != "2024-03-01" || history.End != "2024-03-28" || forecasted != 2 {
+		t.Fatalf("build: got history %+v and %d forecasted drugs", history, forecasted)
+	}
+
+	sanmol, err := service.GetDrugForecast(ctx, "SANMOL")
+	if err != nil {
+		t.Fatalf("get SANMOL forecast: %s", err)
+	}
+	if sanmol.Model != models.ForecastModelMovingAverage7 || sanmol.BacktestError != 0 || sanmol.Unit != "Strip" || len(sanmol.Days) != forecast.HorizonDays {
+		t.Errorf("SANMOL forecast: got %+v", sanmol)
+	}
+
+	promag, err := service.GetDrugForecast(ctx, "PROMAG")
+	if err != nil {
+		t.Fatalf("get PROMAG forecast: %s", err)
+	}
+	if promag.Model != models.ForecastModelSeasonalExponentialSmoothing || promag.Total() != 5*14 {
+		t.Errorf("PROMAG forecast: got model %s and total %f, want seasonal smoothing and 70", promag.Model, promag.Total())
+	}
+	// 2024-03-29 is a Friday and 2024-03-30 is the first of the 5 forecasted Saturdays.
+	if promag.Days[0].Date != "2024-03-29" || promag.Days[0].Quantity != 0 || promag.Days[1].Quantity != 14 {
+		t.Errorf("PROMAG first days: got %+v", promag.Days[:2])
+	}
+
+	code, body := do(router, "GET", "/drugs/SANMOL/forecast")
+	table := unmarshal[cui.Table](t, body)
+	if code != 200 || len(table.Rows) != forecast.HorizonDays || table.Rows[0].ID != "2024-03-29" || table.Rows[0].Columns[1] != "10.0 Strip" || table.Footer[1] != "300.0 Strip" {
+		t.Fatalf("SANMOL forecast table: got code %d, body %s", code, body)
+	}
+
+	code, body = do(router, "GET", "/drugs/UNKNOWN/forecast")
+	if code != 404 {
+		t.Fatalf("unknown drug forecast: got code %d, body %s", code, body)
+	}
+
+	// Nothing was sold in April, so the next build leaves no forecasts.
+	if _, forecasted, err := service.Build(ctx, "2024-04-30", 28); err != nil || forecasted != 0 {
+		t.Fatalf("build April: got %d forecasted drugs and error %v", forecasted, err)
+	}
+
+	code, body = do(router, "GET", "/drugs/SANMOL/forecast")
+	if code != 404 {
+		t.Fatalf("SANMOL forecast after April: got code %d, body %s", code, body)
+	}
+}
+
+func newSale(invoiceNumber string, soldAt time.Time, units ...models.SaleUnit) models.Sale {
+	for i := range units {
+		units[i].IDInSale = i + 1
+	}
+
+	return models.Sale{
+		InvoiceNumber: invoiceNumber,
+		SoldAt:        soldAt,
+		SaleUnits:     units,
+	}
+}
+
+func setup(t *testing.T) (*gorm.DB, *forecast.Service, *gin.Engine) {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open database: %s", err)
+	}
+	if err := db.AutoMigrate(
+		&models.Drug{},
+		&models.DrugUnit{},
+		&models.Sale{},
+		&models.SaleUnit{},
+		&models.DrugForecast{},
+	); err != nil {
+		t.Fatalf("migrate database: %s", err)
+	}
+
+	service := forecast.NewService(db, drug.NewDatabase(db))
+	handler := forecast.NewApiHandler(service)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	// Mirrors the route registration in proxy/api.go, without auth and cache middleware.
+	router.GET("/drugs/:code/forecast", handler.GetDrugForecast)
+
+	return db, service, router
+}
+
+func do(router *gin.Engine, method string, path string) (int, string) {
+	req := httptest.NewRequest(method, path, nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w.Code, w.Body.String()
+}
+
+func mustCreate(t *testing.T, db *gorm.DB, value any) {
+	t.Helper()
+
+	if err := db.Create(value).Error; err != nil {
+		t.Fatalf("create %T: %s", value, err)
+	}
+}
+
+func unmarshal[T any](t *testing.T, body string) T {
+	t.Helper()
+
+	var value T
+	if err := json.Unmarshal([]byte(body), &value); err != nil {
+		t.Fatalf("unmarshal %T from %s: %s", value, body, err)
+	}
+
+	return value
+}
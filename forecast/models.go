@@ -0,0 +1,69 @@
+package forecast
+
+import (
+	"time"
+
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+)
+
+// History is the period of the sales the forecasts are built from, with dates in the YYYY-MM-DD format.
+type History struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// DrugForecast is the expected daily demand of a drug, in the smallest unit of the drug.
+type DrugForecast struct {
+	DrugVmedisCode string `json:"drugVmedisCode"`
+
+	// Unit is empty if the drug has no known units.
+	Unit string `json:"unit"`
+
+	// HistoryEnd is the last day of the sales the forecast is built from, in the YYYY-MM-DD format.
+	HistoryEnd string `json:"historyEnd"`
+
+	Model models.ForecastModel `json:"model"`
+
+	// BacktestError is the mean absolute error of the model over the backtest days.
+	BacktestError float64 `json:"backtestError"`
+
+	Days []DailyForecast `json:"days"`
+}
+
+// DailyForecast is the expected demand of a drug on a day, with the date in the YYYY-MM-DD format.
+type DailyForecast struct {
+	Date     string  `json:"date"`
+	Quantity float64 `json:"quantity"`
+}
+
+// Total returns the expected demand of every forecasted day.
+func (f DrugForecast) Total() float64 {
+	total := 0.0
+	for _, day := range f.Days {
+		total += day.Quantity
+	}
+
+	return total
+}
+
+// fromDBDrugForecasts converts the daily forecasts of a drug, which must not be empty.
+func fromDBDrugForecasts(forecasts []models.DrugForecast) DrugForecast {
+	first := forecasts[0]
+
+	days := make([]DailyForecast, len(forecasts))
+	for i, f := range forecasts {
+		days[i] = DailyForecast{
+			Date:     time.Time(f.Date).Format(time.DateOnly),
+			Quantity: f.Quantity,
+		}
+	}
+
+	return DrugForecast{
+		DrugVmedisCode: first.DrugVmedisCode,
+		Unit:           first.Unit,
+		HistoryEnd:     time.Time(first.HistoryEnd).Format(time.DateOnly),
+		Model:          first.Model,
+		BacktestError:  first.BacktestError,
+		Days:           days,
+	}
+}
@@ -0,0 +1,150 @@
+package forecast
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"slices"
+	"time"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+	"github.com/turfaa/vmedis-proxy-api/pkg2/time2"
+)
+
+const (
+	DefaultHistoryDays = 84
+	minHistoryDays     = 28
+	maxHistoryDays     = 730
+
+	// HorizonDays is the number of days forecasted after the history.
+	HorizonDays = 30
+
+	// backtestDays is the number of the last days of the history the models are backtested on.
+	backtestDays = 14
+)
+
+var (
+	// ErrInvalidForecastRequest is returned when the forecast history is invalid.
+	ErrInvalidForecastRequest = errors.New("invalid forecast request")
+
+	// ErrNoForecast is returned when the drug has no forecast.
+	ErrNoForecast = errors.New("no forecast")
+)
+
+type Service struct {
+	db                   *Database
+	soldQuantitiesGetter SoldQuantitiesGetter
+}
+
+func NewService(db *gorm.DB, soldQuantitiesGetter SoldQuantitiesGetter) *Service {
+	return &Service{
+		db:                   NewDatabase(db),
+		soldQuantitiesGetter: soldQuantitiesGetter,
+	}
+}
+
+// Build builds the daily demand of every sold drug in the given number of days until the given date,
+// forecasts the demand of the next HorizonDays days with the model with the lowest backtest error,
+// and stores the forecasts, replacing the previous forecasts. Drugs without sales in the history aren't forecasted.
+// Empty until defaults to yesterday and non-positive historyDays defaults to DefaultHistoryDays.
+func (s *Service) Build(ctx context.Context, until string, historyDays int) (History, int, error) {
+	historyStart, historyEnd, err := forecastHistory(until, historyDays)
+	if err != nil {
+		return History{}, 0, err
+	}
+
+	numberOfDays := dayIndex(historyStart, historyEnd) + 1
+
+	log.Printf("Forecasting drug demands from their sales from %s to %s", historyStart.Format(time.DateOnly), historyEnd.Format(time.DateOnly))
+
+	soldQuantities, err := s.soldQuantitiesGetter.GetSoldQuantitiesBetweenTime(ctx, historyStart, historyEnd)
+	if err != nil {
+		return History{}, 0, fmt.Errorf("get sold quantities: %w", err)
+	}
+
+	series := make(map[string][]float64)
+	smallestUnits := make(map[string]string)
+	for _, sold := range soldQuantities {
+		if series[sold.DrugCode] == nil {
+			series[sold.DrugCode] = make([]float64, numberOfDays)
+			smallestUnits[sold.DrugCode] = sold.Unit
+		}
+
+		day := min(max(dayIndex(historyStart, sold.SoldAt), 0), numberOfDays-1)
+		series[sold.DrugCode][day] += sold.Quantity
+	}
+
+	drugCodes := slices.Sorted(maps.Keys(series))
+
+	forecasts := make([]models.DrugForecast, 0, len(drugCodes)*HorizonDays)
+	for _, code := range drugCodes {
+		fitted := fit(series[code], backtestDays, HorizonDays)
+		for i, quantity := range fitted.Quantities {
+			forecasts = append(forecasts, models.DrugForecast{
+				DrugVmedisCode: code,
+				Date:           datatypes.Date(beginningOfDay(historyEnd).AddDate(0, 0, i+1)),
+				Quantity:       quantity,
+				Unit:           smallestUnits[code],
+				HistoryEnd:     datatypes.Date(historyEnd),
+				Model:          fitted.Model,
+				BacktestError:  fitted.BacktestError,
+			})
+		}
+	}
+
+	if err := s.db.ReplaceForecasts(ctx, forecasts); err != nil {
+		return History{}, 0, fmt.Errorf("store forecasts: %w", err)
+	}
+
+	return History{
+		Start: historyStart.Format(time.DateOnly),
+		End:   historyEnd.Format(time.DateOnly),
+	}, len(drugCodes), nil
+}
+
+// GetDrugForecast returns the forecast of a drug.
+func (s *Service) GetDrugForecast(ctx context.Context, drugVmedisCode string) (DrugForecast, error) {
+	forecasts, err := s.db.GetDrugForecasts(ctx, drugVmedisCode)
+	if err != nil {
+		return DrugForecast{}, fmt.Errorf("get drug forecasts: %w", err)
+	}
+
+	if len(forecasts) == 0 {
+		return DrugForecast{}, fmt.Errorf("%w: drug %s hasn't been forecasted", ErrNoForecast, drugVmedisCode)
+	}
+
+	return fromDBDrugForecasts(forecasts), nil
+}
+
+// forecastHistory returns the beginning of the first day and the end of the last day of the history.
+func forecastHistory(until string, historyDays int) (time.Time, time.Time, error) {
+	if historyDays <= 0 {
+		historyDays = DefaultHistoryDays
+	}
+
+	if historyDays < minHistoryDays || historyDays > maxHistoryDays {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: history days must be between %d and %d", ErrInvalidForecastRequest, minHistoryDays, maxHistoryDays)
+	}
+
+	historyStart, historyEnd, err := time2.DaysUntil(until, historyDays)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: %w", ErrInvalidForecastRequest, err)
+	}
+
+	return historyStart, historyEnd, nil
+}
+
+// dayIndex returns the number of calendar days from the start to the given time.
+func dayIndex(start time.Time, t time.Time) int {
+	return int(beginningOfDay(t).Sub(beginningOfDay(start)).Round(24*time.Hour) / (24 * time.Hour))
+}
+
+func beginningOfDay(t time.Time) time.Time {
+	t = t.In(time.Local)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+}
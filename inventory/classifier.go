@@ -0,0 +1,132 @@
+package inventory
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"time"
+
+	"gorm.io/datatypes"
+
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+)
+
+const (
+	// Drugs are A until the cumulative revenue share of the drugs before them reaches 80%,
+	// then B until 95%, and C for the rest.
+	abcClassAMaxCumulativeShare = 0.8
+	abcClassBMaxCumulativeShare = 0.95
+
+	// Drugs are X if the coefficient of variation of their weekly quantities is at most 0.5,
+	// Y if it is at most 1, and Z otherwise.
+	xyzClassXMaxCoefficientOfVariation = 0.5
+	xyzClassYMaxCoefficientOfVariation = 1.0
+)
+
+// drugDemand is the revenue and the weekly sold quantities of a drug within a period.
+type drugDemand struct {
+	DrugVmedisCode   string
+	Revenue          float64
+	WeeklyQuantities []float64
+}
+
+// classify classifies the drugs by their demands within the period.
+// Drugs without sales are always CZ.
+func classify(periodStart time.Time, periodEnd time.Time, demands []drugDemand) []models.DrugClassification {
+	demands = slices.Clone(demands)
+	slices.SortStableFunc(demands, func(a, b drugDemand) int {
+		if c := cmp.Compare(b.Revenue, a.Revenue); c != 0 {
+			return c
+		}
+
+		return cmp.Compare(a.DrugVmedisCode, b.DrugVmedisCode)
+	})
+
+	totalRevenue := 0.0
+	for _, demand := range demands {
+		totalRevenue += max(demand.Revenue, 0)
+	}
+
+	classifications := make([]models.DrugClassification, len(demands))
+	cumulativeShare := 0.0
+	for i, demand := range demands {
+		share := 0.0
+		if totalRevenue > 0 {
+			share = max(demand.Revenue, 0) / totalRevenue
+		}
+
+		coefficientOfVariation, sold := calculateCoefficientOfVariation(demand.WeeklyQuantities)
+
+		classification := models.DrugClassification{
+			PeriodStart:    datatypes.Date(periodStart),
+			PeriodEnd:      datatypes.Date(periodEnd),
+			DrugVmedisCode: demand.DrugVmedisCode,
+			Revenue:        demand.Revenue,
+			RevenueShare:   share,
+			ABCClass:       abcClass(cumulativeShare, share),
+			XYZClass:       models.XYZClassZ,
+		}
+
+		if sold {
+			classification.CoefficientOfVariation = &coefficientOfVariation
+			classification.XYZClass = xyzClass(coefficientOfVariation)
+		}
+
+		classifications[i] = classification
+		cumulativeShare += share
+	}
+
+	return classifications
+}
+
+// abcClass returns the ABC class of a drug, given the cumulative revenue share of the drugs before it.
+func abcClass(cumulativeShareBefore float64, share float64) models.ABCClass {
+	switch {
+	case share <= 0:
+		return models.ABCClassC
+	case cumulativeShareBefore < abcClassAMaxCumulativeShare:
+		return models.ABCClassA
+	case cumulativeShareBefore < abcClassBMaxCumulativeShare:
+		return models.ABCClassB
+	default:
+		return models.ABCClassC
+	}
+}
+
+func xyzClass(coefficientOfVariation float64) models.XYZClass {
+	switch {
+	case coefficientOfVariation <= xyzClassXMaxCoefficientOfVariation:
+		return models.XYZClassX
+	case coefficientOfVariation <= xyzClassYMaxCoefficientOfVariation:
+		return models.XYZClassY
+	default:
+		return models.XYZClassZ
+	}
+}
+
+// calculateCoefficientOfVariation returns the population standard deviation of the quantities divided by their mean.
+// It returns false if nothing was sold, since the coefficient of variation is undefined.
+func calculateCoefficientOfVariation(quantities []float64) (float64, bool) {
+	if len(quantities) == 0 {
+		return 0, false
+	}
+
+	sum := 0.0
+	for _, quantity := range quantities {
+		sum += quantity
+	}
+
+	if sum <= 0 {
+		return 0, false
+	}
+
+	mean := sum / float64(len(quantities))
+
+	variance := 0.0
+	for _, quantity := range quantities {
+		variance += (quantity - mean) * (quantity - mean)
+	}
+	variance /= float64(len(quantities))
+
+	return math.Sqrt(variance) / mean, true
+}
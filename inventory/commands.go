@@ -0,0 +1,20 @@
+package inventory
+
+import (
+	"context"
+	"log"
+
+	"gorm.io/gorm"
+)
+
+// ClassifyDrugs classifies every drug by its sales in the given number of weeks until the given date.
+func ClassifyDrugs(ctx context.Context, db *gorm.DB, soldQuantitiesGetter SoldQuantitiesGetter, until string, weeks int) {
+	service := NewService(db, soldQuantitiesGetter)
+
+	period, classified, err := service.Classify(ctx, until, weeks)
+	if err != nil {
+		log.Fatalf("Classify: %s", err)
+	}
+
+	log.Printf("Classified %d drugs from %s to %s", classified, period.Start, period.End)
+}
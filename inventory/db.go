@@ -0,0 +1,119 @@
+package inventory
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+)
+
+const insertBatchSize = 500
+
+type Database struct {
+	db *gorm.DB
+}
+
+func NewDatabase(db *gorm.DB) *Database {
+	return &Database{db: db}
+}
+
+// classificationWithName is a classification joined with the drug name.
+type classificationWithName struct {
+	models.DrugClassification `gorm:"embedded"`
+
+	DrugName string
+}
+
+// period is the start and the end of a classification period.
+type period struct {
+	PeriodStart datatypes.Date
+	PeriodEnd   datatypes.Date
+}
+
+func (d *Database) GetDrugVmedisCodes(ctx context.Context) ([]string, error) {
+	var codes []string
+	if err := d.dbCtx(ctx).Model(&models.Drug{}).Order("vmedis_code").Pluck("vmedis_code", &codes).Error; err != nil {
+		return nil, fmt.Errorf("get drug vmedis codes from db: %w", err)
+	}
+
+	return codes, nil
+}
+
+// ReplaceClassifications replaces the classifications of the period ending at the given date.
+func (d *Database) ReplaceClassifications(ctx context.Context, periodEnd time.Time, classifications []models.DrugClassification) error {
+	return d.dbCtx(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("period_end = ?", datatypes.Date(periodEnd)).Delete(&models.DrugClassification{}).Error; err != nil {
+			return fmt.Errorf("delete classifications of period ending at %s: %w", periodEnd.Format(time.DateOnly), err)
+		}
+
+		if len(classifications) == 0 {
+			return nil
+		}
+
+		if err := tx.CreateInBatches(&classifications, insertBatchSize).Error; err != nil {
+			return fmt.Errorf("create %d classifications: %w", len(classifications), err)
+		}
+
+		return nil
+	})
+}
+
+// GetPeriods returns the classified periods, from the latest.
+func (d *Database) GetPeriods(ctx context.Context) ([]period, error) {
+	var periods []period
+	if err := d.dbCtx(ctx).
+		Model(&models.DrugClassification{}).
+		Distinct("period_start", "period_end").
+		Order("period_end DESC").
+		Find(&periods).
+		Error; err != nil {
+		return nil, fmt.Errorf("get classification periods from db: %w", err)
+	}
+
+	return periods, nil
+}
+
+// GetClassifications returns the classifications of the period ending at the given date,
+// sorted by the revenue descending.
+func (d *Database) GetClassifications(ctx context.Context, periodEnd time.Time) ([]classificationWithName, error) {
+	var classifications []classificationWithName
+	if err := d.classificationsWithNames(ctx).
+		Where("drug_classifications.period_end = ?", datatypes.Date(periodEnd)).
+		Order("drug_classifications.revenue DESC").
+		Order("drugs.name").
+		Find(&classifications).
+		Error; err != nil {
+		return nil, fmt.Errorf("get classifications of period ending at %s from db: %w", periodEnd.Format(time.DateOnly), err)
+	}
+
+	return classifications, nil
+}
+
+// GetDrugClassifications returns the classifications of a drug in every period, from the latest.
+func (d *Database) GetDrugClassifications(ctx context.Context, drugVmedisCode string) ([]classificationWithName, error) {
+	var classifications []classificationWithName
+	if err := d.classificationsWithNames(ctx).
+		Where("drug_classifications.drug_vmedis_code = ?", drugVmedisCode).
+		Order("drug_classifications.period_end DESC").
+		Find(&classifications).
+		Error; err != nil {
+		return nil, fmt.Errorf("get classifications of drug %s from db: %w", drugVmedisCode, err)
+	}
+
+	return classifications, nil
+}
+
+func (d *Database) classificationsWithNames(ctx context.Context) *gorm.DB {
+	return d.dbCtx(ctx).
+		Model(&models.DrugClassification{}).
+		Select("drug_classifications.*, COALESCE(drugs.name, '') AS drug_name").
+		Joins("LEFT JOIN drugs ON drugs.vmedis_code = drug_classifications.drug_vmedis_code")
+}
+
+func (d *Database) dbCtx(ctx context.Context) *gorm.DB {
+	return d.db.WithContext(ctx)
+}
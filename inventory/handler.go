@@ -0,0 +1,247 @@
+package inventory
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/turfaa/vmedis-proxy-api/cui"
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+	"github.com/turfaa/vmedis-proxy-api/money"
+	"github.com/turfaa/vmedis-proxy-api/pkg2/slices2"
+)
+
+type ApiHandler struct {
+	service *Service
+}
+
+func NewApiHandler(service *Service) *ApiHandler {
+	return &ApiHandler{service: service}
+}
+
+// Classify classifies every drug by its sales in the requested period and stores the classifications.
+func (h *ApiHandler) Classify(c *gin.Context) {
+	var request ClassifyRequest
+	if err := c.ShouldBindJSON(&request); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(400, gin.H{"error": fmt.Sprintf("invalid request: %s", err)})
+		return
+	}
+
+	period, classified, err := h.service.Classify(c.Request.Context(), request.Until, request.Weeks)
+	if err != nil {
+		if errors.Is(err, ErrInvalidClassificationRequest) {
+			c.JSON(400, gin.H{"error": fmt.Sprintf("invalid request: %s", err)})
+			return
+		}
+
+		c.JSON(500, gin.H{"error": fmt.Sprintf("failed to classify drugs: %s", err)})
+		return
+	}
+
+	c.JSON(200, ClassifyResponse{Period: period, Classified: classified})
+}
+
+// GetPeriods returns the classified periods, from the latest.
+func (h *ApiHandler) GetPeriods(c *gin.Context) {
+	periods, err := h.service.GetPeriods(c.Request.Context())
+	if err != nil {
+		c.JSON(500, gin.H{"error": fmt.Sprintf("failed to get classification periods: %s", err)})
+		return
+	}
+
+	c.JSON(200, PeriodsResponse{Periods: periods})
+}
+
+// GetClassifications returns the class of each drug in a period as a display-ready table,
+// sorted by the revenue descending. The row IDs are the drug vmedis codes.
+func (h *ApiHandler) GetClassifications(c *gin.Context) {
+	var request ClassificationsRequest
+	if err := c.ShouldBindQuery(&request); err != nil {
+		c.JSON(400, gin.H{"error": fmt.Sprintf("invalid request: %s", err)})
+		return
+	}
+
+	_, classifications, ok := h.getClassifications(c, request.PeriodEnd)
+	if !ok {
+		return
+	}
+
+	class := strings.ToUpper(strings.TrimSpace(request.Class))
+	if class != "" {
+		classifications = slices2.Filter(classifications, func(classification Classification) bool {
+			return matchesClass(classification, class)
+		})
+	}
+
+	c.JSON(200, h.transformClassificationsToTable(classifications))
+}
+
+// GetMatrix returns the number of drugs and their revenue share in each ABC/XYZ class of a period
+// as a display-ready table, with the ABC classes as rows and the XYZ classes as columns.
+func (h *ApiHandler) GetMatrix(c *gin.Context) {
+	_, classifications, ok := h.getClassifications(c, c.Query("periodEnd"))
+	if !ok {
+		return
+	}
+
+	c.JSON(200, h.transformClassificationsToMatrix(classifications))
+}
+
+// GetDrugClassifications returns the classes of a drug in every period as a display-ready table, from the latest.
+func (h *ApiHandler) GetDrugClassifications(c *gin.Context) {
+	classifications, err := h.service.GetDrugClassifications(c.Request.Context(), c.Param("drug_code"))
+	if err != nil {
+		c.JSON(500, gin.H{"error": fmt.Sprintf("failed to get drug classifications: %s", err)})
+		return
+	}
+
+	rows := make([]cui.Row, len(classifications))
+	for i, classification := range classifications {
+		rows[i] = cui.Row{
+			ID: classification.Period.End,
+			Columns: []string{
+				classification.Period.Start + " - " + classification.Period.End,
+				classification.Class(),
+				money.FormatRupiah(classification.Revenue),
+				formatShare(classification.RevenueShare),
+				formatCoefficientOfVariation(classification.CoefficientOfVariation),
+			},
+		}
+	}
+
+	c.JSON(200, cui.Table{
+		Header: []string{"Periode", "Kelas", "Pendapatan", "Kontribusi", "Koefisien Variasi"},
+		Rows:   rows,
+	})
+}
+
+func (h *ApiHandler) getClassifications(c *gin.Context, periodEnd string) (Period, []Classification, bool) {
+	period, classifications, err := h.service.GetClassifications(c.Request.Context(), periodEnd)
+	if err != nil {
+		if errors.Is(err, ErrNoClassifications) {
+			c.JSON(404, gin.H{"error": fmt.Sprintf("classifications not found: %s", err)})
+			return Period{}, nil, false
+		}
+
+		c.JSON(500, gin.H{"error": fmt.Sprintf("failed to get classifications: %s", err)})
+		return Period{}, nil, false
+	}
+
+	return period, classifications, true
+}
+
+func (h *ApiHandler) transformClassificationsToTable(classifications []Classification) cui.Table {
+	rows := make([]cui.Row, len(classifications))
+	for i, classification := range classifications {
+		rows[i] = cui.Row{
+			ID: classification.DrugVmedisCode,
+			Columns: []string{
+				classification.DrugName,
+				classification.Class(),
+				money.FormatRupiah(classification.Revenue),
+				formatShare(classification.RevenueShare),
+				formatCoefficientOfVariation(classification.CoefficientOfVariation),
+			},
+		}
+	}
+
+	return cui.Table{
+		Header: []string{"Nama Obat", "Kelas", "Pendapatan", "Kontribusi", "Koefisien Variasi"},
+		Rows:   rows,
+	}
+}
+
+func (h *ApiHandler) transformClassificationsToMatrix(classifications []Classification) cui.Table {
+	type cell struct {
+		count int
+		share float64
+	}
+
+	cells := make(map[models.ABCClass]map[models.XYZClass]cell)
+	for _, classification := range classifications {
+		if cells[classification.ABCClass] == nil {
+			cells[classification.ABCClass] = make(map[models.XYZClass]cell)
+		}
+
+		current := cells[classification.ABCClass][classification.XYZClass]
+		current.count++
+		current.share += classification.RevenueShare
+		cells[classification.ABCClass][classification.XYZClass] = current
+	}
+
+	header := []string{""}
+	for _, xyz := range models.AllXYZClasses() {
+		header = append(header, string(xyz))
+	}
+	header = append(header, "Total")
+
+	var rows []cui.Row
+	columnTotals := make(map[models.XYZClass]cell)
+	for _, abc := range models.AllABCClasses() {
+		columns := []string{string(abc)}
+
+		var rowTotal cell
+		for _, xyz := range models.AllXYZClasses() {
+			current := cells[abc][xyz]
+			columns = append(columns, formatMatrixCell(current.count, current.share))
+
+			rowTotal.count += current.count
+			rowTotal.share += current.share
+
+			columnTotal := columnTotals[xyz]
+			columnTotal.count += current.count
+			columnTotal.share += current.share
+			columnTotals[xyz] = columnTotal
+		}
+
+		rows = append(rows, cui.Row{
+			ID:      string(abc),
+			Columns: append(columns, formatMatrixCell(rowTotal.count, rowTotal.share)),
+		})
+	}
+
+	footer := []string{"Total"}
+	var total cell
+	for _, xyz := range models.AllXYZClasses() {
+		footer = append(footer, formatMatrixCell(columnTotals[xyz].count, columnTotals[xyz].share))
+		total.count += columnTotals[xyz].count
+		total.share += columnTotals[xyz].share
+	}
+	footer = append(footer, formatMatrixCell(total.count, total.share))
+
+	return cui.Table{
+		Header: header,
+		Rows:   rows,
+		Footer: footer,
+	}
+}
+
+// matchesClass checks whether the classification belongs to the class, e.g. "A", "X", or "AX".
+func matchesClass(classification Classification, class string) bool {
+	switch len(class) {
+	case 1:
+		return string(classification.ABCClass) == class || string(classification.XYZClass) == class
+	default:
+		return classification.Class() == class
+	}
+}
+
+func formatMatrixCell(count int, share float64) string {
+	return strconv.Itoa(count) + " Obat (" + formatShare(share) + ")"
+}
+
+func formatShare(share float64) string {
+	return fmt.Sprintf("%.1f%%", share*100)
+}
+
+func formatCoefficientOfVariation(coefficientOfVariation *float64) string {
+	if coefficientOfVariation == nil {
+		return "-"
+	}
+
+	return fmt.Sprintf("%.2f", *coefficientOfVariation)
+}
@@ -0,0 +1,216 @@
+package inventory_test
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/turfaa/vmedis-proxy-api/cui"
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+	"github.com/turfaa/vmedis-proxy-api/drug"
+	"github.com/turfaa/vmedis-proxy-api/inventory"
+)
+
+// TestClassification checks that the drugs are classified by their revenue share and
+// the variability of their weekly quantities in the smallest unit, and that the stored
+// classifications are shown per drug, as a matrix, and over time.
+func TestClassification(t *testing.T) {
+	db, router := setupRouter(t)
+
+	code, body := do(router, "GET", "/inventory/classification/matrix", "")
+	if code != 404 {
+		t.Fatalf("matrix before classifying: got code %d, body %s", code, body)
+	}
+
+	for _, d := range []models.Drug{
+		{VmedisID: 1, VmedisCode: "SANMOL", Name: "SANMOL 500MG"},
+		{VmedisID: 2, VmedisCode: "BODREX", Name: "BODREX"},
+		{VmedisID: 3, VmedisCode: "PROMAG", Name: "PROMAG"},
+		{VmedisID: 4, VmedisCode: "EMPTY", Name: "EMPTY"},
+	} {
+		mustCreate(t, db, &d)
+	}
+
+	mustCreate(t, db, &[]models.DrugUnit{
+		{DrugVmedisCode: "SANMOL", Unit: "Strip"},
+		{DrugVmedisCode: "SANMOL", Unit: "Box", ParentUnit: "Strip", ConversionToParentUnit: 10, UnitOrder: 1},
+	})
+
+	week := func(i int) time.Time {
+		return time.Date(2024, 3, 1+7*i, 10, 0, 0, 0, time.Local)
+	}
+
+	sales := []models.Sale{
+		// Outside of the period, must be ignored.
+		newSale("S-0", time.Date(2024, 2, 29, 10, 0, 0, 0, time.Local), models.SaleUnit{DrugCode: "BODREX", Amount: 100, Unit: "Strip", Total: 100000}),
+		// A box of SANMOL is 10 strips, so SANMOL sells steadily.
+		newSale("S-1", week(0), models.SaleUnit{DrugCode: "SANMOL", Amount: 1, Unit: "Box", Total: 200}, models.SaleUnit{DrugCode: "PROMAG", Amount: 1, Unit: "Strip", Total: 10}),
+		newSale("S-2", week(1), models.SaleUnit{DrugCode: "SANMOL", Amount: 10, Unit: "Strip", Total: 200}, models.SaleUnit{DrugCode: "PROMAG", Amount: 1, Unit: "Strip", Total: 10}, models.SaleUnit{DrugCode: "BODREX", Amount: 5, Unit: "Strip", Total: 150}),
+		newSale("S-3", week(2), models.SaleUnit{DrugCode: "SANMOL", Amount: 10, Unit: "Strip", Total: 200}, models.SaleUnit{DrugCode: "PROMAG", Amount: 1, Unit: "Strip", Total: 10}),
+		newSale("S-4", week(3), models.SaleUnit{DrugCode: "SANMOL", Amount: 10, Unit: "Strip", Total: 200}, models.SaleUnit{DrugCode: "PROMAG", Amount: 2, Unit: "Strip", Total: 20}),
+	}
+	mustCreate(t, db, &sales)
+
+	code, body = do(router, "POST", "/inventory/classification", `{"until": "2024-03-28", "weeks": 4}`)
+	if code != 200 {
+		t.Fatalf("classify: got code %d, body %s", code, body)
+	}
+
+	classified := unmarshal[inventory.ClassifyResponse](t, body)
+	if classified.Classified != 4 || classified.Period != (inventory.Period{Start: "2024-03-01", End: "2024-03-28"}) {
+		t.Fatalf("classify: got %+v", classified)
+	}
+
+	code, body = do(router, "GET", "/inventory/classification", "")
+	if code != 200 {
+		t.Fatalf("classifications: got code %d, body %s", code, body)
+	}
+
+	wantRows := [][]string{
+		{"SANMOL 500MG", "AX", "Rp 800", "80.0%", "0.00"},
+		{"BODREX", "BZ", "Rp 150", "15.0%", "1.73"},
+		{"PROMAG", "CX", "Rp 50", "5.0%", "0.35"},
+		{"EMPTY", "CZ", "Rp 0", "0.0%", "-"},
+	}
+
+	table := unmarshal[cui.Table](t, body)
+	if len(table.Rows) != len(wantRows) {
+		t.Fatalf("classifications: expected %d rows, got %s", len(wantRows), body)
+	}
+	for i, want := range wantRows {
+		if strings.Join(table.Rows[i].Columns, "|") != strings.Join(want, "|") {
+			t.Errorf("classifications: row %d: got %v, want %v", i, table.Rows[i].Columns, want)
+		}
+	}
+
+	code, body = do(router, "GET", "/inventory/classification?class=z", "")
+	if got := unmarshal[cui.Table](t, body); code != 200 || len(got.Rows) != 2 || got.Rows[0].ID != "BODREX" || got.Rows[1].ID != "EMPTY" {
+		t.Fatalf("Z classifications: got code %d, body %s", code, body)
+	}
+
+	code, body = do(router, "GET", "/inventory/classification/matrix?periodEnd=2024-03-28", "")
+	if code != 200 {
+		t.Fatalf("matrix: got code %d, body %s", code, body)
+	}
+
+	matrix := unmarshal[cui.Table](t, body)
+	wantMatrix := [][]string{
+		{"A", "1 Obat (80.0%)", "0 Obat (0.0%)", "0 Obat (0.0%)", "1 Obat (80.0%)"},
+		{"B", "0 Obat (0.0%)", "0 Obat (0.0%)", "1 Obat (15.0%)", "1 Obat (15.0%)"},
+		{"C", "1 Obat (5.0%)", "0 Obat (0.0%)", "1 Obat (0.0%)", "2 Obat (5.0%)"},
+	}
+	for i, want := range wantMatrix {
+		if strings.Join(matrix.Rows[i].Columns, "|") != strings.Join(want, "|") {
+			t.Errorf("matrix: row %d: got %v, want %v", i, matrix.Rows[i].Columns, want)
+		}
+	}
+	if matrix.Footer[4] != "4 Obat (100.0%)" {
+		t.Errorf("matrix: got footer %v", matrix.Footer)
+	}
+
+	// Classifying a later period keeps the earlier one, so the classes can be tracked over time.
+	code, body = do(router, "POST", "/inventory/classification", `{"until": "2024-03-21", "weeks": 1}`)
+	if code != 200 {
+		t.Fatalf("classify a later period: got code %d, body %s", code, body)
+	}
+
+	code, body = do(router, "GET", "/inventory/classification/periods", "")
+	if periods := unmarshal[inventory.PeriodsResponse](t, body); code != 200 || len(periods.Periods) != 2 || periods.Periods[0].End != "2024-03-28" {
+		t.Fatalf("periods: got code %d, body %s", code, body)
+	}
+
+	code, body = do(router, "GET", "/inventory/classification/drugs/BODREX", "")
+	history := unmarshal[cui.Table](t, body)
+	if code != 200 || len(history.Rows) != 2 || history.Rows[0].Columns[1] != "BZ" || history.Rows[1].Columns[1] != "CZ" {
+		t.Fatalf("BODREX history: got code %d, body %s", code, body)
+	}
+
+	code, body = do(router, "GET", "/inventory/classification?periodEnd=2024-01-31", "")
+	if code != 404 {
+		t.Fatalf("unclassified period: got code %d, body %s", code, body)
+	}
+
+	code, body = do(router, "POST", "/inventory/classification", `{"until": "28-03-2024"}`)
+	if code != 400 {
+		t.Fatalf("invalid until: got code %d, body %s", code, body)
+	}
+}
+
+func newSale(invoiceNumber string, soldAt time.Time, units ...models.SaleUnit) models.Sale {
+	for i := range units {
+		units[i].IDInSale = i + 1
+	}
+
+	return models.Sale{
+		InvoiceNumber: invoiceNumber,
+		SoldAt:        soldAt,
+		SaleUnits:     units,
+	}
+}
+
+func setupRouter(t *testing.T) (*gorm.DB, *gin.Engine) {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open database: %s", err)
+	}
+	if err := db.AutoMigrate(
+		&models.Drug{},
+		&models.DrugUnit{},
+		&models.Sale{},
+		&models.SaleUnit{},
+		&models.DrugClassification{},
+	); err != nil {
+		t.Fatalf("migrate database: %s", err)
+	}
+
+	handler := inventory.NewApiHandler(inventory.NewService(db, drug.NewDatabase(db)))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	// Mirrors the route registration in proxy/api.go, without auth middleware.
+	classification := router.Group("/inventory/classification")
+	{
+		classification.GET("", handler.GetClassifications)
+		classification.POST("", handler.Classify)
+		classification.GET("/matrix", handler.GetMatrix)
+		classification.GET("/periods", handler.GetPeriods)
+		classification.GET("/drugs/:drug_code", handler.GetDrugClassifications)
+	}
+
+	return db, router
+}
+
+func do(router *gin.Engine, method string, path string, body string) (int, string) {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w.Code, w.Body.String()
+}
+
+func mustCreate(t *testing.T, db *gorm.DB, value any) {
+	t.Helper()
+
+	if err := db.Create(value).Error; err != nil {
+		t.Fatalf("create %T: %s", value, err)
+	}
+}
+
+func unmarshal[T any](t *testing.T, body string) T {
+	t.Helper()
+
+	var value T
+	if err := json.Unmarshal([]byte(body), &value); err != nil {
+		t.Fatalf("unmarshal %T from %s: %s", value, body, err)
+	}
+
+	return value
+}
@@ -0,0 +1,26 @@
+package inventory
+
+type ClassifyRequest struct {
+	// Until is the last date of the period in the YYYY-MM-DD format, defaulting to yesterday.
+	Until string `json:"until"`
+
+	// Weeks is the length of the period, defaulting to 12 weeks.
+	Weeks int `json:"weeks"`
+}
+
+type ClassifyResponse struct {
+	Period     Period `json:"period"`
+	Classified int    `json:"classified"`
+}
+
+type ClassificationsRequest struct {
+	// PeriodEnd selects the period by its last date, defaulting to the latest period.
+	PeriodEnd string `form:"periodEnd"`
+
+	// Class filters the drugs by their class, e.g. "A", "X", or "AX".
+	Class string `form:"class"`
+}
+
+type PeriodsResponse struct {
+	Periods []Period `json:"periods"`
+}
@@ -0,0 +1,12 @@
+package inventory
+
+import (
+	"context"
+	"time"
+
+	"github.com/turfaa/vmedis-proxy-api/drug"
+)
+
+type SoldQuantitiesGetter interface {
+	GetSoldQuantitiesBetweenTime(ctx context.Context, from time.Time, until time.Time) ([]drug.SoldQuantity, error)
+}
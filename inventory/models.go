@@ -0,0 +1,59 @@
+package inventory
+
+import (
+	"time"
+
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+)
+
+// Period is a classification period, with dates in the YYYY-MM-DD format.
+type Period struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+func fromDBPeriod(p period) Period {
+	return Period{
+		Start: time.Time(p.PeriodStart).Format(time.DateOnly),
+		End:   time.Time(p.PeriodEnd).Format(time.DateOnly),
+	}
+}
+
+// Classification is the ABC/XYZ class of a drug within a period.
+type Classification struct {
+	Period         Period `json:"period"`
+	DrugVmedisCode string `json:"drugVmedisCode"`
+	DrugName       string `json:"drugName"`
+
+	Revenue float64 `json:"revenue"`
+
+	// RevenueShare is the share of the drug in the revenue of the period, between 0 and 1.
+	RevenueShare float64 `json:"revenueShare"`
+
+	// CoefficientOfVariation is nil if the drug wasn't sold in the period.
+	CoefficientOfVariation *float64 `json:"coefficientOfVariation,omitempty"`
+
+	ABCClass models.ABCClass `json:"abcClass"`
+	XYZClass models.XYZClass `json:"xyzClass"`
+}
+
+func fromDBClassification(c classificationWithName) Classification {
+	return Classification{
+		Period: fromDBPeriod(period{
+			PeriodStart: c.PeriodStart,
+			PeriodEnd:   c.PeriodEnd,
+		}),
+		DrugVmedisCode:         c.DrugVmedisCode,
+		DrugName:               c.DrugName,
+		Revenue:                c.Revenue,
+		RevenueShare:           c.RevenueShare,
+		CoefficientOfVariation: c.CoefficientOfVariation,
+		ABCClass:               c.ABCClass,
+		XYZClass:               c.XYZClass,
+	}
+}
+
+// Class returns the combined class of the drug, e.g. "AX".
+func (c Classification) Class() string {
+	return string(c.ABCClass) + string(c.XYZClass)
+}
@@ -0,0 +1,200 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/turfaa/vmedis-proxy-api/pkg2/slices2"
+	"github.com/turfaa/vmedis-proxy-api/pkg2/time2"
+)
+
+const (
+	DefaultClassificationWeeks = 12
+	maxClassificationWeeks     = 104
+
+	week = 7 * 24 * time.Hour
+)
+
+var (
+	// ErrInvalidClassificationRequest is returned when the classification period is invalid.
+	ErrInvalidClassificationRequest = errors.New("invalid classification request")
+
+	// ErrNoClassifications is returned when the requested period hasn't been classified.
+	ErrNoClassifications = errors.New("no classifications")
+)
+
+type Service struct {
+	db                   *Database
+	soldQuantitiesGetter SoldQuantitiesGetter
+}
+
+func NewService(db *gorm.DB, soldQuantitiesGetter SoldQuantitiesGetter) *Service {
+	return &Service{
+		db:                   NewDatabase(db),
+		soldQuantitiesGetter: soldQuantitiesGetter,
+	}
+}
+
+// Classify classifies every drug by its sales in the given number of weeks until the given date,
+// and stores the classifications of the period, replacing the previous classifications of the same period.
+// Empty until defaults to yesterday and non-positive weeks defaults to DefaultClassificationWeeks.
+func (s *Service) Classify(ctx context.Context, until string, weeks int) (Period, int, error) {
+	periodStart, periodEnd, err := classificationPeriod(until, weeks)
+	if err != nil {
+		return Period{}, 0, err
+	}
+
+	numberOfWeeks := int(periodEnd.Sub(periodStart)/week) + 1
+
+	log.Printf("Classifying drugs by their sales from %s to %s", periodStart.Format(time.DateOnly), periodEnd.Format(time.DateOnly))
+
+	drugCodes, err := s.db.GetDrugVmedisCodes(ctx)
+	if err != nil {
+		return Period{}, 0, fmt.Errorf("get drug codes: %w", err)
+	}
+
+	soldQuantities, err := s.soldQuantitiesGetter.GetSoldQuantitiesBetweenTime(ctx, periodStart, periodEnd)
+	if err != nil {
+		return Period{}, 0, fmt.Errorf("get sold quantities: %w", err)
+	}
+
+	demands := make(map[string]*drugDemand, len(drugCodes))
+	for _, code := range drugCodes {
+		demands[code] = &drugDemand{
+			DrugVmedisCode:   code,
+			WeeklyQuantities: make([]float64, numberOfWeeks),
+		}
+	}
+
+	for _, sold := range soldQuantities {
+		demand, ok := demands[sold.DrugCode]
+		if !ok {
+			continue
+		}
+
+		weekIndex := min(int(sold.SoldAt.Sub(periodStart)/week), numberOfWeeks-1)
+		demand.WeeklyQuantities[weekIndex] += sold.Quantity
+		demand.Revenue += sold.Total
+	}
+
+	classifications := classify(
+		periodStart,
+		periodEnd,
+		slices2.Map(drugCodes, func(code string) drugDemand { return *demands[code] }),
+	)
+
+	if err := s.db.ReplaceClassifications(ctx, periodEnd, classifications); err != nil {
+		return Period{}, 0, fmt.Errorf("store classifications: %w", err)
+	}
+
+	return Period{
+		Start: periodStart.Format(time.DateOnly),
+		End:   periodEnd.Format(time.DateOnly),
+	}, len(classifications), nil
+}
+
+// classificationPeriod returns the beginning of the first day and the end of the last day of the period.
+func classificationPeriod(until string, weeks int) (time.Time, time.Time, error) {
+	if weeks <= 0 {
+		weeks = DefaultClassificationWeeks
+	}
+
+	if weeks > maxClassificationWeeks {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: weeks must be at most %d", ErrInvalidClassificationRequest, maxClassificationWeeks)
+	}
+
+	periodStart, periodEnd, err := time2.DaysUntil(until, 7*weeks)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: %w", ErrInvalidClassificationRequest, err)
+	}
+
+	return periodStart, periodEnd, nil
+}
+
+// GetPeriods returns the classified periods, from the latest.
+func (s *Service) GetPeriods(ctx context.Context) ([]Period, error) {
+	periods, err := s.db.GetPeriods(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get periods: %w", err)
+	}
+
+	return slices2.Map(periods, fromDBPeriod), nil
+}
+
+// GetClassifications returns the classifications of the period ending at the given date, sorted by the revenue descending.
+// Empty periodEnd selects the latest period.
+func (s *Service) GetClassifications(ctx context.Context, periodEnd string) (Period, []Classification, error) {
+	p, err := s.getPeriod(ctx, periodEnd)
+	if err != nil {
+		return Period{}, nil, err
+	}
+
+	end, err := time2.BeginningOfDate(p.End)
+	if err != nil {
+		return Period{}, nil, fmt.Errorf("parse period end: %w", err)
+	}
+
+	classifications, err := s.db.GetClassifications(ctx, end)
+	if err != nil {
+		return Period{}, nil, fmt.Errorf("get classifications: %w", err)
+	}
+
+	return p, slices2.Map(classifications, fromDBClassification), nil
+}
+
+// GetLatestClassifications returns the classifications of the latest period, by the drug vmedis codes.
+// It returns an empty map if no period has been classified.
+func (s *Service) GetLatestClassifications(ctx context.Context) (map[string]Classification, error) {
+	_, classifications, err := s.GetClassifications(ctx, "")
+	if errors.Is(err, ErrNoClassifications) {
+		return map[string]Classification{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	byDrug := make(map[string]Classification, len(classifications))
+	for _, classification := range classifications {
+		byDrug[classification.DrugVmedisCode] = classification
+	}
+
+	return byDrug, nil
+}
+
+// GetDrugClassifications returns the classifications of a drug in every period, from the latest.
+func (s *Service) GetDrugClassifications(ctx context.Context, drugVmedisCode string) ([]Classification, error) {
+	classifications, err := s.db.GetDrugClassifications(ctx, drugVmedisCode)
+	if err != nil {
+		return nil, fmt.Errorf("get drug classifications: %w", err)
+	}
+
+	return slices2.Map(classifications, fromDBClassification), nil
+}
+
+func (s *Service) getPeriod(ctx context.Context, periodEnd string) (Period, error) {
+	periods, err := s.GetPeriods(ctx)
+	if err != nil {
+		return Period{}, err
+	}
+
+	if len(periods) == 0 {
+		return Period{}, fmt.Errorf("%w: no period has been classified", ErrNoClassifications)
+	}
+
+	if periodEnd == "" {
+		return periods[0], nil
+	}
+
+	for _, p := range periods {
+		if p.End == periodEnd {
+			return p, nil
+		}
+	}
+
+	return Period{}, fmt.Errorf("%w: period ending at %s hasn't been classified", ErrNoClassifications, periodEnd)
+}
@@ -0,0 +1,1570 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.10
+// 	protoc        v4.25.1
+// source: events.proto
+
+package kafkapb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// EventMetadata is the metadata of every domain event.
+type EventMetadata struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// event_id is unique per event, and stays the same when the event is redelivered.
+	EventId string `protobuf:"bytes,1,opt,name=event_id,json=eventId,proto3" json:"event_id,omitempty"`
+	// occurred_at is the time the change was found, which may be later than it happened in Vmedis.
+	OccurredAt    *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=occurred_at,json=occurredAt,proto3" json:"occurred_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EventMetadata) Reset() {
+	*x = EventMetadata{}
+	mi := &file_events_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EventMetadata) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EventMetadata) ProtoMessage() {}
+
+func (x *EventMetadata) ProtoReflect() protoreflect.Message {
+	mi := &file_events_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EventMetadata.ProtoReflect.Descriptor instead.
+func (*EventMetadata) Descriptor() ([]byte, []int) {
+	return file_events_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *EventMetadata) GetEventId() string {
+	if x != nil {
+		return x.EventId
+	}
+	return ""
+}
+
+func (x *EventMetadata) GetOccurredAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.OccurredAt
+	}
+	return nil
+}
+
+// DrugPriceChanged is published to "drug.price_changed.v1" when any price of a drug unit changes.
+// The key is the drug's vmedis code.
+type DrugPriceChanged struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	Metadata   *EventMetadata         `protobuf:"bytes,1,opt,name=metadata,proto3" json:"metadata,omitempty"`
+	VmedisCode string                 `protobuf:"bytes,2,opt,name=vmedis_code,json=vmedisCode,proto3" json:"vmedis_code,omitempty"`
+	Unit       string                 `protobuf:"bytes,3,opt,name=unit,proto3" json:"unit,omitempty"`
+	// before is unset when the unit is new.
+	Before        *DrugUnitPrices `protobuf:"bytes,4,opt,name=before,proto3" json:"before,omitempty"`
+	After         *DrugUnitPrices `protobuf:"bytes,5,opt,name=after,proto3" json:"after,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DrugPriceChanged) Reset() {
+	*x = DrugPriceChanged{}
+	mi := &file_events_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DrugPriceChanged) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DrugPriceChanged) ProtoMessage() {}
+
+func (x *DrugPriceChanged) ProtoReflect() protoreflect.Message {
+	mi := &file_events_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DrugPriceChanged.ProtoReflect.Descriptor instead.
+func (*DrugPriceChanged) Descriptor() ([]byte, []int) {
+	return file_events_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *DrugPriceChanged) GetMetadata() *EventMetadata {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+func (x *DrugPriceChanged) GetVmedisCode() string {
+	if x != nil {
+		return x.VmedisCode
+	}
+	return ""
+}
+
+func (x *DrugPriceChanged) GetUnit() string {
+	if x != nil {
+		return x.Unit
+	}
+	return ""
+}
+
+func (x *DrugPriceChanged) GetBefore() *DrugUnitPrices {
+	if x != nil {
+		return x.Before
+	}
+	return nil
+}
+
+func (x *DrugPriceChanged) GetAfter() *DrugUnitPrices {
+	if x != nil {
+		return x.After
+	}
+	return nil
+}
+
+// DrugUnitPrices are the prices of a drug unit for each customer segment.
+type DrugUnitPrices struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// price_one is the price for common customers.
+	PriceOne float64 `protobuf:"fixed64,1,opt,name=price_one,json=priceOne,proto3" json:"price_one,omitempty"`
+	// price_two is the price for medical facilities.
+	PriceTwo float64 `protobuf:"fixed64,2,opt,name=price_two,json=priceTwo,proto3" json:"price_two,omitempty"`
+	// price_three is the price for prescriptions.
+	PriceThree    float64 `protobuf:"fixed64,3,opt,name=price_three,json=priceThree,proto3" json:"price_three,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DrugUnitPrices) Reset() {
+	*x = DrugUnitPrices{}
+	mi := &file_events_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DrugUnitPrices) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DrugUnitPrices) ProtoMessage() {}
+
+func (x *DrugUnitPrices) ProtoReflect() protoreflect.Message {
+	mi := &file_events_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DrugUnitPrices.ProtoReflect.Descriptor instead.
+func (*DrugUnitPrices) Descriptor() ([]byte, []int) {
+	return file_events_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *DrugUnitPrices) GetPriceOne() float64 {
+	if x != nil {
+		return x.PriceOne
+	}
+	return 0
+}
+
+func (x *DrugUnitPrices) GetPriceTwo() float64 {
+	if x != nil {
+		return x.PriceTwo
+	}
+	return 0
+}
+
+func (x *DrugUnitPrices) GetPriceThree() float64 {
+	if x != nil {
+		return x.PriceThree
+	}
+	return 0
+}
+
+// DrugStockChanged is published to "drug.stock_changed.v1" when the stocks of a drug change.
+// The key is the drug's vmedis code.
+type DrugStockChanged struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	Metadata   *EventMetadata         `protobuf:"bytes,1,opt,name=metadata,proto3" json:"metadata,omitempty"`
+	VmedisCode string                 `protobuf:"bytes,2,opt,name=vmedis_code,json=vmedisCode,proto3" json:"vmedis_code,omitempty"`
+	// before and after are the whole stocks of the drug, one per unit.
+	Before        []*DrugStock `protobuf:"bytes,3,rep,name=before,proto3" json:"before,omitempty"`
+	After         []*DrugStock `protobuf:"bytes,4,rep,name=after,proto3" json:"after,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DrugStockChanged) Reset() {
+	*x = DrugStockChanged{}
+	mi := &file_events_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DrugStockChanged) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DrugStockChanged) ProtoMessage() {}
+
+func (x *DrugStockChanged) ProtoReflect() protoreflect.Message {
+	mi := &file_events_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DrugStockChanged.ProtoReflect.Descriptor instead.
+func (*DrugStockChanged) Descriptor() ([]byte, []int) {
+	return file_events_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *DrugStockChanged) GetMetadata() *EventMetadata {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+func (x *DrugStockChanged) GetVmedisCode() string {
+	if x != nil {
+		return x.VmedisCode
+	}
+	return ""
+}
+
+func (x *DrugStockChanged) GetBefore() []*DrugStock {
+	if x != nil {
+		return x.Before
+	}
+	return nil
+}
+
+func (x *DrugStockChanged) GetAfter() []*DrugStock {
+	if x != nil {
+		return x.After
+	}
+	return nil
+}
+
+type DrugStock struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Unit          string                 `protobuf:"bytes,1,opt,name=unit,proto3" json:"unit,omitempty"`
+	Quantity      float64                `protobuf:"fixed64,2,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DrugStock) Reset() {
+	*x = DrugStock{}
+	mi := &file_events_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DrugStock) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DrugStock) ProtoMessage() {}
+
+func (x *DrugStock) ProtoReflect() protoreflect.Message {
+	mi := &file_events_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DrugStock.ProtoReflect.Descriptor instead.
+func (*DrugStock) Descriptor() ([]byte, []int) {
+	return file_events_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *DrugStock) GetUnit() string {
+	if x != nil {
+		return x.Unit
+	}
+	return ""
+}
+
+func (x *DrugStock) GetQuantity() float64 {
+	if x != nil {
+		return x.Quantity
+	}
+	return 0
+}
+
+// SaleRecorded is published to "sale.recorded.v1" when a sale is dumped for the first time,
+// or when its content changed since the last dump. The key is the invoice number.
+type SaleRecorded struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Metadata *EventMetadata         `protobuf:"bytes,1,opt,name=metadata,proto3" json:"metadata,omitempty"`
+	// before is unset when the sale is new.
+	Before        *Sale `protobuf:"bytes,2,opt,name=before,proto3" json:"before,omitempty"`
+	After         *Sale `protobuf:"bytes,3,opt,name=after,proto3" json:"after,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SaleRecorded) Reset() {
+	*x = SaleRecorded{}
+	mi := &file_events_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SaleRecorded) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SaleRecorded) ProtoMessage() {}
+
+func (x *SaleRecorded) ProtoReflect() protoreflect.Message {
+	mi := &file_events_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SaleRecorded.ProtoReflect.Descriptor instead.
+func (*SaleRecorded) Descriptor() ([]byte, []int) {
+	return file_events_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *SaleRecorded) GetMetadata() *EventMetadata {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+func (x *SaleRecorded) GetBefore() *Sale {
+	if x != nil {
+		return x.Before
+	}
+	return nil
+}
+
+func (x *SaleRecorded) GetAfter() *Sale {
+	if x != nil {
+		return x.After
+	}
+	return nil
+}
+
+// SaleDeleted is published to "sale.deleted.v1" when a sale is soft-deleted because it no longer exists in Vmedis.
+// The key is the invoice number.
+type SaleDeleted struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Metadata      *EventMetadata         `protobuf:"bytes,1,opt,name=metadata,proto3" json:"metadata,omitempty"`
+	InvoiceNumber string                 `protobuf:"bytes,2,opt,name=invoice_number,json=invoiceNumber,proto3" json:"invoice_number,omitempty"`
+	// before is the sale as it was stored before the deletion.
+	Before        *Sale `protobuf:"bytes,3,opt,name=before,proto3" json:"before,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SaleDeleted) Reset() {
+	*x = SaleDeleted{}
+	mi := &file_events_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SaleDeleted) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SaleDeleted) ProtoMessage() {}
+
+func (x *SaleDeleted) ProtoReflect() protoreflect.Message {
+	mi := &file_events_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SaleDeleted.ProtoReflect.Descriptor instead.
+func (*SaleDeleted) Descriptor() ([]byte, []int) {
+	return file_events_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *SaleDeleted) GetMetadata() *EventMetadata {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+func (x *SaleDeleted) GetInvoiceNumber() string {
+	if x != nil {
+		return x.InvoiceNumber
+	}
+	return ""
+}
+
+func (x *SaleDeleted) GetBefore() *Sale {
+	if x != nil {
+		return x.Before
+	}
+	return nil
+}
+
+type Sale struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	VmedisId      int64                  `protobuf:"varint,1,opt,name=vmedis_id,json=vmedisId,proto3" json:"vmedis_id,omitempty"`
+	InvoiceNumber string                 `protobuf:"bytes,2,opt,name=invoice_number,json=invoiceNumber,proto3" json:"invoice_number,omitempty"`
+	SoldAt        *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=sold_at,json=soldAt,proto3" json:"sold_at,omitempty"`
+	Cashier       string                 `protobuf:"bytes,4,opt,name=cashier,proto3" json:"cashier,omitempty"`
+	PatientName   string                 `protobuf:"bytes,5,opt,name=patient_name,json=patientName,proto3" json:"patient_name,omitempty"`
+	Doctor        string                 `protobuf:"bytes,6,opt,name=doctor,proto3" json:"doctor,omitempty"`
+	Salesman      string                 `protobuf:"bytes,7,opt,name=salesman,proto3" json:"salesman,omitempty"`
+	Payment       string                 `protobuf:"bytes,8,opt,name=payment,proto3" json:"payment,omitempty"`
+	Total         float64                `protobuf:"fixed64,9,opt,name=total,proto3" json:"total,omitempty"`
+	Units         []*SaleUnit            `protobuf:"bytes,10,rep,name=units,proto3" json:"units,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Sale) Reset() {
+	*x = Sale{}
+	mi := &file_events_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Sale) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Sale) ProtoMessage() {}
+
+func (x *Sale) ProtoReflect() protoreflect.Message {
+	mi := &file_events_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Sale.ProtoReflect.Descriptor instead.
+func (*Sale) Descriptor() ([]byte, []int) {
+	return file_events_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *Sale) GetVmedisId() int64 {
+	if x != nil {
+		return x.VmedisId
+	}
+	return 0
+}
+
+func (x *Sale) GetInvoiceNumber() string {
+	if x != nil {
+		return x.InvoiceNumber
+	}
+	return ""
+}
+
+func (x *Sale) GetSoldAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.SoldAt
+	}
+	return nil
+}
+
+func (x *Sale) GetCashier() string {
+	if x != nil {
+		return x.Cashier
+	}
+	return ""
+}
+
+func (x *Sale) GetPatientName() string {
+	if x != nil {
+		return x.PatientName
+	}
+	return ""
+}
+
+func (x *Sale) GetDoctor() string {
+	if x != nil {
+		return x.Doctor
+	}
+	return ""
+}
+
+func (x *Sale) GetSalesman() string {
+	if x != nil {
+		return x.Salesman
+	}
+	return ""
+}
+
+func (x *Sale) GetPayment() string {
+	if x != nil {
+		return x.Payment
+	}
+	return ""
+}
+
+func (x *Sale) GetTotal() float64 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+func (x *Sale) GetUnits() []*SaleUnit {
+	if x != nil {
+		return x.Units
+	}
+	return nil
+}
+
+type SaleUnit struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	IdInSale      int64                  `protobuf:"varint,1,opt,name=id_in_sale,json=idInSale,proto3" json:"id_in_sale,omitempty"`
+	DrugCode      string                 `protobuf:"bytes,2,opt,name=drug_code,json=drugCode,proto3" json:"drug_code,omitempty"`
+	DrugName      string                 `protobuf:"bytes,3,opt,name=drug_name,json=drugName,proto3" json:"drug_name,omitempty"`
+	Batch         string                 `protobuf:"bytes,4,opt,name=batch,proto3" json:"batch,omitempty"`
+	Amount        float64                `protobuf:"fixed64,5,opt,name=amount,proto3" json:"amount,omitempty"`
+	Unit          string                 `protobuf:"bytes,6,opt,name=unit,proto3" json:"unit,omitempty"`
+	UnitPrice     float64                `protobuf:"fixed64,7,opt,name=unit_price,json=unitPrice,proto3" json:"unit_price,omitempty"`
+	PriceCategory string                 `protobuf:"bytes,8,opt,name=price_category,json=priceCategory,proto3" json:"price_category,omitempty"`
+	Discount      float64                `protobuf:"fixed64,9,opt,name=discount,proto3" json:"discount,omitempty"`
+	Tuslah        float64                `protobuf:"fixed64,10,opt,name=tuslah,proto3" json:"tuslah,omitempty"`
+	Embalase      float64                `protobuf:"fixed64,11,opt,name=embalase,proto3" json:"embalase,omitempty"`
+	Total         float64                `protobuf:"fixed64,12,opt,name=total,proto3" json:"total,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SaleUnit) Reset() {
+	*x = SaleUnit{}
+	mi := &file_events_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SaleUnit) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SaleUnit) ProtoMessage() {}
+
+func (x *SaleUnit) ProtoReflect() protoreflect.Message {
+	mi := &file_events_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SaleUnit.ProtoReflect.Descriptor instead.
+func (*SaleUnit) Descriptor() ([]byte, []int) {
+	return file_events_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *SaleUnit) GetIdInSale() int64 {
+	if x != nil {
+		return x.IdInSale
+	}
+	return 0
+}
+
+func (x *SaleUnit) GetDrugCode() string {
+	if x != nil {
+		return x.DrugCode
+	}
+	return ""
+}
+
+func (x *SaleUnit) GetDrugName() string {
+	if x != nil {
+		return x.DrugName
+	}
+	return ""
+}
+
+func (x *SaleUnit) GetBatch() string {
+	if x != nil {
+		return x.Batch
+	}
+	return ""
+}
+
+func (x *SaleUnit) GetAmount() float64 {
+	if x != nil {
+		return x.Amount
+	}
+	return 0
+}
+
+func (x *SaleUnit) GetUnit() string {
+	if x != nil {
+		return x.Unit
+	}
+	return ""
+}
+
+func (x *SaleUnit) GetUnitPrice() float64 {
+	if x != nil {
+		return x.UnitPrice
+	}
+	return 0
+}
+
+func (x *SaleUnit) GetPriceCategory() string {
+	if x != nil {
+		return x.PriceCategory
+	}
+	return ""
+}
+
+func (x *SaleUnit) GetDiscount() float64 {
+	if x != nil {
+		return x.Discount
+	}
+	return 0
+}
+
+func (x *SaleUnit) GetTuslah() float64 {
+	if x != nil {
+		return x.Tuslah
+	}
+	return 0
+}
+
+func (x *SaleUnit) GetEmbalase() float64 {
+	if x != nil {
+		return x.Embalase
+	}
+	return 0
+}
+
+func (x *SaleUnit) GetTotal() float64 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+// ProcurementRecorded is published to "procurement.recorded.v1" when a procurement is dumped for the first time,
+// or when its content changed since the last dump. The key is the invoice number.
+type ProcurementRecorded struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Metadata *EventMetadata         `protobuf:"bytes,1,opt,name=metadata,proto3" json:"metadata,omitempty"`
+	// before is unset when the procurement is new.
+	Before        *Procurement `protobuf:"bytes,2,opt,name=before,proto3" json:"before,omitempty"`
+	After         *Procurement `protobuf:"bytes,3,opt,name=after,proto3" json:"after,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProcurementRecorded) Reset() {
+	*x = ProcurementRecorded{}
+	mi := &file_events_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProcurementRecorded) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProcurementRecorded) ProtoMessage() {}
+
+func (x *ProcurementRecorded) ProtoReflect() protoreflect.Message {
+	mi := &file_events_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProcurementRecorded.ProtoReflect.Descriptor instead.
+func (*ProcurementRecorded) Descriptor() ([]byte, []int) {
+	return file_events_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *ProcurementRecorded) GetMetadata() *EventMetadata {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+func (x *ProcurementRecorded) GetBefore() *Procurement {
+	if x != nil {
+		return x.Before
+	}
+	return nil
+}
+
+func (x *ProcurementRecorded) GetAfter() *Procurement {
+	if x != nil {
+		return x.After
+	}
+	return nil
+}
+
+type Procurement struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	InvoiceNumber string                 `protobuf:"bytes,1,opt,name=invoice_number,json=invoiceNumber,proto3" json:"invoice_number,omitempty"`
+	// invoice_date is formatted as YYYY-MM-DD.
+	InvoiceDate       string             `protobuf:"bytes,2,opt,name=invoice_date,json=invoiceDate,proto3" json:"invoice_date,omitempty"`
+	Supplier          string             `protobuf:"bytes,3,opt,name=supplier,proto3" json:"supplier,omitempty"`
+	Warehouse         string             `protobuf:"bytes,4,opt,name=warehouse,proto3" json:"warehouse,omitempty"`
+	PaymentType       string             `protobuf:"bytes,5,opt,name=payment_type,json=paymentType,proto3" json:"payment_type,omitempty"`
+	Operator          string             `protobuf:"bytes,6,opt,name=operator,proto3" json:"operator,omitempty"`
+	DiscountAmount    float64            `protobuf:"fixed64,7,opt,name=discount_amount,json=discountAmount,proto3" json:"discount_amount,omitempty"`
+	TaxAmount         float64            `protobuf:"fixed64,8,opt,name=tax_amount,json=taxAmount,proto3" json:"tax_amount,omitempty"`
+	MiscellaneousCost float64            `protobuf:"fixed64,9,opt,name=miscellaneous_cost,json=miscellaneousCost,proto3" json:"miscellaneous_cost,omitempty"`
+	Total             float64            `protobuf:"fixed64,10,opt,name=total,proto3" json:"total,omitempty"`
+	Units             []*ProcurementUnit `protobuf:"bytes,11,rep,name=units,proto3" json:"units,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *Procurement) Reset() {
+	*x = Procurement{}
+	mi := &file_events_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Procurement) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Procurement) ProtoMessage() {}
+
+func (x *Procurement) ProtoReflect() protoreflect.Message {
+	mi := &file_events_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Procurement.ProtoReflect.Descriptor instead.
+func (*Procurement) Descriptor() ([]byte, []int) {
+	return file_events_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *Procurement) GetInvoiceNumber() string {
+	if x != nil {
+		return x.InvoiceNumber
+	}
+	return ""
+}
+
+func (x *Procurement) GetInvoiceDate() string {
+	if x != nil {
+		return x.InvoiceDate
+	}
+	return ""
+}
+
+func (x *Procurement) GetSupplier() string {
+	if x != nil {
+		return x.Supplier
+	}
+	return ""
+}
+
+func (x *Procurement) GetWarehouse() string {
+	if x != nil {
+		return x.Warehouse
+	}
+	return ""
+}
+
+func (x *Procurement) GetPaymentType() string {
+	if x != nil {
+		return x.PaymentType
+	}
+	return ""
+}
+
+func (x *Procurement) GetOperator() string {
+	if x != nil {
+		return x.Operator
+	}
+	return ""
+}
+
+func (x *Procurement) GetDiscountAmount() float64 {
+	if x != nil {
+		return x.DiscountAmount
+	}
+	return 0
+}
+
+func (x *Procurement) GetTaxAmount() float64 {
+	if x != nil {
+		return x.TaxAmount
+	}
+	return 0
+}
+
+func (x *Procurement) GetMiscellaneousCost() float64 {
+	if x != nil {
+		return x.MiscellaneousCost
+	}
+	return 0
+}
+
+func (x *Procurement) GetTotal() float64 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+func (x *Procurement) GetUnits() []*ProcurementUnit {
+	if x != nil {
+		return x.Units
+	}
+	return nil
+}
+
+type ProcurementUnit struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	IdInProcurement int64                  `protobuf:"varint,1,opt,name=id_in_procurement,json=idInProcurement,proto3" json:"id_in_procurement,omitempty"`
+	DrugCode        string                 `protobuf:"bytes,2,opt,name=drug_code,json=drugCode,proto3" json:"drug_code,omitempty"`
+	DrugName        string                 `protobuf:"bytes,3,opt,name=drug_name,json=drugName,proto3" json:"drug_name,omitempty"`
+	Amount          float64                `protobuf:"fixed64,4,opt,name=amount,proto3" json:"amount,omitempty"`
+	Unit            string                 `protobuf:"bytes,5,opt,name=unit,proto3" json:"unit,omitempty"`
+	UnitBasePrice   float64                `protobuf:"fixed64,6,opt,name=unit_base_price,json=unitBasePrice,proto3" json:"unit_base_price,omitempty"`
+	TotalUnitPrice  float64                `protobuf:"fixed64,7,opt,name=total_unit_price,json=totalUnitPrice,proto3" json:"total_unit_price,omitempty"`
+	UnitTaxedPrice  float64                `protobuf:"fixed64,8,opt,name=unit_taxed_price,json=unitTaxedPrice,proto3" json:"unit_taxed_price,omitempty"`
+	ExpiryDate      *timestamppb.Timestamp `protobuf:"bytes,9,opt,name=expiry_date,json=expiryDate,proto3" json:"expiry_date,omitempty"`
+	BatchNumber     string                 `protobuf:"bytes,10,opt,name=batch_number,json=batchNumber,proto3" json:"batch_number,omitempty"`
+	Total           float64                `protobuf:"fixed64,11,opt,name=total,proto3" json:"total,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *ProcurementUnit) Reset() {
+	*x = ProcurementUnit{}
+	mi := &file_events_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProcurementUnit) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProcurementUnit) ProtoMessage() {}
+
+func (x *ProcurementUnit) ProtoReflect() protoreflect.Message {
+	mi := &file_events_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProcurementUnit.ProtoReflect.Descriptor instead.
+func (*ProcurementUnit) Descriptor() ([]byte, []int) {
+	return file_events_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *ProcurementUnit) GetIdInProcurement() int64 {
+	if x != nil {
+		return x.IdInProcurement
+	}
+	return 0
+}
+
+func (x *ProcurementUnit) GetDrugCode() string {
+	if x != nil {
+		return x.DrugCode
+	}
+	return ""
+}
+
+func (x *ProcurementUnit) GetDrugName() string {
+	if x != nil {
+		return x.DrugName
+	}
+	return ""
+}
+
+func (x *ProcurementUnit) GetAmount() float64 {
+	if x != nil {
+		return x.Amount
+	}
+	return 0
+}
+
+func (x *ProcurementUnit) GetUnit() string {
+	if x != nil {
+		return x.Unit
+	}
+	return ""
+}
+
+func (x *ProcurementUnit) GetUnitBasePrice() float64 {
+	if x != nil {
+		return x.UnitBasePrice
+	}
+	return 0
+}
+
+func (x *ProcurementUnit) GetTotalUnitPrice() float64 {
+	if x != nil {
+		return x.TotalUnitPrice
+	}
+	return 0
+}
+
+func (x *ProcurementUnit) GetUnitTaxedPrice() float64 {
+	if x != nil {
+		return x.UnitTaxedPrice
+	}
+	return 0
+}
+
+func (x *ProcurementUnit) GetExpiryDate() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ExpiryDate
+	}
+	return nil
+}
+
+func (x *ProcurementUnit) GetBatchNumber() string {
+	if x != nil {
+		return x.BatchNumber
+	}
+	return ""
+}
+
+func (x *ProcurementUnit) GetTotal() float64 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+// ShiftClosed is published to "shift.closed.v1" when a closed shift is dumped for the first time,
+// or when a dumped shift gets closed. The key is the shift code.
+type ShiftClosed struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Metadata      *EventMetadata         `protobuf:"bytes,1,opt,name=metadata,proto3" json:"metadata,omitempty"`
+	Shift         *Shift                 `protobuf:"bytes,2,opt,name=shift,proto3" json:"shift,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ShiftClosed) Reset() {
+	*x = ShiftClosed{}
+	mi := &file_events_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ShiftClosed) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ShiftClosed) ProtoMessage() {}
+
+func (x *ShiftClosed) ProtoReflect() protoreflect.Message {
+	mi := &file_events_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ShiftClosed.ProtoReflect.Descriptor instead.
+func (*ShiftClosed) Descriptor() ([]byte, []int) {
+	return file_events_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *ShiftClosed) GetMetadata() *EventMetadata {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+func (x *ShiftClosed) GetShift() *Shift {
+	if x != nil {
+		return x.Shift
+	}
+	return nil
+}
+
+type Shift struct {
+	state               protoimpl.MessageState `protogen:"open.v1"`
+	VmedisId            int64                  `protobuf:"varint,1,opt,name=vmedis_id,json=vmedisId,proto3" json:"vmedis_id,omitempty"`
+	Code                string                 `protobuf:"bytes,2,opt,name=code,proto3" json:"code,omitempty"`
+	Cashier             string                 `protobuf:"bytes,3,opt,name=cashier,proto3" json:"cashier,omitempty"`
+	StartedAt           *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=started_at,json=startedAt,proto3" json:"started_at,omitempty"`
+	EndedAt             *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=ended_at,json=endedAt,proto3" json:"ended_at,omitempty"`
+	InitialCash         float64                `protobuf:"fixed64,6,opt,name=initial_cash,json=initialCash,proto3" json:"initial_cash,omitempty"`
+	ExpectedFinalCash   float64                `protobuf:"fixed64,7,opt,name=expected_final_cash,json=expectedFinalCash,proto3" json:"expected_final_cash,omitempty"`
+	ActualFinalCash     float64                `protobuf:"fixed64,8,opt,name=actual_final_cash,json=actualFinalCash,proto3" json:"actual_final_cash,omitempty"`
+	FinalCashDifference float64                `protobuf:"fixed64,9,opt,name=final_cash_difference,json=finalCashDifference,proto3" json:"final_cash_difference,omitempty"`
+	Supervisor          string                 `protobuf:"bytes,10,opt,name=supervisor,proto3" json:"supervisor,omitempty"`
+	Notes               string                 `protobuf:"bytes,11,opt,name=notes,proto3" json:"notes,omitempty"`
+	unknownFields       protoimpl.UnknownFields
+	sizeCache           protoimpl.SizeCache
+}
+
+func (x *Shift) Reset() {
+	*x = Shift{}
+	mi := &file_events_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Shift) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Shift) ProtoMessage() {}
+
+func (x *Shift) ProtoReflect() protoreflect.Message {
+	mi := &file_events_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Shift.ProtoReflect.Descriptor instead.
+func (*Shift) Descriptor() ([]byte, []int) {
+	return file_events_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *Shift) GetVmedisId() int64 {
+	if x != nil {
+		return x.VmedisId
+	}
+	return 0
+}
+
+func (x *Shift) GetCode() string {
+	if x != nil {
+		return x.Code
+	}
+	return ""
+}
+
+func (x *Shift) GetCashier() string {
+	if x != nil {
+		return x.Cashier
+	}
+	return ""
+}
+
+func (x *Shift) GetStartedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.StartedAt
+	}
+	return nil
+}
+
+func (x *Shift) GetEndedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.EndedAt
+	}
+	return nil
+}
+
+func (x *Shift) GetInitialCash() float64 {
+	if x != nil {
+		return x.InitialCash
+	}
+	return 0
+}
+
+func (x *Shift) GetExpectedFinalCash() float64 {
+	if x != nil {
+		return x.ExpectedFinalCash
+	}
+	return 0
+}
+
+func (x *Shift) GetActualFinalCash() float64 {
+	if x != nil {
+		return x.ActualFinalCash
+	}
+	return 0
+}
+
+func (x *Shift) GetFinalCashDifference() float64 {
+	if x != nil {
+		return x.FinalCashDifference
+	}
+	return 0
+}
+
+func (x *Shift) GetSupervisor() string {
+	if x != nil {
+		return x.Supervisor
+	}
+	return ""
+}
+
+func (x *Shift) GetNotes() string {
+	if x != nil {
+		return x.Notes
+	}
+	return ""
+}
+
+// DumpCompleted is published to "dump.completed.v1" when a dump from Vmedis finishes successfully.
+// The key is the dump name.
+type DumpCompleted struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Metadata *EventMetadata         `protobuf:"bytes,1,opt,name=metadata,proto3" json:"metadata,omitempty"`
+	// dump is the name of what was dumped: "drugs", "sales", "procurements", "stock_opnames", or "shifts".
+	Dump      string                 `protobuf:"bytes,2,opt,name=dump,proto3" json:"dump,omitempty"`
+	StartedAt *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=started_at,json=startedAt,proto3" json:"started_at,omitempty"`
+	// records is the number of records fetched from Vmedis and stored.
+	Records       int64 `protobuf:"varint,4,opt,name=records,proto3" json:"records,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DumpCompleted) Reset() {
+	*x = DumpCompleted{}
+	mi := &file_events_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DumpCompleted) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DumpCompleted) ProtoMessage() {}
+
+func (x *DumpCompleted) ProtoReflect() protoreflect.Message {
+	mi := &file_events_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DumpCompleted.ProtoReflect.Descriptor instead.
+func (*DumpCompleted) Descriptor() ([]byte, []int) {
+	return file_events_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *DumpCompleted) GetMetadata() *EventMetadata {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+func (x *DumpCompleted) GetDump() string {
+	if x != nil {
+		return x.Dump
+	}
+	return ""
+}
+
+func (x *DumpCompleted) GetStartedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.StartedAt
+	}
+	return nil
+}
+
+func (x *DumpCompleted) GetRecords() int64 {
+	if x != nil {
+		return x.Records
+	}
+	return 0
+}
+
+// RejectedDrugCreated is published to "rejected_drug.created.v1" when a drug requested by a customer
+// but not available is recorded. The key is the rejected drug ID.
+type RejectedDrugCreated struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Metadata      *EventMetadata         `protobuf:"bytes,1,opt,name=metadata,proto3" json:"metadata,omitempty"`
+	Id            uint64                 `protobuf:"varint,2,opt,name=id,proto3" json:"id,omitempty"`
+	DrugName      string                 `protobuf:"bytes,3,opt,name=drug_name,json=drugName,proto3" json:"drug_name,omitempty"`
+	CreatedBy     string                 `protobuf:"bytes,4,opt,name=created_by,json=createdBy,proto3" json:"created_by,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RejectedDrugCreated) Reset() {
+	*x = RejectedDrugCreated{}
+	mi := &file_events_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RejectedDrugCreated) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RejectedDrugCreated) ProtoMessage() {}
+
+func (x *RejectedDrugCreated) ProtoReflect() protoreflect.Message {
+	mi := &file_events_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RejectedDrugCreated.ProtoReflect.Descriptor instead.
+func (*RejectedDrugCreated) Descriptor() ([]byte, []int) {
+	return file_events_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *RejectedDrugCreated) GetMetadata() *EventMetadata {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+func (x *RejectedDrugCreated) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *RejectedDrugCreated) GetDrugName() string {
+	if x != nil {
+		return x.DrugName
+	}
+	return ""
+}
+
+func (x *RejectedDrugCreated) GetCreatedBy() string {
+	if x != nil {
+		return x.CreatedBy
+	}
+	return ""
+}
+
+var File_events_proto protoreflect.FileDescriptor
+
+const file_events_proto_rawDesc = "" +
+	"\n" +
+	"\fevents.proto\x12\x06vmedis\x1a\x1fgoogle/protobuf/timestamp.proto\"g\n" +
+	"\rEventMetadata\x12\x19\n" +
+	"\bevent_id\x18\x01 \x01(\tR\aeventId\x12;\n" +
+	"\voccurred_at\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"occurredAt\"\xd8\x01\n" +
+	"\x10DrugPriceChanged\x121\n" +
+	"\bmetadata\x18\x01 \x01(\v2\x15.vmedis.EventMetadataR\bmetadata\x12\x1f\n" +
+	"\vvmedis_code\x18\x02 \x01(\tR\n" +
+	"vmedisCode\x12\x12\n" +
+	"\x04unit\x18\x03 \x01(\tR\x04unit\x12.\n" +
+	"\x06before\x18\x04 \x01(\v2\x16.vmedis.DrugUnitPricesR\x06before\x12,\n" +
+	"\x05after\x18\x05 \x01(\v2\x16.vmedis.DrugUnitPricesR\x05after\"k\n" +
+	"\x0eDrugUnitPrices\x12\x1b\n" +
+	"\tprice_one\x18\x01 \x01(\x01R\bpriceOne\x12\x1b\n" +
+	"\tprice_two\x18\x02 \x01(\x01R\bpriceTwo\x12\x1f\n" +
+	"\vprice_three\x18\x03 \x01(\x01R\n" +
+	"priceThree\"\xba\x01\n" +
+	"\x10DrugStockChanged\x121\n" +
+	"\bmetadata\x18\x01 \x01(\v2\x15.vmedis.EventMetadataR\bmetadata\x12\x1f\n" +
+	"\vvmedis_code\x18\x02 \x01(\tR\n" +
+	"vmedisCode\x12)\n" +
+	"\x06before\x18\x03 \x03(\v2\x11.vmedis.DrugStockR\x06before\x12'\n" +
+	"\x05after\x18\x04 \x03(\v2\x11.vmedis.DrugStockR\x05after\";\n" +
+	"\tDrugStock\x12\x12\n" +
+	"\x04unit\x18\x01 \x01(\tR\x04unit\x12\x1a\n" +
+	"\bquantity\x18\x02 \x01(\x01R\bquantity\"\x8b\x01\n" +
+	"\fSaleRecorded\x121\n" +
+	"\bmetadata\x18\x01 \x01(\v2\x15.vmedis.EventMetadataR\bmetadata\x12$\n" +
+	"\x06before\x18\x02 \x01(\v2\f.vmedis.SaleR\x06before\x12\"\n" +
+	"\x05after\x18\x03 \x01(\v2\f.vmedis.SaleR\x05after\"\x8d\x01\n" +
+	"\vSaleDeleted\x121\n" +
+	"\bmetadata\x18\x01 \x01(\v2\x15.vmedis.EventMetadataR\bmetadata\x12%\n" +
+	"\x0einvoice_number\x18\x02 \x01(\tR\rinvoiceNumber\x12$\n" +
+	"\x06before\x18\x03 \x01(\v2\f.vmedis.SaleR\x06before\"\xc8\x02\n" +
+	"\x04Sale\x12\x1b\n" +
+	"\tvmedis_id\x18\x01 \x01(\x03R\bvmedisId\x12%\n" +
+	"\x0einvoice_number\x18\x02 \x01(\tR\rinvoiceNumber\x123\n" +
+	"\asold_at\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\x06soldAt\x12\x18\n" +
+	"\acashier\x18\x04 \x01(\tR\acashier\x12!\n" +
+	"\fpatient_name\x18\x05 \x01(\tR\vpatientName\x12\x16\n" +
+	"\x06doctor\x18\x06 \x01(\tR\x06doctor\x12\x1a\n" +
+	"\bsalesman\x18\a \x01(\tR\bsalesman\x12\x18\n" +
+	"\apayment\x18\b \x01(\tR\apayment\x12\x14\n" +
+	"\x05total\x18\t \x01(\x01R\x05total\x12&\n" +
+	"\x05units\x18\n" +
+	" \x03(\v2\x10.vmedis.SaleUnitR\x05units\"\xd0\x02\n" +
+	"\bSaleUnit\x12\x1c\n" +
+	"\n" +
+	"id_in_sale\x18\x01 \x01(\x03R\bidInSale\x12\x1b\n" +
+	"\tdrug_code\x18\x02 \x01(\tR\bdrugCode\x12\x1b\n" +
+	"\tdrug_name\x18\x03 \x01(\tR\bdrugName\x12\x14\n" +
+	"\x05batch\x18\x04 \x01(\tR\x05batch\x12\x16\n" +
+	"\x06amount\x18\x05 \x01(\x01R\x06amount\x12\x12\n" +
+	"\x04unit\x18\x06 \x01(\tR\x04unit\x12\x1d\n" +
+	"\n" +
+	"unit_price\x18\a \x01(\x01R\tunitPrice\x12%\n" +
+	"\x0eprice_category\x18\b \x01(\tR\rpriceCategory\x12\x1a\n" +
+	"\bdiscount\x18\t \x01(\x01R\bdiscount\x12\x16\n" +
+	"\x06tuslah\x18\n" +
+	" \x01(\x01R\x06tuslah\x12\x1a\n" +
+	"\bembalase\x18\v \x01(\x01R\bembalase\x12\x14\n" +
+	"\x05total\x18\f \x01(\x01R\x05total\"\xa0\x01\n" +
+	"\x13ProcurementRecorded\x121\n" +
+	"\bmetadata\x18\x01 \x01(\v2\x15.vmedis.EventMetadataR\bmetadata\x12+\n" +
+	"\x06before\x18\x02 \x01(\v2\x13.vmedis.ProcurementR\x06before\x12)\n" +
+	"\x05after\x18\x03 \x01(\v2\x13.vmedis.ProcurementR\x05after\"\x8c\x03\n" +
+	"\vProcurement\x12%\n" +
+	"\x0einvoice_number\x18\x01 \x01(\tR\rinvoiceNumber\x12!\n" +
+	"\finvoice_date\x18\x02 \x01(\tR\vinvoiceDate\x12\x1a\n" +
+	"\bsupplier\x18\x03 \x01(\tR\bsupplier\x12\x1c\n" +
+	"\twarehouse\x18\x04 \x01(\tR\twarehouse\x12!\n" +
+	"\fpayment_type\x18\x05 \x01(\tR\vpaymentType\x12\x1a\n" +
+	"\boperator\x18\x06 \x01(\tR\boperator\x12'\n" +
+	"\x0fdiscount_amount\x18\a \x01(\x01R\x0ediscountAmount\x12\x1d\n" +
+	"\n" +
+	"tax_amount\x18\b \x01(\x01R\ttaxAmount\x12-\n" +
+	"\x12miscellaneous_cost\x18\t \x01(\x01R\x11miscellaneousCost\x12\x14\n" +
+	"\x05total\x18\n" +
+	" \x01(\x01R\x05total\x12-\n" +
+	"\x05units\x18\v \x03(\v2\x17.vmedis.ProcurementUnitR\x05units\"\x95\x03\n" +
+	"\x0fProcurementUnit\x12*\n" +
+	"\x11id_in_procurement\x18\x01 \x01(\x03R\x0fidInProcurement\x12\x1b\n" +
+	"\tdrug_code\x18\x02 \x01(\tR\bdrugCode\x12\x1b\n" +
+	"\tdrug_name\x18\x03 \x01(\tR\bdrugName\x12\x16\n" +
+	"\x06amount\x18\x04 \x01(\x01R\x06amount\x12\x12\n" +
+	"\x04unit\x18\x05 \x01(\tR\x04unit\x12&\n" +
+	"\x0funit_base_price\x18\x06 \x01(\x01R\runitBasePrice\x12(\n" +
+	"\x10total_unit_price\x18\a \x01(\x01R\x0etotalUnitPrice\x12(\n" +
+	"\x10unit_taxed_price\x18\b \x01(\x01R\x0eunitTaxedPrice\x12;\n" +
+	"\vexpiry_date\x18\t \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"expiryDate\x12!\n" +
+	"\fbatch_number\x18\n" +
+	" \x01(\tR\vbatchNumber\x12\x14\n" +
+	"\x05total\x18\v \x01(\x01R\x05total\"e\n" +
+	"\vShiftClosed\x121\n" +
+	"\bmetadata\x18\x01 \x01(\v2\x15.vmedis.EventMetadataR\bmetadata\x12#\n" +
+	"\x05shift\x18\x02 \x01(\v2\r.vmedis.ShiftR\x05shift\"\xad\x03\n" +
+	"\x05Shift\x12\x1b\n" +
+	"\tvmedis_id\x18\x01 \x01(\x03R\bvmedisId\x12\x12\n" +
+	"\x04code\x18\x02 \x01(\tR\x04code\x12\x18\n" +
+	"\acashier\x18\x03 \x01(\tR\acashier\x129\n" +
+	"\n" +
+	"started_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\tstartedAt\x125\n" +
+	"\bended_at\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\aendedAt\x12!\n" +
+	"\finitial_cash\x18\x06 \x01(\x01R\vinitialCash\x12.\n" +
+	"\x13expected_final_cash\x18\a \x01(\x01R\x11expectedFinalCash\x12*\n" +
+	"\x11actual_final_cash\x18\b \x01(\x01R\x0factualFinalCash\x122\n" +
+	"\x15final_cash_difference\x18\t \x01(\x01R\x13finalCashDifference\x12\x1e\n" +
+	"\n" +
+	"supervisor\x18\n" +
+	" \x01(\tR\n" +
+	"supervisor\x12\x14\n" +
+	"\x05notes\x18\v \x01(\tR\x05notes\"\xab\x01\n" +
+	"\rDumpCompleted\x121\n" +
+	"\bmetadata\x18\x01 \x01(\v2\x15.vmedis.EventMetadataR\bmetadata\x12\x12\n" +
+	"\x04dump\x18\x02 \x01(\tR\x04dump\x129\n" +
+	"\n" +
+	"started_at\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\tstartedAt\x12\x18\n" +
+	"\arecords\x18\x04 \x01(\x03R\arecords\"\x94\x01\n" +
+	"\x13RejectedDrugCreated\x121\n" +
+	"\bmetadata\x18\x01 \x01(\v2\x15.vmedis.EventMetadataR\bmetadata\x12\x0e\n" +
+	"\x02id\x18\x02 \x01(\x04R\x02id\x12\x1b\n" +
+	"\tdrug_name\x18\x03 \x01(\tR\bdrugName\x12\x1d\n" +
+	"\n" +
+	"created_by\x18\x04 \x01(\tR\tcreatedByB,Z*github.com/turfaa/vmedis-proxy-api/kafkapbb\x06proto3"
+
+var (
+	file_events_proto_rawDescOnce sync.Once
+	file_events_proto_rawDescData []byte
+)
+
+func file_events_proto_rawDescGZIP() []byte {
+	file_events_proto_rawDescOnce.Do(func() {
+		file_events_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_events_proto_rawDesc), len(file_events_proto_rawDesc)))
+	})
+	return file_events_proto_rawDescData
+}
+
+var file_events_proto_msgTypes = make([]protoimpl.MessageInfo, 16)
+var file_events_proto_goTypes = []any{
+	(*EventMetadata)(nil),         // 0: vmedis.EventMetadata
+	(*DrugPriceChanged)(nil),      // 1: vmedis.DrugPriceChanged
+	(*DrugUnitPrices)(nil),        // 2: vmedis.DrugUnitPrices
+	(*DrugStockChanged)(nil),      // 3: vmedis.DrugStockChanged
+	(*DrugStock)(nil),             // 4: vmedis.DrugStock
+	(*SaleRecorded)(nil),          // 5: vmedis.SaleRecorded
+	(*SaleDeleted)(nil),           // 6: vmedis.SaleDeleted
+	(*Sale)(nil),                  // 7: vmedis.Sale
+	(*SaleUnit)(nil),              // 8: vmedis.SaleUnit
+	(*ProcurementRecorded)(nil),   // 9: vmedis.ProcurementRecorded
+	(*Procurement)(nil),           // 10: vmedis.Procurement
+	(*ProcurementUnit)(nil),       // 11: vmedis.ProcurementUnit
+	(*ShiftClosed)(nil),           // 12: vmedis.ShiftClosed
+	(*Shift)(nil),                 // 13: vmedis.Shift
+	(*DumpCompleted)(nil),         // 14: vmedis.DumpCompleted
+	(*RejectedDrugCreated)(nil),   // 15: vmedis.RejectedDrugCreated
+	(*timestamppb.Timestamp)(nil), // 16: google.protobuf.Timestamp
+}
+var file_events_proto_depIdxs = []int32{
+	16, // 0: vmedis.EventMetadata.occurred_at:type_name -> google.protobuf.Timestamp
+	0,  // 1: vmedis.DrugPriceChanged.metadata:type_name -> vmedis.EventMetadata
+	2,  // 2: vmedis.DrugPriceChanged.before:type_name -> vmedis.DrugUnitPrices
+	2,  // 3: vmedis.DrugPriceChanged.after:type_name -> vmedis.DrugUnitPrices
+	0,  // 4: vmedis.DrugStockChanged.metadata:type_name -> vmedis.EventMetadata
+	4,  // 5: vmedis.DrugStockChanged.before:type_name -> vmedis.DrugStock
+	4,  // 6: vmedis.DrugStockChanged.after:type_name -> vmedis.DrugStock
+	0,  // 7: vmedis.SaleRecorded.metadata:type_name -> vmedis.EventMetadata
+	7,  // 8: vmedis.SaleRecorded.before:type_name -> vmedis.Sale
+	7,  // 9: vmedis.SaleRecorded.after:type_name -> vmedis.Sale
+	0,  // 10: vmedis.SaleDeleted.metadata:type_name -> vmedis.EventMetadata
+	7,  // 11: vmedis.SaleDeleted.before:type_name -> vmedis.Sale
+	16, // 12: vmedis.Sale.sold_at:type_name -> google.protobuf.Timestamp
+	8,  // 13: vmedis.Sale.units:type_name -> vmedis.SaleUnit
+	0,  // 14: vmedis.ProcurementRecorded.metadata:type_name -> vmedis.EventMetadata
+	10, // 15: vmedis.ProcurementRecorded.before:type_name -> vmedis.Procurement
+	10, // 16: vmedis.ProcurementRecorded.after:type_name -> vmedis.Procurement
+	11, // 17: vmedis.Procurement.units:type_name -> vmedis.ProcurementUnit
+	16, // 18: vmedis.ProcurementUnit.expiry_date:type_name -> google.protobuf.Timestamp
+	0,  // 19: vmedis.ShiftClosed.metadata:type_name -> vmedis.EventMetadata
+	13, // 20: vmedis.ShiftClosed.shift:type_name -> vmedis.Shift
+	16, // 21: vmedis.Shift.started_at:type_name -> google.protobuf.Timestamp
+	16, // 22: vmedis.Shift.ended_at:type_name -> google.protobuf.Timestamp
+	0,  // 23: vmedis.DumpCompleted.metadata:type_name -> vmedis.EventMetadata
+	16, // 24: vmedis.DumpCompleted.started_at:type_name -> google.protobuf.Timestamp
+	0,  // 25: vmedis.RejectedDrugCreated.metadata:type_name -> vmedis.EventMetadata
+	26, // [26:26] is the sub-list for method output_type
+	26, // [26:26] is the sub-list for method input_type
+	26, // [26:26] is the sub-list for extension type_name
+	26, // [26:26] is the sub-list for extension extendee
+	0,  // [0:26] is the sub-list for field type_name
+}
+
+func init() { file_events_proto_init() }
+func file_events_proto_init() {
+	if File_events_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_events_proto_rawDesc), len(file_events_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   16,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_events_proto_goTypes,
+		DependencyIndexes: file_events_proto_depIdxs,
+		MessageInfos:      file_events_proto_msgTypes,
+	}.Build()
+	File_events_proto = out.File
+	file_events_proto_goTypes = nil
+	file_events_proto_depIdxs = nil
+}
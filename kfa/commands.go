@@ -0,0 +1,43 @@
+package kfa
+
+import (
+	"context"
+	"log"
+	"os"
+
+	"gorm.io/gorm"
+)
+
+// ImportKFAProductsFromFile imports the KFA master file at the given path and
+// regenerates the suggested matches of the drugs without a KFA code.
+// An empty format is guessed from the file extension.
+func ImportKFAProductsFromFile(ctx context.Context, db *gorm.DB, path string, format FileFormat) {
+	if format == "" {
+		var err error
+		format, err = FileFormatFromPath(path)
+		if err != nil {
+			log.Fatalf("FileFormatFromPath: %s", err)
+		}
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		log.Fatalf("Open KFA file %s: %s", path, err)
+	}
+	defer file.Close()
+
+	// Importing doesn't review any match, so no drug has to be refreshed.
+	service := NewService(db, nil)
+
+	imported, err := service.ImportProducts(ctx, file, format)
+	if err != nil {
+		log.Fatalf("ImportProducts: %s", err)
+	}
+	log.Printf("Imported %d KFA products from %s", imported, path)
+
+	suggested, err := service.GenerateMatches(ctx)
+	if err != nil {
+		log.Fatalf("GenerateMatches: %s", err)
+	}
+	log.Printf("Suggested %d KFA matches", suggested)
+}
@@ -0,0 +1,235 @@
+package kfa
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const insertBatchSize = 500
+
+type Database struct {
+	db *gorm.DB
+}
+
+func NewDatabase(db *gorm.DB) *Database {
+	return &Database{db: db}
+}
+
+// matchWithNames is a match joined with the drug and KFA product names.
+type matchWithNames struct {
+	models.KFAMatch `gorm:"embedded"`
+
+	DrugName         string
+	DrugManufacturer string
+	KFAName          string `gorm:"column:kfa_name"`
+	KFAManufacturer  string `gorm:"column:kfa_manufacturer"`
+}
+
+// UpsertProducts inserts the given KFA products, updating the existing ones by their code.
+func (d *Database) UpsertProducts(ctx context.Context, products []models.KFAProduct) error {
+	if len(products) == 0 {
+		return nil
+	}
+
+	if err := d.dbCtx(ctx).
+		Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "code"}},
+			DoUpdates: clause.AssignmentColumns([]string{
+				"updated_at",
+				"name",
+				"manufacturer",
+				"active_ingredient",
+				"strength",
+				"dosage_form",
+			}),
+		}).
+		CreateInBatches(&products, insertBatchSize).
+		Error; err != nil {
+		return fmt.Errorf("upsert %d kfa products: %w", len(products), err)
+	}
+
+	return nil
+}
+
+func (d *Database) GetProducts(ctx context.Context) ([]models.KFAProduct, error) {
+	var products []models.KFAProduct
+	if err := d.dbCtx(ctx).Order("code").Find(&products).Error; err != nil {
+		return nil, fmt.Errorf("get kfa products from db: %w", err)
+	}
+
+	return products, nil
+}
+
+func (d *Database) GetProductsByCodes(ctx context.Context, codes []string) ([]models.KFAProduct, error) {
+	var products []models.KFAProduct
+	if err := d.dbCtx(ctx).Where("code IN ?", codes).Order("code").Find(&products).Error; err != nil {
+		return nil, fmt.Errorf("get kfa products %v from db: %w", codes, err)
+	}
+
+	return products, nil
+}
+
+// GetUnmatchedDrugs returns the drugs that have no KFA code yet.
+func (d *Database) GetUnmatchedDrugs(ctx context.Context) ([]models.Drug, error) {
+	var drugs []models.Drug
+	if err := d.dbCtx(ctx).
+		Where("kfa_code = '' OR kfa_code IS NULL").
+		Order("vmedis_code").
+		Find(&drugs).
+		Error; err != nil {
+		return nil, fmt.Errorf("get unmatched drugs from db: %w", err)
+	}
+
+	return drugs, nil
+}
+
+// ReplacePendingMatches replaces the pending matches of the given drugs with the given matches.
+// Matches that were already reviewed are kept as they are, so a rejected match is not suggested again.
+func (d *Database) ReplacePendingMatches(ctx context.Context, drugVmedisCodes []string, matches []models.KFAMatch) error {
+	if err := d.dbCtx(ctx).Transaction(func(tx *gorm.DB) error {
+		if len(drugVmedisCodes) > 0 {
+			if err := tx.
+				Where("drug_vmedis_code IN ? AND status = ?", drugVmedisCodes, models.KFAMatchStatusPending).
+				Delete(&models.KFAMatch{}).
+				Error; err != nil {
+				return fmt.Errorf("delete pending matches: %w", err)
+			}
+		}
+
+		if len(matches) == 0 {
+			return nil
+		}
+
+		if err := tx.
+			Clauses(clause.OnConflict{DoNothing: true}).
+			CreateInBatches(&matches, insertBatchSize).
+			Error; err != nil {
+			return fmt.Errorf("create matches: %w", err)
+		}
+
+		return nil
+	}); err != nil {
+		return fmt.Errorf("replace pending matches of %d drugs: %w", len(drugVmedisCodes), err)
+	}
+
+	return nil
+}
+
+func (d *Database) GetMatches(ctx context.Context, filters ListFilters) ([]matchWithNames, error) {
+	query := d.matchesWithNamesQuery(ctx)
+
+	if len(filters.Statuses) > 0 {
+		query = query.Where("kfa_matches.status IN ?", filters.Statuses)
+	}
+
+	if filters.DrugVmedisCode != "" {
+		query = query.Where("kfa_matches.drug_vmedis_code = ?", filters.DrugVmedisCode)
+	}
+
+	var matches []matchWithNames
+	if err := query.
+		Order("kfa_matches.confidence DESC, kfa_matches.drug_vmedis_code").
+		Find(&matches).
+		Error; err != nil {
+		return nil, fmt.Errorf("get kfa matches from db: %w", err)
+	}
+
+	return matches, nil
+}
+
+func (d *Database) GetMatchByID(ctx context.Context, id uint) (matchWithNames, error) {
+	var match matchWithNames
+	if err := d.matchesWithNamesQuery(ctx).
+		Where("kfa_matches.id = ?", id).
+		Take(&match).
+		Error; err != nil {
+		return matchWithNames{}, fmt.Errorf("get kfa match %d from db: %w", id, err)
+	}
+
+	return match, nil
+}
+
+// SetMatchStatus sets the status of the given match.
+// Accepting a match also sets the drug's KFA code and rejects the other
+// pending or accepted matches of the same drug, while moving an accepted match
+// to another status clears the drug's KFA code if it's still the match's.
+func (d *Database) SetMatchStatus(ctx context.Context, match models.KFAMatch, status models.KFAMatchStatus, reviewedBy string) error {
+	now := time.Now()
+
+	if err := d.dbCtx(ctx).Transaction(func(tx *gorm.DB) error {
+		updates := map[string]any{
+			"status":      status,
+			"reviewed_at": &now,
+			"reviewed_by": reviewedBy,
+		}
+		if status == models.KFAMatchStatusPending {
+			updates["reviewed_at"] = nil
+			updates["reviewed_by"] = ""
+		}
+
+		if err := tx.Model(&models.KFAMatch{}).Where("id = ?", match.ID).Updates(updates).Error; err != nil {
+			return fmt.Errorf("update match status: %w", err)
+		}
+
+		if status != models.KFAMatchStatusAccepted {
+			if match.Status != models.KFAMatchStatusAccepted {
+				return nil
+			}
+
+			if err := tx.Model(&models.Drug{}).
+				Where("vmedis_code = ? AND kfa_code = ?", match.DrugVmedisCode, match.KFACode).
+				Update("kfa_code", "").
+				Error; err != nil {
+				return fmt.Errorf("clear kfa code of drug %s: %w", match.DrugVmedisCode, err)
+			}
+
+			return nil
+		}
+
+		if err := tx.Model(&models.Drug{}).
+			Where("vmedis_code = ?", match.DrugVmedisCode).
+			Update("kfa_code", match.KFACode).
+			Error; err != nil {
+			return fmt.Errorf("set kfa code of drug %s: %w", match.DrugVmedisCode, err)
+		}
+
+		if err := tx.Model(&models.KFAMatch{}).
+			Where("drug_vmedis_code = ? AND id <> ? AND status <> ?", match.DrugVmedisCode, match.ID, models.KFAMatchStatusRejected).
+			Updates(map[string]any{
+				"status":      models.KFAMatchStatusRejected,
+				"reviewed_at": &now,
+				"reviewed_by": reviewedBy,
+			}).
+			Error; err != nil {
+			return fmt.Errorf("reject other matches of drug %s: %w", match.DrugVmedisCode, err)
+		}
+
+		return nil
+	}); err != nil {
+		return fmt.Errorf("set kfa match %d status to %s: %w", match.ID, status, err)
+	}
+
+	return nil
+}
+
+func (d *Database) matchesWithNamesQuery(ctx context.Context) *gorm.DB {
+	return d.dbCtx(ctx).
+		Model(&models.KFAMatch{}).
+		Select(
+			"kfa_matches.*, " +
+				"drugs.name AS drug_name, drugs.manufacturer AS drug_manufacturer, " +
+				"kfa_products.name AS kfa_name, kfa_products.manufacturer AS kfa_manufacturer",
+		).
+		Joins("LEFT JOIN drugs ON drugs.vmedis_code = kfa_matches.drug_vmedis_code").
+		Joins("LEFT JOIN kfa_products ON kfa_products.code = kfa_matches.kfa_code")
+}
+
+func (d *Database) dbCtx(ctx context.Context) *gorm.DB {
+	return d.db.WithContext(ctx)
+}
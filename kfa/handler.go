@@ -0,0 +1,292 @@
+package kfa
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/turfaa/vmedis-proxy-api/auth"
+	"github.com/turfaa/vmedis-proxy-api/cui"
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+	"github.com/turfaa/vmedis-proxy-api/pkg2/slices2"
+	"github.com/turfaa/vmedis-proxy-api/pkg2/time2"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ApiHandler struct {
+	service *Service
+}
+
+func NewApiHandler(service *Service) *ApiHandler {
+	return &ApiHandler{service: service}
+}
+
+// GetMatches returns the suggested KFA matches as a display-ready table,
+// sorted by confidence descending. The row IDs are the match IDs.
+//
+// The matches can be filtered by query parameters:
+//   - statuses: comma-separated list of statuses (can also be repeated)
+//   - drug_code: exact match on the drug's vmedis code
+func (h *ApiHandler) GetMatches(c *gin.Context) {
+	statuses, err := extractStatuses(c)
+	if err != nil {
+		c.JSON(400, gin.H{"error": fmt.Sprintf("invalid filters: %s", err)})
+		return
+	}
+
+	matches, err := h.service.GetMatches(c.Request.Context(), ListFilters{
+		Statuses:       statuses,
+		DrugVmedisCode: c.Query("drug_code"),
+	})
+	if err != nil {
+		c.JSON(500, gin.H{"error": fmt.Sprintf("failed to get KFA matches: %s", err)})
+		return
+	}
+
+	c.JSON(200, h.transformMatchesToTable(matches))
+}
+
+// GetMatch returns a KFA match as a display-ready key-value table.
+func (h *ApiHandler) GetMatch(c *gin.Context) {
+	match, ok := h.getMatchFromParam(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(200, h.transformMatchToTable(match))
+}
+
+// GetReviewMatchForm returns a form prefilled with the current status of the match.
+// The filled form can be submitted to `PATCH /kfa/matches/:id`.
+func (h *ApiHandler) GetReviewMatchForm(c *gin.Context) {
+	match, ok := h.getMatchFromParam(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(200, cui.Form{
+		Title: fmt.Sprintf("Tinjau Kode KFA %s", match.DrugName),
+		Fields: []cui.Field{
+			{
+				ID:       "status",
+				Label:    "Status",
+				Type:     cui.FieldTypeSelect,
+				Value:    match.Status.String(),
+				Options:  h.statusOptions(),
+				Required: true,
+			},
+		},
+	})
+}
+
+// ReviewMatch accepts or rejects a KFA match.
+// Accepting a match sets the KFA code of the drug.
+func (h *ApiHandler) ReviewMatch(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{"error": fmt.Sprintf("invalid id: %s", err)})
+		return
+	}
+
+	var request ReviewMatchRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(400, gin.H{"error": fmt.Sprintf("invalid request: %s", err)})
+		return
+	}
+
+	request.Status = models.KFAMatchStatus(strings.ToUpper(request.Status.String()))
+	if !request.Status.Valid() {
+		c.JSON(400, gin.H{"error": fmt.Sprintf("invalid status: %s", request.Status)})
+		return
+	}
+
+	match, err := h.service.ReviewMatch(c.Request.Context(), uint(id), request, auth.FromGinContext(c).Email)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(404, gin.H{"error": fmt.Sprintf("KFA match %d not found", id)})
+			return
+		}
+
+		c.JSON(500, gin.H{"error": fmt.Sprintf("failed to review KFA match %d: %s", id, err)})
+		return
+	}
+
+	c.JSON(200, MatchResponse{Match: match})
+}
+
+// GetStatuses returns all known match statuses as labeled options,
+// e.g. for the status filter dropdown.
+func (h *ApiHandler) GetStatuses(c *gin.Context) {
+	c.JSON(200, cui.Options{Options: h.statusOptions()})
+}
+
+func (h *ApiHandler) getMatchFromParam(c *gin.Context) (Match, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{"error": fmt.Sprintf("invalid id: %s", err)})
+		return Match{}, false
+	}
+
+	match, err := h.service.GetMatchByID(c.Request.Context(), uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(404, gin.H{"error": fmt.Sprintf("KFA match %d not found", id)})
+			return Match{}, false
+		}
+
+		c.JSON(500, gin.H{"error": fmt.Sprintf("failed to get KFA match %d: %s", id, err)})
+		return Match{}, false
+	}
+
+	return match, true
+}
+
+func (h *ApiHandler) transformMatchesToTable(matches []Match) cui.Table {
+	header := []string{
+		"Kode Obat Vmedis",
+		"Nama Obat",
+		"Pabrik",
+		"Kode KFA",
+		"Nama KFA",
+		"Pabrik KFA",
+		"Skor",
+		"Status",
+	}
+
+	rows := slices2.Map(matches, func(match Match) cui.Row {
+		return cui.Row{
+			ID: strconv.FormatUint(uint64(match.ID), 10),
+			Columns: []string{
+				match.DrugVmedisCode,
+				match.DrugName,
+				match.DrugManufacturer,
+				match.KFACode,
+				match.KFAName,
+				match.KFAManufacturer,
+				formatConfidence(match.Confidence),
+				statusLabel(match.Status),
+			},
+		}
+	})
+
+	return cui.Table{
+		Header: header,
+		Rows:   rows,
+	}
+}
+
+func (h *ApiHandler) transformMatchToTable(match Match) cui.Table {
+	return cui.Table{
+		Rows: []cui.Row{
+			{
+				ID:      "kode_obat_vmedis",
+				Columns: []string{"Kode Obat Vmedis", match.DrugVmedisCode},
+			},
+			{
+				ID:      "nama_obat",
+				Columns: []string{"Nama Obat", match.DrugName},
+			},
+			{
+				ID:      "pabrik",
+				Columns: []string{"Pabrik", orDash(match.DrugManufacturer)},
+			},
+			{
+				ID:      "kode_kfa",
+				Columns: []string{"Kode KFA", match.KFACode},
+			},
+			{
+				ID:      "nama_kfa",
+				Columns: []string{"Nama KFA", match.KFAName},
+			},
+			{
+				ID:      "pabrik_kfa",
+				Columns: []string{"Pabrik KFA", orDash(match.KFAManufacturer)},
+			},
+			{
+				ID:      "skor",
+				Columns: []string{"Skor", formatConfidence(match.Confidence)},
+			},
+			{
+				ID:      "status",
+				Columns: []string{"Status", statusLabel(match.Status)},
+			},
+			{
+				ID:      "ditinjau_oleh",
+				Columns: []string{"Ditinjau Oleh", orDash(match.ReviewedBy)},
+			},
+			{
+				ID:      "waktu_ditinjau",
+				Columns: []string{"Waktu Ditinjau", formatNullableDateTime(match.ReviewedAt)},
+			},
+		},
+	}
+}
+
+func (h *ApiHandler) statusOptions() []cui.Option {
+	return slices2.Map(h.service.GetStatuses(), func(status models.KFAMatchStatus) cui.Option {
+		return cui.Option{
+			Value: status.String(),
+			Label: statusLabel(status),
+		}
+	})
+}
+
+func statusLabel(status models.KFAMatchStatus) string {
+	switch status {
+	case models.KFAMatchStatusPending:
+		return "Belum Ditinjau"
+	case models.KFAMatchStatusAccepted:
+		return "Diterima"
+	case models.KFAMatchStatusRejected:
+		return "Ditolak"
+	default:
+		return status.String()
+	}
+}
+
+func formatConfidence(confidence float64) string {
+	return fmt.Sprintf("%.0f%%", confidence*100)
+}
+
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+
+	return value
+}
+
+func formatNullableDateTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+
+	return time2.FormatDateTime(*t)
+}
+
+func extractStatuses(c *gin.Context) ([]models.KFAMatchStatus, error) {
+	var statuses []models.KFAMatchStatus
+
+	values := append(c.QueryArray("statuses"), c.QueryArray("status")...)
+	for _, value := range values {
+		for _, raw := range strings.Split(value, ",") {
+			raw = strings.TrimSpace(raw)
+			if raw == "" {
+				continue
+			}
+
+			status := models.KFAMatchStatus(strings.ToUpper(raw))
+			if !status.Valid() {
+				return nil, fmt.Errorf("invalid status: %s", raw)
+			}
+
+			statuses = append(statuses, status)
+		}
+	}
+
+	return statuses, nil
+}
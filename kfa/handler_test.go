@@ -0,0 +1,263 @@
+package kfa_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/turfaa/vmedis-proxy-api/cui"
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+	"github.com/turfaa/vmedis-proxy-api/kfa"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+const kfaMasterCSV = `kfa_code,display_name,manufacturer,active_ingredient,strength,dosage_form
+93000001,Ibuprofen 400 mg Tablet,Casper Pharma,Ibuprofen,400 mg,Tablet
+93000002,Ibuprofen 200 mg Tablet,Sanbe Farma,Ibuprofen,200 mg,Tablet
+93000003,Amoxicillin 500 mg Kapsul,Indofarma,Amoxicillin,500 mg,Kapsul
+,Row Without Code,Nobody,,,
+`
+
+// TestKFAMatchingJourney walks through the KFA matching journey: import the
+// master file, suggest matches, review them from the frontend's point of
+// view, and check that accepting a match sets the drug's KFA code and
+// rejecting it again clears the code.
+func TestKFAMatchingJourney(t *testing.T) {
+	db, router, refresher := setup(t)
+	ctx := context.Background()
+
+	for _, drug := range []models.Drug{
+		{VmedisID: 1, VmedisCode: "OBT1", Name: "IBUPROFEN 400MG TAB 10x10 (CASPER)", Manufacturer: "PT CASPER PHARMA"},
+		{VmedisID: 2, VmedisCode: "OBT2", Name: "AMOXICILLIN 500MG KAPS", Manufacturer: "INDOFARMA"},
+		{VmedisID: 3, VmedisCode: "OBT3", Name: "KASA STERIL", Manufacturer: "ONEMED"},
+		{VmedisID: 4, VmedisCode: "OBT4", KFACode: "93000003", Name: "AMOXICILLIN 500MG", Manufacturer: "INDOFARMA"},
+	} {
+		if err := db.Create(&drug).Error; err != nil {
+			t.Fatalf("create drug: %s", err)
+		}
+	}
+
+	service := kfa.NewService(db, refresher)
+
+	imported, err := service.ImportProducts(ctx, strings.NewReader(kfaMasterCSV), kfa.FileFormatCSV)
+	if err != nil {
+		t.Fatalf("import products: %s", err)
+	}
+	if imported != 3 {
+		t.Fatalf("import products: got %d products, want 3", imported)
+	}
+
+	if _, err := service.GenerateMatches(ctx); err != nil {
+		t.Fatalf("generate matches: %s", err)
+	}
+
+	matches, err := service.GetMatches(ctx, kfa.ListFilters{})
+	if err != nil {
+		t.Fatalf("get matches: %s", err)
+	}
+	for _, match := range matches {
+		if match.DrugVmedisCode == "OBT3" || match.DrugVmedisCode == "OBT4" {
+			t.Fatalf("unexpected match for drug %s: %+v", match.DrugVmedisCode, match)
+		}
+	}
+
+	do := func(method, path, body string) (int, string) {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code, w.Body.String()
+	}
+
+	code, body := do("GET", "/kfa/matches?statuses=pending&drug_code=OBT1", "")
+	if code != 200 {
+		t.Fatalf("list: got code %d, body %s", code, body)
+	}
+	list := unmarshal[cui.Table](t, body)
+	if len(list.Rows) != 2 {
+		t.Fatalf("list: expected both ibuprofen products to be suggested, got %s", body)
+	}
+	if len(list.Rows[0].Columns) != len(list.Header) {
+		t.Fatalf("list: row has %d columns, header has %d", len(list.Rows[0].Columns), len(list.Header))
+	}
+	if list.Rows[0].Columns[3] != "93000001" {
+		t.Fatalf("list: expected the 400 mg Casper product to be the best match, got %s", body)
+	}
+	bestID, otherID := list.Rows[0].ID, list.Rows[1].ID
+
+	code, body = do("GET", "/kfa/matches/"+bestID+"/form", "")
+	if code != 200 {
+		t.Fatalf("get review form: got code %d, body %s", code, body)
+	}
+	form := unmarshal[cui.Form](t, body)
+	if len(form.Fields) != 1 || form.Fields[0].Value != "PENDING" || len(form.Fields[0].Options) != len(models.AllKFAMatchStatuses()) {
+		t.Fatalf("review form: status select not prefilled with options: %s", body)
+	}
+
+	code, body = do("PATCH", "/kfa/matches/"+bestID, `{"status": "WRONG"}`)
+	if code != 400 {
+		t.Fatalf("review with invalid status: got code %d, body %s", code, body)
+	}
+
+	code, body = do("PATCH", "/kfa/matches/"+bestID, `{"status": "ACCEPTED"}`)
+	if code != 200 {
+		t.Fatalf("accept: got code %d, body %s", code, body)
+	}
+	accepted := unmarshal[kfa.MatchResponse](t, body)
+	if accepted.Match.Status != models.KFAMatchStatusAccepted || accepted.Match.ReviewedAt == nil {
+		t.Fatalf("accept: match not marked as accepted: %s", body)
+	}
+
+	var drug models.Drug
+	if err := db.Where("vmedis_code = ?", "OBT1").First(&drug).Error; err != nil {
+		t.Fatalf("get drug: %s", err)
+	}
+	if drug.KFACode != "93000001" {
+		t.Fatalf("accept: got drug KFA code %q, want 93000001", drug.KFACode)
+	}
+	if len(refresher.refreshed) != 1 || refresher.refreshed[0] != "OBT1" {
+		t.Fatalf("accept: got refreshed drugs %v, want OBT1", refresher.refreshed)
+	}
+
+	code, body = do("GET", "/kfa/matches/"+otherID, "")
+	if code != 200 {
+		t.Fatalf("detail of other match: got code %d, body %s", code, body)
+	}
+	if !strings.Contains(body, "Ditolak") {
+		t.Fatalf("detail of other match: expected it to be rejected: %s", body)
+	}
+
+	// Accepting another match of the drug replaces the previously accepted one.
+	code, body = do("PATCH", "/kfa/matches/"+otherID, `{"status": "ACCEPTED"}`)
+	if code != 200 {
+		t.Fatalf("accept other: got code %d, body %s", code, body)
+	}
+	if err := db.Where("vmedis_code = ?", "OBT1").First(&drug).Error; err != nil {
+		t.Fatalf("get drug: %s", err)
+	}
+	if drug.KFACode != "93000002" {
+		t.Fatalf("accept other: got drug KFA code %q, want 93000002", drug.KFACode)
+	}
+
+	code, body = do("GET", "/kfa/matches?statuses=accepted&drug_code=OBT1", "")
+	if code != 200 {
+		t.Fatalf("list accepted: got code %d, body %s", code, body)
+	}
+	if list := unmarshal[cui.Table](t, body); len(list.Rows) != 1 || list.Rows[0].ID != otherID {
+		t.Fatalf("list accepted: expected only the other match to be accepted, got %s", body)
+	}
+
+	// Regenerating the matches must not bring back the reviewed ones, and the
+	// accepted drug is not matched anymore.
+	if _, err := service.GenerateMatches(ctx); err != nil {
+		t.Fatalf("regenerate matches: %s", err)
+	}
+
+	code, body = do("GET", "/kfa/matches?drug_code=OBT1", "")
+	if code != 200 {
+		t.Fatalf("list after regenerate: got code %d, body %s", code, body)
+	}
+	if list := unmarshal[cui.Table](t, body); len(list.Rows) != 2 {
+		t.Fatalf("list after regenerate: expected the two reviewed matches, got %s", body)
+	}
+
+	// Rejecting the accepted match unlinks the drug from its KFA code.
+	code, body = do("PATCH", "/kfa/matches/"+otherID, `{"status": "REJECTED"}`)
+	if code != 200 {
+		t.Fatalf("reject accepted: got code %d, body %s", code, body)
+	}
+	if err := db.Where("vmedis_code = ?", "OBT1").First(&drug).Error; err != nil {
+		t.Fatalf("get drug: %s", err)
+	}
+	if drug.KFACode != "" {
+		t.Fatalf("reject accepted: got drug KFA code %q, want it cleared", drug.KFACode)
+	}
+	if n := len(refresher.refreshed); n != 3 || refresher.refreshed[n-1] != "OBT1" {
+		t.Fatalf("reject accepted: got refreshed drugs %v, want OBT1 refreshed again", refresher.refreshed)
+	}
+
+	code, body = do("GET", "/kfa/matches/999", "")
+	if code != 404 {
+		t.Fatalf("detail of unknown match: got code %d, body %s", code, body)
+	}
+}
+
+// TestParseProductsJSON checks that JSON master files are parsed with the
+// same field aliases as CSV ones, and that numeric codes are kept as written.
+func TestParseProductsJSON(t *testing.T) {
+	products, err := kfa.ParseProducts(strings.NewReader(`[
+		{"kode_kfa": "93000001", "Nama Produk": "Ibuprofen 400 mg Tablet", "nama_pabrik": "Casper Pharma"},
+		{"kode_kfa": 93000002, "nama": "Ibuprofen 200 mg Tablet", "kekuatan": 200},
+		{"kode_kfa": "", "nama": "Without Code"}
+	]`), kfa.FileFormatJSON)
+	if err != nil {
+		t.Fatalf("parse products: %s", err)
+	}
+
+	if len(products) != 2 {
+		t.Fatalf("got %d products, want 2", len(products))
+	}
+
+	if products[0].Code != "93000001" || products[0].Name != "Ibuprofen 400 mg Tablet" || products[0].Manufacturer != "Casper Pharma" {
+		t.Fatalf("unexpected product: %+v", products[0])
+	}
+
+	if products[1].Code != "93000002" || products[1].Name != "Ibuprofen 200 mg Tablet" || products[1].Strength != "200" {
+		t.Fatalf("unexpected product with a numeric code: %+v", products[1])
+	}
+}
+
+// drugRefresher records the drugs refreshed after their KFA codes are set.
+type drugRefresher struct {
+	refreshed []string
+}
+
+func (r *drugRefresher) RefreshDrug(_ context.Context, vmedisCode string) error {
+	r.refreshed = append(r.refreshed, vmedisCode)
+	return nil
+}
+
+func setup(t *testing.T) (*gorm.DB, *gin.Engine, *drugRefresher) {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open database: %s", err)
+	}
+	if err := db.AutoMigrate(&models.Drug{}, &models.KFAProduct{}, &models.KFAMatch{}); err != nil {
+		t.Fatalf("migrate database: %s", err)
+	}
+
+	refresher := &drugRefresher{}
+	handler := kfa.NewApiHandler(kfa.NewService(db, refresher))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	// Mirrors the route registration in proxy/api.go, without auth middleware.
+	kfaMatches := router.Group("/kfa/matches")
+	{
+		kfaMatches.GET("", handler.GetMatches)
+		kfaMatches.GET("/statuses", handler.GetStatuses)
+		kfaMatches.GET("/:id", handler.GetMatch)
+		kfaMatches.GET("/:id/form", handler.GetReviewMatchForm)
+		kfaMatches.PATCH("/:id", handler.ReviewMatch)
+	}
+
+	return db, router, refresher
+}
+
+func unmarshal[T any](t *testing.T, body string) T {
+	t.Helper()
+
+	var value T
+	if err := json.Unmarshal([]byte(body), &value); err != nil {
+		t.Fatalf("unmarshal %T from %s: %s", value, body, err)
+	}
+
+	return value
+}
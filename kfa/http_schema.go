@@ -0,0 +1,13 @@
+package kfa
+
+import (
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+)
+
+type ReviewMatchRequest struct {
+	Status models.KFAMatchStatus `json:"status" binding:"required"`
+}
+
+type MatchResponse struct {
+	Match Match `json:"match"`
+}
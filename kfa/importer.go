@@ -0,0 +1,162 @@
+package kfa
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+	"github.com/turfaa/vmedis-proxy-api/pkg2/csv2"
+)
+
+// FileFormat is the format of a KFA master file.
+type FileFormat string
+
+const (
+	FileFormatCSV  FileFormat = "csv"
+	FileFormatJSON FileFormat = "json"
+)
+
+// FileFormatFromPath guesses the file format from the extension of the given path.
+func FileFormatFromPath(path string) (FileFormat, error) {
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".csv":
+		return FileFormatCSV, nil
+	case ".json":
+		return FileFormatJSON, nil
+	default:
+		return "", fmt.Errorf("unknown KFA file extension %q, expected .csv or .json", ext)
+	}
+}
+
+// productFieldAliases lists the accepted column (CSV) or key (JSON) names of each product field.
+// The KFA exports are not consistent between versions, so both the English and Indonesian names are accepted.
+var productFieldAliases = map[string][]string{
+	"code":              {"kfa_code", "kode_kfa", "code", "kode"},
+	"name":              {"name", "display_name", "nama", "nama_produk"},
+	"manufacturer":      {"manufacturer", "nama_pabrik", "pabrik", "produsen"},
+	"active_ingredient": {"active_ingredient", "active_ingredients", "zat_aktif", "bahan_aktif"},
+	"strength":          {"strength", "kekuatan"},
+	"dosage_form":       {"dosage_form", "bentuk_sediaan", "sediaan"},
+}
+
+// ParseProducts parses the KFA products in the given format.
+// Records without a KFA code are skipped.
+func ParseProducts(r io.Reader, format FileFormat) ([]models.KFAProduct, error) {
+	switch format {
+	case FileFormatCSV:
+		return parseCSVProducts(r)
+	case FileFormatJSON:
+		return parseJSONProducts(r)
+	default:
+		return nil, fmt.Errorf("unknown KFA file format %q", format)
+	}
+}
+
+func parseCSVProducts(r io.Reader) ([]models.KFAProduct, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+
+	fieldIndex := csv2.FieldIndex(header, productFieldAliases)
+
+	if _, ok := fieldIndex["code"]; !ok {
+		return nil, fmt.Errorf("KFA code column not found in header %v", header)
+	}
+	if _, ok := fieldIndex["name"]; !ok {
+		return nil, fmt.Errorf("name column not found in header %v", header)
+	}
+
+	var products []models.KFAProduct
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read line %d: %w", line, err)
+		}
+
+		get := func(field string) string {
+			i, ok := fieldIndex[field]
+			if !ok || i >= len(record) {
+				return ""
+			}
+
+			return strings.TrimSpace(record[i])
+		}
+
+		if product, ok := newProduct(get); ok {
+			products = append(products, product)
+		}
+	}
+
+	return products, nil
+}
+
+func parseJSONProducts(r io.Reader) ([]models.KFAProduct, error) {
+	// Numbers are kept as written, so that numeric codes like 93000001 aren't turned into 9.3000001e+07.
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+
+	var records []map[string]any
+	if err := decoder.Decode(&records); err != nil {
+		return nil, fmt.Errorf("decode JSON array: %w", err)
+	}
+
+	products := make([]models.KFAProduct, 0, len(records))
+	for _, record := range records {
+		normalized := make(map[string]any, len(record))
+		for key, value := range record {
+			normalized[csv2.NormalizeHeader(key)] = value
+		}
+
+		get := func(field string) string {
+			for _, alias := range productFieldAliases[field] {
+				value, ok := normalized[alias]
+				if !ok || value == nil {
+					continue
+				}
+
+				switch v := value.(type) {
+				case string:
+					return strings.TrimSpace(v)
+				case json.Number:
+					return v.String()
+				}
+
+				return fmt.Sprint(value)
+			}
+
+			return ""
+		}
+
+		if product, ok := newProduct(get); ok {
+			products = append(products, product)
+		}
+	}
+
+	return products, nil
+}
+
+func newProduct(get func(field string) string) (models.KFAProduct, bool) {
+	product := models.KFAProduct{
+		Code:             get("code"),
+		Name:             get("name"),
+		Manufacturer:     get("manufacturer"),
+		ActiveIngredient: get("active_ingredient"),
+		Strength:         get("strength"),
+		DosageForm:       get("dosage_form"),
+	}
+
+	return product, product.Code != ""
+}
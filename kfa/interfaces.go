@@ -0,0 +1,9 @@
+package kfa
+
+import (
+	"context"
+)
+
+type DrugRefresher interface {
+	RefreshDrug(ctx context.Context, vmedisCode string) error
+}
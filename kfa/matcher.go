@@ -0,0 +1,242 @@
+package kfa
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+)
+
+const (
+	// minimumConfidence is the lowest confidence of a match to be suggested.
+	minimumConfidence = 0.4
+
+	// maxSuggestionsPerDrug is the maximum number of suggested matches per drug.
+	maxSuggestionsPerDrug = 3
+
+	// nameWeight is the weight of the name similarity in the confidence when both manufacturers are known.
+	// The rest of the weight goes to the manufacturer similarity.
+	nameWeight = 0.8
+)
+
+// tokenSynonyms normalizes the abbreviations commonly used in Vmedis drug names
+// to the words used in the KFA master file.
+var tokenSynonyms = map[string]string{
+	"tab":     "tablet",
+	"tabs":    "tablet",
+	"kaplet":  "tablet",
+	"cap":     "kapsul",
+	"caps":    "kapsul",
+	"kaps":    "kapsul",
+	"capsule": "kapsul",
+	"syr":     "sirup",
+	"syrup":   "sirup",
+	"sir":     "sirup",
+	"susp":    "suspensi",
+	"inj":     "injeksi",
+	"oint":    "salep",
+	"cr":      "krim",
+	"cream":   "krim",
+	"gr":      "g",
+	"gram":    "g",
+	"ug":      "mcg",
+}
+
+// manufacturerStopWords are the words that don't help to tell manufacturers apart.
+var manufacturerStopWords = map[string]bool{
+	"pt":           true,
+	"tbk":          true,
+	"cv":           true,
+	"indonesia":    true,
+	"farma":        true,
+	"pharma":       true,
+	"laboratories": true,
+	"lab":          true,
+}
+
+// suggestion is a KFA product suggested for a drug.
+type suggestion struct {
+	Product    models.KFAProduct
+	Confidence float64
+}
+
+// matcher suggests KFA products for drugs based on the similarity of their names and manufacturers.
+type matcher struct {
+	products             []models.KFAProduct
+	nameTokens           [][]string
+	manufacturerTokens   [][]string
+	productIndexesByWord map[string][]int
+}
+
+func newMatcher(products []models.KFAProduct) *matcher {
+	m := &matcher{
+		products:             products,
+		nameTokens:           make([][]string, len(products)),
+		manufacturerTokens:   make([][]string, len(products)),
+		productIndexesByWord: make(map[string][]int),
+	}
+
+	for i, product := range products {
+		m.nameTokens[i] = tokenize(product.Name)
+		m.manufacturerTokens[i] = manufacturerTokens(product.Manufacturer)
+
+		for _, token := range m.nameTokens[i] {
+			if isIndexedWord(token) {
+				m.productIndexesByWord[token] = append(m.productIndexesByWord[token], i)
+			}
+		}
+	}
+
+	return m
+}
+
+// suggest returns the best matching KFA products of the given drug, sorted by confidence descending.
+func (m *matcher) suggest(drug models.Drug) []suggestion {
+	drugNameTokens := tokenize(drug.Name)
+	drugManufacturerTokens := manufacturerTokens(drug.Manufacturer)
+
+	candidates := make(map[int]bool)
+	for _, token := range drugNameTokens {
+		if !isIndexedWord(token) {
+			continue
+		}
+
+		for _, i := range m.productIndexesByWord[token] {
+			candidates[i] = true
+		}
+	}
+
+	var suggestions []suggestion
+	for i := range candidates {
+		confidence := diceCoefficient(drugNameTokens, m.nameTokens[i])
+		if len(drugManufacturerTokens) > 0 && len(m.manufacturerTokens[i]) > 0 {
+			confidence = nameWeight*confidence + (1-nameWeight)*diceCoefficient(drugManufacturerTokens, m.manufacturerTokens[i])
+		}
+
+		if confidence < minimumConfidence {
+			continue
+		}
+
+		suggestions = append(suggestions, suggestion{Product: m.products[i], Confidence: confidence})
+	}
+
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].Confidence != suggestions[j].Confidence {
+			return suggestions[i].Confidence > suggestions[j].Confidence
+		}
+
+		return suggestions[i].Product.Code < suggestions[j].Product.Code
+	})
+
+	if len(suggestions) > maxSuggestionsPerDrug {
+		suggestions = suggestions[:maxSuggestionsPerDrug]
+	}
+
+	return suggestions
+}
+
+// tokenize splits the given text into lowercase words and numbers,
+// e.g. "IBUPROFEN 400MG TAB" becomes [ibuprofen 400 mg tablet].
+func tokenize(text string) []string {
+	var (
+		tokens  []string
+		current []rune
+		isDigit bool
+	)
+
+	flush := func() {
+		if len(current) == 0 {
+			return
+		}
+
+		token := strings.TrimRight(string(current), ".,")
+		if synonym, ok := tokenSynonyms[token]; ok {
+			token = synonym
+		}
+
+		// Single letters are usually separators, e.g. the "x" in "10x10".
+		if len(current) > 1 || isDigit {
+			tokens = append(tokens, token)
+		}
+
+		current = current[:0]
+	}
+
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.IsDigit(r) || (r == '.' || r == ',') && isDigit && len(current) > 0:
+			if !isDigit {
+				flush()
+			}
+			isDigit = true
+			current = append(current, r)
+
+		case unicode.IsLetter(r):
+			if isDigit {
+				flush()
+			}
+			isDigit = false
+			current = append(current, r)
+
+		default:
+			flush()
+		}
+	}
+	flush()
+
+	return tokens
+}
+
+func manufacturerTokens(manufacturer string) []string {
+	var tokens []string
+	for _, token := range tokenize(manufacturer) {
+		if !manufacturerStopWords[token] {
+			tokens = append(tokens, token)
+		}
+	}
+
+	return tokens
+}
+
+// isIndexedWord returns whether the token is distinctive enough to look up candidates by.
+// Numbers and short words like units would make almost every product a candidate.
+func isIndexedWord(token string) bool {
+	if len(token) < 3 {
+		return false
+	}
+
+	for _, r := range token {
+		if unicode.IsDigit(r) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// diceCoefficient returns the Sørensen–Dice coefficient of the two token sets, between 0 and 1.
+func diceCoefficient(a, b []string) float64 {
+	setA := make(map[string]bool, len(a))
+	for _, token := range a {
+		setA[token] = true
+	}
+
+	setB := make(map[string]bool, len(b))
+	for _, token := range b {
+		setB[token] = true
+	}
+
+	if len(setA)+len(setB) == 0 {
+		return 0
+	}
+
+	intersection := 0
+	for token := range setA {
+		if setB[token] {
+			intersection++
+		}
+	}
+
+	return 2 * float64(intersection) / float64(len(setA)+len(setB))
+}
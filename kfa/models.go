@@ -0,0 +1,69 @@
+package kfa
+
+import (
+	"time"
+
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+)
+
+// Product is a product in the KFA master file.
+type Product struct {
+	Code             string `json:"code"`
+	Name             string `json:"name"`
+	Manufacturer     string `json:"manufacturer"`
+	ActiveIngredient string `json:"activeIngredient"`
+	Strength         string `json:"strength"`
+	DosageForm       string `json:"dosageForm"`
+}
+
+func FromDBProduct(product models.KFAProduct) Product {
+	return Product{
+		Code:             product.Code,
+		Name:             product.Name,
+		Manufacturer:     product.Manufacturer,
+		ActiveIngredient: product.ActiveIngredient,
+		Strength:         product.Strength,
+		DosageForm:       product.DosageForm,
+	}
+}
+
+// Match is a suggested KFA code for a drug, together with the names
+// needed to review it.
+type Match struct {
+	ID               uint                  `json:"id"`
+	CreatedAt        time.Time             `json:"createdAt"`
+	DrugVmedisCode   string                `json:"drugVmedisCode"`
+	DrugName         string                `json:"drugName"`
+	DrugManufacturer string                `json:"drugManufacturer"`
+	KFACode          string                `json:"kfaCode"`
+	KFAName          string                `json:"kfaName"`
+	KFAManufacturer  string                `json:"kfaManufacturer"`
+	Confidence       float64               `json:"confidence"`
+	Status           models.KFAMatchStatus `json:"status"`
+	ReviewedAt       *time.Time            `json:"reviewedAt,omitempty"`
+	ReviewedBy       string                `json:"reviewedBy"`
+}
+
+func fromMatchWithNames(match matchWithNames) Match {
+	return Match{
+		ID:               match.ID,
+		CreatedAt:        match.CreatedAt,
+		DrugVmedisCode:   match.DrugVmedisCode,
+		DrugName:         match.DrugName,
+		DrugManufacturer: match.DrugManufacturer,
+		KFACode:          match.KFACode,
+		KFAName:          match.KFAName,
+		KFAManufacturer:  match.KFAManufacturer,
+		Confidence:       match.Confidence,
+		Status:           match.Status,
+		ReviewedAt:       match.ReviewedAt,
+		ReviewedBy:       match.ReviewedBy,
+	}
+}
+
+// ListFilters are the filters that can be applied when listing matches.
+// Zero-valued fields are ignored.
+type ListFilters struct {
+	Statuses       []models.KFAMatchStatus
+	DrugVmedisCode string
+}
@@ -0,0 +1,148 @@
+package kfa
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+	"github.com/turfaa/vmedis-proxy-api/pkg2/slices2"
+
+	"gorm.io/gorm"
+)
+
+type Service struct {
+	db            *Database
+	drugRefresher DrugRefresher
+}
+
+func NewService(db *gorm.DB, drugRefresher DrugRefresher) *Service {
+	return &Service{
+		db:            NewDatabase(db),
+		drugRefresher: drugRefresher,
+	}
+}
+
+// ImportProducts loads the KFA master file in the given format to the database
+// and returns the number of imported products.
+// Products that already exist are updated by their KFA code.
+func (s *Service) ImportProducts(ctx context.Context, r io.Reader, format FileFormat) (int, error) {
+	products, err := ParseProducts(r, format)
+	if err != nil {
+		return 0, fmt.Errorf("parse KFA products: %w", err)
+	}
+
+	products = dedupeProductsByCode(products)
+
+	if err := s.db.UpsertProducts(ctx, products); err != nil {
+		return 0, fmt.Errorf("upsert KFA products: %w", err)
+	}
+
+	return len(products), nil
+}
+
+// GenerateMatches suggests KFA codes for all drugs that don't have one yet
+// and returns the number of suggested matches.
+// The previous pending suggestions are replaced, while the reviewed ones are kept.
+func (s *Service) GenerateMatches(ctx context.Context) (int, error) {
+	products, err := s.db.GetProducts(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("get KFA products: %w", err)
+	}
+
+	drugs, err := s.db.GetUnmatchedDrugs(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("get unmatched drugs: %w", err)
+	}
+
+	log.Printf("Matching %d drugs against %d KFA products", len(drugs), len(products))
+
+	m := newMatcher(products)
+
+	var matches []models.KFAMatch
+	for _, drug := range drugs {
+		for _, suggestion := range m.suggest(drug) {
+			matches = append(matches, models.KFAMatch{
+				DrugVmedisCode: drug.VmedisCode,
+				KFACode:        suggestion.Product.Code,
+				Confidence:     suggestion.Confidence,
+				Status:         models.KFAMatchStatusPending,
+			})
+		}
+	}
+
+	drugCodes := slices2.Map(drugs, func(drug models.Drug) string { return drug.VmedisCode })
+	if err := s.db.ReplacePendingMatches(ctx, drugCodes, matches); err != nil {
+		return 0, fmt.Errorf("replace pending matches: %w", err)
+	}
+
+	return len(matches), nil
+}
+
+func (s *Service) GetMatches(ctx context.Context, filters ListFilters) ([]Match, error) {
+	matches, err := s.db.GetMatches(ctx, filters)
+	if err != nil {
+		return nil, fmt.Errorf("get KFA matches: %w", err)
+	}
+
+	return slices2.Map(matches, fromMatchWithNames), nil
+}
+
+func (s *Service) GetMatchByID(ctx context.Context, id uint) (Match, error) {
+	match, err := s.db.GetMatchByID(ctx, id)
+	if err != nil {
+		return Match{}, fmt.Errorf("get KFA match %d: %w", id, err)
+	}
+
+	return fromMatchWithNames(match), nil
+}
+
+// ReviewMatch sets the status of the match.
+// Accepting a match sets the KFA code of the drug, refreshes the drug so its changes and cache show the code,
+// and rejects its other pending or accepted matches. Un-accepting a match clears the code and refreshes the drug too.
+func (s *Service) ReviewMatch(ctx context.Context, id uint, request ReviewMatchRequest, reviewedBy string) (Match, error) {
+	if !request.Status.Valid() {
+		return Match{}, fmt.Errorf("invalid status: %s", request.Status)
+	}
+
+	match, err := s.db.GetMatchByID(ctx, id)
+	if err != nil {
+		return Match{}, fmt.Errorf("get KFA match %d: %w", id, err)
+	}
+
+	if err := s.db.SetMatchStatus(ctx, match.KFAMatch, request.Status, reviewedBy); err != nil {
+		return Match{}, fmt.Errorf("set KFA match %d status: %w", id, err)
+	}
+
+	if request.Status == models.KFAMatchStatusAccepted || match.Status == models.KFAMatchStatusAccepted {
+		if err := s.drugRefresher.RefreshDrug(ctx, match.DrugVmedisCode); err != nil {
+			return Match{}, fmt.Errorf("refresh drug %s: %w", match.DrugVmedisCode, err)
+		}
+	}
+
+	return s.GetMatchByID(ctx, id)
+}
+
+func (s *Service) GetStatuses() []models.KFAMatchStatus {
+	return models.AllKFAMatchStatuses()
+}
+
+// dedupeProductsByCode keeps the last product of each KFA code,
+// because one upsert statement can't update the same row twice.
+func dedupeProductsByCode(products []models.KFAProduct) []models.KFAProduct {
+	indexByCode := make(map[string]int, len(products))
+
+	deduped := make([]models.KFAProduct, 0, len(products))
+	for _, product := range products {
+		if i, ok := indexByCode[product.Code]; ok {
+			deduped[i] = product
+			continue
+		}
+
+		indexByCode[product.Code] = len(deduped)
+		deduped = append(deduped, product)
+	}
+
+	return deduped
+}
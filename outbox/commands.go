@@ -0,0 +1,23 @@
+package outbox
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"gorm.io/gorm"
+
+	"github.com/turfaa/vmedis-proxy-api/pkg2/queue"
+)
+
+// RunRelay relays the outbox messages to the queue until the context is done or a termination signal is received.
+func RunRelay(ctx context.Context, db *gorm.DB, q queue.Publisher, config RelayConfig) {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+
+	log.Println("Relaying outbox messages")
+	NewRelay(db, q, config).Run(ctx)
+	log.Println("Outbox relay shut down successfully")
+}
@@ -0,0 +1,69 @@
+// Package outbox publishes messages reliably through a transactional outbox:
+// the messages are written to the DB in the same transaction as the change they announce,
+// and a relay publishes them to the queue afterwards, retrying until it succeeds.
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+	"github.com/turfaa/vmedis-proxy-api/pkg2/queue"
+)
+
+type txKey struct{}
+
+// Outbox is a queue.Publisher that writes the messages to the outbox table instead of publishing them.
+//
+// When the context comes from Transaction, the messages are written in that transaction,
+// so they are only published if the transaction commits.
+type Outbox struct {
+	db *gorm.DB
+}
+
+func (o *Outbox) Publish(ctx context.Context, messages ...queue.Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	db, ok := ctx.Value(txKey{}).(*gorm.DB)
+	if !ok {
+		db = o.db
+	}
+
+	now := time.Now()
+
+	rows := make([]models.OutboxMessage, 0, len(messages))
+	for _, message := range messages {
+		rows = append(rows, models.OutboxMessage{
+			Topic:         message.Topic,
+			Key:           message.Key,
+			Value:         message.Value,
+			Headers:       message.Headers,
+			NextAttemptAt: now,
+		})
+	}
+
+	if err := db.WithContext(ctx).Create(&rows).Error; err != nil {
+		return fmt.Errorf("write %d messages to outbox: %w", len(rows), err)
+	}
+
+	return nil
+}
+
+// Transaction runs fn in a transaction of db. The messages published to an Outbox
+// with the context passed to fn are written in the same transaction.
+func Transaction(ctx context.Context, db *gorm.DB, fn func(ctx context.Context, tx *gorm.DB) error) error {
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(context.WithValue(ctx, txKey{}, tx), tx)
+	})
+}
+
+func NewOutbox(db *gorm.DB) *Outbox {
+	return &Outbox{
+		db: db,
+	}
+}
@@ -0,0 +1,242 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+	"github.com/turfaa/vmedis-proxy-api/pkg2/queue"
+)
+
+// RelayConfig controls how the relay publishes the outbox messages.
+type RelayConfig struct {
+	// BatchSize is the maximum number of messages published at once.
+	BatchSize int
+
+	// PollInterval is the delay between polls after the outbox is drained.
+	PollInterval time.Duration
+
+	// ClaimTimeout is how long a relay has to publish the messages it claimed
+	// before another relay may claim them again.
+	ClaimTimeout time.Duration
+
+	// InitialBackoff is the delay before retrying a message that failed to be published.
+	// The delay doubles on every subsequent failure, up to MaxBackoff.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+
+	// Retention is how long the sent messages are kept before they are deleted.
+	Retention time.Duration
+}
+
+var DefaultRelayConfig = RelayConfig{
+	BatchSize:      100,
+	PollInterval:   time.Second,
+	ClaimTimeout:   time.Minute,
+	InitialBackoff: time.Second,
+	MaxBackoff:     10 * time.Minute,
+	Retention:      7 * 24 * time.Hour,
+}
+
+// Relay publishes the pending outbox messages to the queue, and marks them sent.
+//
+// Several relays may run at the same time: every message is claimed by one relay before it's published.
+// A message is published at least once, as a relay may stop between publishing and marking it sent.
+type Relay struct {
+	db     *gorm.DB
+	queue  queue.Publisher
+	config RelayConfig
+}
+
+// Run relays the pending messages until the context is done.
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.config.PollInterval)
+	defer ticker.Stop()
+
+	lastCleanup := time.Time{}
+	for {
+		if _, err := r.RelayPending(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("Error relaying outbox messages: %s", err)
+		}
+
+		if time.Since(lastCleanup) > time.Hour {
+			if deleted, err := r.DeleteSent(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("Error deleting sent outbox messages: %s", err)
+			} else if deleted > 0 {
+				log.Printf("Deleted %d sent outbox messages", deleted)
+			}
+
+			lastCleanup = time.Now()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RelayPending publishes the messages that are due, batch by batch, until none is left.
+// It returns the number of messages published.
+func (r *Relay) RelayPending(ctx context.Context) (int, error) {
+	relayed := 0
+	for {
+		messages, err := r.claim(ctx)
+		if err != nil {
+			return relayed, fmt.Errorf("claim outbox messages: %w", err)
+		}
+
+		if len(messages) == 0 {
+			return relayed, nil
+		}
+
+		if err := r.publish(ctx, messages); err != nil {
+			return relayed, err
+		}
+
+		relayed += len(messages)
+	}
+}
+
+// DeleteSent deletes the messages sent longer than the retention ago.
+func (r *Relay) DeleteSent(ctx context.Context) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("sent_at < ?", time.Now().Add(-r.config.Retention)).
+		Delete(&models.OutboxMessage{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("delete sent outbox messages: %w", result.Error)
+	}
+
+	return result.RowsAffected, nil
+}
+
+// claim reserves the oldest due messages for this relay by pushing back their next attempt,
+// so other relays skip them while they're being published.
+func (r *Relay) claim(ctx context.Context) ([]models.OutboxMessage, error) {
+	now := time.Now()
+
+	var ids []uint
+	if err := r.db.WithContext(ctx).
+		Model(&models.OutboxMessage{}).
+		Where("sent_at IS NULL AND next_attempt_at <= ?", now).
+		Order("id").
+		Limit(r.config.BatchSize).
+		Pluck("id", &ids).
+		Error; err != nil {
+		return nil, fmt.Errorf("get due outbox messages: %w", err)
+	}
+
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	claimID := uuid.NewString()
+	if err := r.db.WithContext(ctx).
+		Model(&models.OutboxMessage{}).
+		Where("id IN ? AND sent_at IS NULL AND next_attempt_at <= ?", ids, now).
+		Updates(map[string]any{
+			"claimed_by":      claimID,
+			"next_attempt_at": now.Add(r.config.ClaimTimeout),
+		}).
+		Error; err != nil {
+		return nil, fmt.Errorf("claim due outbox messages: %w", err)
+	}
+
+	var messages []models.OutboxMessage
+	if err := r.db.WithContext(ctx).
+		Where("id IN ? AND claimed_by = ?", ids, claimID).
+		Order("id").
+		Find(&messages).
+		Error; err != nil {
+		return nil, fmt.Errorf("get claimed outbox messages: %w", err)
+	}
+
+	return messages, nil
+}
+
+func (r *Relay) publish(ctx context.Context, messages []models.OutboxMessage) error {
+	queueMessages := make([]queue.Message, 0, len(messages))
+	ids := make([]uint, 0, len(messages))
+	for _, message := range messages {
+		queueMessages = append(queueMessages, queue.Message{
+			Topic:   message.Topic,
+			Key:     message.Key,
+			Value:   message.Value,
+			Headers: message.Headers,
+		})
+		ids = append(ids, message.ID)
+	}
+
+	if publishErr := r.queue.Publish(ctx, queueMessages...); publishErr != nil {
+		publishErr = fmt.Errorf("publish %d outbox messages: %w", len(messages), publishErr)
+
+		if err := r.markFailed(context.WithoutCancel(ctx), messages, publishErr); err != nil {
+			return errors.Join(publishErr, err)
+		}
+
+		return publishErr
+	}
+
+	// The messages are already published, so they're marked sent even if the context is done.
+	if err := r.db.WithContext(context.WithoutCancel(ctx)).
+		Model(&models.OutboxMessage{}).
+		Where("id IN ?", ids).
+		Updates(map[string]any{
+			"sent_at":  sql.NullTime{Time: time.Now(), Valid: true},
+			"attempts": gorm.Expr("attempts + 1"),
+		}).
+		Error; err != nil {
+		return fmt.Errorf("mark %d outbox messages sent: %w", len(ids), err)
+	}
+
+	return nil
+}
+
+func (r *Relay) markFailed(ctx context.Context, messages []models.OutboxMessage, publishErr error) error {
+	now := time.Now()
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, message := range messages {
+			attempts := message.Attempts + 1
+
+			if err := tx.
+				Model(&models.OutboxMessage{}).
+				Where("id = ?", message.ID).
+				Updates(map[string]any{
+					"attempts":        attempts,
+					"last_error":      publishErr.Error(),
+					"next_attempt_at": now.Add(r.backoff(attempts)),
+				}).
+				Error; err != nil {
+				return fmt.Errorf("mark outbox message %d failed: %w", message.ID, err)
+			}
+		}
+
+		return nil
+	})
+}
+
+func (r *Relay) backoff(attempts int) time.Duration {
+	backoff := r.config.InitialBackoff
+	for i := 1; i < attempts && backoff < r.config.MaxBackoff; i++ {
+		backoff *= 2
+	}
+
+	return min(backoff, r.config.MaxBackoff)
+}
+
+func NewRelay(db *gorm.DB, q queue.Publisher, config RelayConfig) *Relay {
+	return &Relay{
+		db:     db,
+		queue:  q,
+		config: config,
+	}
+}
@@ -0,0 +1,129 @@
+package outbox_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/turfaa/vmedis-proxy-api/database"
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+	"github.com/turfaa/vmedis-proxy-api/outbox"
+	"github.com/turfaa/vmedis-proxy-api/pkg2/queue"
+)
+
+// TestRelay checks that only the messages of committed transactions are relayed,
+// and that a relayed message is marked sent and not published again.
+func TestRelay(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	ob := outbox.NewOutbox(db)
+
+	if err := outbox.Transaction(ctx, db, func(ctx context.Context, tx *gorm.DB) error {
+		return ob.Publish(ctx, queue.Message{Topic: "topic", Key: []byte("committed"), Headers: map[string]string{"h": "v"}})
+	}); err != nil {
+		t.Fatalf("Transaction() error = %v", err)
+	}
+
+	rollbackErr := errors.New("rollback")
+	if err := outbox.Transaction(ctx, db, func(ctx context.Context, tx *gorm.DB) error {
+		if err := ob.Publish(ctx, queue.Message{Topic: "topic", Key: []byte("rolled back")}); err != nil {
+			return err
+		}
+		return rollbackErr
+	}); !errors.Is(err, rollbackErr) {
+		t.Fatalf("Transaction() error = %v, want %v", err, rollbackErr)
+	}
+
+	publisher := &recordingPublisher{}
+	relay := outbox.NewRelay(db, publisher, outbox.DefaultRelayConfig)
+
+	relayed, err := relay.RelayPending(ctx)
+	if err != nil {
+		t.Fatalf("RelayPending() error = %v", err)
+	}
+	if relayed != 1 || len(publisher.messages) != 1 {
+		t.Fatalf("relayed %d messages, published %v, want only the committed message", relayed, publisher.messages)
+	}
+	if got := publisher.messages[0]; string(got.Key) != "committed" || got.Headers["h"] != "v" {
+		t.Errorf("published %+v, want the committed message with its headers", got)
+	}
+
+	if relayed, err := relay.RelayPending(ctx); err != nil || relayed != 0 {
+		t.Errorf("second RelayPending() = %d, %v, want nothing relayed", relayed, err)
+	}
+
+	var message models.OutboxMessage
+	if err := db.First(&message).Error; err != nil {
+		t.Fatalf("get outbox message: %v", err)
+	}
+	if !message.SentAt.Valid || message.Attempts != 1 {
+		t.Errorf("outbox message = %+v, want sent after 1 attempt", message)
+	}
+}
+
+// TestRelayRetries checks that a message that failed to be published is retried after the backoff.
+func TestRelayRetries(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+
+	if err := outbox.NewOutbox(db).Publish(ctx, queue.Message{Topic: "topic", Key: []byte("key")}); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+
+	config := outbox.DefaultRelayConfig
+	config.InitialBackoff = 50 * time.Millisecond
+
+	publisher := &recordingPublisher{err: errors.New("broker down")}
+	relay := outbox.NewRelay(db, publisher, config)
+
+	if _, err := relay.RelayPending(ctx); err == nil {
+		t.Fatal("RelayPending() error = nil, want the publish error")
+	}
+
+	var message models.OutboxMessage
+	if err := db.First(&message).Error; err != nil {
+		t.Fatalf("get outbox message: %v", err)
+	}
+	if message.SentAt.Valid || message.Attempts != 1 || message.LastError == "" {
+		t.Errorf("outbox message = %+v, want unsent with 1 failed attempt", message)
+	}
+
+	publisher.err = nil
+	if relayed, err := relay.RelayPending(ctx); err != nil || relayed != 0 {
+		t.Errorf("RelayPending() during backoff = %d, %v, want nothing relayed", relayed, err)
+	}
+
+	time.Sleep(config.InitialBackoff)
+
+	if relayed, err := relay.RelayPending(ctx); err != nil || relayed != 1 {
+		t.Errorf("RelayPending() after backoff = %d, %v, want 1 relayed", relayed, err)
+	}
+}
+
+type recordingPublisher struct {
+	messages []queue.Message
+	err      error
+}
+
+func (p *recordingPublisher) Publish(_ context.Context, messages ...queue.Message) error {
+	if p.err != nil {
+		return p.err
+	}
+
+	p.messages = append(p.messages, messages...)
+	return nil
+}
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := database.SqliteDB(t.TempDir() + "/test.db")
+	if err != nil {
+		t.Fatalf("open test database: %v", err)
+	}
+
+	return db
+}
@@ -0,0 +1,34 @@
+package csv2
+
+import "strings"
+
+var headerReplacer = strings.NewReplacer(" ", "_", "-", "_")
+
+// NormalizeHeader normalizes a column name, so "Kode Obat", "kode-obat", and "KODE_OBAT" are the same column.
+// It trims the byte order mark some spreadsheet exports start with.
+func NormalizeHeader(name string) string {
+	name = strings.TrimPrefix(name, "\ufeff")
+	name = strings.ToLower(strings.TrimSpace(name))
+	return headerReplacer.Replace(name)
+}
+
+// FieldIndex returns the index of the column of every field found in the header,
+// matching the normalized column names with the aliases of the field in order.
+func FieldIndex(header []string, fieldAliases map[string][]string) map[string]int {
+	columnIndex := make(map[string]int, len(header))
+	for i, column := range header {
+		columnIndex[NormalizeHeader(column)] = i
+	}
+
+	fieldIndex := make(map[string]int, len(fieldAliases))
+	for field, aliases := range fieldAliases {
+		for _, alias := range aliases {
+			if i, ok := columnIndex[alias]; ok {
+				fieldIndex[field] = i
+				break
+			}
+		}
+	}
+
+	return fieldIndex
+}
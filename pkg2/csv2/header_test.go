@@ -0,0 +1,32 @@
+package csv2_test
+
+import (
+	"maps"
+	"testing"
+
+	"github.com/turfaa/vmedis-proxy-api/pkg2/csv2"
+)
+
+func TestNormalizeHeader(t *testing.T) {
+	for _, name := range []string{"kode_obat", "Kode Obat", " KODE-OBAT ", "\ufeffkode obat"} {
+		if got := csv2.NormalizeHeader(name); got != "kode_obat" {
+			t.Errorf("NormalizeHeader(%q) = %q, want kode_obat", name, got)
+		}
+	}
+}
+
+func TestFieldIndex(t *testing.T) {
+	got := csv2.FieldIndex(
+		[]string{"\ufeffEAN", "Kode Obat", "Vmedis Code", "Harga"},
+		map[string][]string{
+			"barcode":   {"barcode", "ean"},
+			"drug_code": {"vmedis_code", "kode_obat"},
+			"unit":      {"unit", "satuan"},
+		},
+	)
+
+	want := map[string]int{"barcode": 0, "drug_code": 2}
+	if !maps.Equal(got, want) {
+		t.Errorf("FieldIndex() = %v, want %v", got, want)
+	}
+}
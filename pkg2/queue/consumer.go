@@ -0,0 +1,177 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/turfaa/vmedis-proxy-api/pkg2/retry"
+)
+
+var (
+	// DefaultDeadLetterRetryConfig retries sending a message to the dead-letter topic until the context is done.
+	DefaultDeadLetterRetryConfig = retry.Config{
+		MaxRetries:     math.MaxInt,
+		InitialBackoff: time.Second,
+		MaxBackoff:     time.Minute,
+	}
+
+	// DefaultFetchRetryConfig retries a failing fetch, e.g. while the queue is unreachable, until the context is done.
+	DefaultFetchRetryConfig = retry.Config{
+		MaxRetries:     math.MaxInt,
+		InitialBackoff: time.Second,
+		MaxBackoff:     30 * time.Second,
+	}
+)
+
+// ConsumerConfig configures a Consumer.
+type ConsumerConfig struct {
+	// Group is the consumer group the topics are consumed with.
+	Group string
+
+	// DeadLetterTopic receives the messages that still fail after the retries, as made by NewDeadLetter.
+	DeadLetterTopic string
+
+	// Concurrency is the number of messages of a topic handled at the same time, 1 if it's not positive.
+	Concurrency int
+
+	// RetryConfig retries a failing message before it's sent to DeadLetterTopic.
+	RetryConfig retry.Config
+
+	// DeadLetterRetryConfig retries sending a message to DeadLetterTopic, DefaultDeadLetterRetryConfig if it's empty.
+	DeadLetterRetryConfig retry.Config
+
+	// FetchRetryConfig retries a failing fetch, DefaultFetchRetryConfig if it's empty.
+	FetchRetryConfig retry.Config
+}
+
+// Consumer consumes topics with a consumer group, retrying the failing messages with backoff
+// and sending the ones that still fail to a dead-letter topic, so they're neither dropped nor blocking the others.
+type Consumer struct {
+	queue  Queue
+	config ConsumerConfig
+}
+
+// Consume handles the messages of the topic concurrently until the context is done or the subscription is closed,
+// then finishes the messages being handled before returning.
+// A message is committed only after it's handled or sent to the dead-letter topic,
+// so the messages not done before a crash are consumed again.
+func (c *Consumer) Consume(ctx context.Context, topic string, handle func(ctx context.Context, m Message) error) error {
+	subscription, err := c.queue.Subscribe(ctx, topic, c.config.Group)
+	if err != nil {
+		return fmt.Errorf("subscribe to %s: %w", topic, err)
+	}
+	defer func() {
+		if err := subscription.Close(); err != nil {
+			log.Printf("Failed to close %s subscription: %s", topic, err)
+		}
+	}()
+
+	// The messages being handled are finished even after the context is done.
+	handleCtx := context.WithoutCancel(ctx)
+
+	messageChan := make(chan Message, c.config.Concurrency)
+
+	var wg sync.WaitGroup
+	for i := 0; i < c.config.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for m := range messageChan {
+				if !c.handleWithRetry(ctx, m, handle) {
+					// Left uncommitted, so it's consumed again after a restart.
+					continue
+				}
+
+				if err := subscription.Commit(handleCtx, m); err != nil {
+					log.Printf("Failed to commit %s message %s: %s", topic, m.ID, err)
+				}
+			}
+		}()
+	}
+
+	defer func() {
+		close(messageChan)
+		wg.Wait()
+		log.Printf("Finished the in-flight %s messages", topic)
+	}()
+
+	for {
+		// A failing fetch is retried with backoff,
+		// so the consumer only stops when the context is done or the subscription is closed.
+		m, err := retry.Do(ctx, c.config.FetchRetryConfig, func(ctx context.Context) (Message, error) {
+			m, err := subscription.Fetch(ctx)
+			if err != nil && (ctx.Err() != nil || errors.Is(err, ErrClosed)) {
+				return Message{}, retry.Permanent(err)
+			}
+
+			if err != nil {
+				return Message{}, fmt.Errorf("fetch %s message: %w", topic, err)
+			}
+
+			return m, nil
+		})
+		if err != nil {
+			// Stopped by the context or the closed subscription.
+			return nil
+		}
+
+		messageChan <- m
+	}
+}
+
+// handleWithRetry retries the message with backoff, and sends it to the dead-letter topic if it still fails.
+// It returns whether the message is done, either handled or sent to the dead-letter topic.
+//
+// The message is handled even after the context is done. Sending it to the dead-letter topic is retried
+// until it succeeds, because the messages after it can't be committed before it, or until the context is done,
+// leaving it uncommitted to be consumed again after a restart.
+func (c *Consumer) handleWithRetry(ctx context.Context, m Message, handle func(ctx context.Context, m Message) error) bool {
+	handleCtx := context.WithoutCancel(ctx)
+
+	attempts := 0
+	_, handleErr := retry.Do(handleCtx, c.config.RetryConfig, func(ctx context.Context) (struct{}, error) {
+		attempts++
+		return struct{}{}, handle(ctx, m)
+	})
+	if handleErr == nil {
+		return true
+	}
+
+	log.Printf("Failed to handle %s message %s after %d attempt(s), sending it to %s: %s", m.Topic, m.ID, attempts, c.config.DeadLetterTopic, handleErr)
+
+	deadLetter := NewDeadLetter(c.config.DeadLetterTopic, m, handleErr, attempts, time.Now())
+	if _, err := retry.Do(ctx, c.config.DeadLetterRetryConfig, func(context.Context) (struct{}, error) {
+		return struct{}{}, c.queue.Publish(handleCtx, deadLetter)
+	}); err != nil {
+		log.Printf("Failed to send %s message %s to %s, leaving it uncommitted: %s", m.Topic, m.ID, c.config.DeadLetterTopic, err)
+		return false
+	}
+
+	return true
+}
+
+// NewConsumer creates a new Consumer of the queue.
+func NewConsumer(q Queue, config ConsumerConfig) *Consumer {
+	if config.Concurrency <= 0 {
+		config.Concurrency = 1
+	}
+
+	if config.DeadLetterRetryConfig == (retry.Config{}) {
+		config.DeadLetterRetryConfig = DefaultDeadLetterRetryConfig
+	}
+
+	if config.FetchRetryConfig == (retry.Config{}) {
+		config.FetchRetryConfig = DefaultFetchRetryConfig
+	}
+
+	return &Consumer{
+		queue:  q,
+		config: config,
+	}
+}
@@ -0,0 +1,252 @@
+package queue_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/turfaa/vmedis-proxy-api/pkg2/queue"
+	"github.com/turfaa/vmedis-proxy-api/pkg2/retry"
+)
+
+const (
+	consumerTestGroup           = "group"
+	consumerTestDeadLetterTopic = "topic.dead_letter"
+)
+
+// TestConsumerRetriesAndDeadLetters checks that a failing message is retried until it's handled,
+// and that a message still failing after the retries, or failing permanently, is sent to the dead-letter topic,
+// with every message committed in the end.
+func TestConsumerRetriesAndDeadLetters(t *testing.T) {
+	ctx := context.Background()
+	q := queue.NewMemory()
+	mustPublish(t, q, "topic", "flaky", "broken", "invalid")
+
+	var lock sync.Mutex
+	attempts := make(map[string]int)
+	handle := func(_ context.Context, m queue.Message) error {
+		lock.Lock()
+		defer lock.Unlock()
+
+		value := string(m.Value)
+		attempts[value]++
+
+		switch {
+		case value == "flaky" && attempts[value] == 1:
+			return errors.New("vmedis is down")
+		case value == "broken":
+			return errors.New("vmedis is still down")
+		case value == "invalid":
+			return retry.Permanent(errors.New("invalid payload"))
+		}
+
+		return nil
+	}
+
+	if err := runConsumer(t, newTestConsumer(q), handle, func() bool {
+		pending, err := q.Pending(ctx, "topic", consumerTestGroup)
+		return err == nil && len(pending) == 0
+	}); err != nil {
+		t.Fatalf("Consume() error = %v", err)
+	}
+
+	if attempts["flaky"] != 2 || attempts["broken"] != 3 || attempts["invalid"] != 1 {
+		t.Errorf("attempts = %v, want flaky 2, broken 3, and invalid 1", attempts)
+	}
+
+	deadLetters, err := q.Pending(ctx, consumerTestDeadLetterTopic, "replayer")
+	if err != nil {
+		t.Fatalf("get dead letters: %s", err)
+	}
+	if len(deadLetters) != 2 {
+		t.Fatalf("dead letters = %+v, want broken and invalid", deadLetters)
+	}
+
+	broken, invalid := deadLetters[0], deadLetters[1]
+	if string(broken.Value) != "broken" || broken.Headers[queue.DeadLetterOriginalTopicHeader] != "topic" ||
+		broken.Headers[queue.DeadLetterAttemptsHeader] != "3" || broken.Headers[queue.DeadLetterFailedAtHeader] == "" {
+		t.Errorf("broken dead letter = %+v", broken)
+	}
+	if string(invalid.Value) != "invalid" || invalid.Headers[queue.DeadLetterAttemptsHeader] != "1" ||
+		invalid.Headers[queue.DeadLetterErrorHeader] != "after 1 attempt(s): invalid payload" {
+		t.Errorf("invalid dead letter = %+v", invalid)
+	}
+}
+
+// TestConsumerRetriesDeadLetters checks that a failing message is committed only after it's sent to the dead-letter topic,
+// retrying to send it until it succeeds, or leaving it uncommitted if the consumer stops before.
+func TestConsumerRetriesDeadLetters(t *testing.T) {
+	ctx := context.Background()
+	handle := func(context.Context, queue.Message) error {
+		return retry.Permanent(errors.New("invalid payload"))
+	}
+
+	q := &failingDeadLetterQueue{Memory: queue.NewMemory(), failures: 3}
+	mustPublish(t, q, "topic", "invalid")
+
+	if err := runConsumer(t, newTestConsumer(q), handle, func() bool {
+		pending, err := q.Pending(ctx, "topic", consumerTestGroup)
+		return err == nil && len(pending) == 0
+	}); err != nil {
+		t.Fatalf("Consume() error = %v", err)
+	}
+
+	if q.attempts() != 4 {
+		t.Errorf("dead letter publish attempts = %d, want 4", q.attempts())
+	}
+	if deadLetters, err := q.Pending(ctx, consumerTestDeadLetterTopic, "replayer"); err != nil || len(deadLetters) != 1 {
+		t.Errorf("dead letters = %+v, %v, want the invalid message", deadLetters, err)
+	}
+
+	q = &failingDeadLetterQueue{Memory: queue.NewMemory(), failures: -1}
+	mustPublish(t, q, "topic", "invalid")
+
+	if err := runConsumer(t, newTestConsumer(q), handle, func() bool {
+		return q.attempts() >= 3
+	}); err != nil {
+		t.Fatalf("Consume() error = %v", err)
+	}
+
+	if pending, err := q.Pending(ctx, "topic", consumerTestGroup); err != nil || len(pending) != 1 {
+		t.Errorf("pending messages after stopping = %+v, %v, want the invalid message left uncommitted", pending, err)
+	}
+}
+
+// TestConsumerKeepsFetching checks that the consumer keeps fetching after the fetches fail,
+// and only stops when the context is done.
+func TestConsumerKeepsFetching(t *testing.T) {
+	ctx := context.Background()
+
+	q := &flakyQueue{Memory: queue.NewMemory(), failures: 3}
+	mustPublish(t, q, "topic", "a")
+
+	var handled atomic.Int32
+	handle := func(context.Context, queue.Message) error {
+		handled.Add(1)
+		return nil
+	}
+
+	if err := runConsumer(t, newTestConsumer(q), handle, func() bool {
+		pending, err := q.Pending(ctx, "topic", consumerTestGroup)
+		return err == nil && len(pending) == 0
+	}); err != nil {
+		t.Fatalf("Consume() error = %v", err)
+	}
+
+	if handled.Load() != 1 {
+		t.Errorf("handled %d messages, want 1", handled.Load())
+	}
+}
+
+// flakyQueue is a memory queue whose subscriptions fail to fetch the given number of times first.
+type flakyQueue struct {
+	*queue.Memory
+	failures int
+}
+
+func (q *flakyQueue) Subscribe(ctx context.Context, topic string, group string) (queue.Subscription, error) {
+	subscription, err := q.Memory.Subscribe(ctx, topic, group)
+	if err != nil {
+		return nil, err
+	}
+
+	return &flakySubscription{Subscription: subscription, failures: q.failures}, nil
+}
+
+type flakySubscription struct {
+	queue.Subscription
+	failures int
+}
+
+func (s *flakySubscription) Fetch(ctx context.Context) (queue.Message, error) {
+	if s.failures > 0 {
+		s.failures--
+		return queue.Message{}, errors.New("queue is unreachable")
+	}
+
+	return s.Subscription.Fetch(ctx)
+}
+
+// failingDeadLetterQueue is a memory queue failing to publish to the dead-letter topic the given number of times,
+// or forever if it's negative.
+type failingDeadLetterQueue struct {
+	*queue.Memory
+	failures int
+
+	lock  sync.Mutex
+	calls int
+}
+
+func (q *failingDeadLetterQueue) Publish(ctx context.Context, messages ...queue.Message) error {
+	if len(messages) == 0 || messages[0].Topic != consumerTestDeadLetterTopic {
+		return q.Memory.Publish(ctx, messages...)
+	}
+
+	q.lock.Lock()
+	q.calls++
+	fail := q.failures < 0 || q.calls <= q.failures
+	q.lock.Unlock()
+
+	if fail {
+		return errors.New("dead letter topic is down")
+	}
+
+	return q.Memory.Publish(ctx, messages...)
+}
+
+func (q *failingDeadLetterQueue) attempts() int {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	return q.calls
+}
+
+func newTestConsumer(q queue.Queue) *queue.Consumer {
+	return queue.NewConsumer(q, queue.ConsumerConfig{
+		Group:           consumerTestGroup,
+		DeadLetterTopic: consumerTestDeadLetterTopic,
+		RetryConfig: retry.Config{
+			MaxRetries:     2,
+			InitialBackoff: time.Millisecond,
+		},
+		DeadLetterRetryConfig: retry.Config{
+			MaxRetries:     math.MaxInt,
+			InitialBackoff: time.Millisecond,
+			MaxBackoff:     10 * time.Millisecond,
+		},
+		FetchRetryConfig: retry.Config{
+			MaxRetries:     math.MaxInt,
+			InitialBackoff: time.Millisecond,
+			MaxBackoff:     10 * time.Millisecond,
+		},
+	})
+}
+
+// runConsumer consumes the topic until done returns true, then stops the consumer and returns its error.
+func runConsumer(t *testing.T, consumer *queue.Consumer, handle func(ctx context.Context, m queue.Message) error, done func() bool) error {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- consumer.Consume(ctx, "topic", handle)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !done() {
+		if time.Now().After(deadline) {
+			t.Fatalf("consumer didn't finish in time")
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	cancel()
+	return <-errChan
+}
@@ -0,0 +1,31 @@
+package queue
+
+import (
+	"strconv"
+	"time"
+)
+
+// The headers of a dead letter, describing the message that still failed after the consumer retried it.
+const (
+	DeadLetterOriginalTopicHeader = "original_topic"
+	DeadLetterErrorHeader         = "error"
+	DeadLetterAttemptsHeader      = "attempts"
+	DeadLetterFailedAtHeader      = "failed_at"
+)
+
+// NewDeadLetter returns the dead letter of the message that still fails after the given number of attempts,
+// published to the dead-letter topic. It keeps the key and value of the message,
+// with its original topic, the error, and the attempts in the headers.
+func NewDeadLetter(deadLetterTopic string, message Message, cause error, attempts int, failedAt time.Time) Message {
+	return Message{
+		Topic: deadLetterTopic,
+		Key:   message.Key,
+		Value: message.Value,
+		Headers: map[string]string{
+			DeadLetterOriginalTopicHeader: message.Topic,
+			DeadLetterErrorHeader:         cause.Error(),
+			DeadLetterAttemptsHeader:      strconv.Itoa(attempts),
+			DeadLetterFailedAtHeader:      failedAt.Format(time.RFC3339),
+		},
+	}
+}
@@ -0,0 +1,377 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+const kafkaFetchMaxBytes = 1 << 20
+
+// Kafka is a Queue backed by Kafka.
+type Kafka struct {
+	brokers []string
+	writer  *kafka.Writer
+}
+
+func (q *Kafka) Publish(ctx context.Context, messages ...Message) error {
+	kafkaMessages := make([]kafka.Message, 0, len(messages))
+	for _, message := range messages {
+		kafkaMessages = append(kafkaMessages, kafka.Message{
+			Topic:   message.Topic,
+			Key:     message.Key,
+			Value:   message.Value,
+			Headers: kafkaHeaders(message.Headers),
+		})
+	}
+
+	if err := q.writer.WriteMessages(ctx, kafkaMessages...); err != nil {
+		return fmt.Errorf("write kafka messages: %w", err)
+	}
+
+	return nil
+}
+
+func (q *Kafka) Subscribe(_ context.Context, topic string, group string) (Subscription, error) {
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers: q.brokers,
+		Topic:   topic,
+		GroupID: group,
+	})
+
+	return &kafkaSubscription{
+		reader:    reader,
+		committer: newOrderedCommitter(reader),
+	}, nil
+}
+
+// Pending returns the messages after the offsets committed by the group, from the oldest in each partition.
+func (q *Kafka) Pending(ctx context.Context, topic string, group string) ([]Message, error) {
+	client := q.client()
+
+	partitions, err := q.getPartitions(ctx, client, topic)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(partitions) == 0 {
+		return []Message{}, nil
+	}
+
+	committed, err := client.OffsetFetch(ctx, &kafka.OffsetFetchRequest{
+		GroupID: group,
+		Topics:  map[string][]int{topic: partitions},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get committed offsets of %s: %w", topic, err)
+	}
+
+	offsetRequests := make([]kafka.OffsetRequest, 0, 2*len(partitions))
+	for _, partition := range partitions {
+		offsetRequests = append(offsetRequests, kafka.FirstOffsetOf(partition), kafka.LastOffsetOf(partition))
+	}
+
+	offsets, err := client.ListOffsets(ctx, &kafka.ListOffsetsRequest{
+		Topics: map[string][]kafka.OffsetRequest{topic: offsetRequests},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list offsets of %s: %w", topic, err)
+	}
+
+	startOffsets := make(map[int]int64, len(partitions))
+	endOffsets := make(map[int]int64, len(partitions))
+	for _, partition := range offsets.Topics[topic] {
+		if partition.Error != nil {
+			return nil, fmt.Errorf("list offsets of %s partition %d: %w", topic, partition.Partition, partition.Error)
+		}
+
+		startOffsets[partition.Partition] = partition.FirstOffset
+		endOffsets[partition.Partition] = partition.LastOffset
+	}
+
+	for _, partition := range committed.Topics[topic] {
+		// The committed offset is negative if the group has committed nothing.
+		if partition.CommittedOffset > startOffsets[partition.Partition] {
+			startOffsets[partition.Partition] = partition.CommittedOffset
+		}
+	}
+
+	messages := []Message{}
+	for _, partition := range partitions {
+		partitionMessages, err := q.fetch(ctx, client, topic, partition, startOffsets[partition], endOffsets[partition])
+		if err != nil {
+			return nil, err
+		}
+
+		messages = append(messages, partitionMessages...)
+	}
+
+	return messages, nil
+}
+
+// CommitPending commits the offset after the last of the messages in each partition for the group.
+func (q *Kafka) CommitPending(ctx context.Context, topic string, group string, messages []Message) error {
+	nextOffsets := make(map[int]int64)
+	for _, message := range messages {
+		partition, offset, err := parseKafkaID(message.ID)
+		if err != nil {
+			return err
+		}
+
+		if offset+1 > nextOffsets[partition] {
+			nextOffsets[partition] = offset + 1
+		}
+	}
+
+	if len(nextOffsets) == 0 {
+		return nil
+	}
+
+	commits := make([]kafka.OffsetCommit, 0, len(nextOffsets))
+	for partition, offset := range nextOffsets {
+		commits = append(commits, kafka.OffsetCommit{Partition: partition, Offset: offset})
+	}
+
+	// The committer isn't a group member, so the offsets are committed outside of any generation.
+	res, err := q.client().OffsetCommit(ctx, &kafka.OffsetCommitRequest{
+		GroupID:      group,
+		GenerationID: -1,
+		Topics:       map[string][]kafka.OffsetCommit{topic: commits},
+	})
+	if err != nil {
+		return fmt.Errorf("commit offsets of %s: %w", topic, err)
+	}
+
+	for _, partition := range res.Topics[topic] {
+		if partition.Error != nil {
+			return fmt.Errorf("commit offsets of %s partition %d: %w", topic, partition.Partition, partition.Error)
+		}
+	}
+
+	return nil
+}
+
+func (q *Kafka) Close() error {
+	return q.writer.Close()
+}
+
+func (q *Kafka) getPartitions(ctx context.Context, client *kafka.Client, topic string) ([]int, error) {
+	metadata, err := client.Metadata(ctx, &kafka.MetadataRequest{Topics: []string{topic}})
+	if err != nil {
+		return nil, fmt.Errorf("get %s metadata: %w", topic, err)
+	}
+
+	var partitions []int
+	for _, t := range metadata.Topics {
+		if errors.Is(t.Error, kafka.UnknownTopicOrPartition) {
+			// Nothing has ever been published.
+			return nil, nil
+		}
+		if t.Error != nil {
+			return nil, fmt.Errorf("get %s metadata: %w", topic, t.Error)
+		}
+
+		for _, partition := range t.Partitions {
+			partitions = append(partitions, partition.ID)
+		}
+	}
+
+	return partitions, nil
+}
+
+func (q *Kafka) fetch(ctx context.Context, client *kafka.Client, topic string, partition int, startOffset int64, endOffset int64) ([]Message, error) {
+	var messages []Message
+
+	offset := startOffset
+	for offset < endOffset {
+		res, err := client.Fetch(ctx, &kafka.FetchRequest{
+			Topic:     topic,
+			Partition: partition,
+			Offset:    offset,
+			MinBytes:  1,
+			MaxBytes:  kafkaFetchMaxBytes,
+			MaxWait:   time.Second,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("fetch %s partition %d from offset %d: %w", topic, partition, offset, err)
+		}
+		if res.Error != nil {
+			return nil, fmt.Errorf("fetch %s partition %d from offset %d: %w", topic, partition, offset, res.Error)
+		}
+
+		read := 0
+		for {
+			record, err := res.Records.ReadRecord()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("read %s partition %d record: %w", topic, partition, err)
+			}
+
+			// Kafka may return the records before the requested offset in the same batch.
+			if record.Offset < offset || record.Offset >= endOffset {
+				continue
+			}
+
+			message, err := messageFromKafkaRecord(topic, partition, record)
+			if err != nil {
+				return nil, err
+			}
+
+			messages = append(messages, message)
+			offset = record.Offset + 1
+			read++
+		}
+
+		if read == 0 {
+			// The remaining offsets are gone, e.g. by retention or compaction.
+			break
+		}
+	}
+
+	return messages, nil
+}
+
+func (q *Kafka) client() *kafka.Client {
+	return &kafka.Client{Addr: q.writer.Addr}
+}
+
+type kafkaSubscription struct {
+	reader    *kafka.Reader
+	committer *orderedCommitter
+}
+
+func (s *kafkaSubscription) Fetch(ctx context.Context) (Message, error) {
+	m, err := s.reader.FetchMessage(ctx)
+	if errors.Is(err, io.EOF) {
+		return Message{}, ErrClosed
+	}
+	if err != nil {
+		return Message{}, err
+	}
+
+	message := messageFromKafka(m)
+	message.position = s.committer.Track(m)
+	return message, nil
+}
+
+// Commit commits the message once it and every message fetched before it in its partition are committed,
+// since Kafka tracks a single offset per partition.
+func (s *kafkaSubscription) Commit(ctx context.Context, message Message) error {
+	tracked, ok := message.position.(*trackedMessage)
+	if !ok {
+		return fmt.Errorf("message %s wasn't fetched from kafka", message.ID)
+	}
+
+	return s.committer.Done(ctx, tracked)
+}
+
+func (s *kafkaSubscription) Close() error {
+	return s.reader.Close()
+}
+
+func messageFromKafka(m kafka.Message) Message {
+	var headers map[string]string
+	if len(m.Headers) > 0 {
+		headers = make(map[string]string, len(m.Headers))
+		for _, header := range m.Headers {
+			headers[header.Key] = string(header.Value)
+		}
+	}
+
+	return Message{
+		Topic:   m.Topic,
+		Key:     m.Key,
+		Value:   m.Value,
+		Headers: headers,
+		ID:      kafkaID(m.Partition, m.Offset),
+	}
+}
+
+func messageFromKafkaRecord(topic string, partition int, record *kafka.Record) (Message, error) {
+	message := Message{
+		Topic: topic,
+		ID:    kafkaID(partition, record.Offset),
+	}
+
+	if record.Key != nil {
+		key, err := io.ReadAll(record.Key)
+		if err != nil {
+			return Message{}, fmt.Errorf("read key of %s message %s: %w", topic, message.ID, err)
+		}
+		message.Key = key
+	}
+
+	if record.Value != nil {
+		value, err := io.ReadAll(record.Value)
+		if err != nil {
+			return Message{}, fmt.Errorf("read value of %s message %s: %w", topic, message.ID, err)
+		}
+		message.Value = value
+	}
+
+	if len(record.Headers) > 0 {
+		message.Headers = make(map[string]string, len(record.Headers))
+		for _, header := range record.Headers {
+			message.Headers[header.Key] = string(header.Value)
+		}
+	}
+
+	return message, nil
+}
+
+func kafkaHeaders(headers map[string]string) []kafka.Header {
+	if len(headers) == 0 {
+		return nil
+	}
+
+	kafkaHeaders := make([]kafka.Header, 0, len(headers))
+	for key, value := range headers {
+		kafkaHeaders = append(kafkaHeaders, kafka.Header{Key: key, Value: []byte(value)})
+	}
+
+	return kafkaHeaders
+}
+
+func kafkaID(partition int, offset int64) string {
+	return fmt.Sprintf("%d-%d", partition, offset)
+}
+
+func parseKafkaID(id string) (int, int64, error) {
+	partitionStr, offsetStr, ok := strings.Cut(id, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid kafka message id %q", id)
+	}
+
+	partition, err := strconv.Atoi(partitionStr)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid partition of kafka message id %q: %w", id, err)
+	}
+
+	offset, err := strconv.ParseInt(offsetStr, 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid offset of kafka message id %q: %w", id, err)
+	}
+
+	return partition, offset, nil
+}
+
+// NewKafka creates a new Kafka queue on the brokers.
+func NewKafka(brokers []string) *Kafka {
+	return &Kafka{
+		brokers: brokers,
+		writer: &kafka.Writer{
+			Addr:         kafka.TCP(brokers...),
+			Balancer:     &kafka.LeastBytes{},
+			RequiredAcks: kafka.RequireOne,
+			Compression:  kafka.Snappy,
+		},
+	}
+}
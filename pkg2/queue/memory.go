@@ -0,0 +1,339 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+// memoryTopicCapacity is the number of messages kept in a topic, after which the oldest are dropped.
+const memoryTopicCapacity = 10_000
+
+// Memory is a Queue kept in the memory of the process, so the messages are only consumed
+// by the subscriptions in the same process and are lost when it exits.
+type Memory struct {
+	lock   sync.Mutex
+	topics map[string]*memoryTopic
+}
+
+// memoryTopic is the log of a topic. The offset of messages[i] is firstOffset+i.
+type memoryTopic struct {
+	messages    []Message
+	firstOffset int64
+	groups      map[string]*memoryGroup
+
+	// published is closed and replaced when messages are published, to wake up the waiting subscriptions.
+	published chan struct{}
+}
+
+type memoryGroup struct {
+	// next is the offset of the next message to deliver.
+	next int64
+
+	// redeliver has the offsets fetched by closed subscriptions without being committed, delivered before next.
+	redeliver []int64
+
+	// inFlight has the offsets fetched but not committed yet, with the subscription that fetched them.
+	inFlight map[int64]*memorySubscription
+}
+
+func (q *Memory) Publish(_ context.Context, messages ...Message) error {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	for _, message := range messages {
+		topic := q.getTopic(message.Topic)
+
+		message.Key = slices.Clone(message.Key)
+		message.Value = slices.Clone(message.Value)
+		message.ID = strconv.FormatInt(topic.firstOffset+int64(len(topic.messages)), 10)
+		message.position = nil
+
+		topic.messages = append(topic.messages, message)
+
+		if len(topic.messages) > memoryTopicCapacity {
+			topic.trim(topic.firstOffset + int64(len(topic.messages)) - memoryTopicCapacity)
+		}
+
+		close(topic.published)
+		topic.published = make(chan struct{})
+	}
+
+	return nil
+}
+
+func (q *Memory) Subscribe(_ context.Context, topic string, group string) (Subscription, error) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	q.getTopic(topic).getGroup(group)
+
+	return &memorySubscription{
+		queue: q,
+		topic: topic,
+		group: group,
+		done:  make(chan struct{}),
+	}, nil
+}
+
+// Pending returns the messages not committed by the group, including the ones being handled.
+func (q *Memory) Pending(_ context.Context, topic string, group string) ([]Message, error) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	t, ok := q.topics[topic]
+	if !ok {
+		return []Message{}, nil
+	}
+
+	var offsets []int64
+	next := t.firstOffset
+	if g, ok := t.groups[group]; ok {
+		offsets = append(offsets, g.redeliver...)
+		for offset := range g.inFlight {
+			offsets = append(offsets, offset)
+		}
+		next = max(next, g.next)
+	}
+
+	for offset := next; offset < t.firstOffset+int64(len(t.messages)); offset++ {
+		offsets = append(offsets, offset)
+	}
+
+	slices.Sort(offsets)
+
+	messages := make([]Message, 0, len(offsets))
+	for _, offset := range offsets {
+		if message, ok := t.get(offset); ok {
+			messages = append(messages, message)
+		}
+	}
+
+	return messages, nil
+}
+
+// CommitPending commits the messages for the group, and moves the group past the last of them.
+func (q *Memory) CommitPending(_ context.Context, topic string, group string, messages []Message) error {
+	offsets := make([]int64, 0, len(messages))
+	for _, message := range messages {
+		offset, err := strconv.ParseInt(message.ID, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid memory message id %q: %w", message.ID, err)
+		}
+
+		offsets = append(offsets, offset)
+	}
+
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	t := q.getTopic(topic)
+	g := t.getGroup(group)
+
+	for _, offset := range offsets {
+		g.commit(offset)
+		g.next = max(g.next, offset+1)
+	}
+
+	t.trimConsumed()
+	return nil
+}
+
+func (q *Memory) Close() error {
+	return nil
+}
+
+// getTopic returns the topic, creating it if needed. It must be called with the lock held.
+func (q *Memory) getTopic(name string) *memoryTopic {
+	topic, ok := q.topics[name]
+	if !ok {
+		topic = &memoryTopic{
+			groups:    make(map[string]*memoryGroup),
+			published: make(chan struct{}),
+		}
+		q.topics[name] = topic
+	}
+
+	return topic
+}
+
+// getGroup returns the group, creating it from the oldest kept message if needed.
+func (t *memoryTopic) getGroup(name string) *memoryGroup {
+	group, ok := t.groups[name]
+	if !ok {
+		group = &memoryGroup{
+			next:     t.firstOffset,
+			inFlight: make(map[int64]*memorySubscription),
+		}
+		t.groups[name] = group
+	}
+
+	return group
+}
+
+func (t *memoryTopic) get(offset int64) (Message, bool) {
+	if offset < t.firstOffset || offset >= t.firstOffset+int64(len(t.messages)) {
+		return Message{}, false
+	}
+
+	return t.messages[offset-t.firstOffset], true
+}
+
+// trim drops the messages before the offset.
+func (t *memoryTopic) trim(offset int64) {
+	if offset <= t.firstOffset {
+		return
+	}
+
+	dropped := min(offset-t.firstOffset, int64(len(t.messages)))
+	t.messages = slices.Clone(t.messages[dropped:])
+	t.firstOffset += dropped
+}
+
+// trimConsumed drops the messages committed by every group.
+func (t *memoryTopic) trimConsumed() {
+	if len(t.groups) == 0 {
+		return
+	}
+
+	consumed := t.firstOffset + int64(len(t.messages))
+	for _, group := range t.groups {
+		consumed = min(consumed, group.firstUncommitted())
+	}
+
+	t.trim(consumed)
+}
+
+// deliver returns the next message for the subscription, if any.
+func (g *memoryGroup) deliver(t *memoryTopic, s *memorySubscription) (Message, bool) {
+	for len(g.redeliver) > 0 {
+		offset := g.redeliver[0]
+		g.redeliver = g.redeliver[1:]
+
+		if message, ok := t.get(offset); ok {
+			g.inFlight[offset] = s
+			return message, true
+		}
+	}
+
+	g.next = max(g.next, t.firstOffset)
+
+	message, ok := t.get(g.next)
+	if !ok {
+		return Message{}, false
+	}
+
+	g.inFlight[g.next] = s
+	g.next++
+	return message, true
+}
+
+func (g *memoryGroup) commit(offset int64) {
+	delete(g.inFlight, offset)
+	g.redeliver = slices.DeleteFunc(g.redeliver, func(o int64) bool { return o == offset })
+}
+
+func (g *memoryGroup) firstUncommitted() int64 {
+	first := g.next
+	for _, offset := range g.redeliver {
+		first = min(first, offset)
+	}
+	for offset := range g.inFlight {
+		first = min(first, offset)
+	}
+
+	return first
+}
+
+type memorySubscription struct {
+	queue *Memory
+	topic string
+	group string
+
+	closed bool
+	done   chan struct{}
+}
+
+func (s *memorySubscription) Fetch(ctx context.Context) (Message, error) {
+	for {
+		s.queue.lock.Lock()
+
+		if s.closed {
+			s.queue.lock.Unlock()
+			return Message{}, ErrClosed
+		}
+
+		t := s.queue.getTopic(s.topic)
+		message, ok := t.getGroup(s.group).deliver(t, s)
+		published := t.published
+
+		s.queue.lock.Unlock()
+
+		if ok {
+			return message, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return Message{}, ctx.Err()
+		case <-s.done:
+			return Message{}, ErrClosed
+		case <-published:
+		}
+	}
+}
+
+func (s *memorySubscription) Commit(_ context.Context, message Message) error {
+	offset, err := strconv.ParseInt(message.ID, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid memory message id %q: %w", message.ID, err)
+	}
+
+	s.queue.lock.Lock()
+	defer s.queue.lock.Unlock()
+
+	t := s.queue.getTopic(s.topic)
+	t.getGroup(s.group).commit(offset)
+	t.trimConsumed()
+	return nil
+}
+
+// Close stops fetching. The messages fetched but not committed are delivered again to the other subscriptions of the group.
+func (s *memorySubscription) Close() error {
+	s.queue.lock.Lock()
+	defer s.queue.lock.Unlock()
+
+	if s.closed {
+		return nil
+	}
+
+	s.closed = true
+	close(s.done)
+
+	t := s.queue.getTopic(s.topic)
+	g := t.getGroup(s.group)
+
+	for offset, owner := range g.inFlight {
+		if owner == s {
+			delete(g.inFlight, offset)
+			g.redeliver = append(g.redeliver, offset)
+		}
+	}
+	slices.Sort(g.redeliver)
+
+	if len(g.redeliver) > 0 {
+		close(t.published)
+		t.published = make(chan struct{})
+	}
+
+	return nil
+}
+
+// NewMemory creates a new in-process queue.
+func NewMemory() *Memory {
+	return &Memory{
+		topics: make(map[string]*memoryTopic),
+	}
+}
@@ -0,0 +1,137 @@
+package queue_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/turfaa/vmedis-proxy-api/pkg2/queue"
+)
+
+func TestMemory(t *testing.T) {
+	ctx := context.Background()
+	q := queue.NewMemory()
+
+	mustPublish(t, q, "topic", "a", "b", "c")
+
+	sub, err := q.Subscribe(ctx, "topic", "group")
+	if err != nil {
+		t.Fatalf("subscribe: %s", err)
+	}
+
+	a := mustFetch(t, sub, "a")
+	b := mustFetch(t, sub, "b")
+	mustFetch(t, sub, "c")
+
+	if err := sub.Commit(ctx, b); err != nil {
+		t.Fatalf("commit: %s", err)
+	}
+
+	// The other groups consume the topic on their own.
+	other, err := q.Subscribe(ctx, "topic", "other")
+	if err != nil {
+		t.Fatalf("subscribe: %s", err)
+	}
+	mustFetch(t, other, "a")
+
+	// The messages fetched but not committed are delivered again after the subscription is closed.
+	if err := sub.Close(); err != nil {
+		t.Fatalf("close: %s", err)
+	}
+	if _, err := sub.Fetch(ctx); !errors.Is(err, queue.ErrClosed) {
+		t.Fatalf("expected ErrClosed after close, got %v", err)
+	}
+
+	sub, err = q.Subscribe(ctx, "topic", "group")
+	if err != nil {
+		t.Fatalf("subscribe: %s", err)
+	}
+	mustFetch(t, sub, "a")
+	mustFetch(t, sub, "c")
+
+	if err := sub.Commit(ctx, a); err != nil {
+		t.Fatalf("commit: %s", err)
+	}
+
+	// Fetch waits for the next message to be published.
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		mustPublish(t, q, "topic", "d")
+	}()
+	mustFetch(t, sub, "d")
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err := sub.Fetch(timeoutCtx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the fetch to time out, got %v", err)
+	}
+}
+
+func TestMemoryPending(t *testing.T) {
+	ctx := context.Background()
+	q := queue.NewMemory()
+
+	pending, err := q.Pending(ctx, "topic", "group")
+	if err != nil {
+		t.Fatalf("pending: %s", err)
+	}
+	if len(pending) != 0 {
+		t.Fatalf("expected nothing pending in an empty topic, got %v", pending)
+	}
+
+	mustPublish(t, q, "topic", "a", "b")
+
+	pending, err = q.Pending(ctx, "topic", "group")
+	if err != nil {
+		t.Fatalf("pending: %s", err)
+	}
+	if len(pending) != 2 || string(pending[0].Value) != "a" || string(pending[1].Value) != "b" {
+		t.Fatalf("expected a and b pending, got %v", pending)
+	}
+
+	if err := q.CommitPending(ctx, "topic", "group", pending); err != nil {
+		t.Fatalf("commit pending: %s", err)
+	}
+
+	mustPublish(t, q, "topic", "c")
+
+	pending, err = q.Pending(ctx, "topic", "group")
+	if err != nil {
+		t.Fatalf("pending: %s", err)
+	}
+	if len(pending) != 1 || string(pending[0].Value) != "c" {
+		t.Fatalf("expected only c pending after the commit, got %v", pending)
+	}
+}
+
+func mustPublish(t *testing.T, q queue.Queue, topic string, values ...string) {
+	t.Helper()
+
+	messages := make([]queue.Message, 0, len(values))
+	for _, value := range values {
+		messages = append(messages, queue.Message{Topic: topic, Value: []byte(value)})
+	}
+
+	if err := q.Publish(context.Background(), messages...); err != nil {
+		t.Errorf("publish: %s", err)
+	}
+}
+
+func mustFetch(t *testing.T, sub queue.Subscription, expectedValue string) queue.Message {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	message, err := sub.Fetch(ctx)
+	if err != nil {
+		t.Fatalf("fetch: %s", err)
+	}
+
+	if string(message.Value) != expectedValue {
+		t.Fatalf("expected %s, got %s", expectedValue, message.Value)
+	}
+
+	return message
+}
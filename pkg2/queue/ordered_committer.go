@@ -0,0 +1,76 @@
+package queue
+
+import (
+	"context"
+	"sync"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// messageCommitter commits kafka messages, e.g. *kafka.Reader.
+type messageCommitter interface {
+	CommitMessages(ctx context.Context, messages ...kafka.Message) error
+}
+
+// orderedCommitter commits the messages of each partition in the order they were fetched,
+// so a message is only committed after it and every message fetched before it are handled,
+// even though they are handled concurrently.
+// A message never marked done holds back the commits of its partition,
+// so a consumer must not leave a message undone while it keeps consuming.
+type orderedCommitter struct {
+	committer messageCommitter
+
+	lock    sync.Mutex
+	pending map[int][]*trackedMessage
+}
+
+type trackedMessage struct {
+	message kafka.Message
+	done    bool
+}
+
+// Track registers the fetched message. It must be called in the fetched order.
+func (c *orderedCommitter) Track(message kafka.Message) *trackedMessage {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	tracked := &trackedMessage{message: message}
+	c.pending[message.Partition] = append(c.pending[message.Partition], tracked)
+	return tracked
+}
+
+// Done marks the message handled, and commits the last message of its partition
+// whose preceding messages are all handled.
+func (c *orderedCommitter) Done(ctx context.Context, tracked *trackedMessage) error {
+	// Committing under the lock keeps the commits of a partition from going backwards.
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	tracked.done = true
+
+	partition := tracked.message.Partition
+	pending := c.pending[partition]
+
+	handled := 0
+	for handled < len(pending) && pending[handled].done {
+		handled++
+	}
+
+	if handled == 0 {
+		return nil
+	}
+
+	if err := c.committer.CommitMessages(ctx, pending[handled-1].message); err != nil {
+		return err
+	}
+
+	c.pending[partition] = pending[handled:]
+	return nil
+}
+
+func newOrderedCommitter(committer messageCommitter) *orderedCommitter {
+	return &orderedCommitter{
+		committer: committer,
+		pending:   make(map[int][]*trackedMessage),
+	}
+}
@@ -0,0 +1,59 @@
+package queue
+
+import (
+	"context"
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+)
+
+type recordingCommitter struct {
+	committed []kafka.Message
+}
+
+func (r *recordingCommitter) CommitMessages(_ context.Context, messages ...kafka.Message) error {
+	r.committed = append(r.committed, messages...)
+	return nil
+}
+
+// TestOrderedCommitter checks that a message is committed only after every message fetched before it
+// in the same partition is handled, and that the partitions don't block each other.
+func TestOrderedCommitter(t *testing.T) {
+	ctx := context.Background()
+	recorder := &recordingCommitter{}
+	committer := newOrderedCommitter(recorder)
+
+	first := committer.Track(kafka.Message{Partition: 0, Offset: 10})
+	second := committer.Track(kafka.Message{Partition: 0, Offset: 11})
+	third := committer.Track(kafka.Message{Partition: 0, Offset: 12})
+	other := committer.Track(kafka.Message{Partition: 1, Offset: 5})
+
+	mustDone := func(tracked *trackedMessage) {
+		t.Helper()
+		if err := committer.Done(ctx, tracked); err != nil {
+			t.Fatalf("done: %s", err)
+		}
+	}
+
+	mustDone(second)
+	mustDone(third)
+	if len(recorder.committed) != 0 {
+		t.Fatalf("committed before the first message is handled: %v", recorder.committed)
+	}
+
+	mustDone(other)
+	if len(recorder.committed) != 1 || recorder.committed[0].Partition != 1 || recorder.committed[0].Offset != 5 {
+		t.Fatalf("expected partition 1 to be committed on its own, got %v", recorder.committed)
+	}
+
+	mustDone(first)
+	if len(recorder.committed) != 2 || recorder.committed[1].Partition != 0 || recorder.committed[1].Offset != 12 {
+		t.Fatalf("expected the last handled message of partition 0 to be committed, got %v", recorder.committed)
+	}
+
+	fourth := committer.Track(kafka.Message{Partition: 0, Offset: 13})
+	mustDone(fourth)
+	if len(recorder.committed) != 3 || recorder.committed[2].Offset != 13 {
+		t.Fatalf("expected the next message to be committed, got %v", recorder.committed)
+	}
+}
@@ -0,0 +1,83 @@
+// Package queue publishes and consumes messages through a pluggable backend:
+// Kafka, Redis Streams, or an in-process channel.
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Backend is the name of a queue backend.
+type Backend string
+
+const (
+	BackendKafka  Backend = "kafka"
+	BackendRedis  Backend = "redis"
+	BackendMemory Backend = "memory"
+)
+
+// ErrClosed is returned by Subscription.Fetch after the subscription is closed.
+var ErrClosed = errors.New("queue: subscription closed")
+
+// Message is a message published to or consumed from a topic.
+type Message struct {
+	Topic   string
+	Key     []byte
+	Value   []byte
+	Headers map[string]string
+
+	// ID identifies a consumed message within its topic,
+	// e.g. "<partition>-<offset>" in Kafka or the entry ID in Redis Streams.
+	ID string
+
+	// position is the backend-specific position of a consumed message, used to commit it.
+	position any
+}
+
+// Publisher publishes messages to topics.
+type Publisher interface {
+	// Publish publishes the messages to their topics.
+	Publish(ctx context.Context, messages ...Message) error
+}
+
+// Queue publishes messages to topics and consumes them with consumer groups.
+//
+// A message is delivered at least once to each group: the messages fetched but not committed
+// when a subscription stops are delivered again, so the handlers must be idempotent.
+type Queue interface {
+	Publisher
+
+	// Subscribe starts consuming the topic as a member of the group.
+	Subscribe(ctx context.Context, topic string, group string) (Subscription, error)
+
+	// Pending returns the messages of the topic not yet consumed by the group, without consuming them.
+	Pending(ctx context.Context, topic string, group string) ([]Message, error)
+
+	// CommitPending marks the messages returned by Pending as consumed by the group.
+	CommitPending(ctx context.Context, topic string, group string, messages []Message) error
+
+	Close() error
+}
+
+// Subscription consumes a topic as a member of a consumer group.
+type Subscription interface {
+	// Fetch blocks until the next message is available, the context is done, or the subscription is closed.
+	Fetch(ctx context.Context) (Message, error)
+
+	// Commit marks the fetched message as consumed by the group.
+	// The messages may be handled and committed concurrently and out of order.
+	Commit(ctx context.Context, message Message) error
+
+	Close() error
+}
+
+// ParseBackend parses the name of a queue backend.
+func ParseBackend(name string) (Backend, error) {
+	switch backend := Backend(name); backend {
+	case BackendKafka, BackendRedis, BackendMemory:
+		return backend, nil
+	default:
+		return "", fmt.Errorf("unknown queue backend %q, must be one of %s, %s, or %s", name, BackendKafka, BackendRedis, BackendMemory)
+	}
+}
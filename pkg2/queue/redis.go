@@ -0,0 +1,388 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	redisStreamKeyPrefix = "queue:"
+
+	// redisStreamMaxLength is the approximate number of entries kept in a stream.
+	redisStreamMaxLength = 100_000
+
+	redisFetchCount = 10
+	redisFetchBlock = 5 * time.Second
+
+	// redisClaimMinIdle is how long a message fetched by another consumer stays uncommitted
+	// before it's considered abandoned, e.g. by a crashed consumer, and fetched again.
+	redisClaimMinIdle = 5 * time.Minute
+	redisClaimEvery   = time.Minute
+
+	redisKeyField     = "key"
+	redisValueField   = "value"
+	redisHeadersField = "headers"
+)
+
+// Redis is a Queue backed by Redis Streams.
+// Each topic is a stream, and each group is a consumer group of the stream.
+type Redis struct {
+	client redis.UniversalClient
+}
+
+func (q *Redis) Publish(ctx context.Context, messages ...Message) error {
+	_, err := q.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, message := range messages {
+			values := map[string]any{
+				redisKeyField:   message.Key,
+				redisValueField: message.Value,
+			}
+
+			if len(message.Headers) > 0 {
+				headers, err := json.Marshal(message.Headers)
+				if err != nil {
+					return fmt.Errorf("marshal headers of %s message: %w", message.Topic, err)
+				}
+				values[redisHeadersField] = headers
+			}
+
+			pipe.XAdd(ctx, &redis.XAddArgs{
+				Stream: redisStreamKey(message.Topic),
+				MaxLen: redisStreamMaxLength,
+				Approx: true,
+				Values: values,
+			})
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("add redis stream entries: %w", err)
+	}
+
+	return nil
+}
+
+func (q *Redis) Subscribe(ctx context.Context, topic string, group string) (Subscription, error) {
+	if err := q.createGroup(ctx, topic, group); err != nil {
+		return nil, err
+	}
+
+	hostname, _ := os.Hostname()
+
+	return &redisSubscription{
+		client:   q.client,
+		topic:    topic,
+		stream:   redisStreamKey(topic),
+		group:    group,
+		consumer: fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+		done:     make(chan struct{}),
+	}, nil
+}
+
+// Pending returns the messages not yet delivered to the group.
+func (q *Redis) Pending(ctx context.Context, topic string, group string) ([]Message, error) {
+	stream := redisStreamKey(topic)
+
+	groups, err := q.client.XInfoGroups(ctx, stream).Result()
+	if isRedisNoSuchKey(err) {
+		// Nothing has ever been published.
+		return []Message{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get groups of %s: %w", stream, err)
+	}
+
+	start := "-"
+	for _, g := range groups {
+		if g.Name == group {
+			start = "(" + g.LastDeliveredID
+		}
+	}
+
+	entries, err := q.client.XRange(ctx, stream, start, "+").Result()
+	if err != nil {
+		return nil, fmt.Errorf("get %s entries from %s: %w", stream, start, err)
+	}
+
+	messages := make([]Message, 0, len(entries))
+	for _, entry := range entries {
+		messages = append(messages, messageFromRedis(topic, entry))
+	}
+
+	return messages, nil
+}
+
+// CommitPending moves the last delivered entry of the group to the last of the messages.
+func (q *Redis) CommitPending(ctx context.Context, topic string, group string, messages []Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	lastID := messages[0].ID
+	for _, message := range messages[1:] {
+		if compareRedisIDs(message.ID, lastID) > 0 {
+			lastID = message.ID
+		}
+	}
+
+	if err := q.createGroup(ctx, topic, group); err != nil {
+		return err
+	}
+
+	stream := redisStreamKey(topic)
+	if err := q.client.XGroupSetID(ctx, stream, group, lastID).Err(); err != nil {
+		return fmt.Errorf("set last delivered entry of %s group %s to %s: %w", stream, group, lastID, err)
+	}
+
+	return nil
+}
+
+func (q *Redis) Close() error {
+	// The redis client is shared, so it's closed by its owner.
+	return nil
+}
+
+// createGroup creates the group from the start of the stream if it doesn't exist yet.
+func (q *Redis) createGroup(ctx context.Context, topic string, group string) error {
+	stream := redisStreamKey(topic)
+
+	err := q.client.XGroupCreateMkStream(ctx, stream, group, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return fmt.Errorf("create %s group %s: %w", stream, group, err)
+	}
+
+	return nil
+}
+
+type redisSubscription struct {
+	client   redis.UniversalClient
+	topic    string
+	stream   string
+	group    string
+	consumer string
+
+	lock           sync.Mutex
+	buffer         []Message
+	ownPendingRead bool
+	claimStart     string
+	lastClaimAt    time.Time
+
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+func (s *redisSubscription) Fetch(ctx context.Context) (Message, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for len(s.buffer) == 0 {
+		select {
+		case <-s.done:
+			return Message{}, ErrClosed
+		case <-ctx.Done():
+			return Message{}, ctx.Err()
+		default:
+		}
+
+		if err := s.fill(ctx); err != nil {
+			select {
+			case <-s.done:
+				return Message{}, ErrClosed
+			default:
+			}
+
+			return Message{}, err
+		}
+	}
+
+	message := s.buffer[0]
+	s.buffer = s.buffer[1:]
+	return message, nil
+}
+
+// fill buffers the next messages: first the ones fetched by this consumer before a restart,
+// then the ones abandoned by other consumers, then the new ones.
+func (s *redisSubscription) fill(ctx context.Context) error {
+	if !s.ownPendingRead {
+		// A negative block doesn't block, while zero blocks forever.
+		entries, err := s.readGroup(ctx, "0", -1)
+		if err != nil {
+			return err
+		}
+
+		if len(entries) > 0 {
+			s.bufferEntries(ctx, entries)
+			return nil
+		}
+
+		s.ownPendingRead = true
+	}
+
+	if time.Since(s.lastClaimAt) >= redisClaimEvery {
+		start := s.claimStart
+		if start == "" {
+			start = "0-0"
+		}
+
+		entries, next, err := s.client.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+			Stream:   s.stream,
+			Group:    s.group,
+			Consumer: s.consumer,
+			MinIdle:  redisClaimMinIdle,
+			Start:    start,
+			Count:    redisFetchCount,
+		}).Result()
+		if err != nil {
+			return fmt.Errorf("claim abandoned %s entries: %w", s.stream, err)
+		}
+
+		if next == "0-0" {
+			s.claimStart = ""
+			s.lastClaimAt = time.Now()
+		} else {
+			s.claimStart = next
+		}
+
+		if len(entries) > 0 {
+			s.bufferEntries(ctx, entries)
+			return nil
+		}
+	}
+
+	entries, err := s.readGroup(ctx, ">", redisFetchBlock)
+	if err != nil {
+		return err
+	}
+
+	s.bufferEntries(ctx, entries)
+	return nil
+}
+
+func (s *redisSubscription) readGroup(ctx context.Context, id string, block time.Duration) ([]redis.XMessage, error) {
+	args := &redis.XReadGroupArgs{
+		Group:    s.group,
+		Consumer: s.consumer,
+		Streams:  []string{s.stream, id},
+		Count:    redisFetchCount,
+		Block:    block,
+	}
+
+	streams, err := s.client.XReadGroup(ctx, args).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read %s entries: %w", s.stream, err)
+	}
+
+	var entries []redis.XMessage
+	for _, stream := range streams {
+		entries = append(entries, stream.Messages...)
+	}
+
+	return entries, nil
+}
+
+func (s *redisSubscription) bufferEntries(ctx context.Context, entries []redis.XMessage) {
+	for _, entry := range entries {
+		// The entries trimmed after they were fetched come back without values, so there's nothing to handle.
+		// If acking them fails, they're claimed and acked again later.
+		if entry.Values == nil {
+			s.client.XAck(ctx, s.stream, s.group, entry.ID)
+			continue
+		}
+
+		s.buffer = append(s.buffer, messageFromRedis(s.topic, entry))
+	}
+}
+
+func (s *redisSubscription) Commit(ctx context.Context, message Message) error {
+	if err := s.client.XAck(ctx, s.stream, s.group, message.ID).Err(); err != nil {
+		return fmt.Errorf("ack %s entry %s: %w", s.stream, message.ID, err)
+	}
+
+	return nil
+}
+
+// Close stops fetching. The buffered messages stay pending and are fetched again after a restart.
+func (s *redisSubscription) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.done)
+	})
+
+	return nil
+}
+
+func messageFromRedis(topic string, entry redis.XMessage) Message {
+	message := Message{
+		Topic: topic,
+		ID:    entry.ID,
+	}
+
+	if key, ok := entry.Values[redisKeyField].(string); ok {
+		message.Key = []byte(key)
+	}
+
+	if value, ok := entry.Values[redisValueField].(string); ok {
+		message.Value = []byte(value)
+	}
+
+	if headers, ok := entry.Values[redisHeadersField].(string); ok {
+		// Only this package writes the headers, so they're always valid.
+		_ = json.Unmarshal([]byte(headers), &message.Headers)
+	}
+
+	return message
+}
+
+func redisStreamKey(topic string) string {
+	return redisStreamKeyPrefix + topic
+}
+
+func isRedisNoSuchKey(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "no such key")
+}
+
+// compareRedisIDs compares two stream entry IDs, formatted as "<milliseconds>-<sequence>".
+func compareRedisIDs(a string, b string) int {
+	aMillis, aSeq := parseRedisID(a)
+	bMillis, bSeq := parseRedisID(b)
+
+	switch {
+	case aMillis != bMillis:
+		if aMillis < bMillis {
+			return -1
+		}
+		return 1
+	case aSeq < bSeq:
+		return -1
+	case aSeq > bSeq:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func parseRedisID(id string) (uint64, uint64) {
+	millisStr, seqStr, _ := strings.Cut(id, "-")
+	millis, _ := strconv.ParseUint(millisStr, 10, 64)
+	seq, _ := strconv.ParseUint(seqStr, 10, 64)
+	return millis, seq
+}
+
+// NewRedis creates a new Redis Streams queue.
+func NewRedis(client redis.UniversalClient) *Redis {
+	return &Redis{
+		client: client,
+	}
+}
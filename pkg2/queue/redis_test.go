@@ -0,0 +1,216 @@
+package queue_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/turfaa/vmedis-proxy-api/pkg2/queue"
+)
+
+func TestRedis(t *testing.T) {
+	ctx := context.Background()
+	_, client, q := setupRedis(t)
+
+	if err := q.Publish(ctx, queue.Message{
+		Topic:   "topic",
+		Key:     []byte("key"),
+		Value:   []byte("a"),
+		Headers: map[string]string{"attempts": "3"},
+	}); err != nil {
+		t.Fatalf("publish: %s", err)
+	}
+	mustPublish(t, q, "topic", "b", "c")
+
+	sub, err := q.Subscribe(ctx, "topic", "group")
+	if err != nil {
+		t.Fatalf("subscribe: %s", err)
+	}
+
+	a := mustFetch(t, sub, "a")
+	if string(a.Key) != "key" || a.Headers["attempts"] != "3" || a.Topic != "topic" {
+		t.Fatalf("expected the key and headers to be kept, got %+v", a)
+	}
+	b := mustFetch(t, sub, "b")
+	mustFetch(t, sub, "c")
+
+	if err := sub.Commit(ctx, b); err != nil {
+		t.Fatalf("commit: %s", err)
+	}
+
+	if err := sub.Close(); err != nil {
+		t.Fatalf("close: %s", err)
+	}
+	if _, err := sub.Fetch(ctx); !errors.Is(err, queue.ErrClosed) {
+		t.Fatalf("expected ErrClosed after close, got %v", err)
+	}
+
+	// The messages fetched but not committed are delivered again after a restart.
+	sub, err = q.Subscribe(ctx, "topic", "group")
+	if err != nil {
+		t.Fatalf("subscribe: %s", err)
+	}
+	a = mustFetch(t, sub, "a")
+	c := mustFetch(t, sub, "c")
+
+	for _, message := range []queue.Message{a, c} {
+		if err := sub.Commit(ctx, message); err != nil {
+			t.Fatalf("commit: %s", err)
+		}
+	}
+
+	pending, err := client.XPending(ctx, "queue:topic", "group").Result()
+	if err != nil {
+		t.Fatalf("xpending: %s", err)
+	}
+	if pending.Count != 0 {
+		t.Fatalf("expected every message to be acked, got %d pending", pending.Count)
+	}
+}
+
+func TestRedisClaimsAbandonedMessages(t *testing.T) {
+	ctx := context.Background()
+	server, client, q := setupRedis(t)
+
+	sub, err := q.Subscribe(ctx, "topic", "group")
+	if err != nil {
+		t.Fatalf("subscribe: %s", err)
+	}
+
+	mustPublish(t, q, "topic", "a")
+
+	// Another consumer of the group fetches the message, then crashes before committing it.
+	if err := client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    "group",
+		Consumer: "crashed",
+		Streams:  []string{"queue:topic", ">"},
+		Block:    -1,
+	}).Err(); err != nil {
+		t.Fatalf("read as another consumer: %s", err)
+	}
+
+	server.SetTime(time.Now().Add(10 * time.Minute))
+
+	a := mustFetch(t, sub, "a")
+	if err := sub.Commit(ctx, a); err != nil {
+		t.Fatalf("commit: %s", err)
+	}
+
+	pending, err := client.XPending(ctx, "queue:topic", "group").Result()
+	if err != nil {
+		t.Fatalf("xpending: %s", err)
+	}
+	if pending.Count != 0 {
+		t.Fatalf("expected the claimed message to be acked, got %d pending", pending.Count)
+	}
+}
+
+func TestRedisSubscribeToExistingStream(t *testing.T) {
+	ctx := context.Background()
+	_, _, q := setupRedis(t)
+
+	// The stream is created by publishing, before any group exists.
+	mustPublish(t, q, "topic", "a")
+
+	// The new groups start from the beginning of the stream, and subscribing again keeps the existing group,
+	// so its uncommitted message is delivered again.
+	for _, group := range []string{"group", "group", "other"} {
+		sub, err := q.Subscribe(ctx, "topic", group)
+		if err != nil {
+			t.Fatalf("subscribe as %s: %s", group, err)
+		}
+
+		mustFetch(t, sub, "a")
+
+		if err := sub.Close(); err != nil {
+			t.Fatalf("close: %s", err)
+		}
+	}
+}
+
+func TestRedisPending(t *testing.T) {
+	ctx := context.Background()
+	_, _, q := setupRedis(t)
+
+	pending, err := q.Pending(ctx, "topic", "group")
+	if err != nil {
+		t.Fatalf("pending: %s", err)
+	}
+	if len(pending) != 0 {
+		t.Fatalf("expected nothing pending in an empty topic, got %v", pending)
+	}
+
+	mustPublish(t, q, "topic", "a", "b")
+
+	pending, err = q.Pending(ctx, "topic", "group")
+	if err != nil {
+		t.Fatalf("pending: %s", err)
+	}
+	if len(pending) != 2 || string(pending[0].Value) != "a" || string(pending[1].Value) != "b" {
+		t.Fatalf("expected a and b pending, got %v", pending)
+	}
+
+	if err := q.CommitPending(ctx, "topic", "group", pending); err != nil {
+		t.Fatalf("commit pending: %s", err)
+	}
+
+	mustPublish(t, q, "topic", "c")
+
+	pending, err = q.Pending(ctx, "topic", "group")
+	if err != nil {
+		t.Fatalf("pending: %s", err)
+	}
+	if len(pending) != 1 || string(pending[0].Value) != "c" {
+		t.Fatalf("expected only c pending after the commit, got %v", pending)
+	}
+}
+
+func setupRedis(t *testing.T) (*miniredis.Miniredis, *redis.Client, *queue.Redis) {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	client.AddHook(groupSetIDHook{client: client})
+	t.Cleanup(func() { client.Close() })
+
+	return server, client, queue.NewRedis(client)
+}
+
+// groupSetIDHook emulates XGROUP SETID, which miniredis doesn't support, by recreating the group from the ID.
+// It's only equivalent for the groups without any delivered but uncommitted entries.
+type groupSetIDHook struct {
+	client *redis.Client
+}
+
+func (h groupSetIDHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h groupSetIDHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		args := cmd.Args()
+		if len(args) != 5 || args[0] != "xgroup" || args[1] != "setid" {
+			return next(ctx, cmd)
+		}
+
+		stream, group, id := args[2].(string), args[3].(string), args[4].(string)
+		if err := h.client.XGroupDestroy(ctx, stream, group).Err(); err != nil {
+			cmd.SetErr(err)
+			return err
+		}
+		if err := h.client.XGroupCreate(ctx, stream, group, id).Err(); err != nil {
+			cmd.SetErr(err)
+			return err
+		}
+
+		return nil
+	}
+}
+
+func (h groupSetIDHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
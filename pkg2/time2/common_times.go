@@ -60,3 +60,23 @@ func EndOfLastMonth() time.Time {
 	year, month, _ := time.Now().AddDate(0, -1, 0).Date()
 	return time.Date(year, month+1, 0, 23, 59, 59, 999999999, time.Local)
 }
+
+// DaysUntil returns the beginning of the first day and the end of the last day
+// of the given number of days until the given date, or until yesterday if the date is empty.
+func DaysUntil(date string, days int) (from time.Time, until time.Time, err error) {
+	if date == "" {
+		date = time.Now().AddDate(0, 0, -1).Format(time.DateOnly)
+	}
+
+	until, err = EndOfDate(date)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("end of date: %w", err)
+	}
+
+	from, err = BeginningOfDate(until.AddDate(0, 0, 1-days).Format(time.DateOnly))
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("beginning of date: %w", err)
+	}
+
+	return from, until, nil
+}
@@ -20,8 +20,9 @@ func DumpProcurementsBetweenDatesFromVmedisToDB(
 	vmedisClient *vmedisv1.Client,
 	drugProducer UpdatedDrugProducer,
 	drugUnitsGetter DrugUnitsGetter,
+	eventProducer EventProducer,
 ) {
-	service := NewService(db, redisClient, vmedisClient, drugProducer, drugUnitsGetter)
+	service := NewService(db, redisClient, vmedisClient, drugProducer, drugUnitsGetter, eventProducer)
 
 	if err := service.DumpProcurementsBetweenDatesFromVmedisToDB(ctx, startDate, endDate); err != nil {
 		log.Fatalf("DumpProcurementsBetweenDatesFromVmedisToDB: %s", err)
@@ -37,8 +38,9 @@ func ReconcileProcurementsBetweenDatesWithVmedis(
 	vmedisClient *vmedisv1.Client,
 	drugProducer UpdatedDrugProducer,
 	drugUnitsGetter DrugUnitsGetter,
+	eventProducer EventProducer,
 ) {
-	service := NewService(db, redisClient, vmedisClient, drugProducer, drugUnitsGetter)
+	service := NewService(db, redisClient, vmedisClient, drugProducer, drugUnitsGetter, eventProducer)
 
 	if err := service.ReconcileProcurementsBetweenDatesWithVmedis(ctx, startDate, endDate); err != nil {
 		log.Fatalf("ReconcileProcurementsBetweenDatesWithVmedis: %s", err)
@@ -52,10 +54,12 @@ func DumpProcurementRecommendations(
 	vmedisClient *vmedisv1.Client,
 	drugProducer UpdatedDrugProducer,
 	drugUnitsGetter DrugUnitsGetter,
+	eventProducer EventProducer,
+	options RecommendationOptions,
 ) {
-	service := NewService(db, redisClient, vmedisClient, drugProducer, drugUnitsGetter)
+	service := NewService(db, redisClient, vmedisClient, drugProducer, drugUnitsGetter, eventProducer)
 
-	if err := service.DumpRecommendationsFromVmedisToRedis(ctx); err != nil {
+	if err := service.DumpRecommendationsFromVmedisToRedis(ctx, options); err != nil {
 		log.Fatalf("DumpProcurementsBetweenDatesFromVmedisToDB: %s", err)
 	}
 }
@@ -15,6 +15,7 @@ import (
 
 	"github.com/turfaa/vmedis-proxy-api/database"
 	"github.com/turfaa/vmedis-proxy-api/database/models"
+	"github.com/turfaa/vmedis-proxy-api/outbox"
 	"github.com/turfaa/vmedis-proxy-api/pkg2/slices2"
 	"github.com/turfaa/vmedis-proxy-api/pkg2/zstd2"
 	vmedisv1 "github.com/turfaa/vmedis-proxy-api/vmedis/v1"
@@ -118,6 +119,24 @@ func (d *Database) UpsertVmedisProcurements(ctx context.Context, procurements []
 	})
 }
 
+// GetProcurementsByInvoiceNumbers returns the non-deleted procurements with the given invoice numbers, with their units.
+func (d *Database) GetProcurementsByInvoiceNumbers(ctx context.Context, invoiceNumbers []string) ([]models.Procurement, error) {
+	if len(invoiceNumbers) == 0 {
+		return nil, nil
+	}
+
+	var procurements []models.Procurement
+	if err := d.dbCtx(ctx).
+		Preload("ProcurementUnits", func(db *gorm.DB) *gorm.DB { return db.Order("id_in_procurement") }).
+		Where("invoice_number IN ?", invoiceNumbers).
+		Find(&procurements).
+		Error; err != nil {
+		return nil, fmt.Errorf("get procurements %v from DB: %w", invoiceNumbers, err)
+	}
+
+	return procurements, nil
+}
+
 // GetProcurementInvoiceNumbersBetweenTime returns the invoice numbers of the
 // non-deleted procurements whose invoice date falls between the given times.
 func (d *Database) GetProcurementInvoiceNumbersBetweenTime(ctx context.Context, from time.Time, to time.Time) ([]string, error) {
@@ -240,6 +259,7 @@ SELECT
 	procurement_units.amount,
 	procurement_units.unit,
 	procurement_units.total_unit_price,
+	procurement_units.unit_taxed_price,
 	procurement_units.invoice_number,
 	procurements.invoice_date,
 	procurements.supplier
@@ -262,6 +282,166 @@ LIMIT ?
 	return procurements, nil
 }
 
+// GetLatestDrugProcurements returns the latest procurement of every procured drug, sorted by drug name.
+func (d *Database) GetLatestDrugProcurements(ctx context.Context) ([]DrugProcurement, error) {
+	var procurements []DrugProcurement
+	if err := d.dbCtx(ctx).
+		Raw(
+			`
+SELECT
+	created_at,
+	drug_code,
+	drug_name,
+	amount,
+	unit,
+	total_unit_price,
+	unit_taxed_price,
+	invoice_number,
+	invoice_date,
+	supplier
+FROM (
+	SELECT
+		procurement_units.created_at,
+		procurement_units.drug_code,
+		procurement_units.drug_name,
+		procurement_units.amount,
+		procurement_units.unit,
+		procurement_units.total_unit_price,
+		procurement_units.unit_taxed_price,
+		procurement_units.invoice_number,
+		procurements.invoice_date,
+		procurements.supplier,
+		ROW_NUMBER() OVER (
+			PARTITION BY procurement_units.drug_code
+			ORDER BY procurements.invoice_date DESC, procurement_units.created_at DESC, procurement_units.id DESC
+		) AS recency
+	FROM procurement_units
+	JOIN procurements ON procurement_units.invoice_number = procurements.invoice_number
+	WHERE procurement_units.drug_code <> ''
+		AND procurements.deleted_at IS NULL
+		AND procurement_units.deleted_at IS NULL
+) latest_procurements
+WHERE recency = 1
+ORDER BY drug_name, drug_code
+			`,
+		).
+		Find(&procurements).
+		Error; err != nil {
+		return nil, fmt.Errorf("execute SQL query: %w", err)
+	}
+
+	return procurements, nil
+}
+
+// soldUnit is a sold drug unit with the time of its sale.
+type soldUnit struct {
+	DrugCode string
+	Amount   float64
+	Unit     string
+	SoldAt   time.Time
+}
+
+// GetSoldUnitsBetweenTime returns the units of the given drugs sold between the given times.
+func (d *Database) GetSoldUnitsBetweenTime(ctx context.Context, drugCodes []string, from time.Time, until time.Time) ([]soldUnit, error) {
+	if len(drugCodes) == 0 {
+		return nil, nil
+	}
+
+	var units []soldUnit
+	if err := d.dbCtx(ctx).
+		Raw(
+			`
+SELECT
+	sale_units.drug_code,
+	sale_units.amount,
+	sale_units.unit,
+	sales.sold_at
+FROM sale_units
+JOIN sales ON sale_units.invoice_number = sales.invoice_number
+WHERE sale_units.drug_code IN ?
+	AND sales.sold_at BETWEEN ? AND ?
+	AND sales.deleted_at IS NULL
+	AND sale_units.deleted_at IS NULL
+			`,
+			drugCodes,
+			from,
+			until,
+		).
+		Find(&units).
+		Error; err != nil {
+		return nil, fmt.Errorf("get sold units between %s and %s from db: %w", from, until, err)
+	}
+
+	return units, nil
+}
+
+// GetStockedOrSoldDrugs returns the existing drugs with any stock or sold between the given times,
+// with their stocks, sorted by the drug name.
+func (d *Database) GetStockedOrSoldDrugs(ctx context.Context, from time.Time, until time.Time) ([]models.Drug, error) {
+	var drugs []models.Drug
+	if err := d.dbCtx(ctx).
+		Preload("Stocks").
+		Where("removed_at IS NULL").
+		Where(
+			`(
+	vmedis_code IN (SELECT drug_vmedis_code FROM drug_stocks WHERE quantity > 0)
+	OR vmedis_code IN (
+		SELECT sale_units.drug_code
+		FROM sale_units
+		JOIN sales ON sale_units.invoice_number = sales.invoice_number
+		WHERE sales.sold_at BETWEEN ? AND ?
+			AND sales.deleted_at IS NULL
+			AND sale_units.deleted_at IS NULL
+	)
+)`,
+			from,
+			until,
+		).
+		Order("name, vmedis_code").
+		Find(&drugs).
+		Error; err != nil {
+		return nil, fmt.Errorf("get drugs stocked or sold between %s and %s from DB: %w", from, until, err)
+	}
+
+	return drugs, nil
+}
+
+// GetSupplierLeadTimes returns the lead times of the suppliers, sorted by the supplier.
+func (d *Database) GetSupplierLeadTimes(ctx context.Context) ([]SupplierLeadTime, error) {
+	var leadTimes []models.SupplierLeadTime
+	if err := d.dbCtx(ctx).Order("supplier").Find(&leadTimes).Error; err != nil {
+		return nil, fmt.Errorf("get supplier lead times from DB: %w", err)
+	}
+
+	return slices2.Map(leadTimes, FromDBSupplierLeadTime), nil
+}
+
+// UpsertSupplierLeadTime sets the lead time of a supplier.
+func (d *Database) UpsertSupplierLeadTime(ctx context.Context, leadTime SupplierLeadTime) error {
+	if err := d.dbCtx(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "supplier"}},
+			DoUpdates: clause.AssignmentColumns([]string{"updated_at", "days"}),
+		}).
+		Create(&models.SupplierLeadTime{
+			Supplier: leadTime.Supplier,
+			Days:     leadTime.Days,
+		}).
+		Error; err != nil {
+		return fmt.Errorf("upsert lead time of supplier %s to DB: %w", leadTime.Supplier, err)
+	}
+
+	return nil
+}
+
+// Transaction runs fn with a Database in a transaction.
+// The messages published to the outbox with the context passed to fn are written in the same transaction.
+func (d *Database) Transaction(ctx context.Context, fn func(ctx context.Context, tx *Database) error) error {
+	return outbox.Transaction(ctx, d.db, func(ctx context.Context, tx *gorm.DB) error {
+		return fn(ctx, NewDatabase(tx))
+	})
+}
+
 func (d *Database) dbCtx(ctx context.Context) *gorm.DB {
 	return d.db.WithContext(ctx)
 }
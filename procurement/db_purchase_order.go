@@ -0,0 +1,251 @@
+package procurement
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+)
+
+const purchaseOrdersLimit = 100
+
+// GetPurchaseOrders returns the latest purchase orders with their lines, newest first.
+// All statuses are returned if the status is empty.
+func (d *Database) GetPurchaseOrders(ctx context.Context, status models.PurchaseOrderStatus) ([]models.PurchaseOrder, error) {
+	query := d.dbCtx(ctx).Preload("Lines", orderPurchaseOrderLines)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var orders []models.PurchaseOrder
+	if err := query.
+		Order("id DESC").
+		Limit(purchaseOrdersLimit).
+		Find(&orders).
+		Error; err != nil {
+		return nil, fmt.Errorf("get purchase orders from db: %w", err)
+	}
+
+	return orders, nil
+}
+
+// GetPurchaseOrder returns the purchase order with its lines, sorted by the drug names.
+func (d *Database) GetPurchaseOrder(ctx context.Context, id uint) (models.PurchaseOrder, error) {
+	var order models.PurchaseOrder
+	if err := d.dbCtx(ctx).
+		Preload("Lines", orderPurchaseOrderLines).
+		First(&order, id).
+		Error; err != nil {
+		return models.PurchaseOrder{}, fmt.Errorf("get purchase order %d from db: %w", id, err)
+	}
+
+	return order, nil
+}
+
+// GetDraftPurchaseOrderIDOfSupplier returns the ID of the oldest draft purchase order of the supplier,
+// returning gorm.ErrRecordNotFound if there is none.
+func (d *Database) GetDraftPurchaseOrderIDOfSupplier(ctx context.Context, supplier string) (uint, error) {
+	var order models.PurchaseOrder
+	if err := d.dbCtx(ctx).
+		Select("id").
+		Where("supplier = ? AND status = ?", supplier, models.PurchaseOrderStatusDraft).
+		Order("id").
+		First(&order).
+		Error; err != nil {
+		return 0, fmt.Errorf("get draft purchase order of %s from db: %w", supplier, err)
+	}
+
+	return order.ID, nil
+}
+
+// GetOpenPurchaseOrders returns the sent purchase orders that aren't fully received yet with their lines,
+// the earliest sent first.
+func (d *Database) GetOpenPurchaseOrders(ctx context.Context) ([]models.PurchaseOrder, error) {
+	var orders []models.PurchaseOrder
+	if err := d.dbCtx(ctx).
+		Preload("Lines", orderPurchaseOrderLines).
+		Where("status IN ?", []models.PurchaseOrderStatus{models.PurchaseOrderStatusSent, models.PurchaseOrderStatusPartiallyReceived}).
+		Order("sent_at").
+		Order("id").
+		Find(&orders).
+		Error; err != nil {
+		return nil, fmt.Errorf("get open purchase orders from db: %w", err)
+	}
+
+	return orders, nil
+}
+
+func (d *Database) CreatePurchaseOrder(ctx context.Context, order *models.PurchaseOrder) error {
+	if err := d.dbCtx(ctx).Create(order).Error; err != nil {
+		return fmt.Errorf("create purchase order in db: %w", err)
+	}
+
+	return nil
+}
+
+// AddPurchaseOrderLines adds the lines to the purchase order, skipping the drugs that are already in it.
+func (d *Database) AddPurchaseOrderLines(ctx context.Context, lines []models.PurchaseOrderLine) error {
+	if len(lines) == 0 {
+		return nil
+	}
+
+	if err := d.dbCtx(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "purchase_order_id"}, {Name: "drug_code"}},
+			DoNothing: true,
+		}).
+		Create(&lines).
+		Error; err != nil {
+		return fmt.Errorf("add purchase order lines in db: %w", err)
+	}
+
+	return nil
+}
+
+// UpdatePurchaseOrderLine sets the quantity and the unit of the line of the purchase order,
+// returning gorm.ErrRecordNotFound if there is none.
+func (d *Database) UpdatePurchaseOrderLine(ctx context.Context, orderID uint, lineID uint, quantity float64, unit string) error {
+	result := d.dbCtx(ctx).
+		Model(&models.PurchaseOrderLine{}).
+		Where("id = ? AND purchase_order_id = ?", lineID, orderID).
+		Updates(map[string]any{"quantity": quantity, "unit": unit})
+	if result.Error != nil {
+		return fmt.Errorf("update purchase order line %d in db: %w", lineID, result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("update purchase order line %d in db: %w", lineID, gorm.ErrRecordNotFound)
+	}
+
+	return nil
+}
+
+// DeletePurchaseOrderLine deletes the line of the purchase order, returning gorm.ErrRecordNotFound if there is none.
+func (d *Database) DeletePurchaseOrderLine(ctx context.Context, orderID uint, lineID uint) error {
+	result := d.dbCtx(ctx).
+		Where("purchase_order_id = ?", orderID).
+		Delete(&models.PurchaseOrderLine{}, lineID)
+	if result.Error != nil {
+		return fmt.Errorf("delete purchase order line %d from db: %w", lineID, result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("delete purchase order line %d from db: %w", lineID, gorm.ErrRecordNotFound)
+	}
+
+	return nil
+}
+
+// DeleteDraftPurchaseOrder deletes the purchase order and its lines if it's still a draft,
+// returning whether it was deleted.
+func (d *Database) DeleteDraftPurchaseOrder(ctx context.Context, id uint) (bool, error) {
+	deleted := false
+	err := d.dbCtx(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.
+			Where("status = ?", models.PurchaseOrderStatusDraft).
+			Delete(&models.PurchaseOrder{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		deleted = true
+		return tx.Where("purchase_order_id = ?", id).Delete(&models.PurchaseOrderLine{}).Error
+	})
+	if err != nil {
+		return false, fmt.Errorf("delete purchase order %d from db: %w", id, err)
+	}
+
+	return deleted, nil
+}
+
+// SendPurchaseOrder marks the purchase order as sent if it's still a draft, returning whether it was sent now.
+func (d *Database) SendPurchaseOrder(ctx context.Context, id uint, sentBy string, sentAt time.Time) (bool, error) {
+	result := d.dbCtx(ctx).
+		Model(&models.PurchaseOrder{}).
+		Where("id = ? AND status = ?", id, models.PurchaseOrderStatusDraft).
+		Updates(map[string]any{
+			"status":  models.PurchaseOrderStatusSent,
+			"sent_at": sentAt,
+			"sent_by": sentBy,
+		})
+	if result.Error != nil {
+		return false, fmt.Errorf("send purchase order %d in db: %w", id, result.Error)
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+// GetUnmatchedProcurementsOfSupplierSince returns the procurements of the supplier invoiced since the given date,
+// oldest first, with only their units that aren't matched with any purchase order line yet.
+func (d *Database) GetUnmatchedProcurementsOfSupplierSince(ctx context.Context, supplier string, since time.Time) ([]models.Procurement, error) {
+	var procurements []models.Procurement
+	if err := d.dbCtx(ctx).
+		Preload("ProcurementUnits", func(db *gorm.DB) *gorm.DB {
+			return db.
+				Where("NOT EXISTS (?)", d.dbCtx(ctx).
+					Model(&models.PurchaseOrderReceipt{}).
+					Select("1").
+					Where("purchase_order_receipts.invoice_number = procurement_units.invoice_number").
+					Where("purchase_order_receipts.id_in_procurement = procurement_units.id_in_procurement"),
+				).
+				Order("id_in_procurement")
+		}).
+		Where("supplier = ? AND invoice_date >= ?", supplier, datatypes.Date(since)).
+		Order("invoice_date").
+		Order("id").
+		Find(&procurements).
+		Error; err != nil {
+		return nil, fmt.Errorf("get unmatched procurements of %s since %s from db: %w", supplier, since.Format(time.DateOnly), err)
+	}
+
+	return procurements, nil
+}
+
+// ReceivePurchaseOrderLines records the receipts, adds their quantities to the received quantities of their lines,
+// and updates the statuses of the orders.
+func (d *Database) ReceivePurchaseOrderLines(ctx context.Context, receipts []models.PurchaseOrderReceipt, orders []models.PurchaseOrder) error {
+	if err := d.dbCtx(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&receipts).Error; err != nil {
+			return fmt.Errorf("create purchase order receipts: %w", err)
+		}
+
+		for _, order := range orders {
+			for _, line := range order.Lines {
+				if err := tx.
+					Model(&models.PurchaseOrderLine{}).
+					Where("id = ?", line.ID).
+					Update("received_quantity", line.ReceivedQuantity).
+					Error; err != nil {
+					return fmt.Errorf("update received quantity of purchase order line %d: %w", line.ID, err)
+				}
+			}
+
+			if err := tx.
+				Model(&models.PurchaseOrder{}).
+				Where("id = ?", order.ID).
+				Updates(map[string]any{"status": order.Status, "received_at": order.ReceivedAt}).
+				Error; err != nil {
+				return fmt.Errorf("update status of purchase order %d: %w", order.ID, err)
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return fmt.Errorf("receive purchase order lines in db: %w", err)
+	}
+
+	return nil
+}
+
+func orderPurchaseOrderLines(db *gorm.DB) *gorm.DB {
+	return db.Order("drug_name").Order("id")
+}
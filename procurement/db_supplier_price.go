@@ -0,0 +1,63 @@
+package procurement
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// procuredPrice is the price of a procurement unit, in the unit it was procured in.
+type procuredPrice struct {
+	DrugCode                string
+	Supplier                string
+	InvoiceDate             time.Time
+	Amount                  float64
+	Unit                    string
+	UnitTaxedPrice          float64
+	TotalUnitPrice          float64
+	DiscountPercentage      float64
+	DiscountTwoPercentage   float64
+	DiscountThreePercentage float64
+}
+
+// GetProcuredPricesSince returns the prices of the procurement units of the drugs from the suppliers,
+// invoiced since the given time, oldest first.
+func (d *Database) GetProcuredPricesSince(ctx context.Context, drugCodes []string, since time.Time) ([]procuredPrice, error) {
+	if len(drugCodes) == 0 {
+		return nil, nil
+	}
+
+	var prices []procuredPrice
+	if err := d.dbCtx(ctx).
+		Raw(
+			`
+SELECT
+	procurement_units.drug_code,
+	procurements.supplier,
+	procurements.invoice_date,
+	procurement_units.amount,
+	procurement_units.unit,
+	procurement_units.unit_taxed_price,
+	procurement_units.total_unit_price,
+	procurement_units.discount_percentage,
+	procurement_units.discount_two_percentage,
+	procurement_units.discount_three_percentage
+FROM procurement_units
+JOIN procurements ON procurement_units.invoice_number = procurements.invoice_number
+WHERE procurement_units.drug_code IN ?
+	AND procurements.invoice_date >= ?
+	AND procurements.supplier <> ''
+	AND procurements.deleted_at IS NULL
+	AND procurement_units.deleted_at IS NULL
+ORDER BY procurements.invoice_date, procurement_units.created_at, procurement_units.id
+			`,
+			drugCodes,
+			since,
+		).
+		Find(&prices).
+		Error; err != nil {
+		return nil, fmt.Errorf("get procured prices since %s from db: %w", since, err)
+	}
+
+	return prices, nil
+}
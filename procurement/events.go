@@ -0,0 +1,69 @@
+package procurement
+
+import (
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+	"github.com/turfaa/vmedis-proxy-api/kafkapb"
+)
+
+// procurementRecordedEvents returns the events of the procurements that are new or whose content differs from before.
+func procurementRecordedEvents(before []models.Procurement, after []models.Procurement) []*kafkapb.ProcurementRecorded {
+	beforeProcurements := make(map[string]*kafkapb.Procurement, len(before))
+	for _, p := range before {
+		beforeProcurements[p.InvoiceNumber] = procurementEvent(p)
+	}
+
+	var recorded []*kafkapb.ProcurementRecorded
+	for _, p := range after {
+		afterProcurement := procurementEvent(p)
+		beforeProcurement := beforeProcurements[p.InvoiceNumber]
+
+		if beforeProcurement != nil && proto.Equal(beforeProcurement, afterProcurement) {
+			continue
+		}
+
+		recorded = append(recorded, &kafkapb.ProcurementRecorded{
+			Before: beforeProcurement,
+			After:  afterProcurement,
+		})
+	}
+
+	return recorded
+}
+
+func procurementEvent(p models.Procurement) *kafkapb.Procurement {
+	units := make([]*kafkapb.ProcurementUnit, 0, len(p.ProcurementUnits))
+	for _, unit := range p.ProcurementUnits {
+		units = append(units, &kafkapb.ProcurementUnit{
+			IdInProcurement: int64(unit.IDInProcurement),
+			DrugCode:        unit.DrugCode,
+			DrugName:        unit.DrugName,
+			Amount:          unit.Amount,
+			Unit:            unit.Unit,
+			UnitBasePrice:   unit.UnitBasePrice,
+			TotalUnitPrice:  unit.TotalUnitPrice,
+			UnitTaxedPrice:  unit.UnitTaxedPrice,
+			ExpiryDate:      timestamppb.New(unit.ExpiryDate),
+			BatchNumber:     unit.BatchNumber,
+			Total:           unit.Total,
+		})
+	}
+
+	return &kafkapb.Procurement{
+		InvoiceNumber:     p.InvoiceNumber,
+		InvoiceDate:       time.Time(p.InvoiceDate).Format(time.DateOnly),
+		Supplier:          p.Supplier,
+		Warehouse:         p.Warehouse,
+		PaymentType:       p.PaymentType,
+		Operator:          p.Operator,
+		DiscountAmount:    p.DiscountAmount,
+		TaxAmount:         p.TaxAmount,
+		MiscellaneousCost: p.MiscellaneousCost,
+		Total:             p.Total,
+		Units:             units,
+	}
+}
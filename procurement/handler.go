@@ -14,6 +14,9 @@ import (
 
 type ApiHandler struct {
 	service *Service
+
+	// minMarginPercentage is the default margin floor of the margin report.
+	minMarginPercentage float64
 }
 
 func (h *ApiHandler) DumpProcurements(c *gin.Context) {
@@ -58,8 +61,25 @@ func (h *ApiHandler) GetRecommendations(c *gin.Context) {
 }
 
 func (h *ApiHandler) DumpRecommendations(c *gin.Context) {
+	var request DumpRecommendationsRequest
+	if err := c.ShouldBindJSON(&request); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(400, gin.H{
+			"error": fmt.Sprintf("invalid request: %s", err),
+		})
+		return
+	}
+
+	// The options are validated before responding, since the recommendations are generated in the background.
+	options, err := request.Options().withDefaults()
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": fmt.Sprintf("invalid request: %s", err),
+		})
+		return
+	}
+
 	go func() {
-		if err := h.service.DumpRecommendationsFromVmedisToRedis(context.Background()); err != nil {
+		if err := h.service.DumpRecommendationsFromVmedisToRedis(context.Background(), options); err != nil {
 			log.Printf("Failed to dump recommendations from vmedis to redis: %s", err)
 		}
 	}()
@@ -93,8 +113,9 @@ func (h *ApiHandler) GetInvoiceCalculators(c *gin.Context) {
 	c.JSON(200, InvoiceCalculatorsResponse{Calculators: calculators})
 }
 
-func NewApiHandler(service *Service) *ApiHandler {
+func NewApiHandler(service *Service, minMarginPercentage float64) *ApiHandler {
 	return &ApiHandler{
-		service: service,
+		service:             service,
+		minMarginPercentage: minMarginPercentage,
 	}
 }
@@ -0,0 +1,108 @@
+package procurement
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/turfaa/vmedis-proxy-api/cui"
+	"github.com/turfaa/vmedis-proxy-api/money"
+)
+
+const xlsxContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+func (h *ApiHandler) GetMargins(c *gin.Context) {
+	margins, ok := h.getMargins(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(200, h.transformMarginsToTable(margins))
+}
+
+func (h *ApiHandler) ExportMargins(c *gin.Context) {
+	margins, ok := h.getMargins(c)
+	if !ok {
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := WriteMarginsXLSX(&buf, margins); err != nil {
+		c.JSON(500, gin.H{
+			"error": fmt.Sprintf("failed to export margins: %s", err),
+		})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="margin-%s.xlsx"`, time.Now().Format("2006-01-02")))
+	c.Data(200, xlsxContentType, buf.Bytes())
+}
+
+func (h *ApiHandler) getMargins(c *gin.Context) ([]DrugUnitMargin, bool) {
+	var request MarginsRequest
+	if err := c.ShouldBindQuery(&request); err != nil {
+		c.JSON(400, gin.H{
+			"error": fmt.Sprintf("invalid request: %s", err),
+		})
+		return nil, false
+	}
+
+	minMarginPercentage := h.minMarginPercentage
+	if request.MinMarginPercentage != nil {
+		minMarginPercentage = *request.MinMarginPercentage
+	}
+
+	margins, err := h.service.GetMargins(c.Request.Context(), minMarginPercentage, request.FlaggedOnly)
+	if err != nil {
+		c.JSON(500, gin.H{
+			"error": fmt.Sprintf("failed to get margins: %s", err),
+		})
+		return nil, false
+	}
+
+	return margins, true
+}
+
+func (h *ApiHandler) transformMarginsToTable(margins []DrugUnitMargin) cui.Table {
+	header := []string{
+		"Nama Obat",
+		"Satuan",
+		"Harga Beli",
+		"Harga Satu",
+		"Harga Dua",
+		"Harga Tiga",
+		"Status",
+	}
+
+	rows := make([]cui.Row, len(margins))
+	for i, margin := range margins {
+		rows[i] = cui.Row{
+			ID: margin.DrugCode + "-" + strconv.Itoa(i),
+			Columns: []string{
+				margin.DrugName,
+				margin.Unit,
+				money.FormatRupiah(margin.Cost),
+				formatPriceMargin(margin.PriceOne),
+				formatPriceMargin(margin.PriceTwo),
+				formatPriceMargin(margin.PriceThree),
+				marginFlagText(margin.Flag),
+			},
+		}
+	}
+
+	return cui.Table{
+		Header: header,
+		Rows:   rows,
+	}
+}
+
+func formatPriceMargin(margin PriceMargin) string {
+	if margin.Price <= 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("%s (%.1f%%)", money.FormatRupiah(margin.Price), margin.MarginPercentage)
+}
@@ -0,0 +1,275 @@
+package procurement
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/turfaa/vmedis-proxy-api/auth"
+	"github.com/turfaa/vmedis-proxy-api/cui"
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+	"github.com/turfaa/vmedis-proxy-api/pkg2/slices2"
+)
+
+// GetPurchaseOrders returns the latest purchase orders as a table, optionally filtered by the `status` query parameter.
+// The row IDs are the purchase order IDs.
+func (h *ApiHandler) GetPurchaseOrders(c *gin.Context) {
+	status := models.PurchaseOrderStatus(strings.ToUpper(c.Query("status")))
+
+	orders, err := h.service.GetPurchaseOrders(c.Request.Context(), status)
+	if err != nil {
+		if errors.Is(err, ErrInvalidPurchaseOrder) {
+			c.JSON(400, gin.H{"error": fmt.Sprintf("invalid request: %s", err)})
+			return
+		}
+
+		c.JSON(500, gin.H{"error": fmt.Sprintf("failed to get purchase orders: %s", err)})
+		return
+	}
+
+	c.JSON(200, transformPurchaseOrdersToTable(orders))
+}
+
+func (h *ApiHandler) GetPurchaseOrder(c *gin.Context) {
+	id, ok := parsePurchaseOrderID(c)
+	if !ok {
+		return
+	}
+
+	order, err := h.service.GetPurchaseOrder(c.Request.Context(), id)
+	if err != nil {
+		respondPurchaseOrderError(c, id, "get", err)
+		return
+	}
+
+	c.JSON(200, PurchaseOrderResponse{PurchaseOrder: order})
+}
+
+// CreatePurchaseOrdersFromRecommendations groups the current recommendations by their suppliers into draft purchase orders.
+func (h *ApiHandler) CreatePurchaseOrdersFromRecommendations(c *gin.Context) {
+	orders, err := h.service.CreatePurchaseOrdersFromRecommendations(c.Request.Context(), auth.FromGinContext(c).Email)
+	if err != nil {
+		c.JSON(500, gin.H{"error": fmt.Sprintf("failed to create purchase orders: %s", err)})
+		return
+	}
+
+	c.JSON(201, PurchaseOrdersResponse{PurchaseOrders: orders})
+}
+
+// UpdatePurchaseOrderLine changes the ordered quantity of a line of a draft purchase order.
+func (h *ApiHandler) UpdatePurchaseOrderLine(c *gin.Context) {
+	id, ok := parsePurchaseOrderID(c)
+	if !ok {
+		return
+	}
+
+	lineID, ok := parsePurchaseOrderLineID(c)
+	if !ok {
+		return
+	}
+
+	var request PurchaseOrderLineRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(400, gin.H{"error": fmt.Sprintf("invalid request: %s", err)})
+		return
+	}
+
+	line, err := h.service.UpdatePurchaseOrderLine(c.Request.Context(), id, lineID, request)
+	if err != nil {
+		respondPurchaseOrderError(c, id, "update a line of", err)
+		return
+	}
+
+	c.JSON(200, PurchaseOrderLineResponse{Line: line})
+}
+
+func (h *ApiHandler) DeletePurchaseOrderLine(c *gin.Context) {
+	id, ok := parsePurchaseOrderID(c)
+	if !ok {
+		return
+	}
+
+	lineID, ok := parsePurchaseOrderLineID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeletePurchaseOrderLine(c.Request.Context(), id, lineID); err != nil {
+		respondPurchaseOrderError(c, id, "delete a line of", err)
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "Purchase order line deleted successfully"})
+}
+
+func (h *ApiHandler) DeletePurchaseOrder(c *gin.Context) {
+	id, ok := parsePurchaseOrderID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeletePurchaseOrder(c.Request.Context(), id); err != nil {
+		respondPurchaseOrderError(c, id, "delete", err)
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "Purchase order deleted successfully"})
+}
+
+// SendPurchaseOrder marks the draft purchase order as sent to its supplier by the caller.
+func (h *ApiHandler) SendPurchaseOrder(c *gin.Context) {
+	id, ok := parsePurchaseOrderID(c)
+	if !ok {
+		return
+	}
+
+	order, err := h.service.SendPurchaseOrder(c.Request.Context(), id, auth.FromGinContext(c).Email)
+	if err != nil {
+		respondPurchaseOrderError(c, id, "send", err)
+		return
+	}
+
+	c.JSON(200, PurchaseOrderResponse{PurchaseOrder: order})
+}
+
+// ReceivePurchaseOrders matches the stored procurements with the sent purchase orders.
+// It also runs after every procurement dump.
+func (h *ApiHandler) ReceivePurchaseOrders(c *gin.Context) {
+	matched, err := h.service.ReceivePurchaseOrders(c.Request.Context())
+	if err != nil {
+		c.JSON(500, gin.H{"error": fmt.Sprintf("failed to receive purchase orders: %s", err)})
+		return
+	}
+
+	c.JSON(200, ReceivePurchaseOrdersResponse{MatchedProcurementUnits: matched})
+}
+
+// ExportPurchaseOrderPDF returns the purchase order as a PDF file to be sent to its supplier.
+func (h *ApiHandler) ExportPurchaseOrderPDF(c *gin.Context) {
+	id, ok := parsePurchaseOrderID(c)
+	if !ok {
+		return
+	}
+
+	order, err := h.service.GetPurchaseOrder(c.Request.Context(), id)
+	if err != nil {
+		respondPurchaseOrderError(c, id, "get", err)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := RenderPurchaseOrderPDF(&buf, order); err != nil {
+		c.JSON(500, gin.H{"error": fmt.Sprintf("failed to render purchase order %d: %s", id, err)})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.pdf"`, strings.ToLower(formatPurchaseOrderNumber(order.ID))))
+	c.Data(200, "application/pdf", buf.Bytes())
+}
+
+// ExportPurchaseOrderXLSX returns the purchase order as an XLSX file to be sent to its supplier.
+func (h *ApiHandler) ExportPurchaseOrderXLSX(c *gin.Context) {
+	id, ok := parsePurchaseOrderID(c)
+	if !ok {
+		return
+	}
+
+	order, err := h.service.GetPurchaseOrder(c.Request.Context(), id)
+	if err != nil {
+		respondPurchaseOrderError(c, id, "get", err)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := WritePurchaseOrderXLSX(&buf, order); err != nil {
+		c.JSON(500, gin.H{"error": fmt.Sprintf("failed to export purchase order %d: %s", id, err)})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.xlsx"`, strings.ToLower(formatPurchaseOrderNumber(order.ID))))
+	c.Data(200, xlsxContentType, buf.Bytes())
+}
+
+func parsePurchaseOrderID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{"error": fmt.Sprintf("invalid id: %s", err)})
+		return 0, false
+	}
+
+	return uint(id), true
+}
+
+func parsePurchaseOrderLineID(c *gin.Context) (uint, bool) {
+	lineID, err := strconv.ParseUint(c.Param("line_id"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{"error": fmt.Sprintf("invalid line id: %s", err)})
+		return 0, false
+	}
+
+	return uint(lineID), true
+}
+
+func respondPurchaseOrderError(c *gin.Context, id uint, action string, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(404, gin.H{"error": fmt.Sprintf("purchase order %d or its line not found", id)})
+	case errors.Is(err, ErrInvalidPurchaseOrder), errors.Is(err, ErrPurchaseOrderNotDraft):
+		c.JSON(400, gin.H{"error": err.Error()})
+	default:
+		c.JSON(500, gin.H{"error": fmt.Sprintf("failed to %s purchase order %d: %s", action, id, err)})
+	}
+}
+
+func transformPurchaseOrdersToTable(orders []PurchaseOrder) cui.Table {
+	rows := slices2.Map(orders, func(order PurchaseOrder) cui.Row {
+		var received int
+		for _, line := range order.Lines {
+			if line.Received() {
+				received++
+			}
+		}
+
+		return cui.Row{
+			ID: strconv.FormatUint(uint64(order.ID), 10),
+			Columns: []string{
+				formatPurchaseOrderNumber(order.ID),
+				order.Supplier,
+				purchaseOrderDate(order).Format("2006-01-02"),
+				formatPurchaseOrderStatus(order.Status),
+				fmt.Sprintf("%d dari %d obat", received, len(order.Lines)),
+			},
+		}
+	})
+
+	return cui.Table{
+		Header: []string{
+			"No. PO",
+			"Supplier",
+			"Tanggal",
+			"Status",
+			"Diterima",
+		},
+		Rows: rows,
+	}
+}
+
+func formatPurchaseOrderStatus(status models.PurchaseOrderStatus) string {
+	switch status {
+	case models.PurchaseOrderStatusDraft:
+		return "Draf"
+	case models.PurchaseOrderStatusSent:
+		return "Terkirim"
+	case models.PurchaseOrderStatusPartiallyReceived:
+		return "Diterima sebagian"
+	case models.PurchaseOrderStatusReceived:
+		return "Diterima"
+	default:
+		return string(status)
+	}
+}
@@ -0,0 +1,96 @@
+package procurement
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/turfaa/vmedis-proxy-api/cui"
+	"github.com/turfaa/vmedis-proxy-api/money"
+)
+
+// GetDrugSupplierPrices returns the prices of a drug from each supplier as a table, ranked by the effective price.
+// The row IDs are the suppliers.
+func (h *ApiHandler) GetDrugSupplierPrices(c *gin.Context) {
+	var request DrugSupplierPricesRequest
+	if err := c.ShouldBindUri(&request); err != nil {
+		c.JSON(400, gin.H{
+			"error": fmt.Sprintf("invalid request: %s", err),
+		})
+		return
+	}
+
+	if err := c.ShouldBindQuery(&request); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(400, gin.H{
+			"error": fmt.Sprintf("invalid request: %s", err),
+		})
+		return
+	}
+
+	prices, err := h.service.GetDrugSupplierPrices(c.Request.Context(), request.DrugCode, request.Days)
+	if err != nil {
+		if errors.Is(err, ErrInvalidSupplierPriceRequest) {
+			c.JSON(400, gin.H{
+				"error": fmt.Sprintf("invalid request: %s", err),
+			})
+			return
+		}
+
+		c.JSON(500, gin.H{
+			"error": fmt.Sprintf("failed to get drug supplier prices: %s", err),
+		})
+		return
+	}
+
+	c.JSON(200, transformSupplierPricesToTable(prices))
+}
+
+func transformSupplierPricesToTable(prices []SupplierPrice) cui.Table {
+	header := []string{
+		"No",
+		"Supplier",
+		"Harga Efektif",
+		"Harga Terakhir",
+		"Harga Terendah",
+		"Harga Rata-rata",
+		"Diskon Terakhir",
+		"Diskon Rata-rata",
+		"Tanggal Faktur Terakhir",
+		"Jumlah Pembelian",
+	}
+
+	rows := make([]cui.Row, len(prices))
+	for i, price := range prices {
+		rows[i] = cui.Row{
+			ID: price.Supplier,
+			Columns: []string{
+				strconv.Itoa(i + 1),
+				price.Supplier,
+				formatSupplierPrice(price.EffectivePrice, price.Unit),
+				formatSupplierPrice(price.LastPrice, price.Unit),
+				formatSupplierPrice(price.MinPrice, price.Unit),
+				formatSupplierPrice(price.AveragePrice, price.Unit),
+				formatDiscountPercentage(price.LastDiscountPercentage),
+				formatDiscountPercentage(price.AverageDiscountPercentage),
+				price.LastInvoiceDate.Format("2006-01-02"),
+				strconv.Itoa(price.ProcurementCount) + " kali",
+			},
+		}
+	}
+
+	return cui.Table{
+		Header: header,
+		Rows:   rows,
+	}
+}
+
+func formatSupplierPrice(price float64, unit string) string {
+	return money.FormatRupiah(price) + " / " + unit
+}
+
+func formatDiscountPercentage(percentage float64) string {
+	return strconv.FormatFloat(percentage, 'f', -1, 64) + "%"
+}
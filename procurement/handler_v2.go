@@ -144,3 +144,64 @@ func (h *ApiHandler) transformLastDrugProcurementsToTable(procurements []DrugPro
 		Rows:   rows,
 	}
 }
+
+// GetSupplierLeadTimes returns the lead times of the suppliers as a display-ready table,
+// with the lead time of the other suppliers in the footer. The row IDs are the suppliers.
+func (h *ApiHandler) GetSupplierLeadTimes(c *gin.Context) {
+	leadTimes, err := h.service.GetSupplierLeadTimes(c.Request.Context())
+	if err != nil {
+		c.JSON(500, gin.H{
+			"error": fmt.Sprintf("failed to get supplier lead times: %s", err),
+		})
+		return
+	}
+
+	rows := make([]cui.Row, len(leadTimes))
+	for i, leadTime := range leadTimes {
+		rows[i] = cui.Row{
+			ID: leadTime.Supplier,
+			Columns: []string{
+				leadTime.Supplier,
+				formatLeadTimeDays(leadTime.Days),
+			},
+		}
+	}
+
+	c.JSON(200, cui.Table{
+		Header: []string{"Supplier", "Waktu Kirim"},
+		Rows:   rows,
+		Footer: []string{"Supplier lain", formatLeadTimeDays(DefaultLeadTimeDays)},
+	})
+}
+
+// SetSupplierLeadTime sets the lead time of a supplier.
+func (h *ApiHandler) SetSupplierLeadTime(c *gin.Context) {
+	var request SupplierLeadTime
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(400, gin.H{
+			"error": fmt.Sprintf("invalid request: %s", err),
+		})
+		return
+	}
+
+	leadTime, err := h.service.SetSupplierLeadTime(c.Request.Context(), request)
+	if err != nil {
+		if errors.Is(err, ErrInvalidLeadTime) {
+			c.JSON(400, gin.H{
+				"error": fmt.Sprintf("invalid request: %s", err),
+			})
+			return
+		}
+
+		c.JSON(500, gin.H{
+			"error": fmt.Sprintf("failed to set supplier lead time: %s", err),
+		})
+		return
+	}
+
+	c.JSON(200, leadTime)
+}
+
+func formatLeadTimeDays(days float64) string {
+	return formatDays(days) + " hari"
+}
@@ -14,3 +14,8 @@ type UpdatedDrugProducer interface {
 type DrugUnitsGetter interface {
 	GetDrugUnitsByDrugVmedisCodes(ctx context.Context, drugVmedisCodes []string) (map[string][]drug.Unit, error)
 }
+
+type EventProducer interface {
+	ProduceProcurementRecorded(ctx context.Context, events []*kafkapb.ProcurementRecorded) error
+	ProduceDumpCompleted(ctx context.Context, events []*kafkapb.DumpCompleted) error
+}
@@ -0,0 +1,219 @@
+package procurement
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+
+	"github.com/turfaa/vmedis-proxy-api/drug"
+	"github.com/turfaa/vmedis-proxy-api/pkg2/slices2"
+)
+
+const marginsSheetName = "Margin"
+
+// GetMargins returns the margins of every unit of the procured drugs, sorted by drug name and unit size.
+// Units are flagged when a price tier is below the latest procurement cost or below minMarginPercentage.
+// Drugs whose procurement unit is not one of their sale units are skipped, since their cost can't be converted.
+func (s *Service) GetMargins(ctx context.Context, minMarginPercentage float64, flaggedOnly bool) ([]DrugUnitMargin, error) {
+	procurements, err := s.db.GetLatestDrugProcurements(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get latest drug procurements from DB: %w", err)
+	}
+
+	if len(procurements) == 0 {
+		return nil, nil
+	}
+
+	drugCodes := slices2.Map(procurements, func(p DrugProcurement) string { return p.DrugCode })
+	unitsByDrug, err := s.drugUnitsGetter.GetDrugUnitsByDrugVmedisCodes(ctx, drugCodes)
+	if err != nil {
+		return nil, fmt.Errorf("get drug units: %w", err)
+	}
+
+	var margins []DrugUnitMargin
+	for _, procurement := range procurements {
+		drugMargins, ok := calculateDrugMargins(procurement, unitsByDrug[procurement.DrugCode], minMarginPercentage)
+		if !ok {
+			log.Printf("Skipping margin of drug %s: procurement unit %s can't be converted into its sale units", procurement.DrugCode, procurement.Unit)
+			continue
+		}
+
+		for _, margin := range drugMargins {
+			if flaggedOnly && margin.Flag == MarginFlagNone {
+				continue
+			}
+
+			margins = append(margins, margin)
+		}
+	}
+
+	return margins, nil
+}
+
+// calculateDrugMargins converts the procurement cost into each unit and calculates the margins of its price tiers.
+// The units must be sorted from the smallest to the largest, as returned by DrugUnitsGetter.
+func calculateDrugMargins(procurement DrugProcurement, units []drug.Unit, minMarginPercentage float64) ([]DrugUnitMargin, bool) {
+	sizes, ok := drug.UnitSizes(units)
+	if !ok {
+		return nil, false
+	}
+
+	procurementUnitSize := sizes[strings.ToLower(procurement.Unit)]
+	if procurementUnitSize == 0 {
+		return nil, false
+	}
+
+	procurementCost := procurement.UnitTaxedPrice
+	if procurementCost <= 0 {
+		procurementCost = procurement.TotalUnitPrice
+	}
+
+	margins := make([]DrugUnitMargin, len(units))
+	for i, unit := range units {
+		cost := procurementCost / procurementUnitSize * sizes[strings.ToLower(unit.Unit)]
+
+		margin := DrugUnitMargin{
+			DrugCode:        procurement.DrugCode,
+			DrugName:        procurement.DrugName,
+			Unit:            unit.Unit,
+			Cost:            cost,
+			PriceOne:        calculatePriceMargin(unit.PriceOne, cost, minMarginPercentage),
+			PriceTwo:        calculatePriceMargin(unit.PriceTwo, cost, minMarginPercentage),
+			PriceThree:      calculatePriceMargin(unit.PriceThree, cost, minMarginPercentage),
+			LastProcurement: procurement,
+		}
+
+		margin.Flag = worstMarginFlag(margin.PriceOne.Flag, margin.PriceTwo.Flag, margin.PriceThree.Flag)
+		margins[i] = margin
+	}
+
+	return margins, true
+}
+
+func calculatePriceMargin(price float64, cost float64, minMarginPercentage float64) PriceMargin {
+	if price <= 0 {
+		return PriceMargin{}
+	}
+
+	margin := PriceMargin{
+		Price:            price,
+		MarginPercentage: (price - cost) / price * 100,
+	}
+
+	switch {
+	case price < cost:
+		margin.Flag = MarginFlagBelowCost
+	case margin.MarginPercentage < minMarginPercentage:
+		margin.Flag = MarginFlagBelowFloor
+	}
+
+	return margin
+}
+
+func worstMarginFlag(flags ...MarginFlag) MarginFlag {
+	worst := MarginFlagNone
+	for _, flag := range flags {
+		switch {
+		case flag == MarginFlagBelowCost:
+			return MarginFlagBelowCost
+		case flag == MarginFlagBelowFloor:
+			worst = MarginFlagBelowFloor
+		}
+	}
+
+	return worst
+}
+
+// WriteMarginsXLSX writes the margins as an XLSX file.
+func WriteMarginsXLSX(w io.Writer, margins []DrugUnitMargin) error {
+	f := excelize.NewFile()
+	defer func() {
+		if err := f.Close(); err != nil {
+			log.Printf("failed to close file: %s", err)
+		}
+	}()
+
+	if _, err := f.NewSheet(marginsSheetName); err != nil {
+		return fmt.Errorf("create excel sheet: %w", err)
+	}
+
+	var errs []error
+
+	header := []string{
+		"Kode Obat",
+		"Nama Obat",
+		"Satuan",
+		"Harga Beli",
+		"Harga Satu",
+		"Margin Satu (%)",
+		"Harga Dua",
+		"Margin Dua (%)",
+		"Harga Tiga",
+		"Margin Tiga (%)",
+		"Status",
+		"Nomor Faktur Terakhir",
+		"Tanggal Faktur Terakhir",
+		"Supplier",
+	}
+	for i, h := range header {
+		errs = append(errs, f.SetCellStr(marginsSheetName, marginsCell(i, 1), h))
+	}
+
+	for i, margin := range margins {
+		row := i + 2
+
+		errs = append(errs, f.SetCellStr(marginsSheetName, marginsCell(0, row), margin.DrugCode))
+		errs = append(errs, f.SetCellStr(marginsSheetName, marginsCell(1, row), margin.DrugName))
+		errs = append(errs, f.SetCellStr(marginsSheetName, marginsCell(2, row), margin.Unit))
+		errs = append(errs, f.SetCellFloat(marginsSheetName, marginsCell(3, row), margin.Cost, 2, 64))
+
+		for j, price := range []PriceMargin{margin.PriceOne, margin.PriceTwo, margin.PriceThree} {
+			if price.Price <= 0 {
+				continue
+			}
+
+			errs = append(errs, f.SetCellFloat(marginsSheetName, marginsCell(4+2*j, row), price.Price, 2, 64))
+			errs = append(errs, f.SetCellFloat(marginsSheetName, marginsCell(5+2*j, row), price.MarginPercentage, 2, 64))
+		}
+
+		errs = append(errs, f.SetCellStr(marginsSheetName, marginsCell(10, row), marginFlagText(margin.Flag)))
+		errs = append(errs, f.SetCellStr(marginsSheetName, marginsCell(11, row), margin.LastProcurement.InvoiceNumber))
+		errs = append(errs, f.SetCellStr(marginsSheetName, marginsCell(12, row), margin.LastProcurement.InvoiceDate.Format("2006-01-02")))
+		errs = append(errs, f.SetCellStr(marginsSheetName, marginsCell(13, row), margin.LastProcurement.Supplier))
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("fill margins sheet: %w", err)
+	}
+
+	if err := f.DeleteSheet("Sheet1"); err != nil {
+		return fmt.Errorf("delete default XLSX sheet: %w", err)
+	}
+
+	if err := f.Write(w); err != nil {
+		return fmt.Errorf("write XLSX: %w", err)
+	}
+
+	return nil
+}
+
+func marginsCell(column int, row int) string {
+	cell, _ := excelize.CoordinatesToCellName(column+1, row)
+	return cell
+}
+
+func marginFlagText(flag MarginFlag) string {
+	switch flag {
+	case MarginFlagBelowCost:
+		return "Di Bawah Modal"
+	case MarginFlagBelowFloor:
+		return "Di Bawah Margin Minimum"
+	default:
+		return ""
+	}
+}
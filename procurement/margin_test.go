@@ -0,0 +1,144 @@
+package procurement_test
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm"
+
+	"github.com/turfaa/vmedis-proxy-api/cui"
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+	"github.com/turfaa/vmedis-proxy-api/drug"
+	"github.com/turfaa/vmedis-proxy-api/procurement"
+)
+
+// TestMargins checks that the latest procurement cost is converted into every
+// sale unit and that the price tiers below cost or below the margin floor are flagged.
+func TestMargins(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open database: %s", err)
+	}
+	if err := db.AutoMigrate(&models.Procurement{}, &models.ProcurementUnit{}, &models.DrugUnit{}); err != nil {
+		t.Fatalf("migrate database: %s", err)
+	}
+
+	procurements := []models.Procurement{
+		newProcurement("INV-1", "2024-03-01", "PBF Alpha", 0),
+		newProcurement("INV-2", "2024-03-05", "PBF Beta", 0),
+	}
+	procurements[0].ProcurementUnits = []models.ProcurementUnit{
+		{IDInProcurement: 1, DrugCode: "SANMOL", DrugName: "SANMOL 500MG", Unit: "Box", TotalUnitPrice: 30000, UnitTaxedPrice: 33000},
+		{IDInProcurement: 2, DrugCode: "BODREX", DrugName: "BODREX", Unit: "Strip", TotalUnitPrice: 3000},
+		{IDInProcurement: 3, DrugCode: "MYSTERY", DrugName: "MYSTERY", Unit: "Botol", TotalUnitPrice: 3000},
+	}
+	// Only the latest procurement of a drug counts.
+	procurements[1].ProcurementUnits = []models.ProcurementUnit{
+		{IDInProcurement: 1, DrugCode: "SANMOL", DrugName: "SANMOL 500MG", Unit: "Strip", TotalUnitPrice: 3600, UnitTaxedPrice: 4000},
+	}
+	if err := db.Create(&procurements).Error; err != nil {
+		t.Fatalf("seed procurements: %s", err)
+	}
+
+	units := []models.DrugUnit{
+		{DrugVmedisCode: "SANMOL", Unit: "Tablet", UnitOrder: 0, PriceOne: 500, PriceTwo: 420},
+		{DrugVmedisCode: "SANMOL", Unit: "Strip", ParentUnit: "Tablet", ConversionToParentUnit: 10, UnitOrder: 1, PriceOne: 5000, PriceThree: 3900},
+		{DrugVmedisCode: "SANMOL", Unit: "Box", ParentUnit: "Strip", ConversionToParentUnit: 10, UnitOrder: 2, PriceOne: 48000},
+		{DrugVmedisCode: "BODREX", Unit: "Strip", UnitOrder: 0, PriceOne: 4000},
+		{DrugVmedisCode: "MYSTERY", Unit: "Tablet", UnitOrder: 0, PriceOne: 4000},
+	}
+	if err := db.Create(&units).Error; err != nil {
+		t.Fatalf("seed drug units: %s", err)
+	}
+
+	service := procurement.NewService(db, nil, nil, nil, drug.NewDatabase(db), nil)
+
+	margins, err := service.GetMargins(context.Background(), 15, false)
+	if err != nil {
+		t.Fatalf("get margins: %s", err)
+	}
+
+	// MYSTERY is skipped because its procurement unit isn't one of its sale units.
+	if len(margins) != 4 {
+		t.Fatalf("get margins: expected 4 units, got %+v", margins)
+	}
+
+	bodrex := margins[0]
+	if bodrex.DrugCode != "BODREX" || bodrex.Cost != 3000 || bodrex.PriceOne.MarginPercentage != 25 || bodrex.Flag != procurement.MarginFlagNone {
+		t.Fatalf("BODREX strip: got %+v", bodrex)
+	}
+
+	tablet, strip, box := margins[1], margins[2], margins[3]
+	if tablet.Unit != "Tablet" || tablet.Cost != 400 || tablet.PriceOne.MarginPercentage != 20 {
+		t.Fatalf("SANMOL tablet: got %+v", tablet)
+	}
+	if tablet.PriceTwo.Flag != procurement.MarginFlagBelowFloor || tablet.Flag != procurement.MarginFlagBelowFloor {
+		t.Fatalf("SANMOL tablet price two: expected below floor, got %+v", tablet)
+	}
+	if strip.Cost != 4000 || strip.PriceThree.Flag != procurement.MarginFlagBelowCost || strip.Flag != procurement.MarginFlagBelowCost {
+		t.Fatalf("SANMOL strip: expected below cost, got %+v", strip)
+	}
+	if strip.PriceTwo != (procurement.PriceMargin{}) {
+		t.Fatalf("SANMOL strip price two: expected unset, got %+v", strip.PriceTwo)
+	}
+	if box.Cost != 40000 || box.PriceOne.Flag != procurement.MarginFlagNone || box.LastProcurement.InvoiceNumber != "INV-2" {
+		t.Fatalf("SANMOL box: got %+v", box)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	handler := procurement.NewApiHandler(service, 15)
+
+	// Mirrors the route registration in proxy/api.go, without auth middleware.
+	router.GET("/procurements/margins", handler.GetMargins)
+	router.GET("/procurements/margins/xlsx", handler.ExportMargins)
+
+	code, body := do(router, "GET", "/procurements/margins?flaggedOnly=true")
+	if code != 200 {
+		t.Fatalf("flagged margins: got code %d, body %s", code, body)
+	}
+
+	table := unmarshal[cui.Table](t, body)
+	if len(table.Rows) != 2 {
+		t.Fatalf("flagged margins: expected the SANMOL tablet and strip, got %s", body)
+	}
+	if table.Rows[1].Columns[5] != "Rp 3.900 (-2.6%)" || table.Rows[1].Columns[6] != "Di Bawah Modal" {
+		t.Fatalf("flagged margins: unexpected strip row %v", table.Rows[1].Columns)
+	}
+
+	code, body = do(router, "GET", "/procurements/margins?flaggedOnly=true&minMarginPercentage=0")
+	if code != 200 || len(unmarshal[cui.Table](t, body).Rows) != 1 {
+		t.Fatalf("flagged margins with overridden floor: got code %d, body %s", code, body)
+	}
+
+	code, body = do(router, "GET", "/procurements/margins?minMarginPercentage=abc")
+	if code != 400 {
+		t.Fatalf("invalid floor: got code %d, body %s", code, body)
+	}
+
+	req := httptest.NewRequest("GET", "/procurements/margins/xlsx", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != 200 {
+		t.Fatalf("export margins: got code %d, body %s", w.Code, w.Body.String())
+	}
+
+	f, err := excelize.OpenReader(bytes.NewReader(w.Body.Bytes()))
+	if err != nil {
+		t.Fatalf("open exported margins: %s", err)
+	}
+	defer f.Close()
+
+	rows, err := f.GetRows("Margin")
+	if err != nil {
+		t.Fatalf("read exported margins: %s", err)
+	}
+	if len(rows) != 5 || rows[3][2] != "Strip" || rows[3][10] != "Di Bawah Modal" {
+		t.Fatalf("export margins: got rows %v", rows)
+	}
+}
@@ -51,11 +51,112 @@ type RecommendationStatusResponse struct {
 	Status RecommendationStatus `json:"status"`
 }
 
+// RecommendationStrategy is the way the recommended procurement quantities are calculated.
+type RecommendationStrategy string
+
+const (
+	// RecommendationStrategyMinimumStock orders twice the minimum stock minus the current stock.
+	RecommendationStrategyMinimumStock RecommendationStrategy = "MINIMUM_STOCK"
+	// RecommendationStrategyVelocity orders up to the expected demand until the next delivery after the next review,
+	// plus a safety stock for the demand variability, once the stock falls to the reorder point.
+	RecommendationStrategyVelocity RecommendationStrategy = "VELOCITY"
+)
+
+// DumpRecommendationsRequest is the request schema for the procurement recommendations dump API.
+// Empty fields fall back to the defaults of RecommendationOptions.
+type DumpRecommendationsRequest struct {
+	Strategy         RecommendationStrategy `json:"strategy"`
+	HistoryDays      int                    `json:"historyDays"`
+	ReviewPeriodDays float64                `json:"reviewPeriodDays"`
+	ServiceLevel     float64                `json:"serviceLevel"`
+}
+
+// Options returns the recommendation options of the request.
+func (r DumpRecommendationsRequest) Options() RecommendationOptions {
+	return RecommendationOptions{
+		Strategy:         r.Strategy,
+		HistoryDays:      r.HistoryDays,
+		ReviewPeriodDays: r.ReviewPeriodDays,
+		ServiceLevel:     r.ServiceLevel,
+	}
+}
+
+// RecommendationOptions selects the recommendation strategy and its parameters.
+// The parameters other than the strategy are only used by RecommendationStrategyVelocity.
+type RecommendationOptions struct {
+	// Strategy defaults to RecommendationStrategyMinimumStock.
+	Strategy RecommendationStrategy
+
+	// HistoryDays is the number of the last days of sales the velocity is measured from, defaulting to 28 days.
+	HistoryDays int
+
+	// ReviewPeriodDays is the number of days between two orders to the same supplier, defaulting to 7 days.
+	ReviewPeriodDays float64
+
+	// ServiceLevel is the targeted probability of not running out of stock before the next delivery,
+	// between 0.5 and 0.999, defaulting to 0.95.
+	ServiceLevel float64
+}
+
 type Recommendation struct {
 	DrugStock    drug.WithStock `json:",inline"`
 	FromSupplier string         `json:"fromSupplier,omitempty"`
 	Procurement  drug.Stock     `json:"procurement"`
 	Alternatives []drug.Stock   `json:"alternatives,omitempty"`
+
+	Strategy RecommendationStrategy `json:"strategy,omitempty"`
+
+	// Reasoning explains how the procurement quantity was calculated.
+	Reasoning string `json:"reasoning,omitempty"`
+
+	// Velocity is the calculation of RecommendationStrategyVelocity, nil for the other strategies
+	// and for drugs without sales, which fall back to RecommendationStrategyMinimumStock.
+	Velocity *VelocityCalculation `json:"velocity,omitempty"`
+
+	// CheaperSupplier is the supplier that sold the drug cheaper than FromSupplier recently,
+	// nil if there is none or if the drug wasn't procured from FromSupplier recently.
+	CheaperSupplier *SupplierSuggestion `json:"cheaperSupplier,omitempty"`
+}
+
+// VelocityCalculation is the calculation of a recommendation by its sales velocity, in the smallest unit of the drug.
+type VelocityCalculation struct {
+	Unit string `json:"unit"`
+
+	// AverageDailyDemand and DemandStandardDeviation are the mean and the standard deviation of the daily sold quantities.
+	AverageDailyDemand      float64 `json:"averageDailyDemand"`
+	DemandStandardDeviation float64 `json:"demandStandardDeviation"`
+
+	LeadTimeDays     float64 `json:"leadTimeDays"`
+	ReviewPeriodDays float64 `json:"reviewPeriodDays"`
+	ServiceLevel     float64 `json:"serviceLevel"`
+
+	// SafetyStock covers the demand variability during the lead time and the review period.
+	SafetyStock float64 `json:"safetyStock"`
+
+	// ReorderPoint is the stock at which the drug must be ordered to last until the delivery.
+	ReorderPoint float64 `json:"reorderPoint"`
+
+	// TargetStock is the stock to order up to, lasting until the delivery after the next review.
+	TargetStock float64 `json:"targetStock"`
+
+	CurrentStock float64 `json:"currentStock"`
+
+	// OrderQuantity is the target stock minus the current stock, before rounding into purchasable units.
+	OrderQuantity float64 `json:"orderQuantity"`
+}
+
+// SupplierLeadTime is the number of days a supplier takes to deliver an order.
+type SupplierLeadTime struct {
+	Supplier string  `json:"supplier" binding:"required"`
+	Days     float64 `json:"days"`
+}
+
+// FromDBSupplierLeadTime converts models.SupplierLeadTime to SupplierLeadTime.
+func FromDBSupplierLeadTime(leadTime models.SupplierLeadTime) SupplierLeadTime {
+	return SupplierLeadTime{
+		Supplier: leadTime.Supplier,
+		Days:     leadTime.Days,
+	}
 }
 
 // InvoiceCalculatorsResponse is the response schema for the invoice calculators API.
@@ -124,8 +225,55 @@ type DrugProcurement struct {
 	Amount         float64   `json:"amount"`
 	Unit           string    `json:"unit"`
 	TotalUnitPrice float64   `json:"totalUnitPrice"`
+	UnitTaxedPrice float64   `json:"unitTaxedPrice"`
 
 	InvoiceNumber string    `json:"invoiceNumber"`
 	InvoiceDate   time.Time `json:"invoiceDate"`
 	Supplier      string    `json:"supplier"`
 }
+
+// MarginsRequest is the request schema for the margin report APIs.
+type MarginsRequest struct {
+	// MinMarginPercentage overrides the configured margin floor when set.
+	MinMarginPercentage *float64 `json:"minMarginPercentage" form:"minMarginPercentage"`
+
+	// FlaggedOnly only returns the units selling below cost or below the margin floor.
+	FlaggedOnly bool `json:"flaggedOnly" form:"flaggedOnly"`
+}
+
+// MarginFlag marks a unit whose prices don't cover its cost well enough.
+type MarginFlag string
+
+const (
+	MarginFlagNone       MarginFlag = ""
+	MarginFlagBelowFloor MarginFlag = "BELOW_FLOOR"
+	MarginFlagBelowCost  MarginFlag = "BELOW_COST"
+)
+
+// DrugUnitMargin is the margin of a drug unit, based on the latest procurement of the drug.
+type DrugUnitMargin struct {
+	DrugCode string `json:"drugCode"`
+	DrugName string `json:"drugName"`
+	Unit     string `json:"unit"`
+
+	// Cost is the latest procurement price converted into the unit.
+	Cost float64 `json:"cost"`
+
+	// PriceOne, PriceTwo, and PriceThree are the margins of the price tiers of drug.Unit.
+	PriceOne   PriceMargin `json:"priceOne"`
+	PriceTwo   PriceMargin `json:"priceTwo"`
+	PriceThree PriceMargin `json:"priceThree"`
+
+	// Flag is the worst flag of the price tiers.
+	Flag MarginFlag `json:"flag,omitempty"`
+
+	LastProcurement DrugProcurement `json:"lastProcurement"`
+}
+
+// PriceMargin is the margin of a sale price over the cost.
+// Prices that are not set have no margin and are never flagged.
+type PriceMargin struct {
+	Price            float64    `json:"price"`
+	MarginPercentage float64    `json:"marginPercentage"`
+	Flag             MarginFlag `json:"flag,omitempty"`
+}
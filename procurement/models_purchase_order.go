@@ -0,0 +1,87 @@
+package procurement
+
+import (
+	"time"
+
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+	"github.com/turfaa/vmedis-proxy-api/pkg2/slices2"
+)
+
+type PurchaseOrder struct {
+	ID         uint                       `json:"id"`
+	Supplier   string                     `json:"supplier"`
+	Status     models.PurchaseOrderStatus `json:"status"`
+	CreatedBy  string                     `json:"createdBy"`
+	CreatedAt  time.Time                  `json:"createdAt"`
+	SentAt     *time.Time                 `json:"sentAt,omitempty"`
+	SentBy     string                     `json:"sentBy,omitempty"`
+	ReceivedAt *time.Time                 `json:"receivedAt,omitempty"`
+	Lines      []PurchaseOrderLine        `json:"lines"`
+}
+
+func FromDBPurchaseOrder(order models.PurchaseOrder) PurchaseOrder {
+	return PurchaseOrder{
+		ID:         order.ID,
+		Supplier:   order.Supplier,
+		Status:     order.Status,
+		CreatedBy:  order.CreatedBy,
+		CreatedAt:  order.CreatedAt,
+		SentAt:     order.SentAt,
+		SentBy:     order.SentBy,
+		ReceivedAt: order.ReceivedAt,
+		Lines:      slices2.Map(order.Lines, FromDBPurchaseOrderLine),
+	}
+}
+
+type PurchaseOrderLine struct {
+	ID               uint    `json:"id"`
+	DrugCode         string  `json:"drugCode"`
+	DrugName         string  `json:"drugName"`
+	Unit             string  `json:"unit"`
+	Quantity         float64 `json:"quantity"`
+	ReceivedQuantity float64 `json:"receivedQuantity"`
+	Reasoning        string  `json:"reasoning,omitempty"`
+}
+
+// Received returns whether the ordered quantity has been received.
+func (l PurchaseOrderLine) Received() bool {
+	return l.ReceivedQuantity >= l.Quantity
+}
+
+func FromDBPurchaseOrderLine(line models.PurchaseOrderLine) PurchaseOrderLine {
+	return PurchaseOrderLine{
+		ID:               line.ID,
+		DrugCode:         line.DrugCode,
+		DrugName:         line.DrugName,
+		Unit:             line.Unit,
+		Quantity:         line.Quantity,
+		ReceivedQuantity: line.ReceivedQuantity,
+		Reasoning:        line.Reasoning,
+	}
+}
+
+// PurchaseOrderLineRequest changes the ordered quantity of a line of a draft purchase order.
+type PurchaseOrderLineRequest struct {
+	Quantity float64 `json:"quantity" binding:"required"`
+
+	// Unit is one of the units of the drug, keeping the unit of the line if empty.
+	Unit string `json:"unit"`
+}
+
+type PurchaseOrderResponse struct {
+	PurchaseOrder PurchaseOrder `json:"purchaseOrder"`
+}
+
+type PurchaseOrdersResponse struct {
+	PurchaseOrders []PurchaseOrder `json:"purchaseOrders"`
+}
+
+type PurchaseOrderLineResponse struct {
+	Line PurchaseOrderLine `json:"line"`
+}
+
+// ReceivePurchaseOrdersResponse is the result of matching the procurements with the sent purchase orders.
+type ReceivePurchaseOrdersResponse struct {
+	// MatchedProcurementUnits is the number of procurement units newly matched with the purchase order lines.
+	MatchedProcurementUnits int `json:"matchedProcurementUnits"`
+}
@@ -0,0 +1,51 @@
+package procurement
+
+import "time"
+
+// SupplierPrice is the procurement prices of a drug from a supplier over a window,
+// normalised to the smallest unit of the drug.
+// The prices are the unit taxed prices, falling back to the discounted unit prices.
+type SupplierPrice struct {
+	Supplier string `json:"supplier"`
+	Unit     string `json:"unit"`
+
+	// EffectivePrice is the average price weighted by the procured quantity and the recency,
+	// halving the weight of a procurement every 30 days, so the suppliers are compared by their recent prices.
+	EffectivePrice float64 `json:"effectivePrice"`
+
+	LastPrice    float64 `json:"lastPrice"`
+	MinPrice     float64 `json:"minPrice"`
+	AveragePrice float64 `json:"averagePrice"`
+
+	// LastDiscountPercentage and AverageDiscountPercentage are the combined discounts of the procurement units.
+	LastDiscountPercentage    float64 `json:"lastDiscountPercentage"`
+	AverageDiscountPercentage float64 `json:"averageDiscountPercentage"`
+
+	LastInvoiceDate  time.Time `json:"lastInvoiceDate"`
+	ProcurementCount int       `json:"procurementCount"`
+
+	// ProcuredQuantity is the sum of the procured quantities, in the smallest unit.
+	ProcuredQuantity float64 `json:"procuredQuantity"`
+}
+
+// SupplierSuggestion suggests a cheaper supplier for a recommended drug.
+type SupplierSuggestion struct {
+	Supplier string `json:"supplier"`
+	Unit     string `json:"unit"`
+
+	// EffectivePrice is the effective price of the suggested supplier,
+	// and CurrentEffectivePrice is the effective price of the supplier the drug is recommended from.
+	EffectivePrice        float64 `json:"effectivePrice"`
+	CurrentEffectivePrice float64 `json:"currentEffectivePrice"`
+
+	// SavingsPercentage is how much cheaper the suggested supplier is.
+	SavingsPercentage float64 `json:"savingsPercentage"`
+}
+
+// DrugSupplierPricesRequest is the request schema of the supplier price comparison API.
+type DrugSupplierPricesRequest struct {
+	DrugCode string `json:"drugCode" uri:"drug_code"`
+
+	// Days is the length of the window in days, defaulting to DefaultSupplierPriceWindowDays.
+	Days int `json:"days" form:"days"`
+}
@@ -0,0 +1,507 @@
+package procurement
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm"
+
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+	"github.com/turfaa/vmedis-proxy-api/drug"
+	"github.com/turfaa/vmedis-proxy-api/pkg2/slices2"
+)
+
+const purchaseOrderSheetName = "Purchase Order"
+
+var (
+	// ErrPurchaseOrderNotDraft is returned when a purchase order that's already sent is changed or sent again.
+	ErrPurchaseOrderNotDraft = errors.New("purchase order is already sent")
+
+	// ErrInvalidPurchaseOrder is returned when a purchase order or one of its lines is invalid.
+	ErrInvalidPurchaseOrder = errors.New("invalid purchase order")
+)
+
+// GetPurchaseOrders returns the latest purchase orders of the status, or of all statuses if it's empty, newest first.
+func (s *Service) GetPurchaseOrders(ctx context.Context, status models.PurchaseOrderStatus) ([]PurchaseOrder, error) {
+	switch status {
+	case "",
+		models.PurchaseOrderStatusDraft,
+		models.PurchaseOrderStatusSent,
+		models.PurchaseOrderStatusPartiallyReceived,
+		models.PurchaseOrderStatusReceived:
+	default:
+		return nil, fmt.Errorf("%w: unknown status %q", ErrInvalidPurchaseOrder, status)
+	}
+
+	orders, err := s.db.GetPurchaseOrders(ctx, status)
+	if err != nil {
+		return nil, fmt.Errorf("get purchase orders: %w", err)
+	}
+
+	return slices2.Map(orders, FromDBPurchaseOrder), nil
+}
+
+// GetPurchaseOrder returns the purchase order with its lines.
+func (s *Service) GetPurchaseOrder(ctx context.Context, id uint) (PurchaseOrder, error) {
+	order, err := s.db.GetPurchaseOrder(ctx, id)
+	if err != nil {
+		return PurchaseOrder{}, fmt.Errorf("get purchase order %d: %w", id, err)
+	}
+
+	return FromDBPurchaseOrder(order), nil
+}
+
+// CreatePurchaseOrdersFromRecommendations creates a draft purchase order per supplier from the current recommendations,
+// returning the created and the extended purchase orders.
+func (s *Service) CreatePurchaseOrdersFromRecommendations(ctx context.Context, createdBy string) ([]PurchaseOrder, error) {
+	recommendations, err := s.redisDB.GetRecommendations(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get procurement recommendations from Redis: %w", err)
+	}
+
+	return s.createPurchaseOrders(ctx, recommendations.Recommendations, createdBy)
+}
+
+// createPurchaseOrders groups the recommendations by their suppliers into draft purchase orders.
+// The recommendations of a supplier with a draft purchase order are added to it,
+// keeping the lines of the drugs already in it, since they might have been edited.
+func (s *Service) createPurchaseOrders(ctx context.Context, recommendations []Recommendation, createdBy string) ([]PurchaseOrder, error) {
+	linesBySupplier := make(map[string][]models.PurchaseOrderLine)
+	for _, recommendation := range recommendations {
+		supplier := strings.TrimSpace(recommendation.FromSupplier)
+		if supplier == "" {
+			log.Printf("Skipping the recommendation of drug %s without a supplier", recommendation.DrugStock.Drug.VmedisCode)
+			continue
+		}
+
+		linesBySupplier[supplier] = append(linesBySupplier[supplier], models.PurchaseOrderLine{
+			DrugCode:  recommendation.DrugStock.Drug.VmedisCode,
+			DrugName:  recommendation.DrugStock.Drug.Name,
+			Unit:      recommendation.Procurement.Unit,
+			Quantity:  recommendation.Procurement.Quantity,
+			Reasoning: recommendation.Reasoning,
+		})
+	}
+
+	suppliers := make([]string, 0, len(linesBySupplier))
+	for supplier := range linesBySupplier {
+		suppliers = append(suppliers, supplier)
+	}
+	slices.Sort(suppliers)
+
+	orderIDs := make([]uint, 0, len(suppliers))
+	if err := s.db.Transaction(ctx, func(ctx context.Context, tx *Database) error {
+		for _, supplier := range suppliers {
+			lines := linesBySupplier[supplier]
+
+			id, err := tx.GetDraftPurchaseOrderIDOfSupplier(ctx, supplier)
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				order := models.PurchaseOrder{
+					Supplier:  supplier,
+					Status:    models.PurchaseOrderStatusDraft,
+					CreatedBy: createdBy,
+					Lines:     lines,
+				}
+
+				if err := tx.CreatePurchaseOrder(ctx, &order); err != nil {
+					return fmt.Errorf("create purchase order of %s: %w", supplier, err)
+				}
+
+				orderIDs = append(orderIDs, order.ID)
+				continue
+			}
+			if err != nil {
+				return err
+			}
+
+			for i := range lines {
+				lines[i].PurchaseOrderID = id
+			}
+
+			if err := tx.AddPurchaseOrderLines(ctx, lines); err != nil {
+				return fmt.Errorf("add lines to purchase order %d of %s: %w", id, supplier, err)
+			}
+
+			orderIDs = append(orderIDs, id)
+		}
+
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("create purchase orders: %w", err)
+	}
+
+	orders := make([]PurchaseOrder, 0, len(orderIDs))
+	for _, id := range orderIDs {
+		order, err := s.GetPurchaseOrder(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		orders = append(orders, order)
+	}
+
+	return orders, nil
+}
+
+// UpdatePurchaseOrderLine changes the ordered quantity, and optionally the unit, of a line of a draft purchase order.
+func (s *Service) UpdatePurchaseOrderLine(ctx context.Context, orderID uint, lineID uint, request PurchaseOrderLineRequest) (PurchaseOrderLine, error) {
+	order, err := s.getDraftPurchaseOrder(ctx, orderID)
+	if err != nil {
+		return PurchaseOrderLine{}, err
+	}
+
+	lineIndex := slices.IndexFunc(order.Lines, func(line PurchaseOrderLine) bool { return line.ID == lineID })
+	if lineIndex < 0 {
+		return PurchaseOrderLine{}, fmt.Errorf("get purchase order line %d: %w", lineID, gorm.ErrRecordNotFound)
+	}
+	line := order.Lines[lineIndex]
+
+	if request.Quantity <= 0 || math.IsInf(request.Quantity, 0) || math.IsNaN(request.Quantity) {
+		return PurchaseOrderLine{}, fmt.Errorf("%w: quantity must be positive", ErrInvalidPurchaseOrder)
+	}
+
+	unit := line.Unit
+	if requested := strings.TrimSpace(request.Unit); requested != "" {
+		unit, err = s.purchaseOrderUnit(ctx, line.DrugCode, requested)
+		if err != nil {
+			return PurchaseOrderLine{}, err
+		}
+	}
+
+	if err := s.db.UpdatePurchaseOrderLine(ctx, orderID, lineID, request.Quantity, unit); err != nil {
+		return PurchaseOrderLine{}, fmt.Errorf("update purchase order line: %w", err)
+	}
+
+	line.Quantity = request.Quantity
+	line.Unit = unit
+	return line, nil
+}
+
+// purchaseOrderUnit returns the unit of the drug with the requested name, ignoring the case.
+func (s *Service) purchaseOrderUnit(ctx context.Context, drugCode string, requested string) (string, error) {
+	unitsByDrugCode, err := s.drugUnitsGetter.GetDrugUnitsByDrugVmedisCodes(ctx, []string{drugCode})
+	if err != nil {
+		return "", fmt.Errorf("get units of drug %s: %w", drugCode, err)
+	}
+
+	for _, unit := range unitsByDrugCode[drugCode] {
+		if strings.EqualFold(unit.Unit, requested) {
+			return unit.Unit, nil
+		}
+	}
+
+	return "", fmt.Errorf("%w: unit %q isn't a unit of drug %s", ErrInvalidPurchaseOrder, requested, drugCode)
+}
+
+func (s *Service) DeletePurchaseOrderLine(ctx context.Context, orderID uint, lineID uint) error {
+	if _, err := s.getDraftPurchaseOrder(ctx, orderID); err != nil {
+		return err
+	}
+
+	if err := s.db.DeletePurchaseOrderLine(ctx, orderID, lineID); err != nil {
+		return fmt.Errorf("delete purchase order line: %w", err)
+	}
+
+	return nil
+}
+
+// DeletePurchaseOrder deletes a draft purchase order.
+func (s *Service) DeletePurchaseOrder(ctx context.Context, id uint) error {
+	if _, err := s.getDraftPurchaseOrder(ctx, id); err != nil {
+		return err
+	}
+
+	deleted, err := s.db.DeleteDraftPurchaseOrder(ctx, id)
+	if err != nil {
+		return fmt.Errorf("delete purchase order: %w", err)
+	}
+
+	if !deleted {
+		return ErrPurchaseOrderNotDraft
+	}
+
+	return nil
+}
+
+// SendPurchaseOrder marks the draft purchase order as sent to its supplier, after which its lines can't be changed
+// and the procurements from its supplier are matched with it.
+func (s *Service) SendPurchaseOrder(ctx context.Context, id uint, sentBy string) (PurchaseOrder, error) {
+	order, err := s.getDraftPurchaseOrder(ctx, id)
+	if err != nil {
+		return PurchaseOrder{}, err
+	}
+
+	if len(order.Lines) == 0 {
+		return PurchaseOrder{}, fmt.Errorf("%w: purchase order %d has no lines", ErrInvalidPurchaseOrder, id)
+	}
+
+	sent, err := s.db.SendPurchaseOrder(ctx, id, sentBy, time.Now())
+	if err != nil {
+		return PurchaseOrder{}, fmt.Errorf("send purchase order: %w", err)
+	}
+
+	if !sent {
+		return PurchaseOrder{}, ErrPurchaseOrderNotDraft
+	}
+
+	log.Printf("Purchase order %d to %s sent by %s", id, order.Supplier, sentBy)
+	return s.GetPurchaseOrder(ctx, id)
+}
+
+func (s *Service) getDraftPurchaseOrder(ctx context.Context, id uint) (PurchaseOrder, error) {
+	order, err := s.GetPurchaseOrder(ctx, id)
+	if err != nil {
+		return PurchaseOrder{}, err
+	}
+
+	if order.Status != models.PurchaseOrderStatusDraft {
+		return PurchaseOrder{}, ErrPurchaseOrderNotDraft
+	}
+
+	return order, nil
+}
+
+// ReceivePurchaseOrders matches the unmatched procurement units with the lines of the sent purchase orders
+// of the same supplier and drug, returning the number of matched procurement units.
+//
+// A procurement unit is matched with the earliest sent order that was sent on or before its invoice date
+// and hasn't received the ordered quantity of the drug yet. Its amount is converted into the unit of the line,
+// and a procurement unit in a unit that can't be converted is left unmatched.
+// An order is received once every line has received its ordered quantity, and partially received before that.
+func (s *Service) ReceivePurchaseOrders(ctx context.Context) (int, error) {
+	orders, err := s.db.GetOpenPurchaseOrders(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("get open purchase orders: %w", err)
+	}
+
+	if len(orders) == 0 {
+		return 0, nil
+	}
+
+	drugCodes := make([]string, 0)
+	ordersBySupplier := make(map[string][]*models.PurchaseOrder)
+	var suppliers []string
+	for i := range orders {
+		order := &orders[i]
+		if _, ok := ordersBySupplier[order.Supplier]; !ok {
+			suppliers = append(suppliers, order.Supplier)
+		}
+		ordersBySupplier[order.Supplier] = append(ordersBySupplier[order.Supplier], order)
+
+		for _, line := range order.Lines {
+			drugCodes = append(drugCodes, line.DrugCode)
+		}
+	}
+
+	unitsByDrugCode, err := s.drugUnitsGetter.GetDrugUnitsByDrugVmedisCodes(ctx, drugCodes)
+	if err != nil {
+		return 0, fmt.Errorf("get units of the ordered drugs: %w", err)
+	}
+
+	now := time.Now()
+
+	var (
+		receipts      []models.PurchaseOrderReceipt
+		changedOrders []models.PurchaseOrder
+	)
+	for _, supplier := range suppliers {
+		supplierOrders := ordersBySupplier[supplier]
+
+		procurements, err := s.db.GetUnmatchedProcurementsOfSupplierSince(ctx, supplier, beginningOfDay(*supplierOrders[0].SentAt))
+		if err != nil {
+			return 0, fmt.Errorf("get unmatched procurements of %s: %w", supplier, err)
+		}
+
+		changed := make(map[uint]bool)
+		for _, p := range procurements {
+			invoiceDate := time.Time(p.InvoiceDate)
+
+			for _, unit := range p.ProcurementUnits {
+				orderID, receipt, ok := matchPurchaseOrderLine(supplierOrders, unit, invoiceDate, unitsByDrugCode[unit.DrugCode])
+				if !ok {
+					continue
+				}
+
+				receipts = append(receipts, receipt)
+				changed[orderID] = true
+			}
+		}
+
+		for _, order := range supplierOrders {
+			if !changed[order.ID] {
+				continue
+			}
+
+			order.Status = models.PurchaseOrderStatusReceived
+			for _, line := range order.Lines {
+				if line.ReceivedQuantity < line.Quantity {
+					order.Status = models.PurchaseOrderStatusPartiallyReceived
+					break
+				}
+			}
+
+			if order.Status == models.PurchaseOrderStatusReceived {
+				order.ReceivedAt = &now
+			}
+
+			changedOrders = append(changedOrders, *order)
+		}
+	}
+
+	if len(receipts) == 0 {
+		return 0, nil
+	}
+
+	if err := s.db.ReceivePurchaseOrderLines(ctx, receipts, changedOrders); err != nil {
+		return 0, fmt.Errorf("receive purchase order lines: %w", err)
+	}
+
+	log.Printf("Matched %d procurement units with %d purchase orders", len(receipts), len(changedOrders))
+	return len(receipts), nil
+}
+
+// matchPurchaseOrderLine finds the line of the orders to match the procurement unit with,
+// adding the procured amount to its received quantity.
+func matchPurchaseOrderLine(
+	orders []*models.PurchaseOrder,
+	unit models.ProcurementUnit,
+	invoiceDate time.Time,
+	drugUnits []drug.Unit,
+) (uint, models.PurchaseOrderReceipt, bool) {
+	for _, order := range orders {
+		if invoiceDate.Before(beginningOfDay(*order.SentAt)) {
+			continue
+		}
+
+		for i := range order.Lines {
+			line := &order.Lines[i]
+			if line.DrugCode != unit.DrugCode || line.ReceivedQuantity >= line.Quantity {
+				continue
+			}
+
+			quantity, ok := convertQuantity(unit.Amount, unit.Unit, line.Unit, drugUnits)
+			if !ok {
+				log.Printf("Can't convert %s of drug %s in procurement %s into %s of purchase order %d", unit.Unit, unit.DrugCode, unit.InvoiceNumber, line.Unit, order.ID)
+				continue
+			}
+
+			line.ReceivedQuantity = roundQuantity(line.ReceivedQuantity + quantity)
+			return order.ID, models.PurchaseOrderReceipt{
+				PurchaseOrderLineID: line.ID,
+				InvoiceNumber:       unit.InvoiceNumber,
+				IDInProcurement:     unit.IDInProcurement,
+				Quantity:            quantity,
+			}, true
+		}
+	}
+
+	return 0, models.PurchaseOrderReceipt{}, false
+}
+
+// convertQuantity converts the quantity in one unit of the drug into another.
+func convertQuantity(quantity float64, from string, to string, drugUnits []drug.Unit) (float64, bool) {
+	if strings.EqualFold(from, to) {
+		return quantity, true
+	}
+
+	sizes, ok := drug.UnitSizes(drugUnits)
+	if !ok {
+		return 0, false
+	}
+
+	fromSize, fromOK := sizes[strings.ToLower(from)]
+	toSize, toOK := sizes[strings.ToLower(to)]
+	if !fromOK || !toOK {
+		return 0, false
+	}
+
+	return roundQuantity(quantity * fromSize / toSize), true
+}
+
+// WritePurchaseOrderXLSX writes the lines of the purchase order as an XLSX file to be sent to its supplier.
+func WritePurchaseOrderXLSX(w io.Writer, order PurchaseOrder) error {
+	f := excelize.NewFile()
+	defer func() {
+		if err := f.Close(); err != nil {
+			log.Printf("failed to close file: %s", err)
+		}
+	}()
+
+	if _, err := f.NewSheet(purchaseOrderSheetName); err != nil {
+		return fmt.Errorf("create excel sheet: %w", err)
+	}
+
+	var errs []error
+
+	details := [][2]string{
+		{"No. PO", formatPurchaseOrderNumber(order.ID)},
+		{"Supplier", order.Supplier},
+		{"Tanggal", purchaseOrderDate(order).Format(time.DateOnly)},
+	}
+	for i, detail := range details {
+		errs = append(errs, f.SetCellStr(purchaseOrderSheetName, purchaseOrderCell(0, i+1), detail[0]))
+		errs = append(errs, f.SetCellStr(purchaseOrderSheetName, purchaseOrderCell(1, i+1), detail[1]))
+	}
+
+	headerRow := len(details) + 2
+	header := []string{
+		"No",
+		"Kode Obat",
+		"Nama Obat",
+		"Jumlah",
+		"Satuan",
+	}
+	for i, h := range header {
+		errs = append(errs, f.SetCellStr(purchaseOrderSheetName, purchaseOrderCell(i, headerRow), h))
+	}
+
+	for i, line := range order.Lines {
+		row := headerRow + i + 1
+
+		errs = append(errs, f.SetCellInt(purchaseOrderSheetName, purchaseOrderCell(0, row), int64(i+1)))
+		errs = append(errs, f.SetCellStr(purchaseOrderSheetName, purchaseOrderCell(1, row), line.DrugCode))
+		errs = append(errs, f.SetCellStr(purchaseOrderSheetName, purchaseOrderCell(2, row), line.DrugName))
+		errs = append(errs, f.SetCellFloat(purchaseOrderSheetName, purchaseOrderCell(3, row), line.Quantity, -1, 64))
+		errs = append(errs, f.SetCellStr(purchaseOrderSheetName, purchaseOrderCell(4, row), line.Unit))
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("fill purchase order sheet: %w", err)
+	}
+
+	if err := f.DeleteSheet("Sheet1"); err != nil {
+		return fmt.Errorf("delete default XLSX sheet: %w", err)
+	}
+
+	if err := f.Write(w); err != nil {
+		return fmt.Errorf("write XLSX: %w", err)
+	}
+
+	return nil
+}
+
+func purchaseOrderCell(column int, row int) string {
+	cell, _ := excelize.CoordinatesToCellName(column+1, row)
+	return cell
+}
+
+// purchaseOrderDate returns the date the purchase order was sent, or created if it's still a draft.
+func purchaseOrderDate(order PurchaseOrder) time.Time {
+	if order.SentAt != nil {
+		return order.SentAt.In(time.Local)
+	}
+
+	return order.CreatedAt.In(time.Local)
+}
+
+func formatPurchaseOrderNumber(id uint) string {
+	return fmt.Sprintf("PO-%06d", id)
+}
@@ -0,0 +1,110 @@
+package procurement
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+)
+
+const (
+	purchaseOrderMargin     = 15.0
+	purchaseOrderLineHeight = 7.0
+)
+
+// purchaseOrderColumns are the widths of the table columns of a purchase order, filling the width of A4 paper.
+var purchaseOrderColumns = []struct {
+	title string
+	width float64
+	align string
+}{
+	{"No", 10, "C"},
+	{"Kode Obat", 30, "L"},
+	{"Nama Obat", 90, "L"},
+	{"Jumlah", 25, "R"},
+	{"Satuan", 25, "L"},
+}
+
+// RenderPurchaseOrderPDF renders the purchase order as a printable A4 document to be sent to its supplier.
+func RenderPurchaseOrderPDF(w io.Writer, order PurchaseOrder) error {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(purchaseOrderMargin, purchaseOrderMargin, purchaseOrderMargin)
+	pdf.SetAutoPageBreak(true, purchaseOrderMargin)
+	pdf.SetTitle("Purchase Order "+formatPurchaseOrderNumber(order.ID), true)
+
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+
+	// The table header is repeated on every page.
+	pdf.SetHeaderFunc(func() {
+		if pdf.PageNo() > 1 {
+			renderPurchaseOrderTableHeader(pdf, tr)
+		}
+	})
+
+	pdf.AddPage()
+
+	pdf.SetFont("Helvetica", "B", 16)
+	pdf.CellFormat(0, 10, "PURCHASE ORDER", "", 1, "L", false, 0, "")
+
+	pdf.SetFont("Helvetica", "", 10)
+	for _, detail := range [][2]string{
+		{"No. PO", formatPurchaseOrderNumber(order.ID)},
+		{"Supplier", order.Supplier},
+		{"Tanggal", purchaseOrderDate(order).Format(time.DateOnly)},
+	} {
+		pdf.CellFormat(25, 6, tr(detail[0]), "", 0, "L", false, 0, "")
+		pdf.CellFormat(0, 6, tr(": "+detail[1]), "", 1, "L", false, 0, "")
+	}
+	pdf.Ln(4)
+
+	renderPurchaseOrderTableHeader(pdf, tr)
+
+	pdf.SetFont("Helvetica", "", 10)
+	for i, line := range order.Lines {
+		columns := []string{
+			strconv.Itoa(i + 1),
+			line.DrugCode,
+			line.DrugName,
+			strconv.FormatFloat(line.Quantity, 'f', -1, 64),
+			line.Unit,
+		}
+
+		for j, column := range purchaseOrderColumns {
+			pdf.CellFormat(column.width, purchaseOrderLineHeight, tr(fitPurchaseOrderText(pdf, columns[j], column.width)), "1", 0, column.align, false, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+
+	if err := pdf.Output(w); err != nil {
+		return fmt.Errorf("output PDF: %w", err)
+	}
+
+	return nil
+}
+
+func renderPurchaseOrderTableHeader(pdf *fpdf.Fpdf, tr func(string) string) {
+	pdf.SetFont("Helvetica", "B", 10)
+	pdf.SetFillColor(230, 230, 230)
+	for _, column := range purchaseOrderColumns {
+		pdf.CellFormat(column.width, purchaseOrderLineHeight, tr(column.title), "1", 0, "C", true, 0, "")
+	}
+	pdf.Ln(-1)
+	pdf.SetFont("Helvetica", "", 10)
+}
+
+// fitPurchaseOrderText cuts the text to fit the width of a cell, ending it with an ellipsis.
+func fitPurchaseOrderText(pdf *fpdf.Fpdf, text string, width float64) string {
+	maxWidth := width - 2*pdf.GetCellMargin()
+	if pdf.GetStringWidth(text) <= maxWidth {
+		return text
+	}
+
+	runes := []rune(text)
+	for len(runes) > 0 && pdf.GetStringWidth(string(runes)+"...") > maxWidth {
+		runes = runes[:len(runes)-1]
+	}
+
+	return string(runes) + "..."
+}
@@ -0,0 +1,205 @@
+package procurement
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+	"gorm.io/datatypes"
+
+	"github.com/turfaa/vmedis-proxy-api/database"
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+	"github.com/turfaa/vmedis-proxy-api/drug"
+)
+
+// TestPurchaseOrders checks that the recommendations are grouped by their suppliers into draft purchase orders,
+// that only drafts can be edited and sent, and that the procurements invoiced since an order was sent
+// are matched with its lines by drug code, converting their quantities into the units of the lines.
+func TestPurchaseOrders(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := database.SqliteDB(t.TempDir() + "/test.db")
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+
+	if err := db.Create(&[]models.Drug{
+		{VmedisID: 1, VmedisCode: "SANMOL", Name: "SANMOL 500MG"},
+		{VmedisID: 2, VmedisCode: "PROMAG", Name: "PROMAG"},
+	}).Error; err != nil {
+		t.Fatalf("create drugs: %v", err)
+	}
+	if err := db.Create(&[]models.DrugUnit{
+		{DrugVmedisCode: "SANMOL", Unit: "Strip"},
+		{DrugVmedisCode: "SANMOL", Unit: "Box", ParentUnit: "Strip", ConversionToParentUnit: 10, UnitOrder: 1},
+		{DrugVmedisCode: "PROMAG", Unit: "Strip"},
+	}).Error; err != nil {
+		t.Fatalf("create drug units: %v", err)
+	}
+
+	service := NewService(db, nil, nil, nil, drug.NewDatabase(db), nil)
+
+	newRecommendation := func(code string, supplier string, procurement drug.Stock) Recommendation {
+		return Recommendation{
+			DrugStock:    drug.WithStock{Drug: drug.Drug{VmedisCode: code, Name: code}},
+			FromSupplier: supplier,
+			Procurement:  procurement,
+		}
+	}
+
+	orders, err := service.createPurchaseOrders(ctx, []Recommendation{
+		newRecommendation("SANMOL", "PBF Alpha", drug.Stock{Unit: "Box", Quantity: 3}),
+		newRecommendation("PROMAG", "PBF Alpha", drug.Stock{Unit: "Strip", Quantity: 5}),
+		newRecommendation("OBH", "PBF Beta", drug.Stock{Unit: "Botol", Quantity: 2}),
+		newRecommendation("NOSUPPLIER", "", drug.Stock{Unit: "Tablet", Quantity: 1}),
+	}, "staff@example.com")
+	if err != nil {
+		t.Fatalf("createPurchaseOrders() error = %v", err)
+	}
+	if len(orders) != 2 || orders[0].Supplier != "PBF Alpha" || len(orders[0].Lines) != 2 || orders[1].Supplier != "PBF Beta" ||
+		orders[0].Status != models.PurchaseOrderStatusDraft || orders[0].CreatedBy != "staff@example.com" {
+		t.Fatalf("createPurchaseOrders() = %+v, want drafts for PBF Alpha and PBF Beta", orders)
+	}
+	alpha := orders[0]
+
+	// The drugs already in the draft keep their lines, the new ones are added to it.
+	orders, err = service.createPurchaseOrders(ctx, []Recommendation{
+		newRecommendation("SANMOL", "PBF Alpha", drug.Stock{Unit: "Box", Quantity: 4}),
+		newRecommendation("ANTIMO", "PBF Alpha", drug.Stock{Unit: "Strip", Quantity: 1}),
+	}, "staff@example.com")
+	if err != nil {
+		t.Fatalf("createPurchaseOrders() again error = %v", err)
+	}
+	if len(orders) != 1 || orders[0].ID != alpha.ID || len(orders[0].Lines) != 3 {
+		t.Fatalf("createPurchaseOrders() again = %+v, want the lines added to purchase order %d", orders, alpha.ID)
+	}
+	alpha = orders[0]
+	antimo, promag, sanmol := alpha.Lines[0], alpha.Lines[1], alpha.Lines[2]
+	if sanmol.DrugCode != "SANMOL" || sanmol.Quantity != 3 || sanmol.Unit != "Box" {
+		t.Fatalf("SANMOL line = %+v, want the 3 Box from the first recommendation", sanmol)
+	}
+
+	if _, err := service.UpdatePurchaseOrderLine(ctx, alpha.ID, sanmol.ID, PurchaseOrderLineRequest{Quantity: 3, Unit: "Pcs"}); !errors.Is(err, ErrInvalidPurchaseOrder) {
+		t.Errorf("UpdatePurchaseOrderLine() with an unknown unit error = %v, want ErrInvalidPurchaseOrder", err)
+	}
+	if _, err := service.UpdatePurchaseOrderLine(ctx, alpha.ID, sanmol.ID, PurchaseOrderLineRequest{Quantity: -1}); !errors.Is(err, ErrInvalidPurchaseOrder) {
+		t.Errorf("UpdatePurchaseOrderLine() with a negative quantity error = %v, want ErrInvalidPurchaseOrder", err)
+	}
+	if line, err := service.UpdatePurchaseOrderLine(ctx, alpha.ID, sanmol.ID, PurchaseOrderLineRequest{Quantity: 30, Unit: "strip"}); err != nil || line.Quantity != 30 || line.Unit != "Strip" {
+		t.Fatalf("UpdatePurchaseOrderLine() = %+v, %v, want 30 Strip", line, err)
+	}
+	if err := service.DeletePurchaseOrderLine(ctx, alpha.ID, antimo.ID); err != nil {
+		t.Fatalf("DeletePurchaseOrderLine() error = %v", err)
+	}
+
+	alpha, err = service.SendPurchaseOrder(ctx, alpha.ID, "admin@example.com")
+	if err != nil {
+		t.Fatalf("SendPurchaseOrder() error = %v", err)
+	}
+	if alpha.Status != models.PurchaseOrderStatusSent || alpha.SentAt == nil || alpha.SentBy != "admin@example.com" || len(alpha.Lines) != 2 {
+		t.Fatalf("SendPurchaseOrder() = %+v", alpha)
+	}
+
+	if _, err := service.SendPurchaseOrder(ctx, alpha.ID, "admin@example.com"); !errors.Is(err, ErrPurchaseOrderNotDraft) {
+		t.Errorf("SendPurchaseOrder() again error = %v, want ErrPurchaseOrderNotDraft", err)
+	}
+	if _, err := service.UpdatePurchaseOrderLine(ctx, alpha.ID, promag.ID, PurchaseOrderLineRequest{Quantity: 1}); !errors.Is(err, ErrPurchaseOrderNotDraft) {
+		t.Errorf("UpdatePurchaseOrderLine() of a sent order error = %v, want ErrPurchaseOrderNotDraft", err)
+	}
+	if err := service.DeletePurchaseOrder(ctx, alpha.ID); !errors.Is(err, ErrPurchaseOrderNotDraft) {
+		t.Errorf("DeletePurchaseOrder() of a sent order error = %v, want ErrPurchaseOrderNotDraft", err)
+	}
+
+	today := beginningOfDay(time.Now())
+	// Invoiced before the order was sent, from another supplier, or for the draft of PBF Beta, must not be matched.
+	if err := db.Create(&[]models.Procurement{
+		newTestProcurement("INV-OLD", today.AddDate(0, 0, -1), "PBF Alpha", models.ProcurementUnit{DrugCode: "SANMOL", Amount: 3, Unit: "Box"}),
+		newTestProcurement("INV-GAMMA", today, "PBF Gamma", models.ProcurementUnit{DrugCode: "SANMOL", Amount: 3, Unit: "Box"}),
+		newTestProcurement("INV-BETA", today, "PBF Beta", models.ProcurementUnit{DrugCode: "OBH", Amount: 2, Unit: "Botol"}),
+		newTestProcurement("INV-1", today, "PBF Alpha",
+			models.ProcurementUnit{DrugCode: "SANMOL", Amount: 1, Unit: "Box"},
+			models.ProcurementUnit{DrugCode: "ANTIMO", Amount: 1, Unit: "Strip"},
+		),
+	}).Error; err != nil {
+		t.Fatalf("create procurements: %v", err)
+	}
+
+	if matched, err := service.ReceivePurchaseOrders(ctx); err != nil || matched != 1 {
+		t.Fatalf("ReceivePurchaseOrders() = %d, %v, want 1 Box of SANMOL matched", matched, err)
+	}
+	if matched, err := service.ReceivePurchaseOrders(ctx); err != nil || matched != 0 {
+		t.Fatalf("ReceivePurchaseOrders() again = %d, %v, want nothing matched twice", matched, err)
+	}
+
+	alpha, err = service.GetPurchaseOrder(ctx, alpha.ID)
+	if err != nil {
+		t.Fatalf("GetPurchaseOrder() error = %v", err)
+	}
+	if alpha.Status != models.PurchaseOrderStatusPartiallyReceived || alpha.ReceivedAt != nil || alpha.Lines[1].ReceivedQuantity != 10 {
+		t.Fatalf("partially received purchase order = %+v, want 10 Strip of SANMOL received", alpha)
+	}
+
+	if err := db.Create(&[]models.Procurement{
+		newTestProcurement("INV-2", today, "PBF Alpha",
+			models.ProcurementUnit{DrugCode: "SANMOL", Amount: 20, Unit: "Strip"},
+			models.ProcurementUnit{DrugCode: "PROMAG", Amount: 5, Unit: "Strip"},
+		),
+	}).Error; err != nil {
+		t.Fatalf("create procurements: %v", err)
+	}
+
+	if matched, err := service.ReceivePurchaseOrders(ctx); err != nil || matched != 2 {
+		t.Fatalf("ReceivePurchaseOrders() = %d, %v, want the rest of the order matched", matched, err)
+	}
+
+	alpha, err = service.GetPurchaseOrder(ctx, alpha.ID)
+	if err != nil {
+		t.Fatalf("GetPurchaseOrder() error = %v", err)
+	}
+	if alpha.Status != models.PurchaseOrderStatusReceived || alpha.ReceivedAt == nil || !alpha.Lines[0].Received() || !alpha.Lines[1].Received() {
+		t.Fatalf("received purchase order = %+v", alpha)
+	}
+
+	beta, err := service.GetPurchaseOrders(ctx, models.PurchaseOrderStatusDraft)
+	if err != nil || len(beta) != 1 || beta[0].Supplier != "PBF Beta" || beta[0].Lines[0].ReceivedQuantity != 0 {
+		t.Fatalf("GetPurchaseOrders(DRAFT) = %+v, %v, want the unreceived draft of PBF Beta", beta, err)
+	}
+
+	var pdf bytes.Buffer
+	if err := RenderPurchaseOrderPDF(&pdf, alpha); err != nil || !bytes.HasPrefix(pdf.Bytes(), []byte("%PDF")) {
+		t.Errorf("RenderPurchaseOrderPDF() error = %v", err)
+	}
+
+	var xlsx bytes.Buffer
+	if err := WritePurchaseOrderXLSX(&xlsx, alpha); err != nil {
+		t.Fatalf("WritePurchaseOrderXLSX() error = %v", err)
+	}
+
+	f, err := excelize.OpenReader(&xlsx)
+	if err != nil {
+		t.Fatalf("open XLSX: %v", err)
+	}
+	rows, err := f.GetRows(purchaseOrderSheetName)
+	if err != nil {
+		t.Fatalf("get XLSX rows: %v", err)
+	}
+	if len(rows) != 7 || rows[1][1] != "PBF Alpha" || rows[6][2] != "SANMOL" || rows[6][3] != "30" || rows[6][4] != "Strip" {
+		t.Errorf("XLSX rows = %q", rows)
+	}
+}
+
+func newTestProcurement(invoiceNumber string, invoiceDate time.Time, supplier string, units ...models.ProcurementUnit) models.Procurement {
+	for i := range units {
+		units[i].IDInProcurement = i + 1
+	}
+
+	return models.Procurement{
+		InvoiceNumber:    invoiceNumber,
+		InvoiceDate:      datatypes.Date(invoiceDate),
+		Supplier:         supplier,
+		ProcurementUnits: units,
+	}
+}
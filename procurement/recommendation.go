@@ -0,0 +1,475 @@
+package procurement
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/turfaa/vmedis-proxy-api/drug"
+	vmedisv1 "github.com/turfaa/vmedis-proxy-api/vmedis/v1"
+)
+
+const (
+	DefaultRecommendationHistoryDays = 28
+	DefaultReviewPeriodDays          = 7
+	DefaultServiceLevel              = 0.95
+
+	// DefaultLeadTimeDays is the lead time of the suppliers without a lead time.
+	DefaultLeadTimeDays = 2
+
+	maxRecommendationHistoryDays = 365
+	maxReviewPeriodDays          = 90
+	minServiceLevel              = 0.5
+	maxServiceLevel              = 0.999
+	maxLeadTimeDays              = 90
+)
+
+var (
+	// ErrInvalidRecommendationRequest is returned when the recommendation options are invalid.
+	ErrInvalidRecommendationRequest = errors.New("invalid recommendation request")
+
+	// ErrInvalidLeadTime is returned when a supplier lead time is invalid.
+	ErrInvalidLeadTime = errors.New("invalid lead time")
+)
+
+// withDefaults fills the empty options with their defaults and validates them.
+func (o RecommendationOptions) withDefaults() (RecommendationOptions, error) {
+	if o.Strategy == "" {
+		o.Strategy = RecommendationStrategyMinimumStock
+	}
+
+	if o.HistoryDays == 0 {
+		o.HistoryDays = DefaultRecommendationHistoryDays
+	}
+
+	if o.ReviewPeriodDays == 0 {
+		o.ReviewPeriodDays = DefaultReviewPeriodDays
+	}
+
+	if o.ServiceLevel == 0 {
+		o.ServiceLevel = DefaultServiceLevel
+	}
+
+	switch o.Strategy {
+	case RecommendationStrategyMinimumStock, RecommendationStrategyVelocity:
+	default:
+		return RecommendationOptions{}, fmt.Errorf("%w: unknown strategy %q", ErrInvalidRecommendationRequest, o.Strategy)
+	}
+
+	if o.HistoryDays < 1 || o.HistoryDays > maxRecommendationHistoryDays {
+		return RecommendationOptions{}, fmt.Errorf("%w: history days must be between 1 and %d", ErrInvalidRecommendationRequest, maxRecommendationHistoryDays)
+	}
+
+	if o.ReviewPeriodDays <= 0 || o.ReviewPeriodDays > maxReviewPeriodDays {
+		return RecommendationOptions{}, fmt.Errorf("%w: review period days must be positive and at most %d", ErrInvalidRecommendationRequest, maxReviewPeriodDays)
+	}
+
+	if o.ServiceLevel < minServiceLevel || o.ServiceLevel > maxServiceLevel {
+		return RecommendationOptions{}, fmt.Errorf("%w: service level must be between %g and %g", ErrInvalidRecommendationRequest, minServiceLevel, maxServiceLevel)
+	}
+
+	return o, nil
+}
+
+// velocityCandidates returns the drugs considered by RecommendationStrategyVelocity: the out-of-stock drugs from Vmedis,
+// and the drugs in the database with any stock or sold in the history before now, with the supplier of their latest procurement.
+// The drugs from the database have all their stocks, which are totalled by withTotalStock.
+func (s *Service) velocityCandidates(
+	ctx context.Context,
+	oosDrugs []vmedisv1.DrugStock,
+	options RecommendationOptions,
+	now time.Time,
+) ([]vmedisv1.DrugStock, error) {
+	historyStart, historyEnd := recommendationHistory(options, now)
+
+	dbDrugs, err := s.db.GetStockedOrSoldDrugs(ctx, historyStart, historyEnd.Add(-time.Nanosecond))
+	if err != nil {
+		return nil, fmt.Errorf("get stocked or sold drugs: %w", err)
+	}
+
+	latestProcurements, err := s.db.GetLatestDrugProcurements(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get latest drug procurements: %w", err)
+	}
+
+	supplierByDrugCode := make(map[string]string, len(latestProcurements))
+	for _, p := range latestProcurements {
+		supplierByDrugCode[p.DrugCode] = p.Supplier
+	}
+
+	candidates := slices.Clone(oosDrugs)
+
+	oosDrugCodes := make(map[string]struct{}, len(oosDrugs))
+	for _, d := range oosDrugs {
+		oosDrugCodes[d.Drug.VmedisCode] = struct{}{}
+	}
+
+	for _, d := range dbDrugs {
+		// The out-of-stock drugs from Vmedis have more recent stocks.
+		if _, ok := oosDrugCodes[d.VmedisCode]; ok {
+			continue
+		}
+
+		stocks := make([]vmedisv1.Stock, len(d.Stocks))
+		for i, stock := range d.Stocks {
+			stocks[i] = vmedisv1.Stock{Unit: stock.Stock.Unit, Quantity: stock.Stock.Quantity}
+		}
+
+		candidates = append(candidates, vmedisv1.DrugStock{
+			Drug: vmedisv1.Drug{
+				VmedisID:     d.VmedisID,
+				VmedisCode:   d.VmedisCode,
+				KFACode:      d.KFACode,
+				Name:         d.Name,
+				Manufacturer: d.Manufacturer,
+				Supplier:     supplierByDrugCode[d.VmedisCode],
+				MinimumStock: vmedisv1.Stock{Unit: d.MinimumStock.Unit, Quantity: d.MinimumStock.Quantity},
+				Stocks:       stocks,
+			},
+		})
+	}
+
+	return candidates, nil
+}
+
+// recommend calculates the recommended procurement of each drug with the strategy of the options,
+// which must have been filled by withDefaults. The velocity is measured from the sales of the days before now.
+// Drugs above their reorder point aren't recommended by RecommendationStrategyVelocity.
+func (s *Service) recommend(
+	ctx context.Context,
+	drugStocks []vmedisv1.DrugStock,
+	drugUnitsByDrugCode map[string][]drug.Unit,
+	options RecommendationOptions,
+	now time.Time,
+) ([]Recommendation, error) {
+	if options.Strategy == RecommendationStrategyMinimumStock {
+		recommendations := make([]Recommendation, len(drugStocks))
+		for i, drugStock := range drugStocks {
+			recommendations[i] = minimumStockRecommendation(drugStock, drugUnitsByDrugCode[drugStock.Drug.VmedisCode])
+		}
+
+		return recommendations, nil
+	}
+
+	leadTimes, err := s.db.GetSupplierLeadTimes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get supplier lead times: %w", err)
+	}
+
+	leadTimeDays := make(map[string]float64, len(leadTimes))
+	for _, leadTime := range leadTimes {
+		leadTimeDays[leadTime.Supplier] = leadTime.Days
+	}
+
+	drugCodes := make([]string, len(drugStocks))
+	for i, d := range drugStocks {
+		drugCodes[i] = d.Drug.VmedisCode
+	}
+
+	historyStart, historyEnd := recommendationHistory(options, now)
+
+	soldUnits, err := s.db.GetSoldUnitsBetweenTime(ctx, drugCodes, historyStart, historyEnd.Add(-time.Nanosecond))
+	if err != nil {
+		return nil, fmt.Errorf("get sold units: %w", err)
+	}
+
+	soldUnitsByDrugCode := make(map[string][]soldUnit, len(drugCodes))
+	for _, sold := range soldUnits {
+		soldUnitsByDrugCode[sold.DrugCode] = append(soldUnitsByDrugCode[sold.DrugCode], sold)
+	}
+
+	var recommendations []Recommendation
+	for _, drugStock := range drugStocks {
+		code := drugStock.Drug.VmedisCode
+		drugStock = withTotalStock(drugStock, drugUnitsByDrugCode[code])
+
+		leadTime, ok := leadTimeDays[drugStock.Drug.Supplier]
+		if !ok {
+			leadTime = DefaultLeadTimeDays
+		}
+
+		recommendation, ok := velocityRecommendation(
+			drugStock,
+			drugUnitsByDrugCode[code],
+			dailyDemands(soldUnitsByDrugCode[code], drugUnitsByDrugCode[code], historyStart, options.HistoryDays),
+			leadTime,
+			options,
+		)
+		if ok {
+			recommendations = append(recommendations, recommendation)
+		}
+	}
+
+	return recommendations, nil
+}
+
+// recommendationHistory returns the start and the exclusive end of the days the velocity is measured from.
+func recommendationHistory(options RecommendationOptions, now time.Time) (time.Time, time.Time) {
+	historyEnd := beginningOfDay(now)
+	return historyEnd.AddDate(0, 0, -options.HistoryDays), historyEnd
+}
+
+// withTotalStock sets the stock of a drug from the database to the total of its stocks,
+// in the smallest unit if they're in several units. The stocks that can't be converted are left out.
+func withTotalStock(stock vmedisv1.DrugStock, drugUnits []drug.Unit) vmedisv1.DrugStock {
+	if len(stock.Drug.Stocks) == 0 {
+		return stock
+	}
+
+	stock.Stock = stock.Drug.Stocks[0]
+	if len(stock.Drug.Stocks) == 1 {
+		return stock
+	}
+
+	sizes, unit := unitSizes(stock, drugUnits)
+
+	total := 0.0
+	for _, s := range stock.Drug.Stocks {
+		total += s.Quantity * sizes[strings.ToLower(s.Unit)]
+	}
+
+	stock.Stock = vmedisv1.Stock{Unit: unit, Quantity: total}
+	return stock
+}
+
+func minimumStockRecommendation(stock vmedisv1.DrugStock, drugUnits []drug.Unit) Recommendation {
+	procurement, alternatives := calculateRecommendation(stock, drugUnits)
+
+	return Recommendation{
+		DrugStock:    drug.FromVmedisDrugStock(stock),
+		FromSupplier: stock.Drug.Supplier,
+		Procurement:  procurement,
+		Alternatives: alternatives,
+		Strategy:     RecommendationStrategyMinimumStock,
+		Reasoning: minimumStockReasoning(
+			drug.Stock{Unit: stock.Drug.MinimumStock.Unit, Quantity: stock.Drug.MinimumStock.Quantity},
+			drug.Stock{Unit: stock.Stock.Unit, Quantity: stock.Stock.Quantity},
+			procurement,
+		),
+	}
+}
+
+func minimumStockReasoning(minimumStock drug.Stock, currentStock drug.Stock, procurement drug.Stock) string {
+	return fmt.Sprintf("Stok minimum %s × 2 − stok sekarang %s, dipesan %s.", minimumStock, currentStock, procurement)
+}
+
+// velocityRecommendation orders the drug up to its target stock if its stock is at or below its reorder point,
+// with the daily demands in the smallest unit. It returns false if the drug doesn't need to be ordered.
+// Drugs without sales fall back to the minimum stock strategy if their stock is at or below their minimum stock.
+func velocityRecommendation(
+	stock vmedisv1.DrugStock,
+	drugUnits []drug.Unit,
+	demands []float64,
+	leadTimeDays float64,
+	options RecommendationOptions,
+) (Recommendation, bool) {
+	sizes, unit := unitSizes(stock, drugUnits)
+
+	sizeOf := func(unit string) float64 {
+		if size := sizes[strings.ToLower(unit)]; size > 0 {
+			return size
+		}
+
+		return 1
+	}
+
+	currentStock := stock.Stock.Quantity * sizeOf(stock.Stock.Unit)
+
+	average, standardDeviation := meanAndStandardDeviation(demands)
+	if average == 0 {
+		// Like minimumStockRecommendation, but in the smallest unit, as the stock may have been totalled in it.
+		minimumStock := stock.Drug.MinimumStock.Quantity * sizeOf(stock.Drug.MinimumStock.Unit)
+		if currentStock > minimumStock {
+			return Recommendation{}, false
+		}
+
+		// Ordering a larger unit may overshoot the order quantity by up to the minimum stock.
+		procurement, alternatives := roundIntoPurchasableUnits(max(minimumStock*2-currentStock, 1), unit, drugUnits, sizes, minimumStock)
+
+		return Recommendation{
+			DrugStock:    drug.FromVmedisDrugStock(stock),
+			FromSupplier: stock.Drug.Supplier,
+			Procurement:  procurement,
+			Alternatives: alternatives,
+			Strategy:     RecommendationStrategyMinimumStock,
+			Reasoning: fmt.Sprintf(
+				"Tidak terjual dalam %d hari terakhir. %s",
+				len(demands),
+				minimumStockReasoning(
+					drug.Stock{Unit: unit, Quantity: roundQuantity(minimumStock)},
+					drug.Stock{Unit: unit, Quantity: roundQuantity(currentStock)},
+					procurement,
+				),
+			),
+		}, true
+	}
+
+	z := math.Sqrt2 * math.Erfinv(2*options.ServiceLevel-1)
+	safetyStock := z * standardDeviation * math.Sqrt(leadTimeDays+options.ReviewPeriodDays)
+	reorderPoint := average*leadTimeDays + safetyStock
+	targetStock := reorderPoint + average*options.ReviewPeriodDays
+
+	if currentStock > reorderPoint {
+		return Recommendation{}, false
+	}
+
+	calculation := VelocityCalculation{
+		Unit:                    unit,
+		AverageDailyDemand:      average,
+		DemandStandardDeviation: standardDeviation,
+		LeadTimeDays:            leadTimeDays,
+		ReviewPeriodDays:        options.ReviewPeriodDays,
+		ServiceLevel:            options.ServiceLevel,
+		SafetyStock:             safetyStock,
+		ReorderPoint:            reorderPoint,
+		TargetStock:             targetStock,
+		CurrentStock:            currentStock,
+		OrderQuantity:           max(targetStock-currentStock, 1),
+	}
+
+	// Ordering a larger unit may overshoot the order quantity by up to the demand of a review period,
+	// which only delays the next order of the drug.
+	procurement, alternatives := roundIntoPurchasableUnits(calculation.OrderQuantity, unit, drugUnits, sizes, average*options.ReviewPeriodDays)
+
+	return Recommendation{
+		DrugStock:    drug.FromVmedisDrugStock(stock),
+		FromSupplier: stock.Drug.Supplier,
+		Procurement:  procurement,
+		Alternatives: alternatives,
+		Strategy:     RecommendationStrategyVelocity,
+		Reasoning:    velocityReasoning(calculation, len(demands), procurement),
+		Velocity:     &calculation,
+	}, true
+}
+
+// unitSizes returns the content of each unit of the drug in its smallest unit by the lowercased unit name, and the smallest unit.
+// Drugs without convertible units are counted in the unit of their stock.
+func unitSizes(stock vmedisv1.DrugStock, drugUnits []drug.Unit) (map[string]float64, string) {
+	if len(drugUnits) > 0 {
+		if sizes, ok := drug.UnitSizes(drugUnits); ok {
+			return sizes, drugUnits[0].Unit
+		}
+	}
+
+	return map[string]float64{strings.ToLower(stock.Stock.Unit): 1}, stock.Stock.Unit
+}
+
+// roundIntoPurchasableUnits rounds the quantity in the smallest unit up into the largest unit
+// that overshoots it by at most the tolerance, with the other units as the alternatives.
+func roundIntoPurchasableUnits(
+	quantity float64,
+	smallestUnit string,
+	drugUnits []drug.Unit,
+	sizes map[string]float64,
+	tolerance float64,
+) (chosen drug.Stock, alternatives []drug.Stock) {
+	if len(drugUnits) == 0 || len(sizes) != len(drugUnits) {
+		return drug.Stock{Unit: smallestUnit, Quantity: math.Ceil(quantity)}, nil
+	}
+
+	foundChosen := false
+	for i := len(drugUnits) - 1; i >= 0; i-- {
+		size := sizes[strings.ToLower(drugUnits[i].Unit)]
+		rounded := math.Ceil(quantity/size - 1e-9)
+
+		proc := drug.Stock{
+			Unit:     drugUnits[i].Unit,
+			Quantity: rounded,
+		}
+
+		if !foundChosen && (rounded*size-quantity <= tolerance || i == 0) {
+			foundChosen = true
+			chosen = proc
+		} else {
+			alternatives = append(alternatives, proc)
+		}
+	}
+
+	return chosen, alternatives
+}
+
+// dailyDemands returns the sold quantities of each day of the history in the smallest unit.
+// Units that can't be converted are counted as they are.
+func dailyDemands(soldUnits []soldUnit, drugUnits []drug.Unit, historyStart time.Time, historyDays int) []float64 {
+	sizes, _ := drug.UnitSizes(drugUnits)
+
+	demands := make([]float64, historyDays)
+	for _, sold := range soldUnits {
+		day := int(beginningOfDay(sold.SoldAt).Sub(historyStart).Round(24*time.Hour) / (24 * time.Hour))
+		if day < 0 || day >= historyDays {
+			continue
+		}
+
+		size := sizes[strings.ToLower(sold.Unit)]
+		if size <= 0 {
+			size = 1
+		}
+
+		demands[day] += sold.Amount * size
+	}
+
+	return demands
+}
+
+func meanAndStandardDeviation(values []float64) (float64, float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+
+	mean := 0.0
+	for _, value := range values {
+		mean += value
+	}
+	mean /= float64(len(values))
+
+	variance := 0.0
+	for _, value := range values {
+		variance += (value - mean) * (value - mean)
+	}
+	variance /= float64(len(values))
+
+	return mean, math.Sqrt(variance)
+}
+
+func velocityReasoning(calculation VelocityCalculation, historyDays int, procurement drug.Stock) string {
+	quantity := func(q float64) string {
+		return drug.Stock{Unit: calculation.Unit, Quantity: roundQuantity(q)}.String()
+	}
+
+	return fmt.Sprintf(
+		"Rata-rata terjual %s/hari (simpangan baku %s) dalam %d hari terakhir. "+
+			"Dengan waktu kirim %s hari, siklus pemesanan %s hari, dan tingkat layanan %s%%, "+
+			"stok pengaman %s, titik pesan ulang %s, dan stok target %s. "+
+			"Stok sekarang %s, perlu %s, dipesan %s.",
+		quantity(calculation.AverageDailyDemand),
+		quantity(calculation.DemandStandardDeviation),
+		historyDays,
+		formatDays(calculation.LeadTimeDays),
+		formatDays(calculation.ReviewPeriodDays),
+		strconv.FormatFloat(calculation.ServiceLevel*100, 'f', -1, 64),
+		quantity(calculation.SafetyStock),
+		quantity(calculation.ReorderPoint),
+		quantity(calculation.TargetStock),
+		quantity(calculation.CurrentStock),
+		quantity(calculation.OrderQuantity),
+		procurement,
+	)
+}
+
+func roundQuantity(quantity float64) float64 {
+	return math.Round(quantity*100) / 100
+}
+
+func formatDays(days float64) string {
+	return strconv.FormatFloat(days, 'f', -1, 64)
+}
+
+func beginningOfDay(t time.Time) time.Time {
+	t = t.In(time.Local)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+}
@@ -0,0 +1,284 @@
+package procurement
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/turfaa/vmedis-proxy-api/database"
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+	"github.com/turfaa/vmedis-proxy-api/drug"
+	vmedisv1 "github.com/turfaa/vmedis-proxy-api/vmedis/v1"
+)
+
+// TestRecommendByVelocity checks that the velocity strategy measures the daily demand in the smallest unit
+// from the sales before today, orders up to the target stock with the lead time of the supplier,
+// rounds the order into the largest unit that doesn't overshoot it by more than a review period,
+// skips the drugs above their reorder point, and falls back to the minimum stock for drugs without sales.
+func TestRecommendByVelocity(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := database.SqliteDB(t.TempDir() + "/test.db")
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+
+	service := NewService(db, nil, nil, nil, nil, nil)
+
+	if _, err := service.SetSupplierLeadTime(ctx, SupplierLeadTime{Supplier: "PBF Alpha", Days: 0}); !errors.Is(err, ErrInvalidLeadTime) {
+		t.Fatalf("SetSupplierLeadTime() with 0 days error = %v, want ErrInvalidLeadTime", err)
+	}
+	if _, err := service.SetSupplierLeadTime(ctx, SupplierLeadTime{Supplier: " PBF Alpha ", Days: 3}); err != nil {
+		t.Fatalf("SetSupplierLeadTime() error = %v", err)
+	}
+
+	now := time.Date(2026, 8, 8, 12, 0, 0, 0, time.Local)
+
+	// FAST sells 4 strips every other day of the last 28 days, so 20 tablets a day on average with a standard deviation of 20.
+	// STOCKED sells a tablet a day, and NONE and SLOW don't sell at all.
+	var sales []models.Sale
+	for day := 1; day <= 28; day++ {
+		soldAt := now.AddDate(0, 0, -day)
+
+		var units []models.SaleUnit
+		if day%2 == 0 {
+			units = append(units, models.SaleUnit{DrugCode: "FAST", Amount: 4, Unit: "Strip"})
+		}
+		units = append(units, models.SaleUnit{DrugCode: "STOCKED", Amount: 1, Unit: "Tablet"})
+
+		sales = append(sales, newTestSale(fmt.Sprintf("S-%d", day), soldAt, units...))
+	}
+	// Today and before the history, must be ignored.
+	sales = append(sales,
+		newTestSale("S-TODAY", now, models.SaleUnit{DrugCode: "FAST", Amount: 10, Unit: "Box"}),
+		newTestSale("S-OLD", now.AddDate(0, 0, -29), models.SaleUnit{DrugCode: "FAST", Amount: 10, Unit: "Box"}),
+	)
+	if err := db.Create(&sales).Error; err != nil {
+		t.Fatalf("create sales: %v", err)
+	}
+
+	tabletStripBox := []drug.Unit{
+		{Unit: "Tablet"},
+		{Unit: "Strip", ParentUnit: "Tablet", ConversionToParentUnit: 10, UnitOrder: 1},
+		{Unit: "Box", ParentUnit: "Strip", ConversionToParentUnit: 10, UnitOrder: 2},
+	}
+	unitsByDrugCode := map[string][]drug.Unit{
+		"FAST":    tabletStripBox,
+		"STOCKED": tabletStripBox,
+		"NONE":    tabletStripBox,
+		"SLOW":    tabletStripBox,
+	}
+
+	newDrugStock := func(code string, supplier string, minimumStock float64, stock vmedisv1.Stock) vmedisv1.DrugStock {
+		return vmedisv1.DrugStock{
+			Drug: vmedisv1.Drug{
+				VmedisCode:   code,
+				Name:         code,
+				Supplier:     supplier,
+				MinimumStock: vmedisv1.Stock{Unit: "Tablet", Quantity: minimumStock},
+			},
+			Stock: stock,
+		}
+	}
+
+	oosDrugs := []vmedisv1.DrugStock{
+		newDrugStock("FAST", "PBF Alpha", 10, vmedisv1.Stock{Unit: "Strip", Quantity: 5}),
+		newDrugStock("STOCKED", "PBF Beta", 10, vmedisv1.Stock{Unit: "Tablet", Quantity: 10}),
+		newDrugStock("NONE", "PBF Beta", 5, vmedisv1.Stock{Unit: "Tablet", Quantity: 1}),
+		{
+			Drug:  vmedisv1.Drug{VmedisCode: "SLOW", Name: "SLOW", Supplier: "PBF Beta", MinimumStock: vmedisv1.Stock{Unit: "Box", Quantity: 1}},
+			Stock: vmedisv1.Stock{Unit: "Tablet", Quantity: 5},
+		},
+	}
+
+	options, err := RecommendationOptions{Strategy: RecommendationStrategyVelocity}.withDefaults()
+	if err != nil {
+		t.Fatalf("withDefaults() error = %v", err)
+	}
+
+	recommendations, err := service.recommend(ctx, oosDrugs, unitsByDrugCode, options, now)
+	if err != nil {
+		t.Fatalf("recommend() error = %v", err)
+	}
+
+	if len(recommendations) != 3 {
+		t.Fatalf("recommendations = %+v, want FAST, NONE, and SLOW", recommendations)
+	}
+
+	fast := recommendations[0]
+	if fast.DrugStock.Drug.VmedisCode != "FAST" || fast.Strategy != RecommendationStrategyVelocity || fast.Velocity == nil {
+		t.Fatalf("FAST recommendation = %+v", fast)
+	}
+
+	// The safety stock is z(95%) × 20 × √(3 + 7) and the target stock covers the lead time and the review period.
+	safetyStock := 1.6448536269514722 * 20 * math.Sqrt(10)
+	velocity := fast.Velocity
+	if velocity.Unit != "Tablet" || velocity.AverageDailyDemand != 20 || velocity.LeadTimeDays != 3 ||
+		math.Abs(velocity.SafetyStock-safetyStock) > 1e-6 ||
+		math.Abs(velocity.ReorderPoint-(60+safetyStock)) > 1e-6 ||
+		math.Abs(velocity.TargetStock-(200+safetyStock)) > 1e-6 ||
+		velocity.CurrentStock != 50 {
+		t.Errorf("FAST velocity = %+v", velocity)
+	}
+
+	// 254 tablets round up to 3 boxes, overshooting them by less than a week of sales.
+	if fast.Procurement != (drug.Stock{Unit: "Box", Quantity: 3}) || len(fast.Alternatives) != 2 || fast.Alternatives[0] != (drug.Stock{Unit: "Strip", Quantity: 26}) {
+		t.Errorf("FAST procurement = %+v, alternatives = %+v, want 3 Box and 26 Strip", fast.Procurement, fast.Alternatives)
+	}
+	if !strings.Contains(fast.Reasoning, "waktu kirim 3 hari") {
+		t.Errorf("FAST reasoning = %q, want the lead time of PBF Alpha", fast.Reasoning)
+	}
+
+	none := recommendations[1]
+	if none.DrugStock.Drug.VmedisCode != "NONE" || none.Strategy != RecommendationStrategyMinimumStock || none.Velocity != nil ||
+		none.Procurement != (drug.Stock{Unit: "Strip", Quantity: 1}) || !strings.HasPrefix(none.Reasoning, "Tidak terjual dalam 28 hari terakhir.") {
+		t.Errorf("NONE recommendation = %+v, want 1 Strip from the minimum stock", none)
+	}
+
+	// The minimum stock of a box is 100 tablets, so 195 tablets are needed, rounded up to 2 boxes.
+	slow := recommendations[2]
+	if slow.DrugStock.Drug.VmedisCode != "SLOW" || slow.Strategy != RecommendationStrategyMinimumStock ||
+		slow.Procurement != (drug.Stock{Unit: "Box", Quantity: 2}) || len(slow.Alternatives) != 2 || slow.Alternatives[0] != (drug.Stock{Unit: "Strip", Quantity: 20}) {
+		t.Errorf("SLOW procurement = %+v, alternatives = %+v, want 2 Box and 20 Strip", slow.Procurement, slow.Alternatives)
+	}
+	if !strings.Contains(slow.Reasoning, "Stok minimum 100 Tablet × 2 − stok sekarang 5 Tablet") {
+		t.Errorf("SLOW reasoning = %q, want the minimum stock in tablets", slow.Reasoning)
+	}
+
+	if _, err := (RecommendationOptions{Strategy: "UNKNOWN"}).withDefaults(); !errors.Is(err, ErrInvalidRecommendationRequest) {
+		t.Errorf("withDefaults() with an unknown strategy error = %v, want ErrInvalidRecommendationRequest", err)
+	}
+}
+
+// TestRecommendByVelocityConsidersStockedDrugs checks that the velocity strategy also considers the drugs
+// above their minimum stock, which aren't out of stock in Vmedis, if they're stocked or recently sold,
+// with their stocks totalled and the supplier of their latest procurement.
+func TestRecommendByVelocityConsidersStockedDrugs(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := database.SqliteDB(t.TempDir() + "/test.db")
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+
+	service := NewService(db, nil, nil, nil, nil, nil)
+	now := time.Date(2026, 8, 8, 12, 0, 0, 0, time.Local)
+	removedAt := now.AddDate(0, 0, -3)
+
+	minimumStock := models.Stock{Unit: "Tablet", Quantity: 10}
+	drugs := []models.Drug{
+		{VmedisID: 1, VmedisCode: "OOS", Name: "OOS", MinimumStock: minimumStock},
+		{VmedisID: 2, VmedisCode: "STEADY", Name: "STEADY", MinimumStock: minimumStock, Stocks: []models.DrugStock{
+			{Stock: models.Stock{Unit: "Box", Quantity: 1}},
+			{Stock: models.Stock{Unit: "Strip", Quantity: 5}},
+		}},
+		{VmedisID: 3, VmedisCode: "IDLE", Name: "IDLE", MinimumStock: minimumStock, Stocks: []models.DrugStock{
+			{Stock: models.Stock{Unit: "Tablet", Quantity: 50}},
+		}},
+		{VmedisID: 4, VmedisCode: "EMPTY", Name: "EMPTY", MinimumStock: minimumStock, Stocks: []models.DrugStock{
+			{Stock: models.Stock{Unit: "Tablet", Quantity: 0}},
+		}},
+		{VmedisID: 5, VmedisCode: "GONE", Name: "GONE", MinimumStock: minimumStock, RemovedAt: &removedAt, Stocks: []models.DrugStock{
+			{Stock: models.Stock{Unit: "Tablet", Quantity: 50}},
+		}},
+	}
+	if err := db.Create(&drugs).Error; err != nil {
+		t.Fatalf("create drugs: %v", err)
+	}
+
+	// STEADY sells 10 strips every day of the last 28 days, so 100 tablets a day without any deviation.
+	var sales []models.Sale
+	for day := 1; day <= 28; day++ {
+		sales = append(sales, newTestSale(fmt.Sprintf("S-%d", day), now.AddDate(0, 0, -day), models.SaleUnit{DrugCode: "STEADY", Amount: 10, Unit: "Strip"}))
+	}
+	if err := db.Create(&sales).Error; err != nil {
+		t.Fatalf("create sales: %v", err)
+	}
+
+	procurements := []models.Procurement{
+		newTestProcurement("P-1", now.AddDate(0, 0, -60), "PBF Alpha", models.ProcurementUnit{DrugCode: "STEADY", DrugName: "STEADY", Amount: 1, Unit: "Box"}),
+		newTestProcurement("P-2", now.AddDate(0, 0, -30), "PBF Gamma", models.ProcurementUnit{DrugCode: "STEADY", DrugName: "STEADY", Amount: 1, Unit: "Box"}),
+	}
+	if err := db.Create(&procurements).Error; err != nil {
+		t.Fatalf("create procurements: %v", err)
+	}
+
+	options, err := RecommendationOptions{Strategy: RecommendationStrategyVelocity}.withDefaults()
+	if err != nil {
+		t.Fatalf("withDefaults() error = %v", err)
+	}
+
+	oosDrugs := []vmedisv1.DrugStock{{
+		Drug:  vmedisv1.Drug{VmedisCode: "OOS", Name: "OOS", Supplier: "PBF Beta", MinimumStock: vmedisv1.Stock{Unit: "Tablet", Quantity: 10}},
+		Stock: vmedisv1.Stock{Unit: "Tablet", Quantity: 0},
+	}}
+
+	candidates, err := service.velocityCandidates(ctx, oosDrugs, options, now)
+	if err != nil {
+		t.Fatalf("velocityCandidates() error = %v", err)
+	}
+
+	var codes []string
+	for _, candidate := range candidates {
+		codes = append(codes, candidate.Drug.VmedisCode)
+	}
+	if strings.Join(codes, ",") != "OOS,IDLE,STEADY" {
+		t.Fatalf("velocityCandidates() = %v, want OOS from Vmedis, then IDLE and STEADY from the database", codes)
+	}
+	if oos := candidates[0]; oos.Drug.Supplier != "PBF Beta" {
+		t.Errorf("OOS candidate = %+v, want the one from Vmedis", oos)
+	}
+	if steady := candidates[2]; steady.Drug.Supplier != "PBF Gamma" || len(steady.Drug.Stocks) != 2 {
+		t.Errorf("STEADY candidate = %+v, want the supplier of its latest procurement and both of its stocks", steady)
+	}
+
+	tabletStripBox := []drug.Unit{
+		{Unit: "Tablet"},
+		{Unit: "Strip", ParentUnit: "Tablet", ConversionToParentUnit: 10, UnitOrder: 1},
+		{Unit: "Box", ParentUnit: "Strip", ConversionToParentUnit: 10, UnitOrder: 2},
+	}
+	unitsByDrugCode := map[string][]drug.Unit{
+		"OOS":    tabletStripBox,
+		"STEADY": tabletStripBox,
+		"IDLE":   tabletStripBox,
+	}
+
+	recommendations, err := service.recommend(ctx, candidates, unitsByDrugCode, options, now)
+	if err != nil {
+		t.Fatalf("recommend() error = %v", err)
+	}
+
+	// IDLE isn't sold and is above its minimum stock, so it isn't ordered.
+	if len(recommendations) != 2 {
+		t.Fatalf("recommendations = %+v, want OOS and STEADY", recommendations)
+	}
+
+	if oos := recommendations[0]; oos.DrugStock.Drug.VmedisCode != "OOS" || oos.Strategy != RecommendationStrategyMinimumStock {
+		t.Errorf("OOS recommendation = %+v, want one from the minimum stock", oos)
+	}
+
+	// 150 tablets in stock are above the minimum stock but below the reorder point of 2 days of sales,
+	// so 750 tablets are needed to reach the target stock of 9 days of sales, rounded up to 8 boxes.
+	steady := recommendations[1]
+	if steady.DrugStock.Drug.VmedisCode != "STEADY" || steady.Strategy != RecommendationStrategyVelocity || steady.Velocity == nil ||
+		steady.FromSupplier != "PBF Gamma" || steady.Velocity.CurrentStock != 150 || steady.Velocity.ReorderPoint != 200 ||
+		steady.Procurement != (drug.Stock{Unit: "Box", Quantity: 8}) {
+		t.Errorf("STEADY recommendation = %+v, velocity = %+v, want 8 Box from PBF Gamma", steady, steady.Velocity)
+	}
+}
+
+func newTestSale(invoiceNumber string, soldAt time.Time, units ...models.SaleUnit) models.Sale {
+	for i := range units {
+		units[i].IDInSale = i + 1
+	}
+
+	return models.Sale{
+		InvoiceNumber: invoiceNumber,
+		SoldAt:        soldAt,
+		SaleUnits:     units,
+	}
+}
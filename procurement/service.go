@@ -7,12 +7,14 @@ import (
 	"math"
 	"slices"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 	"gorm.io/gorm"
 
 	"github.com/turfaa/vmedis-proxy-api/drug"
+	"github.com/turfaa/vmedis-proxy-api/events"
 	"github.com/turfaa/vmedis-proxy-api/kafkapb"
 	vmedisv1 "github.com/turfaa/vmedis-proxy-api/vmedis/v1"
 )
@@ -27,6 +29,7 @@ type Service struct {
 	vmedis          *vmedisv1.Client
 	drugProducer    UpdatedDrugProducer
 	drugUnitsGetter DrugUnitsGetter
+	events          EventProducer
 }
 
 func (s *Service) GetAggregatedProcurementsBetweenTime(ctx context.Context, from time.Time, to time.Time) ([]AggregatedProcurement, error) {
@@ -57,6 +60,7 @@ func (s *Service) DumpProcurementsBetweenDatesFromVmedisToDB(
 	endDate time.Time,
 ) error {
 	log.Printf("Dumping procurements from %s to %s from vmedis to DB", startDate, endDate)
+	startedAt := time.Now()
 
 	procurements, err := s.vmedis.GetAllProcurementsBetweenDates(ctx, startDate, endDate)
 	if err != nil {
@@ -69,34 +73,68 @@ func (s *Service) DumpProcurementsBetweenDatesFromVmedisToDB(
 
 	log.Printf("Got %d procurements after deduplication", len(procurements))
 
+	// The procurements, their events, and the updated drug messages of each batch are written in one transaction,
+	// so the drugs are always refreshed once the procurements are stored.
 	chunkNum := 1
+	updatedDrugsCount := 0
 	for chunk := range slices.Chunk(procurements, upsertToDBBatchSize) {
-		if err := s.db.UpsertVmedisProcurements(ctx, chunk); err != nil {
-			return fmt.Errorf("upsert vmedis procurements batch %d: %w", chunkNum, err)
+		invoiceNumbers := make([]string, 0, len(chunk))
+		var updatedDrugs []*kafkapb.UpdatedDrugByVmedisCode
+		for _, p := range chunk {
+			invoiceNumbers = append(invoiceNumbers, p.InvoiceNumber)
+
+			for _, pu := range p.ProcurementUnits {
+				updatedDrugs = append(updatedDrugs, &kafkapb.UpdatedDrugByVmedisCode{
+					RequestKey: fmt.Sprintf("procurement:%s:%s", p.InvoiceNumber, pu.DrugCode),
+					VmedisCode: pu.DrugCode,
+				})
+			}
+		}
+
+		if err := s.db.Transaction(ctx, func(ctx context.Context, tx *Database) error {
+			before, err := tx.GetProcurementsByInvoiceNumbers(ctx, invoiceNumbers)
+			if err != nil {
+				return fmt.Errorf("get procurements before upsert: %w", err)
+			}
+
+			if err := tx.UpsertVmedisProcurements(ctx, chunk); err != nil {
+				return fmt.Errorf("upsert vmedis procurements: %w", err)
+			}
+
+			after, err := tx.GetProcurementsByInvoiceNumbers(ctx, invoiceNumbers)
+			if err != nil {
+				return fmt.Errorf("get procurements after upsert: %w", err)
+			}
+
+			if err := s.events.ProduceProcurementRecorded(ctx, procurementRecordedEvents(before, after)); err != nil {
+				return fmt.Errorf("produce procurement recorded events: %w", err)
+			}
+
+			if err := s.drugProducer.ProduceUpdatedDrugByVmedisCode(ctx, updatedDrugs); err != nil {
+				return fmt.Errorf("produce updated drug by vmedis code: %w", err)
+			}
+
+			return nil
+		}); err != nil {
+			return fmt.Errorf("dump procurements batch %d: %w", chunkNum, err)
 		}
 
 		log.Printf("Upserted %d procurements from vmedis to DB batch %d", len(chunk), chunkNum)
 		chunkNum++
+		updatedDrugsCount += len(updatedDrugs)
 	}
 
 	log.Printf("Dumped %d procurements from vmedis to DB", len(procurements))
+	log.Printf("Produced %d updated drugs by vmedis code", updatedDrugsCount)
 
-	var updatedDrugs []*kafkapb.UpdatedDrugByVmedisCode
-	for _, p := range procurements {
-		for _, pu := range p.ProcurementUnits {
-			updatedDrugs = append(updatedDrugs, &kafkapb.UpdatedDrugByVmedisCode{
-				RequestKey: fmt.Sprintf("procurement:%s:%s", p.InvoiceNumber, pu.DrugCode),
-				VmedisCode: pu.DrugCode,
-			})
-		}
+	if _, err := s.ReceivePurchaseOrders(ctx); err != nil {
+		return fmt.Errorf("receive purchase orders: %w", err)
 	}
 
-	if err := s.drugProducer.ProduceUpdatedDrugByVmedisCode(ctx, updatedDrugs); err != nil {
-		return fmt.Errorf("produce updated drug by vmedis code: %w", err)
+	if err := s.events.ProduceDumpCompleted(ctx, []*kafkapb.DumpCompleted{events.DumpCompleted(events.DumpProcurements, startedAt, len(procurements))}); err != nil {
+		return fmt.Errorf("produce dump completed event: %w", err)
 	}
 
-	log.Printf("Produced %d updated drugs by vmedis code", len(updatedDrugs))
-
 	return nil
 }
 
@@ -201,7 +239,7 @@ func deduplicateProcurementsPickGreatestTotal(procurements []vmedisv1.Procuremen
 	return deduplicated
 }
 
-func (s *Service) DumpRecommendationsFromVmedisToRedis(ctx context.Context) error {
+func (s *Service) DumpRecommendationsFromVmedisToRedis(ctx context.Context, options RecommendationOptions) error {
 	token, acquired, err := s.redisDB.AcquireRecommendationsLock(ctx)
 	if err != nil {
 		return fmt.Errorf("acquire procurement recommendations lock: %w", err)
@@ -220,7 +258,7 @@ func (s *Service) DumpRecommendationsFromVmedisToRedis(ctx context.Context) erro
 
 	log.Println("Generating procurement recommendations and writing them to cache")
 
-	recommendations, err := s.GenerateRecommendations(ctx)
+	recommendations, err := s.GenerateRecommendations(ctx, options)
 	if err != nil {
 		return fmt.Errorf("generate procurement recommendations: %w", err)
 	}
@@ -249,7 +287,14 @@ func (s *Service) GetRecommendationStatus(ctx context.Context) (RecommendationSt
 	return RecommendationStatusResponse{Status: status}, nil
 }
 
-func (s *Service) GenerateRecommendations(ctx context.Context) (RecommendationsResponse, error) {
+// GenerateRecommendations calculates the recommended procurements with the strategy of the options,
+// of the out-of-stock drugs, and with RecommendationStrategyVelocity also of the stocked or recently sold drugs.
+func (s *Service) GenerateRecommendations(ctx context.Context, options RecommendationOptions) (RecommendationsResponse, error) {
+	options, err := options.withDefaults()
+	if err != nil {
+		return RecommendationsResponse{}, err
+	}
+
 	log.Printf("Getting all out-of-stock-drugs for writing procurement recommendations")
 	oosDrugs, err := s.vmedis.GetAllOutOfStockDrugs(ctx)
 	if err != nil {
@@ -258,38 +303,50 @@ func (s *Service) GenerateRecommendations(ctx context.Context) (RecommendationsR
 
 	log.Printf("Got %d out-of-stock drugs for writing procurement recommendations", len(oosDrugs))
 
-	log.Printf("Getting drug units of out-of-stock drugs")
-	drugCodes := make([]string, len(oosDrugs))
-	for i, d := range oosDrugs {
+	now := time.Now()
+
+	drugStocks := oosDrugs
+	if options.Strategy == RecommendationStrategyVelocity {
+		log.Printf("Getting stocked or recently sold drugs for writing procurement recommendations")
+		drugStocks, err = s.velocityCandidates(ctx, oosDrugs, options, now)
+		if err != nil {
+			return RecommendationsResponse{}, fmt.Errorf("get stocked or recently sold drugs for writing procurement recommendations: %w", err)
+		}
+
+		log.Printf("Got %d drugs for writing procurement recommendations", len(drugStocks))
+	}
+
+	log.Printf("Getting drug units of the drugs")
+	drugCodes := make([]string, len(drugStocks))
+	for i, d := range drugStocks {
 		drugCodes[i] = d.Drug.VmedisCode
 	}
 
 	drugUnitsByDrugCode, err := s.drugUnitsGetter.GetDrugUnitsByDrugVmedisCodes(ctx, drugCodes)
 	if err != nil {
-		return RecommendationsResponse{}, fmt.Errorf("get drug units of out-of-stock drugs: %w", err)
+		return RecommendationsResponse{}, fmt.Errorf("get drug units of the drugs: %w", err)
 	}
 
 	var unitCount int
 	for _, units := range drugUnitsByDrugCode {
 		unitCount += len(units)
 	}
-	log.Printf("Got %d drug units of out-of-stock drugs", unitCount)
+	log.Printf("Got %d drug units of the drugs", unitCount)
 
-	recommendations := make([]Recommendation, len(oosDrugs))
-	for i, drugStock := range oosDrugs {
-		procurement, alternatives := calculateRecommendation(drugStock, drugUnitsByDrugCode[drugStock.Drug.VmedisCode])
+	log.Printf("Calculating procurement recommendations with the %s strategy", options.Strategy)
+	recommendations, err := s.recommend(ctx, drugStocks, drugUnitsByDrugCode, options, now)
+	if err != nil {
+		return RecommendationsResponse{}, fmt.Errorf("calculate procurement recommendations: %w", err)
+	}
 
-		recommendations[i] = Recommendation{
-			DrugStock:    drug.FromVmedisDrugStock(drugStock),
-			FromSupplier: drugStock.Drug.Supplier,
-			Procurement:  procurement,
-			Alternatives: alternatives,
-		}
+	log.Printf("Suggesting cheaper suppliers for %d procurement recommendations", len(recommendations))
+	if err := s.suggestCheaperSuppliers(ctx, recommendations, drugUnitsByDrugCode, now); err != nil {
+		return RecommendationsResponse{}, fmt.Errorf("suggest cheaper suppliers: %w", err)
 	}
 
 	return RecommendationsResponse{
 		Recommendations: recommendations,
-		ComputedAt:      time.Now(),
+		ComputedAt:      now,
 	}, nil
 }
 
@@ -337,6 +394,34 @@ func calculateRecommendation(stock vmedisv1.DrugStock, drugUnits []drug.Unit) (c
 	return
 }
 
+// GetSupplierLeadTimes returns the lead times of the suppliers, sorted by the supplier.
+func (s *Service) GetSupplierLeadTimes(ctx context.Context) ([]SupplierLeadTime, error) {
+	leadTimes, err := s.db.GetSupplierLeadTimes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get supplier lead times from DB: %w", err)
+	}
+
+	return leadTimes, nil
+}
+
+// SetSupplierLeadTime sets the lead time of a supplier, used by the velocity recommendations.
+func (s *Service) SetSupplierLeadTime(ctx context.Context, leadTime SupplierLeadTime) (SupplierLeadTime, error) {
+	leadTime.Supplier = strings.TrimSpace(leadTime.Supplier)
+	if leadTime.Supplier == "" {
+		return SupplierLeadTime{}, fmt.Errorf("%w: supplier must not be empty", ErrInvalidLeadTime)
+	}
+
+	if leadTime.Days <= 0 || leadTime.Days > maxLeadTimeDays {
+		return SupplierLeadTime{}, fmt.Errorf("%w: days must be positive and at most %d", ErrInvalidLeadTime, maxLeadTimeDays)
+	}
+
+	if err := s.db.UpsertSupplierLeadTime(ctx, leadTime); err != nil {
+		return SupplierLeadTime{}, fmt.Errorf("upsert supplier lead time to DB: %w", err)
+	}
+
+	return leadTime, nil
+}
+
 func (s *Service) GetInvoiceCalculators(ctx context.Context) ([]InvoiceCalculator, error) {
 	calculators, err := s.db.GetInvoiceCalculators(ctx)
 	if err != nil {
@@ -361,6 +446,7 @@ func NewService(
 	vmedisClient *vmedisv1.Client,
 	drugProducer UpdatedDrugProducer,
 	drugUnitsGetter DrugUnitsGetter,
+	eventProducer EventProducer,
 ) *Service {
 	return &Service{
 		db:              NewDatabase(db),
@@ -368,5 +454,6 @@ func NewService(
 		vmedis:          vmedisClient,
 		drugProducer:    drugProducer,
 		drugUnitsGetter: drugUnitsGetter,
+		events:          eventProducer,
 	}
 }
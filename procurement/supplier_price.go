@@ -0,0 +1,254 @@
+package procurement
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/turfaa/vmedis-proxy-api/drug"
+)
+
+const (
+	// DefaultSupplierPriceWindowDays is the window of the supplier prices, also used to suggest cheaper suppliers.
+	DefaultSupplierPriceWindowDays = 180
+
+	maxSupplierPriceWindowDays = 730
+
+	// supplierPriceHalfLifeDays is the age at which a procurement weighs half as much in the effective price.
+	supplierPriceHalfLifeDays = 30
+)
+
+// ErrInvalidSupplierPriceRequest is returned when the supplier price request is invalid.
+var ErrInvalidSupplierPriceRequest = errors.New("invalid supplier price request")
+
+// GetDrugSupplierPrices returns the prices of the drug from each supplier over the last days,
+// ranked by the effective price, with ties broken by the latest invoice date.
+func (s *Service) GetDrugSupplierPrices(ctx context.Context, drugCode string, days int) ([]SupplierPrice, error) {
+	if days == 0 {
+		days = DefaultSupplierPriceWindowDays
+	}
+
+	if days < 1 || days > maxSupplierPriceWindowDays {
+		return nil, fmt.Errorf("%w: days must be between 1 and %d", ErrInvalidSupplierPriceRequest, maxSupplierPriceWindowDays)
+	}
+
+	unitsByDrugCode, err := s.drugUnitsGetter.GetDrugUnitsByDrugVmedisCodes(ctx, []string{drugCode})
+	if err != nil {
+		return nil, fmt.Errorf("get units of drug %s: %w", drugCode, err)
+	}
+
+	pricesByDrugCode, err := s.supplierPrices(ctx, []string{drugCode}, unitsByDrugCode, days, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return pricesByDrugCode[drugCode], nil
+}
+
+// supplierPrices returns the ranked supplier prices of each drug over the days before now.
+// The procurement units in a unit that can't be converted into the smallest unit of the drug are skipped.
+func (s *Service) supplierPrices(
+	ctx context.Context,
+	drugCodes []string,
+	unitsByDrugCode map[string][]drug.Unit,
+	days int,
+	now time.Time,
+) (map[string][]SupplierPrice, error) {
+	since := beginningOfDay(now).AddDate(0, 0, -days)
+
+	procured, err := s.db.GetProcuredPricesSince(ctx, drugCodes, since)
+	if err != nil {
+		return nil, fmt.Errorf("get procured prices: %w", err)
+	}
+
+	procuredByDrugCode := make(map[string][]procuredPrice)
+	for _, p := range procured {
+		procuredByDrugCode[p.DrugCode] = append(procuredByDrugCode[p.DrugCode], p)
+	}
+
+	pricesByDrugCode := make(map[string][]SupplierPrice, len(procuredByDrugCode))
+	for code, drugProcured := range procuredByDrugCode {
+		units := unitsByDrugCode[code]
+		if len(units) == 0 {
+			continue
+		}
+
+		sizes, ok := drug.UnitSizes(units)
+		if !ok {
+			continue
+		}
+
+		if prices := calculateSupplierPrices(drugProcured, sizes, units[0].Unit, now); len(prices) > 0 {
+			pricesByDrugCode[code] = prices
+		}
+	}
+
+	return pricesByDrugCode, nil
+}
+
+// calculateSupplierPrices calculates the price statistics of each supplier from the procured prices of a drug,
+// which must be sorted from the oldest, and ranks them.
+func calculateSupplierPrices(procured []procuredPrice, sizes map[string]float64, smallestUnit string, now time.Time) []SupplierPrice {
+	type accumulator struct {
+		price SupplierPrice
+
+		weightedPrice        float64
+		weightedDiscount     float64
+		recencyWeight        float64
+		recencyWeightedPrice float64
+	}
+
+	var suppliers []string
+	accumulators := make(map[string]*accumulator)
+	for _, p := range procured {
+		size := sizes[strings.ToLower(p.Unit)]
+		if size == 0 || p.Amount <= 0 {
+			continue
+		}
+
+		unitPrice := p.UnitTaxedPrice
+		if unitPrice <= 0 {
+			unitPrice = p.TotalUnitPrice
+		}
+		if unitPrice <= 0 {
+			continue
+		}
+
+		price := unitPrice / size
+		quantity := p.Amount * size
+		discount := combinedDiscountPercentage(p.DiscountPercentage, p.DiscountTwoPercentage, p.DiscountThreePercentage)
+		ageDays := max(now.Sub(p.InvoiceDate).Hours()/24, 0)
+		recencyWeight := quantity * math.Pow(0.5, ageDays/supplierPriceHalfLifeDays)
+
+		acc, ok := accumulators[p.Supplier]
+		if !ok {
+			acc = &accumulator{price: SupplierPrice{Supplier: p.Supplier, Unit: smallestUnit, MinPrice: price}}
+			accumulators[p.Supplier] = acc
+			suppliers = append(suppliers, p.Supplier)
+		}
+
+		acc.price.LastPrice = price
+		acc.price.MinPrice = min(acc.price.MinPrice, price)
+		acc.price.LastDiscountPercentage = discount
+		acc.price.LastInvoiceDate = p.InvoiceDate
+		acc.price.ProcurementCount++
+		acc.price.ProcuredQuantity += quantity
+
+		acc.weightedPrice += price * quantity
+		acc.weightedDiscount += discount * quantity
+		acc.recencyWeight += recencyWeight
+		acc.recencyWeightedPrice += price * recencyWeight
+	}
+
+	prices := make([]SupplierPrice, 0, len(suppliers))
+	for _, supplier := range suppliers {
+		acc := accumulators[supplier]
+		price := acc.price
+
+		price.AveragePrice = acc.weightedPrice / price.ProcuredQuantity
+		price.AverageDiscountPercentage = roundQuantity(acc.weightedDiscount / price.ProcuredQuantity)
+		price.LastDiscountPercentage = roundQuantity(price.LastDiscountPercentage)
+
+		// Procurements long ago weigh next to nothing, in which case the average price is the best estimate.
+		price.EffectivePrice = price.AveragePrice
+		if acc.recencyWeight > 0 {
+			price.EffectivePrice = acc.recencyWeightedPrice / acc.recencyWeight
+		}
+
+		price.EffectivePrice = roundQuantity(price.EffectivePrice)
+		price.LastPrice = roundQuantity(price.LastPrice)
+		price.MinPrice = roundQuantity(price.MinPrice)
+		price.AveragePrice = roundQuantity(price.AveragePrice)
+		price.ProcuredQuantity = roundQuantity(price.ProcuredQuantity)
+
+		prices = append(prices, price)
+	}
+
+	slices.SortFunc(prices, func(a, b SupplierPrice) int {
+		if a.EffectivePrice != b.EffectivePrice {
+			return cmp.Compare(a.EffectivePrice, b.EffectivePrice)
+		}
+
+		if !a.LastInvoiceDate.Equal(b.LastInvoiceDate) {
+			return b.LastInvoiceDate.Compare(a.LastInvoiceDate)
+		}
+
+		return strings.Compare(a.Supplier, b.Supplier)
+	})
+
+	return prices
+}
+
+// combinedDiscountPercentage returns the percentage of the discounts applied one after another.
+func combinedDiscountPercentage(percentages ...float64) float64 {
+	remaining := 1.0
+	for _, percentage := range percentages {
+		remaining *= 1 - percentage/100
+	}
+
+	return (1 - remaining) * 100
+}
+
+// suggestCheaperSuppliers suggests the top ranked supplier of each recommended drug over the default window,
+// if it's cheaper than the supplier the drug is recommended from.
+// Drugs not procured from their supplier within the window get no suggestion, since there is nothing to compare.
+func (s *Service) suggestCheaperSuppliers(
+	ctx context.Context,
+	recommendations []Recommendation,
+	unitsByDrugCode map[string][]drug.Unit,
+	now time.Time,
+) error {
+	if len(recommendations) == 0 {
+		return nil
+	}
+
+	drugCodes := make([]string, len(recommendations))
+	for i, recommendation := range recommendations {
+		drugCodes[i] = recommendation.DrugStock.Drug.VmedisCode
+	}
+
+	pricesByDrugCode, err := s.supplierPrices(ctx, drugCodes, unitsByDrugCode, DefaultSupplierPriceWindowDays, now)
+	if err != nil {
+		return fmt.Errorf("get supplier prices: %w", err)
+	}
+
+	for i := range recommendations {
+		recommendation := &recommendations[i]
+		recommendation.CheaperSupplier = cheaperSupplier(recommendation.FromSupplier, pricesByDrugCode[recommendation.DrugStock.Drug.VmedisCode])
+	}
+
+	return nil
+}
+
+// cheaperSupplier returns the top ranked of the prices if it's cheaper than the price of the current supplier.
+func cheaperSupplier(current string, prices []SupplierPrice) *SupplierSuggestion {
+	current = strings.TrimSpace(current)
+	if current == "" || len(prices) == 0 {
+		return nil
+	}
+
+	currentIndex := slices.IndexFunc(prices, func(price SupplierPrice) bool {
+		return strings.EqualFold(strings.TrimSpace(price.Supplier), current)
+	})
+	if currentIndex <= 0 {
+		return nil
+	}
+
+	best, currentPrice := prices[0], prices[currentIndex]
+	if best.EffectivePrice >= currentPrice.EffectivePrice {
+		return nil
+	}
+
+	return &SupplierSuggestion{
+		Supplier:              best.Supplier,
+		Unit:                  best.Unit,
+		EffectivePrice:        best.EffectivePrice,
+		CurrentEffectivePrice: currentPrice.EffectivePrice,
+		SavingsPercentage:     roundQuantity((currentPrice.EffectivePrice - best.EffectivePrice) / currentPrice.EffectivePrice * 100),
+	}
+}
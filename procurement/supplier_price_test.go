@@ -0,0 +1,142 @@
+package procurement
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/turfaa/vmedis-proxy-api/cui"
+	"github.com/turfaa/vmedis-proxy-api/database"
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+	"github.com/turfaa/vmedis-proxy-api/drug"
+)
+
+// TestSupplierPrices checks that the procured prices of a drug are normalised to its smallest unit per supplier
+// within the window, that the suppliers are ranked by their recency-weighted effective prices,
+// and that a recommendation gets the top ranked supplier if it's cheaper than the supplier it's recommended from.
+func TestSupplierPrices(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := database.SqliteDB(t.TempDir() + "/test.db")
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+
+	if err := db.Create(&models.Drug{VmedisID: 1, VmedisCode: "SANMOL", Name: "SANMOL 500MG"}).Error; err != nil {
+		t.Fatalf("create drug: %v", err)
+	}
+	if err := db.Create(&[]models.DrugUnit{
+		{DrugVmedisCode: "SANMOL", Unit: "Tablet"},
+		{DrugVmedisCode: "SANMOL", Unit: "Strip", ParentUnit: "Tablet", ConversionToParentUnit: 10, UnitOrder: 1},
+		{DrugVmedisCode: "SANMOL", Unit: "Box", ParentUnit: "Strip", ConversionToParentUnit: 10, UnitOrder: 2},
+	}).Error; err != nil {
+		t.Fatalf("create drug units: %v", err)
+	}
+
+	today := beginningOfDay(time.Now())
+	if err := db.Create(&[]models.Procurement{
+		// PBF Alpha was cheaper two months ago, but its recent price dominates its effective price.
+		newTestProcurement("INV-ALPHA-1", today.AddDate(0, 0, -60), "PBF Alpha",
+			models.ProcurementUnit{DrugCode: "SANMOL", Amount: 1, Unit: "Box", UnitTaxedPrice: 100_000, DiscountPercentage: 10},
+		),
+		newTestProcurement("INV-ALPHA-2", today.AddDate(0, 0, -5), "PBF Alpha",
+			models.ProcurementUnit{DrugCode: "SANMOL", Amount: 2, Unit: "Box", UnitTaxedPrice: 120_000},
+		),
+		newTestProcurement("INV-BETA", today.AddDate(0, 0, -90), "PBF Beta",
+			models.ProcurementUnit{DrugCode: "SANMOL", Amount: 10, Unit: "Strip", UnitTaxedPrice: 9_000, DiscountPercentage: 10, DiscountTwoPercentage: 5},
+		),
+		// Without the taxed price, the discounted unit price is used.
+		newTestProcurement("INV-GAMMA", today.AddDate(0, 0, -3), "PBF Gamma",
+			models.ProcurementUnit{DrugCode: "SANMOL", Amount: 5, Unit: "Strip", TotalUnitPrice: 10_500},
+		),
+		// Outside of the window, or in a unit that can't be converted, must be ignored.
+		newTestProcurement("INV-DELTA-1", today.AddDate(0, 0, -200), "PBF Delta",
+			models.ProcurementUnit{DrugCode: "SANMOL", Amount: 1, Unit: "Box", UnitTaxedPrice: 10},
+		),
+		newTestProcurement("INV-DELTA-2", today, "PBF Delta",
+			models.ProcurementUnit{DrugCode: "SANMOL", Amount: 1, Unit: "Pcs", UnitTaxedPrice: 10},
+		),
+	}).Error; err != nil {
+		t.Fatalf("create procurements: %v", err)
+	}
+
+	service := NewService(db, nil, nil, nil, drug.NewDatabase(db), nil)
+
+	prices, err := service.GetDrugSupplierPrices(ctx, "SANMOL", 0)
+	if err != nil {
+		t.Fatalf("GetDrugSupplierPrices() error = %v", err)
+	}
+	if len(prices) != 3 || prices[0].Supplier != "PBF Beta" || prices[1].Supplier != "PBF Gamma" || prices[2].Supplier != "PBF Alpha" {
+		t.Fatalf("GetDrugSupplierPrices() = %+v, want PBF Beta, PBF Gamma, and PBF Alpha", prices)
+	}
+
+	beta, gamma, alpha := prices[0], prices[1], prices[2]
+	if beta.Unit != "Tablet" || beta.EffectivePrice != 900 || beta.LastDiscountPercentage != 14.5 || beta.ProcuredQuantity != 100 {
+		t.Errorf("PBF Beta price = %+v, want 900 per Tablet with a combined discount of 14.5%%", beta)
+	}
+	if gamma.EffectivePrice != 1050 {
+		t.Errorf("PBF Gamma price = %+v, want 1050 per Tablet", gamma)
+	}
+	if alpha.LastPrice != 1200 || alpha.MinPrice != 1000 || alpha.AveragePrice != 1133.33 ||
+		alpha.LastDiscountPercentage != 0 || alpha.AverageDiscountPercentage != 3.33 || alpha.ProcurementCount != 2 ||
+		alpha.EffectivePrice <= alpha.AveragePrice || alpha.EffectivePrice >= alpha.LastPrice {
+		t.Errorf("PBF Alpha price = %+v", alpha)
+	}
+
+	newRecommendation := func(supplier string) Recommendation {
+		return Recommendation{
+			DrugStock:    drug.WithStock{Drug: drug.Drug{VmedisCode: "SANMOL"}},
+			FromSupplier: supplier,
+		}
+	}
+
+	recommendations := []Recommendation{
+		newRecommendation("PBF Alpha"),
+		newRecommendation("PBF Beta"),
+		newRecommendation("PBF Unknown"),
+	}
+	unitsByDrugCode, err := drug.NewDatabase(db).GetDrugUnitsByDrugVmedisCodes(ctx, []string{"SANMOL"})
+	if err != nil {
+		t.Fatalf("get drug units: %v", err)
+	}
+	if err := service.suggestCheaperSuppliers(ctx, recommendations, unitsByDrugCode, time.Now()); err != nil {
+		t.Fatalf("suggestCheaperSuppliers() error = %v", err)
+	}
+
+	suggestion := recommendations[0].CheaperSupplier
+	if suggestion == nil || suggestion.Supplier != "PBF Beta" || suggestion.EffectivePrice != 900 || suggestion.CurrentEffectivePrice != alpha.EffectivePrice ||
+		suggestion.SavingsPercentage != roundQuantity((alpha.EffectivePrice-900)/alpha.EffectivePrice*100) {
+		t.Errorf("suggestion for PBF Alpha = %+v, want PBF Beta", suggestion)
+	}
+	if recommendations[1].CheaperSupplier != nil || recommendations[2].CheaperSupplier != nil {
+		t.Errorf("suggestions for the cheapest and an unknown supplier = %+v, %+v, want none", recommendations[1].CheaperSupplier, recommendations[2].CheaperSupplier)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	// Mirrors the route registration in proxy/api.go, without auth and cache middleware.
+	router.GET("/procurements/drugs/:drug_code/suppliers", NewApiHandler(service, 10).GetDrugSupplierPrices)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/procurements/drugs/SANMOL/suppliers?days=30", nil))
+
+	var table cui.Table
+	if err := json.Unmarshal(w.Body.Bytes(), &table); err != nil {
+		t.Fatalf("unmarshal %s: %v", w.Body.String(), err)
+	}
+	// Only PBF Alpha and PBF Gamma are procured from within the last 30 days.
+	if w.Code != 200 || len(table.Rows) != 2 || table.Rows[0].ID != "PBF Gamma" || table.Rows[0].Columns[2] != "Rp 1.050 / Tablet" || table.Rows[1].Columns[9] != "1 kali" {
+		t.Errorf("supplier prices table: got code %d, body %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/procurements/drugs/SANMOL/suppliers?days=1000", nil))
+	if w.Code != 400 {
+		t.Errorf("supplier prices of 1000 days: got code %d, want 400", w.Code)
+	}
+}
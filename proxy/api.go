@@ -11,8 +11,12 @@ import (
 	gzip "github.com/turfaa/gin-gzip"
 	"gorm.io/gorm"
 
+	"github.com/turfaa/vmedis-proxy-api/alert"
 	"github.com/turfaa/vmedis-proxy-api/auth"
 	"github.com/turfaa/vmedis-proxy-api/drug"
+	"github.com/turfaa/vmedis-proxy-api/forecast"
+	"github.com/turfaa/vmedis-proxy-api/inventory"
+	"github.com/turfaa/vmedis-proxy-api/kfa"
 	"github.com/turfaa/vmedis-proxy-api/pkg2/gin2"
 	"github.com/turfaa/vmedis-proxy-api/procurement"
 	"github.com/turfaa/vmedis-proxy-api/rejecteddrug"
@@ -20,6 +24,7 @@ import (
 	"github.com/turfaa/vmedis-proxy-api/shift"
 	"github.com/turfaa/vmedis-proxy-api/stockopname"
 	"github.com/turfaa/vmedis-proxy-api/vmedis/v1/token"
+	"github.com/turfaa/vmedis-proxy-api/webhook"
 )
 
 // ApiServer is the proxy api server.
@@ -36,6 +41,11 @@ type ApiServer struct {
 	shiftHandler        *shift.ApiHandler
 	tokenHandler        *token.Handler
 	rejectedDrugHandler *rejecteddrug.ApiHandler
+	kfaHandler          *kfa.ApiHandler
+	inventoryHandler    *inventory.ApiHandler
+	forecastHandler     *forecast.ApiHandler
+	webhookHandler      *webhook.ApiHandler
+	alertHandler        *alert.ApiHandler
 }
 
 // GinEngine returns the gin engine of the proxy api server.
@@ -54,6 +64,7 @@ func (s *ApiServer) GinEngine() *gin.Engine {
 func (s *ApiServer) SetupRoute(router *gin.RouterGroup) {
 	store := persist.CacheStore(gin2.NewRedisCacheAdapter(s.redisClient))
 	// store = CompressedCache{Store: store} // try to not use compressed cache for now
+	drugCache := drug.NewCache(s.redisClient)
 
 	v1 := router.Group("/api/v1")
 	{
@@ -119,7 +130,7 @@ func (s *ApiServer) SetupRoute(router *gin.RouterGroup) {
 		{
 			drugs.GET(
 				"",
-				cache.CacheByRequestURI(store, time.Minute),
+				cache.Cache(store, drugsCacheDuration, cache.WithCacheStrategyByRequest(drugsCacheStrategy(drugCache, false))),
 				s.drugHandler.GetDrugs,
 			)
 
@@ -183,13 +194,171 @@ func (s *ApiServer) SetupRoute(router *gin.RouterGroup) {
 		{
 			drugs.GET(
 				"",
-				cache.Cache(store, time.Minute, cache.WithCacheStrategyByRequest(func(c *gin.Context) (bool, cache.Strategy) {
-					return true, cache.Strategy{
-						CacheKey: c.Request.RequestURI + "$$" + string(auth.FromGinContext(c).Role),
-					}
-				})),
+				cache.Cache(store, drugsCacheDuration, cache.WithCacheStrategyByRequest(drugsCacheStrategy(drugCache, true))),
 				s.drugHandler.GetDrugsV2,
 			)
+
+			drugs.GET(
+				"/changes",
+				cache.Cache(store, drugsCacheDuration, cache.WithCacheStrategyByRequest(drugsCacheStrategy(drugCache, true))),
+				s.drugHandler.GetDrugChanges,
+			)
+
+			drugs.GET(
+				"/:code/substitutes",
+				s.drugHandler.GetSubstitutes,
+			)
+
+			drugs.GET(
+				"/:code/forecast",
+				auth.AllowedRoles(auth.RoleAdmin, auth.RoleStaff),
+				cache.CacheByRequestURI(store, time.Minute),
+				s.forecastHandler.GetDrugForecast,
+			)
+
+			drugs.GET(
+				"/by-barcode/:code",
+				s.drugHandler.GetDrugByBarcode,
+			)
+
+			drugs.POST(
+				"/labels",
+				auth.AllowedRoles(auth.RoleAdmin, auth.RoleStaff),
+				s.drugHandler.GenerateLabels,
+			)
+
+			drugs.GET(
+				"/dead-stocks",
+				auth.AllowedRoles(auth.RoleAdmin),
+				cache.CacheByRequestURI(store, time.Minute),
+				s.drugHandler.GetDeadStocks,
+			)
+
+			drugs.GET(
+				"/dead-stocks/xlsx",
+				auth.AllowedRoles(auth.RoleAdmin),
+				s.drugHandler.ExportDeadStocks,
+			)
+
+			drugs.GET(
+				"/dead-letters",
+				auth.AllowedRoles(auth.RoleAdmin),
+				s.drugHandler.GetDeadLetters,
+			)
+
+			drugs.POST(
+				"/dead-letters/requeue",
+				auth.AllowedRoles(auth.RoleAdmin),
+				s.drugHandler.RequeueDeadLetters,
+			)
+
+			barcodes := drugs.Group("/barcodes")
+			{
+				barcodes.GET(
+					"",
+					auth.AllowedRoles(auth.RoleAdmin, auth.RoleStaff),
+					s.drugHandler.GetBarcodes,
+				)
+
+				barcodes.POST(
+					"",
+					auth.AllowedRoles(auth.RoleAdmin),
+					s.drugHandler.CreateBarcode,
+				)
+
+				barcodes.GET(
+					"/form",
+					auth.AllowedRoles(auth.RoleAdmin),
+					s.drugHandler.GetCreateBarcodeForm,
+				)
+
+				barcodes.GET(
+					"/import/form",
+					auth.AllowedRoles(auth.RoleAdmin),
+					s.drugHandler.GetImportBarcodesForm,
+				)
+
+				barcodes.POST(
+					"/import",
+					auth.AllowedRoles(auth.RoleAdmin),
+					s.drugHandler.ImportBarcodes,
+				)
+
+				barcodes.GET(
+					"/:id",
+					auth.AllowedRoles(auth.RoleAdmin, auth.RoleStaff),
+					s.drugHandler.GetBarcode,
+				)
+
+				barcodes.GET(
+					"/:id/form",
+					auth.AllowedRoles(auth.RoleAdmin),
+					s.drugHandler.GetUpdateBarcodeForm,
+				)
+
+				barcodes.PATCH(
+					"/:id",
+					auth.AllowedRoles(auth.RoleAdmin),
+					s.drugHandler.UpdateBarcode,
+				)
+
+				barcodes.DELETE(
+					"/:id",
+					auth.AllowedRoles(auth.RoleAdmin),
+					s.drugHandler.DeleteBarcode,
+				)
+			}
+
+			equivalenceGroups := drugs.Group("/equivalence-groups")
+			{
+				equivalenceGroups.GET(
+					"",
+					auth.AllowedRoles(auth.RoleAdmin, auth.RoleStaff),
+					s.drugHandler.GetEquivalenceGroups,
+				)
+
+				equivalenceGroups.POST(
+					"",
+					auth.AllowedRoles(auth.RoleAdmin),
+					s.drugHandler.CreateEquivalenceGroup,
+				)
+
+				equivalenceGroups.GET(
+					"/form",
+					auth.AllowedRoles(auth.RoleAdmin),
+					s.drugHandler.GetCreateEquivalenceGroupForm,
+				)
+
+				equivalenceGroups.POST(
+					"/seed-from-kfa",
+					auth.AllowedRoles(auth.RoleAdmin),
+					s.drugHandler.SeedEquivalenceGroupsFromKFA,
+				)
+
+				equivalenceGroups.GET(
+					"/:id",
+					auth.AllowedRoles(auth.RoleAdmin, auth.RoleStaff),
+					s.drugHandler.GetEquivalenceGroup,
+				)
+
+				equivalenceGroups.GET(
+					"/:id/form",
+					auth.AllowedRoles(auth.RoleAdmin),
+					s.drugHandler.GetUpdateEquivalenceGroupForm,
+				)
+
+				equivalenceGroups.PATCH(
+					"/:id",
+					auth.AllowedRoles(auth.RoleAdmin),
+					s.drugHandler.UpdateEquivalenceGroup,
+				)
+
+				equivalenceGroups.DELETE(
+					"/:id",
+					auth.AllowedRoles(auth.RoleAdmin),
+					s.drugHandler.DeleteEquivalenceGroup,
+				)
+			}
 		}
 
 		sales := v2.Group("/sales")
@@ -200,6 +369,13 @@ func (s *ApiServer) SetupRoute(router *gin.RouterGroup) {
 				cache.CacheByRequestURI(store, time.Minute),
 				s.saleHandler.GetLastDrugSales,
 			)
+
+			sales.GET(
+				"/analytics",
+				auth.AllowedRoles(auth.RoleAdmin),
+				cache.CacheByRequestURI(store, time.Minute),
+				s.saleHandler.GetAnalytics,
+			)
 		}
 
 		procurements := v2.Group("/procurements")
@@ -211,12 +387,107 @@ func (s *ApiServer) SetupRoute(router *gin.RouterGroup) {
 				s.procurementHandler.GetLastDrugProcurements,
 			)
 
+			procurements.GET(
+				"/drugs/:drug_code/suppliers",
+				auth.AllowedRoles(auth.RoleAdmin, auth.RoleStaff),
+				cache.CacheByRequestURI(store, time.Minute),
+				s.procurementHandler.GetDrugSupplierPrices,
+			)
+
 			procurements.GET(
 				"/suppliers/recap",
 				auth.AllowedRoles(auth.RoleAdmin, auth.RoleStaff),
 				cache.CacheByRequestURI(store, time.Minute),
 				s.procurementHandler.GetSupplierProcurementRecaps,
 			)
+
+			procurements.GET(
+				"/suppliers/lead-times",
+				auth.AllowedRoles(auth.RoleAdmin, auth.RoleStaff),
+				s.procurementHandler.GetSupplierLeadTimes,
+			)
+
+			procurements.PUT(
+				"/suppliers/lead-times",
+				auth.AllowedRoles(auth.RoleAdmin),
+				s.procurementHandler.SetSupplierLeadTime,
+			)
+
+			procurements.GET(
+				"/margins",
+				auth.AllowedRoles(auth.RoleAdmin),
+				cache.CacheByRequestURI(store, time.Minute),
+				s.procurementHandler.GetMargins,
+			)
+
+			procurements.GET(
+				"/margins/xlsx",
+				auth.AllowedRoles(auth.RoleAdmin),
+				s.procurementHandler.ExportMargins,
+			)
+
+			purchaseOrders := procurements.Group("/purchase-orders")
+			{
+				purchaseOrders.GET(
+					"",
+					auth.AllowedRoles(auth.RoleAdmin, auth.RoleStaff),
+					s.procurementHandler.GetPurchaseOrders,
+				)
+
+				purchaseOrders.POST(
+					"",
+					auth.AllowedRoles(auth.RoleAdmin, auth.RoleStaff),
+					s.procurementHandler.CreatePurchaseOrdersFromRecommendations,
+				)
+
+				purchaseOrders.POST(
+					"/receive",
+					auth.AllowedRoles(auth.RoleAdmin, auth.RoleStaff),
+					s.procurementHandler.ReceivePurchaseOrders,
+				)
+
+				purchaseOrders.GET(
+					"/:id",
+					auth.AllowedRoles(auth.RoleAdmin, auth.RoleStaff),
+					s.procurementHandler.GetPurchaseOrder,
+				)
+
+				purchaseOrders.DELETE(
+					"/:id",
+					auth.AllowedRoles(auth.RoleAdmin, auth.RoleStaff),
+					s.procurementHandler.DeletePurchaseOrder,
+				)
+
+				purchaseOrders.PUT(
+					"/:id/lines/:line_id",
+					auth.AllowedRoles(auth.RoleAdmin, auth.RoleStaff),
+					s.procurementHandler.UpdatePurchaseOrderLine,
+				)
+
+				purchaseOrders.DELETE(
+					"/:id/lines/:line_id",
+					auth.AllowedRoles(auth.RoleAdmin, auth.RoleStaff),
+					s.procurementHandler.DeletePurchaseOrderLine,
+				)
+
+				purchaseOrders.POST(
+					"/:id/send",
+					auth.AllowedRoles(auth.RoleAdmin, auth.RoleStaff),
+					s.procurementHandler.SendPurchaseOrder,
+				)
+
+				purchaseOrders.GET(
+					"/:id/pdf",
+					auth.AllowedRoles(auth.RoleAdmin, auth.RoleStaff),
+					s.procurementHandler.ExportPurchaseOrderPDF,
+				)
+
+				purchaseOrders.GET(
+					"/:id/xlsx",
+					auth.AllowedRoles(auth.RoleAdmin, auth.RoleStaff),
+					s.procurementHandler.ExportPurchaseOrderXLSX,
+				)
+			}
 		}
 
 		shifts := v2.Group("/shifts")
@@ -252,6 +523,138 @@ func (s *ApiServer) SetupRoute(router *gin.RouterGroup) {
 			)
 		}
 
+		stockOpnames := v2.Group("/stock-opnames")
+		{
+			cycleCounts := stockOpnames.Group("/cycle-counts")
+			{
+				cycleCounts.POST(
+					"/plan",
+					auth.AllowedRoles(auth.RoleAdmin),
+					s.stockOpnameHandler.PlanCycleCount,
+				)
+
+				cycleCounts.GET(
+					"/tasks",
+					auth.AllowedRoles(auth.RoleAdmin, auth.RoleStaff),
+					s.stockOpnameHandler.GetCycleCountTasks,
+				)
+
+				cycleCounts.GET(
+					"/progress",
+					auth.AllowedRoles(auth.RoleAdmin, auth.RoleStaff),
+					s.stockOpnameHandler.GetCycleCountProgress,
+				)
+			}
+
+			sessions := stockOpnames.Group("/sessions")
+			{
+				sessions.GET(
+					"",
+					auth.AllowedRoles(auth.RoleAdmin, auth.RoleStaff),
+					s.stockOpnameHandler.GetCountSessions,
+				)
+
+				sessions.POST(
+					"",
+					auth.AllowedRoles(auth.RoleAdmin, auth.RoleStaff),
+					s.stockOpnameHandler.CreateCountSession,
+				)
+
+				sessions.GET(
+					"/form",
+					auth.AllowedRoles(auth.RoleAdmin, auth.RoleStaff),
+					s.stockOpnameHandler.GetCreateCountSessionForm,
+				)
+
+				sessions.GET(
+					"/:id",
+					auth.AllowedRoles(auth.RoleAdmin, auth.RoleStaff),
+					s.stockOpnameHandler.GetCountSession,
+				)
+
+				sessions.GET(
+					"/:id/lines/form",
+					auth.AllowedRoles(auth.RoleAdmin, auth.RoleStaff),
+					s.stockOpnameHandler.GetCountSessionLineForm,
+				)
+
+				sessions.PUT(
+					"/:id/lines",
+					auth.AllowedRoles(auth.RoleAdmin, auth.RoleStaff),
+					s.stockOpnameHandler.RecordCountSessionLine,
+				)
+
+				sessions.DELETE(
+					"/:id/lines/:line_id",
+					auth.AllowedRoles(auth.RoleAdmin, auth.RoleStaff),
+					s.stockOpnameHandler.DeleteCountSessionLine,
+				)
+
+				sessions.GET(
+					"/:id/variances",
+					auth.AllowedRoles(auth.RoleAdmin, auth.RoleStaff),
+					s.stockOpnameHandler.GetCountSessionVariances,
+				)
+
+				sessions.GET(
+					"/:id/comparisons",
+					auth.AllowedRoles(auth.RoleAdmin, auth.RoleStaff),
+					s.stockOpnameHandler.GetCountSessionComparisons,
+				)
+
+				sessions.POST(
+					"/:id/approve",
+					auth.AllowedRoles(auth.RoleAdmin),
+					s.stockOpnameHandler.ApproveCountSession,
+				)
+
+				sessions.GET(
+					"/:id/xlsx",
+					auth.AllowedRoles(auth.RoleAdmin),
+					s.stockOpnameHandler.ExportCountSession,
+				)
+			}
+
+			shrinkage := stockOpnames.Group("/shrinkage")
+			{
+				shrinkage.GET(
+					"/periods",
+					auth.AllowedRoles(auth.RoleAdmin),
+					s.stockOpnameHandler.GetShrinkagePeriods,
+				)
+
+				shrinkage.GET(
+					"/drugs",
+					auth.AllowedRoles(auth.RoleAdmin),
+					s.stockOpnameHandler.GetShrinkageDrugs,
+				)
+
+				shrinkage.GET(
+					"/manufacturers",
+					auth.AllowedRoles(auth.RoleAdmin),
+					s.stockOpnameHandler.GetShrinkageManufacturers,
+				)
+
+				shrinkage.GET(
+					"/staff",
+					auth.AllowedRoles(auth.RoleAdmin),
+					s.stockOpnameHandler.GetShrinkageStaff,
+				)
+
+				shrinkage.GET(
+					"/repeat-offenders",
+					auth.AllowedRoles(auth.RoleAdmin),
+					s.stockOpnameHandler.GetShrinkageRepeatOffenders,
+				)
+
+				shrinkage.GET(
+					"/xlsx",
+					auth.AllowedRoles(auth.RoleAdmin),
+					s.stockOpnameHandler.ExportShrinkage,
+				)
+			}
+		}
+
 		rejectedDrugs := v2.Group("/rejected-drugs")
 		{
 			rejectedDrugs.GET(
@@ -303,6 +706,141 @@ func (s *ApiServer) SetupRoute(router *gin.RouterGroup) {
 			)
 		}
 
+		kfaMatches := v2.Group("/kfa/matches")
+		{
+			kfaMatches.GET(
+				"",
+				auth.AllowedRoles(auth.RoleAdmin, auth.RoleStaff),
+				s.kfaHandler.GetMatches,
+			)
+
+			kfaMatches.GET(
+				"/statuses",
+				auth.AllowedRoles(auth.RoleAdmin, auth.RoleStaff),
+				s.kfaHandler.GetStatuses,
+			)
+
+			kfaMatches.GET(
+				"/:id",
+				auth.AllowedRoles(auth.RoleAdmin, auth.RoleStaff),
+				s.kfaHandler.GetMatch,
+			)
+
+			kfaMatches.GET(
+				"/:id/form",
+				auth.AllowedRoles(auth.RoleAdmin, auth.RoleStaff),
+				s.kfaHandler.GetReviewMatchForm,
+			)
+
+			kfaMatches.PATCH(
+				"/:id",
+				auth.AllowedRoles(auth.RoleAdmin, auth.RoleStaff),
+				s.kfaHandler.ReviewMatch,
+			)
+		}
+
+		classification := v2.Group("/inventory/classification")
+		{
+			classification.GET(
+				"",
+				auth.AllowedRoles(auth.RoleAdmin, auth.RoleStaff),
+				cache.CacheByRequestURI(store, time.Minute),
+				s.inventoryHandler.GetClassifications,
+			)
+
+			classification.POST(
+				"",
+				auth.AllowedRoles(auth.RoleAdmin),
+				s.inventoryHandler.Classify,
+			)
+
+			classification.GET(
+				"/matrix",
+				auth.AllowedRoles(auth.RoleAdmin, auth.RoleStaff),
+				cache.CacheByRequestURI(store, time.Minute),
+				s.inventoryHandler.GetMatrix,
+			)
+
+			classification.GET(
+				"/periods",
+				auth.AllowedRoles(auth.RoleAdmin, auth.RoleStaff),
+				s.inventoryHandler.GetPeriods,
+			)
+
+			classification.GET(
+				"/drugs/:drug_code",
+				auth.AllowedRoles(auth.RoleAdmin, auth.RoleStaff),
+				cache.CacheByRequestURI(store, time.Minute),
+				s.inventoryHandler.GetDrugClassifications,
+			)
+		}
+
+		webhooks := v2.Group("/webhooks")
+		{
+			webhooks.GET(
+				"",
+				auth.AllowedRoles(auth.RoleAdmin),
+				s.webhookHandler.GetSubscriptions,
+			)
+
+			webhooks.POST(
+				"",
+				auth.AllowedRoles(auth.RoleAdmin),
+				s.webhookHandler.CreateSubscription,
+			)
+
+			webhooks.GET(
+				"/event-types",
+				auth.AllowedRoles(auth.RoleAdmin),
+				s.webhookHandler.GetEventTypes,
+			)
+
+			webhooks.GET(
+				"/:id",
+				auth.AllowedRoles(auth.RoleAdmin),
+				s.webhookHandler.GetSubscription,
+			)
+
+			webhooks.PATCH(
+				"/:id",
+				auth.AllowedRoles(auth.RoleAdmin),
+				s.webhookHandler.UpdateSubscription,
+			)
+
+			webhooks.DELETE(
+				"/:id",
+				auth.AllowedRoles(auth.RoleAdmin),
+				s.webhookHandler.DeleteSubscription,
+			)
+
+			webhooks.GET(
+				"/:id/deliveries",
+				auth.AllowedRoles(auth.RoleAdmin),
+				s.webhookHandler.GetDeliveries,
+			)
+
+			webhooks.POST(
+				"/:id/ping",
+				auth.AllowedRoles(auth.RoleAdmin),
+				s.webhookHandler.Ping,
+			)
+		}
+
+		alerts := v2.Group("/alerts")
+		{
+			alerts.GET(
+				"",
+				auth.AllowedRoles(auth.RoleAdmin, auth.RoleStaff),
+				s.alertHandler.GetAlerts,
+			)
+
+			alerts.POST(
+				"/:id/acknowledge",
+				auth.AllowedRoles(auth.RoleAdmin, auth.RoleStaff),
+				s.alertHandler.AcknowledgeAlert,
+			)
+		}
+
 		vm := v2.Group("/vmedis")
 		{
 			tokens := vm.Group("/tokens")
@@ -361,6 +899,11 @@ func NewApiServer(
 	shiftHandler *shift.ApiHandler,
 	tokenHandler *token.Handler,
 	rejectedDrugHandler *rejecteddrug.ApiHandler,
+	kfaHandler *kfa.ApiHandler,
+	inventoryHandler *inventory.ApiHandler,
+	forecastHandler *forecast.ApiHandler,
+	webhookHandler *webhook.ApiHandler,
+	alertHandler *alert.ApiHandler,
 ) *ApiServer {
 	return &ApiServer{
 		db:          db,
@@ -375,5 +918,10 @@ func NewApiServer(
 		shiftHandler:        shiftHandler,
 		tokenHandler:        tokenHandler,
 		rejectedDrugHandler: rejectedDrugHandler,
+		kfaHandler:          kfaHandler,
+		inventoryHandler:    inventoryHandler,
+		forecastHandler:     forecastHandler,
+		webhookHandler:      webhookHandler,
+		alertHandler:        alertHandler,
 	}
 }
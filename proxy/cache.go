@@ -2,10 +2,16 @@ package proxy
 
 import (
 	"fmt"
+	"log"
+	"strconv"
 	"time"
 
+	cache "github.com/chenyahui/gin-cache"
 	"github.com/chenyahui/gin-cache/persist"
+	"github.com/gin-gonic/gin"
 
+	"github.com/turfaa/vmedis-proxy-api/auth"
+	"github.com/turfaa/vmedis-proxy-api/drug"
 	"github.com/turfaa/vmedis-proxy-api/pkg2/zstd2"
 )
 
@@ -48,3 +54,30 @@ func (c CompressedCache) Get(key string, value interface{}) error {
 func (c CompressedCache) Delete(key string) error {
 	return c.Store.Delete(key)
 }
+
+// drugsCacheDuration bounds how long a drugs response lives if the drugs version is lost.
+// The responses are normally invalidated by the drugs version instead.
+const drugsCacheDuration = time.Hour
+
+// drugsCacheStrategy caches the drugs responses until any drug changes,
+// by keying them with the drugs version which is bumped on every change.
+// The key also includes the user role if the response depends on it.
+func drugsCacheStrategy(drugCache *drug.Cache, byRole bool) cache.GetCacheStrategyByRequest {
+	return func(c *gin.Context) (bool, cache.Strategy) {
+		version, err := drugCache.GetDrugsVersion(c.Request.Context())
+		if err != nil {
+			log.Printf("Error getting drugs version: %s", err)
+			return false, cache.Strategy{}
+		}
+
+		key := c.Request.RequestURI + "$$v" + strconv.FormatInt(version, 10)
+		if byRole {
+			key += "$$" + string(auth.FromGinContext(c).Role)
+		}
+
+		return true, cache.Strategy{
+			CacheKey:      key,
+			CacheDuration: drugsCacheDuration,
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package proxy
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/turfaa/vmedis-proxy-api/auth"
+	"github.com/turfaa/vmedis-proxy-api/drug"
+)
+
+// TestDrugsCacheStrategy checks that the drugs responses are keyed by the drugs version,
+// so bumping it invalidates them, and by the user role if the response depends on it.
+func TestDrugsCacheStrategy(t *testing.T) {
+	redisClient := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	t.Cleanup(func() { redisClient.Close() })
+
+	drugCache := drug.NewCache(redisClient)
+
+	key := func(byRole bool, role auth.Role) string {
+		t.Helper()
+
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("GET", "/api/v2/drugs", nil)
+		auth.SetGinContext(c, auth.User{Role: role})
+
+		ok, strategy := drugsCacheStrategy(drugCache, byRole)(c)
+		if !ok {
+			t.Fatalf("drugsCacheStrategy() didn't cache the response")
+		}
+		if strategy.CacheDuration != drugsCacheDuration {
+			t.Errorf("drugsCacheStrategy() cache duration = %s, want %s", strategy.CacheDuration, drugsCacheDuration)
+		}
+
+		return strategy.CacheKey
+	}
+
+	before := key(false, auth.RoleAdmin)
+	if again := key(false, auth.RoleStaff); again != before {
+		t.Errorf("cache key without a drug change = %q, want %q", again, before)
+	}
+
+	if err := drugCache.BumpDrugsVersion(context.Background()); err != nil {
+		t.Fatalf("BumpDrugsVersion() error = %v", err)
+	}
+
+	if after := key(false, auth.RoleAdmin); after == before {
+		t.Errorf("cache key after a drug change = %q, want it changed", after)
+	}
+
+	if admin, staff := key(true, auth.RoleAdmin), key(true, auth.RoleStaff); admin == staff {
+		t.Errorf("cache keys by role = %q for both admin and staff, want them different", admin)
+	}
+}
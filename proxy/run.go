@@ -12,14 +12,19 @@ import (
 	"github.com/redis/go-redis/v9"
 	"gorm.io/gorm"
 
+	"github.com/turfaa/vmedis-proxy-api/alert"
 	"github.com/turfaa/vmedis-proxy-api/auth"
 	"github.com/turfaa/vmedis-proxy-api/drug"
+	"github.com/turfaa/vmedis-proxy-api/forecast"
+	"github.com/turfaa/vmedis-proxy-api/inventory"
+	"github.com/turfaa/vmedis-proxy-api/kfa"
 	"github.com/turfaa/vmedis-proxy-api/procurement"
 	"github.com/turfaa/vmedis-proxy-api/rejecteddrug"
 	"github.com/turfaa/vmedis-proxy-api/sale"
 	"github.com/turfaa/vmedis-proxy-api/shift"
 	"github.com/turfaa/vmedis-proxy-api/stockopname"
 	"github.com/turfaa/vmedis-proxy-api/vmedis/v1/token"
+	"github.com/turfaa/vmedis-proxy-api/webhook"
 )
 
 // Config is the proxy server configuration.
@@ -36,6 +41,11 @@ type Config struct {
 	ShiftHandler        *shift.ApiHandler
 	TokenHandler        *token.Handler
 	RejectedDrugHandler *rejecteddrug.ApiHandler
+	KFAHandler          *kfa.ApiHandler
+	InventoryHandler    *inventory.ApiHandler
+	ForecastHandler     *forecast.ApiHandler
+	WebhookHandler      *webhook.ApiHandler
+	AlertHandler        *alert.ApiHandler
 }
 
 // Run runs the proxy server.
@@ -54,6 +64,11 @@ func Run(config Config) {
 		config.ShiftHandler,
 		config.TokenHandler,
 		config.RejectedDrugHandler,
+		config.KFAHandler,
+		config.InventoryHandler,
+		config.ForecastHandler,
+		config.WebhookHandler,
+		config.AlertHandler,
 	)
 
 	engine := apiServer.GinEngine()
@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/turfaa/vmedis-proxy-api/database/models"
+	"github.com/turfaa/vmedis-proxy-api/outbox"
 
 	"gorm.io/gorm"
 )
@@ -18,6 +19,14 @@ func NewDatabase(db *gorm.DB) *Database {
 	return &Database{db: db}
 }
 
+// Transaction runs fn with a Database in a transaction.
+// The messages published to the outbox with the context passed to fn are written in the same transaction.
+func (d *Database) Transaction(ctx context.Context, fn func(ctx context.Context, tx *Database) error) error {
+	return outbox.Transaction(ctx, d.db, func(ctx context.Context, tx *gorm.DB) error {
+		return fn(ctx, NewDatabase(tx))
+	})
+}
+
 func (d *Database) GetRejectedDrugs(ctx context.Context, filters ListFilters) ([]models.RejectedDrug, error) {
 	var rejectedDrugs []models.RejectedDrug
 
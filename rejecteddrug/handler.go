@@ -3,6 +3,7 @@ package rejecteddrug
 import (
 	"errors"
 	"fmt"
+	"log"
 	"strconv"
 	"strings"
 	"time"
@@ -10,6 +11,7 @@ import (
 	"github.com/turfaa/vmedis-proxy-api/auth"
 	"github.com/turfaa/vmedis-proxy-api/cui"
 	"github.com/turfaa/vmedis-proxy-api/database/models"
+	"github.com/turfaa/vmedis-proxy-api/drug"
 	"github.com/turfaa/vmedis-proxy-api/pkg2/slices2"
 	"github.com/turfaa/vmedis-proxy-api/pkg2/time2"
 
@@ -111,6 +113,11 @@ func (h *ApiHandler) UpdateRejectedDrug(c *gin.Context) {
 			return
 		}
 
+		if errors.Is(err, ErrUnknownSubstituteDrug) {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+
 		c.JSON(500, gin.H{"error": fmt.Sprintf("failed to update rejected drug %d: %s", id, err)})
 		return
 	}
@@ -176,30 +183,48 @@ func (h *ApiHandler) GetUpdateRejectedDrugForm(c *gin.Context) {
 		return
 	}
 
-	c.JSON(200, cui.Form{
-		Title: "Perbarui Obat Ditolak",
-		Fields: []cui.Field{
-			{
-				ID:       "drugName",
-				Label:    "Nama Obat",
-				Type:     cui.FieldTypeText,
-				Value:    rejectedDrug.DrugName,
-				Required: true,
-			},
-			{
-				ID:      "resolution",
-				Label:   "Status",
-				Type:    cui.FieldTypeSelect,
-				Value:   rejectedDrug.Resolution.String(),
-				Options: h.resolutionOptions(),
-			},
-			{
-				ID:    "resolutionNotes",
-				Label: "Catatan",
-				Type:  cui.FieldTypeTextArea,
-				Value: rejectedDrug.ResolutionNotes,
-			},
+	fields := []cui.Field{
+		{
+			ID:       "drugName",
+			Label:    "Nama Obat",
+			Type:     cui.FieldTypeText,
+			Value:    rejectedDrug.DrugName,
+			Required: true,
 		},
+		{
+			ID:      "resolution",
+			Label:   "Status",
+			Type:    cui.FieldTypeSelect,
+			Value:   rejectedDrug.Resolution.String(),
+			Options: h.resolutionOptions(),
+		},
+		{
+			ID:    "resolutionNotes",
+			Label: "Catatan",
+			Type:  cui.FieldTypeTextArea,
+			Value: rejectedDrug.ResolutionNotes,
+		},
+	}
+
+	// The suggestions are only a help, so the form is still served without them.
+	substitutes, err := h.service.GetSubstituteSuggestions(c.Request.Context(), rejectedDrug)
+	if err != nil {
+		log.Printf("Error getting substitute suggestions of rejected drug %d: %s", id, err)
+	}
+
+	if len(substitutes) > 0 || rejectedDrug.SubstituteDrugCode != "" {
+		fields = append(fields, cui.Field{
+			ID:      "substituteDrugCode",
+			Label:   "Obat Pengganti",
+			Type:    cui.FieldTypeSelect,
+			Value:   rejectedDrug.SubstituteDrugCode,
+			Options: substituteOptions(substitutes, rejectedDrug.SubstituteDrugCode),
+		})
+	}
+
+	c.JSON(200, cui.Form{
+		Title:  "Perbarui Obat Ditolak",
+		Fields: fields,
 	})
 }
 
@@ -253,6 +278,13 @@ func (h *ApiHandler) transformRejectedDrugToTable(rejectedDrug RejectedDrug) cui
 					rejectedDrug.ResolutionNotes,
 				},
 			},
+			{
+				ID: "obat_pengganti",
+				Columns: []string{
+					"Obat Pengganti",
+					orDash(rejectedDrug.SubstituteDrugCode),
+				},
+			},
 			{
 				ID: "dicatat_oleh",
 				Columns: []string{
@@ -294,6 +326,32 @@ func (h *ApiHandler) resolutionOptions() []cui.Option {
 	})
 }
 
+// substituteOptions returns the suggested substitutes as options, keeping the
+// currently selected substitute selectable even if it is not suggested anymore.
+func substituteOptions(substitutes []drug.Drug, selected string) []cui.Option {
+	options := []cui.Option{{Value: "", Label: "-"}}
+
+	selectedFound := selected == ""
+	for _, substitute := range substitutes {
+		stocks := slices2.Map(substitute.Stocks, func(stock drug.Stock) string { return stock.String() })
+
+		options = append(options, cui.Option{
+			Value: substitute.VmedisCode,
+			Label: fmt.Sprintf("%s (stok %s)", substitute.Name, strings.Join(stocks, " ")),
+		})
+
+		if substitute.VmedisCode == selected {
+			selectedFound = true
+		}
+	}
+
+	if !selectedFound {
+		options = append(options, cui.Option{Value: selected, Label: selected})
+	}
+
+	return options
+}
+
 func resolutionLabel(resolution models.RejectedDrugResolution) string {
 	switch resolution {
 	case models.RejectedDrugResolutionUnresolved:
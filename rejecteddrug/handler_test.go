@@ -1,13 +1,16 @@
 package rejecteddrug_test
 
 import (
+	"context"
 	"encoding/json"
 	"net/http/httptest"
+	"slices"
 	"strings"
 	"testing"
 
 	"github.com/turfaa/vmedis-proxy-api/cui"
 	"github.com/turfaa/vmedis-proxy-api/database/models"
+	"github.com/turfaa/vmedis-proxy-api/drug"
 	"github.com/turfaa/vmedis-proxy-api/rejecteddrug"
 
 	"github.com/gin-gonic/gin"
@@ -20,7 +23,7 @@ import (
 // the list, open the detail, load the prefilled update form, resolve the
 // entry, and delete it.
 func TestRejectedDrugJourney(t *testing.T) {
-	router := setupRouter(t)
+	router := setupRouter(t, nil)
 
 	do := func(method, path, body string) (int, string) {
 		req := httptest.NewRequest(method, path, strings.NewReader(body))
@@ -121,7 +124,102 @@ func TestRejectedDrugJourney(t *testing.T) {
 	}
 }
 
-func setupRouter(t *testing.T) *gin.Engine {
+// TestRejectedDrugSubstituteSuggestions checks that the update form suggests
+// the in-stock substitutes, that picking one resolves the entry as substituted,
+// and that unknown drugs are rejected.
+func TestRejectedDrugSubstituteSuggestions(t *testing.T) {
+	router := setupRouter(t, fakeDrugService{
+		"Paracetamol 500mg": {
+			{
+				VmedisCode: "SANMOL",
+				Name:       "SANMOL 500MG",
+				Stocks:     []drug.Stock{{Unit: "Strip", Quantity: 3}},
+			},
+		},
+	})
+
+	do := func(method, path, body string) (int, string) {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code, w.Body.String()
+	}
+
+	code, body := do("POST", "/rejected-drugs", `{"drugName": "Paracetamol 500mg"}`)
+	if code != 201 {
+		t.Fatalf("create: got code %d, body %s", code, body)
+	}
+
+	code, body = do("GET", "/rejected-drugs/1/form", "")
+	if code != 200 {
+		t.Fatalf("get update form: got code %d, body %s", code, body)
+	}
+	updateForm := unmarshal[cui.Form](t, body)
+	var substituteField *cui.Field
+	for i, field := range updateForm.Fields {
+		if field.ID == "substituteDrugCode" {
+			substituteField = &updateForm.Fields[i]
+		}
+	}
+	if substituteField == nil || len(substituteField.Options) != 2 || substituteField.Options[1].Value != "SANMOL" {
+		t.Fatalf("update form: expected SANMOL to be suggested: %s", body)
+	}
+
+	// Unknown drugs can't be picked as the substitute.
+	code, body = do("PATCH", "/rejected-drugs/1", `{"substituteDrugCode": "SANMOLL"}`)
+	if code != 400 {
+		t.Fatalf("substitute with unknown drug: got code %d, body %s", code, body)
+	}
+
+	code, body = do("GET", "/rejected-drugs/1", "")
+	if code != 200 || strings.Contains(body, "SANMOLL") || !strings.Contains(body, "Belum Diselesaikan") {
+		t.Fatalf("detail after unknown substitute: got code %d, body %s", code, body)
+	}
+
+	code, body = do("PATCH", "/rejected-drugs/1", `{"substituteDrugCode": "SANMOL"}`)
+	if code != 200 {
+		t.Fatalf("substitute: got code %d, body %s", code, body)
+	}
+	substituted := unmarshal[rejecteddrug.RejectedDrugResponse](t, body)
+	if substituted.RejectedDrug.Resolution != models.RejectedDrugResolutionSubstituted ||
+		substituted.RejectedDrug.SubstituteDrugCode != "SANMOL" ||
+		substituted.RejectedDrug.ResolvedAt == nil {
+		t.Fatalf("substitute: entry not resolved as substituted: %s", body)
+	}
+
+	// Entries without suggestions don't get the substitute field.
+	code, body = do("POST", "/rejected-drugs", `{"drugName": "Obat Langka"}`)
+	if code != 201 {
+		t.Fatalf("create: got code %d, body %s", code, body)
+	}
+
+	code, body = do("GET", "/rejected-drugs/2/form", "")
+	if code != 200 || strings.Contains(body, "substituteDrugCode") {
+		t.Fatalf("update form without suggestions: got code %d, body %s", code, body)
+	}
+}
+
+// fakeDrugService has the substitutes by the drug names, and knows only the drugs among the substitutes.
+type fakeDrugService map[string][]drug.Drug
+
+func (f fakeDrugService) GetSubstitutesByDrugName(_ context.Context, name string) ([]drug.Drug, error) {
+	return f[name], nil
+}
+
+func (f fakeDrugService) GetDrugsByVmedisCodes(_ context.Context, vmedisCodes []string) ([]drug.Drug, error) {
+	var drugs []drug.Drug
+	for _, substitutes := range f {
+		for _, substitute := range substitutes {
+			if slices.Contains(vmedisCodes, substitute.VmedisCode) {
+				drugs = append(drugs, substitute)
+			}
+		}
+	}
+
+	return drugs, nil
+}
+
+func setupRouter(t *testing.T, drugService fakeDrugService) *gin.Engine {
 	t.Helper()
 
 	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
@@ -132,7 +230,7 @@ func setupRouter(t *testing.T) *gin.Engine {
 		t.Fatalf("migrate database: %s", err)
 	}
 
-	handler := rejecteddrug.NewApiHandler(rejecteddrug.NewService(db))
+	handler := rejecteddrug.NewApiHandler(rejecteddrug.NewService(db, drugService, drugService, nil))
 
 	gin.SetMode(gin.TestMode)
 	router := gin.New()
@@ -10,10 +10,12 @@ type CreateRejectedDrugRequest struct {
 
 // UpdateRejectedDrugRequest updates a rejected drug entry.
 // Nil fields are left unchanged.
+// Setting a substitute drug without a resolution resolves the entry as substituted.
 type UpdateRejectedDrugRequest struct {
-	DrugName        *string                        `json:"drugName"`
-	Resolution      *models.RejectedDrugResolution `json:"resolution"`
-	ResolutionNotes *string                        `json:"resolutionNotes"`
+	DrugName           *string                        `json:"drugName"`
+	Resolution         *models.RejectedDrugResolution `json:"resolution"`
+	ResolutionNotes    *string                        `json:"resolutionNotes"`
+	SubstituteDrugCode *string                        `json:"substituteDrugCode"`
 }
 
 type RejectedDrugResponse struct {
@@ -0,0 +1,20 @@
+package rejecteddrug
+
+import (
+	"context"
+
+	"github.com/turfaa/vmedis-proxy-api/drug"
+	"github.com/turfaa/vmedis-proxy-api/kafkapb"
+)
+
+type SubstitutesGetter interface {
+	GetSubstitutesByDrugName(ctx context.Context, name string) ([]drug.Drug, error)
+}
+
+type DrugsGetter interface {
+	GetDrugsByVmedisCodes(ctx context.Context, vmedisCodes []string) ([]drug.Drug, error)
+}
+
+type EventProducer interface {
+	ProduceRejectedDrugCreated(ctx context.Context, events []*kafkapb.RejectedDrugCreated) error
+}
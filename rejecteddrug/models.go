@@ -7,28 +7,30 @@ import (
 )
 
 type RejectedDrug struct {
-	ID              uint                          `json:"id"`
-	CreatedAt       time.Time                     `json:"createdAt"`
-	UpdatedAt       time.Time                     `json:"updatedAt"`
-	DrugName        string                        `json:"drugName"`
-	Resolution      models.RejectedDrugResolution `json:"resolution"`
-	ResolutionNotes string                        `json:"resolutionNotes"`
-	ResolvedAt      *time.Time                    `json:"resolvedAt,omitempty"`
-	CreatedBy       string                        `json:"createdBy"`
-	ResolvedBy      string                        `json:"resolvedBy"`
+	ID                 uint                          `json:"id"`
+	CreatedAt          time.Time                     `json:"createdAt"`
+	UpdatedAt          time.Time                     `json:"updatedAt"`
+	DrugName           string                        `json:"drugName"`
+	Resolution         models.RejectedDrugResolution `json:"resolution"`
+	ResolutionNotes    string                        `json:"resolutionNotes"`
+	SubstituteDrugCode string                        `json:"substituteDrugCode"`
+	ResolvedAt         *time.Time                    `json:"resolvedAt,omitempty"`
+	CreatedBy          string                        `json:"createdBy"`
+	ResolvedBy         string                        `json:"resolvedBy"`
 }
 
 func FromDBRejectedDrug(rejectedDrug models.RejectedDrug) RejectedDrug {
 	return RejectedDrug{
-		ID:              rejectedDrug.ID,
-		CreatedAt:       rejectedDrug.CreatedAt,
-		UpdatedAt:       rejectedDrug.UpdatedAt,
-		DrugName:        rejectedDrug.DrugName,
-		Resolution:      rejectedDrug.Resolution,
-		ResolutionNotes: rejectedDrug.ResolutionNotes,
-		ResolvedAt:      rejectedDrug.ResolvedAt,
-		CreatedBy:       rejectedDrug.CreatedBy,
-		ResolvedBy:      rejectedDrug.ResolvedBy,
+		ID:                 rejectedDrug.ID,
+		CreatedAt:          rejectedDrug.CreatedAt,
+		UpdatedAt:          rejectedDrug.UpdatedAt,
+		DrugName:           rejectedDrug.DrugName,
+		Resolution:         rejectedDrug.Resolution,
+		ResolutionNotes:    rejectedDrug.ResolutionNotes,
+		SubstituteDrugCode: rejectedDrug.SubstituteDrugCode,
+		ResolvedAt:         rejectedDrug.ResolvedAt,
+		CreatedBy:          rejectedDrug.CreatedBy,
+		ResolvedBy:         rejectedDrug.ResolvedBy,
 	}
 }
 
@@ -2,21 +2,38 @@ package rejecteddrug
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/turfaa/vmedis-proxy-api/database/models"
+	"github.com/turfaa/vmedis-proxy-api/drug"
+	"github.com/turfaa/vmedis-proxy-api/kafkapb"
 	"github.com/turfaa/vmedis-proxy-api/pkg2/slices2"
 
 	"gorm.io/gorm"
 )
 
+// ErrUnknownSubstituteDrug is returned when the substitute drug code doesn't belong to any drug.
+var ErrUnknownSubstituteDrug = errors.New("unknown substitute drug")
+
 type Service struct {
-	db *Database
+	db                *Database
+	drugsGetter       DrugsGetter
+	substitutesGetter SubstitutesGetter
+	events            EventProducer
 }
 
-func NewService(db *gorm.DB) *Service {
-	return &Service{db: NewDatabase(db)}
+// NewService creates a new rejected drug service.
+// substitutesGetter can be nil, in which case no substitutes are suggested.
+// eventProducer can be nil, in which case no events are produced.
+func NewService(db *gorm.DB, drugsGetter DrugsGetter, substitutesGetter SubstitutesGetter, eventProducer EventProducer) *Service {
+	return &Service{
+		db:                NewDatabase(db),
+		drugsGetter:       drugsGetter,
+		substitutesGetter: substitutesGetter,
+		events:            eventProducer,
+	}
 }
 
 func (s *Service) GetRejectedDrugs(ctx context.Context, filters ListFilters) ([]RejectedDrug, error) {
@@ -38,18 +55,40 @@ func (s *Service) GetRejectedDrugByID(ctx context.Context, id uint) (RejectedDru
 }
 
 func (s *Service) CreateRejectedDrug(ctx context.Context, request CreateRejectedDrugRequest, createdBy string) (RejectedDrug, error) {
-	rejectedDrug, err := s.db.CreateRejectedDrug(ctx, models.RejectedDrug{
-		DrugName:   request.DrugName,
-		Resolution: models.RejectedDrugResolutionUnresolved,
-		CreatedBy:  createdBy,
-	})
-	if err != nil {
-		return RejectedDrug{}, fmt.Errorf("create rejected drug: %w", err)
+	var rejectedDrug models.RejectedDrug
+	if err := s.db.Transaction(ctx, func(ctx context.Context, tx *Database) error {
+		var err error
+		rejectedDrug, err = tx.CreateRejectedDrug(ctx, models.RejectedDrug{
+			DrugName:   request.DrugName,
+			Resolution: models.RejectedDrugResolutionUnresolved,
+			CreatedBy:  createdBy,
+		})
+		if err != nil {
+			return fmt.Errorf("create rejected drug: %w", err)
+		}
+
+		if s.events == nil {
+			return nil
+		}
+
+		if err := s.events.ProduceRejectedDrugCreated(ctx, []*kafkapb.RejectedDrugCreated{{
+			Id:        uint64(rejectedDrug.ID),
+			DrugName:  rejectedDrug.DrugName,
+			CreatedBy: rejectedDrug.CreatedBy,
+		}}); err != nil {
+			return fmt.Errorf("produce rejected drug %d created event: %w", rejectedDrug.ID, err)
+		}
+
+		return nil
+	}); err != nil {
+		return RejectedDrug{}, err
 	}
 
 	return FromDBRejectedDrug(rejectedDrug), nil
 }
 
+// UpdateRejectedDrug updates the rejected drug, resolving it as substituted when a substitute drug is picked
+// without a resolution. It returns ErrUnknownSubstituteDrug if the picked substitute drug doesn't exist.
 func (s *Service) UpdateRejectedDrug(ctx context.Context, id uint, request UpdateRejectedDrugRequest, updatedBy string) (RejectedDrug, error) {
 	rejectedDrug, err := s.db.GetRejectedDrugByID(ctx, id)
 	if err != nil {
@@ -64,14 +103,28 @@ func (s *Service) UpdateRejectedDrug(ctx context.Context, id uint, request Updat
 		rejectedDrug.ResolutionNotes = *request.ResolutionNotes
 	}
 
-	if request.Resolution != nil && *request.Resolution != rejectedDrug.Resolution {
-		if !request.Resolution.Valid() {
-			return RejectedDrug{}, fmt.Errorf("invalid resolution: %s", *request.Resolution)
+	resolution := request.Resolution
+	if request.SubstituteDrugCode != nil {
+		if err := s.checkSubstituteDrug(ctx, rejectedDrug.SubstituteDrugCode, *request.SubstituteDrugCode); err != nil {
+			return RejectedDrug{}, err
+		}
+
+		rejectedDrug.SubstituteDrugCode = *request.SubstituteDrugCode
+
+		if resolution == nil && rejectedDrug.SubstituteDrugCode != "" {
+			substituted := models.RejectedDrugResolutionSubstituted
+			resolution = &substituted
+		}
+	}
+
+	if resolution != nil && *resolution != rejectedDrug.Resolution {
+		if !resolution.Valid() {
+			return RejectedDrug{}, fmt.Errorf("invalid resolution: %s", *resolution)
 		}
 
-		rejectedDrug.Resolution = *request.Resolution
+		rejectedDrug.Resolution = *resolution
 
-		if *request.Resolution == models.RejectedDrugResolutionUnresolved {
+		if *resolution == models.RejectedDrugResolutionUnresolved {
 			rejectedDrug.ResolvedAt = nil
 			rejectedDrug.ResolvedBy = ""
 		} else {
@@ -89,6 +142,25 @@ func (s *Service) UpdateRejectedDrug(ctx context.Context, id uint, request Updat
 	return FromDBRejectedDrug(rejectedDrug), nil
 }
 
+// checkSubstituteDrug checks that the newly picked substitute drug exists.
+// Clearing the substitute drug or keeping the current one isn't checked.
+func (s *Service) checkSubstituteDrug(ctx context.Context, current string, substituteDrugCode string) error {
+	if substituteDrugCode == "" || substituteDrugCode == current {
+		return nil
+	}
+
+	drugs, err := s.drugsGetter.GetDrugsByVmedisCodes(ctx, []string{substituteDrugCode})
+	if err != nil {
+		return fmt.Errorf("get substitute drug %s: %w", substituteDrugCode, err)
+	}
+
+	if len(drugs) == 0 {
+		return fmt.Errorf("%w: %s", ErrUnknownSubstituteDrug, substituteDrugCode)
+	}
+
+	return nil
+}
+
 func (s *Service) DeleteRejectedDrug(ctx context.Context, id uint) error {
 	if err := s.db.DeleteRejectedDrug(ctx, id); err != nil {
 		return fmt.Errorf("delete rejected drug %d: %w", id, err)
@@ -97,6 +169,20 @@ func (s *Service) DeleteRejectedDrug(ctx context.Context, id uint) error {
 	return nil
 }
 
+// GetSubstituteSuggestions returns the in-stock drugs that can substitute the rejected drug.
+func (s *Service) GetSubstituteSuggestions(ctx context.Context, rejectedDrug RejectedDrug) ([]drug.Drug, error) {
+	if s.substitutesGetter == nil {
+		return nil, nil
+	}
+
+	substitutes, err := s.substitutesGetter.GetSubstitutesByDrugName(ctx, rejectedDrug.DrugName)
+	if err != nil {
+		return nil, fmt.Errorf("get substitutes of %s: %w", rejectedDrug.DrugName, err)
+	}
+
+	return substitutes, nil
+}
+
 func (s *Service) GetResolutions() []models.RejectedDrugResolution {
 	return models.AllRejectedDrugResolutions()
 }
@@ -0,0 +1,293 @@
+package sale
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// maxAnalyticsBuckets limits the number of time buckets of one analytics request, e.g. a month of hours.
+const maxAnalyticsBuckets = 1000
+
+// ErrInvalidAnalyticsRequest is returned when the sales analytics are requested with invalid parameters.
+var ErrInvalidAnalyticsRequest = errors.New("invalid sales analytics request")
+
+// unitLevelDimensions are the dimensions of the sale units instead of the sales.
+// Grouping by any of them counts the revenue from the totals of the sale units.
+var unitLevelDimensions = map[AnalyticsDimension]bool{
+	AnalyticsDimensionPriceCategory: true,
+	AnalyticsDimensionManufacturer:  true,
+	AnalyticsDimensionDrug:          true,
+}
+
+// ParseAnalyticsDimensions parses comma-separated dimensions, ignoring the empty ones.
+func ParseAnalyticsDimensions(groupBy string) ([]AnalyticsDimension, error) {
+	var dimensions []AnalyticsDimension
+	for _, part := range strings.Split(groupBy, ",") {
+		dimension := AnalyticsDimension(strings.TrimSpace(part))
+		if dimension == "" {
+			continue
+		}
+
+		switch dimension {
+		case AnalyticsDimensionPayment,
+			AnalyticsDimensionSalesman,
+			AnalyticsDimensionCashier,
+			AnalyticsDimensionDoctor,
+			AnalyticsDimensionPriceCategory,
+			AnalyticsDimensionManufacturer,
+			AnalyticsDimensionDrug:
+		default:
+			return nil, fmt.Errorf("%w: unknown dimension %q", ErrInvalidAnalyticsRequest, dimension)
+		}
+
+		if slices.Contains(dimensions, dimension) {
+			return nil, fmt.Errorf("%w: duplicated dimension %q", ErrInvalidAnalyticsRequest, dimension)
+		}
+
+		dimensions = append(dimensions, dimension)
+	}
+
+	return dimensions, nil
+}
+
+// GetAnalyticsBetweenTime returns the revenue, the transactions, the basket size, and the average ticket
+// of the sales between the given times per time bucket, grouped by the given dimensions.
+func (s *Service) GetAnalyticsBetweenTime(
+	ctx context.Context,
+	from time.Time,
+	to time.Time,
+	bucket AnalyticsBucket,
+	dimensions []AnalyticsDimension,
+) (Analytics, error) {
+	if bucket == "" {
+		bucket = AnalyticsBucketDay
+	}
+
+	buckets, err := analyticsBuckets(from, to, bucket)
+	if err != nil {
+		return Analytics{}, err
+	}
+
+	rows, err := s.db.getAnalyticsRowsBetweenTime(ctx, from, to)
+	if err != nil {
+		return Analytics{}, fmt.Errorf("get analytics rows: %w", err)
+	}
+
+	return buildAnalytics(rows, bucket, buckets, dimensions), nil
+}
+
+func buildAnalytics(rows []analyticsRow, bucket AnalyticsBucket, buckets []time.Time, dimensions []AnalyticsDimension) Analytics {
+	unitLevel := slices.ContainsFunc(dimensions, func(dimension AnalyticsDimension) bool {
+		return unitLevelDimensions[dimension]
+	})
+
+	type group struct {
+		values   []string
+		byBucket []analyticsAccumulator
+		total    analyticsAccumulator
+	}
+
+	var (
+		total  analyticsAccumulator
+		groups = make(map[string]*group)
+		keys   []string
+	)
+
+	for _, row := range rows {
+		if unitLevel && row.UnitID == nil {
+			continue
+		}
+
+		i := bucketIndex(buckets, row.SoldAt)
+		if i < 0 {
+			continue
+		}
+
+		values := make([]string, len(dimensions))
+		for j, dimension := range dimensions {
+			values[j] = row.value(dimension)
+		}
+
+		key := strings.Join(values, "\x00")
+		g, ok := groups[key]
+		if !ok {
+			g = &group{values: values, byBucket: make([]analyticsAccumulator, len(buckets))}
+			groups[key] = g
+			keys = append(keys, key)
+		}
+
+		g.byBucket[i].add(row, unitLevel)
+		g.total.add(row, unitLevel)
+		total.add(row, unitLevel)
+	}
+
+	analyticsGroups := make([]AnalyticsGroup, 0, len(keys))
+	for _, key := range keys {
+		g := groups[key]
+
+		byBucket := make([]AnalyticsMetrics, len(g.byBucket))
+		for i, accumulator := range g.byBucket {
+			byBucket[i] = accumulator.metrics()
+		}
+
+		analyticsGroups = append(analyticsGroups, AnalyticsGroup{
+			Values:   g.values,
+			ByBucket: byBucket,
+			Total:    g.total.metrics(),
+		})
+	}
+
+	slices.SortStableFunc(analyticsGroups, func(a, b AnalyticsGroup) int {
+		return cmp.Or(
+			cmp.Compare(b.Total.Revenue, a.Total.Revenue),
+			slices.Compare(a.Values, b.Values),
+		)
+	})
+
+	return Analytics{
+		Bucket:     bucket,
+		Dimensions: dimensions,
+		Buckets:    buckets,
+		Groups:     analyticsGroups,
+		Total:      total.metrics(),
+	}
+}
+
+func (r analyticsRow) value(dimension AnalyticsDimension) string {
+	switch dimension {
+	case AnalyticsDimensionPayment:
+		return r.Payment
+	case AnalyticsDimensionSalesman:
+		return r.Salesman
+	case AnalyticsDimensionCashier:
+		return r.Cashier
+	case AnalyticsDimensionDoctor:
+		return r.Doctor
+	case AnalyticsDimensionPriceCategory:
+		return r.PriceCategory
+	case AnalyticsDimensionManufacturer:
+		return r.Manufacturer
+	case AnalyticsDimensionDrug:
+		return r.DrugName
+	default:
+		return ""
+	}
+}
+
+// analyticsAccumulator sums up the rows of a group of sales.
+type analyticsAccumulator struct {
+	revenue  float64
+	items    int
+	invoices map[string]struct{}
+}
+
+// add adds the row to the accumulator. Without unit-level dimensions the revenue is
+// the total of each sale counted once, which includes the discounts and rounding of the whole sale.
+func (a *analyticsAccumulator) add(row analyticsRow, unitLevel bool) {
+	if a.invoices == nil {
+		a.invoices = make(map[string]struct{})
+	}
+
+	_, seen := a.invoices[row.InvoiceNumber]
+	a.invoices[row.InvoiceNumber] = struct{}{}
+
+	switch {
+	case unitLevel:
+		a.revenue += row.UnitTotal
+	case !seen:
+		a.revenue += row.SaleTotal
+	}
+
+	if row.UnitID != nil {
+		a.items++
+	}
+}
+
+func (a analyticsAccumulator) metrics() AnalyticsMetrics {
+	metrics := AnalyticsMetrics{
+		Revenue:      a.revenue,
+		Transactions: len(a.invoices),
+		Items:        a.items,
+	}
+
+	if metrics.Transactions > 0 {
+		metrics.BasketSize = float64(metrics.Items) / float64(metrics.Transactions)
+		metrics.AverageTicket = metrics.Revenue / float64(metrics.Transactions)
+	}
+
+	return metrics
+}
+
+// analyticsBuckets returns the start times of the buckets covering the given times.
+// Weeks start on Monday.
+func analyticsBuckets(from time.Time, to time.Time, bucket AnalyticsBucket) ([]time.Time, error) {
+	from, to = from.In(time.Local), to.In(time.Local)
+
+	var start time.Time
+	switch bucket {
+	case AnalyticsBucketHour:
+		start = time.Date(from.Year(), from.Month(), from.Day(), from.Hour(), 0, 0, 0, time.Local)
+	case AnalyticsBucketDay:
+		start = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.Local)
+	case AnalyticsBucketWeek:
+		start = time.Date(from.Year(), from.Month(), from.Day()-(int(from.Weekday())+6)%7, 0, 0, 0, 0, time.Local)
+	case AnalyticsBucketMonth:
+		start = time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, time.Local)
+	default:
+		return nil, fmt.Errorf("%w: unknown bucket %q", ErrInvalidAnalyticsRequest, bucket)
+	}
+
+	var buckets []time.Time
+	for !start.After(to) {
+		if len(buckets) == maxAnalyticsBuckets {
+			return nil, fmt.Errorf("%w: more than %d %s buckets", ErrInvalidAnalyticsRequest, maxAnalyticsBuckets, bucket)
+		}
+
+		buckets = append(buckets, start)
+		start = nextAnalyticsBucket(start, bucket)
+	}
+
+	return buckets, nil
+}
+
+func nextAnalyticsBucket(start time.Time, bucket AnalyticsBucket) time.Time {
+	switch bucket {
+	case AnalyticsBucketHour:
+		return start.Add(time.Hour)
+	case AnalyticsBucketWeek:
+		return start.AddDate(0, 0, 7)
+	case AnalyticsBucketMonth:
+		return start.AddDate(0, 1, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+// bucketIndex returns the index of the bucket of the given time, or -1 if it's before the first bucket.
+func bucketIndex(buckets []time.Time, t time.Time) int {
+	i, found := slices.BinarySearchFunc(buckets, t, func(start time.Time, t time.Time) int {
+		return start.Compare(t)
+	})
+	if found {
+		return i
+	}
+
+	return i - 1
+}
+
+// formatAnalyticsBucket returns the start of the bucket for display.
+func formatAnalyticsBucket(start time.Time, bucket AnalyticsBucket) string {
+	switch bucket {
+	case AnalyticsBucketHour:
+		return start.Format("2006-01-02 15:04")
+	case AnalyticsBucketMonth:
+		return start.Format("2006-01")
+	default:
+		return start.Format(time.DateOnly)
+	}
+}
@@ -0,0 +1,112 @@
+package sale
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/turfaa/vmedis-proxy-api/database"
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+	vmedisv1 "github.com/turfaa/vmedis-proxy-api/vmedis/v1"
+)
+
+// TestGetAnalyticsBetweenTime checks that the sales are bucketed by day and grouped by sale-level and unit-level dimensions,
+// counting the revenue of a sale once, and that soft-deleted sales are left out.
+func TestGetAnalyticsBetweenTime(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := database.SqliteDB(t.TempDir() + "/test.db")
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+
+	service := NewService(db, nil, nil, nopDrugProducer{}, &recordingEventProducer{})
+
+	for i, d := range []models.Drug{
+		{VmedisCode: "D1", Name: "Drug One", Manufacturer: "Kimia Farma"},
+		{VmedisCode: "D2", Name: "Drug Two", Manufacturer: "Sanbe"},
+	} {
+		d.VmedisID = int64(i + 1)
+		if err := db.Create(&d).Error; err != nil {
+			t.Fatalf("create drug %s: %v", d.VmedisCode, err)
+		}
+	}
+
+	date := time.Date(2026, 8, 7, 0, 0, 0, 0, time.Local)
+	newSale := func(id int, invoiceNumber string, at time.Time, payment string, units ...vmedisv1.SaleUnit) vmedisv1.Sale {
+		var total float64
+		for i := range units {
+			units[i].IDInSale = i + 1
+			total += units[i].Total
+		}
+
+		return vmedisv1.Sale{
+			ID:            id,
+			Date:          vmedisv1.Time{Time: at},
+			InvoiceNumber: invoiceNumber,
+			Payment:       payment,
+			Total:         total,
+			SaleUnits:     units,
+		}
+	}
+
+	d1 := vmedisv1.SaleUnit{DrugCode: "D1", DrugName: "Drug One", Amount: 1, Unit: "Strip", PriceCategory: "Umum", Total: 10_000}
+	d2 := vmedisv1.SaleUnit{DrugCode: "D2", DrugName: "Drug Two", Amount: 1, Unit: "Botol", PriceCategory: "Resep", Total: 30_000}
+
+	if err := service.dumpSalesToDB(ctx, []vmedisv1.Sale{
+		newSale(1, "PJ1", date.Add(9*time.Hour), "Tunai", d1, d2),
+		newSale(2, "PJ2", date.Add(15*time.Hour), "QRIS", d1),
+		newSale(3, "PJ3", date.AddDate(0, 0, 1).Add(9*time.Hour), "Tunai", d2),
+		newSale(4, "PJ-DELETED", date.AddDate(0, 0, 1).Add(10*time.Hour), "Tunai", d2),
+	}); err != nil {
+		t.Fatalf("dump sales: %v", err)
+	}
+
+	if err := service.db.DeleteSaleByInvoiceNumber(ctx, "PJ-DELETED"); err != nil {
+		t.Fatalf("delete sale: %v", err)
+	}
+
+	from, to := date, date.AddDate(0, 0, 2).Add(-time.Nanosecond)
+
+	byPayment, err := service.GetAnalyticsBetweenTime(ctx, from, to, AnalyticsBucketDay, []AnalyticsDimension{AnalyticsDimensionPayment})
+	if err != nil {
+		t.Fatalf("GetAnalyticsBetweenTime() by payment error = %v", err)
+	}
+
+	if len(byPayment.Buckets) != 2 || len(byPayment.Groups) != 2 {
+		t.Fatalf("analytics by payment = %+v, want 2 days and 2 payments", byPayment)
+	}
+
+	wantTotal := AnalyticsMetrics{Revenue: 80_000, Transactions: 3, Items: 4, BasketSize: 4.0 / 3, AverageTicket: 80_000.0 / 3}
+	if byPayment.Total != wantTotal {
+		t.Errorf("total = %+v, want %+v", byPayment.Total, wantTotal)
+	}
+
+	cash := byPayment.Groups[0]
+	if cash.Values[0] != "Tunai" || cash.ByBucket[0].Revenue != 40_000 || cash.ByBucket[0].BasketSize != 2 || cash.ByBucket[1].Revenue != 30_000 {
+		t.Errorf("cash group = %+v, want 40000 with 2 items on the first day and 30000 on the second", cash)
+	}
+
+	byManufacturer, err := service.GetAnalyticsBetweenTime(ctx, from, to, AnalyticsBucketWeek, []AnalyticsDimension{AnalyticsDimensionManufacturer})
+	if err != nil {
+		t.Fatalf("GetAnalyticsBetweenTime() by manufacturer error = %v", err)
+	}
+
+	got := make(map[string]AnalyticsMetrics)
+	for _, group := range byManufacturer.Groups {
+		got[group.Values[0]] = group.Total
+	}
+
+	if got["Sanbe"].Revenue != 60_000 || got["Sanbe"].Transactions != 2 || got["Kimia Farma"].Revenue != 20_000 || got["Kimia Farma"].Transactions != 2 {
+		t.Errorf("by manufacturer = %+v, want Sanbe 60000 in 2 sales and Kimia Farma 20000 in 2 sales", got)
+	}
+
+	if _, err := service.GetAnalyticsBetweenTime(ctx, date.AddDate(-1, 0, 0), to, AnalyticsBucketHour, nil); !errors.Is(err, ErrInvalidAnalyticsRequest) {
+		t.Errorf("GetAnalyticsBetweenTime() with a year of hours error = %v, want ErrInvalidAnalyticsRequest", err)
+	}
+
+	if _, err := ParseAnalyticsDimensions("payment,unknown"); !errors.Is(err, ErrInvalidAnalyticsRequest) {
+		t.Errorf("ParseAnalyticsDimensions() with an unknown dimension error = %v, want ErrInvalidAnalyticsRequest", err)
+	}
+}
@@ -18,8 +18,9 @@ func DumpSalesBetweenDatesFromVmedisToDB(
 	vmedisClient *vmedisv1.Client,
 	drugsGetter DrugsGetter,
 	drugProducer UpdatedDrugProducer,
+	eventProducer EventProducer,
 ) {
-	service := NewService(db, vmedisClient, drugsGetter, drugProducer)
+	service := NewService(db, vmedisClient, drugsGetter, drugProducer, eventProducer)
 
 	if err := service.DumpSalesBetweenDatesFromVmedisToDB(ctx, startDate, endDate); err != nil {
 		log.Fatalf("DumpSalesBetweenDatesFromVmedisToDB: %s", err)
@@ -34,8 +35,9 @@ func ReconcileSalesBetweenDatesWithVmedis(
 	vmedisClient *vmedisv1.Client,
 	drugsGetter DrugsGetter,
 	drugProducer UpdatedDrugProducer,
+	eventProducer EventProducer,
 ) {
-	service := NewService(db, vmedisClient, drugsGetter, drugProducer)
+	service := NewService(db, vmedisClient, drugsGetter, drugProducer, eventProducer)
 
 	if err := service.ReconcileSalesBetweenDatesWithVmedis(ctx, startDate, endDate); err != nil {
 		log.Fatalf("ReconcileSalesBetweenDatesWithVmedis: %s", err)
@@ -48,8 +50,9 @@ func DumpTodaySalesStatisticsFromVmedisToDB(
 	vmedisClient *vmedisv1.Client,
 	drugsGetter DrugsGetter,
 	drugProducer UpdatedDrugProducer,
+	eventProducer EventProducer,
 ) {
-	service := NewService(db, vmedisClient, drugsGetter, drugProducer)
+	service := NewService(db, vmedisClient, drugsGetter, drugProducer, eventProducer)
 
 	if err := service.DumpTodaySalesStatisticsFromVmedisToDB(ctx); err != nil {
 		log.Fatalf("Failed to dump today's sales statistics from Vmedis to DB: %s", err)
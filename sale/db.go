@@ -11,6 +11,7 @@ import (
 
 	"github.com/turfaa/vmedis-proxy-api/database"
 	"github.com/turfaa/vmedis-proxy-api/database/models"
+	"github.com/turfaa/vmedis-proxy-api/outbox"
 	"github.com/turfaa/vmedis-proxy-api/pkg2/slices2"
 	vmedisv1 "github.com/turfaa/vmedis-proxy-api/vmedis/v1"
 )
@@ -108,6 +109,62 @@ LIMIT ?
 	return sales, nil
 }
 
+// analyticsRow is a sale unit joined with its sale and the manufacturer of its drug.
+// A sale without units has one row with a nil UnitID.
+type analyticsRow struct {
+	InvoiceNumber string
+	SoldAt        time.Time
+	Payment       string
+	Salesman      string
+	Cashier       string
+	Doctor        string
+	SaleTotal     float64
+	UnitID        *uint
+	UnitTotal     float64
+	PriceCategory string
+	DrugCode      string
+	DrugName      string
+	Manufacturer  string
+}
+
+// getAnalyticsRowsBetweenTime returns the sale units of the sales between the given times, oldest first.
+func (d *Database) getAnalyticsRowsBetweenTime(ctx context.Context, from time.Time, to time.Time) ([]analyticsRow, error) {
+	var rows []analyticsRow
+	if err := d.dbCtx(ctx).
+		Raw(
+			`
+SELECT
+	sales.invoice_number,
+	sales.sold_at,
+	sales.payment,
+	sales.salesman,
+	sales.cashier,
+	sales.doctor,
+	sales.total AS sale_total,
+	sale_units.id AS unit_id,
+	COALESCE(sale_units.total, 0) AS unit_total,
+	COALESCE(sale_units.price_category, '') AS price_category,
+	COALESCE(sale_units.drug_code, '') AS drug_code,
+	COALESCE(sale_units.drug_name, '') AS drug_name,
+	COALESCE(drugs.manufacturer, '') AS manufacturer
+FROM sales
+LEFT JOIN sale_units ON sale_units.invoice_number = sales.invoice_number AND sale_units.deleted_at IS NULL
+LEFT JOIN drugs ON drugs.vmedis_code = sale_units.drug_code
+WHERE sales.sold_at BETWEEN ? AND ?
+	AND sales.deleted_at IS NULL
+ORDER BY sales.sold_at, sale_units.id
+			`,
+			from,
+			to,
+		).
+		Find(&rows).
+		Error; err != nil {
+		return nil, fmt.Errorf("get analytics rows between %s and %s from DB: %w", from, to, err)
+	}
+
+	return rows, nil
+}
+
 func (d *Database) GetSalesStatisticsBetweenTime(ctx context.Context, from time.Time, to time.Time) ([]Statistics, error) {
 	var modelStats []models.SaleStatistics
 	if err := d.dbCtx(ctx).
@@ -188,6 +245,24 @@ func (d *Database) UpsertVmedisSales(ctx context.Context, vmedisSales []vmedisv1
 	})
 }
 
+// GetSalesByInvoiceNumbers returns the non-deleted sales with the given invoice numbers, with their units.
+func (d *Database) GetSalesByInvoiceNumbers(ctx context.Context, invoiceNumbers []string) ([]models.Sale, error) {
+	if len(invoiceNumbers) == 0 {
+		return nil, nil
+	}
+
+	var sales []models.Sale
+	if err := d.dbCtx(ctx).
+		Preload("SaleUnits", func(db *gorm.DB) *gorm.DB { return db.Order("id_in_sale") }).
+		Where("invoice_number IN ?", invoiceNumbers).
+		Find(&sales).
+		Error; err != nil {
+		return nil, fmt.Errorf("get sales %v from DB: %w", invoiceNumbers, err)
+	}
+
+	return sales, nil
+}
+
 // GetSaleInvoiceNumbersBetweenTime returns the invoice numbers of the
 // non-deleted sales sold between the given times.
 func (d *Database) GetSaleInvoiceNumbersBetweenTime(ctx context.Context, from time.Time, to time.Time) ([]string, error) {
@@ -229,6 +304,14 @@ func (d *Database) InsertSalesStatistics(ctx context.Context, stats Statistics)
 	return nil
 }
 
+// Transaction runs fn with a Database in a transaction.
+// The messages published to the outbox with the context passed to fn are written in the same transaction.
+func (d *Database) Transaction(ctx context.Context, fn func(ctx context.Context, tx *Database) error) error {
+	return outbox.Transaction(ctx, d.db, func(ctx context.Context, tx *gorm.DB) error {
+		return fn(ctx, NewDatabase(tx))
+	})
+}
+
 func (d *Database) dbCtx(ctx context.Context) *gorm.DB {
 	return d.db.WithContext(ctx)
 }
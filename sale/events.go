@@ -0,0 +1,67 @@
+package sale
+
+import (
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+	"github.com/turfaa/vmedis-proxy-api/kafkapb"
+)
+
+// saleRecordedEvents returns the events of the sales that are new or whose content differs from before.
+func saleRecordedEvents(before []models.Sale, after []models.Sale) []*kafkapb.SaleRecorded {
+	beforeSales := make(map[string]*kafkapb.Sale, len(before))
+	for _, sale := range before {
+		beforeSales[sale.InvoiceNumber] = saleEvent(sale)
+	}
+
+	var recorded []*kafkapb.SaleRecorded
+	for _, sale := range after {
+		afterSale := saleEvent(sale)
+		beforeSale := beforeSales[sale.InvoiceNumber]
+
+		if beforeSale != nil && proto.Equal(beforeSale, afterSale) {
+			continue
+		}
+
+		recorded = append(recorded, &kafkapb.SaleRecorded{
+			Before: beforeSale,
+			After:  afterSale,
+		})
+	}
+
+	return recorded
+}
+
+func saleEvent(sale models.Sale) *kafkapb.Sale {
+	units := make([]*kafkapb.SaleUnit, 0, len(sale.SaleUnits))
+	for _, unit := range sale.SaleUnits {
+		units = append(units, &kafkapb.SaleUnit{
+			IdInSale:      int64(unit.IDInSale),
+			DrugCode:      unit.DrugCode,
+			DrugName:      unit.DrugName,
+			Batch:         unit.Batch,
+			Amount:        unit.Amount,
+			Unit:          unit.Unit,
+			UnitPrice:     unit.UnitPrice,
+			PriceCategory: unit.PriceCategory,
+			Discount:      unit.Discount,
+			Tuslah:        unit.Tuslah,
+			Embalase:      unit.Embalase,
+			Total:         unit.Total,
+		})
+	}
+
+	return &kafkapb.Sale{
+		VmedisId:      int64(sale.VmedisID),
+		InvoiceNumber: sale.InvoiceNumber,
+		SoldAt:        timestamppb.New(sale.SoldAt),
+		Cashier:       sale.Cashier,
+		PatientName:   sale.PatientName,
+		Doctor:        sale.Doctor,
+		Salesman:      sale.Salesman,
+		Payment:       sale.Payment,
+		Total:         sale.Total,
+		Units:         units,
+	}
+}
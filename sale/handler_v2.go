@@ -5,12 +5,14 @@ import (
 	"fmt"
 	"io"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 
 	"github.com/turfaa/vmedis-proxy-api/cui"
 	"github.com/turfaa/vmedis-proxy-api/drug"
 	"github.com/turfaa/vmedis-proxy-api/money"
+	"github.com/turfaa/vmedis-proxy-api/pkg2/time2"
 )
 
 const defaultLastDrugSalesLimit = 5
@@ -80,3 +82,165 @@ func (s *ApiHandler) transformLastDrugSalesToTable(sales []DrugSale) cui.Table {
 		Rows:   rows,
 	}
 }
+
+// GetAnalytics returns the sales metrics per time bucket, grouped by the requested dimensions,
+// as a table or as chart-ready series.
+func (s *ApiHandler) GetAnalytics(c *gin.Context) {
+	var request AnalyticsRequest
+	if err := c.ShouldBindQuery(&request); err != nil {
+		c.JSON(400, gin.H{
+			"error": fmt.Sprintf("invalid request: %s", err),
+		})
+		return
+	}
+
+	if request.Format != "" && request.Format != AnalyticsFormatTable && request.Format != AnalyticsFormatSeries {
+		c.JSON(400, gin.H{
+			"error": fmt.Sprintf("invalid request: format must be %s or %s", AnalyticsFormatTable, AnalyticsFormatSeries),
+		})
+		return
+	}
+
+	dimensions, err := ParseAnalyticsDimensions(request.GroupBy)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	from, to, err := time2.GetTimeRangeFromQuery(c)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"error": fmt.Sprintf("invalid time range: %s", err),
+		})
+		return
+	}
+
+	analytics, err := s.service.GetAnalyticsBetweenTime(c.Request.Context(), from, to, request.Bucket, dimensions)
+	if err != nil {
+		if errors.Is(err, ErrInvalidAnalyticsRequest) {
+			c.JSON(400, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(500, gin.H{
+			"error": fmt.Sprintf("failed to get sales analytics: %s", err),
+		})
+		return
+	}
+
+	if request.Format == AnalyticsFormatSeries {
+		c.JSON(200, s.transformAnalyticsToSeries(analytics))
+		return
+	}
+
+	c.JSON(200, s.transformAnalyticsToTable(analytics))
+}
+
+var analyticsDimensionHeaders = map[AnalyticsDimension]string{
+	AnalyticsDimensionPayment:       "Pembayaran",
+	AnalyticsDimensionSalesman:      "Salesman",
+	AnalyticsDimensionCashier:       "Kasir",
+	AnalyticsDimensionDoctor:        "Dokter",
+	AnalyticsDimensionPriceCategory: "Kategori Harga",
+	AnalyticsDimensionManufacturer:  "Pabrik",
+	AnalyticsDimensionDrug:          "Obat",
+}
+
+// transformAnalyticsToTable returns a row for each group with sales in each bucket, and the totals in the footer.
+func (s *ApiHandler) transformAnalyticsToTable(analytics Analytics) cui.Table {
+	header := []string{"Periode"}
+	for _, dimension := range analytics.Dimensions {
+		header = append(header, analyticsDimensionHeaders[dimension])
+	}
+	header = append(header, "Pendapatan", "Transaksi", "Ukuran Keranjang", "Rata-rata Transaksi")
+
+	var rows []cui.Row
+	for i, bucket := range analytics.Buckets {
+		for j, group := range analytics.Groups {
+			metrics := group.ByBucket[i]
+			if metrics.Transactions == 0 {
+				continue
+			}
+
+			columns := []string{formatAnalyticsBucket(bucket, analytics.Bucket)}
+			for _, value := range group.Values {
+				columns = append(columns, formatAnalyticsValue(value))
+			}
+
+			rows = append(rows, cui.Row{
+				ID:      fmt.Sprintf("%d-%d", i, j),
+				Columns: append(columns, formatAnalyticsMetrics(metrics)...),
+			})
+		}
+	}
+
+	footer := make([]string, 1+len(analytics.Dimensions))
+	footer[0] = "Total"
+
+	return cui.Table{
+		Header: header,
+		Rows:   rows,
+		Footer: append(footer, formatAnalyticsMetrics(analytics.Total)...),
+	}
+}
+
+// transformAnalyticsToSeries returns a series for each group, or a single Total series without dimensions.
+func (s *ApiHandler) transformAnalyticsToSeries(analytics Analytics) AnalyticsSeriesResponse {
+	labels := make([]string, len(analytics.Buckets))
+	for i, bucket := range analytics.Buckets {
+		labels[i] = formatAnalyticsBucket(bucket, analytics.Bucket)
+	}
+
+	series := make([]AnalyticsSeries, len(analytics.Groups))
+	for i, group := range analytics.Groups {
+		name := "Total"
+		if len(group.Values) > 0 {
+			values := make([]string, len(group.Values))
+			for j, value := range group.Values {
+				values[j] = formatAnalyticsValue(value)
+			}
+			name = strings.Join(values, " / ")
+		}
+
+		series[i] = AnalyticsSeries{
+			Name:          name,
+			Revenue:       make([]float64, len(group.ByBucket)),
+			Transactions:  make([]int, len(group.ByBucket)),
+			BasketSize:    make([]float64, len(group.ByBucket)),
+			AverageTicket: make([]float64, len(group.ByBucket)),
+		}
+
+		for j, metrics := range group.ByBucket {
+			series[i].Revenue[j] = metrics.Revenue
+			series[i].Transactions[j] = metrics.Transactions
+			series[i].BasketSize[j] = metrics.BasketSize
+			series[i].AverageTicket[j] = metrics.AverageTicket
+		}
+	}
+
+	return AnalyticsSeriesResponse{
+		Labels: labels,
+		Series: series,
+	}
+}
+
+func formatAnalyticsMetrics(metrics AnalyticsMetrics) []string {
+	return []string{
+		money.FormatRupiah(metrics.Revenue),
+		strconv.Itoa(metrics.Transactions),
+		strconv.FormatFloat(metrics.BasketSize, 'f', 2, 64),
+		money.FormatRupiah(metrics.AverageTicket),
+	}
+}
+
+func formatAnalyticsValue(value string) string {
+	if value == "" {
+		return "-"
+	}
+
+	return value
+}
@@ -14,3 +14,9 @@ type DrugsGetter interface {
 type UpdatedDrugProducer interface {
 	ProduceUpdatedDrugByVmedisCode(ctx context.Context, messages []*kafkapb.UpdatedDrugByVmedisCode) error
 }
+
+type EventProducer interface {
+	ProduceSaleRecorded(ctx context.Context, events []*kafkapb.SaleRecorded) error
+	ProduceSaleDeleted(ctx context.Context, events []*kafkapb.SaleDeleted) error
+	ProduceDumpCompleted(ctx context.Context, events []*kafkapb.DumpCompleted) error
+}
@@ -195,3 +195,97 @@ func (s StatisticsSensor) ToStatisticsSensorResponse() StatisticsSensorResponse
 		TotalSales: money.FormatRupiah(s.TotalSales),
 	}
 }
+
+// AnalyticsBucket is the length of the time buckets of the sales analytics.
+type AnalyticsBucket string
+
+const (
+	AnalyticsBucketHour  AnalyticsBucket = "hour"
+	AnalyticsBucketDay   AnalyticsBucket = "day"
+	AnalyticsBucketWeek  AnalyticsBucket = "week"
+	AnalyticsBucketMonth AnalyticsBucket = "month"
+)
+
+// AnalyticsDimension is a dimension the sales analytics can be grouped by.
+type AnalyticsDimension string
+
+const (
+	AnalyticsDimensionPayment       AnalyticsDimension = "payment"
+	AnalyticsDimensionSalesman      AnalyticsDimension = "salesman"
+	AnalyticsDimensionCashier       AnalyticsDimension = "cashier"
+	AnalyticsDimensionDoctor        AnalyticsDimension = "doctor"
+	AnalyticsDimensionPriceCategory AnalyticsDimension = "priceCategory"
+	AnalyticsDimensionManufacturer  AnalyticsDimension = "manufacturer"
+	AnalyticsDimensionDrug          AnalyticsDimension = "drug"
+)
+
+// AnalyticsFormat is the response format of the sales analytics API.
+type AnalyticsFormat string
+
+const (
+	AnalyticsFormatTable  AnalyticsFormat = "table"
+	AnalyticsFormatSeries AnalyticsFormat = "series"
+)
+
+// AnalyticsRequest is the request schema of the sales analytics API.
+type AnalyticsRequest struct {
+	// Bucket is the length of the time buckets, defaulting to day.
+	Bucket AnalyticsBucket `form:"bucket"`
+
+	// GroupBy is the comma-separated dimensions to group the sales by, none by default.
+	GroupBy string `form:"groupBy"`
+
+	// Format is table or series, defaulting to table.
+	Format AnalyticsFormat `form:"format"`
+}
+
+// Analytics is the sales metrics of each group of sales in each time bucket.
+type Analytics struct {
+	Bucket     AnalyticsBucket      `json:"bucket"`
+	Dimensions []AnalyticsDimension `json:"dimensions"`
+
+	// Buckets are the start times of the time buckets.
+	Buckets []time.Time      `json:"buckets"`
+	Groups  []AnalyticsGroup `json:"groups"`
+	Total   AnalyticsMetrics `json:"total"`
+}
+
+// AnalyticsGroup is the sales metrics of the sales with the same values of the dimensions.
+type AnalyticsGroup struct {
+	// Values are the values of the dimensions, in the order of the dimensions.
+	Values []string `json:"values"`
+
+	// ByBucket is aligned with the buckets of the analytics.
+	ByBucket []AnalyticsMetrics `json:"byBucket"`
+	Total    AnalyticsMetrics   `json:"total"`
+}
+
+// AnalyticsMetrics is the metrics of a set of sales.
+type AnalyticsMetrics struct {
+	Revenue      float64 `json:"revenue"`
+	Transactions int     `json:"transactions"`
+
+	// Items is the number of sale units, one per drug and unit in a sale.
+	Items int `json:"items"`
+
+	// BasketSize is the average number of items per transaction.
+	BasketSize float64 `json:"basketSize"`
+
+	// AverageTicket is the average revenue per transaction.
+	AverageTicket float64 `json:"averageTicket"`
+}
+
+// AnalyticsSeriesResponse is the chart-ready response of the sales analytics API,
+// with one series per group aligned with the labels of the buckets.
+type AnalyticsSeriesResponse struct {
+	Labels []string          `json:"labels"`
+	Series []AnalyticsSeries `json:"series"`
+}
+
+type AnalyticsSeries struct {
+	Name          string    `json:"name"`
+	Revenue       []float64 `json:"revenue"`
+	Transactions  []int     `json:"transactions"`
+	BasketSize    []float64 `json:"basketSize"`
+	AverageTicket []float64 `json:"averageTicket"`
+}
@@ -16,6 +16,25 @@ func (nopDrugProducer) ProduceUpdatedDrugByVmedisCode(context.Context, []*kafkap
 	return nil
 }
 
+type recordingEventProducer struct {
+	recorded []*kafkapb.SaleRecorded
+	deleted  []*kafkapb.SaleDeleted
+}
+
+func (r *recordingEventProducer) ProduceSaleRecorded(_ context.Context, events []*kafkapb.SaleRecorded) error {
+	r.recorded = append(r.recorded, events...)
+	return nil
+}
+
+func (r *recordingEventProducer) ProduceSaleDeleted(_ context.Context, events []*kafkapb.SaleDeleted) error {
+	r.deleted = append(r.deleted, events...)
+	return nil
+}
+
+func (r *recordingEventProducer) ProduceDumpCompleted(context.Context, []*kafkapb.DumpCompleted) error {
+	return nil
+}
+
 // TestSoftDeleteSalesMissingFromVmedis checks that reconciliation soft-deletes
 // exactly the sales that are in the DB but no longer in Vmedis for the
 // reconciled date: sales still in Vmedis and sales sold on other dates are
@@ -29,7 +48,8 @@ func TestSoftDeleteSalesMissingFromVmedis(t *testing.T) {
 		t.Fatalf("open database: %v", err)
 	}
 
-	service := NewService(db, nil, nil, nopDrugProducer{})
+	eventProducer := &recordingEventProducer{}
+	service := NewService(db, nil, nil, nopDrugProducer{}, eventProducer)
 
 	date := time.Date(2026, 8, 7, 0, 0, 0, 0, time.Local)
 	soldAt := date.Add(10 * time.Hour)
@@ -57,6 +77,23 @@ func TestSoftDeleteSalesMissingFromVmedis(t *testing.T) {
 	if err := service.dumpSalesToDB(ctx, dumped); err != nil {
 		t.Fatalf("dump sales: %v", err)
 	}
+	if len(eventProducer.recorded) != len(dumped) {
+		t.Fatalf("recorded %d sales, want %d", len(eventProducer.recorded), len(dumped))
+	}
+	for _, event := range eventProducer.recorded {
+		if event.Before != nil {
+			t.Errorf("new sale %s has a before value", event.After.InvoiceNumber)
+		}
+	}
+
+	// Dumping the same sales again changes nothing, so nothing is recorded.
+	eventProducer.recorded = nil
+	if err := service.dumpSalesToDB(ctx, dumped); err != nil {
+		t.Fatalf("dump sales again: %v", err)
+	}
+	if len(eventProducer.recorded) != 0 {
+		t.Fatalf("recorded %d unchanged sales, want 0", len(eventProducer.recorded))
+	}
 
 	// PJ1 was deleted in Vmedis; the duplicated PJ2 pair is still there.
 	stillInVmedis := []vmedisv1.Sale{
@@ -71,6 +108,9 @@ func TestSoftDeleteSalesMissingFromVmedis(t *testing.T) {
 	if deleted != 1 {
 		t.Fatalf("soft-deleted %d sales, want 1", deleted)
 	}
+	if len(eventProducer.deleted) != 1 || eventProducer.deleted[0].InvoiceNumber != "PJ1" || eventProducer.deleted[0].Before.GetTotal() != 10 {
+		t.Fatalf("deleted events = %v, want PJ1 with its sale", eventProducer.deleted)
+	}
 
 	visible, err := service.db.GetSaleInvoiceNumbersBetweenTime(ctx, date, date.AddDate(0, 0, 1))
 	if err != nil {
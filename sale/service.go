@@ -11,6 +11,7 @@ import (
 	"golang.org/x/sync/errgroup"
 	"gorm.io/gorm"
 
+	"github.com/turfaa/vmedis-proxy-api/events"
 	"github.com/turfaa/vmedis-proxy-api/kafkapb"
 	"github.com/turfaa/vmedis-proxy-api/pkg2/time2"
 	vmedisv1 "github.com/turfaa/vmedis-proxy-api/vmedis/v1"
@@ -21,6 +22,7 @@ type Service struct {
 	vmedis       *vmedisv1.Client
 	drugsGetter  DrugsGetter
 	drugProducer UpdatedDrugProducer
+	events       EventProducer
 }
 
 func (s *Service) GetSalesBetweenTime(ctx context.Context, from time.Time, to time.Time) ([]Sale, error) {
@@ -174,13 +176,22 @@ func (s *Service) GetSalesStatisticsBetweenTime(ctx context.Context, from time.T
 // DumpSalesBetweenDatesFromVmedisToDB dumps the sales between the given dates from Vmedis to the DB.
 func (s *Service) DumpSalesBetweenDatesFromVmedisToDB(ctx context.Context, startDate time.Time, endDate time.Time) error {
 	log.Printf("Dumping sales between %s and %s from Vmedis to DB", startDate.Format(time.DateOnly), endDate.Format(time.DateOnly))
+	startedAt := time.Now()
 
 	vmedisSales, err := s.vmedis.GetAllSalesBetweenDates(ctx, startDate, endDate)
 	if err != nil {
 		return fmt.Errorf("get sales from vmedis: %w", err)
 	}
 
-	return s.dumpSalesToDB(ctx, vmedisSales)
+	if err := s.dumpSalesToDB(ctx, vmedisSales); err != nil {
+		return err
+	}
+
+	if err := s.events.ProduceDumpCompleted(ctx, []*kafkapb.DumpCompleted{events.DumpCompleted(events.DumpSales, startedAt, len(vmedisSales))}); err != nil {
+		return fmt.Errorf("produce dump completed event: %w", err)
+	}
+
+	return nil
 }
 
 func (s *Service) dumpSalesToDB(ctx context.Context, vmedisSales []vmedisv1.Sale) error {
@@ -188,35 +199,58 @@ func (s *Service) dumpSalesToDB(ctx context.Context, vmedisSales []vmedisv1.Sale
 
 	vmedisSales = s.makeSalesInvoiceNumbersUnique(vmedisSales)
 
+	// The sales, their events, and the updated drug messages of each batch are written in one transaction,
+	// so the drugs are always refreshed once the sales are stored.
 	batchNum := 1
 	totalBatches := (len(vmedisSales)-1)/1000 + 1
 	for batch := range slices.Chunk(vmedisSales, 1000) {
 		log.Printf("[%d/%d] Upserting %d sales to DB", batchNum, totalBatches, len(batch))
 
-		if err := s.db.UpsertVmedisSales(ctx, batch); err != nil {
-			return fmt.Errorf("upsert sales to DB: %w", err)
+		invoiceNumbers := make([]string, 0, len(batch))
+		var updatedDrugs []*kafkapb.UpdatedDrugByVmedisCode
+		for _, sale := range batch {
+			invoiceNumbers = append(invoiceNumbers, sale.InvoiceNumber)
+
+			for _, saleUnit := range sale.SaleUnits {
+				updatedDrugs = append(updatedDrugs, &kafkapb.UpdatedDrugByVmedisCode{
+					RequestKey: fmt.Sprintf("sale:%s:%s", sale.InvoiceNumber, saleUnit.DrugCode),
+					VmedisCode: saleUnit.DrugCode,
+				})
+			}
 		}
 
-		batchNum++
-	}
+		if err := s.db.Transaction(ctx, func(ctx context.Context, tx *Database) error {
+			before, err := tx.GetSalesByInvoiceNumbers(ctx, invoiceNumbers)
+			if err != nil {
+				return fmt.Errorf("get sales before upsert: %w", err)
+			}
+
+			if err := tx.UpsertVmedisSales(ctx, batch); err != nil {
+				return fmt.Errorf("upsert sales to DB: %w", err)
+			}
 
-	log.Printf("Dumped sales from Vmedis to DB, producing updated drug messages")
+			after, err := tx.GetSalesByInvoiceNumbers(ctx, invoiceNumbers)
+			if err != nil {
+				return fmt.Errorf("get sales after upsert: %w", err)
+			}
 
-	messages := make([]*kafkapb.UpdatedDrugByVmedisCode, 0, len(vmedisSales))
-	for _, sale := range vmedisSales {
-		for _, saleUnit := range sale.SaleUnits {
-			messages = append(messages, &kafkapb.UpdatedDrugByVmedisCode{
-				RequestKey: fmt.Sprintf("sale:%s:%s", sale.InvoiceNumber, saleUnit.DrugCode),
-				VmedisCode: saleUnit.DrugCode,
-			})
+			if err := s.events.ProduceSaleRecorded(ctx, saleRecordedEvents(before, after)); err != nil {
+				return fmt.Errorf("produce sale recorded events: %w", err)
+			}
+
+			if err := s.drugProducer.ProduceUpdatedDrugByVmedisCode(ctx, updatedDrugs); err != nil {
+				return fmt.Errorf("produce updated drug messages: %w", err)
+			}
+
+			return nil
+		}); err != nil {
+			return fmt.Errorf("dump sales batch %d: %w", batchNum, err)
 		}
-	}
 
-	if err := s.drugProducer.ProduceUpdatedDrugByVmedisCode(ctx, messages); err != nil {
-		return fmt.Errorf("produce updated drug messages: %w", err)
+		batchNum++
 	}
 
-	log.Printf("Produced updated drug messages, finished dumping sales from Vmedis to DB")
+	log.Printf("Finished dumping sales from Vmedis to DB")
 
 	return nil
 }
@@ -302,15 +336,36 @@ func (s *Service) softDeleteSalesMissingFromVmedis(ctx context.Context, date tim
 		return 0, fmt.Errorf("get sale invoice numbers at %s from DB: %w", date.Format(time.DateOnly), err)
 	}
 
-	deleted := 0
+	var missingInvoiceNumbers []string
 	for _, invoiceNumber := range dbInvoiceNumbers {
-		if _, ok := inVmedis[invoiceNumber]; ok {
-			continue
+		if _, ok := inVmedis[invoiceNumber]; !ok {
+			missingInvoiceNumbers = append(missingInvoiceNumbers, invoiceNumber)
 		}
+	}
+
+	missingSales, err := s.db.GetSalesByInvoiceNumbers(ctx, missingInvoiceNumbers)
+	if err != nil {
+		return 0, fmt.Errorf("get sales missing from vmedis at %s from DB: %w", date.Format(time.DateOnly), err)
+	}
+
+	deleted := 0
+	for _, sale := range missingSales {
+		log.Printf("Sale %s no longer exists in Vmedis, soft-deleting it", sale.InvoiceNumber)
+		if err := s.db.Transaction(ctx, func(ctx context.Context, tx *Database) error {
+			if err := tx.DeleteSaleByInvoiceNumber(ctx, sale.InvoiceNumber); err != nil {
+				return fmt.Errorf("soft-delete sale %s: %w", sale.InvoiceNumber, err)
+			}
+
+			if err := s.events.ProduceSaleDeleted(ctx, []*kafkapb.SaleDeleted{{
+				InvoiceNumber: sale.InvoiceNumber,
+				Before:        saleEvent(sale),
+			}}); err != nil {
+				return fmt.Errorf("produce sale %s deleted event: %w", sale.InvoiceNumber, err)
+			}
 
-		log.Printf("Sale %s no longer exists in Vmedis, soft-deleting it", invoiceNumber)
-		if err := s.db.DeleteSaleByInvoiceNumber(ctx, invoiceNumber); err != nil {
-			return deleted, fmt.Errorf("soft-delete sale %s: %w", invoiceNumber, err)
+			return nil
+		}); err != nil {
+			return deleted, err
 		}
 
 		deleted++
@@ -345,11 +400,13 @@ func NewService(
 	vmedisClient *vmedisv1.Client,
 	drugsGetter DrugsGetter,
 	drugProducer UpdatedDrugProducer,
+	eventProducer EventProducer,
 ) *Service {
 	return &Service{
 		db:           NewDatabase(db),
 		vmedis:       vmedisClient,
 		drugsGetter:  drugsGetter,
 		drugProducer: drugProducer,
+		events:       eventProducer,
 	}
 }
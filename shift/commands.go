@@ -18,8 +18,9 @@ func DumpShiftsFromVmedisToDB(
 	db *gorm.DB,
 	redisClient redis.UniversalClient,
 	vmedisClient *vmedisv1.Client,
+	eventProducer EventProducer,
 ) {
-	service := NewService(db, redisClient, vmedisClient)
+	service := NewService(db, redisClient, vmedisClient, eventProducer)
 
 	if err := service.DumpShiftsFromVmedisToDB(ctx, from, to); err != nil {
 		log.Fatalf("DumpShiftsBetweenTimesFromVmedisToDB: %s", err)
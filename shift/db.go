@@ -10,6 +10,7 @@ import (
 	"github.com/redis/go-redis/v9"
 
 	"github.com/turfaa/vmedis-proxy-api/database/models"
+	"github.com/turfaa/vmedis-proxy-api/outbox"
 	"github.com/turfaa/vmedis-proxy-api/pkg2/slices2"
 	"github.com/turfaa/vmedis-proxy-api/vmedis/v1"
 
@@ -74,6 +75,20 @@ func (d *Database) GetShiftsBetween(ctx context.Context, from time.Time, to time
 	return shifts, nil
 }
 
+// GetShiftsByVmedisIDs returns the shifts with the given vmedis IDs.
+func (d *Database) GetShiftsByVmedisIDs(ctx context.Context, vmedisIDs []int) ([]models.Shift, error) {
+	if len(vmedisIDs) == 0 {
+		return nil, nil
+	}
+
+	var shifts []models.Shift
+	if err := d.dbCtx(ctx).Where("vmedis_id IN ?", vmedisIDs).Find(&shifts).Error; err != nil {
+		return nil, fmt.Errorf("failed to get shifts by vmedis ids from db: %w", err)
+	}
+
+	return shifts, nil
+}
+
 func (d *Database) UpsertVmedisShifts(ctx context.Context, shifts []vmedisv1.Shift) error {
 	if len(shifts) == 0 {
 		return nil
@@ -108,6 +123,14 @@ func (d *Database) UpsertVmedisShifts(ctx context.Context, shifts []vmedisv1.Shi
 	return nil
 }
 
+// Transaction runs fn with a Database in a transaction.
+// The messages published to the outbox with the context passed to fn are written in the same transaction.
+func (d *Database) Transaction(ctx context.Context, fn func(ctx context.Context, tx *Database) error) error {
+	return outbox.Transaction(ctx, d.db, func(ctx context.Context, tx *gorm.DB) error {
+		return fn(ctx, NewDatabase(tx))
+	})
+}
+
 func (d *Database) dbCtx(ctx context.Context) *gorm.DB {
 	return d.db.WithContext(ctx)
 }
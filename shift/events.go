@@ -0,0 +1,45 @@
+package shift
+
+import (
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+	"github.com/turfaa/vmedis-proxy-api/kafkapb"
+)
+
+// shiftClosedEvents returns the events of the shifts that are closed now but were missing or still open before.
+func shiftClosedEvents(before []models.Shift, after []models.Shift) []*kafkapb.ShiftClosed {
+	closedBefore := make(map[int]bool, len(before))
+	for _, shift := range before {
+		closedBefore[shift.VmedisID] = !shift.EndedAt.IsZero()
+	}
+
+	var closed []*kafkapb.ShiftClosed
+	for _, shift := range after {
+		if shift.EndedAt.IsZero() || closedBefore[shift.VmedisID] {
+			continue
+		}
+
+		closed = append(closed, &kafkapb.ShiftClosed{
+			Shift: shiftEvent(shift),
+		})
+	}
+
+	return closed
+}
+
+func shiftEvent(shift models.Shift) *kafkapb.Shift {
+	return &kafkapb.Shift{
+		VmedisId:            int64(shift.VmedisID),
+		Code:                shift.Code,
+		Cashier:             shift.Cashier,
+		StartedAt:           timestamppb.New(shift.StartedAt),
+		EndedAt:             timestamppb.New(shift.EndedAt),
+		InitialCash:         shift.InitialCash,
+		ExpectedFinalCash:   shift.ExpectedFinalCash,
+		ActualFinalCash:     shift.ActualFinalCash,
+		FinalCashDifference: shift.FinalCashDifference,
+		Supervisor:          shift.Supervisor,
+		Notes:               shift.Notes,
+	}
+}
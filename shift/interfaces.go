@@ -0,0 +1,12 @@
+package shift
+
+import (
+	"context"
+
+	"github.com/turfaa/vmedis-proxy-api/kafkapb"
+)
+
+type EventProducer interface {
+	ProduceShiftClosed(ctx context.Context, events []*kafkapb.ShiftClosed) error
+	ProduceDumpCompleted(ctx context.Context, events []*kafkapb.DumpCompleted) error
+}
@@ -7,6 +7,8 @@ import (
 	"time"
 
 	"github.com/redis/go-redis/v9"
+	"github.com/turfaa/vmedis-proxy-api/events"
+	"github.com/turfaa/vmedis-proxy-api/kafkapb"
 	"github.com/turfaa/vmedis-proxy-api/pkg2/slices2"
 	"github.com/turfaa/vmedis-proxy-api/vmedis/v1"
 
@@ -17,13 +19,15 @@ type Service struct {
 	db           *Database
 	redisDB      *RedisDatabase
 	vmedisClient *vmedisv1.Client
+	events       EventProducer
 }
 
-func NewService(db *gorm.DB, redisClient redis.UniversalClient, vmedisClient *vmedisv1.Client) *Service {
+func NewService(db *gorm.DB, redisClient redis.UniversalClient, vmedisClient *vmedisv1.Client, eventProducer EventProducer) *Service {
 	return &Service{
 		db:           NewDatabase(db),
 		redisDB:      NewRedisDatabase(redisClient),
 		vmedisClient: vmedisClient,
+		events:       eventProducer,
 	}
 }
 
@@ -72,17 +76,44 @@ func (s *Service) DumpShiftsFromVmedisToDB(ctx context.Context, from time.Time,
 		}
 	}()
 
+	startedAt := time.Now()
 	vmedisShifts, err := s.vmedisClient.GetAllShiftsBetweenTimes(ctx, from, to)
 	if err != nil {
 		return fmt.Errorf("get all shifts from vmedis between %s and %s: %w", from, to, err)
 	}
 
+	vmedisIDs := slices2.Map(vmedisShifts, func(shift vmedisv1.Shift) int { return shift.ID })
+
 	log.Printf("Dumping %d shifts from vmedis to db", len(vmedisShifts))
-	if err := s.db.UpsertVmedisShifts(ctx, vmedisShifts); err != nil {
-		return fmt.Errorf("upsert vmedis shifts to db: %w", err)
+	if err := s.db.Transaction(ctx, func(ctx context.Context, tx *Database) error {
+		before, err := tx.GetShiftsByVmedisIDs(ctx, vmedisIDs)
+		if err != nil {
+			return fmt.Errorf("get shifts before upsert: %w", err)
+		}
+
+		if err := tx.UpsertVmedisShifts(ctx, vmedisShifts); err != nil {
+			return fmt.Errorf("upsert vmedis shifts to db: %w", err)
+		}
+
+		after, err := tx.GetShiftsByVmedisIDs(ctx, vmedisIDs)
+		if err != nil {
+			return fmt.Errorf("get shifts after upsert: %w", err)
+		}
+
+		if err := s.events.ProduceShiftClosed(ctx, shiftClosedEvents(before, after)); err != nil {
+			return fmt.Errorf("produce shift closed events: %w", err)
+		}
+
+		return nil
+	}); err != nil {
+		return err
 	}
 	log.Printf("Dumped %d shifts from vmedis to db", len(vmedisShifts))
 
+	if err := s.events.ProduceDumpCompleted(ctx, []*kafkapb.DumpCompleted{events.DumpCompleted(events.DumpShifts, startedAt, len(vmedisShifts))}); err != nil {
+		return fmt.Errorf("produce dump completed event: %w", err)
+	}
+
 	return nil
 }
 
@@ -3,21 +3,63 @@ package stockopname
 import (
 	"context"
 	"log"
+	"time"
 
 	"gorm.io/gorm"
 
 	"github.com/turfaa/vmedis-proxy-api/vmedis/v1"
 )
 
-func DumpTodayStockOpnames(
+func DumpStockOpnamesBetweenDatesFromVmedisToDB(
 	ctx context.Context,
+	startDate time.Time,
+	endDate time.Time,
 	db *gorm.DB,
 	vmedisClient *vmedisv1.Client,
 	drugProducer UpdatedDrugProducer,
+	eventProducer EventProducer,
+	drugsGetter DrugsGetter,
 ) {
-	service := NewService(db, vmedisClient, drugProducer)
+	service := NewService(db, vmedisClient, drugProducer, eventProducer, drugsGetter)
 
-	if err := service.DumpTodayStockOpnamesFromVmedisToDB(ctx); err != nil {
-		log.Fatalf("DumpTodayStockOpnamesFromVmedisToDB: %s", err)
+	if err := service.DumpStockOpnamesBetweenDatesFromVmedisToDB(ctx, startDate, endDate); err != nil {
+		log.Fatalf("DumpStockOpnamesBetweenDatesFromVmedisToDB: %s", err)
 	}
 }
+
+func ReconcileStockOpnamesBetweenDatesWithVmedis(
+	ctx context.Context,
+	startDate time.Time,
+	endDate time.Time,
+	db *gorm.DB,
+	vmedisClient *vmedisv1.Client,
+	drugProducer UpdatedDrugProducer,
+	eventProducer EventProducer,
+	drugsGetter DrugsGetter,
+) {
+	service := NewService(db, vmedisClient, drugProducer, eventProducer, drugsGetter)
+
+	if err := service.ReconcileStockOpnamesBetweenDatesWithVmedis(ctx, startDate, endDate); err != nil {
+		log.Fatalf("ReconcileStockOpnamesBetweenDatesWithVmedis: %s", err)
+	}
+}
+
+// PlanCycleCount plans the cycle count from the start date of the request, replacing the tasks from that date.
+func PlanCycleCount(
+	ctx context.Context,
+	db *gorm.DB,
+	vmedisClient *vmedisv1.Client,
+	drugProducer UpdatedDrugProducer,
+	eventProducer EventProducer,
+	drugsGetter DrugsGetter,
+	request CycleCountPlanRequest,
+) {
+	service := NewService(db, vmedisClient, drugProducer, eventProducer, drugsGetter)
+
+	plan, err := service.PlanCycleCount(ctx, request)
+	if err != nil {
+		log.Fatalf("PlanCycleCount: %s", err)
+	}
+
+	log.Printf("Planned %d tasks of %d drugs from %s to %s for %d staff members", plan.Tasks, plan.Drugs, plan.StartDate, plan.EndDate, len(plan.Staff))
+}
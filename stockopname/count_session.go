@@ -0,0 +1,414 @@
+package stockopname
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+	"github.com/turfaa/vmedis-proxy-api/drug"
+	"github.com/turfaa/vmedis-proxy-api/pkg2/slices2"
+	"github.com/turfaa/vmedis-proxy-api/pkg2/time2"
+)
+
+const countSessionSheetName = "Stok Opname"
+
+var (
+	// ErrCountSessionApproved is returned when an approved counting session is changed or approved again.
+	ErrCountSessionApproved = errors.New("count session is already approved")
+
+	// ErrInvalidCountSession is returned when a counting session or one of its lines is invalid.
+	ErrInvalidCountSession = errors.New("invalid count session")
+)
+
+// GetCountSessions returns the latest counting sessions without their lines, newest first.
+func (s *Service) GetCountSessions(ctx context.Context) ([]CountSession, error) {
+	sessions, err := s.db.GetCountSessions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get count sessions: %w", err)
+	}
+
+	return slices2.Map(sessions, FromDBStockCountSession), nil
+}
+
+// GetCountSession returns the counting session with its lines.
+func (s *Service) GetCountSession(ctx context.Context, id uint) (CountSession, error) {
+	session, err := s.db.GetCountSession(ctx, id)
+	if err != nil {
+		return CountSession{}, fmt.Errorf("get count session %d: %w", id, err)
+	}
+
+	return FromDBStockCountSession(session), nil
+}
+
+func (s *Service) CreateCountSession(ctx context.Context, request CreateCountSessionRequest, createdBy string) (CountSession, error) {
+	date := time2.BeginningOfToday()
+	if request.Date != "" {
+		var err error
+		date, err = time2.BeginningOfDate(request.Date)
+		if err != nil {
+			return CountSession{}, fmt.Errorf("%w: date: %s", ErrInvalidCountSession, err)
+		}
+	}
+
+	session := models.StockCountSession{
+		Date:      datatypes.Date(date),
+		Name:      strings.TrimSpace(request.Name),
+		CreatedBy: createdBy,
+	}
+
+	if err := s.db.CreateCountSession(ctx, &session); err != nil {
+		return CountSession{}, fmt.Errorf("create count session: %w", err)
+	}
+
+	return FromDBStockCountSession(session), nil
+}
+
+// RecordCountSessionLine records the counted quantity of a batch of a drug in one of its units,
+// overwriting the line of the same drug, batch, and unit.
+func (s *Service) RecordCountSessionLine(ctx context.Context, sessionID uint, request CountSessionLineRequest, countedBy string) (CountSessionLine, error) {
+	if _, err := s.getOpenCountSession(ctx, sessionID); err != nil {
+		return CountSessionLine{}, err
+	}
+
+	quantity, err := strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(request.Quantity), ",", "."), 64)
+	if err != nil || quantity < 0 {
+		return CountSessionLine{}, fmt.Errorf("%w: quantity %q must be a non-negative number", ErrInvalidCountSession, request.Quantity)
+	}
+
+	d, unit, err := s.getCountedDrug(ctx, request)
+	if err != nil {
+		return CountSessionLine{}, err
+	}
+
+	drugUnit, ok := countedUnit(d, unit)
+	if !ok {
+		return CountSessionLine{}, fmt.Errorf("%w: unit %q isn't a unit of drug %s", ErrInvalidCountSession, unit, d.VmedisCode)
+	}
+
+	line := models.StockCountLine{
+		SessionID: sessionID,
+		DrugCode:  d.VmedisCode,
+		DrugName:  d.Name,
+		BatchCode: strings.TrimSpace(request.BatchCode),
+		Unit:      drugUnit,
+		Quantity:  quantity,
+		CountedBy: countedBy,
+	}
+
+	if err := s.db.UpsertCountSessionLine(ctx, &line); err != nil {
+		return CountSessionLine{}, fmt.Errorf("record count session line: %w", err)
+	}
+
+	return FromDBStockCountLine(line), nil
+}
+
+func (s *Service) DeleteCountSessionLine(ctx context.Context, sessionID uint, lineID uint) error {
+	if _, err := s.getOpenCountSession(ctx, sessionID); err != nil {
+		return err
+	}
+
+	if err := s.db.DeleteCountSessionLine(ctx, sessionID, lineID); err != nil {
+		return fmt.Errorf("delete count session line: %w", err)
+	}
+
+	return nil
+}
+
+// ApproveCountSession approves the counts of the session, after which its lines can't be changed.
+func (s *Service) ApproveCountSession(ctx context.Context, id uint, approvedBy string) (CountSession, error) {
+	session, err := s.getOpenCountSession(ctx, id)
+	if err != nil {
+		return CountSession{}, err
+	}
+
+	if len(session.Lines) == 0 {
+		return CountSession{}, fmt.Errorf("%w: count session %d has no lines", ErrInvalidCountSession, id)
+	}
+
+	approved, err := s.db.ApproveCountSession(ctx, id, approvedBy)
+	if err != nil {
+		return CountSession{}, fmt.Errorf("approve count session: %w", err)
+	}
+
+	if !approved {
+		return CountSession{}, ErrCountSessionApproved
+	}
+
+	log.Printf("Count session %d approved by %s", id, approvedBy)
+	return s.GetCountSession(ctx, id)
+}
+
+// GetCountSessionVariances compares the counted quantities of each drug of the session with its current stocks.
+func (s *Service) GetCountSessionVariances(ctx context.Context, id uint) ([]CountSessionVariance, error) {
+	session, err := s.GetCountSession(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	drugCodes := slices.Compact(slices.Sorted(slices.Values(slices2.Map(session.Lines, func(line CountSessionLine) string {
+		return line.DrugCode
+	}))))
+
+	drugs, err := s.drugsGetter.GetDrugsByVmedisCodes(ctx, drugCodes)
+	if err != nil {
+		return nil, fmt.Errorf("get drugs: %w", err)
+	}
+
+	return countSessionVariances(session.Lines, drugs), nil
+}
+
+// GetCountSessionComparisons compares each line of the session with the stock opname entered into Vmedis for it,
+// from the stock opnames dumped since the date of the session.
+func (s *Service) GetCountSessionComparisons(ctx context.Context, id uint) ([]CountSessionComparison, error) {
+	session, err := s.db.GetCountSession(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("get count session %d: %w", id, err)
+	}
+
+	drugCodes := slices2.Map(session.Lines, func(line models.StockCountLine) string { return line.DrugCode })
+
+	stockOpnames, err := s.db.getStockOpnamesOfDrugsSince(ctx, slices.Compact(slices.Sorted(slices.Values(drugCodes))), dateOf(time.Time(session.Date)))
+	if err != nil {
+		return nil, fmt.Errorf("get stock opnames: %w", err)
+	}
+
+	comparisons := make([]CountSessionComparison, len(session.Lines))
+	for i, line := range session.Lines {
+		comparisons[i].Line = FromDBStockCountLine(line)
+
+		for _, so := range stockOpnames {
+			if so.DrugCode == line.DrugCode &&
+				strings.EqualFold(strings.TrimSpace(so.BatchCode), line.BatchCode) &&
+				strings.EqualFold(so.Unit, line.Unit) {
+				stockOpname := FromModelsStockOpname(so)
+				comparisons[i].StockOpname = &stockOpname
+				break
+			}
+		}
+	}
+
+	return comparisons, nil
+}
+
+// getOpenCountSession returns the session if it isn't approved yet.
+func (s *Service) getOpenCountSession(ctx context.Context, id uint) (CountSession, error) {
+	session, err := s.GetCountSession(ctx, id)
+	if err != nil {
+		return CountSession{}, err
+	}
+
+	if session.Approved() {
+		return CountSession{}, ErrCountSessionApproved
+	}
+
+	return session, nil
+}
+
+// getCountedDrug returns the drug of the line, from its barcode or its drug code, and the unit of the line.
+// The unit of a barcode is used when the line has no unit.
+func (s *Service) getCountedDrug(ctx context.Context, request CountSessionLineRequest) (drug.Drug, string, error) {
+	unit := strings.TrimSpace(request.Unit)
+
+	if barcode := strings.TrimSpace(request.Barcode); barcode != "" {
+		d, barcodeUnit, err := s.drugsGetter.GetDrugByBarcode(ctx, barcode)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return drug.Drug{}, "", fmt.Errorf("%w: barcode %s not found", ErrInvalidCountSession, barcode)
+			}
+
+			return drug.Drug{}, "", fmt.Errorf("get drug by barcode %s: %w", barcode, err)
+		}
+
+		if unit == "" {
+			unit = barcodeUnit
+		}
+
+		return d, unit, nil
+	}
+
+	drugCode := strings.TrimSpace(request.DrugCode)
+	if drugCode == "" {
+		return drug.Drug{}, "", fmt.Errorf("%w: either the barcode or the drug code is required", ErrInvalidCountSession)
+	}
+
+	drugs, err := s.drugsGetter.GetDrugsByVmedisCodes(ctx, []string{drugCode})
+	if err != nil {
+		return drug.Drug{}, "", fmt.Errorf("get drug %s: %w", drugCode, err)
+	}
+
+	if len(drugs) == 0 {
+		return drug.Drug{}, "", fmt.Errorf("%w: drug %s not found", ErrInvalidCountSession, drugCode)
+	}
+
+	return drugs[0], unit, nil
+}
+
+// countedUnit returns the unit of the drug matching the given unit case-insensitively.
+// An empty unit is the only unit of a drug with a single unit.
+func countedUnit(d drug.Drug, unit string) (string, bool) {
+	if unit == "" {
+		if len(d.Units) == 1 {
+			return d.Units[0].Unit, true
+		}
+
+		return "", false
+	}
+
+	for _, u := range d.Units {
+		if strings.EqualFold(u.Unit, unit) {
+			return u.Unit, true
+		}
+	}
+
+	return "", false
+}
+
+// countSessionVariances sums up the counted quantities of each drug over its batches,
+// and compares them with its stocks in the smallest unit.
+// A drug whose units can't be converted only compares the quantities of the same unit.
+func countSessionVariances(lines []CountSessionLine, drugs []drug.Drug) []CountSessionVariance {
+	drugsByCode := make(map[string]drug.Drug, len(drugs))
+	for _, d := range drugs {
+		drugsByCode[d.VmedisCode] = d
+	}
+
+	var variances []CountSessionVariance
+	indexByCode := make(map[string]int)
+	for _, line := range lines {
+		i, ok := indexByCode[line.DrugCode]
+		if !ok {
+			i = len(variances)
+			indexByCode[line.DrugCode] = i
+			variances = append(variances, CountSessionVariance{
+				DrugCode:     line.DrugCode,
+				DrugName:     line.DrugName,
+				SmallestUnit: line.Unit,
+			})
+		}
+
+		variances[i].CountedStocks = addCountedStock(variances[i].CountedStocks, line.Unit, line.Quantity)
+	}
+
+	for i := range variances {
+		variance := &variances[i]
+
+		d := drugsByCode[variance.DrugCode]
+		units := slices.Clone(d.Units)
+		slices.SortFunc(units, func(a, b drug.Unit) int {
+			return cmp.Compare(a.UnitOrder, b.UnitOrder)
+		})
+
+		for _, stock := range d.Stocks {
+			variance.SystemStocks = addCountedStock(variance.SystemStocks, stock.Unit, stock.Quantity)
+		}
+
+		sizes, ok := drug.UnitSizes(units)
+		if !ok || len(units) == 0 {
+			variance.Difference = sumStocks(variance.CountedStocks, variance.SmallestUnit) - sumStocks(variance.SystemStocks, variance.SmallestUnit)
+			continue
+		}
+
+		variance.SmallestUnit = units[0].Unit
+		for _, stock := range variance.CountedStocks {
+			variance.Difference += stock.Quantity * sizes[strings.ToLower(stock.Unit)]
+		}
+
+		for _, stock := range variance.SystemStocks {
+			variance.Difference -= stock.Quantity * sizes[strings.ToLower(stock.Unit)]
+		}
+	}
+
+	return variances
+}
+
+func addCountedStock(stocks []CountedStock, unit string, quantity float64) []CountedStock {
+	for i := range stocks {
+		if strings.EqualFold(stocks[i].Unit, unit) {
+			stocks[i].Quantity += quantity
+			return stocks
+		}
+	}
+
+	return append(stocks, CountedStock{Unit: unit, Quantity: quantity})
+}
+
+func sumStocks(stocks []CountedStock, unit string) float64 {
+	var total float64
+	for _, stock := range stocks {
+		if strings.EqualFold(stock.Unit, unit) {
+			total += stock.Quantity
+		}
+	}
+
+	return total
+}
+
+// WriteCountSessionXLSX writes the lines of the session as an XLSX file, in the columns entered into Vmedis.
+func WriteCountSessionXLSX(w io.Writer, session CountSession) error {
+	f := excelize.NewFile()
+	defer func() {
+		if err := f.Close(); err != nil {
+			log.Printf("failed to close file: %s", err)
+		}
+	}()
+
+	if _, err := f.NewSheet(countSessionSheetName); err != nil {
+		return fmt.Errorf("create excel sheet: %w", err)
+	}
+
+	var errs []error
+
+	header := []string{
+		"Tanggal",
+		"Kode Obat",
+		"Nama Obat",
+		"No. Batch",
+		"Satuan",
+		"Jumlah Fisik",
+	}
+	for i, h := range header {
+		errs = append(errs, f.SetCellStr(countSessionSheetName, countSessionCell(i, 1), h))
+	}
+
+	for i, line := range session.Lines {
+		row := i + 2
+
+		errs = append(errs, f.SetCellStr(countSessionSheetName, countSessionCell(0, row), session.Date))
+		errs = append(errs, f.SetCellStr(countSessionSheetName, countSessionCell(1, row), line.DrugCode))
+		errs = append(errs, f.SetCellStr(countSessionSheetName, countSessionCell(2, row), line.DrugName))
+		errs = append(errs, f.SetCellStr(countSessionSheetName, countSessionCell(3, row), line.BatchCode))
+		errs = append(errs, f.SetCellStr(countSessionSheetName, countSessionCell(4, row), line.Unit))
+		errs = append(errs, f.SetCellFloat(countSessionSheetName, countSessionCell(5, row), line.Quantity, -1, 64))
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("fill count session sheet: %w", err)
+	}
+
+	if err := f.DeleteSheet("Sheet1"); err != nil {
+		return fmt.Errorf("delete default XLSX sheet: %w", err)
+	}
+
+	if err := f.Write(w); err != nil {
+		return fmt.Errorf("write XLSX: %w", err)
+	}
+
+	return nil
+}
+
+func countSessionCell(column int, row int) string {
+	cell, _ := excelize.CoordinatesToCellName(column+1, row)
+	return cell
+}
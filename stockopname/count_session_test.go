@@ -0,0 +1,188 @@
+package stockopname_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+	"github.com/turfaa/vmedis-proxy-api/drug"
+	"github.com/turfaa/vmedis-proxy-api/stockopname"
+)
+
+var paracetamol = drug.Drug{
+	VmedisCode: "PCT",
+	Name:       "Paracetamol",
+	Units: []drug.Unit{
+		{Unit: "Strip", ParentUnit: "Tablet", ConversionToParentUnit: 10, UnitOrder: 1},
+		{Unit: "Tablet", UnitOrder: 0},
+	},
+	Stocks: []drug.Stock{{Unit: "Strip", Quantity: 3}},
+}
+
+// TestCountSession checks that the counted lines of a session are compared with the current stocks,
+// and can't be changed once the session is approved.
+func TestCountSession(t *testing.T) {
+	ctx := context.Background()
+	service := stockopname.NewService(newTestDB(t), nil, nil, nil, drugsGetter{paracetamol})
+
+	session, err := service.CreateCountSession(ctx, stockopname.CreateCountSessionRequest{Name: "Rak A"}, "staff@example.com")
+	if err != nil {
+		t.Fatalf("CreateCountSession() error = %v", err)
+	}
+
+	lines := []stockopname.CountSessionLineRequest{
+		{DrugCode: "PCT", BatchCode: "A1", Unit: "strip", Quantity: "1"},
+		// The same batch and unit is recounted, from the barcode of a Strip.
+		{Barcode: "8991234567890", BatchCode: "A1", Quantity: "2"},
+		{DrugCode: "PCT", BatchCode: "B2", Unit: "Tablet", Quantity: "5"},
+	}
+
+	for _, line := range lines {
+		if _, err := service.RecordCountSessionLine(ctx, session.ID, line, "staff@example.com"); err != nil {
+			t.Fatalf("RecordCountSessionLine(%+v) error = %v", line, err)
+		}
+	}
+
+	if _, err := service.RecordCountSessionLine(ctx, session.ID, stockopname.CountSessionLineRequest{DrugCode: "PCT", Unit: "Box", Quantity: "1"}, "staff@example.com"); !errors.Is(err, stockopname.ErrInvalidCountSession) {
+		t.Errorf("RecordCountSessionLine() with an unknown unit error = %v, want ErrInvalidCountSession", err)
+	}
+
+	variances, err := service.GetCountSessionVariances(ctx, session.ID)
+	if err != nil {
+		t.Fatalf("GetCountSessionVariances() error = %v", err)
+	}
+
+	// 2 Strips and 5 Tablets are 25 Tablets, 5 less than the 3 Strips in stock.
+	if len(variances) != 1 || variances[0].Difference != -5 || variances[0].SmallestUnit != "Tablet" {
+		t.Errorf("GetCountSessionVariances() = %+v, want a -5 Tablet difference", variances)
+	}
+
+	approved, err := service.ApproveCountSession(ctx, session.ID, "admin@example.com")
+	if err != nil {
+		t.Fatalf("ApproveCountSession() error = %v", err)
+	}
+
+	if !approved.Approved() || approved.ApprovedBy != "admin@example.com" || len(approved.Lines) != 2 {
+		t.Errorf("approved session = %+v, want approved by admin@example.com with 2 lines", approved)
+	}
+
+	if _, err := service.RecordCountSessionLine(ctx, session.ID, lines[0], "staff@example.com"); !errors.Is(err, stockopname.ErrCountSessionApproved) {
+		t.Errorf("RecordCountSessionLine() after approval error = %v, want ErrCountSessionApproved", err)
+	}
+
+	if _, err := service.ApproveCountSession(ctx, session.ID, "admin@example.com"); !errors.Is(err, stockopname.ErrCountSessionApproved) {
+		t.Errorf("ApproveCountSession() again error = %v, want ErrCountSessionApproved", err)
+	}
+
+	var buf bytes.Buffer
+	if err := stockopname.WriteCountSessionXLSX(&buf, approved); err != nil {
+		t.Fatalf("WriteCountSessionXLSX() error = %v", err)
+	}
+
+	f, err := excelize.OpenReader(&buf)
+	if err != nil {
+		t.Fatalf("open XLSX: %v", err)
+	}
+
+	rows, err := f.GetRows("Stok Opname")
+	if err != nil || len(rows) != 3 {
+		t.Errorf("XLSX rows = %v, %v, want the header and 2 lines", rows, err)
+	}
+}
+
+// TestCountSessionComparisons checks that each line is compared with the stock opname of its batch and unit
+// dumped from Vmedis since the date of the session.
+func TestCountSessionComparisons(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	service := stockopname.NewService(db, nil, nil, nil, drugsGetter{paracetamol})
+
+	date := lastMonday()
+	session, err := service.CreateCountSession(ctx, stockopname.CreateCountSessionRequest{Name: "Rak A", Date: date.Format(time.DateOnly)}, "staff@example.com")
+	if err != nil {
+		t.Fatalf("CreateCountSession() error = %v", err)
+	}
+
+	for _, line := range []stockopname.CountSessionLineRequest{
+		{DrugCode: "PCT", BatchCode: "A1", Unit: "Strip", Quantity: "2"},
+		{DrugCode: "PCT", BatchCode: "B2", Unit: "Strip", Quantity: "4"},
+		{DrugCode: "PCT", BatchCode: "C3", Unit: "Strip", Quantity: "1"},
+	} {
+		if _, err := service.RecordCountSessionLine(ctx, session.ID, line, "staff@example.com"); err != nil {
+			t.Fatalf("RecordCountSessionLine(%+v) error = %v", line, err)
+		}
+	}
+
+	createBatchStockOpname(t, db, "SO-OLD", "A1", date.AddDate(0, 0, -1), 7)
+	createBatchStockOpname(t, db, "SO-A1", "A1", date, 2)
+	createBatchStockOpname(t, db, "SO-B2", "b2", date.AddDate(0, 0, 1), 3)
+
+	comparisons, err := service.GetCountSessionComparisons(ctx, session.ID)
+	if err != nil {
+		t.Fatalf("GetCountSessionComparisons() error = %v", err)
+	}
+
+	got := make(map[string]string)
+	for _, comparison := range comparisons {
+		switch {
+		case comparison.Matches():
+			got[comparison.Line.BatchCode] = "matches"
+		case comparison.StockOpname != nil:
+			got[comparison.Line.BatchCode] = fmt.Sprintf("differs: %v", comparison.StockOpname.RealQuantity)
+		default:
+			got[comparison.Line.BatchCode] = "missing"
+		}
+	}
+
+	want := map[string]string{"A1": "matches", "B2": "differs: 3", "C3": "missing"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("comparisons = %v, want %v", got, want)
+	}
+}
+
+func createBatchStockOpname(t *testing.T, db *gorm.DB, vmedisID string, batchCode string, date time.Time, realQuantity float64) {
+	t.Helper()
+
+	if err := db.Create(&models.StockOpname{
+		VmedisID:     vmedisID,
+		Date:         datatypes.Date(date),
+		DrugCode:     "PCT",
+		BatchCode:    batchCode,
+		Unit:         "Strip",
+		RealQuantity: realQuantity,
+	}).Error; err != nil {
+		t.Fatalf("create stock opname %s: %v", vmedisID, err)
+	}
+}
+
+type drugsGetter []drug.Drug
+
+func (g drugsGetter) GetDrugsByVmedisCodes(_ context.Context, vmedisCodes []string) ([]drug.Drug, error) {
+	var drugs []drug.Drug
+	for _, d := range g {
+		for _, code := range vmedisCodes {
+			if d.VmedisCode == code {
+				drugs = append(drugs, d)
+			}
+		}
+	}
+
+	return drugs, nil
+}
+
+// GetDrugByBarcode returns the Strip of the first drug for any barcode.
+func (g drugsGetter) GetDrugByBarcode(_ context.Context, _ string) (drug.Drug, string, error) {
+	if len(g) == 0 {
+		return drug.Drug{}, "", gorm.ErrRecordNotFound
+	}
+
+	return g[0], "Strip", nil
+}
@@ -0,0 +1,401 @@
+package stockopname
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"gorm.io/datatypes"
+
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+	"github.com/turfaa/vmedis-proxy-api/pkg2/time2"
+)
+
+const (
+	// DefaultCycleDays is the default length of a cycle count cycle, in days.
+	DefaultCycleDays = 90
+
+	maxCycleDays = 366
+
+	// cycleCountHistoryDays is the number of days of stock opnames the drugs are scored from.
+	cycleCountHistoryDays = 180
+)
+
+// ErrInvalidCycleCountRequest is returned when a cycle count request can't be planned or read.
+var ErrInvalidCycleCountRequest = errors.New("invalid cycle count request")
+
+// cycleCountTiers are the counts per cycle of the drugs with the highest stock opname difference scores,
+// by the cumulative share of the catalog. The other drugs are counted once per cycle.
+var cycleCountTiers = []struct {
+	share  float64
+	counts int
+}{
+	{share: 0.1, counts: 4},
+	{share: 0.3, counts: 2},
+}
+
+// PlanCycleCount spreads the whole catalog across the working days of a cycle starting from request.StartDate,
+// and assigns the drugs of each day to the staff members.
+// The tasks from the start date are replaced by the new plan.
+func (s *Service) PlanCycleCount(ctx context.Context, request CycleCountPlanRequest) (CycleCountPlan, error) {
+	start := time2.BeginningOfToday()
+	if request.StartDate != "" {
+		var err error
+		start, err = time2.BeginningOfDate(request.StartDate)
+		if err != nil {
+			return CycleCountPlan{}, fmt.Errorf("%w: start date: %s", ErrInvalidCycleCountRequest, err)
+		}
+	}
+
+	cycleDays := request.CycleDays
+	if cycleDays <= 0 {
+		cycleDays = DefaultCycleDays
+	}
+
+	if cycleDays > maxCycleDays {
+		return CycleCountPlan{}, fmt.Errorf("%w: cycle can't be longer than %d days", ErrInvalidCycleCountRequest, maxCycleDays)
+	}
+
+	days := workingDays(start, cycleDays)
+	if len(days) == 0 {
+		return CycleCountPlan{}, fmt.Errorf("%w: cycle has no working days", ErrInvalidCycleCountRequest)
+	}
+
+	staff := request.Staff
+	if len(staff) == 0 {
+		var err error
+		staff, err = s.db.getStaffEmails(ctx)
+		if err != nil {
+			return CycleCountPlan{}, fmt.Errorf("get staff: %w", err)
+		}
+	}
+
+	drugs, err := s.db.getCountableDrugs(ctx)
+	if err != nil {
+		return CycleCountPlan{}, fmt.Errorf("get drugs: %w", err)
+	}
+
+	differences, err := s.db.getDailyDifferencesSince(ctx, start.AddDate(0, 0, -cycleCountHistoryDays))
+	if err != nil {
+		return CycleCountPlan{}, fmt.Errorf("get stock opname differences: %w", err)
+	}
+
+	tasks := planCycleCount(drugs, differenceScores(differences), days, staff)
+
+	if err := s.db.Transaction(ctx, func(ctx context.Context, tx *Database) error {
+		return tx.ReplaceCycleCountTasks(ctx, start, tasks)
+	}); err != nil {
+		return CycleCountPlan{}, fmt.Errorf("replace cycle count tasks: %w", err)
+	}
+
+	log.Printf("Planned %d cycle count tasks of %d drugs in %d working days", len(tasks), len(drugs), len(days))
+
+	return CycleCountPlan{
+		StartDate:   start.Format(time.DateOnly),
+		EndDate:     days[len(days)-1].Format(time.DateOnly),
+		WorkingDays: len(days),
+		Drugs:       len(drugs),
+		Tasks:       len(tasks),
+		Staff:       staff,
+	}, nil
+}
+
+// GetCycleCountTasks returns the cycle count tasks of a day with their completion,
+// optionally only the ones assigned to request.Assignee.
+func (s *Service) GetCycleCountTasks(ctx context.Context, request CycleCountTasksRequest) ([]CycleCountTask, error) {
+	from, to := time2.Today()
+	if request.Date != "" {
+		var err error
+		from, to, err = time2.Day(request.Date)
+		if err != nil {
+			return nil, fmt.Errorf("%w: date: %s", ErrInvalidCycleCountRequest, err)
+		}
+	}
+
+	tasks, err := s.getCycleCountTasksBetweenTime(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	if request.Assignee == "" {
+		return tasks, nil
+	}
+
+	return slices.DeleteFunc(tasks, func(task CycleCountTask) bool {
+		return !strings.EqualFold(task.Assignee, request.Assignee)
+	}), nil
+}
+
+// GetCycleCountProgress returns the number of tasks planned and completed by each staff member between the given times.
+func (s *Service) GetCycleCountProgress(ctx context.Context, from, to time.Time) ([]CycleCountProgress, error) {
+	tasks, err := s.getCycleCountTasksBetweenTime(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	progressByAssignee := make(map[string]*CycleCountProgress)
+	for _, task := range tasks {
+		progress, ok := progressByAssignee[task.Assignee]
+		if !ok {
+			progress = &CycleCountProgress{Assignee: task.Assignee}
+			progressByAssignee[task.Assignee] = progress
+		}
+
+		progress.Planned++
+		if task.Completed() {
+			progress.Completed++
+		}
+	}
+
+	progresses := make([]CycleCountProgress, 0, len(progressByAssignee))
+	for _, progress := range progressByAssignee {
+		progresses = append(progresses, *progress)
+	}
+
+	slices.SortFunc(progresses, func(a, b CycleCountProgress) int {
+		return cmp.Compare(a.Assignee, b.Assignee)
+	})
+
+	return progresses, nil
+}
+
+// getCycleCountTasksBetweenTime returns the cycle count tasks between the given times.
+// A task is completed by the first stock opname of its drug from the date of the task until the next task of the drug.
+func (s *Service) getCycleCountTasksBetweenTime(ctx context.Context, from, to time.Time) ([]CycleCountTask, error) {
+	dbTasks, err := s.db.GetCycleCountTasksBetweenDates(ctx, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("get cycle count tasks: %w", err)
+	}
+
+	var drugCodes []string
+	taskDates := make(map[string][]time.Time)
+	for _, task := range dbTasks {
+		if _, ok := taskDates[task.DrugCode]; !ok {
+			drugCodes = append(drugCodes, task.DrugCode)
+		}
+
+		taskDates[task.DrugCode] = append(taskDates[task.DrugCode], dateOf(time.Time(task.Date)))
+	}
+
+	laterTasks, err := s.db.getCycleCountTaskDatesAfter(ctx, drugCodes, to)
+	if err != nil {
+		return nil, fmt.Errorf("get later cycle count tasks: %w", err)
+	}
+
+	for _, task := range laterTasks {
+		taskDates[task.DrugCode] = append(taskDates[task.DrugCode], dateOf(task.Date))
+	}
+
+	countedDates, err := s.db.getStockOpnameDates(ctx, drugCodes, from, time2.EndOfToday())
+	if err != nil {
+		return nil, fmt.Errorf("get stock opname dates: %w", err)
+	}
+
+	stockOpnameDates := make(map[string][]time.Time)
+	for _, counted := range countedDates {
+		stockOpnameDates[counted.DrugCode] = append(stockOpnameDates[counted.DrugCode], dateOf(counted.Date))
+	}
+
+	tasks := make([]CycleCountTask, len(dbTasks))
+	for i, dbTask := range dbTasks {
+		tasks[i] = FromDBCycleCountTask(dbTask)
+
+		date := dateOf(time.Time(dbTask.Date))
+		next := nextDate(taskDates[dbTask.DrugCode], date)
+
+		for _, counted := range stockOpnameDates[dbTask.DrugCode] {
+			if counted.Before(date) {
+				continue
+			}
+
+			if next.IsZero() || counted.Before(next) {
+				tasks[i].CountedAt = counted.Format(time.DateOnly)
+			}
+
+			break
+		}
+	}
+
+	return tasks, nil
+}
+
+// planCycleCount spreads the drugs across the days so each drug is counted its counts per cycle, as evenly apart as possible,
+// while keeping the number of drugs of each day as even as possible.
+// The drugs of each day are sorted by their names and split into contiguous, near-equal chunks for the staff members.
+func planCycleCount(drugs []countableDrug, scores map[string]float64, days []time.Time, staff []string) []models.CycleCountTask {
+	counts := countsPerCycle(drugs, scores, len(days))
+
+	// The most frequent drugs are placed first, while the days are still empty enough to space them evenly.
+	order := make([]int, len(drugs))
+	for i := range order {
+		order[i] = i
+	}
+
+	slices.SortStableFunc(order, func(a, b int) int {
+		return cmp.Or(
+			cmp.Compare(counts[b], counts[a]),
+			cmp.Compare(scores[drugs[b].VmedisCode], scores[drugs[a].VmedisCode]),
+			cmp.Compare(drugs[a].VmedisCode, drugs[b].VmedisCode),
+		)
+	})
+
+	loads := make([]int, len(days))
+	drugsByDay := make([][]int, len(days))
+	for _, i := range order {
+		for _, day := range spreadSlots(loads, counts[i]) {
+			loads[day]++
+			drugsByDay[day] = append(drugsByDay[day], i)
+		}
+	}
+
+	var tasks []models.CycleCountTask
+	for day, dayDrugs := range drugsByDay {
+		slices.SortFunc(dayDrugs, func(a, b int) int {
+			return cmp.Or(
+				cmp.Compare(drugs[a].Name, drugs[b].Name),
+				cmp.Compare(drugs[a].VmedisCode, drugs[b].VmedisCode),
+			)
+		})
+
+		for j, i := range dayDrugs {
+			var assignee string
+			if len(staff) > 0 {
+				assignee = staff[j*len(staff)/len(dayDrugs)]
+			}
+
+			tasks = append(tasks, models.CycleCountTask{
+				Date:           datatypes.Date(days[day]),
+				DrugCode:       drugs[i].VmedisCode,
+				DrugName:       drugs[i].Name,
+				Assignee:       assignee,
+				CountsPerCycle: counts[i],
+			})
+		}
+	}
+
+	return tasks
+}
+
+// countsPerCycle returns the number of times each drug is counted in a cycle.
+// The drugs with the highest scores are counted more often, according to the cycle count tiers.
+func countsPerCycle(drugs []countableDrug, scores map[string]float64, days int) []int {
+	var ranked []int
+	for i, drug := range drugs {
+		if scores[drug.VmedisCode] > 0 {
+			ranked = append(ranked, i)
+		}
+	}
+
+	slices.SortStableFunc(ranked, func(a, b int) int {
+		return cmp.Compare(scores[drugs[b].VmedisCode], scores[drugs[a].VmedisCode])
+	})
+
+	counts := make([]int, len(drugs))
+	for i := range counts {
+		counts[i] = 1
+	}
+
+	for rank, i := range ranked {
+		for _, tier := range cycleCountTiers {
+			if float64(rank) < tier.share*float64(len(drugs)) {
+				counts[i] = min(tier.counts, days)
+				break
+			}
+		}
+	}
+
+	return counts
+}
+
+// spreadSlots returns the days a drug counted the given number of times is placed on.
+// The days are evenly apart, starting from the phase that keeps the busiest day the least busy,
+// then the total load the lowest, then the earliest.
+func spreadSlots(loads []int, count int) []int {
+	step := float64(len(loads)) / float64(count)
+
+	var best []int
+	bestMax, bestSum := 0, 0
+	for phase := 0; phase < int(math.Ceil(step)); phase++ {
+		slots := make([]int, count)
+		maxLoad, sumLoad := 0, 0
+		for k := range slots {
+			slots[k] = (phase + int(math.Round(float64(k)*step))) % len(loads)
+			maxLoad = max(maxLoad, loads[slots[k]])
+			sumLoad += loads[slots[k]]
+		}
+
+		if best == nil || maxLoad < bestMax || (maxLoad == bestMax && sumLoad < bestSum) {
+			best, bestMax, bestSum = slots, maxLoad, sumLoad
+		}
+	}
+
+	return best
+}
+
+// differenceScores scores the drugs by the value and the variance of their daily stock opname differences,
+// as the mean of the absolute differences plus their standard deviation.
+func differenceScores(differences []dailyDifference) map[string]float64 {
+	valuesByDrug := make(map[string][]float64)
+	for _, difference := range differences {
+		valuesByDrug[difference.DrugCode] = append(valuesByDrug[difference.DrugCode], difference.SalePriceDifference)
+	}
+
+	scores := make(map[string]float64, len(valuesByDrug))
+	for drugCode, values := range valuesByDrug {
+		n := float64(len(values))
+
+		var sum, sumAbs float64
+		for _, v := range values {
+			sum += v
+			sumAbs += math.Abs(v)
+		}
+
+		mean := sum / n
+
+		var variance float64
+		for _, v := range values {
+			variance += (v - mean) * (v - mean)
+		}
+
+		scores[drugCode] = sumAbs/n + math.Sqrt(variance/n)
+	}
+
+	return scores
+}
+
+// workingDays returns the working days, Monday to Saturday, of the given number of days from the start.
+func workingDays(start time.Time, days int) []time.Time {
+	var working []time.Time
+	for i := range days {
+		day := start.AddDate(0, 0, i)
+		if day.Weekday() != time.Sunday {
+			working = append(working, day)
+		}
+	}
+
+	return working
+}
+
+// nextDate returns the earliest of the dates after the given date, or the zero time if there is none.
+func nextDate(dates []time.Time, after time.Time) time.Time {
+	var next time.Time
+	for _, date := range dates {
+		if date.After(after) && (next.IsZero() || date.Before(next)) {
+			next = date
+		}
+	}
+
+	return next
+}
+
+// dateOf returns the beginning of the date of the given time, in its own location, as a local time.
+func dateOf(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.Local)
+}
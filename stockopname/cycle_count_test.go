@@ -0,0 +1,210 @@
+package stockopname_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+
+	"github.com/turfaa/vmedis-proxy-api/database"
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+	"github.com/turfaa/vmedis-proxy-api/stockopname"
+)
+
+// TestPlanCycleCount checks that every drug is planned at least once in the cycle, on working days only,
+// that the drug with the largest stock opname differences is counted more often, and that the days are balanced.
+func TestPlanCycleCount(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	service := stockopname.NewService(db, nil, nil, nil, nil)
+
+	start := lastMonday().AddDate(0, 0, -21)
+	createDrugs(t, db, 10)
+	createUsers(t, db, map[string]string{"a@example.com": "staff", "b@example.com": "staff", "admin@example.com": "admin"})
+
+	// D01 keeps missing stock, with a varying value.
+	for i, difference := range []float64{-50000, -10000, 20000} {
+		createStockOpname(t, db, fmt.Sprintf("SO-%d", i), "D01", start.AddDate(0, 0, -7*(i+1)), difference)
+	}
+
+	plan, err := service.PlanCycleCount(ctx, stockopname.CycleCountPlanRequest{
+		StartDate: start.Format(time.DateOnly),
+		CycleDays: 14,
+	})
+	if err != nil {
+		t.Fatalf("PlanCycleCount() error = %v", err)
+	}
+
+	if plan.WorkingDays != 12 || plan.Drugs != 10 || plan.Tasks != 13 {
+		t.Errorf("plan = %+v, want 12 working days, 10 drugs, and 13 tasks", plan)
+	}
+
+	if len(plan.Staff) != 2 {
+		t.Errorf("plan staff = %v, want the 2 staff members", plan.Staff)
+	}
+
+	var tasks []models.CycleCountTask
+	if err := db.Find(&tasks).Error; err != nil {
+		t.Fatalf("find tasks: %v", err)
+	}
+
+	countsByDrug := make(map[string]int)
+	loadsByDate := make(map[string]int)
+	for _, task := range tasks {
+		date := time.Time(task.Date)
+		if date.Weekday() == time.Sunday {
+			t.Errorf("task %+v is planned on a Sunday", task)
+		}
+
+		if task.Assignee != "a@example.com" && task.Assignee != "b@example.com" {
+			t.Errorf("task %+v is assigned to %q, want a staff member", task, task.Assignee)
+		}
+
+		countsByDrug[task.DrugCode]++
+		loadsByDate[date.Format(time.DateOnly)]++
+	}
+
+	for i := 1; i <= 10; i++ {
+		drugCode := fmt.Sprintf("D%02d", i)
+
+		want := 1
+		if drugCode == "D01" {
+			want = 4
+		}
+
+		if countsByDrug[drugCode] != want {
+			t.Errorf("drug %s is planned %d times, want %d", drugCode, countsByDrug[drugCode], want)
+		}
+	}
+
+	for date, load := range loadsByDate {
+		if load > 2 {
+			t.Errorf("%d tasks are planned on %s, want at most 2", load, date)
+		}
+	}
+}
+
+// TestCycleCountCompletion checks that a task is completed by a stock opname of its drug until the next task of the drug.
+func TestCycleCountCompletion(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	service := stockopname.NewService(db, nil, nil, nil, nil)
+
+	start := lastMonday().AddDate(0, 0, -14)
+	createDrugs(t, db, 2)
+
+	plan, err := service.PlanCycleCount(ctx, stockopname.CycleCountPlanRequest{
+		StartDate: start.Format(time.DateOnly),
+		CycleDays: 2,
+		Staff:     []string{"a@example.com"},
+	})
+	if err != nil {
+		t.Fatalf("PlanCycleCount() error = %v", err)
+	}
+
+	if plan.Tasks != 2 {
+		t.Fatalf("plan = %+v, want 2 tasks", plan)
+	}
+
+	// D01 is counted on the day after its task, D02 isn't counted.
+	countedAt := start.AddDate(0, 0, 1)
+	createStockOpname(t, db, "SO-1", "D01", countedAt, 0)
+
+	var completed, planned int
+	for _, date := range []time.Time{start, start.AddDate(0, 0, 1)} {
+		tasks, err := service.GetCycleCountTasks(ctx, stockopname.CycleCountTasksRequest{
+			Date:     date.Format(time.DateOnly),
+			Assignee: "A@example.com",
+		})
+		if err != nil {
+			t.Fatalf("GetCycleCountTasks() error = %v", err)
+		}
+
+		for _, task := range tasks {
+			planned++
+
+			switch task.DrugCode {
+			case "D01":
+				if task.CountedAt != countedAt.Format(time.DateOnly) {
+					t.Errorf("task %+v, want counted at %s", task, countedAt.Format(time.DateOnly))
+				}
+				completed++
+
+			case "D02":
+				if task.Completed() {
+					t.Errorf("task %+v, want not completed", task)
+				}
+			}
+		}
+	}
+
+	if planned != 2 || completed != 1 {
+		t.Errorf("got %d tasks with %d completed, want 2 tasks with 1 completed", planned, completed)
+	}
+
+	progresses, err := service.GetCycleCountProgress(ctx, start, start.AddDate(0, 0, 2))
+	if err != nil {
+		t.Fatalf("GetCycleCountProgress() error = %v", err)
+	}
+
+	want := stockopname.CycleCountProgress{Assignee: "a@example.com", Planned: 2, Completed: 1}
+	if len(progresses) != 1 || progresses[0] != want {
+		t.Errorf("GetCycleCountProgress() = %+v, want [%+v]", progresses, want)
+	}
+}
+
+// lastMonday returns the beginning of the Monday of the last week.
+func lastMonday() time.Time {
+	year, month, day := time.Now().Date()
+	today := time.Date(year, month, day, 0, 0, 0, 0, time.Local)
+	daysSinceMonday := (int(today.Weekday()) + 6) % 7
+	return today.AddDate(0, 0, -daysSinceMonday-7)
+}
+
+func createDrugs(t *testing.T, db *gorm.DB, n int) {
+	t.Helper()
+
+	for i := 1; i <= n; i++ {
+		code := fmt.Sprintf("D%02d", i)
+		if err := db.Create(&models.Drug{VmedisID: int64(i), VmedisCode: code, Name: "Obat " + code}).Error; err != nil {
+			t.Fatalf("create drug %s: %v", code, err)
+		}
+	}
+}
+
+func createUsers(t *testing.T, db *gorm.DB, roles map[string]string) {
+	t.Helper()
+
+	for email, role := range roles {
+		if err := db.Create(&models.User{Email: email, Role: role}).Error; err != nil {
+			t.Fatalf("create user %s: %v", email, err)
+		}
+	}
+}
+
+func createStockOpname(t *testing.T, db *gorm.DB, vmedisID string, drugCode string, date time.Time, salePriceDifference float64) {
+	t.Helper()
+
+	if err := db.Create(&models.StockOpname{
+		VmedisID:            vmedisID,
+		Date:                datatypes.Date(date),
+		DrugCode:            drugCode,
+		SalePriceDifference: salePriceDifference,
+	}).Error; err != nil {
+		t.Fatalf("create stock opname %s: %v", vmedisID, err)
+	}
+}
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := database.SqliteDB(t.TempDir() + "/test.db")
+	if err != nil {
+		t.Fatalf("open test database: %v", err)
+	}
+
+	return db
+}
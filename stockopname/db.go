@@ -10,7 +10,9 @@ import (
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 
+	"github.com/turfaa/vmedis-proxy-api/database"
 	"github.com/turfaa/vmedis-proxy-api/database/models"
+	"github.com/turfaa/vmedis-proxy-api/outbox"
 	"github.com/turfaa/vmedis-proxy-api/vmedis/v1"
 )
 
@@ -40,7 +42,7 @@ func (d *Database) UpsertVmedisStockOpnames(ctx context.Context, stockOpnames []
 	for i, so := range stockOpnames {
 		id := so.ID
 		if id == "" {
-			id = fmt.Sprintf("%s-%s-%s-%s-%d", so.DrugCode, so.BatchCode, so.Unit, so.Date.Time.Format("2006-01-02"), rand.Int())
+			id = fmt.Sprintf("%s%d", fallbackVmedisIDPrefix(so), rand.Int())
 		}
 
 		soModels[i] = models.StockOpname{
@@ -63,7 +65,7 @@ func (d *Database) UpsertVmedisStockOpnames(ctx context.Context, stockOpnames []
 		Clauses(
 			clause.OnConflict{
 				Columns: []clause.Column{{Name: "vmedis_id"}},
-				DoUpdates: clause.AssignmentColumns([]string{
+				DoUpdates: database.UndeleteAndUpdateColumns([]string{
 					"updated_at",
 					"date",
 					"drug_code",
@@ -86,6 +88,49 @@ func (d *Database) UpsertVmedisStockOpnames(ctx context.Context, stockOpnames []
 	return nil
 }
 
+// GetStockOpnameKeysBetweenTime returns the Vmedis IDs and the drug codes of the stock opnames between the given times.
+func (d *Database) GetStockOpnameKeysBetweenTime(ctx context.Context, from, to time.Time) ([]models.StockOpname, error) {
+	var soModels []models.StockOpname
+	if err := d.dbCtx(ctx).
+		Select("vmedis_id", "drug_code").
+		Where("date BETWEEN ? AND ?", from, to).
+		Find(&soModels).
+		Error; err != nil {
+		return nil, fmt.Errorf("get stock opname keys between %s and %s from DB: %w", from, to, err)
+	}
+
+	return soModels, nil
+}
+
+// DeleteStockOpnamesByVmedisIDs soft-deletes the stock opnames with the given Vmedis IDs.
+func (d *Database) DeleteStockOpnamesByVmedisIDs(ctx context.Context, vmedisIDs []string) error {
+	if len(vmedisIDs) == 0 {
+		return nil
+	}
+
+	if err := d.dbCtx(ctx).
+		Where("vmedis_id IN ?", vmedisIDs).
+		Delete(&models.StockOpname{}).
+		Error; err != nil {
+		return fmt.Errorf("delete %d stock opnames from DB: %w", len(vmedisIDs), err)
+	}
+
+	return nil
+}
+
+// fallbackVmedisIDPrefix is the prefix of the ID given to a stock opname without an ID in Vmedis.
+func fallbackVmedisIDPrefix(so vmedisv1.StockOpname) string {
+	return fmt.Sprintf("%s-%s-%s-%s-", so.DrugCode, so.BatchCode, so.Unit, so.Date.Time.Format("2006-01-02"))
+}
+
+// Transaction runs fn with a Database in a transaction.
+// The messages published to the outbox with the context passed to fn are written in the same transaction.
+func (d *Database) Transaction(ctx context.Context, fn func(ctx context.Context, tx *Database) error) error {
+	return outbox.Transaction(ctx, d.db, func(ctx context.Context, tx *gorm.DB) error {
+		return fn(ctx, NewDatabase(tx))
+	})
+}
+
 func (d *Database) dbCtx(ctx context.Context) *gorm.DB {
 	return d.db.WithContext(ctx)
 }
@@ -0,0 +1,123 @@
+package stockopname
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+)
+
+const countSessionsLimit = 100
+
+// GetCountSessions returns the latest counting sessions without their lines, newest first.
+func (d *Database) GetCountSessions(ctx context.Context) ([]models.StockCountSession, error) {
+	var sessions []models.StockCountSession
+	if err := d.dbCtx(ctx).
+		Order("date DESC").
+		Order("id DESC").
+		Limit(countSessionsLimit).
+		Find(&sessions).
+		Error; err != nil {
+		return nil, fmt.Errorf("get count sessions from db: %w", err)
+	}
+
+	return sessions, nil
+}
+
+// GetCountSession returns the counting session with its lines, sorted by the drug names.
+func (d *Database) GetCountSession(ctx context.Context, id uint) (models.StockCountSession, error) {
+	var session models.StockCountSession
+	if err := d.dbCtx(ctx).
+		Preload("Lines", func(db *gorm.DB) *gorm.DB {
+			return db.Order("drug_name").Order("batch_code").Order("unit")
+		}).
+		First(&session, id).
+		Error; err != nil {
+		return models.StockCountSession{}, fmt.Errorf("get count session %d from db: %w", id, err)
+	}
+
+	return session, nil
+}
+
+func (d *Database) CreateCountSession(ctx context.Context, session *models.StockCountSession) error {
+	if err := d.dbCtx(ctx).Create(session).Error; err != nil {
+		return fmt.Errorf("create count session in db: %w", err)
+	}
+
+	return nil
+}
+
+// UpsertCountSessionLine creates the line, or overwrites the quantity of the line with the same drug, batch, and unit.
+func (d *Database) UpsertCountSessionLine(ctx context.Context, line *models.StockCountLine) error {
+	if err := d.dbCtx(ctx).
+		Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "session_id"}, {Name: "drug_code"}, {Name: "batch_code"}, {Name: "unit"}},
+			DoUpdates: clause.AssignmentColumns([]string{
+				"updated_at",
+				"drug_name",
+				"quantity",
+				"counted_by",
+			}),
+		}).
+		Create(line).
+		Error; err != nil {
+		return fmt.Errorf("upsert count session line in db: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteCountSessionLine deletes the line of the session, returning gorm.ErrRecordNotFound if there is none.
+func (d *Database) DeleteCountSessionLine(ctx context.Context, sessionID uint, lineID uint) error {
+	result := d.dbCtx(ctx).
+		Where("session_id = ?", sessionID).
+		Delete(&models.StockCountLine{}, lineID)
+	if result.Error != nil {
+		return fmt.Errorf("delete count session line %d from db: %w", lineID, result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("delete count session line %d from db: %w", lineID, gorm.ErrRecordNotFound)
+	}
+
+	return nil
+}
+
+// ApproveCountSession approves the session if it isn't approved yet, returning whether it was approved now.
+func (d *Database) ApproveCountSession(ctx context.Context, id uint, approvedBy string) (bool, error) {
+	now := time.Now()
+
+	result := d.dbCtx(ctx).
+		Model(&models.StockCountSession{}).
+		Where("id = ? AND approved_at IS NULL", id).
+		Updates(map[string]any{"approved_at": now, "approved_by": approvedBy})
+	if result.Error != nil {
+		return false, fmt.Errorf("approve count session %d in db: %w", id, result.Error)
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+// getStockOpnamesOfDrugsSince returns the stock opnames of the given drugs since the given time, oldest first.
+func (d *Database) getStockOpnamesOfDrugsSince(ctx context.Context, drugCodes []string, since time.Time) ([]models.StockOpname, error) {
+	if len(drugCodes) == 0 {
+		return nil, nil
+	}
+
+	var stockOpnames []models.StockOpname
+	if err := d.dbCtx(ctx).
+		Where("drug_code IN ?", drugCodes).
+		Where("date >= ?", since).
+		Order("date").
+		Order("id").
+		Find(&stockOpnames).
+		Error; err != nil {
+		return nil, fmt.Errorf("get stock opnames of %d drugs from db: %w", len(drugCodes), err)
+	}
+
+	return stockOpnames, nil
+}
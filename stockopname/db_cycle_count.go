@@ -0,0 +1,157 @@
+package stockopname
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+)
+
+const cycleCountTasksBatchSize = 500
+
+// countableDrug is a drug that can be planned in a cycle count.
+type countableDrug struct {
+	VmedisCode string
+	Name       string
+}
+
+// dailyDifference is the sale price difference of the stock opnames of a drug on a date.
+type dailyDifference struct {
+	DrugCode            string
+	SalePriceDifference float64
+}
+
+// drugDate is a date related to a drug.
+type drugDate struct {
+	DrugCode string
+	Date     time.Time
+}
+
+// getCountableDrugs returns the drugs that still exist in Vmedis, sorted by their names.
+func (d *Database) getCountableDrugs(ctx context.Context) ([]countableDrug, error) {
+	var drugs []countableDrug
+	if err := d.dbCtx(ctx).
+		Model(&models.Drug{}).
+		Select("vmedis_code", "name").
+		Where("removed_at IS NULL").
+		Order("name").
+		Find(&drugs).
+		Error; err != nil {
+		return nil, fmt.Errorf("get countable drugs from db: %w", err)
+	}
+
+	return drugs, nil
+}
+
+// getDailyDifferencesSince returns the sale price differences of the stock opnames since the given time,
+// summed up per drug and date.
+func (d *Database) getDailyDifferencesSince(ctx context.Context, since time.Time) ([]dailyDifference, error) {
+	var differences []dailyDifference
+	if err := d.dbCtx(ctx).
+		Model(&models.StockOpname{}).
+		Select("drug_code", "SUM(sale_price_difference) AS sale_price_difference").
+		Where("date >= ?", since).
+		Group("drug_code").
+		Group("date").
+		Find(&differences).
+		Error; err != nil {
+		return nil, fmt.Errorf("get daily stock opname differences from db: %w", err)
+	}
+
+	return differences, nil
+}
+
+// getStaffEmails returns the emails of the users with the staff role, sorted.
+func (d *Database) getStaffEmails(ctx context.Context) ([]string, error) {
+	var emails []string
+	if err := d.dbCtx(ctx).
+		Model(&models.User{}).
+		Where("role = ?", "staff").
+		Order("email").
+		Pluck("email", &emails).
+		Error; err != nil {
+		return nil, fmt.Errorf("get staff emails from db: %w", err)
+	}
+
+	return emails, nil
+}
+
+// ReplaceCycleCountTasks deletes the cycle count tasks from the given date and creates the given tasks.
+func (d *Database) ReplaceCycleCountTasks(ctx context.Context, from time.Time, tasks []models.CycleCountTask) error {
+	if err := d.dbCtx(ctx).
+		Where("date >= ?", from).
+		Delete(&models.CycleCountTask{}).
+		Error; err != nil {
+		return fmt.Errorf("delete cycle count tasks from %s from db: %w", from.Format(time.DateOnly), err)
+	}
+
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	if err := d.dbCtx(ctx).
+		CreateInBatches(&tasks, cycleCountTasksBatchSize).
+		Error; err != nil {
+		return fmt.Errorf("create %d cycle count tasks in db: %w", len(tasks), err)
+	}
+
+	return nil
+}
+
+// GetCycleCountTasksBetweenDates returns the cycle count tasks between the given dates, inclusive,
+// sorted by their dates and drug names.
+func (d *Database) GetCycleCountTasksBetweenDates(ctx context.Context, from, to time.Time) ([]models.CycleCountTask, error) {
+	var tasks []models.CycleCountTask
+	if err := d.dbCtx(ctx).
+		Where("date BETWEEN ? AND ?", from, to).
+		Order("date").
+		Order("drug_name").
+		Find(&tasks).
+		Error; err != nil {
+		return nil, fmt.Errorf("get cycle count tasks from db: %w", err)
+	}
+
+	return tasks, nil
+}
+
+// getCycleCountTaskDatesAfter returns the dates of the tasks of the given drugs after the given time.
+func (d *Database) getCycleCountTaskDatesAfter(ctx context.Context, drugCodes []string, after time.Time) ([]drugDate, error) {
+	if len(drugCodes) == 0 {
+		return nil, nil
+	}
+
+	var dates []drugDate
+	if err := d.dbCtx(ctx).
+		Model(&models.CycleCountTask{}).
+		Select("drug_code", "date").
+		Where("drug_code IN ?", drugCodes).
+		Where("date > ?", after).
+		Find(&dates).
+		Error; err != nil {
+		return nil, fmt.Errorf("get cycle count task dates of %d drugs from db: %w", len(drugCodes), err)
+	}
+
+	return dates, nil
+}
+
+// getStockOpnameDates returns the distinct dates the given drugs were stock opnamed between the given times, sorted.
+func (d *Database) getStockOpnameDates(ctx context.Context, drugCodes []string, from, to time.Time) ([]drugDate, error) {
+	if len(drugCodes) == 0 {
+		return nil, nil
+	}
+
+	var dates []drugDate
+	if err := d.dbCtx(ctx).
+		Model(&models.StockOpname{}).
+		Distinct("drug_code", "date").
+		Where("drug_code IN ?", drugCodes).
+		Where("date BETWEEN ? AND ?", from, to).
+		Order("date").
+		Find(&dates).
+		Error; err != nil {
+		return nil, fmt.Errorf("get stock opname dates of %d drugs from db: %w", len(drugCodes), err)
+	}
+
+	return dates, nil
+}
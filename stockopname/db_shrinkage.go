@@ -0,0 +1,104 @@
+package stockopname
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+)
+
+// saleTotal is the total of a sale.
+type saleTotal struct {
+	SoldAt time.Time
+	Total  float64
+}
+
+// countedLine is a line of a counting session with the date of its session.
+type countedLine struct {
+	DrugCode  string
+	BatchCode string
+	Unit      string
+	Date      time.Time
+	CountedBy string
+}
+
+// getManufacturers returns the manufacturers of the given drugs, by their vmedis codes.
+func (d *Database) getManufacturers(ctx context.Context, drugCodes []string) (map[string]string, error) {
+	if len(drugCodes) == 0 {
+		return nil, nil
+	}
+
+	var drugs []models.Drug
+	if err := d.dbCtx(ctx).
+		Select("vmedis_code", "manufacturer").
+		Where("vmedis_code IN ?", drugCodes).
+		Find(&drugs).
+		Error; err != nil {
+		return nil, fmt.Errorf("get manufacturers of %d drugs from db: %w", len(drugCodes), err)
+	}
+
+	manufacturers := make(map[string]string, len(drugs))
+	for _, drug := range drugs {
+		manufacturers[drug.VmedisCode] = drug.Manufacturer
+	}
+
+	return manufacturers, nil
+}
+
+// getSaleTotalsBetweenTime returns the totals of the sales between the given times.
+func (d *Database) getSaleTotalsBetweenTime(ctx context.Context, from, to time.Time) ([]saleTotal, error) {
+	var totals []saleTotal
+	if err := d.dbCtx(ctx).
+		Model(&models.Sale{}).
+		Select("sold_at", "total").
+		Where("sold_at BETWEEN ? AND ?", from, to).
+		Find(&totals).
+		Error; err != nil {
+		return nil, fmt.Errorf("get sale totals from db: %w", err)
+	}
+
+	return totals, nil
+}
+
+// getCountedLinesBetweenTime returns the lines of the counting sessions dated between the given times, oldest first.
+func (d *Database) getCountedLinesBetweenTime(ctx context.Context, from, to time.Time) ([]countedLine, error) {
+	var lines []countedLine
+	if err := d.dbCtx(ctx).
+		Model(&models.StockCountLine{}).
+		Select(
+			"stock_count_lines.drug_code",
+			"stock_count_lines.batch_code",
+			"stock_count_lines.unit",
+			"stock_count_sessions.date",
+			"stock_count_lines.counted_by",
+		).
+		Joins("JOIN stock_count_sessions ON stock_count_sessions.id = stock_count_lines.session_id").
+		Where("stock_count_sessions.date BETWEEN ? AND ?", from, to).
+		Order("stock_count_sessions.date").
+		Find(&lines).
+		Error; err != nil {
+		return nil, fmt.Errorf("get counted lines from db: %w", err)
+	}
+
+	return lines, nil
+}
+
+// getCycleCountTasksOfDrugsBetweenTime returns the cycle count tasks of the given drugs between the given times, oldest first.
+func (d *Database) getCycleCountTasksOfDrugsBetweenTime(ctx context.Context, drugCodes []string, from, to time.Time) ([]models.CycleCountTask, error) {
+	if len(drugCodes) == 0 {
+		return nil, nil
+	}
+
+	var tasks []models.CycleCountTask
+	if err := d.dbCtx(ctx).
+		Where("drug_code IN ?", drugCodes).
+		Where("date BETWEEN ? AND ?", from, to).
+		Order("date").
+		Find(&tasks).
+		Error; err != nil {
+		return nil, fmt.Errorf("get cycle count tasks of %d drugs from db: %w", len(drugCodes), err)
+	}
+
+	return tasks, nil
+}
@@ -0,0 +1,416 @@
+package stockopname
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/turfaa/vmedis-proxy-api/auth"
+	"github.com/turfaa/vmedis-proxy-api/cui"
+	"github.com/turfaa/vmedis-proxy-api/pkg2/slices2"
+	"github.com/turfaa/vmedis-proxy-api/pkg2/time2"
+)
+
+const xlsxContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+// GetCountSessions returns the latest counting sessions as a table.
+// The row IDs are the session IDs.
+func (h *ApiHandler) GetCountSessions(c *gin.Context) {
+	sessions, err := h.service.GetCountSessions(c.Request.Context())
+	if err != nil {
+		c.JSON(500, gin.H{"error": fmt.Sprintf("failed to get count sessions: %s", err)})
+		return
+	}
+
+	c.JSON(200, transformCountSessionsToTable(sessions))
+}
+
+func (h *ApiHandler) GetCountSession(c *gin.Context) {
+	id, ok := parseCountSessionID(c)
+	if !ok {
+		return
+	}
+
+	session, err := h.service.GetCountSession(c.Request.Context(), id)
+	if err != nil {
+		respondCountSessionError(c, id, "get", err)
+		return
+	}
+
+	c.JSON(200, CountSessionResponse{Session: session})
+}
+
+func (h *ApiHandler) CreateCountSession(c *gin.Context) {
+	var request CreateCountSessionRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(400, gin.H{"error": fmt.Sprintf("invalid request: %s", err)})
+		return
+	}
+
+	session, err := h.service.CreateCountSession(c.Request.Context(), request, auth.FromGinContext(c).Email)
+	if err != nil {
+		if errors.Is(err, ErrInvalidCountSession) {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(500, gin.H{"error": fmt.Sprintf("failed to create count session: %s", err)})
+		return
+	}
+
+	c.JSON(201, CountSessionResponse{Session: session})
+}
+
+// GetCreateCountSessionForm returns an empty form for starting a counting session.
+// The filled form can be submitted to `POST /stock-opnames/sessions`.
+func (h *ApiHandler) GetCreateCountSessionForm(c *gin.Context) {
+	c.JSON(200, cui.Form{
+		Title: "Mulai Sesi Stok Opname",
+		Fields: []cui.Field{
+			{
+				ID:       "name",
+				Label:    "Nama Sesi",
+				Type:     cui.FieldTypeText,
+				Required: true,
+			},
+			{
+				ID:    "date",
+				Label: "Tanggal (YYYY-MM-DD)",
+				Type:  cui.FieldTypeText,
+				Value: time2.BeginningOfToday().Format("2006-01-02"),
+			},
+		},
+	})
+}
+
+// RecordCountSessionLine records a counted quantity in the session.
+func (h *ApiHandler) RecordCountSessionLine(c *gin.Context) {
+	id, ok := parseCountSessionID(c)
+	if !ok {
+		return
+	}
+
+	var request CountSessionLineRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(400, gin.H{"error": fmt.Sprintf("invalid request: %s", err)})
+		return
+	}
+
+	line, err := h.service.RecordCountSessionLine(c.Request.Context(), id, request, auth.FromGinContext(c).Email)
+	if err != nil {
+		respondCountSessionError(c, id, "record a line of", err)
+		return
+	}
+
+	c.JSON(200, CountSessionLineResponse{Line: line})
+}
+
+// GetCountSessionLineForm returns a form for recording a counted quantity, prefilled with the given query parameters,
+// e.g. the barcode scanned by the frontend.
+// The filled form can be submitted to `PUT /stock-opnames/sessions/:id/lines`.
+func (h *ApiHandler) GetCountSessionLineForm(c *gin.Context) {
+	c.JSON(200, cui.Form{
+		Title: "Catat Hasil Hitung",
+		Fields: []cui.Field{
+			{
+				ID:    "barcode",
+				Label: "Barcode",
+				Type:  cui.FieldTypeText,
+				Value: c.Query("barcode"),
+			},
+			{
+				ID:    "drugCode",
+				Label: "Kode Obat Vmedis (jika tanpa barcode)",
+				Type:  cui.FieldTypeText,
+				Value: c.Query("drug_code"),
+			},
+			{
+				ID:    "batchCode",
+				Label: "No. Batch",
+				Type:  cui.FieldTypeText,
+				Value: c.Query("batch_code"),
+			},
+			{
+				ID:    "unit",
+				Label: "Satuan",
+				Type:  cui.FieldTypeText,
+				Value: c.Query("unit"),
+			},
+			{
+				ID:       "quantity",
+				Label:    "Jumlah Fisik",
+				Type:     cui.FieldTypeText,
+				Required: true,
+			},
+		},
+	})
+}
+
+func (h *ApiHandler) DeleteCountSessionLine(c *gin.Context) {
+	id, ok := parseCountSessionID(c)
+	if !ok {
+		return
+	}
+
+	lineID, err := strconv.ParseUint(c.Param("line_id"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{"error": fmt.Sprintf("invalid line id: %s", err)})
+		return
+	}
+
+	if err := h.service.DeleteCountSessionLine(c.Request.Context(), id, uint(lineID)); err != nil {
+		respondCountSessionError(c, id, "delete a line of", err)
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "Count session line deleted successfully"})
+}
+
+// ApproveCountSession approves the counts of the session by the caller.
+func (h *ApiHandler) ApproveCountSession(c *gin.Context) {
+	id, ok := parseCountSessionID(c)
+	if !ok {
+		return
+	}
+
+	session, err := h.service.ApproveCountSession(c.Request.Context(), id, auth.FromGinContext(c).Email)
+	if err != nil {
+		respondCountSessionError(c, id, "approve", err)
+		return
+	}
+
+	c.JSON(200, CountSessionResponse{Session: session})
+}
+
+// GetCountSessionVariances returns the counted quantities of each drug of the session compared with its current stocks as a table.
+func (h *ApiHandler) GetCountSessionVariances(c *gin.Context) {
+	id, ok := parseCountSessionID(c)
+	if !ok {
+		return
+	}
+
+	variances, err := h.service.GetCountSessionVariances(c.Request.Context(), id)
+	if err != nil {
+		respondCountSessionError(c, id, "get the variances of", err)
+		return
+	}
+
+	c.JSON(200, transformCountSessionVariancesToTable(variances))
+}
+
+// GetCountSessionComparisons returns the lines of the session compared with the stock opnames entered into Vmedis as a table.
+func (h *ApiHandler) GetCountSessionComparisons(c *gin.Context) {
+	id, ok := parseCountSessionID(c)
+	if !ok {
+		return
+	}
+
+	comparisons, err := h.service.GetCountSessionComparisons(c.Request.Context(), id)
+	if err != nil {
+		respondCountSessionError(c, id, "compare", err)
+		return
+	}
+
+	c.JSON(200, transformCountSessionComparisonsToTable(comparisons))
+}
+
+// ExportCountSession returns the lines of an approved session as an XLSX file to be entered into Vmedis.
+func (h *ApiHandler) ExportCountSession(c *gin.Context) {
+	id, ok := parseCountSessionID(c)
+	if !ok {
+		return
+	}
+
+	session, err := h.service.GetCountSession(c.Request.Context(), id)
+	if err != nil {
+		respondCountSessionError(c, id, "get", err)
+		return
+	}
+
+	if !session.Approved() {
+		c.JSON(400, gin.H{"error": fmt.Sprintf("count session %d must be approved before it's exported", id)})
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := WriteCountSessionXLSX(&buf, session); err != nil {
+		c.JSON(500, gin.H{"error": fmt.Sprintf("failed to export count session %d: %s", id, err)})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="stok-opname-%s-%d.xlsx"`, session.Date, session.ID))
+	c.Data(200, xlsxContentType, buf.Bytes())
+}
+
+func parseCountSessionID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{"error": fmt.Sprintf("invalid id: %s", err)})
+		return 0, false
+	}
+
+	return uint(id), true
+}
+
+func respondCountSessionError(c *gin.Context, id uint, action string, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(404, gin.H{"error": fmt.Sprintf("count session %d not found", id)})
+	case errors.Is(err, ErrInvalidCountSession), errors.Is(err, ErrCountSessionApproved):
+		c.JSON(400, gin.H{"error": err.Error()})
+	default:
+		c.JSON(500, gin.H{"error": fmt.Sprintf("failed to %s count session %d: %s", action, id, err)})
+	}
+}
+
+func transformCountSessionsToTable(sessions []CountSession) cui.Table {
+	rows := slices2.Map(sessions, func(session CountSession) cui.Row {
+		status := "Sedang dihitung"
+		if session.Approved() {
+			status = fmt.Sprintf("Disetujui oleh %s", session.ApprovedBy)
+		}
+
+		return cui.Row{
+			ID: strconv.FormatUint(uint64(session.ID), 10),
+			Columns: []string{
+				session.Name,
+				session.Date,
+				session.CreatedBy,
+				status,
+			},
+		}
+	})
+
+	return cui.Table{
+		Header: []string{
+			"Nama Sesi",
+			"Tanggal",
+			"Dibuat Oleh",
+			"Status",
+		},
+		Rows: rows,
+	}
+}
+
+func transformCountSessionVariancesToTable(variances []CountSessionVariance) cui.Table {
+	var differing int
+
+	rows := make([]cui.Row, len(variances))
+	for i, variance := range variances {
+		if variance.Difference != 0 {
+			differing++
+		}
+
+		rows[i] = cui.Row{
+			ID: variance.DrugCode,
+			Columns: []string{
+				variance.DrugName,
+				formatCountedStocks(variance.SystemStocks),
+				formatCountedStocks(variance.CountedStocks),
+				formatDifference(variance.Difference, variance.SmallestUnit),
+			},
+		}
+	}
+
+	return cui.Table{
+		Header: []string{
+			"Nama Obat",
+			"Stok Sistem",
+			"Stok Fisik",
+			"Selisih",
+		},
+		Rows: rows,
+		Footer: []string{
+			"Total",
+			"",
+			"",
+			fmt.Sprintf("%d dari %d obat berselisih", differing, len(variances)),
+		},
+	}
+}
+
+func transformCountSessionComparisonsToTable(comparisons []CountSessionComparison) cui.Table {
+	var matching int
+
+	rows := make([]cui.Row, len(comparisons))
+	for i, comparison := range comparisons {
+		vmedisQuantity := "-"
+		status := "Belum diinput"
+		if comparison.StockOpname != nil {
+			vmedisQuantity = formatQuantity(comparison.StockOpname.RealQuantity)
+			status = "Berbeda"
+		}
+
+		if comparison.Matches() {
+			matching++
+			status = "Sesuai"
+		}
+
+		rows[i] = cui.Row{
+			ID: strconv.FormatUint(uint64(comparison.Line.ID), 10),
+			Columns: []string{
+				comparison.Line.DrugName,
+				orDash(comparison.Line.BatchCode),
+				comparison.Line.Unit,
+				formatQuantity(comparison.Line.Quantity),
+				vmedisQuantity,
+				status,
+			},
+		}
+	}
+
+	return cui.Table{
+		Header: []string{
+			"Nama Obat",
+			"No. Batch",
+			"Satuan",
+			"Jumlah Fisik",
+			"Jumlah di Vmedis",
+			"Status",
+		},
+		Rows: rows,
+		Footer: []string{
+			"Total",
+			"",
+			"",
+			"",
+			"",
+			fmt.Sprintf("%d dari %d sesuai", matching, len(comparisons)),
+		},
+	}
+}
+
+func formatCountedStocks(stocks []CountedStock) string {
+	if len(stocks) == 0 {
+		return "-"
+	}
+
+	return strings.Join(slices2.Map(stocks, func(stock CountedStock) string {
+		return fmt.Sprintf("%s %s", formatQuantity(stock.Quantity), stock.Unit)
+	}), ", ")
+}
+
+func formatDifference(difference float64, unit string) string {
+	if difference > 0 {
+		return fmt.Sprintf("+%s %s", formatQuantity(difference), unit)
+	}
+
+	return fmt.Sprintf("%s %s", formatQuantity(difference), unit)
+}
+
+func formatQuantity(quantity float64) string {
+	return strconv.FormatFloat(quantity, 'f', -1, 64)
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+
+	return s
+}
@@ -0,0 +1,164 @@
+package stockopname
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/turfaa/vmedis-proxy-api/cui"
+	"github.com/turfaa/vmedis-proxy-api/pkg2/time2"
+)
+
+// PlanCycleCount plans the cycle count from the start date of the request, replacing the tasks from that date.
+func (h *ApiHandler) PlanCycleCount(c *gin.Context) {
+	var request CycleCountPlanRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(400, gin.H{"error": fmt.Sprintf("invalid request: %s", err)})
+		return
+	}
+
+	plan, err := h.service.PlanCycleCount(c.Request.Context(), request)
+	if err != nil {
+		if errors.Is(err, ErrInvalidCycleCountRequest) {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(500, gin.H{"error": fmt.Sprintf("failed to plan cycle count: %s", err)})
+		return
+	}
+
+	c.JSON(200, CycleCountPlanResponse{Plan: plan})
+}
+
+// GetCycleCountTasks returns the cycle count tasks of a day as a table.
+func (h *ApiHandler) GetCycleCountTasks(c *gin.Context) {
+	var request CycleCountTasksRequest
+	if err := c.ShouldBindQuery(&request); err != nil {
+		c.JSON(400, gin.H{"error": fmt.Sprintf("invalid request: %s", err)})
+		return
+	}
+
+	tasks, err := h.service.GetCycleCountTasks(c.Request.Context(), request)
+	if err != nil {
+		if errors.Is(err, ErrInvalidCycleCountRequest) {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(500, gin.H{"error": fmt.Sprintf("failed to get cycle count tasks: %s", err)})
+		return
+	}
+
+	c.JSON(200, transformCycleCountTasksToTable(tasks))
+}
+
+// GetCycleCountProgress returns the cycle count progress of each staff member in a time range as a table.
+func (h *ApiHandler) GetCycleCountProgress(c *gin.Context) {
+	from, to, err := time2.GetTimeRangeFromQuery(c)
+	if err != nil {
+		c.JSON(400, gin.H{"error": fmt.Sprintf("invalid time range: %s", err)})
+		return
+	}
+
+	progresses, err := h.service.GetCycleCountProgress(c.Request.Context(), from, to)
+	if err != nil {
+		c.JSON(500, gin.H{"error": fmt.Sprintf("failed to get cycle count progress: %s", err)})
+		return
+	}
+
+	c.JSON(200, transformCycleCountProgressesToTable(progresses))
+}
+
+func transformCycleCountTasksToTable(tasks []CycleCountTask) cui.Table {
+	var completed int
+
+	rows := make([]cui.Row, len(tasks))
+	for i, task := range tasks {
+		status := "Belum"
+		if task.Completed() {
+			completed++
+			status = fmt.Sprintf("Sudah (%s)", task.CountedAt)
+		}
+
+		rows[i] = cui.Row{
+			ID: task.DrugCode,
+			Columns: []string{
+				task.DrugName,
+				formatAssignee(task.Assignee),
+				fmt.Sprintf("%dx per siklus", task.CountsPerCycle),
+				status,
+			},
+		}
+	}
+
+	return cui.Table{
+		Header: []string{
+			"Nama Obat",
+			"Petugas",
+			"Frekuensi",
+			"Status",
+		},
+		Rows: rows,
+		Footer: []string{
+			"Total",
+			"",
+			"",
+			fmt.Sprintf("%d dari %d selesai", completed, len(tasks)),
+		},
+	}
+}
+
+func transformCycleCountProgressesToTable(progresses []CycleCountProgress) cui.Table {
+	var total CycleCountProgress
+
+	rows := make([]cui.Row, len(progresses))
+	for i, progress := range progresses {
+		total.Planned += progress.Planned
+		total.Completed += progress.Completed
+
+		rows[i] = cui.Row{
+			ID: progress.Assignee,
+			Columns: []string{
+				formatAssignee(progress.Assignee),
+				strconv.Itoa(progress.Planned),
+				strconv.Itoa(progress.Completed),
+				formatCompletion(progress),
+			},
+		}
+	}
+
+	return cui.Table{
+		Header: []string{
+			"Petugas",
+			"Direncanakan",
+			"Selesai",
+			"Persentase",
+		},
+		Rows: rows,
+		Footer: []string{
+			"Total",
+			strconv.Itoa(total.Planned),
+			strconv.Itoa(total.Completed),
+			formatCompletion(total),
+		},
+	}
+}
+
+func formatAssignee(assignee string) string {
+	if assignee == "" {
+		return "-"
+	}
+
+	return assignee
+}
+
+func formatCompletion(progress CycleCountProgress) string {
+	if progress.Planned == 0 {
+		return "-"
+	}
+
+	return fmt.Sprintf("%.1f%%", float64(progress.Completed)/float64(progress.Planned)*100)
+}
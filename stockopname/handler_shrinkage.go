@@ -0,0 +1,241 @@
+package stockopname
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/turfaa/vmedis-proxy-api/cui"
+	"github.com/turfaa/vmedis-proxy-api/money"
+	"github.com/turfaa/vmedis-proxy-api/pkg2/time2"
+)
+
+// GetShrinkagePeriods returns the stock opname differences and the shrinkage ratio of each period as a table.
+func (h *ApiHandler) GetShrinkagePeriods(c *gin.Context) {
+	report, ok := h.getShrinkageReport(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(200, transformShrinkagePeriodsToTable(report.Periods))
+}
+
+// GetShrinkageDrugs returns the stock opname differences of each drug per period as a table.
+func (h *ApiHandler) GetShrinkageDrugs(c *gin.Context) {
+	report, ok := h.getShrinkageReport(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(200, transformShrinkageGroupsToTable("Nama Obat", report.Periods, report.Drugs))
+}
+
+// GetShrinkageManufacturers returns the stock opname differences of each manufacturer per period as a table.
+func (h *ApiHandler) GetShrinkageManufacturers(c *gin.Context) {
+	report, ok := h.getShrinkageReport(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(200, transformShrinkageGroupsToTable("Pabrik", report.Periods, report.Manufacturers))
+}
+
+// GetShrinkageStaff returns the stock opname differences of each staff member who did the counts per period as a table.
+func (h *ApiHandler) GetShrinkageStaff(c *gin.Context) {
+	report, ok := h.getShrinkageReport(c)
+	if !ok {
+		return
+	}
+
+	staff := make([]ShrinkageGroup, len(report.Staff))
+	for i, group := range report.Staff {
+		group.Name = staffName(group.Name)
+		staff[i] = group
+	}
+
+	c.JSON(200, transformShrinkageGroupsToTable("Petugas", report.Periods, staff))
+}
+
+// GetShrinkageRepeatOffenders returns the drugs counted short again and again as a table.
+func (h *ApiHandler) GetShrinkageRepeatOffenders(c *gin.Context) {
+	report, ok := h.getShrinkageReport(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(200, transformRepeatOffendersToTable(report.RepeatOffenders))
+}
+
+// ExportShrinkage returns the shrinkage report as an XLSX file.
+func (h *ApiHandler) ExportShrinkage(c *gin.Context) {
+	report, ok := h.getShrinkageReport(c)
+	if !ok {
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := WriteShrinkageXLSX(&buf, report); err != nil {
+		c.JSON(500, gin.H{"error": fmt.Sprintf("failed to write shrinkage report XLSX: %s", err)})
+		return
+	}
+
+	var start, end string
+	if len(report.Periods) > 0 {
+		start, end = report.Periods[0].Start, report.Periods[len(report.Periods)-1].End
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="penyusutan-%s-%s.xlsx"`, start, end))
+	c.Data(200, xlsxContentType, buf.Bytes())
+}
+
+// getShrinkageReport returns the shrinkage report of the time range in the query,
+// defaulting to this month and the two months before, writing the error response if it fails.
+func (h *ApiHandler) getShrinkageReport(c *gin.Context) (ShrinkageReport, bool) {
+	var request ShrinkageRequest
+	if err := c.ShouldBindQuery(&request); err != nil {
+		c.JSON(400, gin.H{"error": fmt.Sprintf("invalid request: %s", err)})
+		return ShrinkageReport{}, false
+	}
+
+	from, to, err := getShrinkageTimeRange(c)
+	if err != nil {
+		c.JSON(400, gin.H{"error": fmt.Sprintf("invalid time range: %s", err)})
+		return ShrinkageReport{}, false
+	}
+
+	report, err := h.service.GetShrinkageReport(c.Request.Context(), from, to, request)
+	if err != nil {
+		if errors.Is(err, ErrInvalidShrinkageRequest) {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return ShrinkageReport{}, false
+		}
+
+		c.JSON(500, gin.H{"error": fmt.Sprintf("failed to get shrinkage report: %s", err)})
+		return ShrinkageReport{}, false
+	}
+
+	return report, true
+}
+
+func getShrinkageTimeRange(c *gin.Context) (time.Time, time.Time, error) {
+	if c.Query("date") != "" || c.Query("from") != "" {
+		return time2.GetTimeRangeFromQuery(c)
+	}
+
+	today := time2.BeginningOfToday()
+	return time.Date(today.Year(), today.Month()-2, 1, 0, 0, 0, 0, time.Local), time2.EndOfToday(), nil
+}
+
+func transformShrinkagePeriodsToTable(periods []ShrinkagePeriod) cui.Table {
+	var total ShrinkagePeriod
+
+	rows := make([]cui.Row, len(periods))
+	for i, period := range periods {
+		total.Value.HPPDifference += period.Value.HPPDifference
+		total.Value.SalePriceDifference += period.Value.SalePriceDifference
+		total.Value.Shrinkage += period.Value.Shrinkage
+		total.Sales += period.Sales
+
+		rows[i] = cui.Row{
+			ID: period.Start,
+			Columns: []string{
+				period.Label(),
+				money.FormatRupiah(period.Value.HPPDifference),
+				money.FormatRupiah(period.Value.SalePriceDifference),
+				money.FormatRupiah(period.Value.Shrinkage),
+				money.FormatRupiah(period.Sales),
+				formatShrinkageRatio(period.ShrinkageRatio()),
+			},
+		}
+	}
+
+	return cui.Table{
+		Header: []string{
+			"Periode",
+			"Selisih HPP",
+			"Selisih Harga Jual",
+			"Penyusutan",
+			"Penjualan",
+			"Rasio Penyusutan",
+		},
+		Rows: rows,
+		Footer: []string{
+			"Total",
+			money.FormatRupiah(total.Value.HPPDifference),
+			money.FormatRupiah(total.Value.SalePriceDifference),
+			money.FormatRupiah(total.Value.Shrinkage),
+			money.FormatRupiah(total.Sales),
+			formatShrinkageRatio(total.ShrinkageRatio()),
+		},
+	}
+}
+
+// transformShrinkageGroupsToTable returns a table with the sale price difference of each group per period, and the totals.
+func transformShrinkageGroupsToTable(nameHeader string, periods []ShrinkagePeriod, groups []ShrinkageGroup) cui.Table {
+	header := []string{nameHeader}
+	for _, period := range periods {
+		header = append(header, period.Label())
+	}
+	header = append(header, "Total Selisih HPP", "Total Selisih Harga Jual", "Total Penyusutan")
+
+	rows := make([]cui.Row, len(groups))
+	for i, group := range groups {
+		columns := []string{orDash(group.Name)}
+		for _, value := range group.ByPeriod {
+			columns = append(columns, money.FormatRupiah(value.SalePriceDifference))
+		}
+		columns = append(columns,
+			money.FormatRupiah(group.Total.HPPDifference),
+			money.FormatRupiah(group.Total.SalePriceDifference),
+			money.FormatRupiah(group.Total.Shrinkage),
+		)
+
+		rows[i] = cui.Row{
+			ID:      group.Key,
+			Columns: columns,
+		}
+	}
+
+	return cui.Table{
+		Header: header,
+		Rows:   rows,
+	}
+}
+
+func transformRepeatOffendersToTable(offenders []RepeatOffender) cui.Table {
+	rows := make([]cui.Row, len(offenders))
+	for i, offender := range offenders {
+		rows[i] = cui.Row{
+			ID: offender.DrugCode,
+			Columns: []string{
+				offender.DrugName,
+				orDash(offender.Manufacturer),
+				fmt.Sprintf("%d dari %d kali", offender.NegativeCounts, offender.Counts),
+				offender.LastNegativeDate,
+				money.FormatRupiah(offender.Total.HPPDifference),
+				money.FormatRupiah(offender.Total.SalePriceDifference),
+				money.FormatRupiah(offender.Total.Shrinkage),
+			},
+		}
+	}
+
+	return cui.Table{
+		Header: []string{
+			"Nama Obat",
+			"Pabrik",
+			"Minus",
+			"Minus Terakhir",
+			"Selisih HPP",
+			"Selisih Harga Jual",
+			"Penyusutan",
+		},
+		Rows: rows,
+	}
+}
+
+func formatShrinkageRatio(ratio float64) string {
+	return fmt.Sprintf("%.2f%%", ratio*100)
+}
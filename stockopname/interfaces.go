@@ -3,9 +3,19 @@ package stockopname
 import (
 	"context"
 
+	"github.com/turfaa/vmedis-proxy-api/drug"
 	"github.com/turfaa/vmedis-proxy-api/kafkapb"
 )
 
 type UpdatedDrugProducer interface {
 	ProduceUpdatedDrugByVmedisCode(ctx context.Context, messages []*kafkapb.UpdatedDrugByVmedisCode) error
 }
+
+type EventProducer interface {
+	ProduceDumpCompleted(ctx context.Context, events []*kafkapb.DumpCompleted) error
+}
+
+type DrugsGetter interface {
+	GetDrugsByVmedisCodes(ctx context.Context, vmedisCodes []string) ([]drug.Drug, error)
+	GetDrugByBarcode(ctx context.Context, code string) (drug.Drug, string, error)
+}
@@ -0,0 +1,124 @@
+package stockopname
+
+import (
+	"time"
+
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+	"github.com/turfaa/vmedis-proxy-api/pkg2/slices2"
+)
+
+type CountSession struct {
+	ID         uint               `json:"id"`
+	Date       string             `json:"date"`
+	Name       string             `json:"name"`
+	CreatedBy  string             `json:"createdBy"`
+	CreatedAt  time.Time          `json:"createdAt"`
+	ApprovedAt *time.Time         `json:"approvedAt,omitempty"`
+	ApprovedBy string             `json:"approvedBy,omitempty"`
+	Lines      []CountSessionLine `json:"lines"`
+}
+
+func (s CountSession) Approved() bool {
+	return s.ApprovedAt != nil
+}
+
+func FromDBStockCountSession(session models.StockCountSession) CountSession {
+	return CountSession{
+		ID:         session.ID,
+		Date:       time.Time(session.Date).Format(time.DateOnly),
+		Name:       session.Name,
+		CreatedBy:  session.CreatedBy,
+		CreatedAt:  session.CreatedAt,
+		ApprovedAt: session.ApprovedAt,
+		ApprovedBy: session.ApprovedBy,
+		Lines:      slices2.Map(session.Lines, FromDBStockCountLine),
+	}
+}
+
+type CountSessionLine struct {
+	ID        uint      `json:"id"`
+	DrugCode  string    `json:"drugCode"`
+	DrugName  string    `json:"drugName"`
+	BatchCode string    `json:"batchCode"`
+	Unit      string    `json:"unit"`
+	Quantity  float64   `json:"quantity"`
+	CountedBy string    `json:"countedBy"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+func FromDBStockCountLine(line models.StockCountLine) CountSessionLine {
+	return CountSessionLine{
+		ID:        line.ID,
+		DrugCode:  line.DrugCode,
+		DrugName:  line.DrugName,
+		BatchCode: line.BatchCode,
+		Unit:      line.Unit,
+		Quantity:  line.Quantity,
+		CountedBy: line.CountedBy,
+		UpdatedAt: line.UpdatedAt,
+	}
+}
+
+// CountSessionVariance is the difference between the counted and the current system stock of a drug.
+type CountSessionVariance struct {
+	DrugCode string `json:"drugCode"`
+	DrugName string `json:"drugName"`
+
+	// SystemStocks are the current stocks of the drug in drug_stocks.
+	SystemStocks []CountedStock `json:"systemStocks"`
+
+	// CountedStocks are the counted quantities of the drug, summed up per unit over the batches.
+	CountedStocks []CountedStock `json:"countedStocks"`
+
+	// Difference is the counted minus the system stock, in the smallest unit.
+	Difference   float64 `json:"difference"`
+	SmallestUnit string  `json:"smallestUnit"`
+}
+
+// CountedStock is a quantity of a drug in one of its units.
+type CountedStock struct {
+	Unit     string  `json:"unit"`
+	Quantity float64 `json:"quantity"`
+}
+
+// CountSessionComparison compares a counted line with the stock opname entered into Vmedis for it.
+type CountSessionComparison struct {
+	Line CountSessionLine `json:"line"`
+
+	// StockOpname is the first stock opname of the batch and unit of the drug from the date of the session,
+	// nil if it isn't entered into Vmedis yet.
+	StockOpname *StockOpname `json:"stockOpname,omitempty"`
+}
+
+// Matches returns whether the stock opname entered into Vmedis has the counted quantity.
+func (c CountSessionComparison) Matches() bool {
+	return c.StockOpname != nil && c.StockOpname.RealQuantity == c.Line.Quantity
+}
+
+// CreateCountSessionRequest is the request schema of the counting session creation API.
+type CreateCountSessionRequest struct {
+	Name string `json:"name" binding:"required"`
+
+	// Date is the date of the count in the YYYY-MM-DD format, defaulting to today.
+	Date string `json:"date"`
+}
+
+// CountSessionLineRequest records the counted quantity of a batch of a drug in one of its units.
+// A line with the same drug, batch, and unit is overwritten.
+// The fields are strings so a filled cui.Form can be submitted as-is.
+type CountSessionLineRequest struct {
+	// Barcode identifies the drug, and the unit if the unit is empty, in place of the drug code.
+	Barcode   string `json:"barcode"`
+	DrugCode  string `json:"drugCode"`
+	BatchCode string `json:"batchCode"`
+	Unit      string `json:"unit"`
+	Quantity  string `json:"quantity" binding:"required"`
+}
+
+type CountSessionResponse struct {
+	Session CountSession `json:"session"`
+}
+
+type CountSessionLineResponse struct {
+	Line CountSessionLine `json:"line"`
+}
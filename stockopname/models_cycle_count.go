@@ -0,0 +1,75 @@
+package stockopname
+
+import (
+	"time"
+
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+)
+
+type CycleCountPlanRequest struct {
+	// StartDate is the first date of the cycle in the YYYY-MM-DD format, defaulting to today.
+	// The tasks planned from the start date are replaced, the earlier ones are kept.
+	StartDate string `json:"startDate"`
+
+	// CycleDays is the length of the cycle in days, defaulting to 90 days.
+	// Every drug is counted at least once per cycle.
+	CycleDays int `json:"cycleDays"`
+
+	// Staff are the emails of the staff members counting the drugs, defaulting to the users with the staff role.
+	Staff []string `json:"staff"`
+}
+
+type CycleCountPlan struct {
+	StartDate   string   `json:"startDate"`
+	EndDate     string   `json:"endDate"`
+	WorkingDays int      `json:"workingDays"`
+	Drugs       int      `json:"drugs"`
+	Tasks       int      `json:"tasks"`
+	Staff       []string `json:"staff"`
+}
+
+type CycleCountTasksRequest struct {
+	// Date is the date of the tasks in the YYYY-MM-DD format, defaulting to today.
+	Date string `form:"date"`
+
+	// Assignee filters the tasks by the email of their staff member.
+	Assignee string `form:"assignee"`
+}
+
+type CycleCountTask struct {
+	Date           string `json:"date"`
+	DrugCode       string `json:"drugCode"`
+	DrugName       string `json:"drugName"`
+	Assignee       string `json:"assignee"`
+	CountsPerCycle int    `json:"countsPerCycle"`
+
+	// CountedAt is the date of the first stock opname of the drug from the date of the task until its next task,
+	// empty if the task isn't completed yet.
+	CountedAt string `json:"countedAt,omitempty"`
+}
+
+func (t CycleCountTask) Completed() bool {
+	return t.CountedAt != ""
+}
+
+func FromDBCycleCountTask(task models.CycleCountTask) CycleCountTask {
+	return CycleCountTask{
+		Date:           time.Time(task.Date).Format(time.DateOnly),
+		DrugCode:       task.DrugCode,
+		DrugName:       task.DrugName,
+		Assignee:       task.Assignee,
+		CountsPerCycle: task.CountsPerCycle,
+	}
+}
+
+// CycleCountProgress is the number of tasks planned and completed by a staff member.
+type CycleCountProgress struct {
+	Assignee  string `json:"assignee"`
+	Planned   int    `json:"planned"`
+	Completed int    `json:"completed"`
+}
+
+// CycleCountPlanResponse is the response schema of the cycle count planning API.
+type CycleCountPlanResponse struct {
+	Plan CycleCountPlan `json:"plan"`
+}
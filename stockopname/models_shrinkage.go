@@ -0,0 +1,101 @@
+package stockopname
+
+import "fmt"
+
+// ShrinkagePeriodType is the length of the periods the shrinkage is reported in.
+type ShrinkagePeriodType string
+
+const (
+	ShrinkagePeriodWeek  ShrinkagePeriodType = "week"
+	ShrinkagePeriodMonth ShrinkagePeriodType = "month"
+
+	defaultShrinkageMinNegativeCounts = 3
+)
+
+// ShrinkageRequest is the request schema of the shrinkage report APIs.
+type ShrinkageRequest struct {
+	// Period is the length of the periods, week or month, defaulting to month.
+	Period ShrinkagePeriodType `form:"period"`
+
+	// MinNegativeCounts is the minimum number of days a drug was counted short to be a repeat offender, defaulting to 3.
+	MinNegativeCounts int `form:"minNegativeCounts"`
+}
+
+// ShrinkageReport is the trend of the stock opname differences between two times.
+type ShrinkageReport struct {
+	Periods []ShrinkagePeriod `json:"periods"`
+
+	// Drugs, Manufacturers, and Staff break the differences down by their keys,
+	// with the values of each group aligned with the periods.
+	// The staff member of a stock opname is the one who counted it in a counting session,
+	// or else the assignee of the cycle count task it completed, empty if unknown.
+	Drugs         []ShrinkageGroup `json:"drugs"`
+	Manufacturers []ShrinkageGroup `json:"manufacturers"`
+	Staff         []ShrinkageGroup `json:"staff"`
+
+	RepeatOffenders []RepeatOffender `json:"repeatOffenders"`
+}
+
+// ShrinkageValue is the sum of the stock opname differences.
+type ShrinkageValue struct {
+	HPPDifference       float64 `json:"hppDifference"`
+	SalePriceDifference float64 `json:"salePriceDifference"`
+
+	// Shrinkage is the value of the missing stocks at the sale price, the sum of the negative sale price differences as a positive number.
+	Shrinkage float64 `json:"shrinkage"`
+}
+
+func (v ShrinkageValue) add(so StockOpname) ShrinkageValue {
+	v.HPPDifference += so.HPPDifference
+	v.SalePriceDifference += so.SalePriceDifference
+	if so.SalePriceDifference < 0 {
+		v.Shrinkage -= so.SalePriceDifference
+	}
+
+	return v
+}
+
+// ShrinkagePeriod is the sum of the stock opname differences in a week or a month, cut at the ends of the report.
+type ShrinkagePeriod struct {
+	Start string         `json:"start"`
+	End   string         `json:"end"`
+	Value ShrinkageValue `json:"value"`
+
+	// Sales is the total of the sales in the period.
+	Sales float64 `json:"sales"`
+}
+
+// Label returns the dates of the period for display.
+func (p ShrinkagePeriod) Label() string {
+	return fmt.Sprintf("%s s.d. %s", p.Start, p.End)
+}
+
+// ShrinkageRatio returns the shrinkage as a fraction of the sales, or zero without sales.
+func (p ShrinkagePeriod) ShrinkageRatio() float64 {
+	if p.Sales == 0 {
+		return 0
+	}
+
+	return p.Value.Shrinkage / p.Sales
+}
+
+// ShrinkageGroup is the sum of the stock opname differences of a drug, a manufacturer, or a staff member.
+type ShrinkageGroup struct {
+	Key      string           `json:"key"`
+	Name     string           `json:"name"`
+	Total    ShrinkageValue   `json:"total"`
+	ByPeriod []ShrinkageValue `json:"byPeriod"`
+}
+
+// RepeatOffender is a drug counted short on many days.
+type RepeatOffender struct {
+	DrugCode     string `json:"drugCode"`
+	DrugName     string `json:"drugName"`
+	Manufacturer string `json:"manufacturer"`
+
+	// NegativeCounts is the number of days the drug was counted short, out of the Counts days it was counted.
+	NegativeCounts   int            `json:"negativeCounts"`
+	Counts           int            `json:"counts"`
+	LastNegativeDate string         `json:"lastNegativeDate"`
+	Total            ShrinkageValue `json:"total"`
+}
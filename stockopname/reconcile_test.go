@@ -0,0 +1,107 @@
+package stockopname
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/turfaa/vmedis-proxy-api/database"
+	"github.com/turfaa/vmedis-proxy-api/kafkapb"
+	vmedisv1 "github.com/turfaa/vmedis-proxy-api/vmedis/v1"
+)
+
+type recordingDrugProducer struct {
+	messages []*kafkapb.UpdatedDrugByVmedisCode
+}
+
+func (r *recordingDrugProducer) ProduceUpdatedDrugByVmedisCode(_ context.Context, messages []*kafkapb.UpdatedDrugByVmedisCode) error {
+	r.messages = append(r.messages, messages...)
+	return nil
+}
+
+type nopEventProducer struct{}
+
+func (nopEventProducer) ProduceDumpCompleted(context.Context, []*kafkapb.DumpCompleted) error {
+	return nil
+}
+
+// TestSoftDeleteStockOpnamesMissingFromVmedis checks that reconciliation soft-deletes
+// exactly the stock opnames that are in the DB but no longer in Vmedis for the
+// reconciled date, keeping the ones of other dates and the ones without an ID in Vmedis,
+// and that dumping a deleted stock opname again revives it.
+func TestSoftDeleteStockOpnamesMissingFromVmedis(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := database.SqliteDB(t.TempDir() + "/test.db")
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+
+	drugProducer := &recordingDrugProducer{}
+	service := NewService(db, nil, drugProducer, nopEventProducer{}, nil)
+
+	date := time.Date(2026, 8, 7, 0, 0, 0, 0, time.Local)
+	newStockOpname := func(id string, drugCode string, at time.Time) vmedisv1.StockOpname {
+		return vmedisv1.StockOpname{
+			ID:        id,
+			Date:      vmedisv1.Date{Time: at},
+			DrugCode:  drugCode,
+			BatchCode: "B1",
+			Unit:      "Strip",
+		}
+	}
+
+	dumped := []vmedisv1.StockOpname{
+		newStockOpname("SO1", "D1", date),
+		newStockOpname("SO2", "D2", date),
+		newStockOpname("", "D3", date),
+		newStockOpname("SO-OTHER-DATE", "D4", date.AddDate(0, 0, -1)),
+	}
+	if err := service.dumpStockOpnamesToDB(ctx, time.Now(), dumped); err != nil {
+		t.Fatalf("dump stock opnames: %v", err)
+	}
+
+	// SO1 was deleted in Vmedis; SO2 and the stock opname without an ID are still there.
+	drugProducer.messages = nil
+	stillInVmedis := []vmedisv1.StockOpname{
+		newStockOpname("SO2", "D2", date),
+		newStockOpname("", "D3", date),
+	}
+
+	deleted, err := service.softDeleteStockOpnamesMissingFromVmedis(ctx, date, stillInVmedis)
+	if err != nil {
+		t.Fatalf("soft delete stock opnames missing from vmedis: %v", err)
+	}
+	if deleted != 1 {
+		t.Fatalf("soft-deleted %d stock opnames, want 1", deleted)
+	}
+	if len(drugProducer.messages) != 1 || drugProducer.messages[0].VmedisCode != "D1" {
+		t.Errorf("updated drugs = %v, want D1", drugProducer.messages)
+	}
+
+	visible, err := service.db.GetStockOpnamesBetweenTime(ctx, date.AddDate(0, 0, -1), date)
+	if err != nil {
+		t.Fatalf("get visible stock opnames: %v", err)
+	}
+
+	visibleDrugs := make(map[string]bool)
+	for _, so := range visible {
+		visibleDrugs[so.DrugCode] = true
+	}
+	if len(visibleDrugs) != 3 || visibleDrugs["D1"] {
+		t.Errorf("visible drugs = %v, want D2, D3, and D4", visibleDrugs)
+	}
+
+	// SO1 comes back in Vmedis, so the next dump revives it.
+	if err := service.dumpStockOpnamesToDB(ctx, time.Now(), []vmedisv1.StockOpname{newStockOpname("SO1", "D1", date)}); err != nil {
+		t.Fatalf("dump stock opnames again: %v", err)
+	}
+
+	revived, err := service.db.GetStockOpnamesBetweenTime(ctx, date, date)
+	if err != nil {
+		t.Fatalf("get revived stock opnames: %v", err)
+	}
+	if len(revived) != 3 {
+		t.Errorf("stock opnames after the re-dump = %+v, want SO1, SO2, and the one without an ID", revived)
+	}
+}
@@ -5,11 +5,14 @@ import (
 	"fmt"
 	"log"
 	"math"
+	"slices"
 	"sort"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
 
+	"github.com/turfaa/vmedis-proxy-api/events"
 	"github.com/turfaa/vmedis-proxy-api/kafkapb"
 	"github.com/turfaa/vmedis-proxy-api/vmedis/v1"
 )
@@ -18,6 +21,8 @@ type Service struct {
 	db           *Database
 	vmedis       *vmedisv1.Client
 	drugProducer UpdatedDrugProducer
+	events       EventProducer
+	drugsGetter  DrugsGetter
 }
 
 func (s *Service) GetStockOpnamesBetweenTime(ctx context.Context, from, to time.Time) ([]StockOpname, error) {
@@ -127,6 +132,7 @@ func addStockOpnameToSummary(summary Summary, stockOpname StockOpname) Summary {
 
 func (s *Service) DumpTodayStockOpnamesFromVmedisToDB(ctx context.Context) error {
 	log.Println("Dumping stock opnames")
+	startedAt := time.Now()
 
 	log.Println("Getting all today's stock opnames from Vmedis")
 	stockOpnames, err := s.vmedis.GetAllTodayStockOpnames(ctx)
@@ -134,6 +140,23 @@ func (s *Service) DumpTodayStockOpnamesFromVmedisToDB(ctx context.Context) error
 		return fmt.Errorf("get all today's stock opnames from Vmedis: %w", err)
 	}
 
+	return s.dumpStockOpnamesToDB(ctx, startedAt, stockOpnames)
+}
+
+// DumpStockOpnamesBetweenDatesFromVmedisToDB dumps the stock opnames between the given dates from Vmedis to the DB.
+func (s *Service) DumpStockOpnamesBetweenDatesFromVmedisToDB(ctx context.Context, startDate time.Time, endDate time.Time) error {
+	log.Printf("Dumping stock opnames between %s and %s", startDate.Format(time.DateOnly), endDate.Format(time.DateOnly))
+	startedAt := time.Now()
+
+	stockOpnames, err := s.vmedis.GetAllStockOpnamesBetweenDates(ctx, startDate, endDate)
+	if err != nil {
+		return fmt.Errorf("get stock opnames between %s and %s from Vmedis: %w", startDate.Format(time.DateOnly), endDate.Format(time.DateOnly), err)
+	}
+
+	return s.dumpStockOpnamesToDB(ctx, startedAt, stockOpnames)
+}
+
+func (s *Service) dumpStockOpnamesToDB(ctx context.Context, startedAt time.Time, stockOpnames []vmedisv1.StockOpname) error {
 	if len(stockOpnames) == 0 {
 		log.Println("No stock opnames found")
 		return nil
@@ -141,12 +164,6 @@ func (s *Service) DumpTodayStockOpnamesFromVmedisToDB(ctx context.Context) error
 
 	log.Printf("Got %d stock opnames from Vmedis", len(stockOpnames))
 
-	log.Println("Upserting stock opnames to DB")
-	if err := s.db.UpsertVmedisStockOpnames(ctx, stockOpnames); err != nil {
-		return fmt.Errorf("upsert vmedis stock opnames: %w", err)
-	}
-	log.Println("Done upserting stock opnames to DB")
-
 	kafkaMessages := make([]*kafkapb.UpdatedDrugByVmedisCode, 0, len(stockOpnames))
 	for _, so := range stockOpnames {
 		kafkaMessages = append(kafkaMessages, &kafkapb.UpdatedDrugByVmedisCode{
@@ -155,21 +172,141 @@ func (s *Service) DumpTodayStockOpnamesFromVmedisToDB(ctx context.Context) error
 		})
 	}
 
-	log.Printf("Producing %d updated drugs kafka messages", len(kafkaMessages))
-	if err := s.drugProducer.ProduceUpdatedDrugByVmedisCode(ctx, kafkaMessages); err != nil {
-		log.Printf("Error producing updated drugs: %s", err)
-	} else {
-		log.Printf("Produced %d updated drugs kafka messages", len(kafkaMessages))
+	log.Printf("Upserting stock opnames to DB and producing %d updated drugs kafka messages", len(kafkaMessages))
+	if err := s.db.Transaction(ctx, func(ctx context.Context, tx *Database) error {
+		if err := tx.UpsertVmedisStockOpnames(ctx, stockOpnames); err != nil {
+			return fmt.Errorf("upsert vmedis stock opnames: %w", err)
+		}
+
+		if err := s.drugProducer.ProduceUpdatedDrugByVmedisCode(ctx, kafkaMessages); err != nil {
+			return fmt.Errorf("produce updated drugs: %w", err)
+		}
+
+		return nil
+	}); err != nil {
+		return err
+	}
+	log.Println("Done upserting stock opnames to DB")
+
+	if err := s.events.ProduceDumpCompleted(ctx, []*kafkapb.DumpCompleted{events.DumpCompleted(events.DumpStockOpnames, startedAt, len(stockOpnames))}); err != nil {
+		return fmt.Errorf("produce dump completed event: %w", err)
 	}
 
 	log.Println("Done dumping stock opnames")
 	return nil
 }
 
-func NewService(db *gorm.DB, vmedisClient *vmedisv1.Client, drugProducer UpdatedDrugProducer) *Service {
+// ReconcileStockOpnamesBetweenDatesWithVmedis re-fetches the stock opnames of each date between
+// startDate and endDate from Vmedis, one date at a time, and soft-deletes the
+// stock opnames that are stored in the DB but no longer exist in Vmedis. Stock opnames
+// can be deleted in Vmedis after being dumped, and the dump only upserts, so this is
+// the way deletions propagate to the DB.
+func (s *Service) ReconcileStockOpnamesBetweenDatesWithVmedis(ctx context.Context, startDate time.Time, endDate time.Time) error {
+	log.Printf("Reconciling stock opnames between %s and %s with Vmedis", startDate.Format(time.DateOnly), endDate.Format(time.DateOnly))
+
+	for date := startDate; !date.After(endDate); date = date.AddDate(0, 0, 1) {
+		if err := s.reconcileStockOpnamesAtDateWithVmedis(ctx, date); err != nil {
+			return fmt.Errorf("reconcile stock opnames at %s: %w", date.Format(time.DateOnly), err)
+		}
+	}
+
+	log.Printf("Finished reconciling stock opnames between %s and %s with Vmedis", startDate.Format(time.DateOnly), endDate.Format(time.DateOnly))
+	return nil
+}
+
+func (s *Service) reconcileStockOpnamesAtDateWithVmedis(ctx context.Context, date time.Time) error {
+	log.Printf("Reconciling stock opnames at %s with Vmedis", date.Format(time.DateOnly))
+
+	vmedisStockOpnames, err := s.vmedis.GetAllStockOpnamesBetweenDates(ctx, date, date)
+	if err != nil {
+		return fmt.Errorf("get stock opnames at %s from vmedis: %w", date.Format(time.DateOnly), err)
+	}
+
+	deleted, err := s.softDeleteStockOpnamesMissingFromVmedis(ctx, date, vmedisStockOpnames)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Reconciled stock opnames at %s with Vmedis: %d stock opnames in Vmedis, %d stock opnames soft-deleted", date.Format(time.DateOnly), len(vmedisStockOpnames), deleted)
+	return nil
+}
+
+// softDeleteStockOpnamesMissingFromVmedis soft-deletes the DB stock opnames of the given
+// date whose Vmedis IDs are not in vmedisStockOpnames, and refreshes their drugs.
+// A stock opname without an ID in Vmedis is stored with a random suffix,
+// so it's matched by the prefix of its ID instead.
+func (s *Service) softDeleteStockOpnamesMissingFromVmedis(ctx context.Context, date time.Time, vmedisStockOpnames []vmedisv1.StockOpname) (int, error) {
+	inVmedis := make(map[string]struct{}, len(vmedisStockOpnames))
+	var fallbackPrefixes []string
+	for _, so := range vmedisStockOpnames {
+		if so.ID == "" {
+			fallbackPrefixes = append(fallbackPrefixes, fallbackVmedisIDPrefix(so))
+			continue
+		}
+
+		inVmedis[so.ID] = struct{}{}
+	}
+
+	beginningOfDate := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.Local)
+	endOfDate := time.Date(date.Year(), date.Month(), date.Day(), 23, 59, 59, 999999999, time.Local)
+
+	dbStockOpnames, err := s.db.GetStockOpnameKeysBetweenTime(ctx, beginningOfDate, endOfDate)
+	if err != nil {
+		return 0, fmt.Errorf("get stock opnames at %s from DB: %w", date.Format(time.DateOnly), err)
+	}
+
+	var missingIDs []string
+	var updatedDrugs []*kafkapb.UpdatedDrugByVmedisCode
+	for _, so := range dbStockOpnames {
+		if _, ok := inVmedis[so.VmedisID]; ok {
+			continue
+		}
+
+		if slices.ContainsFunc(fallbackPrefixes, func(prefix string) bool { return strings.HasPrefix(so.VmedisID, prefix) }) {
+			continue
+		}
+
+		log.Printf("Stock opname %s of drug %s no longer exists in Vmedis, soft-deleting it", so.VmedisID, so.DrugCode)
+		missingIDs = append(missingIDs, so.VmedisID)
+		updatedDrugs = append(updatedDrugs, &kafkapb.UpdatedDrugByVmedisCode{
+			RequestKey: fmt.Sprintf("stock-opname-deleted:%s:%s", so.VmedisID, so.DrugCode),
+			VmedisCode: so.DrugCode,
+		})
+	}
+
+	if len(missingIDs) == 0 {
+		return 0, nil
+	}
+
+	if err := s.db.Transaction(ctx, func(ctx context.Context, tx *Database) error {
+		if err := tx.DeleteStockOpnamesByVmedisIDs(ctx, missingIDs); err != nil {
+			return fmt.Errorf("soft-delete stock opnames: %w", err)
+		}
+
+		if err := s.drugProducer.ProduceUpdatedDrugByVmedisCode(ctx, updatedDrugs); err != nil {
+			return fmt.Errorf("produce updated drugs: %w", err)
+		}
+
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+
+	return len(missingIDs), nil
+}
+
+func NewService(
+	db *gorm.DB,
+	vmedisClient *vmedisv1.Client,
+	drugProducer UpdatedDrugProducer,
+	eventProducer EventProducer,
+	drugsGetter DrugsGetter,
+) *Service {
 	return &Service{
 		db:           NewDatabase(db),
 		vmedis:       vmedisClient,
 		drugProducer: drugProducer,
+		events:       eventProducer,
+		drugsGetter:  drugsGetter,
 	}
 }
@@ -0,0 +1,489 @@
+package stockopname
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+	"github.com/turfaa/vmedis-proxy-api/pkg2/time2"
+)
+
+const (
+	// countedLineAttributionDays is the number of days a stock opname is entered into Vmedis after it's counted in a counting session.
+	countedLineAttributionDays = 7
+
+	unknownStaff = "Tidak diketahui"
+)
+
+// ErrInvalidShrinkageRequest is returned when a shrinkage report is requested with invalid parameters.
+var ErrInvalidShrinkageRequest = errors.New("invalid shrinkage request")
+
+// GetShrinkageReport returns the trend of the stock opname differences between the given times,
+// broken down by period, drug, manufacturer, and staff member, with the drugs counted short again and again.
+func (s *Service) GetShrinkageReport(ctx context.Context, from, to time.Time, request ShrinkageRequest) (ShrinkageReport, error) {
+	periodType := request.Period
+	if periodType == "" {
+		periodType = ShrinkagePeriodMonth
+	}
+
+	if periodType != ShrinkagePeriodWeek && periodType != ShrinkagePeriodMonth {
+		return ShrinkageReport{}, fmt.Errorf("%w: period must be %s or %s", ErrInvalidShrinkageRequest, ShrinkagePeriodWeek, ShrinkagePeriodMonth)
+	}
+
+	minNegativeCounts := request.MinNegativeCounts
+	if minNegativeCounts <= 0 {
+		minNegativeCounts = defaultShrinkageMinNegativeCounts
+	}
+
+	stockOpnames, err := s.db.GetStockOpnamesBetweenTime(ctx, from, to)
+	if err != nil {
+		return ShrinkageReport{}, fmt.Errorf("get stock opnames: %w", err)
+	}
+
+	drugCodes := slices.Compact(slices.Sorted(func(yield func(string) bool) {
+		for _, so := range stockOpnames {
+			if !yield(so.DrugCode) {
+				return
+			}
+		}
+	}))
+
+	manufacturers, err := s.db.getManufacturers(ctx, drugCodes)
+	if err != nil {
+		return ShrinkageReport{}, fmt.Errorf("get manufacturers: %w", err)
+	}
+
+	sales, err := s.db.getSaleTotalsBetweenTime(ctx, from, to)
+	if err != nil {
+		return ShrinkageReport{}, fmt.Errorf("get sales: %w", err)
+	}
+
+	lines, err := s.db.getCountedLinesBetweenTime(ctx, from.AddDate(0, 0, -countedLineAttributionDays), to)
+	if err != nil {
+		return ShrinkageReport{}, fmt.Errorf("get counted lines: %w", err)
+	}
+
+	tasks, err := s.db.getCycleCountTasksOfDrugsBetweenTime(ctx, drugCodes, from.AddDate(0, 0, -maxCycleDays), to)
+	if err != nil {
+		return ShrinkageReport{}, fmt.Errorf("get cycle count tasks: %w", err)
+	}
+
+	periods := newShrinkagePeriods(from, to, periodType)
+	for _, sale := range sales {
+		if i := periods.index(sale.SoldAt); i >= 0 {
+			periods.periods[i].Sales += sale.Total
+		}
+	}
+
+	return buildShrinkageReport(stockOpnames, periods, manufacturers, newStaffAttribution(lines, tasks), minNegativeCounts), nil
+}
+
+func buildShrinkageReport(
+	stockOpnames []StockOpname,
+	periods shrinkagePeriods,
+	manufacturers map[string]string,
+	attribution staffAttribution,
+	minNegativeCounts int,
+) ShrinkageReport {
+	drugs := newShrinkageGrouper(len(periods.periods))
+	manufacturerGroups := newShrinkageGrouper(len(periods.periods))
+	staff := newShrinkageGrouper(len(periods.periods))
+
+	// The differences of a drug are netted per day, so a batch counted short and another counted over on the same day cancel out.
+	dailyDifferences := make(map[string]map[string]float64)
+
+	for _, so := range stockOpnames {
+		date, err := time2.BeginningOfDate(so.Date)
+		if err != nil {
+			continue
+		}
+
+		period := periods.index(date)
+		if period < 0 {
+			continue
+		}
+
+		periods.periods[period].Value = periods.periods[period].Value.add(so)
+
+		manufacturer := manufacturers[so.DrugCode]
+		drugs.add(so.DrugCode, so.DrugName, period, so)
+		manufacturerGroups.add(strings.ToUpper(manufacturer), manufacturer, period, so)
+
+		staffMember := attribution.staff(so, date)
+		staff.add(staffMember, staffMember, period, so)
+
+		if dailyDifferences[so.DrugCode] == nil {
+			dailyDifferences[so.DrugCode] = make(map[string]float64)
+		}
+		dailyDifferences[so.DrugCode][so.Date] += so.SalePriceDifference
+	}
+
+	drugGroups := drugs.sorted()
+
+	var repeatOffenders []RepeatOffender
+	for _, group := range drugGroups {
+		offender := RepeatOffender{
+			DrugCode:     group.Key,
+			DrugName:     group.Name,
+			Manufacturer: manufacturers[group.Key],
+			Counts:       len(dailyDifferences[group.Key]),
+			Total:        group.Total,
+		}
+
+		for date, difference := range dailyDifferences[group.Key] {
+			if difference >= 0 {
+				continue
+			}
+
+			offender.NegativeCounts++
+			offender.LastNegativeDate = max(offender.LastNegativeDate, date)
+		}
+
+		if offender.NegativeCounts >= minNegativeCounts {
+			repeatOffenders = append(repeatOffenders, offender)
+		}
+	}
+
+	slices.SortStableFunc(repeatOffenders, func(a, b RepeatOffender) int {
+		return cmp.Or(
+			cmp.Compare(b.NegativeCounts, a.NegativeCounts),
+			cmp.Compare(b.Total.Shrinkage, a.Total.Shrinkage),
+		)
+	})
+
+	return ShrinkageReport{
+		Periods:         periods.periods,
+		Drugs:           drugGroups,
+		Manufacturers:   manufacturerGroups.sorted(),
+		Staff:           staff.sorted(),
+		RepeatOffenders: repeatOffenders,
+	}
+}
+
+// shrinkagePeriods are the consecutive periods between two times.
+type shrinkagePeriods struct {
+	starts  []time.Time
+	periods []ShrinkagePeriod
+}
+
+// newShrinkagePeriods splits the dates between the given times into weeks starting on Monday or calendar months.
+// The first and the last periods are cut at the given times.
+func newShrinkagePeriods(from, to time.Time, periodType ShrinkagePeriodType) shrinkagePeriods {
+	from, to = dateOf(from), dateOf(to)
+
+	start := from
+	if periodType == ShrinkagePeriodWeek {
+		start = start.AddDate(0, 0, -(int(start.Weekday())+6)%7)
+	} else {
+		start = start.AddDate(0, 0, 1-start.Day())
+	}
+
+	var periods shrinkagePeriods
+	for !start.After(to) {
+		next := start.AddDate(0, 0, 7)
+		if periodType == ShrinkagePeriodMonth {
+			next = start.AddDate(0, 1, 0)
+		}
+
+		periods.starts = append(periods.starts, start)
+		periods.periods = append(periods.periods, ShrinkagePeriod{
+			Start: later(start, from).Format(time.DateOnly),
+			End:   earlier(next.AddDate(0, 0, -1), to).Format(time.DateOnly),
+		})
+
+		start = next
+	}
+
+	return periods
+}
+
+// index returns the index of the period of the given time, or -1 if it's outside the periods.
+func (p shrinkagePeriods) index(t time.Time) int {
+	if len(p.starts) == 0 {
+		return -1
+	}
+
+	date := dateOf(t)
+	last, err := time2.BeginningOfDate(p.periods[len(p.periods)-1].End)
+	if err != nil || date.Before(p.starts[0]) || date.After(last) {
+		return -1
+	}
+
+	i, found := slices.BinarySearchFunc(p.starts, date, func(start time.Time, date time.Time) int {
+		return start.Compare(date)
+	})
+	if found {
+		return i
+	}
+
+	return i - 1
+}
+
+// shrinkageGrouper sums up the differences of the stock opnames by a key, per period.
+type shrinkageGrouper struct {
+	periods int
+	groups  map[string]*ShrinkageGroup
+}
+
+func newShrinkageGrouper(periods int) *shrinkageGrouper {
+	return &shrinkageGrouper{
+		periods: periods,
+		groups:  make(map[string]*ShrinkageGroup),
+	}
+}
+
+func (g *shrinkageGrouper) add(key string, name string, period int, so StockOpname) {
+	group, ok := g.groups[key]
+	if !ok {
+		group = &ShrinkageGroup{
+			Key:      key,
+			Name:     name,
+			ByPeriod: make([]ShrinkageValue, g.periods),
+		}
+		g.groups[key] = group
+	}
+
+	group.Total = group.Total.add(so)
+	group.ByPeriod[period] = group.ByPeriod[period].add(so)
+}
+
+// sorted returns the groups from the largest loss at the sale price.
+func (g *shrinkageGrouper) sorted() []ShrinkageGroup {
+	groups := make([]ShrinkageGroup, 0, len(g.groups))
+	for _, group := range g.groups {
+		groups = append(groups, *group)
+	}
+
+	slices.SortFunc(groups, func(a, b ShrinkageGroup) int {
+		return cmp.Or(
+			cmp.Compare(a.Total.SalePriceDifference, b.Total.SalePriceDifference),
+			cmp.Compare(a.Key, b.Key),
+		)
+	})
+
+	return groups
+}
+
+// staffAttribution finds the staff member who counted a stock opname.
+type staffAttribution struct {
+	linesByKey  map[string][]countedLine
+	tasksByDrug map[string][]models.CycleCountTask
+}
+
+// newStaffAttribution expects the lines and the tasks to be sorted by their dates.
+func newStaffAttribution(lines []countedLine, tasks []models.CycleCountTask) staffAttribution {
+	attribution := staffAttribution{
+		linesByKey:  make(map[string][]countedLine),
+		tasksByDrug: make(map[string][]models.CycleCountTask),
+	}
+
+	for _, line := range lines {
+		key := countedLineKey(line.DrugCode, line.BatchCode, line.Unit)
+		attribution.linesByKey[key] = append(attribution.linesByKey[key], line)
+	}
+
+	for _, task := range tasks {
+		attribution.tasksByDrug[task.DrugCode] = append(attribution.tasksByDrug[task.DrugCode], task)
+	}
+
+	return attribution
+}
+
+// staff returns the staff member who counted the batch in the latest counting session up to a few days before the stock opname,
+// or else the assignee of the latest cycle count task of the drug up to the stock opname, or empty if unknown.
+func (a staffAttribution) staff(so StockOpname, date time.Time) string {
+	lines := a.linesByKey[countedLineKey(so.DrugCode, so.BatchCode, so.Unit)]
+	for i := len(lines) - 1; i >= 0; i-- {
+		lineDate := dateOf(lines[i].Date)
+		if lineDate.After(date) {
+			continue
+		}
+
+		if lineDate.AddDate(0, 0, countedLineAttributionDays).Before(date) {
+			break
+		}
+
+		return lines[i].CountedBy
+	}
+
+	tasks := a.tasksByDrug[so.DrugCode]
+	for i := len(tasks) - 1; i >= 0; i-- {
+		if !dateOf(time.Time(tasks[i].Date)).After(date) {
+			return tasks[i].Assignee
+		}
+	}
+
+	return ""
+}
+
+func countedLineKey(drugCode, batchCode, unit string) string {
+	return strings.ToLower(fmt.Sprintf("%s#%s#%s", drugCode, strings.TrimSpace(batchCode), unit))
+}
+
+func earlier(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+
+	return b
+}
+
+func later(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+
+	return b
+}
+
+// WriteShrinkageXLSX writes the shrinkage report as an XLSX file, one sheet per breakdown.
+func WriteShrinkageXLSX(w io.Writer, report ShrinkageReport) error {
+	f := excelize.NewFile()
+	defer func() {
+		if err := f.Close(); err != nil {
+			log.Printf("failed to close file: %s", err)
+		}
+	}()
+
+	periodRows := make([][]any, 0, len(report.Periods))
+	for _, period := range report.Periods {
+		periodRows = append(periodRows, []any{
+			period.Label(),
+			period.Value.HPPDifference,
+			period.Value.SalePriceDifference,
+			period.Value.Shrinkage,
+			period.Sales,
+			period.ShrinkageRatio(),
+		})
+	}
+
+	offenderRows := make([][]any, 0, len(report.RepeatOffenders))
+	for _, offender := range report.RepeatOffenders {
+		offenderRows = append(offenderRows, []any{
+			offender.DrugCode,
+			offender.DrugName,
+			offender.Manufacturer,
+			offender.NegativeCounts,
+			offender.Counts,
+			offender.LastNegativeDate,
+			offender.Total.HPPDifference,
+			offender.Total.SalePriceDifference,
+			offender.Total.Shrinkage,
+		})
+	}
+
+	sheets := []struct {
+		name   string
+		header []string
+		rows   [][]any
+	}{
+		{
+			name:   "Per Periode",
+			header: []string{"Periode", "Selisih HPP", "Selisih Harga Jual", "Penyusutan", "Penjualan", "Rasio Penyusutan"},
+			rows:   periodRows,
+		},
+		{
+			name:   "Per Obat",
+			header: shrinkageGroupHeader(report.Periods, "Kode Obat", "Nama Obat"),
+			rows: shrinkageGroupRows(report.Drugs, func(group ShrinkageGroup) []any {
+				return []any{group.Key, group.Name}
+			}),
+		},
+		{
+			name:   "Per Pabrik",
+			header: shrinkageGroupHeader(report.Periods, "Pabrik"),
+			rows: shrinkageGroupRows(report.Manufacturers, func(group ShrinkageGroup) []any {
+				return []any{group.Name}
+			}),
+		},
+		{
+			name:   "Per Petugas",
+			header: shrinkageGroupHeader(report.Periods, "Petugas"),
+			rows: shrinkageGroupRows(report.Staff, func(group ShrinkageGroup) []any {
+				return []any{staffName(group.Name)}
+			}),
+		},
+		{
+			name:   "Obat Berulang Minus",
+			header: []string{"Kode Obat", "Nama Obat", "Pabrik", "Jumlah Minus", "Jumlah Dihitung", "Minus Terakhir", "Selisih HPP", "Selisih Harga Jual", "Penyusutan"},
+			rows:   offenderRows,
+		},
+	}
+
+	for _, sheet := range sheets {
+		if _, err := f.NewSheet(sheet.name); err != nil {
+			return fmt.Errorf("create excel sheet %s: %w", sheet.name, err)
+		}
+
+		var errs []error
+		for i, h := range sheet.header {
+			errs = append(errs, f.SetCellStr(sheet.name, shrinkageCell(i, 1), h))
+		}
+
+		for i, row := range sheet.rows {
+			errs = append(errs, f.SetSheetRow(sheet.name, shrinkageCell(0, i+2), &row))
+		}
+
+		if err := errors.Join(errs...); err != nil {
+			return fmt.Errorf("fill %s sheet: %w", sheet.name, err)
+		}
+	}
+
+	if err := f.DeleteSheet("Sheet1"); err != nil {
+		return fmt.Errorf("delete default XLSX sheet: %w", err)
+	}
+
+	if err := f.Write(w); err != nil {
+		return fmt.Errorf("write XLSX: %w", err)
+	}
+
+	return nil
+}
+
+// shrinkageGroupHeader returns the header of a group breakdown:
+// the key columns, the sale price difference of each period, and the totals.
+func shrinkageGroupHeader(periods []ShrinkagePeriod, keyColumns ...string) []string {
+	header := slices.Clone(keyColumns)
+	for _, period := range periods {
+		header = append(header, period.Label())
+	}
+
+	return append(header, "Total Selisih HPP", "Total Selisih Harga Jual", "Total Penyusutan")
+}
+
+func shrinkageGroupRows(groups []ShrinkageGroup, keyCells func(ShrinkageGroup) []any) [][]any {
+	rows := make([][]any, 0, len(groups))
+	for _, group := range groups {
+		row := keyCells(group)
+		for _, value := range group.ByPeriod {
+			row = append(row, value.SalePriceDifference)
+		}
+
+		rows = append(rows, append(row, group.Total.HPPDifference, group.Total.SalePriceDifference, group.Total.Shrinkage))
+	}
+
+	return rows
+}
+
+func shrinkageCell(column int, row int) string {
+	cell, _ := excelize.CoordinatesToCellName(column+1, row)
+	return cell
+}
+
+// staffName returns the staff member for display, or a placeholder if unknown.
+func staffName(staff string) string {
+	if staff == "" {
+		return unknownStaff
+	}
+
+	return staff
+}
@@ -0,0 +1,153 @@
+package stockopname_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+	"github.com/turfaa/vmedis-proxy-api/stockopname"
+)
+
+// TestShrinkageReport checks that the stock opname differences are broken down by month, drug, manufacturer, and staff member,
+// and that the drugs counted short on enough days are reported as repeat offenders.
+func TestShrinkageReport(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	service := stockopname.NewService(db, nil, nil, nil, drugsGetter{paracetamol})
+
+	for i, d := range []models.Drug{
+		{VmedisCode: "PCT", Name: "Paracetamol", Manufacturer: "Kimia Farma"},
+		{VmedisCode: "AMX", Name: "Amoxicillin", Manufacturer: "Sanbe"},
+	} {
+		d.VmedisID = int64(i + 1)
+		if err := db.Create(&d).Error; err != nil {
+			t.Fatalf("create drug %s: %v", d.VmedisCode, err)
+		}
+	}
+
+	session, err := service.CreateCountSession(ctx, stockopname.CreateCountSessionRequest{Name: "Rak A", Date: "2026-01-03"}, "admin@example.com")
+	if err != nil {
+		t.Fatalf("CreateCountSession() error = %v", err)
+	}
+
+	if _, err := service.RecordCountSessionLine(ctx, session.ID, stockopname.CountSessionLineRequest{DrugCode: "PCT", BatchCode: "A1", Unit: "Strip", Quantity: "1"}, "a@example.com"); err != nil {
+		t.Fatalf("RecordCountSessionLine() error = %v", err)
+	}
+
+	if err := db.Create(&models.CycleCountTask{Date: datatypes.Date(date(t, "2026-01-10")), DrugCode: "PCT", Assignee: "b@example.com"}).Error; err != nil {
+		t.Fatalf("create cycle count task: %v", err)
+	}
+
+	for i, sale := range []struct {
+		date  string
+		total float64
+	}{
+		{"2026-01-20", 100_000},
+		{"2026-02-20", 50_000},
+	} {
+		if err := db.Create(&models.Sale{InvoiceNumber: fmt.Sprintf("INV-%d", i), SoldAt: date(t, sale.date).Add(10 * time.Hour), Total: sale.total}).Error; err != nil {
+			t.Fatalf("create sale: %v", err)
+		}
+	}
+
+	// The first stock opname is counted in the session, the later ones are attributed to the cycle count task.
+	createShrinkageStockOpname(t, db, "SO-1", "PCT", "A1", "2026-01-05", -1_000)
+	createShrinkageStockOpname(t, db, "SO-2", "PCT", "A1", "2026-01-12", -2_000)
+	createShrinkageStockOpname(t, db, "SO-3", "PCT", "A1", "2026-02-02", -3_000)
+	createShrinkageStockOpname(t, db, "SO-4", "PCT", "B2", "2026-02-02", 500)
+	createShrinkageStockOpname(t, db, "SO-5", "AMX", "C3", "2026-02-10", 100)
+	createShrinkageStockOpname(t, db, "SO-6", "AMX", "C3", "2026-03-01", -100)
+
+	report, err := service.GetShrinkageReport(ctx, date(t, "2026-01-01"), date(t, "2026-02-28").Add(24*time.Hour-time.Nanosecond), stockopname.ShrinkageRequest{})
+	if err != nil {
+		t.Fatalf("GetShrinkageReport() error = %v", err)
+	}
+
+	if len(report.Periods) != 2 || report.Periods[1].Start != "2026-02-01" || report.Periods[1].End != "2026-02-28" {
+		t.Fatalf("periods = %+v, want January and February", report.Periods)
+	}
+
+	if got := report.Periods[0]; got.Value.Shrinkage != 3_000 || got.Sales != 100_000 || math.Abs(got.ShrinkageRatio()-0.03) > 1e-9 {
+		t.Errorf("January = %+v, want 3000 shrinkage out of 100000 sales", got)
+	}
+
+	if got := report.Periods[1].Value; got.SalePriceDifference != -2_400 || got.Shrinkage != 3_000 {
+		t.Errorf("February = %+v, want -2400 difference and 3000 shrinkage", got)
+	}
+
+	if len(report.Manufacturers) != 2 || report.Manufacturers[0].Name != "Kimia Farma" || report.Manufacturers[0].ByPeriod[1].SalePriceDifference != -2_500 {
+		t.Errorf("manufacturers = %+v, want Kimia Farma first with -2500 in February", report.Manufacturers)
+	}
+
+	staff := make(map[string]float64)
+	for _, group := range report.Staff {
+		staff[group.Name] = group.Total.SalePriceDifference
+	}
+
+	wantStaff := map[string]float64{"a@example.com": -1_000, "b@example.com": -4_500, "": 100}
+	if fmt.Sprint(staff) != fmt.Sprint(wantStaff) {
+		t.Errorf("staff = %v, want %v", staff, wantStaff)
+	}
+
+	if len(report.RepeatOffenders) != 1 {
+		t.Fatalf("repeat offenders = %+v, want only PCT", report.RepeatOffenders)
+	}
+
+	if got := report.RepeatOffenders[0]; got.DrugCode != "PCT" || got.NegativeCounts != 3 || got.Counts != 3 || got.LastNegativeDate != "2026-02-02" {
+		t.Errorf("repeat offender = %+v, want PCT counted short on 3 of 3 days, last on 2026-02-02", got)
+	}
+
+	var buf bytes.Buffer
+	if err := stockopname.WriteShrinkageXLSX(&buf, report); err != nil {
+		t.Fatalf("WriteShrinkageXLSX() error = %v", err)
+	}
+
+	f, err := excelize.OpenReader(&buf)
+	if err != nil {
+		t.Fatalf("open XLSX: %v", err)
+	}
+
+	if sheets := f.GetSheetList(); len(sheets) != 5 {
+		t.Errorf("XLSX sheets = %v, want 5", sheets)
+	}
+
+	rows, err := f.GetRows("Per Obat")
+	if err != nil || len(rows) != 3 || len(rows[0]) != 7 {
+		t.Errorf("XLSX drug rows = %v, %v, want the header and 2 drugs with 2 periods and 3 totals", rows, err)
+	}
+}
+
+func createShrinkageStockOpname(t *testing.T, db *gorm.DB, vmedisID string, drugCode string, batchCode string, soDate string, salePriceDifference float64) {
+	t.Helper()
+
+	if err := db.Create(&models.StockOpname{
+		VmedisID:            vmedisID,
+		Date:                datatypes.Date(date(t, soDate)),
+		DrugCode:            drugCode,
+		BatchCode:           batchCode,
+		Unit:                "Strip",
+		HPPDifference:       salePriceDifference * 0.8,
+		SalePriceDifference: salePriceDifference,
+	}).Error; err != nil {
+		t.Fatalf("create stock opname %s: %v", vmedisID, err)
+	}
+}
+
+func date(t *testing.T, s string) time.Time {
+	t.Helper()
+
+	d, err := time.ParseInLocation(time.DateOnly, s, time.Local)
+	if err != nil {
+		t.Fatalf("parse date %s: %v", s, err)
+	}
+
+	return d
+}
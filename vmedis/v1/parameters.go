@@ -47,3 +47,9 @@ type ParameterTypeSales struct{}
 func (ParameterTypeSales) QueryLabel() string {
 	return "AptLapPenjualanobatBatchSearch"
 }
+
+type ParameterTypeStockOpnames struct{}
+
+func (ParameterTypeStockOpnames) QueryLabel() string {
+	return "LaporanStokopnameBatchSearch"
+}
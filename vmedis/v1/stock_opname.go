@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"time"
 
 	"github.com/PuerkitoBio/goquery"
 )
@@ -34,6 +35,48 @@ func (c *Client) GetAllTodayStockOpnames(ctx context.Context) ([]StockOpname, er
 	return stockOpnames, nil
 }
 
+// GetAllStockOpnamesBetweenDates gets all stock opnames between the given dates from vmedis.
+// It fetches every /laporan-stokopname-batch/index page concurrently
+// and returns an error if any page cannot be fetched or parsed.
+// The duplicated IDs are augmented in the order of the pages, the same way GetAllTodayStockOpnames does.
+func (c *Client) GetAllStockOpnamesBetweenDates(ctx context.Context, startDate time.Time, endDate time.Time) ([]StockOpname, error) {
+	stockOpnames, err := getAllPages(ctx, "stock opnames", c.concurrency, func(ctx context.Context, page int) ([]StockOpname, []int, error) {
+		res, err := c.GetStockOpnames(ctx, SearchByTimeParameters[ParameterTypeStockOpnames]{
+			StartTime: startDate,
+			EndTime:   endDate,
+			Page:      page,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return res.StockOpnames, res.OtherPages, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	augmentDuplicatedStockOpnameCodes(stockOpnames)
+	return stockOpnames, nil
+}
+
+// GetStockOpnames gets one page of stock opnames matching the given search parameters from vmedis.
+// It calls the /laporan-stokopname-batch/index page and tries to parse the stock opnames from it.
+func (c *Client) GetStockOpnames(ctx context.Context, params SearchByTimeParameters[ParameterTypeStockOpnames]) (StockOpnamesResponse, error) {
+	res, err := c.get(ctx, fmt.Sprintf("/laporan-stokopname-batch/index?%s", params.ToQuery(dateFormat)))
+	if err != nil {
+		return StockOpnamesResponse{}, fmt.Errorf("get stock opnames with params %+v: %w", params, err)
+	}
+	defer res.Body.Close()
+
+	sos, err := ParseStockOpnames(res.Body)
+	if err != nil {
+		return StockOpnamesResponse{}, fmt.Errorf("parse stock opnames with params %+v: %w", params, err)
+	}
+
+	return sos, nil
+}
+
 // GetTodayStockOpnames gets all stock opnames from today from vmedis.
 // It calls the /laporan-stokopname-batch/index?page=<page> page and try to parse the stock opnames from it.
 func (c *Client) GetTodayStockOpnames(ctx context.Context, page int) (StockOpnamesResponse, error) {
@@ -0,0 +1,37 @@
+package webhook
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/turfaa/vmedis-proxy-api/pkg2/queue"
+)
+
+// RunWebhooks turns the domain events into webhook events and sends them to the subscriptions
+// until the context is done or a termination signal is received.
+func RunWebhooks(ctx context.Context, service *Service, q queue.Queue, config DispatcherConfig) {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+
+	log.Println("Running webhooks")
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+		NewConsumer(service, q).StartConsuming(ctx)
+	}()
+
+	go func() {
+		defer wg.Done()
+		NewDispatcher(service, config).Run(ctx)
+	}()
+
+	wg.Wait()
+	log.Println("Webhooks shut down successfully")
+}
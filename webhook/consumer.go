@@ -0,0 +1,135 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/turfaa/vmedis-proxy-api/events"
+	"github.com/turfaa/vmedis-proxy-api/kafkapb"
+	"github.com/turfaa/vmedis-proxy-api/pkg2/queue"
+	"github.com/turfaa/vmedis-proxy-api/pkg2/retry"
+)
+
+// ConsumerGroup is the consumer group of the domain events turned into webhook events.
+const ConsumerGroup = "vmedis-proxy-webhooks"
+
+// DeadLetterTopic receives the domain events that still fail after the consumer retries.
+const DeadLetterTopic = "webhook.dead_letter"
+
+// consumerRetryConfig retries a failing event a few times before it's sent to DeadLetterTopic,
+// so a broken event doesn't block the ones after it.
+var consumerRetryConfig = retry.Config{
+	MaxRetries:     3,
+	InitialBackoff: time.Second,
+	MaxBackoff:     10 * time.Second,
+}
+
+// Consumer turns the domain events into webhook events, and queues them to the subscriptions.
+type Consumer struct {
+	service  *Service
+	consumer *queue.Consumer
+}
+
+// StartConsuming consumes the domain event topics until the context is done.
+func (c *Consumer) StartConsuming(ctx context.Context) {
+	handlers := map[string]func(ctx context.Context, value []byte) error{
+		events.DrugStockChangedTopic:    c.handleDrugStockChanged,
+		events.DumpCompletedTopic:       c.handleDumpCompleted,
+		events.ShiftClosedTopic:         c.handleShiftClosed,
+		events.RejectedDrugCreatedTopic: c.handleRejectedDrugCreated,
+	}
+
+	var wg sync.WaitGroup
+	for topic, handler := range handlers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.consume(ctx, topic, handler)
+		}()
+	}
+
+	wg.Wait()
+}
+
+func (c *Consumer) consume(ctx context.Context, topic string, handler func(ctx context.Context, value []byte) error) {
+	if err := c.consumer.Consume(ctx, topic, func(ctx context.Context, m queue.Message) error {
+		return handler(ctx, m.Value)
+	}); err != nil {
+		log.Printf("Error consuming %s: %s", topic, err)
+	}
+}
+
+func (c *Consumer) handleDrugStockChanged(ctx context.Context, value []byte) error {
+	if c.service.drugsGetter == nil {
+		return nil
+	}
+
+	var stockChanged kafkapb.DrugStockChanged
+	if err := protojson.Unmarshal(value, &stockChanged); err != nil {
+		return retry.Permanent(fmt.Errorf("unmarshal drug stock changed event: %w", err))
+	}
+
+	drugs, err := c.service.drugsGetter.GetDrugsByVmedisCodes(ctx, []string{stockChanged.GetVmedisCode()})
+	if err != nil {
+		return fmt.Errorf("get drug %s: %w", stockChanged.GetVmedisCode(), err)
+	}
+
+	if len(drugs) == 0 {
+		return nil
+	}
+
+	event, ok := lowStockEvent(&stockChanged, drugs[0])
+	if !ok {
+		return nil
+	}
+
+	return c.service.Notify(ctx, event)
+}
+
+func (c *Consumer) handleDumpCompleted(ctx context.Context, value []byte) error {
+	var dumpCompleted kafkapb.DumpCompleted
+	if err := protojson.Unmarshal(value, &dumpCompleted); err != nil {
+		return retry.Permanent(fmt.Errorf("unmarshal dump completed event: %w", err))
+	}
+
+	return c.service.Notify(ctx, dumpCompletedEvent(&dumpCompleted))
+}
+
+func (c *Consumer) handleShiftClosed(ctx context.Context, value []byte) error {
+	var shiftClosed kafkapb.ShiftClosed
+	if err := protojson.Unmarshal(value, &shiftClosed); err != nil {
+		return retry.Permanent(fmt.Errorf("unmarshal shift closed event: %w", err))
+	}
+
+	event, ok := shiftDiscrepancyEvent(&shiftClosed)
+	if !ok {
+		return nil
+	}
+
+	return c.service.Notify(ctx, event)
+}
+
+func (c *Consumer) handleRejectedDrugCreated(ctx context.Context, value []byte) error {
+	var rejectedDrugCreated kafkapb.RejectedDrugCreated
+	if err := protojson.Unmarshal(value, &rejectedDrugCreated); err != nil {
+		return retry.Permanent(fmt.Errorf("unmarshal rejected drug created event: %w", err))
+	}
+
+	return c.service.Notify(ctx, rejectedDrugCreatedEvent(&rejectedDrugCreated))
+}
+
+func NewConsumer(service *Service, q queue.Queue) *Consumer {
+	return &Consumer{
+		service: service,
+		consumer: queue.NewConsumer(q, queue.ConsumerConfig{
+			Group:           ConsumerGroup,
+			DeadLetterTopic: DeadLetterTopic,
+			RetryConfig:     consumerRetryConfig,
+		}),
+	}
+}
@@ -0,0 +1,184 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+)
+
+type Database struct {
+	db *gorm.DB
+}
+
+func (d *Database) GetSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error) {
+	var subscriptions []models.WebhookSubscription
+	if err := d.dbCtx(ctx).Order("id").Find(&subscriptions).Error; err != nil {
+		return nil, fmt.Errorf("get webhook subscriptions from db: %w", err)
+	}
+
+	return subscriptions, nil
+}
+
+func (d *Database) GetActiveSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error) {
+	var subscriptions []models.WebhookSubscription
+	if err := d.dbCtx(ctx).Where("active = ?", true).Order("id").Find(&subscriptions).Error; err != nil {
+		return nil, fmt.Errorf("get active webhook subscriptions from db: %w", err)
+	}
+
+	return subscriptions, nil
+}
+
+func (d *Database) GetSubscriptionByID(ctx context.Context, id uint) (models.WebhookSubscription, error) {
+	var subscription models.WebhookSubscription
+	if err := d.dbCtx(ctx).First(&subscription, id).Error; err != nil {
+		return models.WebhookSubscription{}, fmt.Errorf("get webhook subscription %d from db: %w", id, err)
+	}
+
+	return subscription, nil
+}
+
+func (d *Database) CreateSubscription(ctx context.Context, subscription models.WebhookSubscription) (models.WebhookSubscription, error) {
+	if err := d.dbCtx(ctx).Create(&subscription).Error; err != nil {
+		return models.WebhookSubscription{}, fmt.Errorf("create webhook subscription: %w", err)
+	}
+
+	return subscription, nil
+}
+
+func (d *Database) SaveSubscription(ctx context.Context, subscription models.WebhookSubscription) (models.WebhookSubscription, error) {
+	if err := d.dbCtx(ctx).Save(&subscription).Error; err != nil {
+		return models.WebhookSubscription{}, fmt.Errorf("save webhook subscription %d: %w", subscription.ID, err)
+	}
+
+	return subscription, nil
+}
+
+// DeleteSubscription deletes the subscription and its delivery log.
+func (d *Database) DeleteSubscription(ctx context.Context, id uint) error {
+	return d.dbCtx(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("subscription_id = ?", id).Delete(&models.WebhookDelivery{}).Error; err != nil {
+			return fmt.Errorf("delete deliveries of webhook subscription %d: %w", id, err)
+		}
+
+		if err := tx.Delete(&models.WebhookSubscription{}, id).Error; err != nil {
+			return fmt.Errorf("delete webhook subscription %d: %w", id, err)
+		}
+
+		return nil
+	})
+}
+
+// CreateDeliveries creates the pending deliveries, skipping the ones of an event already delivered to the subscription,
+// so an event consumed more than once is only delivered once.
+func (d *Database) CreateDeliveries(ctx context.Context, deliveries []models.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	if err := d.dbCtx(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "subscription_id"}, {Name: "event_id"}},
+			DoNothing: true,
+		}).
+		Create(&deliveries).
+		Error; err != nil {
+		return fmt.Errorf("create %d webhook deliveries: %w", len(deliveries), err)
+	}
+
+	return nil
+}
+
+// GetDeliveriesBySubscriptionID returns the latest deliveries of the subscription, newest first.
+func (d *Database) GetDeliveriesBySubscriptionID(ctx context.Context, subscriptionID uint, limit int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	if err := d.dbCtx(ctx).
+		Where("subscription_id = ?", subscriptionID).
+		Order("id DESC").
+		Limit(limit).
+		Find(&deliveries).
+		Error; err != nil {
+		return nil, fmt.Errorf("get deliveries of webhook subscription %d from db: %w", subscriptionID, err)
+	}
+
+	return deliveries, nil
+}
+
+// ClaimDueDeliveries reserves the oldest due pending deliveries for this dispatcher by pushing back their next attempt,
+// so other dispatchers skip them while they're being sent.
+func (d *Database) ClaimDueDeliveries(ctx context.Context, limit int, claimTimeout time.Duration) ([]models.WebhookDelivery, error) {
+	now := time.Now()
+
+	var ids []uint
+	if err := d.dbCtx(ctx).
+		Model(&models.WebhookDelivery{}).
+		Where("status = ? AND next_attempt_at <= ?", models.WebhookDeliveryStatusPending, now).
+		Order("id").
+		Limit(limit).
+		Pluck("id", &ids).
+		Error; err != nil {
+		return nil, fmt.Errorf("get due webhook deliveries: %w", err)
+	}
+
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	claimID := uuid.NewString()
+	if err := d.dbCtx(ctx).
+		Model(&models.WebhookDelivery{}).
+		Where("id IN ? AND status = ? AND next_attempt_at <= ?", ids, models.WebhookDeliveryStatusPending, now).
+		Updates(map[string]any{
+			"claimed_by":      claimID,
+			"next_attempt_at": now.Add(claimTimeout),
+		}).
+		Error; err != nil {
+		return nil, fmt.Errorf("claim due webhook deliveries: %w", err)
+	}
+
+	var deliveries []models.WebhookDelivery
+	if err := d.dbCtx(ctx).
+		Where("id IN ? AND claimed_by = ?", ids, claimID).
+		Order("id").
+		Find(&deliveries).
+		Error; err != nil {
+		return nil, fmt.Errorf("get claimed webhook deliveries: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+// SaveDeliveryAttempt saves the result of an attempt of the delivery.
+func (d *Database) SaveDeliveryAttempt(ctx context.Context, delivery models.WebhookDelivery) error {
+	if err := d.dbCtx(ctx).
+		Model(&models.WebhookDelivery{}).
+		Where("id = ?", delivery.ID).
+		Updates(map[string]any{
+			"status":           delivery.Status,
+			"attempts":         delivery.Attempts,
+			"next_attempt_at":  delivery.NextAttemptAt,
+			"last_status_code": delivery.LastStatusCode,
+			"last_error":       delivery.LastError,
+			"delivered_at":     delivery.DeliveredAt,
+		}).
+		Error; err != nil {
+		return fmt.Errorf("save attempt of webhook delivery %d: %w", delivery.ID, err)
+	}
+
+	return nil
+}
+
+func (d *Database) dbCtx(ctx context.Context) *gorm.DB {
+	return d.db.WithContext(ctx)
+}
+
+func NewDatabase(db *gorm.DB) *Database {
+	return &Database{
+		db: db,
+	}
+}
@@ -0,0 +1,194 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+)
+
+// DispatcherConfig controls how the dispatcher sends the webhook deliveries.
+type DispatcherConfig struct {
+	// BatchSize is the maximum number of deliveries claimed at once.
+	BatchSize int
+
+	// PollInterval is the delay between polls after no delivery is due.
+	PollInterval time.Duration
+
+	// Timeout is the timeout of a request to a subscription URL.
+	Timeout time.Duration
+
+	// MaxAttempts is the number of attempts after which a delivery is marked failed.
+	MaxAttempts int
+
+	// InitialBackoff is the delay before retrying a failed attempt.
+	// The delay doubles on every subsequent failure, up to MaxBackoff.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// DefaultDispatcherConfig retries a delivery for about a day before giving up.
+var DefaultDispatcherConfig = DispatcherConfig{
+	BatchSize:      50,
+	PollInterval:   time.Second,
+	Timeout:        10 * time.Second,
+	MaxAttempts:    12,
+	InitialBackoff: 30 * time.Second,
+	MaxBackoff:     6 * time.Hour,
+}
+
+// Dispatcher sends the pending webhook deliveries to their subscriptions.
+//
+// Several dispatchers may run at the same time: every delivery is claimed by one dispatcher before it's sent.
+type Dispatcher struct {
+	db         *Database
+	httpClient *http.Client
+	config     DispatcherConfig
+}
+
+// Run sends the due deliveries until the context is done.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := d.DispatchDue(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("Error dispatching webhook deliveries: %s", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DispatchDue sends the deliveries that are due, batch by batch, until none is left.
+// It returns the number of attempts made.
+func (d *Dispatcher) DispatchDue(ctx context.Context) (int, error) {
+	attempts := 0
+	for {
+		deliveries, err := d.db.ClaimDueDeliveries(ctx, d.config.BatchSize, d.config.Timeout*2)
+		if err != nil {
+			return attempts, fmt.Errorf("claim due webhook deliveries: %w", err)
+		}
+
+		if len(deliveries) == 0 {
+			return attempts, nil
+		}
+
+		// The subscriptions are re-read per batch, so a changed URL or secret takes effect on the next attempt.
+		subscriptions, err := d.db.GetSubscriptions(ctx)
+		if err != nil {
+			return attempts, fmt.Errorf("get webhook subscriptions: %w", err)
+		}
+
+		subscriptionsByID := make(map[uint]models.WebhookSubscription, len(subscriptions))
+		for _, subscription := range subscriptions {
+			subscriptionsByID[subscription.ID] = subscription
+		}
+
+		var errs []error
+		for _, delivery := range deliveries {
+			subscription, ok := subscriptionsByID[delivery.SubscriptionID]
+			if !ok {
+				continue
+			}
+
+			delivery = d.attempt(ctx, subscription, delivery)
+			attempts++
+
+			if err := d.db.SaveDeliveryAttempt(context.WithoutCancel(ctx), delivery); err != nil {
+				errs = append(errs, err)
+			}
+		}
+
+		if len(errs) > 0 {
+			return attempts, errors.Join(errs...)
+		}
+	}
+}
+
+// attempt sends the delivery to the subscription, and returns the delivery updated with the result of the attempt.
+func (d *Dispatcher) attempt(ctx context.Context, subscription models.WebhookSubscription, delivery models.WebhookDelivery) models.WebhookDelivery {
+	delivery.Attempts++
+
+	statusCode, err := d.send(ctx, subscription, delivery)
+	delivery.LastStatusCode = statusCode
+
+	if err == nil {
+		now := time.Now()
+		delivery.Status = models.WebhookDeliveryStatusSucceeded
+		delivery.LastError = ""
+		delivery.DeliveredAt = &now
+		return delivery
+	}
+
+	delivery.LastError = err.Error()
+
+	if delivery.Attempts >= d.config.MaxAttempts {
+		log.Printf("Webhook delivery %d to %s failed after %d attempts: %s", delivery.ID, subscription.URL, delivery.Attempts, err)
+		delivery.Status = models.WebhookDeliveryStatusFailed
+		return delivery
+	}
+
+	delivery.NextAttemptAt = time.Now().Add(d.backoff(delivery.Attempts))
+	return delivery
+}
+
+func (d *Dispatcher) send(ctx context.Context, subscription models.WebhookSubscription, delivery models.WebhookDelivery) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, d.config.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("create request: %w", err)
+	}
+
+	now := time.Now()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEventID, delivery.EventID)
+	req.Header.Set(HeaderEventType, delivery.EventType)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set(HeaderSignature, Sign(subscription.Secret, now, delivery.Payload))
+
+	res, err := d.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("send request: %w", err)
+	}
+	defer res.Body.Close()
+
+	// The body is drained so the connection can be reused.
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 1<<16))
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return res.StatusCode, fmt.Errorf("got status code %d", res.StatusCode)
+	}
+
+	return res.StatusCode, nil
+}
+
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	backoff := d.config.InitialBackoff
+	for i := 1; i < attempts && backoff < d.config.MaxBackoff; i++ {
+		backoff *= 2
+	}
+
+	return min(backoff, d.config.MaxBackoff)
+}
+
+func NewDispatcher(service *Service, config DispatcherConfig) *Dispatcher {
+	return &Dispatcher{
+		db:         service.db,
+		httpClient: &http.Client{},
+		config:     config,
+	}
+}
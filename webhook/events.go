@@ -0,0 +1,98 @@
+package webhook
+
+import (
+	"github.com/turfaa/vmedis-proxy-api/drug"
+	"github.com/turfaa/vmedis-proxy-api/kafkapb"
+)
+
+// lowStockEvent returns the low stock event of the drug if its stock in the unit of its minimum stock
+// dropped to or below the minimum stock, and false otherwise.
+// Drugs without a minimum stock are never low.
+func lowStockEvent(stockChanged *kafkapb.DrugStockChanged, d drug.Drug) (Event, bool) {
+	minimum := d.MinimumStock
+	if minimum.Quantity <= 0 {
+		return Event{}, false
+	}
+
+	before := stockQuantity(stockChanged.GetBefore(), minimum.Unit)
+	after := stockQuantity(stockChanged.GetAfter(), minimum.Unit)
+	if before <= minimum.Quantity || after > minimum.Quantity {
+		return Event{}, false
+	}
+
+	return Event{
+		ID:         stockChanged.GetMetadata().GetEventId(),
+		Type:       EventTypeLowStock,
+		OccurredAt: stockChanged.GetMetadata().GetOccurredAt().AsTime(),
+		Data: LowStockData{
+			DrugCode:     d.VmedisCode,
+			DrugName:     d.Name,
+			Unit:         minimum.Unit,
+			Quantity:     after,
+			MinimumStock: minimum.Quantity,
+		},
+	}, true
+}
+
+func stockQuantity(stocks []*kafkapb.DrugStock, unit string) float64 {
+	for _, stock := range stocks {
+		if stock.GetUnit() == unit {
+			return stock.GetQuantity()
+		}
+	}
+
+	return 0
+}
+
+func dumpCompletedEvent(dumpCompleted *kafkapb.DumpCompleted) Event {
+	return Event{
+		ID:         dumpCompleted.GetMetadata().GetEventId(),
+		Type:       EventTypeDumpCompleted,
+		OccurredAt: dumpCompleted.GetMetadata().GetOccurredAt().AsTime(),
+		Data: DumpCompletedData{
+			Dump:       dumpCompleted.GetDump(),
+			StartedAt:  dumpCompleted.GetStartedAt().AsTime(),
+			FinishedAt: dumpCompleted.GetMetadata().GetOccurredAt().AsTime(),
+			Records:    dumpCompleted.GetRecords(),
+		},
+	}
+}
+
+// shiftDiscrepancyEvent returns the discrepancy event of the closed shift if its actual final cash
+// differs from the expected one, and false otherwise.
+func shiftDiscrepancyEvent(shiftClosed *kafkapb.ShiftClosed) (Event, bool) {
+	shift := shiftClosed.GetShift()
+	if shift.GetFinalCashDifference() == 0 {
+		return Event{}, false
+	}
+
+	return Event{
+		ID:         shiftClosed.GetMetadata().GetEventId(),
+		Type:       EventTypeShiftDiscrepancy,
+		OccurredAt: shiftClosed.GetMetadata().GetOccurredAt().AsTime(),
+		Data: ShiftDiscrepancyData{
+			Code:                shift.GetCode(),
+			Cashier:             shift.GetCashier(),
+			StartedAt:           shift.GetStartedAt().AsTime(),
+			EndedAt:             shift.GetEndedAt().AsTime(),
+			ExpectedFinalCash:   shift.GetExpectedFinalCash(),
+			ActualFinalCash:     shift.GetActualFinalCash(),
+			FinalCashDifference: shift.GetFinalCashDifference(),
+			Supervisor:          shift.GetSupervisor(),
+			Notes:               shift.GetNotes(),
+		},
+	}, true
+}
+
+func rejectedDrugCreatedEvent(rejectedDrugCreated *kafkapb.RejectedDrugCreated) Event {
+	return Event{
+		ID:         rejectedDrugCreated.GetMetadata().GetEventId(),
+		Type:       EventTypeRejectedDrugCreated,
+		OccurredAt: rejectedDrugCreated.GetMetadata().GetOccurredAt().AsTime(),
+		Data: RejectedDrugCreatedData{
+			ID:        rejectedDrugCreated.GetId(),
+			DrugName:  rejectedDrugCreated.GetDrugName(),
+			CreatedBy: rejectedDrugCreated.GetCreatedBy(),
+		},
+	}
+}
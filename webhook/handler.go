@@ -0,0 +1,161 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/turfaa/vmedis-proxy-api/auth"
+)
+
+type ApiHandler struct {
+	service *Service
+}
+
+func NewApiHandler(service *Service) *ApiHandler {
+	return &ApiHandler{service: service}
+}
+
+func (h *ApiHandler) GetSubscriptions(c *gin.Context) {
+	subscriptions, err := h.service.GetSubscriptions(c.Request.Context())
+	if err != nil {
+		c.JSON(500, gin.H{"error": fmt.Sprintf("failed to get webhook subscriptions: %s", err)})
+		return
+	}
+
+	c.JSON(200, SubscriptionsResponse{Subscriptions: subscriptions})
+}
+
+func (h *ApiHandler) GetSubscription(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+
+	subscription, err := h.service.GetSubscriptionByID(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, id, "get", err)
+		return
+	}
+
+	c.JSON(200, SubscriptionResponse{Subscription: subscription})
+}
+
+// CreateSubscription creates a webhook subscription.
+// The response is the only one containing the secret the events are signed with.
+func (h *ApiHandler) CreateSubscription(c *gin.Context) {
+	var request CreateSubscriptionRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(400, gin.H{"error": fmt.Sprintf("invalid request: %s", err)})
+		return
+	}
+
+	subscription, secret, err := h.service.CreateSubscription(c.Request.Context(), request, auth.FromGinContext(c).Email)
+	if err != nil {
+		if errors.Is(err, ErrInvalidEventType) {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(500, gin.H{"error": fmt.Sprintf("failed to create webhook subscription: %s", err)})
+		return
+	}
+
+	c.JSON(201, CreateSubscriptionResponse{Subscription: subscription, Secret: secret})
+}
+
+func (h *ApiHandler) UpdateSubscription(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+
+	var request UpdateSubscriptionRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(400, gin.H{"error": fmt.Sprintf("invalid request: %s", err)})
+		return
+	}
+
+	subscription, err := h.service.UpdateSubscription(c.Request.Context(), id, request)
+	if err != nil {
+		respondError(c, id, "update", err)
+		return
+	}
+
+	c.JSON(200, SubscriptionResponse{Subscription: subscription})
+}
+
+func (h *ApiHandler) DeleteSubscription(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteSubscription(c.Request.Context(), id); err != nil {
+		respondError(c, id, "delete", err)
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "Webhook subscription deleted successfully"})
+}
+
+// GetDeliveries returns the delivery log of the subscription: its latest deliveries, newest first.
+func (h *ApiHandler) GetDeliveries(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+
+	deliveries, err := h.service.GetDeliveries(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, id, "get deliveries of", err)
+		return
+	}
+
+	c.JSON(200, DeliveriesResponse{Deliveries: deliveries})
+}
+
+// Ping queues a ping event to the subscription to test it.
+// The result shows up in the delivery log.
+func (h *ApiHandler) Ping(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+
+	delivery, err := h.service.Ping(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, id, "ping", err)
+		return
+	}
+
+	c.JSON(202, delivery)
+}
+
+func (h *ApiHandler) GetEventTypes(c *gin.Context) {
+	c.JSON(200, EventTypesResponse{EventTypes: AllEventTypes()})
+}
+
+func parseID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(400, gin.H{"error": fmt.Sprintf("invalid id: %s", err)})
+		return 0, false
+	}
+
+	return uint(id), true
+}
+
+func respondError(c *gin.Context, id uint, action string, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(404, gin.H{"error": fmt.Sprintf("webhook subscription %d not found", id)})
+	case errors.Is(err, ErrInvalidEventType):
+		c.JSON(400, gin.H{"error": err.Error()})
+	default:
+		c.JSON(500, gin.H{"error": fmt.Sprintf("failed to %s webhook subscription %d: %s", action, id, err)})
+	}
+}
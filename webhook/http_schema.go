@@ -0,0 +1,41 @@
+package webhook
+
+// CreateSubscriptionRequest creates a webhook subscription.
+// A random secret is generated when the secret is empty.
+type CreateSubscriptionRequest struct {
+	URL        string      `json:"url" binding:"required,url"`
+	Secret     string      `json:"secret"`
+	EventTypes []EventType `json:"eventTypes" binding:"required,min=1"`
+}
+
+// UpdateSubscriptionRequest updates a webhook subscription.
+// Nil fields are left unchanged.
+type UpdateSubscriptionRequest struct {
+	URL        *string     `json:"url" binding:"omitempty,url"`
+	Secret     *string     `json:"secret"`
+	EventTypes []EventType `json:"eventTypes" binding:"omitempty,min=1"`
+	Active     *bool       `json:"active"`
+}
+
+type SubscriptionResponse struct {
+	Subscription Subscription `json:"subscription"`
+}
+
+// CreateSubscriptionResponse is the only response that contains the secret,
+// so the caller can verify the signatures of the events.
+type CreateSubscriptionResponse struct {
+	Subscription Subscription `json:"subscription"`
+	Secret       string       `json:"secret"`
+}
+
+type SubscriptionsResponse struct {
+	Subscriptions []Subscription `json:"subscriptions"`
+}
+
+type DeliveriesResponse struct {
+	Deliveries []Delivery `json:"deliveries"`
+}
+
+type EventTypesResponse struct {
+	EventTypes []EventType `json:"eventTypes"`
+}
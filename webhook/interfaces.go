@@ -0,0 +1,11 @@
+package webhook
+
+import (
+	"context"
+
+	"github.com/turfaa/vmedis-proxy-api/drug"
+)
+
+type DrugsGetter interface {
+	GetDrugsByVmedisCodes(ctx context.Context, vmedisCodes []string) ([]drug.Drug, error)
+}
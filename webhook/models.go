@@ -0,0 +1,153 @@
+package webhook
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+)
+
+// EventType is the type of event a webhook subscription receives.
+type EventType string
+
+const (
+	// EventTypeLowStock is sent when the stock of a drug drops to or below its minimum stock.
+	EventTypeLowStock EventType = "drug.low_stock"
+
+	// EventTypeDumpCompleted is sent when a dump from Vmedis finishes successfully.
+	EventTypeDumpCompleted EventType = "dump.completed"
+
+	// EventTypeShiftDiscrepancy is sent when a shift is closed with an actual final cash different from the expected one.
+	EventTypeShiftDiscrepancy EventType = "shift.closed_with_discrepancy"
+
+	// EventTypeRejectedDrugCreated is sent when a drug requested by a customer but not available is recorded.
+	EventTypeRejectedDrugCreated EventType = "rejected_drug.created"
+
+	// EventTypePing is sent to test a subscription. Every subscription receives it.
+	EventTypePing EventType = "ping"
+)
+
+func AllEventTypes() []EventType {
+	return []EventType{
+		EventTypeLowStock,
+		EventTypeDumpCompleted,
+		EventTypeShiftDiscrepancy,
+		EventTypeRejectedDrugCreated,
+	}
+}
+
+func (t EventType) Valid() bool {
+	for _, eventType := range AllEventTypes() {
+		if t == eventType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Event is the JSON body sent to the webhook subscriptions.
+type Event struct {
+	// ID is unique per event, and stays the same when the delivery is retried.
+	ID         string    `json:"id"`
+	Type       EventType `json:"type"`
+	OccurredAt time.Time `json:"occurredAt"`
+	Data       any       `json:"data"`
+}
+
+type LowStockData struct {
+	DrugCode     string  `json:"drugCode"`
+	DrugName     string  `json:"drugName"`
+	Unit         string  `json:"unit"`
+	Quantity     float64 `json:"quantity"`
+	MinimumStock float64 `json:"minimumStock"`
+}
+
+type DumpCompletedData struct {
+	Dump       string    `json:"dump"`
+	StartedAt  time.Time `json:"startedAt"`
+	FinishedAt time.Time `json:"finishedAt"`
+	Records    int64     `json:"records"`
+}
+
+type ShiftDiscrepancyData struct {
+	Code                string    `json:"code"`
+	Cashier             string    `json:"cashier"`
+	StartedAt           time.Time `json:"startedAt"`
+	EndedAt             time.Time `json:"endedAt"`
+	ExpectedFinalCash   float64   `json:"expectedFinalCash"`
+	ActualFinalCash     float64   `json:"actualFinalCash"`
+	FinalCashDifference float64   `json:"finalCashDifference"`
+	Supervisor          string    `json:"supervisor"`
+	Notes               string    `json:"notes"`
+}
+
+type RejectedDrugCreatedData struct {
+	ID        uint64 `json:"id"`
+	DrugName  string `json:"drugName"`
+	CreatedBy string `json:"createdBy"`
+}
+
+type Subscription struct {
+	ID         uint        `json:"id"`
+	CreatedAt  time.Time   `json:"createdAt"`
+	UpdatedAt  time.Time   `json:"updatedAt"`
+	URL        string      `json:"url"`
+	EventTypes []EventType `json:"eventTypes"`
+	Active     bool        `json:"active"`
+	CreatedBy  string      `json:"createdBy"`
+}
+
+func FromDBSubscription(subscription models.WebhookSubscription) Subscription {
+	eventTypes := make([]EventType, 0, len(subscription.EventTypes))
+	for _, eventType := range subscription.EventTypes {
+		eventTypes = append(eventTypes, EventType(eventType))
+	}
+
+	return Subscription{
+		ID:         subscription.ID,
+		CreatedAt:  subscription.CreatedAt,
+		UpdatedAt:  subscription.UpdatedAt,
+		URL:        subscription.URL,
+		EventTypes: eventTypes,
+		Active:     subscription.Active,
+		CreatedBy:  subscription.CreatedBy,
+	}
+}
+
+type Delivery struct {
+	ID             uint                         `json:"id"`
+	CreatedAt      time.Time                    `json:"createdAt"`
+	SubscriptionID uint                         `json:"subscriptionId"`
+	EventID        string                       `json:"eventId"`
+	EventType      EventType                    `json:"eventType"`
+	Payload        json.RawMessage              `json:"payload"`
+	Status         models.WebhookDeliveryStatus `json:"status"`
+	Attempts       int                          `json:"attempts"`
+	NextAttemptAt  *time.Time                   `json:"nextAttemptAt,omitempty"`
+	LastStatusCode int                          `json:"lastStatusCode,omitempty"`
+	LastError      string                       `json:"lastError,omitempty"`
+	DeliveredAt    *time.Time                   `json:"deliveredAt,omitempty"`
+}
+
+func FromDBDelivery(delivery models.WebhookDelivery) Delivery {
+	var nextAttemptAt *time.Time
+	if delivery.Status == models.WebhookDeliveryStatusPending {
+		nextAttemptAt = &delivery.NextAttemptAt
+	}
+
+	return Delivery{
+		ID:             delivery.ID,
+		CreatedAt:      delivery.CreatedAt,
+		SubscriptionID: delivery.SubscriptionID,
+		EventID:        delivery.EventID,
+		EventType:      EventType(delivery.EventType),
+		Payload:        delivery.Payload,
+		Status:         delivery.Status,
+		Attempts:       delivery.Attempts,
+		NextAttemptAt:  nextAttemptAt,
+		LastStatusCode: delivery.LastStatusCode,
+		LastError:      delivery.LastError,
+		DeliveredAt:    delivery.DeliveredAt,
+	}
+}
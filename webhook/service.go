@@ -0,0 +1,233 @@
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+	"github.com/turfaa/vmedis-proxy-api/pkg2/slices2"
+)
+
+const deliveriesLimit = 100
+
+// ErrInvalidEventType is returned when a subscription subscribes to an unknown event type.
+var ErrInvalidEventType = errors.New("invalid event type")
+
+type Service struct {
+	db          *Database
+	drugsGetter DrugsGetter
+}
+
+func (s *Service) GetSubscriptions(ctx context.Context) ([]Subscription, error) {
+	subscriptions, err := s.db.GetSubscriptions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get webhook subscriptions: %w", err)
+	}
+
+	return slices2.Map(subscriptions, FromDBSubscription), nil
+}
+
+func (s *Service) GetSubscriptionByID(ctx context.Context, id uint) (Subscription, error) {
+	subscription, err := s.db.GetSubscriptionByID(ctx, id)
+	if err != nil {
+		return Subscription{}, fmt.Errorf("get webhook subscription %d: %w", id, err)
+	}
+
+	return FromDBSubscription(subscription), nil
+}
+
+// CreateSubscription creates an active subscription, and returns it with its secret.
+func (s *Service) CreateSubscription(ctx context.Context, request CreateSubscriptionRequest, createdBy string) (Subscription, string, error) {
+	eventTypes, err := eventTypeStrings(request.EventTypes)
+	if err != nil {
+		return Subscription{}, "", err
+	}
+
+	secret := request.Secret
+	if secret == "" {
+		secret, err = generateSecret()
+		if err != nil {
+			return Subscription{}, "", fmt.Errorf("generate secret: %w", err)
+		}
+	}
+
+	subscription, err := s.db.CreateSubscription(ctx, models.WebhookSubscription{
+		URL:        request.URL,
+		Secret:     secret,
+		EventTypes: eventTypes,
+		Active:     true,
+		CreatedBy:  createdBy,
+	})
+	if err != nil {
+		return Subscription{}, "", fmt.Errorf("create webhook subscription: %w", err)
+	}
+
+	return FromDBSubscription(subscription), secret, nil
+}
+
+func (s *Service) UpdateSubscription(ctx context.Context, id uint, request UpdateSubscriptionRequest) (Subscription, error) {
+	subscription, err := s.db.GetSubscriptionByID(ctx, id)
+	if err != nil {
+		return Subscription{}, fmt.Errorf("get webhook subscription %d: %w", id, err)
+	}
+
+	if request.URL != nil {
+		subscription.URL = *request.URL
+	}
+
+	if request.Secret != nil && *request.Secret != "" {
+		subscription.Secret = *request.Secret
+	}
+
+	if request.EventTypes != nil {
+		subscription.EventTypes, err = eventTypeStrings(request.EventTypes)
+		if err != nil {
+			return Subscription{}, err
+		}
+	}
+
+	if request.Active != nil {
+		subscription.Active = *request.Active
+	}
+
+	subscription, err = s.db.SaveSubscription(ctx, subscription)
+	if err != nil {
+		return Subscription{}, fmt.Errorf("save webhook subscription %d: %w", id, err)
+	}
+
+	return FromDBSubscription(subscription), nil
+}
+
+func (s *Service) DeleteSubscription(ctx context.Context, id uint) error {
+	if err := s.db.DeleteSubscription(ctx, id); err != nil {
+		return fmt.Errorf("delete webhook subscription %d: %w", id, err)
+	}
+
+	return nil
+}
+
+// GetDeliveries returns the latest deliveries of the subscription, newest first.
+func (s *Service) GetDeliveries(ctx context.Context, subscriptionID uint) ([]Delivery, error) {
+	if _, err := s.db.GetSubscriptionByID(ctx, subscriptionID); err != nil {
+		return nil, fmt.Errorf("get webhook subscription %d: %w", subscriptionID, err)
+	}
+
+	deliveries, err := s.db.GetDeliveriesBySubscriptionID(ctx, subscriptionID, deliveriesLimit)
+	if err != nil {
+		return nil, fmt.Errorf("get deliveries of webhook subscription %d: %w", subscriptionID, err)
+	}
+
+	return slices2.Map(deliveries, FromDBDelivery), nil
+}
+
+// Ping queues a ping event to the subscription, even if it's inactive, to test it.
+func (s *Service) Ping(ctx context.Context, subscriptionID uint) (Delivery, error) {
+	subscription, err := s.db.GetSubscriptionByID(ctx, subscriptionID)
+	if err != nil {
+		return Delivery{}, fmt.Errorf("get webhook subscription %d: %w", subscriptionID, err)
+	}
+
+	delivery, err := newDelivery(subscription.ID, Event{
+		ID:         uuid.NewString(),
+		Type:       EventTypePing,
+		OccurredAt: time.Now(),
+		Data:       struct{}{},
+	})
+	if err != nil {
+		return Delivery{}, err
+	}
+
+	deliveries := []models.WebhookDelivery{delivery}
+	if err := s.db.CreateDeliveries(ctx, deliveries); err != nil {
+		return Delivery{}, fmt.Errorf("create ping delivery: %w", err)
+	}
+
+	return FromDBDelivery(deliveries[0]), nil
+}
+
+// Notify queues the event to every active subscription subscribed to its type.
+// An event notified more than once is only delivered once to each subscription.
+func (s *Service) Notify(ctx context.Context, event Event) error {
+	subscriptions, err := s.db.GetActiveSubscriptions(ctx)
+	if err != nil {
+		return fmt.Errorf("get active webhook subscriptions: %w", err)
+	}
+
+	var deliveries []models.WebhookDelivery
+	for _, subscription := range subscriptions {
+		if !slices.Contains(subscription.EventTypes, string(event.Type)) {
+			continue
+		}
+
+		delivery, err := newDelivery(subscription.ID, event)
+		if err != nil {
+			return err
+		}
+
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err := s.db.CreateDeliveries(ctx, deliveries); err != nil {
+		return fmt.Errorf("create deliveries of %s event %s: %w", event.Type, event.ID, err)
+	}
+
+	return nil
+}
+
+func newDelivery(subscriptionID uint, event Event) (models.WebhookDelivery, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return models.WebhookDelivery{}, fmt.Errorf("marshal %s event %s: %w", event.Type, event.ID, err)
+	}
+
+	return models.WebhookDelivery{
+		SubscriptionID: subscriptionID,
+		EventID:        event.ID,
+		EventType:      string(event.Type),
+		Payload:        payload,
+		Status:         models.WebhookDeliveryStatusPending,
+		NextAttemptAt:  time.Now(),
+	}, nil
+}
+
+func eventTypeStrings(eventTypes []EventType) ([]string, error) {
+	strs := make([]string, 0, len(eventTypes))
+	for _, eventType := range eventTypes {
+		if !eventType.Valid() {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidEventType, eventType)
+		}
+
+		if !slices.Contains(strs, string(eventType)) {
+			strs = append(strs, string(eventType))
+		}
+	}
+
+	return strs, nil
+}
+
+func generateSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(secret), nil
+}
+
+// NewService creates a new webhook service.
+// drugsGetter can be nil, in which case no low stock events are sent.
+func NewService(db *gorm.DB, drugsGetter DrugsGetter) *Service {
+	return &Service{
+		db:          NewDatabase(db),
+		drugsGetter: drugsGetter,
+	}
+}
@@ -0,0 +1,250 @@
+package webhook_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/turfaa/vmedis-proxy-api/database"
+	"github.com/turfaa/vmedis-proxy-api/database/models"
+	"github.com/turfaa/vmedis-proxy-api/events"
+	"github.com/turfaa/vmedis-proxy-api/kafkapb"
+	"github.com/turfaa/vmedis-proxy-api/pkg2/queue"
+	"github.com/turfaa/vmedis-proxy-api/webhook"
+)
+
+// TestWebhookDelivery checks that an event is delivered as signed JSON only to the active subscriptions
+// subscribed to its type, and that the delivery is logged.
+func TestWebhookDelivery(t *testing.T) {
+	ctx := context.Background()
+	service := newTestService(t)
+	receiver := newTestReceiver(t, "secret", http.StatusOK)
+
+	subscription, _, err := service.CreateSubscription(ctx, webhook.CreateSubscriptionRequest{
+		URL:        receiver.server.URL,
+		Secret:     "secret",
+		EventTypes: []webhook.EventType{webhook.EventTypeDumpCompleted},
+	}, "admin@example.com")
+	if err != nil {
+		t.Fatalf("CreateSubscription() error = %v", err)
+	}
+
+	if _, _, err := service.CreateSubscription(ctx, webhook.CreateSubscriptionRequest{
+		URL:        receiver.server.URL,
+		EventTypes: []webhook.EventType{webhook.EventTypeLowStock},
+	}, "admin@example.com"); err != nil {
+		t.Fatalf("CreateSubscription() error = %v", err)
+	}
+
+	event := webhook.Event{
+		ID:         "event-1",
+		Type:       webhook.EventTypeDumpCompleted,
+		OccurredAt: time.Now(),
+		Data:       webhook.DumpCompletedData{Dump: events.DumpSales, Records: 10},
+	}
+
+	// Notifying twice, as when a domain event is consumed twice, delivers the event once.
+	for range 2 {
+		if err := service.Notify(ctx, event); err != nil {
+			t.Fatalf("Notify() error = %v", err)
+		}
+	}
+
+	dispatcher := webhook.NewDispatcher(service, webhook.DefaultDispatcherConfig)
+	if attempts, err := dispatcher.DispatchDue(ctx); err != nil || attempts != 1 {
+		t.Fatalf("DispatchDue() = %d, %v, want 1 attempt", attempts, err)
+	}
+
+	requests := receiver.requests()
+	if len(requests) != 1 {
+		t.Fatalf("received %d requests, want 1", len(requests))
+	}
+
+	var received webhook.Event
+	if err := json.Unmarshal(requests[0].body, &received); err != nil {
+		t.Fatalf("unmarshal received body: %v", err)
+	}
+	if received.ID != "event-1" || received.Type != webhook.EventTypeDumpCompleted {
+		t.Errorf("received event %+v, want event-1 of type %s", received, webhook.EventTypeDumpCompleted)
+	}
+	if !requests[0].signatureValid {
+		t.Errorf("received request with an invalid signature")
+	}
+
+	deliveries, err := service.GetDeliveries(ctx, subscription.ID)
+	if err != nil {
+		t.Fatalf("GetDeliveries() error = %v", err)
+	}
+	if len(deliveries) != 1 || deliveries[0].Status != models.WebhookDeliveryStatusSucceeded || deliveries[0].LastStatusCode != 200 {
+		t.Errorf("deliveries = %+v, want 1 succeeded delivery", deliveries)
+	}
+}
+
+// TestWebhookRetries checks that a failed delivery is retried after the backoff,
+// and marked failed after the last attempt.
+func TestWebhookRetries(t *testing.T) {
+	ctx := context.Background()
+	service := newTestService(t)
+	receiver := newTestReceiver(t, "secret", http.StatusInternalServerError)
+
+	subscription, _, err := service.CreateSubscription(ctx, webhook.CreateSubscriptionRequest{
+		URL:        receiver.server.URL,
+		Secret:     "secret",
+		EventTypes: []webhook.EventType{webhook.EventTypeRejectedDrugCreated},
+	}, "admin@example.com")
+	if err != nil {
+		t.Fatalf("CreateSubscription() error = %v", err)
+	}
+
+	if err := service.Notify(ctx, webhook.Event{ID: "event-1", Type: webhook.EventTypeRejectedDrugCreated}); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+
+	config := webhook.DefaultDispatcherConfig
+	config.MaxAttempts = 2
+	config.InitialBackoff = 50 * time.Millisecond
+	dispatcher := webhook.NewDispatcher(service, config)
+
+	if attempts, err := dispatcher.DispatchDue(ctx); err != nil || attempts != 1 {
+		t.Fatalf("first DispatchDue() = %d, %v, want 1 attempt", attempts, err)
+	}
+	if attempts, err := dispatcher.DispatchDue(ctx); err != nil || attempts != 0 {
+		t.Fatalf("DispatchDue() during backoff = %d, %v, want no attempt", attempts, err)
+	}
+
+	deliveries, err := service.GetDeliveries(ctx, subscription.ID)
+	if err != nil {
+		t.Fatalf("GetDeliveries() error = %v", err)
+	}
+	if len(deliveries) != 1 || deliveries[0].Status != models.WebhookDeliveryStatusPending || deliveries[0].LastStatusCode != 500 {
+		t.Fatalf("deliveries = %+v, want 1 pending delivery that got 500", deliveries)
+	}
+
+	time.Sleep(config.InitialBackoff)
+
+	if attempts, err := dispatcher.DispatchDue(ctx); err != nil || attempts != 1 {
+		t.Fatalf("DispatchDue() after backoff = %d, %v, want 1 attempt", attempts, err)
+	}
+
+	deliveries, err = service.GetDeliveries(ctx, subscription.ID)
+	if err != nil {
+		t.Fatalf("GetDeliveries() error = %v", err)
+	}
+	if deliveries[0].Status != models.WebhookDeliveryStatusFailed || deliveries[0].Attempts != 2 {
+		t.Errorf("delivery = %+v, want failed after 2 attempts", deliveries[0])
+	}
+}
+
+// TestConsumer checks that only the closed shifts with a cash discrepancy are sent as webhook events.
+func TestConsumer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	service := newTestService(t)
+	q := queue.NewMemory()
+
+	subscription, _, err := service.CreateSubscription(ctx, webhook.CreateSubscriptionRequest{
+		URL:        "http://localhost",
+		EventTypes: []webhook.EventType{webhook.EventTypeShiftDiscrepancy},
+	}, "admin@example.com")
+	if err != nil {
+		t.Fatalf("CreateSubscription() error = %v", err)
+	}
+
+	if err := events.NewProducer(q).ProduceShiftClosed(ctx, []*kafkapb.ShiftClosed{
+		{Shift: &kafkapb.Shift{Code: "BALANCED"}},
+		{Shift: &kafkapb.Shift{Code: "SHORT", ExpectedFinalCash: 100000, ActualFinalCash: 90000, FinalCashDifference: -10000}},
+	}); err != nil {
+		t.Fatalf("ProduceShiftClosed() error = %v", err)
+	}
+
+	go webhook.NewConsumer(service, q).StartConsuming(ctx)
+
+	var deliveries []webhook.Delivery
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		deliveries, err = service.GetDeliveries(ctx, subscription.ID)
+		if err != nil {
+			t.Fatalf("GetDeliveries() error = %v", err)
+		}
+
+		if len(deliveries) > 0 {
+			break
+		}
+	}
+
+	// Give the consumer time to wrongly deliver the balanced shift.
+	time.Sleep(100 * time.Millisecond)
+	deliveries, err = service.GetDeliveries(ctx, subscription.ID)
+	if err != nil {
+		t.Fatalf("GetDeliveries() error = %v", err)
+	}
+
+	if len(deliveries) != 1 {
+		t.Fatalf("got %d deliveries, want 1 for the shift with a discrepancy", len(deliveries))
+	}
+
+	var event struct {
+		Data webhook.ShiftDiscrepancyData `json:"data"`
+	}
+	if err := json.Unmarshal(deliveries[0].Payload, &event); err != nil {
+		t.Fatalf("unmarshal delivery payload: %v", err)
+	}
+	if event.Data.Code != "SHORT" || event.Data.FinalCashDifference != -10000 {
+		t.Errorf("delivered %+v, want the SHORT shift", event.Data)
+	}
+}
+
+type receivedRequest struct {
+	body           []byte
+	signatureValid bool
+}
+
+type testReceiver struct {
+	server *httptest.Server
+
+	mu       sync.Mutex
+	received []receivedRequest
+}
+
+func (r *testReceiver) requests() []receivedRequest {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.received
+}
+
+func newTestReceiver(t *testing.T, secret string, statusCode int) *testReceiver {
+	t.Helper()
+
+	receiver := &testReceiver{}
+	receiver.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+
+		receiver.mu.Lock()
+		receiver.received = append(receiver.received, receivedRequest{
+			body:           body,
+			signatureValid: webhook.Verify(secret, req.Header.Get(webhook.HeaderTimestamp), req.Header.Get(webhook.HeaderSignature), body),
+		})
+		receiver.mu.Unlock()
+
+		w.WriteHeader(statusCode)
+	}))
+	t.Cleanup(receiver.server.Close)
+
+	return receiver
+}
+
+func newTestService(t *testing.T) *webhook.Service {
+	t.Helper()
+
+	db, err := database.SqliteDB(t.TempDir() + "/test.db")
+	if err != nil {
+		t.Fatalf("open test database: %v", err)
+	}
+
+	return webhook.NewService(db, nil)
+}